@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// runAck handles "notify ack [FILE]": touches FILE, or the user's own acknowledgment file, which
+// acknowledges the windows shown with -ack-file FILE (or -ack-file default); bound to a keyboard
+// shortcut of the desktop, it acknowledges without finding the window
+func runAck(args []string) int {
+	fs := flag.NewFlagSet("ack", flag.ExitOnError)
+	printPath := fs.Bool("path", false, "Print the file instead of touching it")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: notify ack [-path] [FILE]")
+		fmt.Fprintln(os.Stderr, "Acknowledges the notifications shown with -ack-file FILE (default: -ack-file default)")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	value := notify.AckFileDefault
+	if fs.NArg() == 1 {
+		value = fs.Arg(0)
+	}
+	if *printPath {
+		path, err := notify.AckFilePath(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(path)
+		return 0
+	}
+	if err := notify.TouchAckFile(value); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
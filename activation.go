@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// activationScheme is the URI scheme registered for "notify activate", launched by Windows
+// when a toast is clicked in Action Center after the notify process that showed it exited
+const activationScheme = "krankybearnotify"
+
+// activationURI returns the URI a click on the toast of stored notification id launches
+func activationURI(id string) string {
+	return activationScheme + "://ack?id=" + url.QueryEscape(id)
+}
+
+// parseActivationURI returns the stored notification ID of an activation URI, and whether the
+// click should also open the notification's link
+func parseActivationURI(rawURI string) (string, bool, error) {
+	u, err := url.Parse(rawURI)
+	if err != nil || u.Scheme != activationScheme || u.Host != "ack" {
+		return "", false, fmt.Errorf("invalid activation URI %q", rawURI)
+	}
+	id := u.Query().Get("id")
+	if id == "" || strings.Trim(id, "0123456789abcdef") != "" {
+		return "", false, fmt.Errorf("invalid notification ID in %q", rawURI)
+	}
+	return id, u.Query().Get("open") == "1", nil
+}
+
+// registerActivationProtocol registers activationScheme for the current user so it launches
+// "exePath activate URI" (Windows only); an existing registration for exePath is kept
+func registerActivationProtocol(exePath string) error {
+	if runtime.GOOS != "windows" {
+		return nil
+	}
+	key := `HKCU\Software\Classes\` + activationScheme
+	command := fmt.Sprintf(`"%s" activate "%%1"`, exePath)
+	if output, err := exec.Command("reg", "query", key+`\shell\open\command`, "/ve").Output(); err == nil && strings.Contains(string(output), command) {
+		return nil
+	}
+	for _, args := range [][]string{
+		{"add", key, "/ve", "/d", "URL:KrankyBearNotify toast activation", "/f"},
+		{"add", key, "/v", "URL Protocol", "/d", "", "/f"},
+		{"add", key + `\shell\open\command`, "/ve", "/d", command, "/f"},
+	} {
+		if output, err := exec.Command("reg", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("reg %s failed: %v (output: %s)", strings.Join(args[:2], " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// runActivate handles "notify activate URI": records the click on a toast as the acknowledgment
+// of the stored notification, posts it to its -callback-url and opens its link if asked to
+// A notification already acknowledged is not reported again
+func runActivate(args []string) int {
+	notify.HideConsoleWindow() // Launched by the protocol handler, the console would flash
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: notify activate %s://ack?id=ID\n", activationScheme)
+		return 2
+	}
+	id, open, err := parseActivationURI(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	items, err := loadStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var item *StoredNotification
+	for i := range items {
+		if items[i].ID == id {
+			item = &items[i]
+		}
+	}
+	if item == nil {
+		fmt.Fprintf(os.Stderr, "Error: notification %s not found in store\n", id)
+		return 1
+	}
+
+	if item.State != inboxStateAcknowledged {
+		reporter := resultReporter{title: item.Title, inboxID: id, callbackURL: item.CallbackURL}
+		reporter.report(actionAcknowledged, "toast")
+	}
+	if open && item.Link != "" {
+		notify.OpenLink(item.Link)
+	}
+	return 0
+}
+
+// toastActivation records a toast about to be shown in the local store and returns the URI its
+// clicks launch, or "" when it cannot be recorded or the protocol cannot be registered
+func toastActivation(shown notify.Notification, reporter *resultReporter) string {
+	exePath, err := os.Executable()
+	if err == nil {
+		err = registerActivationProtocol(exePath)
+	}
+	if err != nil {
+		log.Printf("Warning: Toast clicks will not be recorded: %v", err)
+		return ""
+	}
+	message, link := shown.Message, shown.PrimaryLink()
+	if shown.Sensitive {
+		message, link = redactedMessage, ""
+	}
+	if reporter.inboxID == "" {
+		reporter.inboxID = recordDelivery(shown.Title, message, shown.Category, link)
+	}
+	if reporter.inboxID == "" {
+		return ""
+	}
+	err = updateStoredNotification(reporter.inboxID, func(item *StoredNotification) {
+		item.Link = shown.PrimaryLink()
+		item.CallbackURL = shown.CallbackURL
+	})
+	if err != nil {
+		log.Printf("Warning: Toast clicks will not be recorded: %v", err)
+		return ""
+	}
+	return activationURI(reporter.inboxID)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestParseActivationURI tests reading the notification ID back from toast activation URIs
+func TestParseActivationURI(t *testing.T) {
+	id, open, err := parseActivationURI(activationURI("0123456789abcdef") + "&open=1")
+	if err != nil || id != "0123456789abcdef" || !open {
+		t.Errorf("parseActivationURI = %q, %v, %v", id, open, err)
+	}
+	for _, uri := range []string{
+		"https://ack?id=0123456789abcdef",
+		"krankybearnotify://run?id=0123456789abcdef",
+		"krankybearnotify://ack?id=../../etc",
+		"krankybearnotify://ack",
+	} {
+		if _, _, err := parseActivationURI(uri); err == nil {
+			t.Errorf("parseActivationURI(%q) should fail", uri)
+		}
+	}
+}
+
+// TestRunActivate tests that a toast click acknowledges the stored notification and posts
+// its -callback-url once, even when clicked again
+func TestRunActivate(t *testing.T) {
+	useTempStore(t)
+	var callbacks int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callbacks++
+	}))
+	defer server.Close()
+
+	reporter := resultReporter{title: "Patch"}
+	uri := toastActivation(notify.Notification{Title: "Patch", Message: "See https://example.com/patch", CallbackURL: server.URL}, &reporter)
+	if uri == "" {
+		t.Fatal("Expected an activation URI")
+	}
+	reporter.report(actionDelivered, "toast")
+
+	for i := 0; i < 2; i++ {
+		if code := runActivate([]string{uri}); code != 0 {
+			t.Fatalf("runActivate returned %d", code)
+		}
+	}
+	items, err := loadStore()
+	if err != nil || len(items) != 1 {
+		t.Fatalf("loadStore = %+v, %v", items, err)
+	}
+	item := items[0]
+	if item.State != inboxStateAcknowledged || item.Action != actionAcknowledged || item.Method != "toast" || item.Link != "https://example.com/patch" {
+		t.Errorf("Unexpected stored notification %+v", item)
+	}
+	if callbacks != 1 {
+		t.Errorf("Callback posted %d times, want 1", callbacks)
+	}
+
+	// The delivery reported after a quick click does not undo the acknowledgment
+	reporter.report(actionDelivered, "toast")
+	if items, _ := loadStore(); items[0].State != inboxStateAcknowledged || items[0].Action != actionAcknowledged {
+		t.Errorf("Late delivery report changed %+v", items[0])
+	}
+	if code := runActivate([]string{"krankybearnotify://ack?id=ffffffffffffffff"}); code != 1 {
+		t.Errorf("runActivate(unknown) = %d, want 1", code)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// preferNativeArchFlag hands the invocation to the native build next to an emulated binary
+const preferNativeArchFlag = "prefer-native-arch"
+
+// hasPreferNativeArch reports whether args ask for -prefer-native-arch (checked before flag parsing,
+// so the emulated process never starts a GUI)
+func hasPreferNativeArch(args []string) bool {
+	for _, arg := range args {
+		if strings.TrimLeft(arg, "-") == preferNativeArchFlag && strings.HasPrefix(arg, "-") {
+			return true
+		}
+	}
+	return false
+}
+
+// runNativeBuild runs the native build next to this emulated executable with args (without
+// -prefer-native-arch) and returns its exit code; ok is false when notify runs natively or
+// there is no native build, and this process carries on itself
+func runNativeBuild(args []string) (code int, ok bool) {
+	e := notify.DetectEmulation()
+	if e == nil {
+		return 0, false
+	}
+	native := notify.NativeBuild()
+	if native == "" {
+		log.Printf("Running as %s, no %s build next to this one", e, e.NativeArch)
+		return 0, false
+	}
+	var forwarded []string
+	for _, arg := range args {
+		if strings.TrimLeft(arg, "-") != preferNativeArchFlag {
+			forwarded = append(forwarded, arg)
+		}
+	}
+	log.Printf("Running as %s, handing over to %s", e, native)
+	cmd := exec.Command(native, forwarded...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+			return exitErr.ExitCode(), true
+		}
+		fmt.Fprintf(os.Stderr, "Warning: Could not run the native build %s: %v\n", native, err)
+		return 0, false
+	}
+	return 0, true
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// runBreakGlass handles "notify breakglass keygen|sign", the tooling for the escrowed
+// signing key that authorizes -priority breakglass notifications
+func runBreakGlass(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: notify breakglass keygen -out DIR | notify breakglass sign -key FILE -issuer NAME -title TITLE -message MESSAGE [-reason TEXT] [-valid 1h]")
+		return 2
+	}
+	switch args[0] {
+	case "keygen":
+		return breakGlassKeygen(args[1:])
+	case "sign":
+		return breakGlassSign(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "Error: unknown breakglass command %q (use keygen or sign)\n", args[0])
+	return 2
+}
+
+// breakGlassKeygen writes a new signing key (breakglass.key, to be escrowed offline)
+// and its public key (breakglass.pub, to be installed at notify.BreakGlassKeyPath)
+func breakGlassKeygen(args []string) int {
+	fs := flag.NewFlagSet("breakglass keygen", flag.ExitOnError)
+	outDir := fs.String("out", ".", "Directory for breakglass.key and breakglass.pub")
+	fs.Parse(args)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to generate key: %v\n", err)
+		return 1
+	}
+	keyPath := filepath.Join(*outDir, "breakglass.key")
+	pubPath := filepath.Join(*outDir, "breakglass.pub")
+	if _, err := os.Stat(keyPath); err == nil {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists, refusing to overwrite a signing key\n", keyPath)
+		return 1
+	}
+	if err := os.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(privateKey)+"\n"), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	pub := fmt.Sprintf("# KrankyBearNotify break-glass key, generated %s\n%s\n", time.Now().Format("2006-01-02"), base64.StdEncoding.EncodeToString(publicKey))
+	if err := os.WriteFile(pubPath, []byte(pub), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Signing key: %s (keep offline, e.g. in escrow with the security team)\n", keyPath)
+	fmt.Printf("Public key:  %s (install as %s, writable by root/Administrators only)\n", pubPath, notify.BreakGlassKeyPath())
+	return 0
+}
+
+// breakGlassSign prints a token authorizing one title and message as a break-glass notification
+func breakGlassSign(args []string) int {
+	fs := flag.NewFlagSet("breakglass sign", flag.ExitOnError)
+	keyPath := fs.String("key", "breakglass.key", "Signing key written by notify breakglass keygen")
+	issuer := fs.String("issuer", "", "Who authorized the notification (recorded in every audit entry)")
+	reason := fs.String("reason", "", "Why break glass is needed (recorded in every audit entry)")
+	valid := fs.Duration("valid", time.Hour, "How long the token can be used")
+	var n notify.Notification
+	fs.StringVar(&n.Title, "title", notify.DefaultTitle, "Notification title, exactly as passed to notify")
+	fs.StringVar(&n.Message, "message", notify.DefaultMessage, "Notification message, exactly as passed to notify")
+	encoded := fs.Bool("encoded", false, "Decode percent-encoded -title and -message (use when notify is run with -encoded)")
+	legacyDecode := fs.Bool("legacy-decode", false, "Decode -title and -message like notify -legacy-decode")
+	fs.Parse(args)
+
+	mode := decodeNone
+	if *encoded {
+		mode = decodeEncoded
+	} else if *legacyDecode {
+		mode = decodeLegacy
+	}
+
+	data, err := os.ReadFile(*keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read signing key: %v\n", err)
+		return 1
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != ed25519.PrivateKeySize {
+		fmt.Fprintf(os.Stderr, "Error: %s is not a break-glass signing key\n", *keyPath)
+		return 1
+	}
+
+	// notify checks the token against the decoded text, so sign the decoded text
+	if err := decodeNotification(&n, mode); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	token, err := notify.SignBreakGlass(ed25519.PrivateKey(raw), n, *issuer, *reason, *valid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println(token)
+	return 0
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// calendarFetchTimeout bounds downloading a -calendar ICS/CalDAV URL
+const calendarFetchTimeout = 30 * time.Second
+
+// maxWindowSearchDays is how far ahead nextWindow looks for a delivery window
+const maxWindowSearchDays = 366
+
+// weekdayNames maps the day names accepted by -business-hours to time.Weekday
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// calendarEvent is a holiday or other blocked period from a work calendar
+type calendarEvent struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+	AllDay  bool
+	Yearly  bool // RRULE:FREQ=YEARLY, e.g. fixed-date public holidays
+}
+
+// workCalendar decides when "business hours only" notifications may be delivered
+type workCalendar struct {
+	Days   [7]bool       // Work days, indexed by time.Weekday
+	Start  time.Duration // Start of the work day, from midnight
+	End    time.Duration // End of the work day, from midnight
+	Events []calendarEvent
+	Loc    *time.Location
+}
+
+// deliveryWindow is the next period in which a notification may be delivered
+type deliveryWindow struct {
+	Start   time.Time
+	End     time.Time
+	Skipped []string // Days passed over and why, e.g. "Sat 27 Dec (not a work day)"
+}
+
+// parseBusinessHours parses a -business-hours value such as "Mon-Fri 09:00-17:00",
+// "Mon,Wed,Fri 08:30-12:00" or "09:00-17:00" (Monday to Friday)
+func parseBusinessHours(value string, loc *time.Location) (*workCalendar, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid business hours %q (expected e.g. \"Mon-Fri 09:00-17:00\")", value)
+	}
+
+	cal := &workCalendar{Loc: loc}
+	days := "mon-fri"
+	hours := fields[0]
+	if len(fields) == 2 {
+		days, hours = strings.ToLower(fields[0]), fields[1]
+	}
+
+	for _, part := range strings.Split(days, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdayNames[from]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q in business hours (use Mon, Tue, ... Sun)", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdayNames[to]; !ok {
+				return nil, fmt.Errorf("invalid day %q in business hours (use Mon, Tue, ... Sun)", to)
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			cal.Days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid hours %q in business hours (expected HH:MM-HH:MM)", hours)
+	}
+	var err error
+	if cal.Start, err = parseClock(from); err != nil {
+		return nil, err
+	}
+	if cal.End, err = parseClock(to); err != nil {
+		return nil, err
+	}
+	if cal.End <= cal.Start {
+		return nil, fmt.Errorf("business hours %q end before they start", hours)
+	}
+	return cal, nil
+}
+
+// parseClock parses HH:MM into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// loadCalendarEvents reads holidays from an ICS file or an http(s) ICS/CalDAV URL
+func loadCalendarEvents(source string, loc *time.Location) ([]calendarEvent, error) {
+	var data []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: calendarFetchTimeout}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch calendar: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("could not fetch calendar: %s", resp.Status)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("could not fetch calendar: %v", err)
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(source); err != nil {
+			return nil, fmt.Errorf("could not read calendar: %v", err)
+		}
+	}
+	return parseICS(data, loc)
+}
+
+// parseICS extracts the VEVENTs of an iCalendar file
+// Floating times (no Z or TZID) are read in loc; only yearly recurrence is supported
+func parseICS(data []byte, loc *time.Location) ([]calendarEvent, error) {
+	// Unfold continuation lines (RFC 5545 3.1)
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read calendar: %v", err)
+	}
+
+	var events []calendarEvent
+	var current *calendarEvent
+	hasEnd := false
+	for n, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(name, ";")
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &calendarEvent{}
+			hasEnd = false
+		case name == "END" && value == "VEVENT" && current != nil:
+			if current.Start.IsZero() {
+				return nil, fmt.Errorf("calendar line %d: event %q has no DTSTART", n+1, current.Summary)
+			}
+			if !hasEnd {
+				current.End = current.Start.Add(time.Second)
+				if current.AllDay {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "SUMMARY":
+			current.Summary = strings.ReplaceAll(value, `\,`, ",")
+		case name == "RRULE":
+			current.Yearly = strings.Contains(value, "FREQ=YEARLY")
+		case name == "DTSTART" || name == "DTEND":
+			t, allDay, err := parseICSTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("calendar line %d: %v", n+1, err)
+			}
+			if name == "DTSTART" {
+				current.Start, current.AllDay = t, allDay
+			} else {
+				current.End, hasEnd = t, true
+			}
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events, nil
+}
+
+// parseICSTime parses a DTSTART/DTEND value with its parameters (VALUE=DATE, TZID=...)
+func parseICSTime(value, params string, loc *time.Location) (time.Time, bool, error) {
+	for _, param := range strings.Split(params, ";") {
+		if tzid, ok := strings.CutPrefix(param, "TZID="); ok {
+			if zone, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+				loc = zone
+			}
+		}
+	}
+	if len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// occurrence returns the event's period in the given year for yearly events
+func (e calendarEvent) occurrence(year int) (time.Time, time.Time) {
+	if !e.Yearly {
+		return e.Start, e.End
+	}
+	shift := year - e.Start.Year()
+	return e.Start.AddDate(shift, 0, 0), e.End.AddDate(shift, 0, 0)
+}
+
+// blockingEvent returns the event covering t, if any
+func (c *workCalendar) blockingEvent(t time.Time) (calendarEvent, time.Time, bool) {
+	for _, e := range c.Events {
+		for _, year := range []int{t.Year() - 1, t.Year()} {
+			start, end := e.occurrence(year)
+			if !t.Before(start) && t.Before(end) {
+				return e, end, true
+			}
+		}
+	}
+	return calendarEvent{}, time.Time{}, false
+}
+
+// at returns the wall-clock time offset from midnight on day (correct across DST changes)
+func (c *workCalendar) at(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, c.Loc)
+}
+
+// nextWindow returns the first delivery window at or after from, skipping
+// non-work days and calendar events
+func (c *workCalendar) nextWindow(from time.Time) (deliveryWindow, error) {
+	var window deliveryWindow
+	from = from.In(c.Loc)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, c.Loc)
+
+	for i := 0; i < maxWindowSearchDays; i++ {
+		label := day.Format("Mon 2 Jan")
+		dayStart := c.at(day, c.Start)
+		dayEnd := c.at(day, c.End)
+
+		if !c.Days[day.Weekday()] {
+			window.Skipped = append(window.Skipped, label+" (not a work day)")
+			day = day.AddDate(0, 0, 1)
+			continue
+		}
+
+		start := dayStart
+		if from.After(start) {
+			start = from
+		}
+		// Step past events (holidays, closures) overlapping the rest of the day
+		var blockedBy string
+		for start.Before(dayEnd) {
+			event, end, blocked := c.blockingEvent(start)
+			if !blocked {
+				break
+			}
+			blockedBy = event.Summary
+			start = end
+		}
+		if start.Before(dayEnd) {
+			window.Start, window.End = start, dayEnd
+			return window, nil
+		}
+
+		switch {
+		case blockedBy != "":
+			window.Skipped = append(window.Skipped, label+" ("+blockedBy+")")
+		case i == 0:
+			window.Skipped = append(window.Skipped, label+" (after hours)")
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return window, fmt.Errorf("no delivery window in the next %d days", maxWindowSearchDays)
+}
+
+// loadWorkCalendar builds the calendar for -business-hours and -calendar in the -tz zone
+// (the machine's local zone when -tz is not set)
+func loadWorkCalendar(hours, source, tz string) (*workCalendar, error) {
+	loc := time.Local
+	if tz != "" {
+		var err error
+		if loc, err = notify.LoadTimeZone(tz); err != nil {
+			return nil, err
+		}
+	}
+	cal, err := parseBusinessHours(hours, loc)
+	if err != nil {
+		return nil, err
+	}
+	if source != "" {
+		if cal.Events, err = loadCalendarEvents(source, loc); err != nil {
+			return nil, err
+		}
+	}
+	return cal, nil
+}
+
+// waitUntil sleeps until t, re-checking the wall clock every minute so a
+// machine that was suspended does not deliver late
+func waitUntil(t time.Time) {
+	for {
+		remaining := time.Until(t)
+		if remaining <= 0 {
+			return
+		}
+		if remaining > time.Minute {
+			remaining = time.Minute
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// printDeliveryPlan prints the -dry-run report: the business-hours window and the rollout decision
+// breakGlass is set for an authorized -priority breakglass notification, which ignores business hours
+func printDeliveryPlan(hours, source string, cal *workCalendar, window *deliveryWindow, breakGlass *notify.BreakGlassClaims, inRollout bool, rolloutPercent int) {
+	fmt.Println("=== Delivery Plan (dry run) ===")
+	if breakGlass != nil {
+		fmt.Printf("Priority: breakglass, authorized by %s until %s\n", breakGlass.Issuer, time.Unix(breakGlass.Expires, 0).Format("Mon 2 Jan 2006 15:04 MST"))
+		fmt.Println("Business hours: ignored, deliver immediately")
+	} else if cal == nil {
+		fmt.Println("Business hours: not configured, deliver immediately")
+	} else {
+		fmt.Printf("Business hours: %s (%s)\n", hours, cal.Loc)
+		if source != "" {
+			fmt.Printf("Calendar: %s (%d events)\n", source, len(cal.Events))
+		}
+		for _, skipped := range window.Skipped {
+			fmt.Printf("  skipping %s\n", skipped)
+		}
+		if window.Start.After(time.Now()) {
+			fmt.Printf("Next delivery window: %s - %s\n", window.Start.Format("Mon 2 Jan 2006 15:04 MST"), window.End.Format("15:04 MST"))
+		} else {
+			fmt.Printf("Inside business hours: deliver immediately (window ends %s)\n", window.End.Format("15:04 MST"))
+		}
+	}
+
+	if rolloutPercent < 100 {
+		if inRollout {
+			fmt.Printf("Rollout: this machine is inside the %d%% rollout\n", rolloutPercent)
+		} else {
+			fmt.Printf("Rollout: this machine is outside the %d%% rollout, nothing would be shown\n", rolloutPercent)
+		}
+	}
+}
+
+// parseDeliverBy parses a -deliver-by deadline: a time of day ("17:00", today in tz or
+// local time) or an RFC 3339 timestamp (as passed to notify processes in other users' sessions)
+func parseDeliverBy(value, tz string, now time.Time) (time.Time, error) {
+	if deadline, err := time.Parse(time.RFC3339, value); err == nil {
+		return deadline, nil
+	}
+	loc := time.Local
+	if tz != "" {
+		var err error
+		if loc, err = notify.LoadTimeZone(tz); err != nil {
+			return time.Time{}, err
+		}
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -deliver-by %q (use HH:MM or an RFC 3339 time)", value)
+	}
+	now = now.In(loc)
+	deadline := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	if !deadline.After(now) {
+		return time.Time{}, fmt.Errorf("-deliver-by %s has already passed today", value)
+	}
+	return deadline, nil
+}
+
+// printEscalationPlan prints the -deliver-by attempts for a dry run
+func printEscalationPlan(start, deadline time.Time) {
+	fmt.Printf("Deliver by: %s\n", deadline.Format("Mon 2 Jan 2006 15:04 MST"))
+	for _, step := range notify.EscalationPlan(start, deadline) {
+		switch {
+		case step.Final:
+			fmt.Printf("  %s  wall broadcast (Linux) and a window that stays until acknowledged\n", step.At.Format("15:04"))
+		case step.Mode == notify.ModeNative:
+			fmt.Printf("  %s  toast / notification center\n", step.At.Format("15:04"))
+		default:
+			fmt.Printf("  %s  window, if not yet acknowledged\n", step.At.Format("15:04"))
+		}
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testHolidays is a small ICS calendar with an all-day yearly holiday and a timed closure
+const testHolidays = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas Day\r\n" +
+	"DTSTART;VALUE=DATE:20201225\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Office closed for\r\n" +
+	"  training\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20251230T090000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20251230T130000\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+// TestParseBusinessHours tests day lists, ranges and the Monday-Friday default
+func TestParseBusinessHours(t *testing.T) {
+	cal, err := parseBusinessHours("Mon,Wed-Thu 08:30-12:00", time.UTC)
+	if err != nil {
+		t.Fatalf("parseBusinessHours failed: %v", err)
+	}
+	want := [7]bool{time.Monday: true, time.Wednesday: true, time.Thursday: true}
+	if cal.Days != want {
+		t.Errorf("Expected Mon, Wed and Thu, got %v", cal.Days)
+	}
+	if cal.Start != 8*time.Hour+30*time.Minute || cal.End != 12*time.Hour {
+		t.Errorf("Expected 08:30-12:00, got %v-%v", cal.Start, cal.End)
+	}
+
+	cal, err = parseBusinessHours("09:00-17:00", time.UTC)
+	if err != nil {
+		t.Fatalf("parseBusinessHours failed: %v", err)
+	}
+	if cal.Days[time.Saturday] || cal.Days[time.Sunday] || !cal.Days[time.Friday] {
+		t.Errorf("Expected Monday to Friday by default, got %v", cal.Days)
+	}
+
+	for _, bad := range []string{"", "Mon-Fri", "Funday 09:00-17:00", "Mon-Fri 17:00-09:00", "Mon-Fri 9am-5pm"} {
+		if _, err := parseBusinessHours(bad, time.UTC); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+// TestParseICS tests all-day, timed, folded and yearly events
+func TestParseICS(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	events, err := parseICS([]byte(testHolidays), berlin)
+	if err != nil {
+		t.Fatalf("parseICS failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	christmas := events[0]
+	if !christmas.AllDay || !christmas.Yearly || christmas.End.Sub(christmas.Start) != 24*time.Hour {
+		t.Errorf("Expected a yearly all-day event, got %+v", christmas)
+	}
+	if events[1].Summary != "Office closed for training" {
+		t.Errorf("Expected the folded summary to be unfolded, got %q", events[1].Summary)
+	}
+
+	if _, err := parseICS([]byte("BEGIN:VEVENT\nSUMMARY:No date\nEND:VEVENT\n"), berlin); err == nil {
+		t.Error("Expected an error for an event without DTSTART")
+	}
+}
+
+// TestNextWindow tests skipping weekends, yearly holidays, timed closures and after-hours
+func TestNextWindow(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	cal, _ := parseBusinessHours("Mon-Fri 09:00-17:00", berlin)
+	cal.Events, _ = parseICS([]byte(testHolidays), berlin)
+
+	tests := []struct {
+		name    string
+		from    time.Time
+		start   string
+		skipped int
+	}{
+		{"inside hours", time.Date(2025, 12, 22, 10, 0, 0, 0, berlin), "Mon 22 Dec 10:00", 0},
+		{"before hours", time.Date(2025, 12, 22, 7, 0, 0, 0, berlin), "Mon 22 Dec 09:00", 0},
+		{"after hours", time.Date(2025, 12, 22, 18, 0, 0, 0, berlin), "Tue 23 Dec 09:00", 1},
+		{"holiday", time.Date(2025, 12, 24, 18, 0, 0, 0, berlin), "Fri 26 Dec 09:00", 2},
+		{"weekend", time.Date(2025, 12, 27, 12, 0, 0, 0, berlin), "Mon 29 Dec 09:00", 2},
+		{"timed closure", time.Date(2025, 12, 30, 8, 0, 0, 0, berlin), "Tue 30 Dec 13:00", 0},
+	}
+	for _, tt := range tests {
+		window, err := cal.nextWindow(tt.from)
+		if err != nil {
+			t.Errorf("%s: nextWindow failed: %v", tt.name, err)
+			continue
+		}
+		if got := window.Start.Format("Mon 2 Jan 15:04"); got != tt.start {
+			t.Errorf("%s: expected window at %s, got %s (skipped %v)", tt.name, tt.start, got, window.Skipped)
+		}
+		if len(window.Skipped) != tt.skipped {
+			t.Errorf("%s: expected %d skipped days, got %v", tt.name, tt.skipped, window.Skipped)
+		}
+	}
+
+	window, _ := cal.nextWindow(time.Date(2025, 12, 24, 18, 0, 0, 0, berlin))
+	if !strings.Contains(strings.Join(window.Skipped, ";"), "Christmas Day") {
+		t.Errorf("Expected Christmas Day in the skipped days, got %v", window.Skipped)
+	}
+}
+
+// TestParseDeliverBy tests -deliver-by times of day and RFC 3339 deadlines
+func TestParseDeliverBy(t *testing.T) {
+	now := time.Date(2025, 3, 3, 9, 30, 0, 0, time.UTC)
+
+	deadline, err := parseDeliverBy("17:00", "Europe/Berlin", now)
+	if err != nil {
+		t.Fatalf("parseDeliverBy failed: %v", err)
+	}
+	if want := time.Date(2025, 3, 3, 16, 0, 0, 0, time.UTC); !deadline.Equal(want) {
+		t.Errorf("17:00 Berlin = %v, want %v", deadline, want)
+	}
+
+	deadline, err = parseDeliverBy("2025-03-04T08:00:00Z", "", now)
+	if err != nil || !deadline.Equal(time.Date(2025, 3, 4, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("RFC 3339 deadline = %v, %v", deadline, err)
+	}
+
+	if _, err := parseDeliverBy("10:00", "Europe/Berlin", now); err == nil {
+		t.Error("Expected an error for a time that has passed today")
+	}
+	if _, err := parseDeliverBy("5pm", "", now); err == nil {
+		t.Error("Expected an error for an invalid time")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"time"
+)
+
+// callbackTimeout bounds each -callback-url request, so an unreachable server cannot keep notify running
+const callbackTimeout = 10 * time.Second
+
+// callbackAttempts is how often a failed -callback-url POST is tried before giving up
+const callbackAttempts = 3
+
+// callbackPayload is the JSON posted to -callback-url: the -result-json payload with the user
+// and machine the outcome happened on
+type callbackPayload struct {
+	NotificationResult
+	User     string `json:"user,omitempty"`
+	Hostname string `json:"hostname"`
+}
+
+// validateCallbackURL checks a -callback-url value: an absolute http(s) URL
+func validateCallbackURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid -callback-url %q (use an http or https URL)", rawURL)
+	}
+	return nil
+}
+
+// postCallback posts result to callbackURL, retrying network errors and server errors
+func postCallback(callbackURL string, result NotificationResult) error {
+	payload := callbackPayload{NotificationResult: result}
+	payload.Hostname, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		payload.User = u.Username
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not encode callback: %v", err)
+	}
+
+	client := &http.Client{Timeout: callbackTimeout}
+	for attempt := 1; ; attempt++ {
+		retry, err := sendCallback(client, callbackURL, data)
+		if err == nil || !retry || attempt == callbackAttempts {
+			return err
+		}
+		log.Printf("Callback attempt %d failed: %v", attempt, err)
+		time.Sleep(time.Duration(attempt) * 2 * time.Second)
+	}
+}
+
+// sendCallback makes one -callback-url request, returning whether a failure is worth retrying
+// (network errors and 5xx responses, not 4xx)
+func sendCallback(client *http.Client, callbackURL string, data []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(data))
+	if err != nil {
+		return false, fmt.Errorf("could not create callback request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KrankyBearNotify/"+appVersion)
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("callback failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500, fmt.Errorf("callback failed: %s", resp.Status)
+	}
+	return false, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestPostCallback tests the payload posted to -callback-url and that 4xx responses are not retried
+func TestPostCallback(t *testing.T) {
+	var requests int
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected %s request with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Could not decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	result := NotificationResult{MachineID: "abc", Action: actionTimeout, Method: "fyne", Title: "Patch", Timestamp: "2026-01-02T03:04:05Z"}
+	if err := postCallback(server.URL+"/ack", result); err != nil {
+		t.Fatalf("postCallback failed: %v", err)
+	}
+	for _, key := range []string{"machine_id", "action", "title", "timestamp", "hostname"} {
+		if _, ok := payload[key]; !ok {
+			t.Errorf("Payload %v has no %q", payload, key)
+		}
+	}
+	if payload["action"] != actionTimeout {
+		t.Errorf("Payload action = %v, want %q", payload["action"], actionTimeout)
+	}
+
+	requests = 0
+	if err := postCallback(server.URL+"/gone", result); err == nil {
+		t.Error("Expected an error for a 410 response")
+	}
+	if requests != 1 {
+		t.Errorf("A 410 response was tried %d times, want 1", requests)
+	}
+}
+
+// TestValidateCallbackURL tests which -callback-url values are accepted
+func TestValidateCallbackURL(t *testing.T) {
+	for rawURL, valid := range map[string]bool{
+		"https://acks.example.com/notify": true,
+		"http://10.0.0.5:8080/ack":        true,
+		"ftp://example.com/ack":           false,
+		"acks.example.com/notify":         false,
+		"https://":                        false,
+	} {
+		if err := validateCallbackURL(rawURL); (err == nil) != valid {
+			t.Errorf("validateCallbackURL(%q) = %v, want valid %v", rawURL, err, valid)
+		}
+	}
+}
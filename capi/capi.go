@@ -0,0 +1,88 @@
+// Package main builds libkrankybearnotify, a C shared library (go build -buildmode=c-shared
+// ./capi) for agents written in C, C++, .NET or Python that show notifications with a function
+// call instead of building notify command lines and parsing their output
+// Each notification is shown by a notify CLI process: Fyne needs the main thread of its
+// process, which a library called from any of the host's threads cannot have
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"os/exec"
+	"sync"
+	"unsafe"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+var (
+	// mu guards executable
+	mu sync.Mutex
+
+	// executable is the notify CLI that shows the notifications (notify_set_executable)
+	executable string
+)
+
+// notify_show shows the notification described by jsonSpec, a notification spec in JSON as
+// sent to notify serve, and blocks until it is acknowledged, times out or is handed off
+// It returns the result as JSON, {"action": ..., "method": ...} or {"error": ...}, to be
+// released with notify_free; it may be called from any thread, also several at once
+//
+//export notify_show
+func notify_show(jsonSpec *C.char) *C.char {
+	data := []byte(C.GoString(jsonSpec))
+	opts, err := parseRequest(data)
+	if err != nil {
+		return resultString(errorResult(notify.DefaultTitle, err))
+	}
+	spec, err := childSpec(data)
+	if err != nil {
+		return resultString(errorResult(opts.Title, err))
+	}
+	mu.Lock()
+	exePath := notifyExecutable()
+	mu.Unlock()
+	return resultString(runNotify(exePath, spec, opts.Title, opts.Debug))
+}
+
+// notify_set_executable sets the path of the notify CLI that shows the notifications, and
+// reaches each logged-in user's session when the host runs as root/SYSTEM
+//
+//export notify_set_executable
+func notify_set_executable(path *C.char) {
+	mu.Lock()
+	defer mu.Unlock()
+	executable = C.GoString(path)
+}
+
+// notify_free releases a string returned by notify_show
+//
+//export notify_free
+func notify_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// notifyExecutable returns the notify CLI: the one set with notify_set_executable, else notify
+// on the PATH; never the host program, which does not understand the notify flags
+func notifyExecutable() string {
+	if executable != "" {
+		return executable
+	}
+	if path, err := exec.LookPath("notify"); err == nil {
+		return path
+	}
+	return "notify" // Fails to start with a clear error
+}
+
+// resultString returns r as a JSON C string for notify_show
+func resultString(r showResult) *C.char {
+	return C.CString(encodeResult(r))
+}
+
+// main is required by -buildmode=c-shared, and not called
+func main() {}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
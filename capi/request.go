@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/client"
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// showRequest is the JSON of notify_show: a notification spec, as sent to notify serve
+type showRequest struct {
+	client.Notification
+	Timeout json.RawMessage `json:"timeout,omitempty"` // Seconds or "auto", replacing the client's seconds
+	Debug   bool            `json:"debug,omitempty"`   // Log to stderr, and pass -debug to other users' notify processes
+}
+
+// showResult is the JSON notify_show returns: fields of the notify -result-json result, or
+// Error when nothing could be shown
+type showResult struct {
+	Action     string                `json:"action,omitempty"`
+	Method     string                `json:"method,omitempty"`
+	Input      *string               `json:"input,omitempty"` // Text entered for input, when acknowledged
+	Choice     string                `json:"choice,omitempty"`
+	Downgrades []string              `json:"downgrades,omitempty"`
+	Targets    []notify.TargetResult `json:"targets,omitempty"`
+	Title      string                `json:"title,omitempty"`
+	Timestamp  string                `json:"timestamp"`
+	Error      string                `json:"error,omitempty"`
+}
+
+// choiceExitBase is the exit code of notify for the first of the choices, as in the CLI
+const choiceExitBase = 10
+
+// deliveryModes maps the delivery.mode of a spec to the library modes
+var deliveryModes = map[string]string{
+	"":        notify.ModeAuto,
+	"auto":    notify.ModeAuto,
+	"quick":   notify.ModeQuick,
+	"native":  notify.ModeNative,
+	"wall":    notify.ModeWall,
+	"webview": notify.ModeWebView,
+	"basic":   notify.ModeBasic,
+}
+
+// parseRequest decodes the JSON of notify_show into the Options the notify CLI would send,
+// checked as the CLI checks its flags, so mistakes are reported without starting notify
+func parseRequest(data []byte) (notify.Options, error) {
+	var req showRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return notify.Options{}, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if req.Message == "" && req.HTML == "" && req.Cancel == "" {
+		return notify.Options{}, fmt.Errorf("missing required field \"message\"")
+	}
+	if req.Schedule != nil && (req.Schedule.BusinessHours != "" || req.Schedule.Calendar != "") {
+		return notify.Options{}, fmt.Errorf("schedule.business_hours and schedule.calendar can block notify_show for hours, run the notify CLI for them")
+	}
+
+	n := notify.Notification{
+		Title:            req.Title,
+		Message:          req.Message,
+		ButtonText:       req.Button,
+		Timeout:          notify.UrgencyTimeout(req.Urgency),
+		IconPath:         req.Icon,
+		IconData:         req.IconData,
+		BuiltinIcon:      req.BuiltinIcon,
+		Link:             req.Link,
+		Width:            req.Width,
+		Height:           req.Height,
+		TimeZone:         req.TimeZone,
+		Locale:           req.Locale,
+		Category:         req.Category,
+		Sound:            req.Sound,
+		Input:            req.Input,
+		InputDefault:     req.InputDefault,
+		InputPlaceholder: req.InputPlaceholder,
+		Choices:          strings.Join(req.Choices, ","),
+		HTML:             req.HTML,
+		Theme:            req.Theme,
+		BackgroundColor:  req.BgColor,
+		ForegroundColor:  req.FgColor,
+		AccentColor:      req.AccentColor,
+		Font:             req.Font,
+		FontSize:         req.FontSize,
+		State:            req.State,
+		StateID:          req.StateID,
+		OTP:              req.OTP,
+		Urgency:          req.Urgency,
+		Priority:         req.Priority,
+		BreakGlassToken:  req.BreakGlassToken,
+		AckFile:          req.AckFile,
+		RespectDND:       req.RespectDND,
+		OverrideDND:      req.OverrideDND,
+		Sensitive:        req.Sensitive,
+		RedactAfterAck:   req.RedactAfterAck,
+		Monitor:          req.Monitor,
+		Topmost:          req.Topmost,
+		Frameless:        req.Frameless,
+		Fullscreen:       req.Fullscreen,
+		Stacking:         req.Stacking,
+		HideCountdown:    req.HideCountdown,
+		RTL:              req.RTL,
+		ID:               req.ID,
+		Replace:          req.Replace,
+		Cancel:           req.Cancel,
+	}
+	if n.Title == "" {
+		n.Title = notify.DefaultTitle
+	}
+	if n.ButtonText == "" {
+		n.ButtonText = notify.DefaultButton
+	}
+	if n.Width == 0 {
+		n.Width = notify.DefaultWidth
+	}
+	if n.Height == 0 {
+		n.Height = notify.DefaultHeight
+	}
+	if len(req.Timeout) > 0 {
+		var seconds int
+		if string(req.Timeout) == `"auto"` {
+			n.Timeout = notify.TimeoutAuto
+		} else if err := json.Unmarshal(req.Timeout, &seconds); err != nil || seconds < 0 {
+			return notify.Options{}, fmt.Errorf("invalid timeout %s (use seconds, 0 for no timeout, or \"auto\")", req.Timeout)
+		} else {
+			n.Timeout = seconds
+		}
+	}
+	var err error
+	if req.OTPExpiry != "" {
+		if n.OTPExpiry, err = time.ParseDuration(req.OTPExpiry); err != nil {
+			return notify.Options{}, fmt.Errorf("invalid otp_expiry: %v", err)
+		}
+	}
+	if req.AttentionAfter != "" {
+		if n.AttentionAfter, err = time.ParseDuration(req.AttentionAfter); err != nil {
+			return notify.Options{}, fmt.Errorf("invalid attention_after: %v", err)
+		}
+	}
+	if req.StateIcons != nil {
+		icons := map[string]string{}
+		for state, path := range map[string]string{
+			notify.StatePending: req.StateIcons.Pending,
+			notify.StateWorking: req.StateIcons.Working,
+			notify.StateSuccess: req.StateIcons.Success,
+			notify.StateFailed:  req.StateIcons.Failed,
+		} {
+			if path != "" {
+				icons[state] = path
+			}
+		}
+		n.StateIcons = notify.FormatStateIcons(icons)
+	}
+
+	for _, err := range []error{
+		notify.ValidateUrgency(n.Urgency),
+		notify.ValidatePriority(n.Priority),
+		notify.ValidateTheme(n.Theme),
+		notify.ValidateLocale(n.Locale),
+		notify.ValidateColor("bg-color", n.BackgroundColor),
+		notify.ValidateColor("fg-color", n.ForegroundColor),
+		notify.ValidateColor("accent-color", n.AccentColor),
+		notify.ValidateFont(n.Font),
+		notify.ValidateFontSize(n.FontSize),
+		notify.ValidateState(n.State),
+		notify.ValidateStateID(n.StateID),
+		notify.ValidateMonitor(n.Monitor),
+		notify.ValidateStacking(n.Stacking),
+		notify.ValidateID(n.ID),
+		notify.ValidateID(n.Cancel),
+		notify.ValidateSound(n.Sound),
+		notify.ValidateIconData(n.IconData),
+		notify.ValidateBuiltinIcon(n.BuiltinIcon),
+		notify.ValidateOTP(n.OTP),
+		notify.ValidateRespectDND(n.RespectDND),
+		notify.ValidateAckFile(n.AckFile),
+	} {
+		if err != nil {
+			return notify.Options{}, err
+		}
+	}
+	if n.Link != "" {
+		if err := notify.ValidateLink(n.Link); err != nil {
+			return notify.Options{}, err
+		}
+	}
+
+	opts := notify.Options{Notification: n, Mode: notify.ModeAuto, Debug: req.Debug}
+	if d := req.Delivery; d != nil {
+		mode, ok := deliveryModes[d.Mode]
+		if !ok {
+			return notify.Options{}, fmt.Errorf("invalid delivery.mode %q (use auto, quick, native, wall, webview or basic)", d.Mode)
+		}
+		opts.Mode = mode
+		if d.ForceWall {
+			opts.Mode = notify.ModeWall
+		}
+		opts.Disconnected = d.Disconnected
+		opts.GUIOnly = d.GUIOnly
+	}
+	if req.Schedule != nil && req.Schedule.DeliverBy != "" {
+		if opts.DeliverBy, err = time.Parse(time.RFC3339, req.Schedule.DeliverBy); err != nil {
+			return notify.Options{}, fmt.Errorf("invalid schedule.deliver_by %q (use an RFC 3339 time)", req.Schedule.DeliverBy)
+		}
+	}
+	return opts, nil
+}
+
+// childSpec returns the request as a spec file for the notify CLI: without debug (a flag of
+// the CLI) and with delivery.mode wall as delivery.force_wall
+func childSpec(data []byte) ([]byte, error) {
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	delete(spec, "debug")
+	if delivery, ok := spec["delivery"].(map[string]interface{}); ok && delivery["mode"] == "wall" {
+		delete(delivery, "mode")
+		delivery["force_wall"] = true
+	}
+	return json.Marshal(spec)
+}
+
+// runNotify shows spec with "notify -spec FILE -result-json" and returns its result: the last
+// line it prints, also when it exits with the index of one of the choices
+func runNotify(exePath string, spec []byte, title string, debug bool) showResult {
+	specFile, err := os.CreateTemp("", "notify-capi-*.json")
+	if err != nil {
+		return errorResult(title, fmt.Errorf("could not write the spec: %v", err))
+	}
+	defer os.Remove(specFile.Name())
+	specFile.Write(spec)
+	specFile.Close()
+
+	args := []string{"-spec", specFile.Name(), "-result-json"}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(exePath, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if debug {
+		cmd.Args = append(cmd.Args, "-debug")
+		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+	}
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return errorResult(title, fmt.Errorf("could not start notify: %v", err))
+	}
+
+	var result struct {
+		showResult
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if jsonErr := json.Unmarshal([]byte(lines[len(lines)-1]), &result); jsonErr != nil {
+		if err != nil {
+			return errorResult(title, fmt.Errorf("notify failed: %v: %s", err, lastLine(stderr.String())))
+		}
+		return errorResult(title, fmt.Errorf("could not read the notify result: %v", jsonErr))
+	}
+	if err != nil && exitErr.ExitCode() < choiceExitBase {
+		message := lastLine(stderr.String())
+		if len(result.Errors) > 0 {
+			messages := make([]string, len(result.Errors))
+			for i, e := range result.Errors {
+				messages[i] = e.Message
+			}
+			message = strings.Join(messages, "; ")
+		}
+		return errorResult(title, fmt.Errorf("notify failed: %v: %s", err, message))
+	}
+	return result.showResult
+}
+
+// errorResult returns the notify_show result when nothing could be shown
+func errorResult(title string, err error) showResult {
+	return showResult{Title: title, Timestamp: time.Now().Format(time.RFC3339), Error: err.Error()}
+}
+
+// lastLine returns the last non-empty line of s
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// encodeResult returns r as the JSON of notify_show
+func encodeResult(r showResult) string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return `{"error":"could not encode the result"}`
+	}
+	return string(data)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
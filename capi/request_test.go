@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestParseRequest tests that a notify_show spec becomes the Options the notify CLI would send
+func TestParseRequest(t *testing.T) {
+	opts, err := parseRequest([]byte(`{
+		"title": "Patch",
+		"message": "Reboot tonight",
+		"timeout": "auto",
+		"urgency": "warning",
+		"choices": ["Now", "Tonight"],
+		"attention_after": "60s",
+		"state_icons": {"success": "/opt/agent/done.png"},
+		"delivery": {"mode": "native", "gui_only": true}
+	}`))
+	if err != nil {
+		t.Fatalf("parseRequest failed: %v", err)
+	}
+	n := opts.Notification
+	if n.Title != "Patch" || n.ButtonText != "OK" || n.Width != notify.DefaultWidth || n.Timeout != notify.TimeoutAuto {
+		t.Errorf("Unexpected notification %+v", n)
+	}
+	if n.Choices != "Now,Tonight" || n.AttentionAfter.Seconds() != 60 || n.StateIcons != "success=/opt/agent/done.png" {
+		t.Errorf("Unexpected choices, attention or state icons in %+v", n)
+	}
+	if opts.Mode != notify.ModeNative || !opts.GUIOnly {
+		t.Errorf("Unexpected delivery %+v", opts)
+	}
+
+	opts, err = parseRequest([]byte(`{"message": "Disk full", "urgency": "critical"}`))
+	if err != nil || opts.Title != notify.DefaultTitle || opts.Timeout != 0 || opts.Mode != notify.ModeAuto {
+		t.Errorf("Expected the defaults and the critical timeout, got %+v, %v", opts, err)
+	}
+
+	for spec, want := range map[string]string{
+		`{"title": "x"}`:                                        "message",
+		`{"message": "x", "timeout": -1}`:                       "timeout",
+		`{"message": "x", "urgency": "urgent"}`:                 "urgency",
+		`{"message": "x", "delivery": {"mode": "fast"}}`:        "delivery.mode",
+		`{"message": "x", "schedule": {"business_hours": "x"}}`: "notify CLI",
+		`not json`: "invalid JSON",
+	} {
+		if _, err := parseRequest([]byte(spec)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseRequest(%s) = %v, want an error about %s", spec, err, want)
+		}
+	}
+}
+
+// TestChildSpec tests the spec written for the notify CLI
+func TestChildSpec(t *testing.T) {
+	spec, err := childSpec([]byte(`{"message": "Disk full", "debug": true, "delivery": {"mode": "wall", "gui_only": false}}`))
+	if err != nil || string(spec) != `{"delivery":{"force_wall":true,"gui_only":false},"message":"Disk full"}` {
+		t.Errorf("childSpec = %s, %v", spec, err)
+	}
+}
+
+// TestRunNotify tests the JSON notify_show returns for what notify printed and exited with
+func TestRunNotify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stand-in notify is a shell script")
+	}
+	exePath := filepath.Join(t.TempDir(), "notify")
+	run := func(script string) map[string]interface{} {
+		if err := os.WriteFile(exePath, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		var r map[string]interface{}
+		encoded := encodeResult(runNotify(exePath, []byte(`{"message":"x"}`), "Name", false))
+		if err := json.Unmarshal([]byte(encoded), &r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := run(`test -f "$2" && test "$3" = -result-json || exit 1
+echo '{"action":"acknowledged","method":"fyne","input":"","title":"Name","timestamp":"2025-03-01T09:00:00Z"}'`)
+	if r["action"] != "acknowledged" || r["method"] != "fyne" || r["input"] != "" || r["error"] != nil {
+		t.Errorf("Unexpected result %v", r)
+	}
+	if r := run(`echo '{"action":"acknowledged","choice":"Tonight"}'; exit 11`); r["choice"] != "Tonight" || r["error"] != nil {
+		t.Errorf("Expected the choice exit code to be a result, got %v", r)
+	}
+	if r := run(`echo '{"action":"invalid","errors":[{"flag":"icon","message":"icon not found"}]}'; exit 2`); !strings.Contains(r["error"].(string), "icon not found") || r["action"] != nil {
+		t.Errorf("Expected the argument errors, got %v", r)
+	}
+	if r := run(`echo 'no display' >&2; exit 1`); !strings.Contains(r["error"].(string), "no display") || r["title"] != "Name" {
+		t.Errorf("Expected the error of notify, got %v", r)
+	}
+	if r := encodeResult(runNotify(filepath.Join(t.TempDir(), "missing"), nil, "Name", false)); !strings.Contains(r, "could not start notify") {
+		t.Errorf("Expected a missing notify to be reported, got %s", r)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "fmt"
+
+// choiceExitBase is the exit code for the first -choices option, the second exits with
+// choiceExitBase+1 and so on, so a choice is never mistaken for success (0), an error (1) or a usage error (2)
+const choiceExitBase = 10
+
+// maxChoices keeps every choice's exit code within the 0-255 a process can return
+const maxChoices = 255 - choiceExitBase
+
+// validateChoices checks the options of -choices: at least two, distinct, and few enough for an exit code each
+func validateChoices(choices []string) error {
+	if len(choices) < 2 {
+		return fmt.Errorf("-choices needs at least two comma-separated options")
+	}
+	if len(choices) > maxChoices {
+		return fmt.Errorf("-choices has %d options, at most %d are supported", len(choices), maxChoices)
+	}
+	seen := map[string]bool{}
+	for _, choice := range choices {
+		if seen[choice] {
+			return fmt.Errorf("-choices lists %q more than once", choice)
+		}
+		seen[choice] = true
+	}
+	return nil
+}
+
+// choiceIndex returns the position of choice in choices, or -1 when nothing was chosen
+func choiceIndex(choices []string, choice string) int {
+	if choice == "" {
+		return -1
+	}
+	for i, option := range choices {
+		if option == choice {
+			return i
+		}
+	}
+	return -1
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
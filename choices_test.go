@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidateChoices tests the checks on the -choices options
+func TestValidateChoices(t *testing.T) {
+	tests := []struct {
+		choices []string
+		wantErr string
+	}{
+		{[]string{"Now", "Tonight", "Tomorrow"}, ""},
+		{[]string{"Now"}, "at least two"},
+		{nil, "at least two"},
+		{[]string{"Now", "Later", "Now"}, `"Now" more than once`},
+		{make([]string, maxChoices+1), "at most 245"},
+	}
+	for _, tt := range tests {
+		err := validateChoices(tt.choices)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("validateChoices(%v) = %v, want nil", tt.choices, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("validateChoices(%d options) = %v, want error containing %q", len(tt.choices), err, tt.wantErr)
+		}
+	}
+}
+
+// TestChoiceIndex tests that the chosen option maps to its position for the exit code
+func TestChoiceIndex(t *testing.T) {
+	choices := []string{"Now", "Tonight", "Tomorrow"}
+	tests := map[string]int{"Now": 0, "Tomorrow": 2, "": -1, "Never": -1}
+	for choice, want := range tests {
+		if got := choiceIndex(choices, choice); got != want {
+			t.Errorf("choiceIndex(%q) = %d, want %d", choice, got, want)
+		}
+	}
+}
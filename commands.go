@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// checkCommands are the "notify check NAME" checks, each also available as a legacy -check-NAME flag
+// Each prints its findings and returns the exit code (0 when the feature is available)
+var checkCommands = map[string]func() int{
+	"gui":         checkGUIAvailable,
+	"opengl":      checkOpenGLAvailable,
+	"webview":     checkWebViewAvailable,
+	"wall":        checkWallAvailable,
+	"deps":        checkDependencies,
+	"elevation":   checkElevation,
+	"permissions": checkPermissions,
+	"arch":        checkArch,
+	"dnd":         checkDoNotDisturb,
+}
+
+// runCheck handles "notify check NAME"
+func runCheck(args []string) int {
+	if len(args) != 1 || checkCommands[args[0]] == nil {
+		names := make([]string, 0, len(checkCommands))
+		for name := range checkCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "Usage: notify check NAME\nNAME is one of: %v\n", names)
+		return 2
+	}
+	return checkCommands[args[0]]()
+}
+
+// checkGUIAvailable reports whether GUI mode is available (-check-gui)
+func checkGUIAvailable() int {
+	if !notify.IsGUIAvailable() {
+		fmt.Println("GUI mode is not available")
+		return 1
+	}
+	fmt.Println("GUI mode is available")
+	// On Linux, also check for missing libraries
+	if runtime.GOOS == "linux" {
+		notify.WarnMissingDependencies()
+	}
+	return 0
+}
+
+// checkOpenGLAvailable reports whether OpenGL (and so Fyne) can be used (-check-opengl)
+func checkOpenGLAvailable() int {
+	if notify.IsOpenGLAvailable() {
+		fmt.Println("OpenGL is available")
+		fmt.Println("Fyne GUI can be used")
+		return 0
+	}
+	fmt.Println("OpenGL is not available")
+	if e := notify.DetectEmulation(); e != nil {
+		fmt.Printf("Running as %s (see notify check arch)\n", e)
+	}
+	if runtime.GOOS == "windows" {
+		fmt.Println("Will use native Windows MessageBox as fallback")
+	}
+	return 1
+}
+
+// checkWebViewAvailable reports the WebView build and runtime (-check-webview)
+func checkWebViewAvailable() int {
+	if notify.ReportWebView() {
+		return 0
+	}
+	return 1
+}
+
+// checkWallAvailable reports whether wall broadcast can be used (-check-wall)
+func checkWallAvailable() int {
+	if notify.IsWallAvailable() {
+		fmt.Println("Wall broadcast is available")
+		fmt.Println("Can send notifications to all logged-in users")
+		return 0
+	}
+	if runtime.GOOS != "linux" {
+		fmt.Println("Wall broadcast is only available on Linux")
+	} else {
+		fmt.Println("Wall command not found")
+		fmt.Println("Install with: sudo apt install bsdutils (usually pre-installed)")
+	}
+	return 1
+}
+
+// checkDependencies reports missing runtime libraries (-check-deps, Linux only)
+func checkDependencies() int {
+	if runtime.GOOS != "linux" {
+		fmt.Println("Dependency check is only available on Linux")
+		return 1
+	}
+	if notify.ReportDependencies() {
+		return 0
+	}
+	return 1
+}
+
+// checkElevation reports the elevation state (-check-elevation)
+// Exit code 0 means the process can notify other users (system or elevated)
+func checkElevation() int {
+	if notify.ReportElevation() {
+		return 0
+	}
+	return 1
+}
+
+// checkPermissions reports the macOS privacy permissions and prints the MDM profile granting them (-check-permissions)
+// Exit code 0 means every permission is granted
+func checkPermissions() int {
+	if notify.ReportPermissions() {
+		return 0
+	}
+	return 1
+}
+
+// checkArch reports whether notify runs under emulation (Rosetta 2, x64 emulation on Windows
+// on ARM) and the native build to use instead; exit code 0 means it runs natively
+func checkArch() int {
+	if notify.ReportEmulation() {
+		return 0
+	}
+	return 1
+}
+
+// checkDoNotDisturb reports whether the user has do not disturb on (Focus Assist, macOS Focus,
+// GNOME); exit code 0 means it is off
+func checkDoNotDisturb() int {
+	if notify.ReportDoNotDisturb() {
+		return 0
+	}
+	return 1
+}
+
+// printVersion prints the version, platform and license information (notify version, -version)
+func printVersion() {
+	fmt.Printf("Notify: v%s\n", appVersion)
+	if runtime.GOOS == "linux" {
+		glibcver, err := getGlibcVersion()
+		if err != nil {
+			glibcver = "(glibc version undetected)"
+		}
+		fmt.Printf("Platform: %s/%s (glibc %s)\n", runtime.GOOS, runtime.GOARCH, glibcver)
+	} else {
+		fmt.Printf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	}
+	if e := notify.DetectEmulation(); e != nil {
+		fmt.Printf("Emulation: %s\n", e)
+		if native := notify.NativeBuild(); native != "" {
+			fmt.Printf("Native build: %s (run it instead, or pass -prefer-native-arch)\n", native)
+		}
+	}
+	fmt.Printf("Copyright: %s\n", appCopyright)
+	fmt.Println("License: GNU GPL-3.0")
+	fmt.Println("Source: https://github.com/amarillier/krankybearnotify")
+	fmt.Println("Documentation: https://github.com/amarillier/krankybearnotify/blob/main/README.md")
+}
+
+// runUpdateCheck checks GitHub for a newer release (notify update, -checkupdate)
+func runUpdateCheck() int {
+	fmt.Printf("Checking for updates...\n")
+	fmt.Printf("Current version: %s\n\n", appVersion)
+
+	// Get executable directory for storing update check file
+	exePath, err := os.Executable()
+	if err != nil {
+		log.Printf("Warning: Could not determine executable path: %v", err)
+		exePath = "."
+	}
+	exeDir := filepath.Dir(exePath)
+	checkFilePath := filepath.Join(exeDir, "latestcheck.json")
+
+	// Save current directory and change to executable directory
+	originalDir, _ := os.Getwd()
+	os.Chdir(exeDir)
+	defer os.Chdir(originalDir)
+
+	updtmsg, updateAvailable := updateChecker("amarillier", "KrankyBearNotify", "Kranky Bear Notify", "https://github.com/amarillier/KrankyBearNotify/releases/latest")
+
+	if updateAvailable {
+		fmt.Println(updtmsg)
+	} else {
+		fmt.Println("You are running the latest version!")
+	}
+	fmt.Printf("Update check data saved to: %s\n", checkFilePath)
+	return 0
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,177 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+	"gopkg.in/yaml.v3"
+)
+
+// configSchema is the published JSON Schema for config files (schema/config.schema.json)
+//
+//go:embed schema/config.schema.json
+var configSchema []byte
+
+// modeFlags are the delivery mode flags; a mode chosen on the command line or in a
+// spec replaces the config's delivery.mode rather than competing with it
+var modeFlags = []string{"quick", "native", "force-webview", "win-webview", "win-basic", "force-wall"}
+
+// defaultConfigPaths returns the config files in order of precedence: the user's
+// config, then the system-wide config
+// User: ~/.config/krankybearnotify/config.yaml (the user config directory on macOS/Windows)
+// System: /etc/krankybearnotify.yaml, or %ProgramData%\KrankyBearNotify\config.yaml on Windows
+func defaultConfigPaths() []string {
+	var paths []string
+	if configDir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(configDir, "krankybearnotify", "config.yaml"))
+	}
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		paths = append(paths, filepath.Join(programData, "KrankyBearNotify", "config.yaml"))
+	} else {
+		paths = append(paths, "/etc/krankybearnotify.yaml")
+	}
+	return paths
+}
+
+// notifyConfig is a loaded config file
+type notifyConfig struct {
+	Path     string
+	Defaults NotificationSpec // Config files use the spec field names for defaults
+	Policy   *ContentPolicy   // The policy section, nil if there is none
+	Support  *SupportConfig   // The support section (notify support-bundle), nil if there is none
+	Icons    *IconCacheConfig // The icon_cache section, nil if there is none
+}
+
+// IconCacheConfig is the icon_cache section of a config file (see notify.IconCache)
+type IconCacheConfig struct {
+	Dir          string `yaml:"dir"` // Environment variables are expanded, so $HOME keeps it per user
+	MaxAgeDays   int    `yaml:"max_age_days"`
+	MaxSizeMB    int    `yaml:"max_size_mb"`
+	RefreshHours int    `yaml:"refresh_hours"`
+}
+
+// iconCache returns the icon cache the config files set, each setting from the first file that
+// has it (the user's config over the system-wide one); unset ones are the defaults
+func iconCache(configs []*notifyConfig) notify.IconCache {
+	var cache notify.IconCache
+	for _, config := range configs {
+		c := config.Icons
+		if c == nil {
+			continue
+		}
+		if cache.Dir == "" && c.Dir != "" {
+			cache.Dir = filepath.Clean(os.ExpandEnv(c.Dir))
+		}
+		if cache.MaxAge == 0 {
+			cache.MaxAge = time.Duration(c.MaxAgeDays) * 24 * time.Hour
+		}
+		if cache.MaxBytes == 0 {
+			cache.MaxBytes = int64(c.MaxSizeMB) << 20
+		}
+		if cache.Refresh == 0 {
+			cache.Refresh = time.Duration(c.RefreshHours) * time.Hour
+		}
+	}
+	return cache
+}
+
+// loadConfig reads and validates a config file, returning nil if it does not exist
+func loadConfig(path string) (*notifyConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read config: %v", err)
+	}
+
+	var schema jsonSchema
+	if err := json.Unmarshal(configSchema, &schema); err != nil {
+		return nil, fmt.Errorf("invalid embedded config schema: %v", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: invalid YAML: %v", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	errs := validateSpecNode(doc.Content[0], &schema, "")
+	errs = append(errs, checkSpecTimeZone(doc.Content[0])...)
+	if len(errs) > 0 {
+		lines := make([]string, len(errs))
+		for i, e := range errs {
+			lines[i] = path + ":" + e.String()
+		}
+		return nil, fmt.Errorf("invalid config:\n%s", strings.Join(lines, "\n"))
+	}
+
+	config := notifyConfig{Path: path}
+	var policy struct {
+		Policy  *ContentPolicy   `yaml:"policy"`
+		Support *SupportConfig   `yaml:"support"`
+		Icons   *IconCacheConfig `yaml:"icon_cache"`
+	}
+	if err := doc.Content[0].Decode(&config.Defaults); err != nil {
+		return nil, fmt.Errorf("%s: could not decode config: %v", path, err)
+	}
+	if err := doc.Content[0].Decode(&policy); err != nil {
+		return nil, fmt.Errorf("%s: could not decode policy: %v", path, err)
+	}
+	config.Policy, config.Support, config.Icons = policy.Policy, policy.Support, policy.Icons
+	return &config, nil
+}
+
+// applyConfigFiles sets every flag still unset from the config files, the first
+// path taking precedence (so flags override -spec, which overrides the config files)
+// Returns the config files found, whose policies all apply
+func applyConfigFiles(fs *flag.FlagSet, paths []string) ([]*notifyConfig, error) {
+	modeChosen := false
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range modeFlags {
+			if f.Name == name {
+				modeChosen = true
+			}
+		}
+	})
+
+	var configs []*notifyConfig
+	for _, path := range paths {
+		config, err := loadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			continue
+		}
+		configs = append(configs, config)
+		values := config.Defaults.flagValues()
+		if modeChosen {
+			for _, name := range modeFlags {
+				delete(values, name)
+			}
+		}
+		if err := setUnsetFlags(fs, values, path); err != nil {
+			return nil, err
+		}
+		if config.Defaults.Delivery.Mode != "" || config.Defaults.Delivery.ForceWall != nil {
+			modeChosen = true
+		}
+	}
+	return configs, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
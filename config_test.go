@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestApplyConfigFiles tests that the command line beats the user config, which beats the system config
+func TestApplyConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	userConfig := filepath.Join(dir, "user.yaml")
+	systemConfig := filepath.Join(dir, "system.yaml")
+	os.WriteFile(userConfig, []byte("button: Got it\ntimeout: 30\nicon: /usr/share/pixmaps/logo.png\n"), 0644)
+	os.WriteFile(systemConfig, []byte("button: OK then\ntimeout: 60\nwidth: 500\ndelivery:\n  mode: quick\npolicy:\n  max_message_length: 500\n  allowed_url_domains:\n    - example.com\n"), 0644)
+
+	var n notify.Notification
+	fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+	notify.BindFlags(fs, &n)
+	quick := fs.Bool("quick", false, "")
+	fs.Bool("force-webview", false, "")
+	fs.Bool("win-basic", false, "")
+	if err := fs.Parse([]string{"-timeout", "5", "-force-webview", "-builtin-icon", "warning"}); err != nil {
+		t.Fatal(err)
+	}
+
+	configs, err := applyConfigFiles(fs, []string{userConfig, systemConfig, filepath.Join(dir, "missing.yaml")})
+	if err != nil {
+		t.Fatalf("applyConfigFiles failed: %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 config files, got %d", len(configs))
+	}
+	if configs[0].Policy != nil || configs[1].Policy == nil || configs[1].Policy.MaxMessageLength != 500 {
+		t.Errorf("Expected only the system config to have a policy, got %+v and %+v", configs[0].Policy, configs[1].Policy)
+	}
+	if n.Timeout != 5 {
+		t.Errorf("Expected the command-line timeout 5, got %d", n.Timeout)
+	}
+	if n.ButtonText != "Got it" {
+		t.Errorf("Expected the user config button, got %q", n.ButtonText)
+	}
+	if n.Width != 500 {
+		t.Errorf("Expected the system config width 500, got %d", n.Width)
+	}
+	if n.IconPath != "" || n.BuiltinIcon != "warning" {
+		t.Errorf("Expected -builtin-icon on the command line to win over the config's icon, got %q and %q", n.IconPath, n.BuiltinIcon)
+	}
+	if *quick {
+		t.Error("Expected -force-webview on the command line to win over the config's quick mode")
+	}
+}
+
+// TestIconCacheConfig tests that each icon_cache setting comes from the first config that has it
+func TestIconCacheConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ICON_CACHE_HOME", dir)
+	userConfig := filepath.Join(dir, "user.yaml")
+	systemConfig := filepath.Join(dir, "system.yaml")
+	os.WriteFile(userConfig, []byte("icon_cache:\n  dir: $ICON_CACHE_HOME/icons\n  max_size_mb: 20\n"), 0644)
+	os.WriteFile(systemConfig, []byte("icon_cache:\n  dir: /var/cache/icons\n  max_size_mb: 500\n  max_age_days: 7\n"), 0644)
+
+	var configs []*notifyConfig
+	for _, path := range []string{userConfig, systemConfig} {
+		config, err := loadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, config)
+	}
+	want := notify.IconCache{Dir: filepath.Join(dir, "icons"), MaxAge: 7 * 24 * time.Hour, MaxBytes: 20 << 20}
+	if got := iconCache(configs); got != want {
+		t.Errorf("iconCache = %+v, want %+v", got, want)
+	}
+	if got := iconCache(nil); got != (notify.IconCache{}) {
+		t.Errorf("iconCache without configs = %+v, want the defaults", got)
+	}
+
+	os.WriteFile(userConfig, []byte("icon_cache:\n  max_size_mb: 0\n"), 0644)
+	if _, err := loadConfig(userConfig); err == nil {
+		t.Error("Expected max_size_mb 0 to be rejected")
+	}
+}
+
+// TestLoadConfigRejectsUnknownKeys tests that typos and spec-only fields are reported
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+	for _, content := range []string{"titel: Hello\n", "rollout:\n  percent: 10\n", "timeout: -1\n", "policy:\n  max_title_lenght: 10\n"} {
+		path := filepath.Join(dir, "config.yaml")
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := loadConfig(path); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// demoCase is one notification of the notify demo gallery
+type demoCase struct {
+	name    string
+	title   string
+	message string
+	args    []string
+}
+
+// demoCases are the representative notifications notify demo shows, in order: every urgency
+// and the kinds of window fleet notifications use, so theming, scaling, fonts and sound can be
+// checked on a reference image before a rollout
+var demoCases = []demoCase{
+	{name: "plain", title: "Kranky Bear Notify", message: "A plain notification with the default look. Check the title, the message font and the window size."},
+	{name: "info", title: "Software update available", message: "Version 2.4 of the office suite is ready to install.", args: []string{"-urgency", "info"}},
+	{name: "warning", title: "Disk almost full", message: "Drive C: has 2 GB free. Remove files you no longer need.", args: []string{"-urgency", "warning"}},
+	{name: "critical", title: "Security incident", message: "Disconnect from the VPN now and call the service desk.", args: []string{"-urgency", "critical"}},
+	{name: "choices", title: "Restart required", message: "Updates were installed. When should this computer restart?", args: []string{"-choices", "Now,Tonight,Tomorrow", "-button", "Schedule"}},
+	{name: "progress", title: "Backup running", message: "The nightly backup is copying your files.", args: []string{"-state", "working"}},
+	{name: "prompt", title: "Asset check", message: "Enter the asset tag on the sticker under your laptop.", args: []string{"-input", "-input-placeholder", "e.g. IT-04711", "-button", "Submit"}},
+	{name: "link", title: "Policy update", message: "The acceptable use policy changed. Please read it by Friday.", args: []string{"-link", "https://github.com/amarillier/krankybearnotify"}},
+	{name: "sound", title: "Meeting in 5 minutes", message: "This one plays the system notification sound.", args: []string{"-sound", "system"}},
+	{name: "toast", title: "Deploy finished", message: "A toast in the notification center instead of a window.", args: []string{"-native"}},
+	{name: "fullscreen", title: "Evacuation drill", message: "A full-screen alert. Acknowledge it to continue the demo.", args: []string{"-fullscreen", "-urgency", "critical"}},
+}
+
+// demoToastPause is how long notify demo waits after a toast, which does not block, before the next
+const demoToastPause = 5 * time.Second
+
+// runDemo handles "notify demo [NAME...] [-- FLAGS]": shows the demo notifications one after the
+// other on this machine, each in its own notify process, with FLAGS (e.g. -theme dark) added to
+// every one; returns 1 when a notification could not be shown
+func runDemo(args []string) int {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	list := fs.Bool("list", false, "List the demo notifications and exit")
+	timeout := fs.Int("timeout", 15, "Timeout of each notification in seconds (0 to wait for each to be acknowledged)")
+	pause := fs.Duration("pause", time.Second, "Pause between notifications")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: notify demo [-list] [-timeout SECONDS] [-pause DURATION] [NAME...] [-- FLAGS]")
+		fmt.Fprintln(os.Stderr, "Shows representative notifications one after the other, with FLAGS added to each, e.g. -- -theme dark -font-size 18")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	names, extra := fs.Args(), []string(nil)
+	for i, arg := range names {
+		if arg == "--" {
+			names, extra = names[:i], names[i+1:]
+			break
+		}
+	}
+	cases, err := selectDemoCases(names)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *list {
+		for _, c := range demoCases {
+			fmt.Printf("%-11s %s\n", c.name, c.title)
+		}
+		return 0
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not find notify executable: %v\n", err)
+		return 1
+	}
+	failed := 0
+	for i, c := range cases {
+		if i > 0 {
+			time.Sleep(*pause)
+		}
+		fmt.Printf("[%d/%d] %s: %s\n", i+1, len(cases), c.name, c.title)
+		cmd := exec.Command(exePath, c.cmdArgs(*timeout, extra)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			// -choices exits with the index of the chosen option, which is not a failure
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() < choiceExitBase {
+				fmt.Printf("[%d/%d] %s failed: %v\n", i+1, len(cases), c.name, err)
+				failed++
+			}
+		}
+		if c.name == "toast" {
+			time.Sleep(demoToastPause)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d demo notifications failed\n", failed, len(cases))
+		return 1
+	}
+	return 0
+}
+
+// selectDemoCases returns the demo cases named, in the order given, or all of them
+func selectDemoCases(names []string) ([]demoCase, error) {
+	if len(names) == 0 {
+		return demoCases, nil
+	}
+	var cases []demoCase
+	for _, name := range names {
+		found := false
+		for _, c := range demoCases {
+			if c.name == strings.ToLower(name) {
+				cases, found = append(cases, c), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown demo notification %q (see notify demo -list)", name)
+		}
+	}
+	return cases, nil
+}
+
+// cmdArgs returns the notify arguments of a demo case; extra flags come last, so they override
+// the case's own
+func (c demoCase) cmdArgs(timeout int, extra []string) []string {
+	args := []string{"-title", c.title, "-message", c.message, "-timeout", strconv.Itoa(timeout)}
+	args = append(args, c.args...)
+	return append(args, extra...)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
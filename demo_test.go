@@ -0,0 +1,31 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestSelectDemoCases tests choosing demo notifications by name
+func TestSelectDemoCases(t *testing.T) {
+	all, err := selectDemoCases(nil)
+	if err != nil || len(all) != len(demoCases) {
+		t.Fatalf("selectDemoCases(nil) = %d cases, %v; want all %d", len(all), err, len(demoCases))
+	}
+	cases, err := selectDemoCases([]string{"critical", "Toast"})
+	if err != nil || len(cases) != 2 || cases[0].name != "critical" || cases[1].name != "toast" {
+		t.Errorf("selectDemoCases(critical, Toast) = %v, %v", cases, err)
+	}
+	if _, err := selectDemoCases([]string{"sideways"}); err == nil {
+		t.Error("selectDemoCases accepted an unknown name")
+	}
+}
+
+// TestDemoCaseArgs tests that the flags given after -- override the demo case's own
+func TestDemoCaseArgs(t *testing.T) {
+	c := demoCase{name: "info", title: "T", message: "M", args: []string{"-urgency", "info"}}
+	got := c.cmdArgs(15, []string{"-urgency", "warning"})
+	want := []string{"-title", "T", "-message", "M", "-timeout", "15", "-urgency", "info", "-urgency", "warning"}
+	if !slices.Equal(got, want) {
+		t.Errorf("cmdArgs = %q, want %q", got, want)
+	}
+}
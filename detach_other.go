@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package main
+
+import "os/exec"
+
+// detachProcess is a no-op on platforms without sessions or process groups
+func detachProcess(cmd *exec.Cmd) {
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
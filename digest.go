@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxDigestItemLength caps a digest item, "Title: first line of the message", in runes
+const maxDigestItemLength = 120
+
+// launchFunc starts the notify process of a spec file and calls done with its result
+type launchFunc func(specPath string, done func(*NotificationResult, error)) error
+
+// digestLauncher coalesces bursts for -digest-window: a notification is shown at once unless
+// one was shown less than a window ago, then it is held until the window ends, and the held
+// ones are shown together in one digest (a single one is shown as is)
+// Each category has its own digest, so opt-outs still apply; break-glass and critical
+// notifications, and those returning an answer or controlling a window, are never held
+type digestLauncher struct {
+	window time.Duration
+	launch launchFunc
+
+	mu    sync.Mutex
+	held  map[string][]digestEntry // By category
+	timer *time.Timer              // Running while a window is open
+}
+
+// digestEntry is a notification held for the next digest
+type digestEntry struct {
+	specPath string
+	spec     *NotificationSpec
+	done     func(*NotificationResult, error)
+}
+
+// newDigestLauncher returns launch coalescing bursts within window, or launch itself when
+// window is 0
+func newDigestLauncher(window time.Duration, launch launchFunc) launchFunc {
+	if window <= 0 {
+		return launch
+	}
+	d := &digestLauncher{window: window, launch: launch, held: map[string][]digestEntry{}}
+	return d.show
+}
+
+// show launches specPath, or holds it for the digest when a window is open
+func (d *digestLauncher) show(specPath string, done func(*NotificationResult, error)) error {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return d.launch(specPath, done)
+	}
+	spec, _, err := parseSpec(data, "")
+	if err != nil || !digestible(spec) {
+		return d.launch(specPath, done)
+	}
+
+	d.mu.Lock()
+	if d.timer == nil {
+		d.timer = time.AfterFunc(d.window, d.flush)
+		d.mu.Unlock()
+		return d.launch(specPath, done)
+	}
+	d.held[spec.Category] = append(d.held[spec.Category], digestEntry{specPath: specPath, spec: spec, done: done})
+	d.mu.Unlock()
+	return nil
+}
+
+// flush ends a window: the held notifications are shown, and a new window starts, or the
+// window closes when nothing was held
+func (d *digestLauncher) flush() {
+	d.mu.Lock()
+	held := d.held
+	d.held = map[string][]digestEntry{}
+	if len(held) == 0 {
+		d.timer = nil
+	} else {
+		d.timer = time.AfterFunc(d.window, d.flush)
+	}
+	d.mu.Unlock()
+
+	categories := make([]string, 0, len(held))
+	for category := range held {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		entries := held[category]
+		if len(entries) == 1 {
+			if err := d.launch(entries[0].specPath, entries[0].done); err != nil {
+				entries[0].done(nil, err)
+			}
+			continue
+		}
+		d.showDigest(category, entries)
+	}
+}
+
+// showDigest shows one notification listing entries, and gives each its result
+func (d *digestLauncher) showDigest(category string, entries []digestEntry) {
+	fail := func(err error) {
+		log.Printf("Could not show a digest of %d notifications: %v", len(entries), err)
+		for _, entry := range entries {
+			entry.done(nil, err)
+		}
+	}
+	items := make([]string, len(entries))
+	urgency := ""
+	for i, entry := range entries {
+		os.Remove(entry.specPath)
+		items[i] = digestItem(entry.spec)
+		if entry.spec.Urgency == "warning" {
+			urgency = "warning"
+		}
+	}
+	spec := map[string]interface{}{
+		"version":      1,
+		"title":        fmt.Sprintf("%d new notifications", len(entries)),
+		"message":      fmt.Sprintf("%d notifications arrived within %v.", len(entries), d.window),
+		"digest_items": items,
+	}
+	if category != "" {
+		spec["category"] = category
+	}
+	if urgency != "" {
+		spec["urgency"] = urgency
+	}
+	data, _ := json.Marshal(spec)
+	specFile, err := os.CreateTemp("", "notify-digest-*.yaml")
+	if err != nil {
+		fail(err)
+		return
+	}
+	specFile.Write(data)
+	specFile.Close()
+	err = d.launch(specFile.Name(), func(result *NotificationResult, err error) {
+		for _, entry := range entries {
+			if result == nil {
+				entry.done(nil, err)
+				continue
+			}
+			own := *result
+			own.Title = entry.spec.Title
+			own.Digest = len(entries)
+			entry.done(&own, err)
+		}
+	})
+	if err != nil {
+		fail(err)
+	}
+}
+
+// digestible reports whether a notification may wait for a digest: not break-glass or
+// critical, not asking for an answer, not closing or updating a window by ID, not scheduled,
+// and without template variables, which the digest would list unexpanded
+func digestible(spec *NotificationSpec) bool {
+	if spec.Priority == "breakglass" || spec.Urgency == "critical" || len(spec.Choices) > 0 || len(spec.Digest) > 0 {
+		return false
+	}
+	if spec.Input != nil && *spec.Input {
+		return false
+	}
+	if spec.ID != "" || spec.Cancel != "" || spec.State != "" || spec.StateID != "" {
+		return false
+	}
+	schedule := spec.Schedule
+	if schedule.BusinessHours != "" || schedule.DeliverBy != "" || schedule.At != "" || schedule.Delay != "" || schedule.Every != "" {
+		return false
+	}
+	return !strings.Contains(spec.Title+spec.Message, "{{")
+}
+
+// digestItem is how a notification is listed in a digest: its title and the first line of
+// its message, or the title only for a sensitive one
+func digestItem(spec *NotificationSpec) string {
+	item := spec.Title
+	message, _, _ := strings.Cut(strings.TrimSpace(spec.Message), "\n")
+	if message != "" && (spec.Sensitive == nil || !*spec.Sensitive) {
+		if item != "" {
+			item += ": "
+		}
+		item += message
+	}
+	if item == "" {
+		item = "(no title)"
+	}
+	if utf8.RuneCountInString(item) > maxDigestItemLength {
+		item = string([]rune(item)[:maxDigestItemLength-1]) + "…"
+	}
+	return item
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDigestLauncher tests that a burst is shown as the first notification and one digest per
+// category, that critical notifications are not held, and that a quiet window closes
+func TestDigestLauncher(t *testing.T) {
+	var mu sync.Mutex
+	var shown []*NotificationSpec
+	launch := newDigestLauncher(100*time.Millisecond, func(specPath string, done func(*NotificationResult, error)) error {
+		data, _ := os.ReadFile(specPath)
+		os.Remove(specPath)
+		spec, _, err := parseSpec(data, "")
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		shown = append(shown, spec)
+		mu.Unlock()
+		done(&NotificationResult{Action: actionAcknowledged, Method: "fyne", Title: spec.Title}, nil)
+		return nil
+	})
+	results := make(chan *NotificationResult, 10)
+	send := func(spec string) {
+		file, _ := os.CreateTemp(t.TempDir(), "spec-*.yaml")
+		file.WriteString("version: 1\n" + spec)
+		file.Close()
+		if err := launch(file.Name(), func(result *NotificationResult, err error) {
+			if err != nil {
+				t.Errorf("Launch failed: %v", err)
+			}
+			results <- result
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	titles := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		var titles []string
+		for _, spec := range shown {
+			titles = append(titles, spec.Title)
+		}
+		return titles
+	}
+
+	send("title: Backup failed\nmessage: db1\n")
+	send("title: Disk full\nmessage: db2\n")
+	send("title: CPU hot\nmessage: db3\nsensitive: true\n")
+	send("title: Printer jammed\nmessage: 2nd floor\ncategory: office\n")
+	send("title: Breach\nmessage: Disconnect now\nurgency: critical\n")
+	if got := strings.Join(titles(), ", "); got != "Backup failed, Breach" {
+		t.Errorf("Shown at once: %s, want the first and the critical one", got)
+	}
+
+	got := map[string]*NotificationResult{}
+	for len(got) < 5 {
+		select {
+		case result := <-results:
+			got[result.Title] = result
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for the results, have %v", got)
+		}
+	}
+	if got["Disk full"].Digest != 2 || got["CPU hot"].Digest != 2 || got["Printer jammed"].Digest != 0 || got["Backup failed"].Digest != 0 {
+		t.Errorf("Expected Disk full and CPU hot in a digest of 2, got %+v", got)
+	}
+	if got := strings.Join(titles(), ", "); got != "Backup failed, Breach, 2 new notifications, Printer jammed" {
+		t.Errorf("Shown: %s", got)
+	}
+	mu.Lock()
+	digest := shown[2]
+	mu.Unlock()
+	if strings.Join(digest.Digest, "|") != "Disk full: db2|CPU hot" {
+		t.Errorf("Digest items %q, want the sensitive message left out", digest.Digest)
+	}
+
+	// The window after the digest passes without notifications, so the next is shown at once
+	time.Sleep(250 * time.Millisecond)
+	send("title: All clear\nmessage: Done\n")
+	if result := <-results; result.Title != "All clear" || result.Digest != 0 {
+		t.Errorf("Expected All clear shown at once, got %+v", result)
+	}
+}
+
+// TestDigestItem tests how notifications are listed in a digest
+func TestDigestItem(t *testing.T) {
+	if got := digestItem(&NotificationSpec{Title: "Backup", Message: "Failed on db1\nSee the log"}); got != "Backup: Failed on db1" {
+		t.Errorf("digestItem = %q, want the first line of the message", got)
+	}
+	if got := digestItem(&NotificationSpec{Message: "Only text"}); got != "Only text" {
+		t.Errorf("digestItem without a title = %q", got)
+	}
+	if got := digestItem(&NotificationSpec{Title: strings.Repeat("x", 200)}); len([]rune(got)) != maxDigestItemLength || !strings.HasSuffix(got, "…") {
+		t.Errorf("digestItem of a long title = %q, want it cut to %d runes", got, maxDigestItemLength)
+	}
+	if digestible(&NotificationSpec{Title: "Reboot at {{localtime:22:00}}"}) {
+		t.Error("Expected a notification with template variables not to be held for a digest")
+	}
+}
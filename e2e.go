@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// e2eTimeout is the -timeout given to each delivery so it closes itself
+const e2eTimeout = 4
+
+// e2eSettleDelay is how long to wait after launching a delivery before taking the screenshot
+const e2eSettleDelay = 2 * time.Second
+
+// e2eMinCoverage is the minimum fraction of screenshot pixels that must differ from
+// the empty virtual display for a notification window to count as rendered
+const e2eMinCoverage = 0.005
+
+// e2eCase is one real delivery exercised by notify test-e2e
+type e2eCase struct {
+	name       string
+	args       []string
+	wantAction string // Action expected in the -result-json output
+	screenshot bool   // Assert on a screenshot of the virtual display
+	skip       string // Reason the case cannot run on this machine
+}
+
+// virtualDisplay is a headless display server started for the Linux test run
+type virtualDisplay struct {
+	name  string   // "Xvfb" or "weston"
+	env   []string // Environment for clients (DISPLAY or WAYLAND_DISPLAY)
+	shots bool     // Whether screenshots can be taken with ImageMagick import
+	cmd   *exec.Cmd
+}
+
+// runE2ETests runs notify test-e2e and returns the process exit code
+// On Linux it starts Xvfb (or weston headless), runs real Fyne and WebView deliveries,
+// screenshots the display and checks pixels (and text, if tesseract is installed)
+// On Windows and macOS it drives the native paths in the current desktop session
+func runE2ETests(args []string) int {
+	fs := flag.NewFlagSet("test-e2e", flag.ExitOnError)
+	display := fs.String("display", ":99", "Linux: X display number for Xvfb")
+	outDir := fs.String("out", "", "Directory for screenshots (default: a temporary directory)")
+	fs.Parse(args)
+
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not find notify executable: %v\n", err)
+		return 1
+	}
+
+	if *outDir == "" {
+		*outDir, err = os.MkdirTemp("", "notify-e2e-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	env := os.Environ()
+	var vd *virtualDisplay
+	if runtime.GOOS == "linux" {
+		vd, err = startVirtualDisplay(*display)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer vd.stop()
+		env = append(env, vd.env...)
+		fmt.Printf("Virtual display: %s\n", vd.name)
+	}
+
+	fmt.Printf("Screenshots: %s\n", *outDir)
+	failed := 0
+	for _, tc := range e2eCases(vd) {
+		if tc.skip != "" {
+			fmt.Printf("SKIP %-10s %s\n", tc.name, tc.skip)
+			continue
+		}
+		start := time.Now()
+		if err := runE2ECase(exePath, env, vd, *outDir, tc); err != nil {
+			fmt.Printf("FAIL %-10s %v\n", tc.name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("PASS %-10s (%.1fs)\n", tc.name, time.Since(start).Seconds())
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d test(s) failed\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// e2eCases returns the deliveries to exercise on this platform
+func e2eCases(vd *virtualDisplay) []e2eCase {
+	webviewSkip := ""
+	if !notify.WebViewCompiledIn {
+		webviewSkip = "webview support not compiled in (build with -tags webview on macOS and Linux)"
+	} else if ok, detail := notify.DetectWebViewRuntime(); !ok {
+		webviewSkip = detail
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		return []e2eCase{
+			{name: "fyne", wantAction: actionTimeout, screenshot: vd.shots},
+			{name: "webview", args: []string{"-force-webview"}, wantAction: actionTimeout, screenshot: vd.shots, skip: webviewSkip},
+		}
+	case "windows":
+		// MessageBox cannot close itself, so the native path is covered by -quick (WTSSendMessage with a timeout)
+		return []e2eCase{
+			{name: "quick", args: []string{"-quick"}, wantAction: actionDelivered},
+			{name: "webview", args: []string{"-win-webview"}, wantAction: actionTimeout, skip: webviewSkip},
+		}
+	case "darwin":
+		return []e2eCase{
+			{name: "quick", args: []string{"-quick"}, wantAction: actionDelivered},
+			{name: "fyne", wantAction: actionTimeout},
+		}
+	}
+	return []e2eCase{{name: "all", skip: "no end-to-end tests for " + runtime.GOOS}}
+}
+
+// runE2ECase launches one delivery, screenshots it while it is showing,
+// and checks the exit code and the acknowledgment result
+func runE2ECase(exePath string, env []string, vd *virtualDisplay, outDir string, tc e2eCase) error {
+	title := "KrankyBear E2E " + tc.name
+	args := append([]string{
+		"-title", title,
+		"-message", "End-to-end test delivery",
+		"-timeout", fmt.Sprintf("%d", e2eTimeout),
+		"-result-json",
+	}, tc.args...)
+
+	cmd := exec.Command(exePath, args...)
+	cmd.Env = env
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("could not start notify: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var shotErr error
+	if tc.screenshot {
+		time.Sleep(e2eSettleDelay)
+		shotPath := filepath.Join(outDir, tc.name+".png")
+		shotErr = assertScreenshot(vd, shotPath, title)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("notify exited with %v (stderr: %s)", err, strings.TrimSpace(stderr.String()))
+		}
+	case <-time.After(time.Duration(e2eTimeout+20) * time.Second):
+		cmd.Process.Kill()
+		return fmt.Errorf("notify did not close itself within %ds", e2eTimeout+20)
+	}
+	if shotErr != nil {
+		return shotErr
+	}
+
+	// The last line on stdout is the -result-json payload
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	var result NotificationResult
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &result); err != nil {
+		return fmt.Errorf("no JSON result on stdout: %q", stdout.String())
+	}
+	if result.Action != tc.wantAction {
+		return fmt.Errorf("expected action %q, got %q (method %s)", tc.wantAction, result.Action, result.Method)
+	}
+	return nil
+}
+
+// startVirtualDisplay starts Xvfb on display, or weston's headless backend if Xvfb
+// is not installed (weston runs deliveries but cannot be screenshotted)
+func startVirtualDisplay(display string) (*virtualDisplay, error) {
+	_, importErr := exec.LookPath("import")
+
+	if _, err := exec.LookPath("Xvfb"); err == nil {
+		cmd := exec.Command("Xvfb", display, "-screen", "0", "1280x1024x24", "+extension", "GLX", "-nolisten", "tcp")
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("could not start Xvfb: %v", err)
+		}
+		// Xvfb creates its socket once it is ready for clients
+		socket := "/tmp/.X11-unix/X" + strings.TrimPrefix(display, ":")
+		for i := 0; i < 50; i++ {
+			if _, err := os.Stat(socket); err == nil {
+				return &virtualDisplay{name: "Xvfb", env: []string{"DISPLAY=" + display}, shots: importErr == nil, cmd: cmd}, nil
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("Xvfb did not start on %s", display)
+	}
+
+	if _, err := exec.LookPath("weston"); err == nil {
+		socket := "notify-e2e"
+		cmd := exec.Command("weston", "--backend=headless-backend.so", "--socket="+socket, "--idle-time=0")
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("could not start weston: %v", err)
+		}
+		time.Sleep(time.Second)
+		return &virtualDisplay{name: "weston (headless, no screenshots)", env: []string{"WAYLAND_DISPLAY=" + socket}, cmd: cmd}, nil
+	}
+
+	return nil, fmt.Errorf("neither Xvfb nor weston found (install with: sudo apt install xvfb imagemagick)")
+}
+
+// stop shuts down the virtual display server
+func (vd *virtualDisplay) stop() {
+	if vd.cmd != nil && vd.cmd.Process != nil {
+		vd.cmd.Process.Kill()
+		vd.cmd.Wait()
+	}
+}
+
+// assertScreenshot captures the virtual display and checks that a window was drawn
+// If tesseract is installed, it also checks that the title text is readable
+func assertScreenshot(vd *virtualDisplay, shotPath, title string) error {
+	display := strings.TrimPrefix(vd.env[0], "DISPLAY=")
+	if output, err := exec.Command("import", "-display", display, "-window", "root", shotPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("screenshot failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+
+	f, err := os.Open(shotPath)
+	if err != nil {
+		return err
+	}
+	img, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("could not read screenshot %s: %v", shotPath, err)
+	}
+	if coverage := screenCoverage(img); coverage < e2eMinCoverage {
+		return fmt.Errorf("nothing rendered: only %.2f%% of %s differs from the empty display", coverage*100, shotPath)
+	}
+
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return nil
+	}
+	output, err := exec.Command("tesseract", shotPath, "stdout").Output()
+	if err != nil {
+		return fmt.Errorf("tesseract failed: %v", err)
+	}
+	if !ocrContains(string(output), title) {
+		return fmt.Errorf("title %q not found in screenshot text: %q", title, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// screenCoverage returns the fraction of pixels that differ from the top-left
+// pixel, which is the background of an otherwise empty virtual display
+func screenCoverage(img image.Image) float64 {
+	bounds := img.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return 0
+	}
+	bgR, bgG, bgB, _ := img.At(bounds.Min.X, bounds.Min.Y).RGBA()
+	changed := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if r != bgR || g != bgG || b != bgB {
+				changed++
+			}
+		}
+	}
+	return float64(changed) / float64(total)
+}
+
+// ocrContains reports whether OCR output contains the first word of want,
+// ignoring case and whitespace (OCR often splits or merges words)
+func ocrContains(ocr, want string) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), ""))
+	}
+	words := strings.Fields(want)
+	if len(words) == 0 {
+		return true
+	}
+	return strings.Contains(normalize(ocr), normalize(words[0]))
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
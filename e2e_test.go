@@ -0,0 +1,35 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestScreenCoverage tests the pixel assertion used by notify test-e2e
+func TestScreenCoverage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	if coverage := screenCoverage(img); coverage != 0 {
+		t.Errorf("Expected empty display to have 0 coverage, got %v", coverage)
+	}
+
+	// Draw a 20x10 "window"
+	for y := 40; y < 50; y++ {
+		for x := 40; x < 60; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	if coverage := screenCoverage(img); coverage != 0.02 {
+		t.Errorf("Expected 2%% coverage, got %v", coverage)
+	}
+}
+
+// TestOCRContains tests the tolerant OCR text match
+func TestOCRContains(t *testing.T) {
+	if !ocrContains("Kranky Bear E2E fyne\nEnd-to-end test", "KrankyBear E2E fyne") {
+		t.Error("Expected split words to match")
+	}
+	if ocrContains("Something else entirely", "KrankyBear E2E fyne") {
+		t.Error("Expected unrelated text not to match")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// How -title, -message, -html, -button and -icon values are decoded
+const (
+	decodeNone    = iota // Used as given (default), so % and + are shown literally
+	decodeEncoded        // -encoded: percent-encoding is decoded, an invalid escape is an error
+	decodeLegacy         // -legacy-decode: URL query decoding ('+' becomes a space), undecodable values are used as given
+)
+
+// decodeArgument decodes one command-line value according to mode
+func decodeArgument(value string, mode int) (string, error) {
+	switch mode {
+	case decodeEncoded:
+		return url.PathUnescape(value)
+	case decodeLegacy:
+		if decoded, err := url.QueryUnescape(value); err == nil {
+			return decoded, nil
+		}
+	}
+	return value, nil
+}
+
+// encodeArgument encodes value so decodeArgument with mode returns it, for command lines of
+// other notify processes
+func encodeArgument(value string, mode int) string {
+	switch mode {
+	case decodeEncoded:
+		return url.PathEscape(value)
+	case decodeLegacy:
+		return url.QueryEscape(value)
+	}
+	return value
+}
+
+// decodeNotification decodes the text and icon path of n according to mode
+func decodeNotification(n *notify.Notification, mode int) error {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"title", &n.Title},
+		{"message", &n.Message},
+		{"html", &n.HTML},
+		{"button", &n.ButtonText},
+		{"icon", &n.IconPath},
+	}
+	for _, field := range fields {
+		decoded, err := decodeArgument(*field.value, mode)
+		if err != nil {
+			return fmt.Errorf("-%s is not valid percent-encoding: %v", field.name, err)
+		}
+		*field.value = decoded
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+// TestDecodeArgument tests the three decoding modes on strings the old blanket decoding corrupted
+func TestDecodeArgument(t *testing.T) {
+	tests := []struct {
+		in     string
+		mode   int
+		want   string
+		hasErr bool
+	}{
+		{"50% off + free shipping", decodeNone, "50% off + free shipping", false},
+		{"Build%20Complete", decodeNone, "Build%20Complete", false},
+		{"Build%20Complete+now", decodeEncoded, "Build Complete+now", false},
+		{"50% off", decodeEncoded, "", true},
+		{"Build%20Complete+now", decodeLegacy, "Build Complete now", false},
+		{"50% off", decodeLegacy, "50% off", false},
+	}
+	for _, tt := range tests {
+		got, err := decodeArgument(tt.in, tt.mode)
+		if (err != nil) != tt.hasErr {
+			t.Errorf("decodeArgument(%q, %d) error = %v, want error %v", tt.in, tt.mode, err, tt.hasErr)
+			continue
+		}
+		if !tt.hasErr && got != tt.want {
+			t.Errorf("decodeArgument(%q, %d) = %q, want %q", tt.in, tt.mode, got, tt.want)
+		}
+	}
+}
+
+// FuzzDecodeArgument tests that plain values are never changed, that -encoded round-trips
+// anything that was percent-encoded, and that legacy decoding never fails
+func FuzzDecodeArgument(f *testing.F) {
+	for _, seed := range []string{"", "Hello World", "50% off", "a+b", "%zz", "%E2%9C%93", "100%"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if got, err := decodeArgument(s, decodeNone); err != nil || got != s {
+			t.Fatalf("decodeNone changed %q to %q (%v)", s, got, err)
+		}
+		if got, err := decodeArgument(url.PathEscape(s), decodeEncoded); err != nil || got != s {
+			t.Fatalf("-encoded did not round-trip %q: got %q (%v)", s, got, err)
+		}
+		if _, err := decodeArgument(s, decodeLegacy); err != nil {
+			t.Fatalf("legacy decoding failed for %q: %v", s, err)
+		}
+	})
+}
@@ -0,0 +1,24 @@
+# Shows a notification with libkrankybearnotify, the C shared library that runs notify, and
+# prints the result (build it with "make build-lib"; notify must be on the PATH)
+import ctypes
+import json
+import sys
+
+lib = ctypes.CDLL(sys.argv[1] if len(sys.argv) > 1 else "bin/libkrankybearnotify.so")
+lib.notify_show.argtypes = [ctypes.c_char_p]
+lib.notify_show.restype = ctypes.c_void_p  # Freed with notify_free, so not c_char_p
+lib.notify_free.argtypes = [ctypes.c_void_p]
+
+spec = {
+    "title": "Patch Tuesday",
+    "message": "Updates are ready. When should they be installed?",
+    "choices": ["Now", "Tonight"],
+    "timeout": 0,
+}
+ptr = lib.notify_show(json.dumps(spec).encode())
+result = json.loads(ctypes.string_at(ptr).decode())
+lib.notify_free(ptr)
+
+if "error" in result:
+    sys.exit("notify: " + result["error"])
+print(result["action"], result.get("choice", ""))
@@ -0,0 +1,58 @@
+// sdk-client shows a notification through notify serve with the Go client and prints every
+// result the server reports while it runs
+//
+//	notify serve -listen 127.0.0.1:8787 -token-file serve.token
+//	NOTIFY_TOKEN=$(cat serve.token) go run ./examples/sdk-client
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/client"
+)
+
+func main() {
+	c := client.New("http://127.0.0.1:8787", os.Getenv("NOTIFY_TOKEN"))
+	ctx := context.Background()
+
+	// Follow every notification that finishes, also those posted by other tools
+	watcher, err := c.Watch(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer watcher.Close()
+	go func() {
+		for {
+			record, err := watcher.Next()
+			if err != nil {
+				return
+			}
+			if record.Status == client.StatusFailed {
+				fmt.Printf("[watch] %s failed: %s\n", record.ID, record.Error)
+				continue
+			}
+			fmt.Printf("[watch] %s: %s via %s\n", record.ID, record.Result.Action, record.Result.Method)
+		}
+	}()
+
+	result, err := c.Send(ctx, client.Notification{
+		Title:   "Maintenance Tonight",
+		Message: "The build servers restart at 22:00. When suits you?",
+		Choices: []string{"Now", "Tonight", "Tomorrow"},
+		Timeout: client.Int(0),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if result.Action == client.ActionAcknowledged {
+		fmt.Printf("%s chose %q\n", result.ID, result.Choice)
+	} else {
+		fmt.Printf("%s finished: %s\n", result.ID, result.Action)
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
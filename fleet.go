@@ -0,0 +1,555 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fleet connections: the controller writes an empty line when it has had nothing to send for
+// fleetHeartbeat, so an agent that has heard nothing for fleetAgentSilence reconnects, and agents
+// reconnect after a failure with a delay doubling up to fleetMaxBackoff
+const (
+	fleetHeartbeat     = 30 * time.Second
+	fleetAgentSilence  = 2*fleetHeartbeat + 10*time.Second
+	fleetMaxBackoff    = time.Minute
+	fleetAgentQueue    = 16   // Notifications waiting to be sent to an agent
+	maxFleetRecords    = 1000 // Fleet notifications remembered for GET /v1/fleet/notifications/{id}
+	fleetStatusOffline = "offline"
+)
+
+// agentNamePattern is what agents may call themselves: a host name
+var agentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,252}$`)
+
+// fleetJob is a notification the controller sends an agent, one JSON line of its connection
+type fleetJob struct {
+	ID   string `json:"id"`
+	Spec string `json:"spec"`
+}
+
+// fleetReport is an agent's result of a fleet notification (POST /v1/agents/results)
+type fleetReport struct {
+	ID     string              `json:"id"`
+	Host   string              `json:"host"`
+	Status string              `json:"status"` // serveStatusDone or serveStatusFailed
+	Result *NotificationResult `json:"result,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// fleetHostStatus is how a fleet notification went on one host: pending (sent to the agent),
+// offline (the host had no agent connected), done or failed
+type fleetHostStatus struct {
+	Status string              `json:"status"`
+	Result *NotificationResult `json:"result,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// fleetRecord is a notification pushed to agents, as GET /v1/fleet/notifications/{id} returns it
+type fleetRecord struct {
+	ID    string                     `json:"id"`
+	Hosts map[string]fleetHostStatus `json:"hosts"`
+}
+
+// fleetAgent is a connected agent
+type fleetAgent struct {
+	Host      string    `json:"host"`
+	Connected time.Time `json:"connected"`
+	jobs      chan fleetJob
+	done      chan struct{} // Closed when another connection of the same host replaces this one
+}
+
+// fleetController is the controller side of notify serve -controller: the connected agents and
+// the notifications pushed to them
+type fleetController struct {
+	mu      sync.Mutex
+	agents  map[string]*fleetAgent
+	records map[string]*fleetRecord
+	order   []string // Record IDs, oldest first
+}
+
+// fleetRoutes adds the controller's routes: agents connect and report results, clients push
+// notifications and follow them per host
+func (s *notifyServer) fleetRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/v1/agents", s.fleet.handleAgents)
+	mux.HandleFunc("/v1/agents/connect", s.fleet.handleConnect)
+	mux.HandleFunc("/v1/agents/results", s.fleet.handleReport)
+	mux.HandleFunc("/v1/fleet/notifications", s.fleet.handlePush)
+	mux.HandleFunc("/v1/fleet/notifications/", s.fleet.handleFleetRecord)
+}
+
+// handleAgents lists the connected agents, by host name
+func (f *fleetController) handleAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	f.mu.Lock()
+	agents := make([]fleetAgent, 0, len(f.agents))
+	for _, agent := range f.agents {
+		agents = append(agents, fleetAgent{Host: agent.Host, Connected: agent.Connected})
+	}
+	f.mu.Unlock()
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Host < agents[j].Host })
+	writeJSON(w, http.StatusOK, agents)
+}
+
+// handleConnect is an agent's connection, ?host=NAME: the notifications for it are streamed one
+// JSON line each until it disconnects, or connects again
+func (f *fleetController) handleConnect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	host := r.URL.Query().Get("host")
+	if !agentNamePattern.MatchString(host) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid agent host name %q", host))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	agent := &fleetAgent{Host: host, Connected: time.Now(), jobs: make(chan fleetJob, fleetAgentQueue), done: make(chan struct{})}
+	f.mu.Lock()
+	if f.agents == nil {
+		f.agents = map[string]*fleetAgent{}
+	}
+	if previous := f.agents[host]; previous != nil {
+		close(previous.done)
+	}
+	f.agents[host] = agent
+	f.mu.Unlock()
+	log.Printf("Agent %s connected from %s", host, r.RemoteAddr)
+	defer f.disconnect(agent)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	encoder := json.NewEncoder(w)
+	heartbeat := time.NewTicker(fleetHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-agent.done:
+			return
+		case <-heartbeat.C:
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return
+			}
+		case job := <-agent.jobs:
+			if err := encoder.Encode(job); err != nil {
+				f.report(fleetReport{ID: job.ID, Host: host, Status: serveStatusFailed, Error: "agent disconnected before the notification was sent"})
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// disconnect removes agent, failing the notifications still queued for it
+func (f *fleetController) disconnect(agent *fleetAgent) {
+	f.mu.Lock()
+	if f.agents[agent.Host] == agent {
+		delete(f.agents, agent.Host)
+	}
+	f.mu.Unlock()
+	log.Printf("Agent %s disconnected", agent.Host)
+	for {
+		select {
+		case job := <-agent.jobs:
+			f.report(fleetReport{ID: job.ID, Host: agent.Host, Status: serveStatusFailed, Error: "agent disconnected before the notification was sent"})
+		default:
+			return
+		}
+	}
+}
+
+// handleReport records an agent's result of a fleet notification
+func (f *fleetController) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	data, ok := readRequestBody(w, r)
+	if !ok {
+		return
+	}
+	var report fleetReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid report: %v", err))
+		return
+	}
+	if report.Status != serveStatusDone && report.Status != serveStatusFailed {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid report status %q", report.Status))
+		return
+	}
+	if !f.report(report) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no notification %q sent to %q", report.ID, report.Host))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// report sets the status of a host of a fleet notification; false when it was not sent there
+func (f *fleetController) report(report fleetReport) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	record := f.records[report.ID]
+	if record == nil {
+		return false
+	}
+	if _, sent := record.Hosts[report.Host]; !sent {
+		return false
+	}
+	record.Hosts[report.Host] = fleetHostStatus{Status: report.Status, Result: report.Result, Error: report.Error}
+	return true
+}
+
+// handlePush sends the posted spec to the agents of ?hosts=a,b, or to every connected agent,
+// and returns 202 with the notification ID and the status of each host
+func (f *fleetController) handlePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	data, ok := readRequestBody(w, r)
+	if !ok {
+		return
+	}
+	if err := checkServeSpec(data); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var hosts []string
+	if list := r.URL.Query().Get("hosts"); list != "" {
+		for _, host := range strings.Split(list, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	record := f.push(string(data), hosts)
+	if len(record.Hosts) == 0 {
+		writeJSONError(w, http.StatusServiceUnavailable, "no agents are connected")
+		return
+	}
+	w.Header().Set("Location", "/v1/fleet/notifications/"+record.ID)
+	writeJSON(w, http.StatusAccepted, record)
+}
+
+// push queues spec for the agents of hosts (every connected agent when empty) and returns the
+// new fleet notification
+func (f *fleetController) push(spec string, hosts []string) fleetRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(hosts) == 0 {
+		for host := range f.agents {
+			hosts = append(hosts, host)
+		}
+	}
+	record := &fleetRecord{ID: newNotificationID(), Hosts: map[string]fleetHostStatus{}}
+	for _, host := range hosts {
+		agent := f.agents[host]
+		if agent == nil {
+			record.Hosts[host] = fleetHostStatus{Status: fleetStatusOffline}
+			continue
+		}
+		select {
+		case agent.jobs <- fleetJob{ID: record.ID, Spec: spec}:
+			record.Hosts[host] = fleetHostStatus{Status: serveStatusPending}
+		default:
+			record.Hosts[host] = fleetHostStatus{Status: serveStatusFailed, Error: "too many notifications queued for the agent"}
+		}
+	}
+
+	if f.records == nil {
+		f.records = map[string]*fleetRecord{}
+	}
+	f.records[record.ID] = record
+	f.order = append(f.order, record.ID)
+	for len(f.order) > maxFleetRecords {
+		delete(f.records, f.order[0])
+		f.order = f.order[1:]
+	}
+	return record.copy()
+}
+
+// copy returns a copy of r that is safe to use without the lock
+func (r *fleetRecord) copy() fleetRecord {
+	c := fleetRecord{ID: r.ID, Hosts: make(map[string]fleetHostStatus, len(r.Hosts))}
+	for host, status := range r.Hosts {
+		c.Hosts[host] = status
+	}
+	return c
+}
+
+// handleFleetRecord returns the status of each host of a fleet notification
+func (f *fleetController) handleFleetRecord(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/v1/fleet/notifications/")
+	f.mu.Lock()
+	record := f.records[id]
+	var c fleetRecord
+	if record != nil {
+		c = record.copy()
+	}
+	f.mu.Unlock()
+	if record == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no fleet notification %q (only the last %d are kept)", id, maxFleetRecords))
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// fleetAgentClient is the agent side: it connects to the controller, shows the notifications it
+// sends with launch, and reports their results
+type fleetAgentClient struct {
+	controller string // Base URL of the controller
+	token      string
+	host       string
+	client     *http.Client
+	launch     func(specPath string, done func(*NotificationResult, error)) error
+}
+
+// agentTLSConfig returns the TLS configuration of notify agent: its client certificate, and
+// the CAs of the controller's certificate when not the system's
+func agentTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("-cert and -key go together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load the client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the CA certificates: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates in %s", caFile)
+		}
+	}
+	return config, nil
+}
+
+// readScopedToken returns the first token of a token file that grants scope, so the
+// controller's own -token-file can be used as is
+func readScopedToken(path, scope string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %v", err)
+	}
+	tokens, err := parseServeTokens(string(data))
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	for _, token := range tokens {
+		if token.grants(scope) {
+			return token.token, nil
+		}
+	}
+	return "", fmt.Errorf("%s has no token with the %s scope", path, scope)
+}
+
+// runAgent handles "notify agent -controller URL": stays connected to the controller and shows
+// the notifications it pushes to this host
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	controller := fs.String("controller", "", "URL of the controller (notify serve -controller), e.g. https://notify.example.com:8787")
+	tokenFile := fs.String("token-file", "", "File holding the bearer token of the controller (the first one with the agent or manage scope, as in its -token-file)")
+	name := fs.String("name", "", "Host name to register as (default: this machine's host name)")
+	debug := fs.Bool("debug", false, "Log the connection and pass -debug to notification processes")
+	certFile := fs.String("cert", "", "Client certificate (PEM) for a controller with -client-ca, with -key")
+	keyFile := fs.String("key", "", "Private key (PEM) of -cert")
+	caFile := fs.String("ca", "", "CA certificates (PEM) that sign the controller's certificate (default: the system's)")
+	digestWindow := fs.Duration("digest-window", 0, "Hold notifications arriving within this time of the last one shown and show them together in one digest when it ends, e.g. 30s (default: each at once)")
+	fs.Parse(args)
+
+	if !*debug {
+		log.SetOutput(io.Discard)
+	}
+	base, err := url.Parse(*controller)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		fmt.Fprintln(os.Stderr, "Error: -controller must be the http(s) URL of notify serve -controller")
+		return 2
+	}
+	if *digestWindow < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -digest-window cannot be negative")
+		return 2
+	}
+	if *name == "" {
+		if *name, err = os.Hostname(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not get the host name, use -name: %v\n", err)
+			return 1
+		}
+	}
+	if !agentNamePattern.MatchString(*name) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -name %q (use a host name)\n", *name)
+		return 2
+	}
+	tlsConfig, err := agentTLSConfig(*certFile, *keyFile, *caFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	agent := &fleetAgentClient{controller: strings.TrimSuffix(*controller, "/"), host: *name, client: &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}}}
+	if *tokenFile != "" {
+		if agent.token, err = readScopedToken(*tokenFile, scopeAgent); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get executable path: %v\n", err)
+		return 1
+	}
+	agent.launch = newDigestLauncher(*digestWindow, func(specPath string, done func(*NotificationResult, error)) error {
+		return launchSpec(exePath, specPath, true, *debug, done)
+	})
+
+	fmt.Printf("notify agent %s connecting to %s\n", agent.host, agent.controller)
+	backoff := time.Second
+	for {
+		connected := time.Now()
+		err := agent.session(context.Background())
+		if time.Since(connected) > fleetMaxBackoff {
+			backoff = time.Second // The connection worked for a while: this is a new failure
+		}
+		log.Printf("Controller connection ended: %v, reconnecting in %s", err, backoff)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, fleetMaxBackoff)
+	}
+}
+
+// session connects to the controller and shows its notifications until the connection ends
+func (a *fleetAgentClient) session(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.controller+"/v1/agents/connect?host="+url.QueryEscape(a.host), nil)
+	if err != nil {
+		return err
+	}
+	a.authorize(req)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("controller answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	log.Printf("Connected to %s as %s", a.controller, a.host)
+
+	// A controller that has gone quiet, without even heartbeats, is a dead connection
+	silence := time.AfterFunc(fleetAgentSilence, cancel)
+	defer silence.Stop()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 2*serveRequestLimit)
+	for scanner.Scan() {
+		silence.Reset(fleetAgentSilence)
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var job fleetJob
+		if err := json.Unmarshal(line, &job); err != nil {
+			log.Printf("Ignoring invalid notification from the controller: %v", err)
+			continue
+		}
+		a.show(job)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("controller closed the connection")
+}
+
+// show launches the notification of job and reports its result when it finishes
+func (a *fleetAgentClient) show(job fleetJob) {
+	failed := func(err error) {
+		a.report(fleetReport{ID: job.ID, Host: a.host, Status: serveStatusFailed, Error: err.Error()})
+	}
+	specFile, err := os.CreateTemp("", "notify-agent-*.yaml")
+	if err != nil {
+		failed(err)
+		return
+	}
+	specFile.WriteString(job.Spec)
+	specFile.Close()
+	err = a.launch(specFile.Name(), func(result *NotificationResult, err error) {
+		if err != nil {
+			failed(err)
+			return
+		}
+		a.report(fleetReport{ID: job.ID, Host: a.host, Status: serveStatusDone, Result: result})
+	})
+	if err != nil {
+		failed(err)
+	}
+}
+
+// report posts a result to the controller
+func (a *fleetAgentClient) report(report fleetReport) {
+	data, _ := json.Marshal(report)
+	req, err := http.NewRequest(http.MethodPost, a.controller+"/v1/agents/results", bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	a.authorize(req)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		log.Printf("Could not report the result of %s: %v", report.ID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		log.Printf("Could not report the result of %s: %s", report.ID, resp.Status)
+	}
+}
+
+// authorize adds the bearer token to req
+func (a *fleetAgentClient) authorize(req *http.Request) {
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFleet tests that an agent connects to the controller, shows what is pushed to it and
+// reports the result, and that each host's status is tracked
+func TestFleet(t *testing.T) {
+	server := &notifyServer{tokens: []serveToken{{token: "secret", scopes: []string{scopeManage}}}, fleet: &fleetController{}}
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+
+	shown := make(chan string, 1)
+	agent := &fleetAgentClient{
+		controller: httpServer.URL,
+		token:      "secret",
+		host:       "lab1",
+		client:     httpServer.Client(),
+		launch: func(specPath string, done func(*NotificationResult, error)) error {
+			data, _ := os.ReadFile(specPath)
+			os.Remove(specPath)
+			shown <- string(data)
+			done(&NotificationResult{Action: actionAcknowledged, Method: "fyne", Title: "Lab closing"}, nil)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sessionEnded := make(chan error, 1)
+	go func() { sessionEnded <- agent.session(ctx) }()
+
+	request := func(method, path, token, body string) (*http.Response, map[string]interface{}) {
+		req, _ := http.NewRequest(method, httpServer.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp, decoded
+	}
+	waitFor := func(what string, check func() bool) {
+		for deadline := time.Now().Add(5 * time.Second); !check(); time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+		}
+	}
+	waitFor("the agent to connect", func() bool {
+		server.fleet.mu.Lock()
+		defer server.fleet.mu.Unlock()
+		return server.fleet.agents["lab1"] != nil
+	})
+
+	spec := "version: 1\ntitle: Lab closing\nmessage: Save your work\n"
+	if resp, _ := request("POST", "/v1/fleet/notifications", "", spec); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Push without a token: %s, want 401", resp.Status)
+	}
+	if resp, _ := request("POST", "/v1/fleet/notifications", "secret", "version: 1\ntitel: Typo\n"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Push of an invalid spec: %s, want 400", resp.Status)
+	}
+
+	resp, pushed := request("POST", "/v1/fleet/notifications?hosts=lab1,lab9", "secret", spec)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Push: %s %v", resp.Status, pushed)
+	}
+	hosts := pushed["hosts"].(map[string]interface{})
+	if hosts["lab1"].(map[string]interface{})["status"] != serveStatusPending || hosts["lab9"].(map[string]interface{})["status"] != fleetStatusOffline {
+		t.Errorf("Expected lab1 pending and lab9 offline, got %v", hosts)
+	}
+	select {
+	case got := <-shown:
+		if got != spec {
+			t.Errorf("Agent showed %q, want %q", got, spec)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The agent did not show the notification")
+	}
+
+	id := pushed["id"].(string)
+	waitFor("the result of lab1", func() bool {
+		server.fleet.mu.Lock()
+		defer server.fleet.mu.Unlock()
+		return server.fleet.records[id].Hosts["lab1"].Status == serveStatusDone
+	})
+	_, record := request("GET", "/v1/fleet/notifications/"+id, "secret", "")
+	lab1 := record["hosts"].(map[string]interface{})["lab1"].(map[string]interface{})
+	if lab1["result"].(map[string]interface{})["action"] != actionAcknowledged {
+		t.Errorf("Expected lab1 acknowledged, got %v", lab1)
+	}
+	if resp, _ := request("POST", "/v1/agents/results", "secret", `{"id":"`+id+`","host":"lab7","status":"done"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Report of a host the notification was not sent to: %s, want 404", resp.Status)
+	}
+
+	cancel()
+	<-sessionEnded
+	waitFor("the agent to disconnect", func() bool {
+		server.fleet.mu.Lock()
+		defer server.fleet.mu.Unlock()
+		return len(server.fleet.agents) == 0
+	})
+	if resp, _ := request("POST", "/v1/fleet/notifications", "secret", spec); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Push with no agents connected: %s, want 503", resp.Status)
+	}
+}
+
+// TestFleetRoutesNeedController tests that notify serve without -controller has no fleet routes
+func TestFleetRoutesNeedController(t *testing.T) {
+	handler := (&notifyServer{}).handler()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/agents", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /v1/agents without -controller: %d, want 404", w.Code)
+	}
+}
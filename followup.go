@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// maxFollowUpDepth limits follow-up chains, including a spec that follows up with itself
+const maxFollowUpDepth = 10
+
+// launchFollowUps starts a detached notify process for every follow-up matching the outcome
+// Each process waits for the follow-up's delay (-followup-delay) and then shows its spec
+// The waiting process does not survive a reboot or logoff
+func launchFollowUps(followUps []SpecFollowUp, specDir string, action string, depth int) {
+	if len(followUps) == 0 {
+		return
+	}
+	if depth >= maxFollowUpDepth {
+		log.Printf("Follow-up chain reached %d levels, not launching more follow-ups", maxFollowUpDepth)
+		return
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		log.Printf("Warning: Could not launch follow-ups: %v", err)
+		return
+	}
+
+	for _, followUp := range followUps {
+		if followUp.On != action && followUp.On != "any" {
+			continue
+		}
+
+		specPath, err := filepath.Abs(resolveSpecPath(specDir, followUp.Spec))
+		if err != nil {
+			log.Printf("Warning: Could not resolve follow-up spec %s: %v", followUp.Spec, err)
+			continue
+		}
+
+		args := []string{"-spec", specPath, "-followup-depth", fmt.Sprintf("%d", depth+1)}
+		if followUp.Delay != "" {
+			args = append(args, "-followup-delay", followUp.Delay)
+		}
+		for _, arg := range os.Args {
+			if arg == "-debug" {
+				args = append(args, arg)
+			}
+		}
+
+		cmd := exec.Command(exePath, args...)
+		detachProcess(cmd)
+		if err := cmd.Start(); err != nil {
+			log.Printf("Warning: Could not launch follow-up %s: %v", specPath, err)
+			continue
+		}
+		// Don't wait: the follow-up outlives this process
+		cmd.Process.Release()
+		log.Printf("Launched follow-up %s on %s (delay %s)", specPath, action, followUp.Delay)
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
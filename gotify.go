@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+	"golang.org/x/net/websocket"
+)
+
+// gotifyTokenEnv holds the Gotify application token -gotify publishes with
+const gotifyTokenEnv = "GOTIFY_TOKEN"
+
+// gotifyClientTokenEnv holds the Gotify client token notify subscribe -gotify receives with
+const gotifyClientTokenEnv = "GOTIFY_CLIENT_TOKEN"
+
+// gotifyCatchUp is how many recent messages are checked for ones missed while reconnecting
+const gotifyCatchUp = 100
+
+// gotifyPublish is a message posted to a Gotify server; the link is the click URL of the
+// Gotify apps
+type gotifyPublish struct {
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+// gotifyEvent is a message received from a Gotify server, on the stream or from GET /message
+type gotifyEvent struct {
+	gotifyMessage
+	ID int `json:"id"`
+}
+
+// gotifySubscriber shows the messages a Gotify client receives with launch
+type gotifySubscriber struct {
+	server string // Base URL, without a trailing /
+	token  string // Client token
+	lastID int    // Last message shown, to catch up after a reconnection
+	client *http.Client
+	launch func(specPath string, done func(*NotificationResult, error)) error
+}
+
+// parseGotifyServer checks a Gotify server URL and returns it without a trailing /
+func parseGotifyServer(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid Gotify server %q (use an http or https URL)", rawURL)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// gotifyPublishMessage is the Gotify message for a notification: urgency sets the priority (a
+// -native toast is low, critical is 10 so the apps alert loudly), the link is the click URL,
+// and a -sensitive message is not sent to the phones
+func gotifyPublishMessage(n notify.Notification, native bool) gotifyPublish {
+	m := gotifyPublish{Title: n.Title, Message: n.Message, Priority: 5}
+	if n.Sensitive {
+		m.Message = redactedMessage
+	}
+	switch {
+	case n.Priority == notify.PriorityBreakGlass || n.Urgency == notify.UrgencyCritical:
+		m.Priority = 10
+	case n.Urgency == notify.UrgencyWarning:
+		m.Priority = 7
+	case native:
+		m.Priority = 2
+	}
+	if n.Link != "" {
+		m.Extras = map[string]interface{}{
+			"client::notification": map[string]interface{}{"click": map[string]string{"url": n.Link}},
+		}
+	}
+	return m
+}
+
+// publishGotify posts m to the Gotify server with an application token
+func publishGotify(server, token string, m gotifyPublish) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("could not encode Gotify message: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, server+"/message", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("could not create Gotify request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KrankyBearNotify/"+appVersion)
+	req.Header.Set("X-Gotify-Key", token)
+	resp, err := (&http.Client{Timeout: ntfyTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("gotify request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gotify request failed: %s%s", resp.Status, gotifyError(resp.Body))
+	}
+	return nil
+}
+
+// gotifyError returns the errorDescription of a Gotify error response, as ": description"
+func gotifyError(body io.Reader) string {
+	var reply struct {
+		Description string `json:"errorDescription"`
+	}
+	json.NewDecoder(io.LimitReader(body, 4096)).Decode(&reply)
+	if reply.Description == "" {
+		return ""
+	}
+	return ": " + reply.Description
+}
+
+// run keeps the stream open, reconnecting after failures
+func (s *gotifySubscriber) run() {
+	backoff := time.Second
+	for {
+		connected := time.Now()
+		err := s.session(context.Background())
+		if time.Since(connected) > fleetMaxBackoff {
+			backoff = time.Second // The connection worked for a while: this is a new failure
+		}
+		log.Printf("Gotify stream from %s ended: %v, reconnecting in %s", s.server, err, backoff)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, fleetMaxBackoff)
+	}
+}
+
+// session opens the message stream (a websocket at /stream) and shows its messages until it
+// ends. After a reconnection, the messages missed in between are shown first
+func (s *gotifySubscriber) session(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wsURL := "ws" + strings.TrimPrefix(s.server, "http") + "/stream"
+	config, err := websocket.NewConfig(wsURL, s.server)
+	if err != nil {
+		return err
+	}
+	config.Header.Set("X-Gotify-Key", s.token)
+	config.Header.Set("User-Agent", "KrankyBearNotify/"+appVersion)
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	log.Printf("Receiving Gotify messages from %s", s.server)
+
+	// The stream only has new messages: the ones that came while disconnected are listed by
+	// GET /message, newest first
+	if s.lastID > 0 {
+		if err := s.catchUp(ctx); err != nil {
+			log.Printf("Could not list the Gotify messages missed while reconnecting: %v", err)
+		}
+	}
+	for {
+		var event gotifyEvent
+		if err := websocket.JSON.Receive(conn, &event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("gotify closed the connection")
+			}
+			return err
+		}
+		s.show(event)
+	}
+}
+
+// catchUp shows the messages received after lastID, oldest first
+func (s *gotifySubscriber) catchUp(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.server+"/message?limit="+strconv.Itoa(gotifyCatchUp), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Gotify-Key", s.token)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("gotify answered %s%s", resp.Status, gotifyError(resp.Body))
+	}
+	var page struct {
+		Messages []gotifyEvent `json:"messages"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return fmt.Errorf("could not read Gotify messages: %v", err)
+	}
+	sort.Slice(page.Messages, func(i, j int) bool { return page.Messages[i].ID < page.Messages[j].ID })
+	for _, event := range page.Messages {
+		if event.ID > s.lastID {
+			s.show(event)
+		}
+	}
+	return nil
+}
+
+// show launches a notification for a Gotify message, mapped as by the /gotify endpoint of notify serve
+func (s *gotifySubscriber) show(event gotifyEvent) {
+	if event.ID <= s.lastID {
+		return // Already shown from the catch-up list
+	}
+	s.lastID = event.ID
+	spec, err := gotifySpec(event.gotifyMessage)
+	if err != nil {
+		log.Printf("Ignoring Gotify message %d: %v", event.ID, err)
+		return
+	}
+	launchPushSpec(fmt.Sprintf("Gotify message %d", event.ID), spec, s.launch)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+	"golang.org/x/net/websocket"
+)
+
+// TestPublishGotify tests that the notification is posted with the application token, and that
+// notify serve maps it back onto the same notification
+func TestPublishGotify(t *testing.T) {
+	var got gotifyMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "app-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"Unauthorized","errorCode":401,"errorDescription":"you need to provide a valid access token"}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	n := notify.Notification{Title: "Disk", Message: "Disk almost full", Urgency: notify.UrgencyCritical, Link: "https://grafana.example.com/d/disk"}
+	if err := publishGotify(server.URL, "app-token", gotifyPublishMessage(n, false)); err != nil {
+		t.Fatalf("publishGotify failed: %v", err)
+	}
+	spec, err := gotifySpec(got)
+	if err != nil || spec.Title != n.Title || spec.Message != n.Message || spec.Link != n.Link || spec.Timeout == nil || *spec.Timeout != 0 {
+		t.Errorf("Expected a critical notification to map back onto one that stays, got %+v, %v", spec, err)
+	}
+	if m := gotifyPublishMessage(notify.Notification{Message: "x"}, true); m.Priority != 2 || m.Extras != nil {
+		t.Errorf("Expected a -native notification without a link to be priority 2 without extras, got %+v", m)
+	}
+	if err := publishGotify(server.URL, "wrong", gotifyPublishMessage(n, false)); err == nil || !strings.Contains(err.Error(), "valid access token") {
+		t.Errorf("Expected Gotify's error, got %v", err)
+	}
+
+	for _, value := range []string{"gotify.example.com", "ftp://gotify.example.com", "https://gotify.example.com?token=x"} {
+		if _, err := parseGotifyServer(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+// TestGotifySubscribe tests that stream messages are shown, and that after a reconnection the
+// messages missed in between are shown once, oldest first
+func TestGotifySubscribe(t *testing.T) {
+	stream := make(chan string, 10)
+	mux := http.NewServeMux()
+	mux.Handle("/stream", websocket.Handler(func(conn *websocket.Conn) {
+		if conn.Request().Header.Get("X-Gotify-Key") != "client-token" {
+			return
+		}
+		for message := range stream {
+			if message == "" {
+				return // Drop the connection
+			}
+			websocket.Message.Send(conn, message)
+		}
+	}))
+	mux.HandleFunc("/message", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"messages":[{"id":5,"message":"Five"},{"id":4,"message":"Four"},{"id":2,"message":"Two"}],"paging":{"size":3}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var shown []string
+	s := &gotifySubscriber{server: server.URL, token: "client-token", client: server.Client(), launch: func(specPath string, done func(*NotificationResult, error)) error {
+		data, _ := os.ReadFile(specPath)
+		os.Remove(specPath)
+		var spec pushSpec
+		json.Unmarshal(data, &spec)
+		shown = append(shown, spec.Message)
+		return nil
+	}}
+
+	stream <- `{"id":1,"title":"Disk","message":"One","priority":9}`
+	stream <- `{"id":2,"message":"Two","priority":20}`
+	stream <- ""
+	if err := s.session(context.Background()); err == nil {
+		t.Error("Expected the session to end with the connection")
+	}
+	stream <- `{"id":5,"message":"Five"}`
+	stream <- `{"id":6,"message":"Six"}`
+	stream <- ""
+	s.session(context.Background())
+	if got := strings.Join(shown, ","); got != "One,Four,Five,Six" {
+		t.Errorf("Shown %s, want One,Four,Five,Six", got)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHistoryBytes caps the history file; when it grows beyond, the older half is dropped
+const maxHistoryBytes = 4 << 20
+
+// HistoryEntry is a notification recorded in the per-user notification history: what was shown,
+// to whom, how, and what became of it
+type HistoryEntry struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Priority string    `json:"priority,omitempty"`
+	Variant  string    `json:"variant,omitempty"`
+	User     string    `json:"user,omitempty"`    // User of the notify process that showed it
+	Targets  []string  `json:"targets,omitempty"` // Users reached by a root/SYSTEM fan-out
+	Method   string    `json:"method,omitempty"`
+	Action   string    `json:"action"`
+	Choice   string    `json:"choice,omitempty"`
+	Updated  time.Time `json:"updated,omitempty"` // When a later report (a toast click) changed the action
+}
+
+// getHistoryPath returns the location of the notification history of the current user
+func getHistoryPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "history.jsonl"), nil
+}
+
+// historyEntry returns the history entry of the notification reported with action and method
+// -sensitive messages are not kept, nor -redact-after-ack messages once acknowledged
+func (r resultReporter) historyEntry(action, method string) HistoryEntry {
+	entry := HistoryEntry{
+		ID:       r.inboxID,
+		Time:     time.Now(),
+		Title:    r.title,
+		Message:  r.message,
+		Category: r.category,
+		Priority: r.priority,
+		Variant:  r.variant,
+		Method:   method,
+		Action:   action,
+		Choice:   r.choice,
+	}
+	if entry.ID == "" {
+		entry.ID = newNotificationID()
+	}
+	if r.sensitive || (r.redactAfterAck && action == actionAcknowledged) {
+		entry.Message = redactedMessage
+	}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	for _, target := range r.targets {
+		entry.Targets = append(entry.Targets, target.Username)
+	}
+	return entry
+}
+
+// recordHistory appends entry to the notification history
+// A lock file serializes concurrent notify processes appending to the same history
+func recordHistory(entry HistoryEntry) error {
+	historyPath, err := getHistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0700); err != nil {
+		return fmt.Errorf("could not create history directory: %v", err)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not encode history entry: %v", err)
+	}
+
+	unlock, err := lockFile(historyPath + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open history: %v", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write history: %v", err)
+	}
+	return trimHistory(historyPath)
+}
+
+// trimHistory drops the older half of the history at historyPath once it exceeds maxHistoryBytes
+// The caller holds the history lock
+func trimHistory(historyPath string) error {
+	info, err := os.Stat(historyPath)
+	if err != nil || info.Size() <= maxHistoryBytes {
+		return err
+	}
+	data, err := os.ReadFile(historyPath)
+	if err != nil {
+		return fmt.Errorf("could not read history: %v", err)
+	}
+	keep := data[len(data)/2:]
+	if i := bytes.IndexByte(keep, '\n'); i >= 0 {
+		keep = keep[i+1:]
+	}
+	tmpPath := historyPath + ".tmp"
+	if err := os.WriteFile(tmpPath, keep, 0600); err != nil {
+		return fmt.Errorf("could not write history: %v", err)
+	}
+	return os.Rename(tmpPath, historyPath)
+}
+
+// loadHistory reads the notification history, newest first
+// Later reports of the same notification (a toast clicked after notify exited) update its action
+func loadHistory() ([]HistoryEntry, error) {
+	historyPath, err := getHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(historyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read history: %v", err)
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	index := map[string]int{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.ID == "" {
+			continue // A line cut short by a crash or by trimming
+		}
+		i, seen := index[entry.ID]
+		if !seen {
+			index[entry.ID] = len(entries)
+			entries = append(entries, entry)
+			continue
+		}
+		first := &entries[i]
+		first.Action, first.Method, first.Updated = entry.Action, entry.Method, entry.Time
+		if entry.Choice != "" {
+			first.Choice = entry.Choice
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read history %s: %v", historyPath, err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// historyFilter selects history entries for notify history
+type historyFilter struct {
+	search   string // Case-insensitive text in the title, message, category or users
+	since    time.Time
+	category string
+	action   string
+}
+
+// matches reports whether entry passes the filter
+func (f historyFilter) matches(entry HistoryEntry) bool {
+	if entry.Time.Before(f.since) {
+		return false
+	}
+	if f.category != "" && entry.Category != f.category {
+		return false
+	}
+	if f.action != "" && entry.Action != f.action {
+		return false
+	}
+	if f.search == "" {
+		return true
+	}
+	text := strings.Join(append([]string{entry.Title, entry.Message, entry.Category, entry.User}, entry.Targets...), "\n")
+	return strings.Contains(strings.ToLower(text), strings.ToLower(f.search))
+}
+
+// parseSince parses a -since value: a duration back from now ("36h", "7d"), a date or an RFC 3339 time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -since %q (use a duration such as 36h or 7d, a date such as 2025-03-01, or an RFC 3339 time)", value)
+}
+
+// runHistory handles "notify history": lists and searches the notifications this user's notify
+// processes delivered, newest first
+func runHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	search := fs.String("search", "", "Only notifications containing this text (title, message, category or user)")
+	since := fs.String("since", "", "Only notifications since a duration ago (36h, 7d), a date or an RFC 3339 time")
+	category := fs.String("category", "", "Only notifications of this -category")
+	action := fs.String("action", "", "Only notifications with this result (acknowledged, timeout, delivered, skipped, suppressed)")
+	limit := fs.Int("n", 50, "Show at most this many notifications (0 for all)")
+	jsonOutput := fs.Bool("json", false, "Print the notifications as JSON")
+	fs.Parse(args)
+
+	filter := historyFilter{search: *search, category: *category, action: *action}
+	if *since != "" {
+		var err error
+		if filter.since, err = parseSince(*since, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	matched := []HistoryEntry{}
+	for _, entry := range entries {
+		if filter.matches(entry) {
+			matched = append(matched, entry)
+		}
+		if *limit > 0 && len(matched) == *limit {
+			break
+		}
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(matched, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	if len(matched) == 0 {
+		fmt.Println("No notifications in the history match.")
+		return 0
+	}
+	fmt.Printf("%-16s %-12s %-10s %-12s %s\n", "TIME", "RESULT", "METHOD", "USER", "TITLE")
+	for _, entry := range matched {
+		who := entry.User
+		if len(entry.Targets) > 0 {
+			who = fmt.Sprintf("%d users", len(entry.Targets))
+		}
+		fmt.Printf("%-16s %-12s %-10s %-12s %s\n", entry.Time.Local().Format("2006-01-02 15:04"), entry.Action, entry.Method, who, entry.Title)
+		if entry.Message != "" {
+			fmt.Printf("    %s\n", strings.ReplaceAll(entry.Message, "\n", "\n    "))
+		}
+	}
+	return 0
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHistoryRecordsReports tests that reported notifications are recorded, a later toast click
+// updates the entry, and sensitive messages are kept out
+func TestHistoryRecordsReports(t *testing.T) {
+	useTempStore(t)
+
+	id := recordDelivery("Patch", "Reboot tonight", "", "")
+	reporter := resultReporter{title: "Patch", message: "Reboot tonight", category: "maintenance", inboxID: id}
+	reporter.report(actionDelivered, "toast")
+	clicked := resultReporter{title: "Patch", inboxID: id}
+	clicked.report(actionAcknowledged, "toast")
+
+	secret := resultReporter{title: "Code", message: "Your code is 482913", sensitive: true}
+	secret.report(actionTimeout, "fyne")
+
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatalf("loadHistory failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	code, patch := entries[0], entries[1]
+	if patch.ID != id || patch.Action != actionAcknowledged || patch.Message != "Reboot tonight" || patch.Updated.IsZero() {
+		t.Errorf("Expected the toast click to update the patch entry, got %+v", patch)
+	}
+	if code.Message != redactedMessage || code.Action != actionTimeout {
+		t.Errorf("Expected the sensitive message to be hidden, got %+v", code)
+	}
+
+	filter := historyFilter{search: "REBOOT"}
+	if !filter.matches(patch) || filter.matches(code) {
+		t.Error("Expected -search to match the message, case-insensitively")
+	}
+	if (historyFilter{category: "maintenance", action: actionTimeout}).matches(patch) {
+		t.Error("Expected -action timeout to exclude an acknowledged notification")
+	}
+	if (historyFilter{since: time.Now().Add(time.Hour)}).matches(patch) {
+		t.Error("Expected -since in the future to exclude every notification")
+	}
+}
+
+// TestTrimHistory tests that an oversized history keeps its newer half, starting at a whole line
+func TestTrimHistory(t *testing.T) {
+	path := t.TempDir() + "/history.jsonl"
+	line := `{"id":"x","action":"timeout","title":"` + strings.Repeat("a", 1000) + `"}` + "\n"
+	full := strings.Repeat(line, maxHistoryBytes/len(line)+10)
+	if err := os.WriteFile(path, []byte(full), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := trimHistory(path); err != nil {
+		t.Fatalf("trimHistory failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > len(full)/2 || len(data)%len(line) != 0 || !strings.HasPrefix(string(data), `{"id"`) {
+		t.Errorf("Expected the newer half from a line start, got %d bytes starting %q", len(data), data[:10])
+	}
+}
+
+// TestParseSince tests the -since formats
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"36h":                  now.Add(-36 * time.Hour),
+		"7d":                   now.AddDate(0, 0, -7),
+		"2025-03-01T08:00:00Z": time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		got, err := parseSince(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseSince("last week", now); err == nil {
+		t.Error("Expected an error for an invalid -since")
+	}
+}
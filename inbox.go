@@ -0,0 +1,345 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// defaultSnoozeDuration is how long the inbox Snooze action hides a notification
+const defaultSnoozeDuration = time.Hour
+
+// inboxSnoozeCheck is how often an open inbox looks for snoozes that have ended
+const inboxSnoozeCheck = 30 * time.Second
+
+// runInbox handles "notify inbox [-tray]": opens the inbox window listing pending and recent
+// notifications from the local store, with per-item open, acknowledge and snooze actions
+// While it runs, snoozed notifications are shown again when their snooze ends
+func runInbox(args []string) int {
+	fs := flag.NewFlagSet("inbox", flag.ExitOnError)
+	tray := fs.Bool("tray", false, "Stay in the system tray (open the inbox from its menu) and show snoozed notifications again when their snooze ends; start it at login")
+	fs.Parse(args)
+
+	items, err := loadStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	a := app.New()
+	w := a.NewWindow("Notification Inbox")
+	w.SetIcon(notify.DefaultIcon())
+	trayApp, hasTray := a.(desktop.App)
+	if *tray && !hasTray {
+		fmt.Fprintln(os.Stderr, "Error: this desktop has no system tray, run notify inbox without -tray")
+		return 1
+	}
+
+	selectedID := ""
+
+	// Detail pane for the selected notification
+	detailTitle := widget.NewLabel("Select a notification")
+	detailTitle.TextStyle.Bold = true
+	detailInfo := widget.NewLabel("")
+	detailMessage := widget.NewLabel("")
+	detailMessage.Wrapping = fyne.TextWrapWord
+
+	var list *widget.List
+
+	// Opting out of the selected notification's category is the inbox's preference control
+	optOutButton := widget.NewButton("Opt out", nil)
+	optOutButton.Disable()
+	selectedCategory := ""
+	categoryOptedOut := false
+	updateOptOutButton := func(category string) {
+		selectedCategory = category
+		if category == "" {
+			optOutButton.SetText("Opt out")
+			optOutButton.Disable()
+			return
+		}
+		categoryOptedOut = false
+		if categories, err := loadOptOuts(); err == nil {
+			for _, optedOut := range categories {
+				if optedOut == category {
+					categoryOptedOut = true
+				}
+			}
+		}
+		if categoryOptedOut {
+			optOutButton.SetText("Opt back in to " + category)
+		} else {
+			optOutButton.SetText("Opt out of " + category)
+		}
+		optOutButton.Enable()
+	}
+	optOutButton.OnTapped = func() {
+		if selectedCategory == "" {
+			return
+		}
+		if err := setOptOut(selectedCategory, !categoryOptedOut); err != nil {
+			log.Printf("Warning: Could not update opt-outs: %v", err)
+		}
+		updateOptOutButton(selectedCategory)
+	}
+
+	// The tray menu shows how many notifications are pending
+	openItem := fyne.NewMenuItem("Open inbox", func() {
+		w.Show()
+		w.RequestFocus()
+	})
+	trayMenu := fyne.NewMenu("KrankyBear Notify", openItem)
+	updateTray := func() {
+		if !*tray {
+			return
+		}
+		pending := 0
+		for _, item := range items {
+			if item.isPending() {
+				pending++
+			}
+		}
+		openItem.Label = "Open inbox"
+		if pending > 0 {
+			openItem.Label = fmt.Sprintf("Open inbox (%d pending)", pending)
+		}
+		trayMenu.Refresh()
+	}
+
+	// reload re-reads the store so changes made by other notify processes show up
+	reload := func() {
+		if reloaded, err := loadStore(); err == nil {
+			items = reloaded
+		} else {
+			log.Printf("Warning: Could not reload notification store: %v", err)
+		}
+		list.Refresh()
+		updateTray()
+	}
+
+	// Open: the notification's link, in the browser
+	selectedLink := ""
+	openButton := widget.NewButton("Open link", func() {
+		if selectedLink != "" {
+			notify.OpenLink(selectedLink)
+		}
+	})
+	openButton.Disable()
+
+	// showDetails fills the detail pane for the item with the given ID
+	showDetails := func(id string) {
+		for _, item := range items {
+			if item.ID == id {
+				info := item.Received.Format("2006-01-02 15:04:05") + " - " + item.describeState()
+				if item.Category != "" {
+					info += " - " + item.Category
+				}
+				detailTitle.SetText(item.Title)
+				detailInfo.SetText(info)
+				detailMessage.SetText(item.Message)
+				updateOptOutButton(item.Category)
+				selectedLink = item.Link
+				if selectedLink != "" {
+					openButton.Enable()
+				} else {
+					openButton.Disable()
+				}
+				return
+			}
+		}
+	}
+
+	list = widget.NewList(
+		func() int {
+			return len(items)
+		},
+		func() fyne.CanvasObject {
+			return container.NewVBox(widget.NewLabel("Title"), widget.NewLabel("Received"))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			item := items[id]
+			row := obj.(*fyne.Container)
+			titleLabel := row.Objects[0].(*widget.Label)
+			infoLabel := row.Objects[1].(*widget.Label)
+
+			// Pending notifications are shown in bold, like unread mail
+			titleLabel.TextStyle.Bold = item.isPending()
+			titleLabel.SetText(item.Title)
+			infoLabel.SetText(item.Received.Format("2006-01-02 15:04") + " - " + item.describeState())
+		},
+	)
+
+	// Opening an unread notification marks it as read
+	list.OnSelected = func(id widget.ListItemID) {
+		item := items[id]
+		selectedID = item.ID
+		if item.State == inboxStateUnread {
+			if err := updateStoredNotification(item.ID, func(stored *StoredNotification) {
+				stored.State = inboxStateRead
+			}); err != nil {
+				log.Printf("Warning: Could not mark notification as read: %v", err)
+			}
+			reload()
+		}
+		showDetails(selectedID)
+	}
+
+	acknowledgeButton := widget.NewButton("Acknowledge", func() {
+		if selectedID == "" {
+			return
+		}
+		if err := updateStoredNotification(selectedID, func(stored *StoredNotification) {
+			stored.State = inboxStateAcknowledged
+			stored.Action = actionAcknowledged
+		}); err != nil {
+			log.Printf("Warning: Could not acknowledge notification: %v", err)
+		}
+		reload()
+		showDetails(selectedID)
+	})
+
+	snoozeButton := widget.NewButton("Snooze 1h", func() {
+		if selectedID == "" {
+			return
+		}
+		snoozeStoredNotification(selectedID)
+		reload()
+		showDetails(selectedID)
+	})
+
+	// wake shows the notifications whose snooze has ended again, each in its own window
+	wake := func() {
+		woken, err := wakeSnoozed(time.Now())
+		if err != nil {
+			log.Printf("Warning: Could not check snoozed notifications: %v", err)
+			return
+		}
+		if len(woken) == 0 {
+			return
+		}
+		reload()
+		for _, item := range woken {
+			showSnoozeEnded(a, item, func() {
+				reload()
+				if selectedID != "" {
+					showDetails(selectedID)
+				}
+			})
+		}
+	}
+	refreshButton := widget.NewButton("Refresh", func() {
+		reload()
+		wake()
+	})
+
+	details := container.NewBorder(
+		container.NewVBox(detailTitle, detailInfo, widget.NewSeparator()),                           // top
+		container.NewHBox(openButton, acknowledgeButton, snoozeButton, optOutButton, refreshButton), // bottom
+		nil, // left
+		nil, // right
+		container.NewVScroll(detailMessage),
+	)
+
+	split := container.NewHSplit(list, container.NewPadded(details))
+	split.Offset = 0.4
+
+	if len(items) == 0 {
+		detailTitle.SetText("No notifications yet")
+	}
+
+	w.SetContent(split)
+	w.Resize(fyne.NewSize(760, 450))
+	w.CenterOnScreen()
+
+	// Snoozes that ended while no inbox was running are shown as soon as it starts
+	a.Lifecycle().SetOnStarted(func() {
+		wake()
+		go func() {
+			for range time.Tick(inboxSnoozeCheck) {
+				fyne.Do(wake)
+			}
+		}()
+	})
+	if *tray {
+		// Closing the window keeps notify in the tray; Quit is in the tray menu
+		trayApp.SetSystemTrayIcon(notify.DefaultIcon())
+		trayApp.SetSystemTrayMenu(trayMenu)
+		w.SetCloseIntercept(w.Hide)
+		updateTray()
+		a.Run()
+		return 0
+	}
+	w.ShowAndRun()
+	return 0
+}
+
+// snoozeStoredNotification hides a notification from the pending ones for defaultSnoozeDuration
+func snoozeStoredNotification(id string) {
+	if err := updateStoredNotification(id, func(stored *StoredNotification) {
+		stored.State = inboxStateSnoozed
+		stored.SnoozedUntil = time.Now().Add(defaultSnoozeDuration)
+	}); err != nil {
+		log.Printf("Warning: Could not snooze notification: %v", err)
+	}
+}
+
+// showSnoozeEnded shows a notification again in a window of the inbox app when its snooze
+// has ended, with the actions of the inbox; changed is called after an action changed the store
+func showSnoozeEnded(a fyne.App, item StoredNotification, changed func()) {
+	w := a.NewWindow(item.Title)
+	w.SetIcon(notify.DefaultIcon())
+
+	title := widget.NewLabel(item.Title)
+	title.TextStyle.Bold = true
+	title.Wrapping = fyne.TextWrapWord
+	info := widget.NewLabel("Snoozed, received " + item.Received.Format("2006-01-02 15:04"))
+	message := widget.NewLabel(item.Message)
+	message.Wrapping = fyne.TextWrapWord
+
+	buttons := container.NewHBox()
+	if item.Link != "" {
+		buttons.Add(widget.NewButton("Open link", func() {
+			notify.OpenLink(item.Link)
+		}))
+	}
+	buttons.Add(widget.NewButton("Snooze 1h", func() {
+		snoozeStoredNotification(item.ID)
+		changed()
+		w.Close()
+	}))
+	acknowledge := widget.NewButton("Acknowledge", func() {
+		if err := updateStoredNotification(item.ID, func(stored *StoredNotification) {
+			stored.State = inboxStateAcknowledged
+			stored.Action = actionAcknowledged
+		}); err != nil {
+			log.Printf("Warning: Could not acknowledge notification: %v", err)
+		}
+		changed()
+		w.Close()
+	})
+	acknowledge.Importance = widget.HighImportance
+	buttons.Add(acknowledge)
+
+	w.SetContent(container.NewPadded(container.NewBorder(
+		container.NewVBox(title, info, widget.NewSeparator()), // top
+		container.NewCenter(buttons),                          // bottom
+		nil,                                                   // left
+		nil,                                                   // right
+		container.NewVScroll(message),
+	)))
+	w.Resize(fyne.NewSize(420, 220))
+	w.CenterOnScreen()
+	w.Show()
+	w.RequestFocus()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// Urgencies of ntfy and Gotify messages, mapped onto notify features by pushSpec.setUrgency
+const (
+	pushLow     = iota // Non-blocking notification center toast (-native)
+	pushDefault        // Window with the default timeout
+	pushHigh           // Window that stays until acknowledged
+	pushUrgent         // Window that stays, and draws attention when ignored
+)
+
+// pushAttentionAfter is the -attention-after of urgent ntfy messages
+const pushAttentionAfter = "60s"
+
+// ntfyMessage is an ntfy publish request (https://docs.ntfy.sh/publish/), as posted in JSON
+// Attachments, icons, delays, e-mail and phone calls have no notify equivalent and are ignored
+type ntfyMessage struct {
+	Topic    string       `json:"topic"`
+	Message  string       `json:"message"`
+	Title    string       `json:"title"`
+	Tags     []string     `json:"tags"`
+	Priority int          `json:"priority"`
+	Click    string       `json:"click"`
+	Actions  []ntfyAction `json:"actions"`
+}
+
+// ntfyAction is an ntfy action button; only "view" (open a URL) maps to notify, as -link
+type ntfyAction struct {
+	Action string `json:"action"`
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+}
+
+// gotifyMessage is a Gotify message (POST /message of the Gotify API)
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority *int   `json:"priority"`
+	Extras   struct {
+		Notification struct {
+			Click struct {
+				URL string `json:"url"`
+			} `json:"click"`
+		} `json:"client::notification"`
+	} `json:"extras"`
+}
+
+// pushSpec is the notification spec built from an ntfy or Gotify message
+type pushSpec struct {
+	Version        int           `json:"version"`
+	Title          string        `json:"title,omitempty"`
+	Message        string        `json:"message"`
+	Link           string        `json:"link,omitempty"`
+	Category       string        `json:"category,omitempty"`
+	Timeout        *int          `json:"timeout,omitempty"`
+	AttentionAfter string        `json:"attention_after,omitempty"`
+	Delivery       *pushDelivery `json:"delivery,omitempty"`
+}
+
+// pushDelivery is the delivery section of a pushSpec
+type pushDelivery struct {
+	Mode string `json:"mode"`
+}
+
+// setUrgency maps an urgency onto delivery mode, timeout and attention
+func (p *pushSpec) setUrgency(urgency int) {
+	noTimeout := 0
+	switch urgency {
+	case pushLow:
+		p.Delivery = &pushDelivery{Mode: "native"}
+	case pushHigh:
+		p.Timeout = &noTimeout
+	case pushUrgent:
+		p.Timeout = &noTimeout
+		p.AttentionAfter = pushAttentionAfter
+	}
+}
+
+// setLink sets the link from an ntfy click or Gotify click URL, ignoring the URLs notify
+// cannot open (ntfy also allows mailto: and geo:)
+func (p *pushSpec) setLink(url string) {
+	if url == "" || p.Link != "" {
+		return
+	}
+	if err := notify.ValidateLink(url); err != nil {
+		log.Printf("Ignoring click URL: %v", err)
+		return
+	}
+	p.Link = url
+}
+
+// ntfySpec converts an ntfy message: the topic is the title when there is none, the first tag
+// that is a valid category name becomes the -category, and the click URL (or else the first
+// view action) becomes the -link
+func ntfySpec(m ntfyMessage) (pushSpec, error) {
+	spec := pushSpec{Version: 1, Title: m.Title, Message: m.Message}
+	if spec.Title == "" {
+		spec.Title = m.Topic
+	}
+	if spec.Message == "" {
+		spec.Message = "triggered" // What ntfy sends for an empty message
+	}
+	for _, tag := range m.Tags {
+		if category := strings.ToLower(strings.TrimSpace(tag)); validateCategory(category) == nil {
+			spec.Category = category
+			break
+		}
+	}
+	spec.setLink(m.Click)
+	for _, action := range m.Actions {
+		if action.Action == "view" {
+			spec.setLink(action.URL)
+		}
+	}
+
+	switch {
+	case m.Priority == 0 || m.Priority == 3:
+		spec.setUrgency(pushDefault)
+	case m.Priority == 1 || m.Priority == 2:
+		spec.setUrgency(pushLow)
+	case m.Priority == 4:
+		spec.setUrgency(pushHigh)
+	case m.Priority == 5:
+		spec.setUrgency(pushUrgent)
+	default:
+		return spec, fmt.Errorf("invalid ntfy priority %d (use 1-5)", m.Priority)
+	}
+	return spec, nil
+}
+
+// parseNtfyPriority parses an X-Priority header: 1-5 or min, low, default, high, max/urgent
+func parseNtfyPriority(value string) (int, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return 0, nil
+	case "min":
+		return 1, nil
+	case "low":
+		return 2, nil
+	case "default":
+		return 3, nil
+	case "high":
+		return 4, nil
+	case "max", "urgent":
+		return 5, nil
+	}
+	priority, err := strconv.Atoi(value)
+	if err != nil || priority < 1 || priority > 5 {
+		return 0, fmt.Errorf("invalid ntfy priority %q (use 1-5, min, low, default, high, max or urgent)", value)
+	}
+	return priority, nil
+}
+
+// gotifySpec converts a Gotify message: priority 0-3 is a toast, 4-7 a window, 8-10 a window
+// that stays until acknowledged; extras client::notification click.url becomes the -link
+func gotifySpec(m gotifyMessage) (pushSpec, error) {
+	spec := pushSpec{Version: 1, Title: m.Title, Message: m.Message}
+	if spec.Title == "" {
+		spec.Title = notify.DefaultTitle // Gotify shows the application name, notify has none
+	}
+	if spec.Message == "" {
+		return spec, fmt.Errorf("message is required")
+	}
+	spec.setLink(m.Extras.Notification.Click.URL)
+	urgency := pushDefault
+	if m.Priority != nil {
+		switch p := *m.Priority; {
+		case p < 0 || p > 10:
+			return spec, fmt.Errorf("invalid Gotify priority %d (use 0-10)", p)
+		case p <= 3:
+			urgency = pushLow
+		case p >= 8:
+			urgency = pushHigh
+		}
+	}
+	spec.setUrgency(urgency)
+	return spec, nil
+}
+
+// handleNtfy accepts ntfy publish requests, so ntfy publishers can use notify serve as their
+// server: JSON to /ntfy, or the message body to /ntfy/TOPIC with X-Title, X-Priority,
+// X-Tags and X-Click headers (or the same query parameters)
+func (s *notifyServer) handleNtfy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		writeJSONError(w, http.StatusMethodNotAllowed, "use POST or PUT")
+		return
+	}
+	data, ok := readRequestBody(w, r)
+	if !ok {
+		return
+	}
+
+	var m ntfyMessage
+	topic := strings.Trim(strings.TrimPrefix(r.URL.Path, "/ntfy"), "/")
+	if topic == "" {
+		if err := json.Unmarshal(data, &m); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid ntfy JSON message: %v", err))
+			return
+		}
+	} else {
+		if strings.Contains(topic, "/") {
+			writeJSONError(w, http.StatusNotFound, "publish to /ntfy/TOPIC")
+			return
+		}
+		priority, err := parseNtfyPriority(ntfyParam(r, "X-Priority", "Priority", "prio", "p"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		m = ntfyMessage{
+			Topic:    topic,
+			Message:  strings.TrimSpace(string(data)),
+			Title:    ntfyParam(r, "X-Title", "Title", "ti", "t"),
+			Priority: priority,
+			Click:    ntfyParam(r, "X-Click", "Click"),
+		}
+		if m.Message == "" {
+			m.Message = ntfyParam(r, "X-Message", "Message", "m")
+		}
+		if tags := ntfyParam(r, "X-Tags", "Tags", "Tag", "ta"); tags != "" {
+			m.Tags = strings.Split(tags, ",")
+		}
+	}
+
+	spec, err := ntfySpec(m)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	specData, err := json.Marshal(spec)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.show(w, specData, "", func(id string) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":       id,
+			"time":     time.Now().Unix(),
+			"event":    "message",
+			"topic":    m.Topic,
+			"title":    m.Title,
+			"message":  spec.Message,
+			"priority": m.Priority,
+			"tags":     m.Tags,
+			"click":    m.Click,
+		})
+	})
+}
+
+// ntfyParam returns the first of the ntfy header names (and their query parameter forms) that is set
+func ntfyParam(r *http.Request, names ...string) string {
+	for _, name := range names {
+		if value := r.Header.Get(name); value != "" {
+			return value
+		}
+	}
+	query := r.URL.Query()
+	for _, name := range names {
+		if value := query.Get(strings.ToLower(strings.TrimPrefix(name, "X-"))); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// handleGotify accepts Gotify messages at /gotify/message, so Gotify publishers can use
+// notify serve as their server; the body is JSON or a form, as for Gotify
+func (s *notifyServer) handleGotify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	var m gotifyMessage
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		data, ok := readRequestBody(w, r)
+		if !ok {
+			return
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid Gotify message: %v", err))
+			return
+		}
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, serveRequestLimit)
+		err := r.ParseForm()
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			err = r.ParseMultipartForm(serveRequestLimit) // curl -F, as in the Gotify documentation
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid Gotify message: %v", err))
+			return
+		}
+		m.Title, m.Message = r.PostForm.Get("title"), r.PostForm.Get("message")
+		if value := r.PostForm.Get("priority"); value != "" {
+			priority, err := strconv.Atoi(value)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid Gotify priority %q", value))
+				return
+			}
+			m.Priority = &priority
+		}
+	}
+
+	spec, err := gotifySpec(m)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	specData, err := json.Marshal(spec)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.show(w, specData, "", func(id string) {
+		// Gotify clients expect a numeric ID; the notify ID is in the Location header
+		s.mu.Lock()
+		s.gotifyIDs++
+		gotifyID := s.gotifyIDs
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":       gotifyID,
+			"appid":    0,
+			"title":    m.Title,
+			"message":  m.Message,
+			"priority": m.Priority,
+			"date":     time.Now().Format(time.RFC3339),
+		})
+	})
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
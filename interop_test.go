@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestNtfySpec tests mapping ntfy priority, tags, click and view actions onto a spec
+func TestNtfySpec(t *testing.T) {
+	spec, err := ntfySpec(ntfyMessage{
+		Topic:    "backups",
+		Priority: 5,
+		Tags:     []string{"Warning Sign", "Security", "skull"},
+		Actions:  []ntfyAction{{Action: "http", URL: "https://ci.example.com/retry"}, {Action: "view", URL: "https://ci.example.com/run/42"}},
+	})
+	if err != nil {
+		t.Fatalf("ntfySpec failed: %v", err)
+	}
+	if spec.Title != "backups" || spec.Message != "triggered" || spec.Category != "security" || spec.Link != "https://ci.example.com/run/42" {
+		t.Errorf("Unexpected spec %+v", spec)
+	}
+	if spec.Timeout == nil || *spec.Timeout != 0 || spec.AttentionAfter != pushAttentionAfter || spec.Delivery != nil {
+		t.Errorf("Priority 5 should stay until acknowledged and draw attention, got %+v", spec)
+	}
+
+	spec, _ = ntfySpec(ntfyMessage{Title: "Done", Message: "Build passed", Priority: 2, Click: "mailto:ops@example.com"})
+	if spec.Delivery == nil || spec.Delivery.Mode != "native" || spec.Timeout != nil || spec.Link != "" {
+		t.Errorf("Priority 2 should be a toast without a mailto link, got %+v", spec)
+	}
+	if _, err := ntfySpec(ntfyMessage{Message: "x", Priority: 7}); err == nil {
+		t.Error("Expected an error for priority 7")
+	}
+
+	for value, want := range map[string]int{"": 0, "min": 1, "default": 3, "URGENT": 5, "4": 4} {
+		if got, err := parseNtfyPriority(value); err != nil || got != want {
+			t.Errorf("parseNtfyPriority(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+	if _, err := parseNtfyPriority("6"); err == nil {
+		t.Error("Expected an error for priority 6")
+	}
+}
+
+// TestGotifySpec tests mapping Gotify priorities and the click URL onto a spec
+func TestGotifySpec(t *testing.T) {
+	priority := func(p int) *int { return &p }
+	m := gotifyMessage{Title: "Disk", Message: "Disk almost full", Priority: priority(9)}
+	m.Extras.Notification.Click.URL = "https://grafana.example.com/d/disk"
+	spec, err := gotifySpec(m)
+	if err != nil {
+		t.Fatalf("gotifySpec failed: %v", err)
+	}
+	if spec.Link != m.Extras.Notification.Click.URL || spec.Timeout == nil || *spec.Timeout != 0 || spec.AttentionAfter != "" {
+		t.Errorf("Unexpected spec %+v", spec)
+	}
+	if spec, _ := gotifySpec(gotifyMessage{Message: "x", Priority: priority(2)}); spec.Delivery == nil {
+		t.Errorf("Priority 2 should be a toast, got %+v", spec)
+	}
+	if spec, _ := gotifySpec(gotifyMessage{Message: "x"}); spec.Delivery != nil || spec.Timeout != nil {
+		t.Errorf("No priority should be a default window, got %+v", spec)
+	}
+	for _, m := range []gotifyMessage{{Title: "No message"}, {Message: "x", Priority: priority(11)}} {
+		if _, err := gotifySpec(m); err == nil {
+			t.Errorf("gotifySpec(%+v) should fail", m)
+		}
+	}
+}
+
+// TestServeInterop tests the ntfy and Gotify endpoints: authorization, and the specs they launch
+func TestServeInterop(t *testing.T) {
+	var launched string
+	server := &notifyServer{
+		tokens: []serveToken{{token: "secret", scopes: []string{scopeManage}}},
+		launch: func(specPath string, done func(*NotificationResult, error)) error {
+			data, _ := os.ReadFile(specPath)
+			os.Remove(specPath)
+			launched = string(data)
+			go done(&NotificationResult{Action: actionAcknowledged}, nil)
+			return nil
+		},
+	}
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+
+	post := func(path, body string, header map[string]string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, httpServer.URL+path, strings.NewReader(body))
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("/ntfy/backups", "Backup failed", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("ntfy without token: status %d, want 401", resp.StatusCode)
+	}
+	resp := post("/ntfy/backups", "Backup failed", map[string]string{"Authorization": "Bearer secret", "X-Title": "Backups", "X-Priority": "high", "X-Tags": "ops"})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Location") == "" {
+		t.Errorf("ntfy publish: status %d, Location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if !strings.Contains(launched, `"title":"Backups"`) || !strings.Contains(launched, `"timeout":0`) || !strings.Contains(launched, `"category":"ops"`) {
+		t.Errorf("Unexpected spec from ntfy: %s", launched)
+	}
+
+	if resp := post("/ntfy", `{"topic":"ci","message":"Deployed","priority":9}`, map[string]string{"Authorization": "Bearer secret"}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("ntfy JSON with priority 9: status %d, want 400", resp.StatusCode)
+	}
+
+	form := url.Values{"title": {"Disk"}, "message": {"Disk almost full"}, "priority": {"1"}}.Encode()
+	formHeader := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	if resp := post("/gotify/message?token=wrong", form, formHeader); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Gotify with a wrong token: status %d, want 401", resp.StatusCode)
+	}
+	if resp := post("/gotify/message?token=secret", form, formHeader); resp.StatusCode != http.StatusOK {
+		t.Errorf("Gotify form message: status %d", resp.StatusCode)
+	}
+	if !strings.Contains(launched, `"mode":"native"`) {
+		t.Errorf("Unexpected spec from Gotify: %s", launched)
+	}
+	multipart := "--b\r\nContent-Disposition: form-data; name=\"message\"\r\n\r\nDisk full\r\n--b--\r\n"
+	if resp := post("/gotify/message?token=secret", multipart, map[string]string{"Content-Type": "multipart/form-data; boundary=b"}); resp.StatusCode != http.StatusOK {
+		t.Errorf("Gotify multipart message: status %d", resp.StatusCode)
+	}
+	resp = post("/gotify/message", `{"message":"Reboot tonight","priority":8}`, map[string]string{"X-Gotify-Key": "secret", "Content-Type": "application/json"})
+	if resp.StatusCode != http.StatusOK || !strings.Contains(launched, `"timeout":0`) {
+		t.Errorf("Gotify JSON message: status %d, spec %s", resp.StatusCode, launched)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Inventory holds optional machine details that let a central console join
+// acknowledgments to CMDB records without a separate agent call
+type Inventory struct {
+	Hostname      string   `json:"hostname"`
+	Serial        string   `json:"serial,omitempty"`
+	OS            string   `json:"os"`
+	OSBuild       string   `json:"os_build,omitempty"`
+	Arch          string   `json:"arch"`
+	LoggedInUsers []string `json:"logged_in_users,omitempty"`
+}
+
+// collectInventory gathers the inventory fields for this machine
+// Fields that cannot be determined (e.g. serial without root) are left empty
+func collectInventory() *Inventory {
+	hostname, _ := os.Hostname()
+	return &Inventory{
+		Hostname:      hostname,
+		Serial:        getSerialNumber(),
+		OS:            runtime.GOOS,
+		OSBuild:       getOSBuild(),
+		Arch:          runtime.GOARCH,
+		LoggedInUsers: getLoggedInUsers(),
+	}
+}
+
+// getSerialNumber returns the hardware serial number from SMBIOS
+func getSerialNumber() string {
+	switch runtime.GOOS {
+	case "linux":
+		// Readable by root only on most distributions
+		if data, err := os.ReadFile("/sys/class/dmi/id/product_serial"); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	case "darwin":
+		output, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+		if err != nil {
+			return ""
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			if strings.Contains(line, "IOPlatformSerialNumber") {
+				// Format: "IOPlatformSerialNumber" = "C02XXXXXXXXX"
+				parts := strings.Split(line, "\"")
+				if len(parts) >= 4 {
+					return parts[3]
+				}
+			}
+		}
+	case "windows":
+		output, err := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+			"(Get-CimInstance -ClassName Win32_BIOS).SerialNumber").Output()
+		if err == nil {
+			return strings.TrimSpace(string(output))
+		}
+	}
+	return ""
+}
+
+// getOSBuild returns a human-readable operating system version and build
+func getOSBuild() string {
+	switch runtime.GOOS {
+	case "linux":
+		prettyName := ""
+		if data, err := os.ReadFile("/etc/os-release"); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if strings.HasPrefix(line, "PRETTY_NAME=") {
+					prettyName = strings.Trim(strings.TrimPrefix(line, "PRETTY_NAME="), "\"")
+				}
+			}
+		}
+		kernel := ""
+		if output, err := exec.Command("uname", "-r").Output(); err == nil {
+			kernel = strings.TrimSpace(string(output))
+		}
+		return strings.TrimSpace(prettyName + " " + kernel)
+	case "darwin":
+		version, _ := exec.Command("sw_vers", "-productVersion").Output()
+		build, _ := exec.Command("sw_vers", "-buildVersion").Output()
+		return strings.TrimSpace("macOS " + strings.TrimSpace(string(version)) + " " + strings.TrimSpace(string(build)))
+	case "windows":
+		// Output: "Microsoft Windows [Version 10.0.19045.4170]"
+		if output, err := exec.Command("cmd", "/c", "ver").Output(); err == nil {
+			return strings.TrimSpace(string(output))
+		}
+	}
+	return ""
+}
+
+// getLoggedInUsers returns the unique usernames with a session on this machine
+func getLoggedInUsers() []string {
+	seen := map[string]bool{}
+	var users []string
+
+	var output []byte
+	var err error
+	if runtime.GOOS == "windows" {
+		output, err = exec.Command("quser").Output()
+	} else {
+		output, err = exec.Command("who").Output()
+	}
+	if err != nil {
+		return users
+	}
+
+	for i, line := range strings.Split(string(output), "\n") {
+		// quser prints a header line, who does not
+		if runtime.GOOS == "windows" && i == 0 {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		username := strings.TrimPrefix(fields[0], ">")
+		if !seen[username] {
+			seen[username] = true
+			users = append(users, username)
+		}
+	}
+
+	sort.Strings(users)
+	return users
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"unicode/utf8"
+)
+
+// maxJournalEntrySize caps a journalctl JSON line; larger entries (core dumps and the like) are skipped
+const maxJournalEntrySize = 1 << 20
+
+// runWatchJournal handles -watch-journal: follows the systemd journal with journalctl and shows
+// a notification for each new entry that passes -watch-match, until interrupted
+// Without root, journalctl only shows the entries of the user unless they are in the
+// systemd-journal or adm group
+func runWatchJournal(opts watchOptions) int {
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get executable path: %v\n", err)
+		return 1
+	}
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: journalctl not found: -watch-journal needs the systemd journal (Linux)")
+		return 1
+	}
+	cmd := exec.Command("journalctl", "--follow", "--lines=0", "--output=json")
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to run journalctl: %v\n", err)
+		return 1
+	}
+	filter := newWatchFilter(opts)
+
+	fmt.Println("Following the systemd journal (Ctrl+C to stop)")
+	reader := bufio.NewReaderSize(stdout, 64<<10)
+	for {
+		line, err := readJournalLine(reader)
+		if err != nil {
+			break
+		}
+		if line == nil {
+			log.Printf("Warning: Skipping a journal entry larger than %d bytes", maxJournalEntrySize)
+			continue
+		}
+		fields, err := parseJournalEntry(line)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		if opts.rules.match(fields) {
+			showTriggered(exePath, opts.rules.spec(fields), filter, opts.debug)
+		}
+	}
+	err = cmd.Wait()
+	fmt.Fprintf(os.Stderr, "Error: journalctl stopped: %v\n", err)
+	return 1
+}
+
+// readJournalLine returns the next line of journalctl output, nil for a line longer than
+// maxJournalEntrySize (which is consumed)
+func readJournalLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+			if len(line) > maxJournalEntrySize {
+				line, tooLong = nil, true
+			}
+		}
+		if !isPrefix {
+			break
+		}
+	}
+	if tooLong {
+		return nil, nil
+	}
+	return append([]byte{}, line...), nil
+}
+
+// parseJournalEntry returns the fields of a journalctl --output=json entry; binary fields,
+// which journalctl writes as arrays of bytes, are kept when they are valid UTF-8
+func parseJournalEntry(line []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, fmt.Errorf("invalid journal entry: %v", err)
+	}
+	fields := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if string(value) == "null" {
+			continue // Too large for journalctl to output
+		}
+		var text string
+		if err := json.Unmarshal(value, &text); err == nil {
+			fields[name] = text
+			continue
+		}
+		var data []byte
+		var bytes []int
+		if err := json.Unmarshal(value, &bytes); err == nil {
+			for _, b := range bytes {
+				data = append(data, byte(b))
+			}
+			if utf8.Valid(data) {
+				fields[name] = string(data)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// TestParseJournalEntry tests text fields, binary fields and bad entries of journalctl --output=json
+func TestParseJournalEntry(t *testing.T) {
+	fields, err := parseJournalEntry([]byte(`{"MESSAGE": "Started backup.service", "PRIORITY": "6", "_SYSTEMD_UNIT": "init.scope", "BINARY": [104, 105], "INVALID": [255, 254], "TOO_LARGE": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"MESSAGE": "Started backup.service", "PRIORITY": "6", "_SYSTEMD_UNIT": "init.scope", "BINARY": "hi"}
+	if len(fields) != len(want) {
+		t.Errorf("parseJournalEntry = %v, want %v", fields, want)
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("%s = %q, want %q", name, fields[name], value)
+		}
+	}
+	if _, err := parseJournalEntry([]byte(`-- No entries --`)); err == nil {
+		t.Error("parseJournalEntry accepted a line that is not JSON")
+	}
+}
+
+// TestReadJournalLine tests that oversized entries are skipped without losing the next one
+func TestReadJournalLine(t *testing.T) {
+	input := `{"MESSAGE": "one"}` + "\n" + strings.Repeat("x", maxJournalEntrySize+10) + "\n" + `{"MESSAGE": "two"}` + "\n"
+	reader := bufio.NewReaderSize(strings.NewReader(input), 4096)
+	for i, want := range []string{`{"MESSAGE": "one"}`, "", `{"MESSAGE": "two"}`} {
+		line, err := readJournalLine(reader)
+		if err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if want == "" && line != nil {
+			t.Errorf("line %d has %d bytes, want it skipped", i, len(line))
+		} else if want != "" && string(line) != want {
+			t.Errorf("line %d = %q, want %q", i, line, want)
+		}
+	}
+	if _, err := readJournalLine(reader); err == nil {
+		t.Error("readJournalLine did not report the end of the output")
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// getMachineID returns a stable, anonymized identifier for this machine
+// The platform's own machine identifier is preferred so the ID survives reinstalls,
+// falling back to a random ID persisted in the config directory.
+// The raw identifier is never exposed directly - it is hashed with an app-specific prefix
+func getMachineID() string {
+	rawID := readPlatformMachineID()
+	if rawID == "" {
+		rawID = readOrCreateStoredMachineID()
+	}
+	if rawID == "" {
+		// Last resort: hostname is at least stable between runs
+		rawID, _ = os.Hostname()
+	}
+
+	sum := sha256.Sum256([]byte("krankybearnotify:" + rawID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// readPlatformMachineID reads the operating system's machine identifier
+// Linux: /etc/machine-id (systemd) or the dbus machine-id
+// macOS: IOPlatformUUID from the IOPlatformExpertDevice
+// Windows: MachineGuid from HKLM\SOFTWARE\Microsoft\Cryptography
+func readPlatformMachineID() string {
+	switch runtime.GOOS {
+	case "linux":
+		for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+			if data, err := os.ReadFile(path); err == nil {
+				if id := strings.TrimSpace(string(data)); id != "" {
+					return id
+				}
+			}
+		}
+	case "darwin":
+		output, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+		if err != nil {
+			return ""
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			if strings.Contains(line, "IOPlatformUUID") {
+				// Format: "IOPlatformUUID" = "XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX"
+				parts := strings.Split(line, "\"")
+				if len(parts) >= 4 {
+					return parts[3]
+				}
+			}
+		}
+	case "windows":
+		output, err := exec.Command("reg", "query", `HKLM\SOFTWARE\Microsoft\Cryptography`, "/v", "MachineGuid").Output()
+		if err != nil {
+			return ""
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.Fields(line)
+			// Format: MachineGuid    REG_SZ    xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+			if len(fields) == 3 && fields[0] == "MachineGuid" {
+				return fields[2]
+			}
+		}
+	}
+	return ""
+}
+
+// readOrCreateStoredMachineID returns the machine ID stored in the config directory,
+// generating and persisting a new random one if none exists yet
+func readOrCreateStoredMachineID() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		log.Printf("Warning: Could not determine config directory for machine ID: %v", err)
+		return ""
+	}
+	idPath := filepath.Join(configDir, "krankybearnotify", "machine-id")
+
+	if data, err := os.ReadFile(idPath); err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id
+		}
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Warning: Could not generate machine ID: %v", err)
+		return ""
+	}
+	id := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(idPath), 0755); err != nil {
+		log.Printf("Warning: Could not create config directory: %v", err)
+		return id
+	}
+	if err := os.WriteFile(idPath, []byte(id+"\n"), 0644); err != nil {
+		log.Printf("Warning: Could not persist machine ID: %v", err)
+	}
+	return id
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,23 +13,13 @@ import (
 	"strings"
 	"time"
 
-	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/app"
-	"fyne.io/fyne/v2/canvas"
-	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/storage"
-	"fyne.io/fyne/v2/widget"
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
 	updatechecker "github.com/amarillier/go-update-checker"
 )
 
 const (
-	appVersion     = "0.1.2"
-	appAuthor      = "Allan Marillier"
-	defaultTitle   = "Notification"
-	defaultMessage = "This is a notification message"
-	defaultTimeout = 10  // seconds
-	defaultWidth   = 400 // pixels
-	defaultHeight  = 250 // pixels
+	appVersion = "0.1.2"
+	appAuthor  = "Allan Marillier"
 )
 
 var appCopyright = "Copyright (c) Allan Marillier, 2024-" + strconv.Itoa(time.Now().Year())
@@ -42,11 +31,37 @@ func init() {
 A cross-platform notification application for Mac, Windows, and Linux
 
 USAGE:
-  %s [OPTIONS]
-
-OPTIONS:
+  %[2]s [send] [OPTIONS]
+  %[2]s COMMAND [ARGUMENTS]
+
+COMMANDS:
+  send               Show a notification (the default when the first argument is a flag)
+  check NAME         Check gui, opengl, webview, wall, deps, elevation, permissions, arch or dnd and exit
+  serve              Accept notification specs over HTTP (see notify serve -h)
+  remote -hosts H,H  Show a notification on other machines over SSH (see notify remote -h)
+  agent              Stay connected to a notify serve -controller and show what it pushes
+  verify -host NAME  Show a test notification on one machine through notify serve or an agent, and report it
+  subscribe TOPIC    Show a notification for each message of ntfy topics (topic@server) or -gotify URL
+  run -- COMMAND     Run a command, then notify whether it succeeded (see notify run -h)
+  state ID STATE     Change the state icon (and message) of the window shown with -state-id
+  ack [FILE]         Acknowledge the windows shown with -ack-file (bind it to a keyboard shortcut)
+  demo [NAME...]     Show a gallery of notifications to check theming, fonts and sound on this machine
+  support-bundle     Collect logs, checks and redacted config files into a zip for a support ticket
+  update             Check for updates
+  version            Show version information
+  status             Show agent health and pending notifications
+  inbox [-tray]      Open the notification inbox, or keep it in the system tray
+  history            List and search the notifications delivered to this user (see notify history -h)
+  stats              Acknowledgment rates per A/B variant from -result-json output
+  optout             Opt out of notification categories (list, add, remove)
+  validate-spec      Check YAML notification specs against the schema
+  test-e2e           Run end-to-end delivery tests
+  breakglass         Create the break-glass key or sign a token (keygen, sign)
+  activate URI       Record a click on a Windows toast (launched through the krankybearnotify: protocol)
+
+OPTIONS (send):
 `, appVersion, os.Args[0])
-		flag.PrintDefaults()
+		printVisibleDefaults(flag.CommandLine)
 		fmt.Fprintf(os.Stderr, `
 EXAMPLES:
   # Show a simple notification
@@ -58,17 +73,17 @@ EXAMPLES:
   # Custom button text
   %s -title "Confirm" -message "Please review" -button "Got it!" -timeout 0
 
-  # URL-encoded parameters (automatically decoded)
-  %s -title "Build%%20Complete" -message "Path:%%20/home/user%%2Fproject" -icon "%%2Fpath%%2Fto%%2Ficon.png"
+  # Percent-encoded parameters (decoded with -encoded)
+  %s -encoded -title "Build%%20Complete" -message "Path:%%20/home/user%%2Fproject" -icon "%%2Fpath%%2Fto%%2Ficon.png"
 
   # Check if GUI is available (useful for scripts)
-  %s -check-gui
+  %s check gui
 
   # Check for missing runtime dependencies (Linux)
-  %s -check-deps
+  %s check deps
 
   # Check for updates
-  %s -cu
+  %s update
 
   # Notification that stays until manually closed
   %s -title "Important" -message "Please review" -timeout 0
@@ -76,7 +91,7 @@ EXAMPLES:
   # Windows: Force MessageBox mode (for VMs where OpenGL fails)
   %s -win-basic -title "VM Alert" -message "Uses Windows MessageBox"
 
-  # Windows: Force WebView mode (better UI, requires webview build)
+  # Windows: Force WebView mode (better UI, needs the WebView2 Runtime)
   %s -win-webview -title "Modern Alert" -message "Uses HTML/CSS/JS"
 
   # Linux: Send to GUI users only (no wall broadcast)
@@ -96,6 +111,22 @@ For more information, visit: https://github.com/amarillier/krankybearnotify
 	}
 }
 
+// hiddenFlags are left out of the usage: hooks for testing, not for the notifications users see
+var hiddenFlags = map[string]bool{"automation": true}
+
+// printVisibleDefaults prints the defaults of the flags of fs, except hiddenFlags
+func printVisibleDefaults(fs *flag.FlagSet) {
+	visible := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	visible.SetOutput(fs.Output())
+	fs.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			visible.Var(f.Value, f.Name, f.Usage)
+			visible.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	visible.PrintDefaults()
+}
+
 func main() {
 	// CRITICAL: Handle version flag BEFORE any other code runs
 	// This prevents Fyne GUI initialization which can hang in some environments
@@ -114,7 +145,7 @@ func main() {
 			}
 		}
 		if hasTargetUser && !hasDebug {
-			hideConsoleWindow()
+			notify.HideConsoleWindow()
 		}
 	}
 
@@ -131,26 +162,71 @@ func main() {
 	// Quick pre-check for version flag to avoid GUI initialization
 	for _, arg := range os.Args[1:] {
 		if arg == "-version" || arg == "--version" {
-			// Declare glibcver outside the Linux-specific blocks so it's in scope for both
-			glibcver := ""
-			if runtime.GOOS == "linux" {
-				glibcVer, glibcErr := getGlibcVersion()
-				if glibcErr != nil {
-					glibcver = "(glibc version undetected)"
-				} else {
-					glibcver = glibcVer
-				}
-			}
-			fmt.Printf("Notify: v%s\n", appVersion)
-			if runtime.GOOS == "linux" {
-				fmt.Printf("Platform: %s/%s (glibc %s)\n", runtime.GOOS, runtime.GOARCH, glibcver)
-			} else {
-				fmt.Printf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
-			}
-			fmt.Printf("Copyright: %s\n", appCopyright)
-			fmt.Println("License: GNU GPL-3.0")
-			fmt.Println("Source: https://github.com/amarillier/krankybearnotify")
-			fmt.Println("Documentation: https://github.com/amarillier/krankybearnotify/blob/main/README.md")
+			printVersion()
+			os.Exit(0)
+		}
+	}
+
+	// An emulated binary (Rosetta 2, x64 on Windows on ARM) hands everything to the native build
+	// next to it before anything else starts
+	if hasPreferNativeArch(os.Args[1:]) {
+		if code, ok := runNativeBuild(os.Args[1:]); ok {
+			os.Exit(code)
+		}
+	}
+
+	// Subcommands; anything else (flags, or "send" followed by flags) shows a notification
+	sendCommand := false
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "send":
+			// "notify send -title ..." is the legacy flat invocation with the command name removed
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+			sendCommand = true
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "remote":
+			os.Exit(runRemote(os.Args[2:]))
+		case "agent":
+			os.Exit(runAgent(os.Args[2:]))
+		case "verify":
+			os.Exit(runVerify(os.Args[2:]))
+		case "subscribe":
+			os.Exit(runSubscribe(os.Args[2:]))
+		case "activate":
+			os.Exit(runActivate(os.Args[2:]))
+		case "run":
+			os.Exit(runJob(os.Args[2:]))
+		case "state":
+			os.Exit(runState(os.Args[2:]))
+		case "ack":
+			os.Exit(runAck(os.Args[2:]))
+		case "demo":
+			os.Exit(runDemo(os.Args[2:]))
+		case "support-bundle":
+			os.Exit(runSupportBundle(os.Args[2:]))
+		case "optout":
+			os.Exit(runOptOut(os.Args[2:]))
+		case "stats":
+			os.Exit(runStats(os.Args[2:]))
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		case "inbox":
+			os.Exit(runInbox(os.Args[2:]))
+		case "test-e2e":
+			os.Exit(runE2ETests(os.Args[2:]))
+		case "validate-spec":
+			os.Exit(validateSpecFiles(os.Args[2:]))
+		case "status":
+			os.Exit(showStatus(os.Args[2:]))
+		case "breakglass":
+			os.Exit(runBreakGlass(os.Args[2:]))
+		case "update":
+			os.Exit(runUpdateCheck())
+		case "version":
+			printVersion()
 			os.Exit(0)
 		}
 	}
@@ -165,35 +241,80 @@ func main() {
 		}
 	}
 
-	// Show help if no arguments provided at all
-	if len(os.Args) == 1 {
+	// Show help if no arguments provided at all ("notify send" alone shows the default notification)
+	if len(os.Args) == 1 && !sendCommand {
 		showHelp = true
 	}
 
 	// Command-line flags
-	title := flag.String("title", defaultTitle, "Notification title (URL/percent-encoded characters will be decoded)")
-	message := flag.String("message", defaultMessage, "Notification message (URL/percent-encoded characters will be decoded)")
-	buttonText := flag.String("button", "OK", "Button text (URL/percent-encoded characters will be decoded)")
-	timeout := flag.Int("timeout", defaultTimeout, "Timeout in seconds (0 for no timeout)")
-	width := flag.Int("width", defaultWidth, "Window width in pixels")
-	height := flag.Int("height", defaultHeight, "Window height in pixels")
+	// The notification itself (title, message, button, timeout, size, icon) is shared by every backend
+	var n notify.Notification
+	notify.BindFlags(flag.CommandLine, &n)
+	messageFile := flag.String("message-file", "", "Read the notification message from this text file (UTF-8, or UTF-16 with a byte order mark), for long or multi-line messages")
+	followUpDelay := flag.Duration("followup-delay", 0, "Internal: Wait this long before showing (set when launched as a follow-up)")
+	followUpDepth := flag.Int("followup-depth", 0, "Internal: Position in a follow-up chain")
+	businessHours := flag.String("business-hours", "", "Only deliver during business hours, e.g. \"Mon-Fri 09:00-17:00\" (in -tz or local time), waiting for the next window")
+	calendarSource := flag.String("calendar", "", "Work calendar (ICS file or http(s) ICS/CalDAV URL) whose events, e.g. holidays, -business-hours skips")
+	at := flag.String("at", "", "Show the notification at this time, e.g. \"2024-12-01 09:00\" or 17:00 (in -tz or local time); a waiting notify process shows it and notify returns right away")
+	delay := flag.Duration("delay", 0, "Show the notification after this long, e.g. 30m; a waiting notify process shows it and notify returns right away")
+	scheduledAt := flag.String("scheduled-at", "", "Internal: Wait until this RFC 3339 time before showing (set when armed with -at or -delay)")
+	deliverBy := flag.String("deliver-by", "", "Deadline for acknowledgment, e.g. 17:00 (in -tz or local time): escalates from a toast to windows to wall as it approaches")
+	every := flag.Duration("every", 0, "Show the notification again this long after each showing, e.g. 1h, until it is acknowledged (see -stop-on, -count and -until)")
+	count := flag.Int("count", 0, "With -every: show the notification at most this many times (0 for no limit)")
+	until := flag.String("until", "", "With -every: do not show the notification after this time, e.g. \"2024-12-01 17:00\" or 17:00 (in -tz or local time)")
+	stopOn := flag.String("stop-on", "", "With -every and -choices: comma-separated choices that stop the reminders (default: any acknowledgment)")
+	dryRun := flag.Bool("dry-run", false, "Print when and whether the notification would be delivered, then exit without showing it")
+	plan := flag.Bool("plan", false, "Print the delivery mechanisms and fallbacks for -os and -session, then exit without showing anything")
+	planOS := flag.String("os", "", "With -plan: target operating system (windows, macos, linux; default: this one)")
+	planSession := flag.String("session", "", "With -plan: target session (desktop, rdp, ssh, system, headless; default: desktop)")
+	configPath := flag.String("config", "", "Config file with defaults (default: ~/.config/krankybearnotify/config.yaml, then /etc/krankybearnotify.yaml)")
+	specPath := flag.String("spec", "", "YAML notification spec file (see schema/notification-spec.schema.json), command-line flags override it")
+	encoded := flag.Bool("encoded", false, "Decode percent-encoded -title, -message, -html, -button and -icon values (e.g. %20 for a space)")
+	legacyDecode := flag.Bool("legacy-decode", false, "Decode -title, -message, -button and -icon like versions before -encoded (URL query decoding, + becomes a space)")
 	autosize := flag.Bool("autosize", false, "Auto-size window based on message length (max 600x400)")
 	checkGUI := flag.Bool("check-gui", false, "Check if GUI mode is available and exit")
 	checkOpenGL := flag.Bool("check-opengl", false, "Check if OpenGL is available and exit")
+	checkWebViewFlag := flag.Bool("check-webview", false, "Check if WebView mode is available (build and runtime) and exit")
 	checkWall := flag.Bool("check-wall", false, "Check if wall broadcast is available (Linux) and exit")
 	checkDeps := flag.Bool("check-deps", false, "Check for missing runtime dependencies (Linux) and exit")
+	checkElevationFlag := flag.Bool("check-elevation", false, "Report elevation state (system, elevated, filtered, standard) and exit")
+	checkPermissionsFlag := flag.Bool("check-permissions", false, "macOS: Check privacy permissions (notifications, automation, screen recording), print the MDM profile granting them and exit")
 	winBasic := flag.Bool("win-basic", false, "Windows: Force basic mode (MessageBox instead of Fyne)")
-	winWebView := flag.Bool("win-webview", false, "Windows: Force WebView mode (requires -tags webview build)")
+	winWebView := flag.Bool("win-webview", false, "Windows: Force WebView mode (needs the WebView2 Runtime)")
+	flag.BoolVar(winWebView, "force-webview", false, "Force WebView mode on any platform (alias for -win-webview, requires -tags webview build on macOS and Linux)")
 	guiOnly := flag.Bool("gui-only", false, "Linux: Send to GUI users only (no wall broadcast)")
 	forceWall := flag.Bool("force-wall", false, "Linux: Force wall broadcast only (no GUI)")
+	gotifyFlag := flag.String("gotify", "", "Also publish the notification to this Gotify server URL ("+gotifyTokenEnv+" is the application token)")
+	ntfyFlag := flag.String("ntfy", "", "Also publish the notification to this ntfy topic, as topic@server (server defaults to ntfy.sh, "+ntfyTokenEnv+" is the access token) so phones receive it")
+	disconnected := flag.String("disconnected", notify.DisconnectedDeliverOnReconnect, "Windows: Policy for disconnected RDP/console sessions (skip, queue, deliver-on-reconnect)")
+	watchFile := flag.String("watch-file", "", "Tail this file and show a notification for each JSON notification spec appended to it, one per line (runs until interrupted)")
+	watchJournal := flag.Bool("watch-journal", false, "Linux: Follow the systemd journal and show a notification for each new entry matching -watch-match (runs until interrupted)")
+	watchSyslog := flag.String("watch-syslog", "", "Receive syslog messages on this UDP host:port or Unix datagram socket path and show a notification for each one matching -watch-match (runs until interrupted)")
+	var watchRules eventRules
+	flag.Func("watch-match", "With -watch-file, -watch-journal or -watch-syslog: only show events whose FIELD=glob, FIELD!=glob or FIELD~regexp, e.g. SYSLOG_IDENTIFIER=sshd (repeat to require several)", func(expr string) error {
+		m, err := parseEventMatch(expr)
+		if err == nil {
+			watchRules.matches = append(watchRules.matches, m)
+		}
+		return err
+	})
+	flag.StringVar(&watchRules.title, "watch-title", defaultEventTitle, "With -watch-journal or -watch-syslog: notification title, {{FIELD}} is replaced with the field of the event")
+	flag.StringVar(&watchRules.message, "watch-message", defaultEventMessage, "With -watch-journal or -watch-syslog: notification message, {{FIELD}} is replaced with the field of the event")
+	watchDedupe := flag.Duration("watch-dedupe", 10*time.Minute, "With -watch-file, -watch-journal or -watch-syslog: skip a title and message already shown within this long (0 to show every one)")
+	watchRate := flag.Int("watch-rate", 6, "With -watch-file, -watch-journal or -watch-syslog: most notifications shown per minute, further events are skipped (0 for no limit)")
+	targetsPath := flag.String("targets", "", "CSV of users to notify when run as root/SYSTEM (username, session, title, message, {{column}} variables), with per-user results")
+	reconnectTask := flag.String("reconnect-task", "", "Internal: Scheduled task that launched this process, removed after the notification is shown")
+	checksum := flag.String("checksum", "", "Internal: Checksum of the notification from the process that launched this one, verified before showing it")
 	targetUser := flag.Bool("target-user", false, "Internal: Marks process as already running as target user (prevents re-elevation)")
+	quick := flag.Bool("quick", false, "Fast path: deliver with the lightest native mechanism (WTSSendMessage/notify-send/osascript) within 500ms, no GUI framework")
+	native := flag.Bool("native", false, "Send a non-blocking notification through the OS notification center (toast/Notification Center/libnotify) instead of opening a window")
+	flag.Bool(preferNativeArchFlag, false, "When running under emulation (Rosetta 2, x64 on Windows on ARM), run the native build next to this one instead (see notify check arch)")
 	debug := flag.Bool("debug", false, "Enable debug output (shows log messages)")
 	version := flag.Bool("version", false, "Show version information and exit")
-
-	// Icon flag with alias
-	var icon string
-	flag.StringVar(&icon, "icon", "", "Path to icon image file (PNG, JPEG, etc.) (URL/percent-encoded characters will be decoded)")
-	flag.StringVar(&icon, "image", "", "Path to icon image file (alias for -icon) (URL/percent-encoded characters will be decoded)")
+	rolloutPercent := flag.Int("rollout-percent", 100, "Percentage of machines (0-100) that display the notification, selected by hashed machine ID")
+	rolloutSalt := flag.String("rollout-salt", "", "Campaign name mixed into the rollout hash so each campaign selects different machines")
+	resultJSON := flag.Bool("result-json", false, "Print a JSON result (machine ID, action taken, timestamp) to stdout when the notification finishes")
+	includeInventory := flag.Bool("include-inventory", false, "Include inventory fields (hostname, serial, OS build, logged-in users) in the JSON result")
 
 	// Update checker flags (with alias)
 	var checkUpdate bool
@@ -206,8 +327,40 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Parse command-line flags (help/version already handled above)
-	flag.Parse()
+	// Parse command-line flags (help/version already handled above); a bad flag is reported
+	// like the other argument errors below
+	parseFlags(flag.CommandLine, os.Args[1:])
+	received := n // As the launching process sent it, for -checksum
+
+	// Values from a -spec file fill in any flags not given on the command line
+	var spec *NotificationSpec
+	if *specPath != "" {
+		var err error
+		spec, err = applySpec(flag.CommandLine, *specPath)
+		if err != nil {
+			argumentErrors{{Flag: "spec", Message: err.Error()}}.exitIfAny(*resultJSON, n.Title)
+		}
+	}
+
+	// -message-file replaces a -message from config files, not one given here or in the spec
+	messageGiven := false
+	flag.Visit(func(f *flag.Flag) {
+		messageGiven = messageGiven || f.Name == "message"
+	})
+
+	// Config files fill in whatever the command line and spec left unset
+	configPaths := defaultConfigPaths()
+	if *configPath != "" {
+		if _, err := os.Stat(*configPath); err != nil {
+			argumentErrors{{Flag: "config", Message: err.Error()}}.exitIfAny(*resultJSON, n.Title)
+		}
+		configPaths = []string{*configPath}
+	}
+	configs, err := applyConfigFiles(flag.CommandLine, configPaths)
+	if err != nil {
+		argumentErrors{{Flag: "config", Message: err.Error()}}.exitIfAny(*resultJSON, n.Title)
+	}
+	notify.SetIconCache(iconCache(configs))
 
 	// Suppress unused variable warning for targetUser
 	// This flag is checked in shouldShowToOtherUsers() via os.Args
@@ -234,671 +387,542 @@ func main() {
 		}
 	}
 
-	// URL decode title, message, button text, and icon parameters
-	// This handles percent-encoded characters like %2d (-), %2f (/), %20 (space), etc.
-	if decodedTitle, err := url.QueryUnescape(*title); err == nil {
-		*title = decodedTitle
-	} else {
-		log.Printf("Warning: Failed to URL decode title: %v", err)
-	}
-	if decodedMessage, err := url.QueryUnescape(*message); err == nil {
-		*message = decodedMessage
-	} else {
-		log.Printf("Warning: Failed to URL decode message: %v", err)
-	}
-	if decodedButtonText, err := url.QueryUnescape(*buttonText); err == nil {
-		*buttonText = decodedButtonText
-	} else {
-		log.Printf("Warning: Failed to URL decode button text: %v", err)
-	}
-	if icon != "" {
-		if decodedIcon, err := url.QueryUnescape(icon); err == nil {
-			icon = decodedIcon
-		} else {
-			log.Printf("Warning: Failed to URL decode icon path: %v", err)
+	// Trigger sources: each line appended to the -watch-file is a notification spec, each journal
+	// or syslog event is mapped to one; every notification is shown by its own notify process
+	if *watchFile != "" || *watchJournal || *watchSyslog != "" {
+		var problems argumentErrors
+		if *watchRate < 0 {
+			problems.add("watch-rate", "-watch-rate must be 0 or more")
 		}
-
-		// Add .png extension if no extension provided
-		// This ensures all modes (Fyne, WebView, MessageBox) get the same icon path processing
-		ext := filepath.Ext(icon)
-		if ext == "" {
-			icon = icon + ".png"
-			log.Printf("No extension provided, added .png: %s", icon)
+		if *watchFile != "" && (*watchJournal || *watchSyslog != "") || *watchJournal && *watchSyslog != "" {
+			problems.add("", "use only one of -watch-file, -watch-journal and -watch-syslog")
+		}
+		problems.exitIfAny(*resultJSON, "")
+		opts := watchOptions{dedupe: *watchDedupe, rate: *watchRate, debug: *debug, rules: watchRules}
+		switch {
+		case *watchJournal:
+			os.Exit(runWatchJournal(opts))
+		case *watchSyslog != "":
+			os.Exit(runWatchSyslog(*watchSyslog, opts))
 		}
+		os.Exit(runWatchFile(*watchFile, opts))
 	}
 
-	// Show version if requested
-	if *version {
-		fmt.Printf("Notify v%s\n", appVersion)
-		fmt.Printf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
-		fmt.Printf("Copyright: %s\n", appCopyright)
-		fmt.Println("License: MIT")
-		fmt.Println("Source: https://github.com/amarillier/krankybearnotify")
-		fmt.Println("Documentation: https://github.com/amarillier/krankybearnotify/blob/main/README.md")
-		os.Exit(0)
+	// A child launched in another user's session checks that the text survived the
+	// quoting of sudo, launchctl or the scheduled task that brought it here
+	if err := notify.VerifyChecksum(received, *checksum); err != nil {
+		log.Printf("Error: %v", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Check for updates if requested
-	if checkUpdate {
-		fmt.Printf("Checking for updates...\n")
-		fmt.Printf("Current version: %s\n\n", appVersion)
-
-		// Get executable directory for storing update check file
-		exePath, err := os.Executable()
-		if err != nil {
-			log.Printf("Warning: Could not determine executable path: %v", err)
-			exePath = "."
-		}
-		exeDir := filepath.Dir(exePath)
-		checkFilePath := filepath.Join(exeDir, "latestcheck.json")
-
-		// Save current directory and change to executable directory
-		originalDir, _ := os.Getwd()
-		os.Chdir(exeDir)
-		defer os.Chdir(originalDir)
-
-		updtmsg, updateAvailable := updateChecker("amarillier", "KrankyBearNotify", "Kranky Bear Notify", "https://github.com/amarillier/KrankyBearNotify/releases/latest")
+	// Decode -encoded (or -legacy-decode) values, then normalize the text (NFC, no control
+	// characters) so the policy, the store and every backend see what is displayed
+	decodeMode := decodeNone
+	if *encoded {
+		decodeMode = decodeEncoded
+	} else if *legacyDecode {
+		decodeMode = decodeLegacy
+	}
+	if err := decodeNotification(&n, decodeMode); err != nil {
+		argumentErrors{{Flag: "encoded", Message: err.Error()}}.exitIfAny(*resultJSON, "")
+	}
 
-		if updateAvailable {
-			fmt.Println(updtmsg)
-			fmt.Printf("\nUpdate check data saved to: %s\n", checkFilePath)
-			os.Exit(0)
+	// -message-file and -message - (or no message with a pipe or file on stdin) read the message,
+	// so long text and command output need no quoting; processes launched by this one get text
+	// from stdin as -message, and the waiting process of -at reads the -message-file when it shows it
+	messageSet := *messageFile != ""
+	flag.Visit(func(f *flag.Flag) {
+		messageSet = messageSet || f.Name == "message"
+	})
+	messageFromStdin := n.Message == stdinMessage && messageSet && *messageFile == ""
+	messageFromPipe := !messageSet && n.HTML == "" && n.Cancel == "" && !*targetUser && *followUpDepth == 0 && *scheduledAt == "" && stdinIsPiped()
+	switch {
+	case *messageFile != "" && messageGiven:
+		argumentErrors{{Flag: "message-file", Message: "use either -message or -message-file, not both"}}.exitIfAny(*resultJSON, n.Title)
+	case *messageFile != "":
+		if n.Message, err = readMessageFile(*messageFile); err != nil {
+			argumentErrors{{Flag: "message-file", Message: err.Error()}}.exitIfAny(*resultJSON, n.Title)
+		}
+	case messageFromStdin:
+		if n.Message, err = readStdinMessage(os.Stdin); err != nil {
+			argumentErrors{{Flag: "message", Message: err.Error()}}.exitIfAny(*resultJSON, n.Title)
+		}
+	case messageFromPipe:
+		// Without -message an empty or silent stdin keeps the default message
+		if message, err := readPipedMessage(os.Stdin, stdinWait); err != nil {
+			log.Printf("%v, showing the default message", err)
 		} else {
-			fmt.Println("You are running the latest version!")
-			fmt.Printf("Update check data saved to: %s\n", checkFilePath)
-			os.Exit(0)
+			n.Message = message
 		}
 	}
+	n = n.Normalize()
 
-	// Check dependencies if requested (Linux only)
-	if *checkDeps {
-		if runtime.GOOS == "linux" {
-			checkLinuxDependencies()
-		} else {
-			fmt.Println("Dependency check is only available on Linux")
-			os.Exit(1)
+	// -html is shown by WebView; the other backends show -message, or the text of the fragment
+	if n.HTML != "" {
+		if !messageSet {
+			n.Message = notify.HTMLText(n.HTML)
 		}
 	}
 
-	// Check GUI mode if requested
-	if *checkGUI {
-		if isGUIAvailable() {
-			fmt.Println("GUI mode is available")
-			// On Linux, also check for missing libraries
-			if runtime.GOOS == "linux" {
-				checkLinuxDependenciesQuiet()
-			}
-			os.Exit(0)
-		} else {
-			fmt.Println("GUI mode is not available")
-			os.Exit(1)
+	if n.IconPath != "" && !notify.IsIconURL(n.IconPath) {
+		// Add .png extension if no extension provided
+		// This ensures all modes (Fyne, WebView, MessageBox) get the same icon path processing
+		ext := filepath.Ext(n.IconPath)
+		if ext == "" {
+			n.IconPath = n.IconPath + ".png"
+			log.Printf("No extension provided, added .png: %s", n.IconPath)
 		}
 	}
 
-	// Check OpenGL if requested
-	if *checkOpenGL {
-		if isOpenGLAvailable() {
-			fmt.Println("OpenGL is available")
-			fmt.Println("Fyne GUI can be used")
-			os.Exit(0)
-		} else {
-			fmt.Println("OpenGL is not available")
-			if runtime.GOOS == "windows" {
-				fmt.Println("Will use native Windows MessageBox as fallback")
-			}
-			os.Exit(1)
-		}
+	// {{localtime:...}} is rendered by the notifier (per user when fanning out); render it
+	// here too so template errors are reported up front and results carry the shown title
+	displayed, err := n.WithLocalTimes(time.Now())
+	if err != nil {
+		argumentErrors{{Message: err.Error()}}.exitIfAny(*resultJSON, n.Title)
 	}
 
-	// Check wall broadcast if requested
-	if *checkWall {
-		if isWallAvailable() {
-			fmt.Println("Wall broadcast is available")
-			fmt.Println("Can send notifications to all logged-in users")
-			os.Exit(0)
-		} else {
-			if runtime.GOOS != "linux" {
-				fmt.Println("Wall broadcast is only available on Linux")
-			} else {
-				fmt.Println("Wall command not found")
-				fmt.Println("Install with: sudo apt install bsdutils (usually pre-installed)")
-			}
-			os.Exit(1)
-		}
+	// Acknowledgment payload reporting (only prints when -result-json is set)
+	reporter := resultReporter{
+		jsonOutput:       *resultJSON,
+		includeInventory: *includeInventory,
+		title:            displayed.Title,
+		message:          displayed.Message,
+		category:         displayed.Category,
+		sensitive:        displayed.Sensitive,
+		redactAfterAck:   n.RedactAfterAck,
+		callbackURL:      n.CallbackURL,
+		followUpDepth:    *followUpDepth,
+	}
+	if spec != nil {
+		reporter.followUps = spec.FollowUps
+		reporter.variant = spec.variant
+		reporter.specDir = filepath.Dir(*specPath)
 	}
 
-	// Force wall broadcast mode if requested (Linux only)
-	if *forceWall {
-		if runtime.GOOS != "linux" {
-			log.Fatal("Force-wall mode is only available on Linux")
-		}
-		if !isWallAvailable() {
-			log.Fatal("Wall command not found. Install with: sudo apt install bsdutils")
-		}
-		log.Println("Force-wall mode enabled, using wall broadcast")
-		err := broadcastWallMessage(*title, *message, *timeout)
-		if err != nil {
-			log.Fatalf("Failed to send wall broadcast: %v", err)
-		}
+	// Show version if requested
+	if *version {
+		printVersion()
 		os.Exit(0)
 	}
 
-	// Windows: Force WebView mode if requested (bypass OpenGL check)
-	// BUT skip if running as SYSTEM with other users (will be handled by elevated notification logic)
-	if *winWebView {
-		if runtime.GOOS != "windows" {
-			log.Fatal("-win-webview flag is only supported on Windows")
+	// Legacy check flags, same as "notify update" and "notify check NAME"
+	if checkUpdate {
+		os.Exit(runUpdateCheck())
+	}
+	legacyChecks := []struct {
+		set  bool
+		name string
+	}{
+		{*checkDeps, "deps"},
+		{*checkElevationFlag, "elevation"},
+		{*checkPermissionsFlag, "permissions"},
+		{*checkGUI, "gui"},
+		{*checkOpenGL, "opengl"},
+		{*checkWebViewFlag, "webview"},
+		{*checkWall, "wall"},
+	}
+	for _, check := range legacyChecks {
+		if check.set {
+			os.Exit(checkCommands[check.name]())
+		}
+	}
+
+	// Every problem with the arguments is reported at once, as one JSON result with -result-json,
+	// and exits with exitInvalidArguments so orchestration can tell it from a failed delivery
+	var problems argumentErrors
+	problems.check("disconnected", notify.ValidateDisconnectedPolicy(*disconnected))
+	problems.check("priority", notify.ValidatePriority(n.Priority))
+	urgencyErr := notify.ValidateUrgency(n.Urgency)
+	problems.check("urgency", urgencyErr)
+	// The urgency chooses the timeout unless -timeout (or a spec or config file) sets it
+	timeoutSet := false
+	flag.Visit(func(f *flag.Flag) {
+		timeoutSet = timeoutSet || f.Name == "timeout"
+	})
+	if !timeoutSet && urgencyErr == nil {
+		n.Timeout = notify.UrgencyTimeout(n.Urgency)
+	}
+	problems.check("reading-speed", notify.ValidateReadingTimeout(n.ReadingSpeed, n.TimeoutMin, n.TimeoutMax))
+	if n.Timeout == notify.TimeoutAuto {
+		n.Timeout = displayed.ReadingTimeout()
+		log.Printf("Timeout: auto, %ds to read it at %d words per minute", n.Timeout, n.ReadingSpeed)
+	}
+	problems.check("theme", notify.ValidateTheme(n.Theme))
+	problems.check("locale", notify.ValidateLocale(n.Locale))
+	problems.check("font", notify.ValidateFont(n.Font))
+	problems.check("font-size", notify.ValidateFontSize(n.FontSize))
+	// The font is loaded by each user's notify process when fanning out, which runs elsewhere
+	if n.Font != "" {
+		if abs, err := filepath.Abs(n.Font); err == nil {
+			n.Font = abs
+		}
+	}
+	problems.check("state", notify.ValidateState(n.State))
+	problems.check("state-id", notify.ValidateStateID(n.StateID))
+	_, err = notify.ParseStateIcons(n.StateIcons)
+	problems.check("state-icons", err)
+	colorFlags := []struct{ name, value string }{
+		{"bg-color", n.BackgroundColor},
+		{"fg-color", n.ForegroundColor},
+		{"accent-color", n.AccentColor},
+	}
+	for _, colorFlag := range colorFlags {
+		problems.check(colorFlag.name, notify.ValidateColor(colorFlag.name, colorFlag.value))
+	}
+	problems.check("desktop", notify.ValidateDesktop(n.Desktop))
+	problems.check("monitor", notify.ValidateMonitor(n.Monitor))
+	problems.check("stacking", notify.ValidateStacking(n.Stacking))
+	problems.check("id", notify.ValidateID(n.ID))
+	problems.check("cancel", notify.ValidateID(n.Cancel))
+	if n.Replace && n.ID == "" {
+		problems.add("replace", "-replace requires -id")
+	}
+	if n.Automation != "" && (*quick || *native || *forceWall || *winBasic) {
+		problems.add("", "-automation needs a Fyne or WebView window (not -quick, -native, -force-wall or -win-basic)")
+	}
+	if n.Cancel != "" && (*quick || *native || *forceWall || *winBasic) {
+		problems.add("", "-cancel closes a window shown with -id (not -quick, -native, -force-wall or -win-basic)")
+	}
+	problems.check("sound", notify.ValidateSound(n.Sound))
+	problems.check("otp", notify.ValidateOTP(n.OTP))
+	if n.Link != "" {
+		problems.check("link", notify.ValidateLink(n.Link))
+	}
+	if notify.IsIconURL(n.IconPath) {
+		problems.check("icon", notify.ValidateIconURL(n.IconPath))
+	}
+	problems.check("icon-data", notify.ValidateIconData(n.IconData))
+	problems.check("builtin-icon", notify.ValidateBuiltinIcon(n.BuiltinIcon))
+	if (n.Input || n.Choices != "") && (*quick || *native || *forceWall || *winBasic) {
+		problems.add("", "-input and -choices need a window that can show them (not -quick, -native, -force-wall or -win-basic)")
+	}
+	problems.check("ack-file", notify.ValidateAckFile(n.AckFile))
+	problems.check("respect-dnd", notify.ValidateRespectDND(n.RespectDND))
+	if n.AckFile != "" && (*quick || *native || *forceWall || *winBasic) {
+		problems.add("", "-ack-file needs a window it can close (not -quick, -native, -force-wall or -win-basic)")
+	}
+	if (n.OnClick != "" || len(n.OnChoice) > 0) && (*quick || *native || *forceWall) {
+		problems.add("", "-on-click and -on-choice need a window the user can acknowledge (not -quick, -native or -force-wall)")
+	}
+	problems.check("on-click", validateClickCommands(n))
+	if n.CallbackURL != "" {
+		problems.check("callback-url", validateCallbackURL(n.CallbackURL))
+	}
+	var ntfy ntfyTarget
+	if *ntfyFlag != "" {
+		ntfy, err = parseNtfyTarget(*ntfyFlag)
+		problems.check("ntfy", err)
+	}
+	var gotifyServer string
+	gotifyToken := os.Getenv(gotifyTokenEnv)
+	if *gotifyFlag != "" {
+		gotifyServer, err = parseGotifyServer(*gotifyFlag)
+		problems.check("gotify", err)
+		if gotifyToken == "" {
+			problems.add("gotify", "-gotify needs the application token in %s", gotifyTokenEnv)
+		}
+	}
+	if n.Sensitive && n.MobileMirror {
+		problems.add("", "-sensitive cannot be combined with -mobile-mirror, which serves the message to phones on the network")
+	}
+	if n.Choices != "" {
+		problems.check("choices", validateChoices(n.ChoiceList()))
+	}
+	if n.Category != "" {
+		problems.check("category", validateCategory(n.Category))
+	}
+	var deadline time.Time
+	if *deliverBy != "" {
+		deadline, err = parseDeliverBy(*deliverBy, n.TimeZone, time.Now())
+		problems.check("deliver-by", err)
+	}
+	var repeatUntil time.Time
+	if *until != "" {
+		repeatUntil, err = parseTime("until", *until, n.TimeZone, time.Now())
+		problems.check("until", err)
+	}
+	problems.check("every", notify.ValidateEvery(*every, *count, repeatUntil, *stopOn, n.ChoiceList()))
+	if *every > 0 && !deadline.IsZero() {
+		problems.add("", "-every cannot be combined with -deliver-by, which already shows the notification again until acknowledged")
+	}
+	if *every > 0 && *count == 0 && repeatUntil.IsZero() && (*quick || *native || *forceWall) {
+		problems.add("every", "-every with -quick, -native or -force-wall, which cannot be acknowledged, requires -count or -until")
+	}
+	// A process armed with -at or -delay ignores them (a spec sets them again), it already waited
+	var showAt time.Time
+	if *scheduledAt != "" {
+		showAt, err = time.Parse(time.RFC3339, *scheduledAt)
+		problems.check("scheduled-at", err)
+	} else {
+		showAt, err = scheduleTime(*at, *delay, n.TimeZone, time.Now())
+		if *at != "" {
+			problems.check("at", err)
+		} else {
+			problems.check("delay", err)
 		}
+	}
+	if !*plan && (*planOS != "" || *planSession != "") {
+		problems.add("", "-os and -session require -plan")
+	}
+	if *calendarSource != "" && *businessHours == "" {
+		problems.add("calendar", "-calendar requires -business-hours")
+	}
+	if *rolloutPercent < 0 || *rolloutPercent > 100 {
+		problems.add("rollout-percent", "invalid -rollout-percent %d: must be between 0 and 100", *rolloutPercent)
+	}
 
-		// If running as SYSTEM with logged-in users, defer to the elevated notification handler
-		if shouldShowToOtherUsers() {
-			log.Println("-win-webview flag detected, but running as SYSTEM with logged-in users")
-			log.Println("Will launch as target user (flag will be passed to child process)")
-			// Continue to the elevated notification logic below
-		} else {
-			log.Println("Windows WebView mode enabled, skipping OpenGL check")
-			if !isWebViewAvailable() {
-				log.Fatal("WebView not available. Build with: go build -tags webview")
+	// -targets: each personalized title and message must render, and passes the content
+	// policies like the notification's own (below)
+	var targets []notify.Target
+	var personalized []notify.Notification
+	if *targetsPath != "" {
+		targets, err = loadTargets(*targetsPath, n)
+		problems.check("targets", err)
+		for _, target := range targets {
+			p := n
+			if target.Title != "" {
+				p.Title = target.Title
 			}
-			log.Println("Using WebView (HTML/CSS/JS)")
-			err := showWebViewNotification(*title, *message, *timeout, icon, *width, *height, *buttonText)
-			if err != nil {
-				log.Fatalf("Failed to show WebView notification: %v", err)
+			if target.Message != "" {
+				p.Message, p.HTML = target.Message, ""
 			}
-			os.Exit(0)
+			if p, err = p.WithLocalTimes(time.Now()); err != nil {
+				problems.add("targets", "%s: %v", target.Username, err)
+				continue
+			}
+			personalized = append(personalized, p)
 		}
 	}
+	problems.exitIfAny(*resultJSON, displayed.Title)
 
-	// Windows: Force basic mode if requested (bypass OpenGL check)
-	// BUT skip if running as SYSTEM with other users (will be handled by elevated notification logic)
-	if *winBasic {
-		if runtime.GOOS != "windows" {
-			log.Fatal("-win-basic flag is only supported on Windows")
+	// Content policies from the config files are enforced before anything is shown
+	for _, config := range configs {
+		if config.Policy == nil {
+			continue
 		}
-
-		// If running as SYSTEM with logged-in users, defer to the elevated notification handler
-		if shouldShowToOtherUsers() {
-			log.Println("-win-basic flag detected, but running as SYSTEM with logged-in users")
-			log.Println("Will launch as target user (flag will be passed to child process)")
-			// Continue to the elevated notification logic below
-		} else {
-			log.Println("Windows basic mode enabled, using MessageBox")
-			err := showWindowsMessageBox(*title, *message, *timeout)
-			if err != nil {
-				log.Fatalf("Failed to show notification: %v", err)
-			}
-			os.Exit(0)
+		if err := config.Policy.check(displayed, config.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
-	}
-
-	// Special handling when running as root/SYSTEM/Administrator
-	// Show to BOTH GUI users and terminal users (Linux only has wall)
-	if shouldShowToOtherUsers() {
-		log.Println("Running with elevated privileges, notifying logged-in users")
-
-		guiSuccess := false
-		wallSuccess := false
-
-		// Try to show GUI to logged-in GUI users (unless force-wall is set)
-		if !*forceWall {
-			if err := showNotificationToUsers(*title, *message, *timeout, icon, *width, *height, *buttonText); err == nil {
-				log.Println("✓ Notification shown to GUI user(s)")
-				guiSuccess = true
-			} else {
-				log.Printf("✗ Could not show GUI to users: %v", err)
+		for i, p := range personalized {
+			if err := config.Policy.check(p, config.Path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", targets[i].Username, err)
+				os.Exit(1)
 			}
 		}
+	}
 
-		// Linux-specific: Send wall broadcast to terminal sessions
-		// Skip if -gui-only flag is set
-		if runtime.GOOS == "linux" && !*guiOnly && isWallAvailable() {
-			if *forceWall {
-				log.Println("Sending wall broadcast only (force-wall mode)")
-			} else {
-				log.Println("Also sending wall broadcast to terminal sessions")
-			}
-			err := broadcastWallMessage(*title, *message, *timeout)
-			if err != nil {
-				log.Printf("✗ Wall broadcast failed: %v", err)
-			} else {
-				log.Println("✓ Wall broadcast sent to terminal users")
-				wallSuccess = true
-			}
+	// Break glass: check the token before anything is bypassed (the notifier checks it again and audits the use)
+	var breakGlass *notify.BreakGlassClaims
+	if n.Priority == notify.PriorityBreakGlass {
+		claims, err := notify.AuthorizeBreakGlass(n, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
-
-		// Exit if at least one method succeeded
-		if guiSuccess || wallSuccess {
+		breakGlass = &claims
+		reporter.priority = n.Priority
+	}
+
+	// Delivery mode flags, in order of precedence
+	opts := notify.Options{
+		Notification: n,
+		Mode:         notify.ModeAuto,
+		Autosize:     *autosize,
+		GUIOnly:      *guiOnly,
+		Disconnected: *disconnected,
+		Debug:        *debug,
+		DeliverBy:    deadline,
+		Every:        *every,
+		Count:        *count,
+		Until:        repeatUntil,
+		StopOn:       *stopOn,
+		Targets:      targets,
+	}
+	switch {
+	case *quick:
+		opts.Mode = notify.ModeQuick
+	case *native:
+		opts.Mode = notify.ModeNative
+	case *forceWall:
+		opts.Mode = notify.ModeWall
+	case *winWebView:
+		opts.Mode = notify.ModeWebView
+	case *winBasic:
+		opts.Mode = notify.ModeBasic
+	}
+
+	// Plan: print the fallback chain on the described machine, which need not be this one
+	if *plan {
+		os.Exit(printFallbackPlan(opts, *planOS, *planSession))
+	}
+
+	// Launched by a deliver-on-reconnect task: remove it so it only fires once
+	if *reconnectTask != "" {
+		notify.RemoveReconnectTask(*reconnectTask)
+	}
+
+	// Staged rollout: only the configured percentage of machines display the notification
+	// The decision is made once here, before waiting for -at, -delay or business hours and before
+	// any fan-out to logged-in users, so machines outside the rollout do not wait for nothing
+	inRollout := true
+	if *rolloutPercent < 100 {
+		machineID := getMachineID()
+		inRollout = isInRollout(machineID, *rolloutSalt, *rolloutPercent)
+		switch {
+		case inRollout:
+			log.Printf("Machine %s is inside the %d%% rollout (salt %q)", machineID, *rolloutPercent, *rolloutSalt)
+		case !*dryRun:
+			log.Printf("Machine %s is outside the %d%% rollout (salt %q), not displaying notification", machineID, *rolloutPercent, *rolloutSalt)
+			reporter.report(actionSkipped, "")
 			os.Exit(0)
 		}
+	}
 
-		// If both failed, check if we're running as SYSTEM on Windows
-		// SYSTEM doesn't have a desktop, so don't try to show GUI to SYSTEM itself
-		if runtime.GOOS == "windows" && isRunningAsSystem() {
-			log.Println("ERROR: Running as SYSTEM but could not notify any users via scheduled task")
-			log.Println("SYSTEM account has no desktop/display - cannot show GUI directly")
-			log.Fatal("Notification failed: No logged-in users found or scheduled task creation failed")
+	// -at and -delay: a detached notify process waits and shows the notification, this one returns
+	if !showAt.IsZero() && *scheduledAt == "" && !*dryRun {
+		args := os.Args[1:]
+		if messageFromStdin {
+			args = append(args, "-message", encodeArgument(n.Message, decodeMode))
 		}
-
-		// If both failed, log and continue to try normal GUI
-		log.Println("Warning: Could not notify via GUI or wall, trying normal GUI mode")
+		os.Exit(armSchedule(showAt, args, reporter))
+	}
+	if *scheduledAt != "" {
+		log.Printf("Scheduled: waiting until %s before showing", showAt.Format(time.RFC3339))
+		waitUntil(showAt)
 	}
 
-	// Auto-size window if requested
-	if *autosize {
-		calculatedWidth, calculatedHeight := calculateWindowSize(*title, *message, *buttonText, icon != "")
-		// Use calculated size but respect user-provided maximums
-		if *width == defaultWidth {
-			*width = calculatedWidth
-		}
-		if *height == defaultHeight {
-			*height = calculatedHeight
-		}
-		log.Printf("Auto-sizing enabled: calculated %dx%d, using %dx%d", calculatedWidth, calculatedHeight, *width, *height)
+	// Launched as a follow-up: wait for the follow-up's delay before showing
+	if *followUpDelay > 0 {
+		log.Printf("Follow-up: waiting %v before showing", *followUpDelay)
+		time.Sleep(*followUpDelay)
 	}
 
-	// Verify GUI is available before showing notification
-	if !isGUIAvailable() {
-		// Try wall broadcast on Linux as fallback
-		if runtime.GOOS == "linux" && isWallAvailable() {
-			log.Println("GUI not available, using wall broadcast")
-			err := broadcastWallMessage(*title, *message, *timeout)
-			if err != nil {
-				log.Fatalf("Failed to broadcast message: %v", err)
+	// Business hours: find the next delivery window, skipping non-work days and calendar events
+	// Break-glass notifications are delivered immediately regardless
+	var workCal *workCalendar
+	var window deliveryWindow
+	if *businessHours != "" && breakGlass != nil {
+		log.Printf("Break glass: ignoring business hours %q", *businessHours)
+	} else if *businessHours != "" {
+		var err error
+		if workCal, err = loadWorkCalendar(*businessHours, *calendarSource, n.TimeZone); err == nil {
+			start := time.Now()
+			if showAt.After(start) {
+				start = showAt // Business hours at the time of -at or -delay
 			}
-			os.Exit(0)
+			window, err = workCal.nextWindow(start)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
-		log.Fatal("GUI mode is not available and no fallback notification method found.")
 	}
 
-	// Check OpenGL availability (primarily for Windows)
-	openglAvailable := isOpenGLAvailable()
-	log.Printf("OpenGL availability check result: %v", openglAvailable)
-
-	if !openglAvailable {
-		log.Println("Warning: OpenGL not available, trying alternative GUI")
-
-		// On Windows, when running as SYSTEM, skip WebView (it has permission issues)
-		// and go directly to MessageBox which is more reliable for services
-		skipWebView := false
-		if runtime.GOOS == "windows" && isRunningAsSystem() {
-			log.Println("Running as SYSTEM on Windows, skipping WebView (using MessageBox for reliability)")
-			skipWebView = true
+	// Dry run: report the delivery window and rollout decision without showing anything
+	if *dryRun {
+		printDeliveryPlan(*businessHours, *calendarSource, workCal, &window, breakGlass, inRollout, *rolloutPercent)
+		if !showAt.IsZero() {
+			fmt.Printf("Scheduled: shown at %s by a waiting notify process\n", showAt.Format("Mon 2 Jan 2006 15:04 MST"))
 		}
-
-		// Try WebView first (works on all platforms, better UI) unless skipped
-		if !skipWebView && isWebViewAvailable() {
-			log.Println("Using WebView (HTML/CSS/JS) for notification")
-			err := showWebViewNotification(*title, *message, *timeout, icon, *width, *height, *buttonText)
-			if err != nil {
-				log.Printf("WebView failed: %v, trying basic fallback", err)
-			} else {
-				os.Exit(0)
+		if !deadline.IsZero() {
+			start := time.Now()
+			if showAt.After(start) {
+				start = showAt
 			}
-		}
-
-		// Fall back to native OS dialogs as last resort
-		if runtime.GOOS == "windows" {
-			log.Println("Using native Windows MessageBox")
-			err := showWindowsMessageBox(*title, *message, *timeout)
-			if err != nil {
-				log.Fatalf("Failed to show notification: %v", err)
+			if workCal != nil && window.Start.After(start) {
+				start = window.Start
 			}
-			os.Exit(0)
-		} else {
-			log.Fatal("OpenGL not available and no suitable fallback GUI for this platform")
+			printEscalationPlan(start, deadline)
 		}
-	}
-
-	// Create the notification window with Fyne (when OpenGL is available)
-	log.Println("Attempting to create Fyne GUI (OpenGL detected as available)")
-	showNotification(*title, *message, *timeout, icon, *width, *height, *buttonText)
-}
-
-// showNotification displays a notification window with the given title, message, timeout, optional icon, window dimensions, and button text
-func showNotification(title, message string, timeout int, iconPath string, width, height int, buttonText string) {
-	// Add panic recovery in case Fyne initialization fails despite OpenGL check
-	defer func() {
-		if err := recover(); err != nil {
-			log.Printf("Fyne GUI failed to initialize (panic): %v", err)
-			log.Println("Falling back to alternative notification method")
-
-			// Try fallbacks
-			if runtime.GOOS == "windows" {
-				if werr := showWindowsMessageBox(title, message, timeout); werr != nil {
-					log.Fatalf("All notification methods failed: %v", werr)
-				}
-			} else {
-				log.Fatalf("Fyne GUI failed and no fallback available for this platform")
-			}
+		if *every > 0 {
+			printRecurrence(*every, *count, repeatUntil, *stopOn)
 		}
-	}()
-
-	a := app.New()
-	w := a.NewWindow(title)
-	w.SetIcon(resourceKrankyBearBeretPng)
-
-	// Windows-specific: Add zombie process prevention timeout
-	// In VMs without proper OpenGL, Fyne may hang invisibly without crashing
-	if runtime.GOOS == "windows" {
-		// Calculate a reasonable zombie prevention timeout
-		// Use the larger of: (user timeout + 15 seconds) or 30 seconds minimum
-		zombieTimeout := timeout + 15
-		if zombieTimeout < 30 {
-			zombieTimeout = 30
-		}
-
-		go func() {
-			time.Sleep(time.Duration(zombieTimeout) * time.Second)
-			log.Printf("Warning: Zombie prevention timeout reached (%d seconds), forcing exit", zombieTimeout)
-
-			// Try graceful quit using DoAndWait (proper Fyne thread-safe call)
-			go func() {
-				defer func() {
-					// Catch any panic from Quit()
-					if r := recover(); r != nil {
-						log.Printf("Panic during graceful quit (expected if hung): %v", r)
-					}
-				}()
-				fyne.DoAndWait(func() {
-					a.Quit()
-				})
-			}()
-
-			// Force exit immediately - if we've reached timeout, Fyne is hung anyway
-			time.Sleep(100 * time.Millisecond) // Brief moment for quit attempt
-			log.Printf("Forcing process termination")
-			os.Exit(0)
-		}()
-		log.Printf("Zombie prevention timeout set: %d seconds", zombieTimeout)
+		os.Exit(0)
 	}
 
-	// Set the window size BEFORE creating content
-	// This ensures the layout managers respect our dimensions
-	windowSize := fyne.NewSize(float32(width), float32(height))
-
-	// Create the UI
-	titleLabel := widget.NewLabel(title)
-	titleLabel.TextStyle.Bold = true
-
-	messageLabel := widget.NewLabel(message)
-	messageLabel.Wrapping = fyne.TextWrapWord // Enable word wrapping
-
-	okButton := widget.NewButton(buttonText, func() {
-		w.Close()
-	})
+	if workCal != nil && window.Start.After(time.Now()) {
+		log.Printf("Outside business hours, waiting until %s", window.Start.Format(time.RFC3339))
+		waitUntil(window.Start)
+	}
 
-	// Create the main content (title, message, button)
-	mainContent := container.NewVBox(
-		titleLabel,
-		widget.NewSeparator(),
-		messageLabel,
-		widget.NewSeparator(),
-		okButton,
-	)
-
-	// Add icon if specified
-	var content fyne.CanvasObject
-	if iconPath != "" {
-		iconImage := loadIcon(iconPath)
-		if iconImage != nil {
-			// Create horizontal layout with icon on the left
-			// Use Border layout to ensure message text gets proper width
-			iconContainer := container.NewVBox(iconImage)
-			content = container.NewBorder(
-				nil,                                // top
-				nil,                                // bottom
-				container.NewPadded(iconContainer), // left (icon)
-				nil,                                // right
-				container.NewPadded(mainContent),   // center (content gets remaining space)
-			)
-		} else {
-			// If icon fails to load, just use main content
-			content = mainContent
+	// Notifications displayed in this user's session are recorded in the local store
+	notifier := notify.New()
+	notifier.OnDisplay = func(shown notify.Notification) {
+		// -deliver-by and -every show the notification several times, record it once
+		// -sensitive messages are not kept in the inbox, only that they were shown
+		if reporter.inboxID == "" {
+			message, link := shown.Message, shown.PrimaryLink()
+			if shown.Sensitive {
+				message, link = redactedMessage, ""
+			}
+			reporter.inboxID = recordDelivery(shown.Title, message, shown.Category, link)
 		}
-	} else {
-		content = mainContent
 	}
-
-	// Wrap content in a padded container
-	paddedContent := container.NewPadded(content)
-
-	w.SetContent(paddedContent)
-	w.Resize(windowSize)
-	w.SetFixedSize(false) // Allow manual resizing but start at our size
-	w.CenterOnScreen()
-
-	// Set up auto-close if timeout is specified
-	if timeout > 0 {
-		go func() {
-			time.Sleep(time.Duration(timeout) * time.Second)
-			fyne.DoAndWait(func() {
-				w.Close()
-			})
-		}()
+	// Opt-outs are per user, so they are checked in the user's own session (after any fan-out)
+	notifier.Suppress = func(shown notify.Notification) bool {
+		return categorySuppressed(shown.Category, configs)
 	}
-
-	// Show the window
-	w.Show()
-
-	// Force the window to respect our size after showing
-	// This is necessary because Fyne may resize based on content
-	w.Resize(windowSize)
-
-	// Run the app
-	a.Run()
-}
-
-// calculateWindowSize calculates optimal window dimensions based on content
-// Returns width and height capped at reasonable maximums
-func calculateWindowSize(title, message, buttonText string, hasIcon bool) (int, int) {
-	// Base dimensions
-	minWidth := 300
-	minHeight := 150
-	maxWidth := 600
-	maxHeight := 400
-
-	// Estimate based on text length
-	// Average character width: ~7 pixels for normal text
-	// Average line height: ~20 pixels
-
-	// Calculate width based on longest line in message
-	messageWidth := estimateTextWidth(message)
-	titleWidth := estimateTextWidth(title)
-	buttonWidth := 100 + len(buttonText)*7 // Button has padding
-
-	// Use the longest element
-	contentWidth := messageWidth
-	if titleWidth > contentWidth {
-		contentWidth = titleWidth
+	// Windows toasts: clicks in Action Center after this process exited are recorded by notify activate
+	notifier.ToastActivation = func(shown notify.Notification) string {
+		return toastActivation(shown, &reporter)
 	}
-	if buttonWidth > contentWidth {
-		contentWidth = buttonWidth
+	notifier.OnScreenshotShared = func(png []byte) {
+		reporter.screenshot = png
 	}
-
-	// Add padding and icon space
-	width := contentWidth + 60 // 30px padding on each side
-	if hasIcon {
-		width += 80 // Space for icon
+	// Windows: a hung Fyne may never return from Send, so exit rather than stay as an invisible process
+	notifier.OnHang = func() {
+		time.Sleep(2 * time.Second) // Send returns with the error when the window quits
+		fmt.Fprintln(os.Stderr, "Error: the notification window did not respond and was closed")
+		os.Exit(1)
 	}
 
-	// Apply width constraints BEFORE calculating line count
-	// This ensures line count is based on the actual available width
-	if width < minWidth {
-		width = minWidth
-	}
-	if width > maxWidth {
-		width = maxWidth
+	// -ntfy and -gotify: phones and other subscribers get it while the notification is shown here
+	var published []<-chan struct{}
+	if *ntfyFlag != "" {
+		m := ntfyPublishMessage(ntfy.Topic, n, opts.Mode == notify.ModeNative)
+		published = append(published, startPublish("ntfy topic "+ntfy.String(), func() error { return publishNtfy(ntfy, m) }))
 	}
-
-	// Calculate height based on message lines (using constrained width)
-	messageLines := estimateLineCount(message, width-60)
-	titleLines := 1
-	if len(title) > 50 {
-		titleLines = 2
+	if *gotifyFlag != "" {
+		m := gotifyPublishMessage(n, opts.Mode == notify.ModeNative)
+		published = append(published, startPublish("Gotify server "+gotifyServer, func() error { return publishGotify(gotifyServer, gotifyToken, m) }))
 	}
 
-	// Calculate total height
-	height := 40 + // Top padding
-		(titleLines * 30) + // Title
-		(messageLines * 25) + // Message lines
-		50 + // Button
-		30 // Bottom padding
-
-	// Apply height constraints
-	if height < minHeight {
-		height = minHeight
+	result, err := notifier.Send(opts)
+	for _, done := range published {
+		<-done
 	}
-	if height > maxHeight {
-		height = maxHeight
-	}
-
-	return width, height
-}
-
-// estimateTextWidth estimates the pixel width of text
-func estimateTextWidth(text string) int {
-	const avgCharWidth = 7
-	maxLineLength := 0
-
-	// Split by newlines and find longest line
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
-		if len(line) > maxLineLength {
-			maxLineLength = len(line)
-		}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
-
-	return maxLineLength * avgCharWidth
-}
-
-// estimateLineCount estimates how many lines the text will take with word wrapping
-func estimateLineCount(text string, availableWidth int) int {
-	if text == "" {
-		return 1
+	// -on-click / -on-choice: started right away, the result and follow-ups come after
+	if result.Action == actionAcknowledged {
+		runClickCommand(n, result.Choice)
 	}
+	reporter.input, reporter.inputRequested = result.Input, n.Input
+	reporter.choice = result.Choice
+	reporter.downgrades = result.Downgrades
+	reporter.targets = result.Targets
+	reporter.report(result.Action, result.Method)
 
-	const avgCharWidth = 7
-	charsPerLine := availableWidth / avgCharWidth
-
-	if charsPerLine <= 0 {
-		charsPerLine = 40 // Fallback
+	// -targets: one row per target (they are in the JSON result with -result-json)
+	if !*resultJSON && len(result.Targets) > 0 {
+		printTargetResults(result.Targets)
 	}
 
-	// Count lines considering word wrap
-	words := strings.Fields(text)
-	lines := 1
-	currentLineLength := 0
-
-	for _, word := range words {
-		wordLength := len(word) + 1 // +1 for space
-		if currentLineLength+wordLength > charsPerLine {
-			lines++
-			currentLineLength = wordLength
-		} else {
-			currentLineLength += wordLength
+	// -input and -choices: the entered values are the output (they are in the JSON result with -result-json)
+	if !*resultJSON && result.Action == actionAcknowledged {
+		if n.Input {
+			fmt.Println(result.Input)
 		}
-	}
-
-	// Add explicit newlines
-	lines += strings.Count(text, "\n")
-
-	return lines
-}
-
-// resolveIconPath resolves an icon path by looking in the executable's directory if it's just a filename
-// Returns the resolved path that should be used to load the icon
-func resolveIconPath(iconPath string) string {
-	if iconPath == "" {
-		return ""
-	}
-
-	// Determine the actual path to use
-	actualPath := iconPath
-
-	// Check if the path is just a filename (no directory separators)
-	if filepath.Base(iconPath) == iconPath {
-		// It's just a filename, look in the executable's directory
-		exePath, err := os.Executable()
-		if err != nil {
-			log.Printf("Warning: Could not determine executable path: %v", err)
-		} else {
-			exeDir := filepath.Dir(exePath)
-			exeDirIconPath := filepath.Join(exeDir, iconPath)
-
-			// Check if the file exists in the executable's directory
-			if _, err := os.Stat(exeDirIconPath); err == nil {
-				log.Printf("Found icon in executable directory: %s", exeDirIconPath)
-				actualPath = exeDirIconPath
-			} else {
-				log.Printf("Icon not found in executable directory (%s), trying current directory", exeDirIconPath)
-			}
+		if result.Choice != "" {
+			fmt.Println(result.Choice)
 		}
 	}
-
-	return actualPath
-}
-
-// loadIcon loads an image from the specified file path and returns it as a canvas.Image
-// If only a filename is provided (no directory separators), it will look for the file
-// in the executable's directory first, then fall back to the current directory
-// Note: .png extension is added earlier in main() if needed, so iconPath should already have an extension
-func loadIcon(iconPath string) *canvas.Image {
-	if iconPath == "" {
-		return nil
-	}
-
-	// Resolve the icon path (look in exe directory if needed)
-	actualPath := resolveIconPath(iconPath)
-
-	// Check if file exists at the determined path
-	if _, err := os.Stat(actualPath); os.IsNotExist(err) {
-		log.Printf("Warning: Icon file not found: %s", actualPath)
-		return nil
-	}
-
-	log.Printf("Loading icon from: %s", actualPath)
-
-	// Convert to absolute path to ensure Fyne can find it
-	absPath, err := filepath.Abs(actualPath)
-	if err != nil {
-		log.Printf("Warning: Could not get absolute path for icon: %v", err)
-		absPath = actualPath
-	} else {
-		log.Printf("Absolute icon path: %s", absPath)
-	}
-
-	// Load the image using Fyne's storage
-	// Note: NewFileURI handles Windows paths correctly, including spaces
-	uri := storage.NewFileURI(absPath)
-	log.Printf("Icon URI: %s", uri.String())
-
-	img := canvas.NewImageFromURI(uri)
-
-	if img == nil {
-		log.Printf("Warning: Failed to load icon from URI: %s (path: %s)", uri.String(), absPath)
-		return nil
-	}
-
-	log.Printf("Successfully loaded icon: %s", absPath)
-
-	// Set image properties
-	img.FillMode = canvas.ImageFillContain
-	img.SetMinSize(fyne.NewSize(64, 64))
-
-	return img
-}
-
-// isGUIAvailable checks if GUI mode is available on the current system
-func isGUIAvailable() bool {
-	switch runtime.GOOS {
-	case "linux":
-		return isLinuxGUIAvailable()
-	case "darwin":
-		return isMacGUIAvailable()
-	case "windows":
-		return isWindowsGUIAvailable()
-	default:
-		return false
+	if index := choiceIndex(n.ChoiceList(), result.Choice); index >= 0 {
+		os.Exit(choiceExitBase + index)
 	}
 }
 
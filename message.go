@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// stdinMessage is the -message value that reads the message from stdin
+const stdinMessage = "-"
+
+// maxMessageInput is the most of stdin kept as the message, and the largest -message-file;
+// longer output (e.g. a job log piped in) keeps its end, where errors usually are
+const maxMessageInput = 64 << 10
+
+// stdinWait is how long a missing -message waits for data on a piped stdin before the default
+// message is shown, for scripts that run notify with a stdin they never write to or close
+const stdinWait = 2 * time.Second
+
+// stdinIsPiped reports whether stdin is a pipe or a redirected file rather than a terminal,
+// so a missing -message can be read from it
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && (info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular())
+}
+
+// readStdinMessage reads a message from r until EOF: the last maxMessageInput bytes from the
+// start of a line, without the trailing newline. Longer input is read to the end without
+// keeping more than twice that in memory
+func readStdinMessage(r io.Reader) (string, error) {
+	var data []byte
+	truncated := false
+	chunk := make([]byte, 32<<10)
+	for {
+		count, err := r.Read(chunk)
+		data = append(data, chunk[:count]...)
+		if len(data) > 2*maxMessageInput {
+			data = append(data[:0], data[len(data)-maxMessageInput:]...)
+			truncated = true
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not read the message from stdin: %v", err)
+		}
+	}
+	if len(data) > maxMessageInput {
+		data = data[len(data)-maxMessageInput:]
+		truncated = true
+	}
+	if truncated {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	message := messageText(data)
+	if message == "" {
+		return "", fmt.Errorf("no message on stdin")
+	}
+	if truncated {
+		message = "…\n" + message
+	}
+	return message, nil
+}
+
+// readPipedMessage reads the message of a notification without -message from r, a piped stdin,
+// like readStdinMessage, but gives up when nothing arrives within wait
+func readPipedMessage(r io.Reader, wait time.Duration) (string, error) {
+	type read struct {
+		data []byte
+		err  error
+	}
+	first := make(chan read, 1)
+	go func() {
+		chunk := make([]byte, 32<<10)
+		count, err := r.Read(chunk)
+		first <- read{chunk[:count], err}
+	}()
+	select {
+	case got := <-first:
+		if got.err == io.EOF {
+			return readStdinMessage(bytes.NewReader(got.data))
+		}
+		if got.err != nil {
+			return "", fmt.Errorf("could not read the message from stdin: %v", got.err)
+		}
+		return readStdinMessage(io.MultiReader(bytes.NewReader(got.data), r))
+	case <-time.After(wait):
+		return "", fmt.Errorf("no message on stdin within %s", wait)
+	}
+}
+
+// readMessageFile reads a -message-file: UTF-8, or UTF-16 with a byte order mark (as written
+// by Windows PowerShell), without the trailing newline
+func readMessageFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read the message file: %v", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxMessageInput+1))
+	if err != nil {
+		return "", fmt.Errorf("could not read the message file: %v", err)
+	}
+	if len(data) > maxMessageInput {
+		return "", fmt.Errorf("message file %s is larger than %d KB", path, maxMessageInput>>10)
+	}
+	message := messageText(data)
+	if message == "" {
+		return "", fmt.Errorf("message file %s is empty", path)
+	}
+	return message, nil
+}
+
+// messageText decodes message bytes read from stdin or a file: the byte order mark is
+// dropped, UTF-16 converted and invalid UTF-8 replaced, and trailing newlines removed
+func messageText(data []byte) string {
+	var text string
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		bigEndian := data[0] == 0xFE
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			} else {
+				units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+			}
+		}
+		text = string(utf16.Decode(units))
+	default:
+		text = string(bytes.ToValidUTF8(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")), []byte("�")))
+	}
+	return strings.TrimRight(text, "\r\n")
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReadStdinMessage tests that piped output loses its trailing newline and keeps its end
+func TestReadStdinMessage(t *testing.T) {
+	message, err := readStdinMessage(strings.NewReader("Backup finished\nerrors: 0\n"))
+	if err != nil || message != "Backup finished\nerrors: 0" {
+		t.Errorf("readStdinMessage = %q, %v", message, err)
+	}
+
+	long := strings.Repeat("copying file\n", maxMessageInput/10) + "error: disk full\n"
+	message, err = readStdinMessage(strings.NewReader(long))
+	if err != nil || len(message) > maxMessageInput+len("…\n") || !strings.HasPrefix(message, "…\ncopying file\n") || !strings.HasSuffix(message, "error: disk full") {
+		t.Errorf("readStdinMessage of %d bytes = %d bytes, %v", len(long), len(message), err)
+	}
+
+	if _, err := readStdinMessage(strings.NewReader("\n")); err == nil {
+		t.Error("readStdinMessage of an empty stdin should fail")
+	}
+}
+
+// TestReadPipedMessage tests that a missing -message reads a pipe, but gives up on an empty pipe
+// or one nothing is written to, so notify shows its default message
+func TestReadPipedMessage(t *testing.T) {
+	if message, err := readPipedMessage(strings.NewReader("Disk usage: 91%\n"), time.Second); err != nil || message != "Disk usage: 91%" {
+		t.Errorf("readPipedMessage = %q, %v", message, err)
+	}
+	if _, err := readPipedMessage(strings.NewReader(""), time.Second); err == nil {
+		t.Error("readPipedMessage of an empty pipe should fail")
+	}
+
+	silent, writer := io.Pipe()
+	defer writer.Close()
+	start := time.Now()
+	if _, err := readPipedMessage(silent, 50*time.Millisecond); err == nil || !strings.Contains(err.Error(), "within") || time.Since(start) > time.Second {
+		t.Errorf("readPipedMessage of a silent pipe = %v after %s", err, time.Since(start))
+	}
+}
+
+// TestEncodeArgument tests that encoded arguments decode to the original value
+func TestEncodeArgument(t *testing.T) {
+	value := "50% done + 2 errors/warnings\nsee log"
+	for _, mode := range []int{decodeNone, decodeEncoded, decodeLegacy} {
+		if decoded, err := decodeArgument(encodeArgument(value, mode), mode); err != nil || decoded != value {
+			t.Errorf("mode %d: decoded %q, %v", mode, decoded, err)
+		}
+	}
+}
+
+// TestReadMessageFile tests UTF-8 and UTF-16 message files, and the size limit
+func TestReadMessageFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"utf8.txt":  []byte("\xEF\xBB\xBFMaintenance tonight\r\nSave your work\r\n"),
+		"utf16.txt": {0xFF, 0xFE, 'M', 0, 'a', 0, 'i', 0, 'n', 0, 't', 0, 'e', 0, 'n', 0, 'a', 0, 'n', 0, 'c', 0, 'e', 0, ' ', 0, 't', 0, 'o', 0, 'n', 0, 'i', 0, 'g', 0, 'h', 0, 't', 0, '\r', 0, '\n', 0, 'S', 0, 'a', 0, 'v', 0, 'e', 0, ' ', 0, 'y', 0, 'o', 0, 'u', 0, 'r', 0, ' ', 0, 'w', 0, 'o', 0, 'r', 0, 'k', 0},
+		"large.txt": []byte(strings.Repeat("x", maxMessageInput+1)),
+		"empty.txt": []byte("\n"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"utf8.txt", "utf16.txt"} {
+		if message, err := readMessageFile(filepath.Join(dir, name)); err != nil || message != "Maintenance tonight\r\nSave your work" {
+			t.Errorf("readMessageFile(%s) = %q, %v", name, message, err)
+		}
+	}
+	for _, name := range []string{"large.txt", "empty.txt", "missing.txt"} {
+		if _, err := readMessageFile(filepath.Join(dir, name)); err == nil {
+			t.Errorf("readMessageFile(%s) should fail", name)
+		}
+	}
+}
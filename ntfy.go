@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// defaultNtfyServer is the server of a -ntfy topic without @server
+const defaultNtfyServer = "https://ntfy.sh"
+
+// ntfyTimeout bounds publishing to -ntfy, so an unreachable server cannot keep notify running
+const ntfyTimeout = 10 * time.Second
+
+// ntfyTokenEnv is the environment variable holding the ntfy access token, kept off the command line
+const ntfyTokenEnv = "NTFY_TOKEN"
+
+// ntfySubscribeSilence is how long a subscription may hear nothing, not even the keepalive
+// ntfy sends every 45 seconds, before it reconnects
+const ntfySubscribeSilence = 2 * time.Minute
+
+// ntfyTopicPattern is what ntfy accepts as a topic name
+var ntfyTopicPattern = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+
+// ntfyTarget is a topic on an ntfy server, as given to -ntfy topic@server
+type ntfyTarget struct {
+	Server string // Base URL, without a trailing /
+	Topic  string
+}
+
+// String returns the target as the topic URL
+func (t ntfyTarget) String() string {
+	return t.Server + "/" + t.Topic
+}
+
+// parseNtfyTarget parses a -ntfy value: topic, topic@host[:port][/path] (https) or
+// topic@http(s)://host[:port][/path]
+func parseNtfyTarget(value string) (ntfyTarget, error) {
+	topic, server, hasServer := strings.Cut(value, "@")
+	if !ntfyTopicPattern.MatchString(topic) {
+		return ntfyTarget{}, fmt.Errorf("invalid ntfy topic %q (use topic@server, the topic with letters, digits, - and _ only)", topic)
+	}
+	if !hasServer {
+		return ntfyTarget{Server: defaultNtfyServer, Topic: topic}, nil
+	}
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return ntfyTarget{}, fmt.Errorf("invalid ntfy server %q (use a host name or an http or https URL)", server)
+	}
+	return ntfyTarget{Server: strings.TrimSuffix(u.String(), "/"), Topic: topic}, nil
+}
+
+// ntfyPublishMessage is the ntfy message for a notification: urgency sets the priority (a
+// -native toast is low), the category is a tag and the link the click URL; a -sensitive
+// message is not sent to the phones
+func ntfyPublishMessage(topic string, n notify.Notification, native bool) ntfyMessage {
+	m := ntfyMessage{Topic: topic, Title: n.Title, Message: n.Message, Priority: 3, Click: n.Link}
+	if n.Sensitive {
+		m.Message = redactedMessage
+	}
+	switch {
+	case n.Priority == notify.PriorityBreakGlass || n.Urgency == notify.UrgencyCritical:
+		m.Priority = 5
+	case n.Urgency == notify.UrgencyWarning:
+		m.Priority = 4
+	case native:
+		m.Priority = 2
+	}
+	if n.Category != "" {
+		m.Tags = []string{n.Category}
+	}
+	return m
+}
+
+// publishNtfy publishes m to the target's server as JSON, with the NTFY_TOKEN access token
+func publishNtfy(target ntfyTarget, m ntfyMessage) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("could not encode ntfy message: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, target.Server, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("could not create ntfy request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KrankyBearNotify/"+appVersion)
+	if token := os.Getenv(ntfyTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: ntfyTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("ntfy request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var reply struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&reply)
+		if reply.Error != "" {
+			return fmt.Errorf("ntfy request failed: %s: %s", resp.Status, reply.Error)
+		}
+		return fmt.Errorf("ntfy request failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// startPublish publishes to another channel (-ntfy, -gotify) in the background while the
+// notification is shown; the returned channel is closed when it is done. A failure is logged
+// and does not fail the notification
+func startPublish(channel string, publish func() error) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := publish(); err != nil {
+			log.Printf("Warning: Could not publish to %s: %v", channel, err)
+			return
+		}
+		log.Printf("Published to %s", channel)
+	}()
+	return done
+}
+
+// ntfyEvent is a line of an ntfy JSON subscription stream (https://docs.ntfy.sh/subscribe/api/)
+type ntfyEvent struct {
+	ntfyMessage
+	ID    string `json:"id"`
+	Event string `json:"event"` // open, keepalive, message or poll_request
+}
+
+// ntfySubscriber shows the messages published to an ntfy topic with launch
+type ntfySubscriber struct {
+	target ntfyTarget
+	token  string
+	since  string // Message ID, Unix time, duration or "all" to start from; then the last message seen
+	client *http.Client
+	launch func(specPath string, done func(*NotificationResult, error)) error
+}
+
+// runSubscribe handles "notify subscribe topic@server... [-gotify URL]": shows a notification for
+// each message published to the ntfy topics, or received by the Gotify client, until interrupted
+func runSubscribe(args []string) int {
+	fs := flag.NewFlagSet("subscribe", flag.ExitOnError)
+	gotifyURL := fs.String("gotify", "", "Also show the messages of this Gotify server, received with the client token in "+gotifyClientTokenEnv)
+	since := fs.String("since", "", "Also show the messages the ntfy server has kept since this: a message ID, Unix time, duration (e.g. 10m) or all")
+	debug := fs.Bool("debug", false, "Log the connection and pass -debug to notification processes")
+	digestWindow := fs.Duration("digest-window", 0, "Hold notifications arriving within this time of the last one shown and show them together in one digest when it ends, e.g. 30s (default: each at once)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: notify subscribe [OPTIONS] [topic[@server]...]")
+		fmt.Fprintf(os.Stderr, "The ntfy server defaults to ntfy.sh; %s is the access token\n", ntfyTokenEnv)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 && *gotifyURL == "" {
+		fs.Usage()
+		return 2
+	}
+	if *digestWindow < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -digest-window cannot be negative")
+		return 2
+	}
+	var gotifyServer string
+	if *gotifyURL != "" {
+		var err error
+		if gotifyServer, err = parseGotifyServer(*gotifyURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		if os.Getenv(gotifyClientTokenEnv) == "" {
+			fmt.Fprintf(os.Stderr, "Error: -gotify needs a client token in %s\n", gotifyClientTokenEnv)
+			return 2
+		}
+	}
+	var targets []ntfyTarget
+	for _, arg := range fs.Args() {
+		target, err := parseNtfyTarget(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		targets = append(targets, target)
+	}
+	if !*debug {
+		log.SetOutput(io.Discard)
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get executable path: %v\n", err)
+		return 1
+	}
+	launch := newDigestLauncher(*digestWindow, func(specPath string, done func(*NotificationResult, error)) error {
+		return launchSpec(exePath, specPath, true, *debug, done)
+	})
+
+	for _, target := range targets {
+		fmt.Printf("notify subscribed to %s\n", target)
+		go (&ntfySubscriber{target: target, token: os.Getenv(ntfyTokenEnv), since: *since, client: &http.Client{}, launch: launch}).run()
+	}
+	if gotifyServer != "" {
+		fmt.Printf("notify receiving Gotify messages from %s\n", gotifyServer)
+		go (&gotifySubscriber{server: gotifyServer, token: os.Getenv(gotifyClientTokenEnv), client: &http.Client{}, launch: launch}).run()
+	}
+	select {}
+}
+
+// run keeps the subscription open, reconnecting after failures from the last message seen
+func (s *ntfySubscriber) run() {
+	backoff := time.Second
+	for {
+		connected := time.Now()
+		err := s.session(context.Background())
+		if time.Since(connected) > fleetMaxBackoff {
+			backoff = time.Second // The connection worked for a while: this is a new failure
+		}
+		log.Printf("Subscription to %s ended: %v, reconnecting in %s", s.target, err, backoff)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, fleetMaxBackoff)
+	}
+}
+
+// session subscribes to the topic and shows its messages until the connection ends
+func (s *ntfySubscriber) session(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	streamURL := s.target.String() + "/json"
+	if s.since != "" {
+		streamURL += "?since=" + url.QueryEscape(s.since)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "KrankyBearNotify/"+appVersion)
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ntfy answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	log.Printf("Subscribed to %s", s.target)
+
+	silence := time.AfterFunc(ntfySubscribeSilence, cancel)
+	defer silence.Stop()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 2*serveRequestLimit)
+	for scanner.Scan() {
+		silence.Reset(ntfySubscribeSilence)
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event ntfyEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			log.Printf("Ignoring invalid ntfy event: %v", err)
+			continue
+		}
+		if event.Event != "message" {
+			continue
+		}
+		s.since = event.ID // Reconnecting resumes after this message, without showing it again
+		s.show(event)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("ntfy closed the connection")
+}
+
+// show launches a notification for an ntfy message, mapped as by the /ntfy endpoint of notify serve
+func (s *ntfySubscriber) show(event ntfyEvent) {
+	spec, err := ntfySpec(event.ntfyMessage)
+	if err != nil {
+		log.Printf("Ignoring ntfy message %s: %v", event.ID, err)
+		return
+	}
+	launchPushSpec("ntfy message "+event.ID, spec, s.launch)
+}
+
+// launchPushSpec shows a notification for a received ntfy or Gotify message, unless the spec
+// made from it is not one notify serve would accept
+func launchPushSpec(name string, spec pushSpec, launch func(specPath string, done func(*NotificationResult, error)) error) {
+	data, _ := json.Marshal(spec)
+	if err := checkServeSpec(data); err != nil {
+		log.Printf("Ignoring %s: %v", name, err)
+		return
+	}
+	specFile, err := os.CreateTemp("", "notify-push-*.yaml")
+	if err != nil {
+		log.Printf("Could not show %s: %v", name, err)
+		return
+	}
+	specFile.Write(data)
+	specFile.Close()
+	err = launch(specFile.Name(), func(result *NotificationResult, err error) {
+		if err != nil {
+			log.Printf("%s: %v", name, err)
+			return
+		}
+		log.Printf("%s: %s", name, result.Action)
+	})
+	if err != nil {
+		log.Printf("Could not show %s: %v", name, err)
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// clickCommand returns the command line n runs when acknowledged with choice: the -on-choice
+// command for the chosen option, or else -on-click ("" for none)
+func clickCommand(n notify.Notification, choice string) string {
+	for _, entry := range n.OnChoice {
+		if option, command, _ := strings.Cut(entry, "="); choice != "" && strings.TrimSpace(option) == choice {
+			return command
+		}
+	}
+	return n.OnClick
+}
+
+// validateClickCommands checks -on-click and -on-choice: each command line must parse, and
+// each -on-choice must name one of the -choices options
+func validateClickCommands(n notify.Notification) error {
+	if n.OnClick != "" {
+		if _, err := splitCommandLine(n.OnClick); err != nil {
+			return fmt.Errorf("invalid -on-click: %v", err)
+		}
+	}
+	choices := n.ChoiceList()
+	for _, entry := range n.OnChoice {
+		option, command, found := strings.Cut(entry, "=")
+		if !found {
+			return fmt.Errorf("invalid -on-choice %q (use Option=command)", entry)
+		}
+		if choiceIndex(choices, strings.TrimSpace(option)) < 0 {
+			return fmt.Errorf("-on-choice option %q is not one of the -choices", strings.TrimSpace(option))
+		}
+		if _, err := splitCommandLine(command); err != nil {
+			return fmt.Errorf("invalid -on-choice command for %q: %v", strings.TrimSpace(option), err)
+		}
+	}
+	return nil
+}
+
+// splitCommandLine splits a command line into the program and its arguments on spaces
+// Double or single quotes group an argument with spaces; backslashes are kept as they are,
+// so Windows paths need no escaping
+func splitCommandLine(commandLine string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range commandLine {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, commandLine)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
+
+// runClickCommand starts the command for an acknowledgment with choice, detached so that
+// it keeps running after notify exits
+// It runs as the user who acknowledged: after an elevated fan-out, in that user's process
+func runClickCommand(n notify.Notification, choice string) {
+	commandLine := clickCommand(n, choice)
+	if commandLine == "" {
+		return
+	}
+	args, err := splitCommandLine(commandLine)
+	if err != nil {
+		log.Printf("Warning: Not running the click command: %v", err)
+		return
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		log.Printf("Warning: Could not run the click command %s: %v", args[0], err)
+		return
+	}
+	log.Printf("Started click command %s (pid %d)", args[0], cmd.Process.Pid)
+	// Don't wait: the command outlives this process
+	cmd.Process.Release()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestSplitCommandLine tests splitting -on-click command lines, with quotes and Windows paths
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`updater --now`, []string{"updater", "--now"}},
+		{`  C:\Tools\updater.exe   /now `, []string{`C:\Tools\updater.exe`, "/now"}},
+		{`"C:\Program Files\Updater\update.exe" /quiet`, []string{`C:\Program Files\Updater\update.exe`, "/quiet"}},
+		{`open -a 'Self Service' --args "patch ""now"""`, []string{"open", "-a", "Self Service", "--args", "patch now"}},
+		{`echo ""`, []string{"echo", ""}},
+	}
+	for _, tt := range tests {
+		got, err := splitCommandLine(tt.in)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommandLine(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "   ", `updater "unterminated`} {
+		if got, err := splitCommandLine(in); err == nil {
+			t.Errorf("splitCommandLine(%q) = %q, expected an error", in, got)
+		}
+	}
+}
+
+// TestClickCommand tests choosing between -on-choice and -on-click
+func TestClickCommand(t *testing.T) {
+	n := notify.Notification{
+		Choices:  "Now,Tonight,Tomorrow",
+		OnClick:  "updater --schedule",
+		OnChoice: []string{"Now=updater --now", " Tonight = updater --at 22:00"},
+	}
+	tests := map[string]string{
+		"Now":      "updater --now",
+		"Tonight":  " updater --at 22:00",
+		"Tomorrow": "updater --schedule",
+		"":         "updater --schedule",
+	}
+	for choice, want := range tests {
+		if got := clickCommand(n, choice); got != want {
+			t.Errorf("clickCommand(%q) = %q, want %q", choice, got, want)
+		}
+	}
+	if err := validateClickCommands(n); err != nil {
+		t.Errorf("validateClickCommands failed: %v", err)
+	}
+}
+
+// TestValidateClickCommands tests the errors for -on-click and -on-choice
+func TestValidateClickCommands(t *testing.T) {
+	tests := []struct {
+		n    notify.Notification
+		want string
+	}{
+		{notify.Notification{OnClick: `"unterminated`}, "invalid -on-click"},
+		{notify.Notification{Choices: "Now", OnChoice: []string{"Now"}}, "use Option=command"},
+		{notify.Notification{Choices: "Now", OnChoice: []string{"Later=updater"}}, `"Later" is not one of the -choices`},
+		{notify.Notification{OnChoice: []string{"Now=updater"}}, `"Now" is not one of the -choices`},
+		{notify.Notification{Choices: "Now", OnChoice: []string{"Now="}}, "empty command"},
+	}
+	for _, tt := range tests {
+		if err := validateClickCommands(tt.n); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("validateClickCommands(%+v) = %v, want an error containing %q", tt.n, err, tt.want)
+		}
+	}
+}
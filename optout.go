@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// categoryPattern is the form of -category names: lowercase, so opt-outs match however the sender spells them
+var categoryPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// validateCategory checks a -category value
+func validateCategory(category string) error {
+	if !categoryPattern.MatchString(category) {
+		return fmt.Errorf("invalid category %q (use lowercase letters, digits, '.', '_' and '-')", category)
+	}
+	return nil
+}
+
+// getOptOutPath returns the file listing the categories the current user opted out of
+func getOptOutPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "optouts.json"), nil
+}
+
+// loadOptOuts returns the categories the current user opted out of, sorted
+func loadOptOuts() ([]string, error) {
+	optOutPath, err := getOptOutPath()
+	if err != nil {
+		return nil, err
+	}
+	var categories []string
+	data, err := os.ReadFile(optOutPath)
+	if os.IsNotExist(err) {
+		return categories, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read opt-outs: %v", err)
+	}
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return nil, fmt.Errorf("could not parse opt-outs %s: %v", optOutPath, err)
+	}
+	sort.Strings(categories)
+	return categories, nil
+}
+
+// setOptOut adds category to, or removes it from, the current user's opt-outs
+func setOptOut(category string, optOut bool) error {
+	optOutPath, err := getOptOutPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(optOutPath), 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %v", err)
+	}
+	unlock, err := lockFile(optOutPath + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	categories, err := loadOptOuts()
+	if err != nil {
+		return err
+	}
+	var updated []string
+	for _, existing := range categories {
+		if existing != category {
+			updated = append(updated, existing)
+		}
+	}
+	if optOut {
+		updated = append(updated, category)
+	}
+	sort.Strings(updated)
+
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode opt-outs: %v", err)
+	}
+	tmpPath := optOutPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("could not write opt-outs: %v", err)
+	}
+	return os.Rename(tmpPath, optOutPath)
+}
+
+// mandatoryBy returns the config file whose policy makes category mandatory
+// (delivered even to users who opted out), or "" if none does
+func mandatoryBy(category string, configs []*notifyConfig) string {
+	for _, config := range configs {
+		if config.Policy == nil {
+			continue
+		}
+		for _, mandatory := range config.Policy.MandatoryCategories {
+			if mandatory == category {
+				return config.Path
+			}
+		}
+	}
+	return ""
+}
+
+// categorySuppressed reports whether the current user opted out of category and no
+// policy overrides it; an unreadable opt-out list delivers the notification
+func categorySuppressed(category string, configs []*notifyConfig) bool {
+	if category == "" {
+		return false
+	}
+	categories, err := loadOptOuts()
+	if err != nil {
+		log.Printf("Warning: Could not read opt-outs, delivering: %v", err)
+		return false
+	}
+	for _, optedOut := range categories {
+		if optedOut != category {
+			continue
+		}
+		if path := mandatoryBy(category, configs); path != "" {
+			log.Printf("User opted out of category %q, but the policy in %s makes it mandatory", category, path)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// runOptOut handles "notify optout list|add CATEGORY|remove CATEGORY" for the current user
+func runOptOut(args []string) int {
+	usage := "Usage: notify optout list | notify optout add CATEGORY | notify optout remove CATEGORY"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		categories, err := loadOptOuts()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(categories) == 0 {
+			fmt.Println("Not opted out of any categories")
+			return 0
+		}
+		configs := loadDefaultConfigs()
+		for _, category := range categories {
+			if path := mandatoryBy(category, configs); path != "" {
+				fmt.Printf("%s (mandatory by policy in %s, still delivered)\n", category, path)
+			} else {
+				fmt.Println(category)
+			}
+		}
+		return 0
+
+	case "add", "remove":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		category := args[1]
+		if err := validateCategory(category); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := setOptOut(category, args[0] == "add"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if args[0] == "remove" {
+			fmt.Printf("Opted back in to %s notifications\n", category)
+			return 0
+		}
+		fmt.Printf("Opted out of %s notifications\n", category)
+		if path := mandatoryBy(category, loadDefaultConfigs()); path != "" {
+			fmt.Printf("Note: the policy in %s makes %s mandatory, so they are still delivered\n", path, category)
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown optout command %q (use list, add or remove)\n", args[0])
+	return 2
+}
+
+// loadDefaultConfigs loads the user and system config files for their policies,
+// warning about (and skipping) files that cannot be loaded
+func loadDefaultConfigs() []*notifyConfig {
+	var configs []*notifyConfig
+	for _, path := range defaultConfigPaths() {
+		config, err := loadConfig(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		}
+		if config != nil {
+			configs = append(configs, config)
+		}
+	}
+	return configs
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestOptOutRoundTrip tests adding and removing category opt-outs for the current user
+func TestOptOutRoundTrip(t *testing.T) {
+	useTempStore(t)
+
+	if categorySuppressed("newsletter", nil) {
+		t.Fatal("Expected no suppression before opting out")
+	}
+	if err := setOptOut("newsletter", true); err != nil {
+		t.Fatalf("setOptOut failed: %v", err)
+	}
+	if err := setOptOut("newsletter", true); err != nil {
+		t.Fatalf("setOptOut twice failed: %v", err)
+	}
+	categories, err := loadOptOuts()
+	if err != nil {
+		t.Fatalf("loadOptOuts failed: %v", err)
+	}
+	if len(categories) != 1 || categories[0] != "newsletter" {
+		t.Errorf("Expected [newsletter], got %v", categories)
+	}
+	if !categorySuppressed("newsletter", nil) {
+		t.Error("Expected newsletter to be suppressed after opting out")
+	}
+	if categorySuppressed("security", nil) || categorySuppressed("", nil) {
+		t.Error("Expected other and empty categories to be delivered")
+	}
+
+	if err := setOptOut("newsletter", false); err != nil {
+		t.Fatalf("setOptOut(false) failed: %v", err)
+	}
+	if categorySuppressed("newsletter", nil) {
+		t.Error("Expected newsletter to be delivered after opting back in")
+	}
+}
+
+// TestMandatoryCategoryOverridesOptOut tests that a policy's mandatory categories are delivered despite opt-outs
+func TestMandatoryCategoryOverridesOptOut(t *testing.T) {
+	useTempStore(t)
+
+	for _, category := range []string{"security", "newsletter"} {
+		if err := setOptOut(category, true); err != nil {
+			t.Fatalf("setOptOut failed: %v", err)
+		}
+	}
+	configs := []*notifyConfig{
+		{Path: "/etc/krankybearnotify.yaml", Policy: &ContentPolicy{MandatoryCategories: []string{"security"}}},
+		{Path: "user.yaml"},
+	}
+	if categorySuppressed("security", configs) {
+		t.Error("Expected the mandatory security category to be delivered")
+	}
+	if !categorySuppressed("newsletter", configs) {
+		t.Error("Expected newsletter to stay suppressed")
+	}
+	if got := mandatoryBy("security", configs); got != "/etc/krankybearnotify.yaml" {
+		t.Errorf("mandatoryBy = %q, want /etc/krankybearnotify.yaml", got)
+	}
+}
+
+// TestValidateCategory tests -category names
+func TestValidateCategory(t *testing.T) {
+	for _, category := range []string{"newsletter", "it.maintenance", "patch_tuesday", "team-42"} {
+		if err := validateCategory(category); err != nil {
+			t.Errorf("validateCategory(%q) failed: %v", category, err)
+		}
+	}
+	for _, category := range []string{"", "Newsletter", "has space", "-leading", "../etc"} {
+		if err := validateCategory(category); err == nil {
+			t.Errorf("validateCategory(%q) = nil, want an error", category)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Package client talks to the HTTP API of notify serve, so Go programs can show notifications
+// and follow their results without running the notify CLI and parsing its output
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Client is a client of a notify serve instance
+type Client struct {
+	BaseURL    string       // e.g. "http://127.0.0.1:8787"
+	Token      string       // Bearer token of notify serve -token-file, empty for none
+	HTTPClient *http.Client // http.DefaultClient when nil; Send waits for the user, so avoid short timeouts
+}
+
+// Error is an error response of notify serve
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("notify serve: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// New returns a client for the notify serve instance at baseURL
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token}
+}
+
+// Send shows n and waits until it is acknowledged, times out or is handed off
+func (c *Client) Send(ctx context.Context, n Notification) (Result, error) {
+	var result Result
+	resp, err := c.post(ctx, n, true)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if err := decodeResponse(resp, http.StatusOK, &result); err != nil {
+		return result, err
+	}
+	result.ID = path.Base(resp.Header.Get("Location"))
+	return result, nil
+}
+
+// Submit shows n without waiting and returns its ID, for Get and Watch
+func (c *Client) Submit(ctx context.Context, n Notification) (string, error) {
+	resp, err := c.post(ctx, n, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var accepted struct {
+		ID string `json:"id"`
+	}
+	if err := decodeResponse(resp, http.StatusAccepted, &accepted); err != nil {
+		return "", err
+	}
+	return accepted.ID, nil
+}
+
+// SendOnce shows n under id, chosen by the caller (up to 128 letters, digits, '.', ':', '-' and
+// '_'), and waits like Send; notify serve shows each id only once, so retrying after a network
+// error or a crash cannot show it twice: an id it has a receipt of fails with an *Error of
+// StatusCode 409 (http.StatusConflict), and Reconcile tells what became of it
+func (c *Client) SendOnce(ctx context.Context, id string, n Notification) (Result, error) {
+	var result Result
+	resp, err := c.put(ctx, id, n, true)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if err := decodeResponse(resp, http.StatusOK, &result); err != nil {
+		return result, err
+	}
+	result.ID = id
+	return result, nil
+}
+
+// SubmitOnce shows n under id without waiting, only once like SendOnce
+func (c *Client) SubmitOnce(ctx context.Context, id string, n Notification) error {
+	resp, err := c.put(ctx, id, n, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var accepted struct {
+		ID string `json:"id"`
+	}
+	return decodeResponse(resp, http.StatusAccepted, &accepted)
+}
+
+// Reconcile returns the receipts notify serve has of ids, and the ids it has none of, which
+// the caller may resend with SendOnce or SubmitOnce (unless it has a result for them itself)
+func (c *Client) Reconcile(ctx context.Context, ids []string) (Reconciliation, error) {
+	var reconciliation Reconciliation
+	body, err := json.Marshal(map[string][]string{"ids": ids})
+	if err != nil {
+		return reconciliation, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/v1/receipts", body)
+	if err != nil {
+		return reconciliation, err
+	}
+	defer resp.Body.Close()
+	err = decodeResponse(resp, http.StatusOK, &reconciliation)
+	return reconciliation, err
+}
+
+// Receipts returns the receipts notify serve updated since since (all of them when zero),
+// oldest first
+func (c *Client) Receipts(ctx context.Context, since time.Time) ([]Receipt, error) {
+	endpoint := "/v1/receipts"
+	if !since.IsZero() {
+		endpoint += "?since=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	}
+	resp, err := c.do(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Receipts []Receipt `json:"receipts"`
+	}
+	err = decodeResponse(resp, http.StatusOK, &list)
+	return list.Receipts, err
+}
+
+// Get returns the notification with id, pending or finished
+// notify serve keeps the most recent notifications, and after that (or a restart) what its
+// delivery receipts recorded
+func (c *Client) Get(ctx context.Context, id string) (Record, error) {
+	var record Record
+	resp, err := c.do(ctx, http.MethodGet, "/v1/notifications/"+url.PathEscape(id), nil)
+	if err != nil {
+		return record, err
+	}
+	defer resp.Body.Close()
+	err = decodeResponse(resp, http.StatusOK, &record)
+	return record, err
+}
+
+// post sends n to POST /v1/notifications
+func (c *Client) post(ctx context.Context, n Notification, wait bool) (*http.Response, error) {
+	if n.Version == 0 {
+		n.Version = SpecVersion
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode notification: %v", err)
+	}
+	endpoint := "/v1/notifications"
+	if !wait {
+		endpoint += "?wait=false"
+	}
+	return c.do(ctx, http.MethodPost, endpoint, body)
+}
+
+// put sends n to PUT /v1/notifications/{id}
+func (c *Client) put(ctx context.Context, id string, n Notification, wait bool) (*http.Response, error) {
+	if n.Version == 0 {
+		n.Version = SpecVersion
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode notification: %v", err)
+	}
+	endpoint := "/v1/notifications/" + url.PathEscape(id)
+	if !wait {
+		endpoint += "?wait=false"
+	}
+	return c.do(ctx, http.MethodPut, endpoint, body)
+}
+
+// do sends a request to notify serve
+func (c *Client) do(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// decodeResponse decodes the body of resp into v, or returns the *Error of an unexpected status
+func decodeResponse(resp *http.Response, status int, v interface{}) error {
+	if resp.StatusCode != status {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, &failure) != nil || failure.Error == "" {
+			failure.Error = strings.TrimSpace(string(data))
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: failure.Error}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("could not decode notify serve response: %v", err)
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestSendChoice tests that the choices are posted in the spec and the chosen one is read from
+// the result, as notify serve answers once the user picked it (see TestServeChoice in the main
+// package for the same against notify serve itself)
+func TestSendChoice(t *testing.T) {
+	var posted map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"error":"missing or invalid bearer token"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/notifications":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Header().Set("Location", "/v1/notifications/4f1c2a9e")
+			io.WriteString(w, `{"machine_id":"m1","action":"acknowledged","method":"fyne","choice":"Tonight","title":"Reboot","timestamp":"2025-03-01T09:00:00Z"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/notifications/4f1c2a9e":
+			io.WriteString(w, `{"id":"4f1c2a9e","status":"done","result":{"action":"acknowledged","choice":"Tonight","title":"Reboot","timestamp":"2025-03-01T09:00:00Z"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	c := New(server.URL, "secret")
+	result, err := c.Send(ctx, Notification{Title: "Reboot", Message: "When?", Choices: []string{"Now", "Tonight"}, Timeout: Int(0)})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if choices, _ := posted["choices"].([]interface{}); len(choices) != 2 || choices[1] != "Tonight" || posted["timeout"] != 0.0 {
+		t.Errorf("Unexpected spec posted: %v", posted)
+	}
+	if result.ID != "4f1c2a9e" || result.Action != ActionAcknowledged || result.Choice != "Tonight" || result.Timestamp.IsZero() {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if record, err := c.Get(ctx, result.ID); err != nil || record.Status != StatusDone || record.Result == nil || record.Result.Choice != "Tonight" {
+		t.Errorf("Get = %+v, %v", record, err)
+	}
+
+	_, err = New(server.URL, "wrong").Send(ctx, Notification{Title: "Reboot"})
+	if apiErr, ok := err.(*Error); !ok || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "missing or invalid bearer token" {
+		t.Errorf("Expected a 401 *Error with the message, got %v", err)
+	}
+}
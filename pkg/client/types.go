@@ -0,0 +1,157 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SpecVersion is the notification spec version the client sends
+const SpecVersion = 1
+
+// Result actions, as reported by the notify CLI
+const (
+	ActionAcknowledged = "acknowledged" // User clicked the button
+	ActionTimeout      = "timeout"      // Notification closed itself after the timeout
+	ActionDelivered    = "delivered"    // Handed off (wall broadcast, other users' sessions), no ack available
+	ActionSkipped      = "skipped"      // Not displayed on this machine (e.g. outside the rollout)
+	ActionSuppressed   = "suppressed"   // Not displayed because the user opted out of its category
+	ActionReplaced     = "replaced"     // Shown in the window already open with the same ID
+	ActionCanceled     = "canceled"     // Window closed by Cancel, or by a later notification with the same ID
+)
+
+// Statuses of a Record
+const (
+	StatusPending     = "pending"     // Shown, not finished yet
+	StatusDone        = "done"        // Finished, Result is set
+	StatusFailed      = "failed"      // The notify process failed, Error says why
+	StatusInterrupted = "interrupted" // notify serve stopped while it was shown; it may have been seen and is not shown again
+)
+
+// Notification is a notification spec (see schema/notification-spec.schema.json), sent as JSON
+// Empty fields are left out, so the notify defaults apply; use Int for a Timeout of 0 (no timeout)
+type Notification struct {
+	Version          int         `json:"version"` // SpecVersion when 0
+	Title            string      `json:"title,omitempty"`
+	Message          string      `json:"message,omitempty"`
+	HTML             string      `json:"html,omitempty"`
+	Button           string      `json:"button,omitempty"`
+	Timeout          *int        `json:"timeout,omitempty"` // Seconds, 0 for no timeout
+	Width            int         `json:"width,omitempty"`
+	Height           int         `json:"height,omitempty"`
+	Icon             string      `json:"icon,omitempty"` // Path on the machine running notify serve
+	IconData         string      `json:"icon_data,omitempty"`
+	BuiltinIcon      string      `json:"builtin_icon,omitempty"`
+	Link             string      `json:"link,omitempty"`
+	Category         string      `json:"category,omitempty"`
+	Sound            string      `json:"sound,omitempty"`    // "system", a file on the machine running notify serve, or a system sound name
+	Urgency          string      `json:"urgency,omitempty"`  // "info", "warning" or "critical"
+	Theme            string      `json:"theme,omitempty"`    // "auto", "dark" or "light"
+	BgColor          string      `json:"bg_color,omitempty"` // Hex, e.g. "#1b1f3a"
+	FgColor          string      `json:"fg_color,omitempty"`
+	AccentColor      string      `json:"accent_color,omitempty"`
+	Font             string      `json:"font,omitempty"` // .ttf or .otf on the machine running notify serve
+	FontSize         int         `json:"font_size,omitempty"`
+	TimeZone         string      `json:"timezone,omitempty"`
+	Locale           string      `json:"locale,omitempty"` // e.g. "de"; empty for each user's language
+	Priority         string      `json:"priority,omitempty"`
+	BreakGlassToken  string      `json:"breakglass_token,omitempty"`
+	Input            bool        `json:"input,omitempty"`
+	InputDefault     string      `json:"input_default,omitempty"`
+	InputPlaceholder string      `json:"input_placeholder,omitempty"`
+	Choices          []string    `json:"choices,omitempty"`
+	OTP              string      `json:"otp,omitempty"`
+	OTPExpiry        string      `json:"otp_expiry,omitempty"` // Go duration, e.g. "5m"
+	Sensitive        bool        `json:"sensitive,omitempty"`
+	RedactAfterAck   bool        `json:"redact_after_ack,omitempty"`
+	AttentionAfter   string      `json:"attention_after,omitempty"` // Go duration, e.g. "60s"
+	AckFile          string      `json:"ack_file,omitempty"`        // File on the machine running notify serve, or "default"
+	RespectDND       string      `json:"respect_dnd,omitempty"`     // "defer" or "downgrade"
+	OverrideDND      bool        `json:"override_dnd,omitempty"`
+	Monitor          string      `json:"monitor,omitempty"` // "1", "2"..., "primary" or "all"
+	Topmost          bool        `json:"topmost,omitempty"`
+	Frameless        bool        `json:"frameless,omitempty"`
+	Fullscreen       bool        `json:"fullscreen,omitempty"`
+	Stacking         string      `json:"stacking,omitempty"` // "stack", "queue" or "overlap"
+	HideCountdown    bool        `json:"hide_countdown,omitempty"`
+	RTL              bool        `json:"rtl,omitempty"`
+	ID               string      `json:"id,omitempty"` // Window ID: a later notification with the same ID closes or (Replace) updates it
+	Replace          bool        `json:"replace,omitempty"`
+	Cancel           string      `json:"cancel,omitempty"`   // Close the window shown with this ID instead of showing a notification
+	State            string      `json:"state,omitempty"`    // "pending", "working", "success" or "failed"
+	StateID          string      `json:"state_id,omitempty"` // Changed with notify state on the machine running notify serve
+	StateIcons       *StateIcons `json:"state_icons,omitempty"`
+	Delivery         *Delivery   `json:"delivery,omitempty"`
+	Schedule         *Schedule   `json:"schedule,omitempty"`
+}
+
+// StateIcons are image files, on the machine running notify serve, replacing the state icons
+type StateIcons struct {
+	Pending string `json:"pending,omitempty"`
+	Working string `json:"working,omitempty"`
+	Success string `json:"success,omitempty"`
+	Failed  string `json:"failed,omitempty"`
+}
+
+// Delivery selects how a Notification is shown
+type Delivery struct {
+	Mode         string `json:"mode,omitempty"`         // auto, quick, native, wall, webview or basic
+	Disconnected string `json:"disconnected,omitempty"` // Windows RDP: skip, queue or deliver-on-reconnect
+	GUIOnly      bool   `json:"gui_only,omitempty"`
+	ForceWall    bool   `json:"force_wall,omitempty"`
+}
+
+// Schedule delays a Notification to business hours or a deadline
+type Schedule struct {
+	BusinessHours string `json:"business_hours,omitempty"`
+	Calendar      string `json:"calendar,omitempty"`
+	DeliverBy     string `json:"deliver_by,omitempty"`
+}
+
+// Result is what happened to a notification (the notify -result-json payload)
+type Result struct {
+	ID         string          `json:"-"` // Set by Client.Send from the Location header
+	MachineID  string          `json:"machine_id"`
+	Action     string          `json:"action"`           // One of the Action constants
+	Method     string          `json:"method,omitempty"` // "fyne", "webview", "messagebox", "wall", "users", ...
+	Priority   string          `json:"priority,omitempty"`
+	Screenshot []byte          `json:"screenshot,omitempty"` // PNG the user agreed to share
+	Input      *string         `json:"input,omitempty"`      // Text entered for Input, when acknowledged
+	Choice     string          `json:"choice,omitempty"`     // Option chosen from Choices
+	Downgrades []string        `json:"downgrades,omitempty"` // What Method could not show as given
+	Title      string          `json:"title"`
+	Variant    string          `json:"variant,omitempty"`
+	Digest     int             `json:"digest,omitempty"` // Notifications shown together in one digest by notify serve -digest-window
+	Timestamp  time.Time       `json:"timestamp"`
+	Inventory  json.RawMessage `json:"inventory,omitempty"`
+}
+
+// Record is a notification posted to notify serve: pending until it finishes with a Result
+type Record struct {
+	ID     string  `json:"id"`
+	Status string  `json:"status"` // One of the Status constants
+	Result *Result `json:"result,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// Receipt records that notify serve received a notification ID, and what became of it
+type Receipt struct {
+	ID       string    `json:"id"`
+	Status   string    `json:"status"` // One of the Status constants
+	Result   *Result   `json:"result,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Received time.Time `json:"received"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Reconciliation is the answer of notify serve to Reconcile
+type Reconciliation struct {
+	Receipts []Receipt `json:"receipts"` // Of the IDs notify serve received, in any status
+	Unknown  []string  `json:"unknown"`  // IDs it has no receipt of: never received, or forgotten (re-imaged, over 400 days old)
+}
+
+// Int returns a pointer to v, for Notification.Timeout
+func Int(v int) *int {
+	return &v
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
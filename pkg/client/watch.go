@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Watcher is a stream of notifications finishing on a notify serve instance, opened by Watch
+type Watcher struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+}
+
+// Watch opens a stream of the notifications that finish from now on, done or failed,
+// including those posted by other clients; the stream ends when ctx is canceled or Close is called
+func (c *Client) Watch(ctx context.Context) (*Watcher, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/v1/results", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, decodeResponse(resp, http.StatusOK, nil)
+	}
+	return &Watcher{body: resp.Body, decoder: json.NewDecoder(resp.Body)}, nil
+}
+
+// Next blocks until the next notification finishes and returns it
+// It returns io.EOF when notify serve closed the stream, and an error once the context is
+// canceled or the connection breaks
+func (w *Watcher) Next() (Record, error) {
+	var record Record
+	if err := w.decoder.Decode(&record); err != nil {
+		if err == io.EOF {
+			return record, io.EOF
+		}
+		return record, fmt.Errorf("results stream: %v", err)
+	}
+	return record, nil
+}
+
+// Close ends the stream
+func (w *Watcher) Close() error {
+	return w.body.Close()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
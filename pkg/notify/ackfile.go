@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AckFileDefault as Notification.AckFile watches the current user's acknowledgment file, the
+// one "notify ack" touches, so a desktop keyboard shortcut running it acknowledges any window
+const AckFileDefault = "default"
+
+// ackFilePollInterval is how often a window with an AckFile checks it
+const ackFilePollInterval = 250 * time.Millisecond
+
+// AckFilePath returns the file behind a Notification.AckFile value: the value itself, or for
+// AckFileDefault the acknowledgment file of the current user
+func AckFilePath(value string) (string, error) {
+	if value != AckFileDefault {
+		return value, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "ack"), nil
+}
+
+// ValidateAckFile checks an -ack-file value: the file may not exist yet, but must not be a
+// directory, and its directory must exist so the user can create it
+func ValidateAckFile(value string) error {
+	if value == "" || value == AckFileDefault {
+		return nil
+	}
+	if info, err := os.Stat(value); err == nil && info.IsDir() {
+		return fmt.Errorf("invalid ack file %q: is a directory", value)
+	}
+	if info, err := os.Stat(filepath.Dir(value)); err != nil || !info.IsDir() {
+		return fmt.Errorf("invalid ack file %q: directory %s does not exist", value, filepath.Dir(value))
+	}
+	return nil
+}
+
+// TouchAckFile creates the acknowledgment file of value, or updates its modification time,
+// acknowledging the windows watching it (notify ack)
+func TouchAckFile(value string) error {
+	path, err := AckFilePath(value)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create %s: %v", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create ack file: %v", err)
+	}
+	f.Close()
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return fmt.Errorf("could not touch ack file: %v", err)
+	}
+	return nil
+}
+
+// watchAckFile returns a channel closed when the acknowledgment file of value is created or
+// touched after the window opened; the watch ends when stop is closed
+// Changes are detected by comparing with the file as it was, not with the clock, because file
+// systems store modification times coarsely (FAT to 2 seconds) and network shares have their own clock
+func watchAckFile(value string, stop <-chan struct{}) <-chan struct{} {
+	acked := make(chan struct{})
+	path, err := AckFilePath(value)
+	if err != nil {
+		log.Printf("Warning: Ack file unavailable: %v", err)
+		return acked
+	}
+	before, _ := os.Stat(path) // nil when the file does not exist yet
+	log.Printf("Ack file: touch %s to acknowledge", path)
+	go func() {
+		ticker := time.NewTicker(ackFilePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if ackFileTouched(path, before) {
+				log.Printf("Ack file: %s touched, acknowledging", path)
+				close(acked)
+				return
+			}
+		}
+	}()
+	return acked
+}
+
+// ackFileTouched reports whether path was created or modified since it was before (nil when
+// it did not exist)
+func ackFileTouched(path string, before os.FileInfo) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return before == nil || !info.ModTime().Equal(before.ModTime()) || info.Size() != before.Size()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
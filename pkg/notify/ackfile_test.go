@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestValidateAckFile tests the -ack-file checks
+func TestValidateAckFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, value := range []string{"", AckFileDefault, filepath.Join(dir, "ack")} {
+		if err := ValidateAckFile(value); err != nil {
+			t.Errorf("ValidateAckFile(%q) = %v", value, err)
+		}
+	}
+	for _, value := range []string{dir, filepath.Join(dir, "missing", "ack")} {
+		if err := ValidateAckFile(value); err == nil {
+			t.Errorf("ValidateAckFile(%q) accepted it", value)
+		}
+	}
+}
+
+// TestWatchAckFile tests that notify ack acknowledges a window watching the user's own file,
+// whether it existed before the window opened or not
+func TestWatchAckFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // os.UserConfigDir on Linux
+	t.Setenv("HOME", dir)            // macOS
+	t.Setenv("AppData", dir)         // Windows
+
+	for _, existing := range []bool{false, true} {
+		stop := make(chan struct{})
+		acked := watchAckFile(AckFileDefault, stop)
+		select {
+		case <-acked:
+			t.Fatalf("acknowledged before the file was touched (existing %v)", existing)
+		case <-time.After(2 * ackFilePollInterval):
+		}
+		if err := TouchAckFile(AckFileDefault); err != nil {
+			t.Fatalf("TouchAckFile failed: %v", err)
+		}
+		select {
+		case <-acked:
+		case <-time.After(5 * time.Second):
+			t.Errorf("not acknowledged after the file was touched (existing %v)", existing)
+		}
+		close(stop)
+	}
+
+	path, err := AckFilePath(AckFileDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("TouchAckFile did not create %s: %v", path, err)
+	}
+}
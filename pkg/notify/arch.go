@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Emulation describes a notify binary built for another architecture than the machine's,
+// run by the operating system's translation layer
+type Emulation struct {
+	ProcessArch string // Architecture the binary was built for (runtime.GOARCH)
+	NativeArch  string // Architecture of the machine, e.g. "arm64"
+	Translator  string // What runs it, e.g. "Rosetta 2" or "x64 emulation"
+}
+
+// DetectEmulation reports whether this process runs under emulation: Rosetta 2 on Apple
+// Silicon, or x64/x86 emulation on Windows on ARM; nil when it runs natively
+func DetectEmulation() *Emulation {
+	return detectEmulation()
+}
+
+// String describes the emulation for version and check output
+func (e *Emulation) String() string {
+	return fmt.Sprintf("%s build running under %s on %s", e.ProcessArch, e.Translator, e.NativeArch)
+}
+
+// emulatedOpenGLUnreliable reports whether OpenGL is not to be trusted under emulation e, so
+// IsOpenGLAvailable reports it missing: the OpenGL of x64 emulation on Windows on ARM is a
+// compatibility layer that Fyne windows often fail to draw with (Rosetta 2 translates it reliably)
+func emulatedOpenGLUnreliable(e *Emulation) bool {
+	return e != nil && runtime.GOOS == "windows"
+}
+
+// NativeBuild returns the path of a notify build for the machine's own architecture next to the
+// running executable (for example notify-macos-arm64 next to notify-macos-amd64), or "" when the
+// process is not emulated or there is none
+func NativeBuild() string {
+	e := DetectEmulation()
+	if e == nil {
+		return ""
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	for _, candidate := range nativeBuildCandidates(exePath, e.ProcessArch, e.NativeArch) {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// nativeBuildCandidates returns where a native build of exePath may be: the same name with the
+// architecture replaced (the release naming), then notify-ARCH in the same directory
+func nativeBuildCandidates(exePath, processArch, nativeArch string) []string {
+	dir, base := filepath.Split(exePath)
+	ext := filepath.Ext(base)
+	var candidates []string
+	aliases := map[string][]string{
+		"amd64": {"amd64", "x86_64", "x64"},
+		"386":   {"386", "x86"},
+	}
+	names := aliases[processArch]
+	if names == nil {
+		names = []string{processArch}
+	}
+	for _, name := range names {
+		if strings.Contains(base, name) {
+			candidates = append(candidates, filepath.Join(dir, strings.Replace(base, name, nativeArch, 1)))
+			break
+		}
+	}
+	candidates = append(candidates, filepath.Join(dir, "notify-"+nativeArch+ext))
+	return candidates
+}
+
+// ReportEmulation prints whether this process runs under emulation and, if so, the native build
+// to run instead; returns true when it runs natively
+func ReportEmulation() bool {
+	e := DetectEmulation()
+	if e == nil {
+		fmt.Printf("Architecture: %s (native)\n", runtime.GOARCH)
+		return true
+	}
+	fmt.Printf("Architecture: %s\n", e)
+	if emulatedOpenGLUnreliable(e) {
+		fmt.Println("OpenGL under emulation is unreliable: WebView or MessageBox windows are used instead of Fyne")
+	}
+	if native := NativeBuild(); native != "" {
+		fmt.Printf("Native build available: %s (run it instead, or pass -prefer-native-arch)\n", native)
+	} else {
+		fmt.Printf("Install the %s build of notify for native performance\n", e.NativeArch)
+	}
+	return false
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,21 @@
+//go:build darwin
+
+package notify
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// detectEmulation asks the kernel whether this process is translated by Rosetta 2
+// (sysctl.proc_translated is 1 when translated, 0 when native, and missing on Intel Macs)
+func detectEmulation() *Emulation {
+	output, err := exec.Command("sysctl", "-n", "sysctl.proc_translated").Output()
+	if err != nil || strings.TrimSpace(string(output)) != "1" {
+		return nil
+	}
+	return &Emulation{ProcessArch: runtime.GOARCH, NativeArch: "arm64", Translator: "Rosetta 2"}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,11 @@
+//go:build !darwin && !windows
+
+package notify
+
+// detectEmulation reports native execution: Linux binaries for another architecture run
+// through binfmt handlers such as QEMU, which are not detected
+func detectEmulation() *Emulation {
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
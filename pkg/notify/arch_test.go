@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestNativeBuildCandidates tests where the native build of an emulated binary is looked for
+func TestNativeBuildCandidates(t *testing.T) {
+	dir := filepath.Join("opt", "notify")
+	tests := []struct {
+		exe, processArch string
+		want             []string
+	}{
+		{"notify-macos-amd64", "amd64", []string{"notify-macos-arm64", "notify-arm64"}},
+		{"notify-win-amd64.exe", "amd64", []string{"notify-win-arm64.exe", "notify-arm64.exe"}},
+		{"notify-x86_64", "amd64", []string{"notify-arm64", "notify-arm64"}},
+		{"notify-386.exe", "386", []string{"notify-arm64.exe", "notify-arm64.exe"}},
+		{"notify.exe", "amd64", []string{"notify-arm64.exe"}},
+	}
+	for _, tt := range tests {
+		var want []string
+		for _, name := range tt.want {
+			want = append(want, filepath.Join(dir, name))
+		}
+		if got := nativeBuildCandidates(filepath.Join(dir, tt.exe), tt.processArch, "arm64"); !reflect.DeepEqual(got, want) {
+			t.Errorf("nativeBuildCandidates(%q, %q) = %v, want %v", tt.exe, tt.processArch, got, want)
+		}
+	}
+}
+
+// TestEmulatedOpenGLUnreliable tests that native processes keep using OpenGL
+func TestEmulatedOpenGLUnreliable(t *testing.T) {
+	if emulatedOpenGLUnreliable(nil) {
+		t.Error("emulatedOpenGLUnreliable(nil) = true, want false")
+	}
+}
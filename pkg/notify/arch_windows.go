@@ -0,0 +1,38 @@
+//go:build windows
+
+package notify
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var isWow64Process2 = syscall.NewLazyDLL("kernel32.dll").NewProc("IsWow64Process2")
+
+// detectEmulation compares the machine's architecture (IsWow64Process2) with the one notify was
+// built for: on Windows on ARM, x64 builds run under x64 emulation and x86 builds under WOW64
+func detectEmulation() *Emulation {
+	const IMAGE_FILE_MACHINE_ARM64 = 0xAA64
+	if isWow64Process2.Find() != nil {
+		return nil // Before Windows 10 1511, which has no ARM64 release
+	}
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return nil
+	}
+	var processMachine, nativeMachine uint16
+	if ok, _, _ := isWow64Process2.Call(uintptr(process), uintptr(unsafe.Pointer(&processMachine)), uintptr(unsafe.Pointer(&nativeMachine))); ok == 0 {
+		return nil
+	}
+	if nativeMachine != IMAGE_FILE_MACHINE_ARM64 || runtime.GOARCH == "arm64" {
+		return nil
+	}
+	translator := "x64 emulation"
+	if runtime.GOARCH == "386" {
+		translator = "WOW64 x86 emulation"
+	}
+	return &Emulation{ProcessArch: runtime.GOARCH, NativeArch: "arm64", Translator: translator}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"image/color"
+	"log"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+)
+
+// attentionColor is the highlight the window background pulses to when it is being ignored
+var attentionColor = color.NRGBA{R: 255, G: 193, B: 7, A: 160}
+
+// watchAttention draws attention to w while the user has not interacted with it: every
+// after, the background pulses, the window flashes in the taskbar and is raised to the top
+// Bringing the window to the foreground or typing into it counts as interaction
+// (a click on the window brings it to the foreground)
+func watchAttention(a fyne.App, w fyne.Window, after time.Duration, background *canvas.Rectangle) {
+	var mu sync.Mutex
+	done := false
+	stop := func() {
+		mu.Lock()
+		done = true
+		mu.Unlock()
+	}
+	a.Lifecycle().SetOnEnteredForeground(stop)
+	w.Canvas().SetOnTypedKey(func(*fyne.KeyEvent) { stop() })
+	w.SetOnClosed(stop)
+
+	pulse := canvas.NewColorRGBAAnimation(color.Transparent, attentionColor, 600*time.Millisecond, func(c color.Color) {
+		background.FillColor = c
+		background.Refresh()
+	})
+	pulse.AutoReverse = true
+	pulse.RepeatCount = 2
+
+	go func() {
+		for {
+			time.Sleep(after)
+			mu.Lock()
+			ignored := !done
+			mu.Unlock()
+			if !ignored {
+				return
+			}
+			log.Printf("No interaction for %v, drawing attention to the notification", after)
+			fyne.Do(func() {
+				pulse.Start()
+				w.RequestFocus()
+			})
+			if err := requestWindowAttention(w.Title()); err != nil {
+				log.Printf("Warning: Could not flash or raise the window: %v", err)
+			}
+		}
+	}()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
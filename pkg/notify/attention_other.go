@@ -0,0 +1,64 @@
+//go:build !windows
+
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// requestWindowAttention marks the window titled title as needing attention and raises it
+// macOS: makes this process the frontmost application (System Events, may need Automation permission)
+// Linux: wmctrl (urgency hint, which flashes the taskbar entry, then activate), falling back to xdotool
+// Wayland compositors do not let clients raise windows, so only the pulse is shown there
+func requestWindowAttention(title string) error {
+	var commands [][]string
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf(`tell application "System Events" to set frontmost of (first process whose unix id is %d) to true`, os.Getpid())
+		commands = [][]string{{"osascript", "-e", script}}
+	} else if _, err := exec.LookPath("wmctrl"); err == nil {
+		commands = [][]string{
+			{"wmctrl", "-F", "-r", title, "-b", "add,demands_attention"},
+			{"wmctrl", "-F", "-a", title},
+		}
+	} else if _, err := exec.LookPath("xdotool"); err == nil {
+		commands = [][]string{{"xdotool", "search", "--name", "^" + regexp.QuoteMeta(title) + "$", "windowactivate"}}
+	} else {
+		return fmt.Errorf("neither wmctrl nor xdotool is installed")
+	}
+
+	for _, command := range commands {
+		output, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s failed: %v (output: %s)", command[0], err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// keepWindowOnTop keeps the window titled title above other windows, waiting up to 5 seconds
+// for the GUI to create it
+// Linux: wmctrl (X11 and XWayland); macOS has no way to do this for another toolkit's window
+func keepWindowOnTop(title string) error {
+	if runtime.GOOS == "darwin" {
+		return fmt.Errorf("not supported on macOS")
+	}
+	if _, err := exec.LookPath("wmctrl"); err != nil {
+		return fmt.Errorf("wmctrl is not installed")
+	}
+	var output []byte
+	var err error
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(250 * time.Millisecond) {
+		if output, err = exec.Command("wmctrl", "-F", "-r", title, "-b", "add,above").CombinedOutput(); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("wmctrl failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,51 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Window functions in user32.dll (declared in gui_check_windows.go)
+var (
+	findWindow          = user32.NewProc("FindWindowW")
+	flashWindowEx       = user32.NewProc("FlashWindowEx")
+	setForegroundWindow = user32.NewProc("SetForegroundWindow")
+)
+
+// flashWInfo is FLASHWINFO for FlashWindowEx
+type flashWInfo struct {
+	cbSize    uint32
+	hwnd      uintptr
+	dwFlags   uint32
+	uCount    uint32
+	dwTimeout uint32
+}
+
+// requestWindowAttention flashes the taskbar button of the window titled title until
+// it comes to the foreground, and asks Windows to bring it there
+// Windows may refuse the foreground change, the flashing button is then the signal
+func requestWindowAttention(title string) error {
+	const (
+		FLASHW_ALL       = 0x00000003 // Caption and taskbar button
+		FLASHW_TIMERNOFG = 0x0000000C // Until the window comes to the foreground
+	)
+	titlePtr, err := syscall.UTF16PtrFromString(title)
+	if err != nil {
+		return err
+	}
+	hwnd, _, _ := findWindow.Call(0, uintptr(unsafe.Pointer(titlePtr)))
+	if hwnd == 0 {
+		return fmt.Errorf("window %q not found", title)
+	}
+
+	info := flashWInfo{hwnd: hwnd, dwFlags: FLASHW_ALL | FLASHW_TIMERNOFG}
+	info.cbSize = uint32(unsafe.Sizeof(info))
+	flashWindowEx.Call(uintptr(unsafe.Pointer(&info)))
+	setForegroundWindow.Call(hwnd)
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// automationTimeout bounds how long an -automation request waits for the window to answer
+const automationTimeout = 5 * time.Second
+
+// AutomationSnapshot is what the -automation socket reports of the open window
+type AutomationSnapshot struct {
+	Renderer string             `json:"renderer"` // "fyne" or "webview"
+	Title    string             `json:"title"`
+	Texts    []string           `json:"texts"` // Visible text, top to bottom
+	Buttons  []AutomationButton `json:"buttons"`
+}
+
+// AutomationButton is a button of the window, clicked by its Name
+type AutomationButton struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// automationWindow is the open window an -automation socket drives
+type automationWindow interface {
+	snapshot() (AutomationSnapshot, error)
+	click(name string) error
+}
+
+// startAutomation serves window to test scripts on the Unix socket at path, until stop is called:
+// GET /v1/window returns an AutomationSnapshot, POST /v1/click {"button": NAME} clicks a button
+// The socket is only accessible to the current user
+func startAutomation(path string, window automationWindow) (stop func(), err error) {
+	os.Remove(path) // Left by a process that did not exit cleanly
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %v", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("could not restrict %s: %v", path, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/window", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			automationReply(w, http.StatusMethodNotAllowed, map[string]string{"error": "use GET"})
+			return
+		}
+		snapshot, err := window.snapshot()
+		if err != nil {
+			automationReply(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			return
+		}
+		automationReply(w, http.StatusOK, snapshot)
+	})
+	mux.HandleFunc("/v1/click", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			automationReply(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+			return
+		}
+		var request struct {
+			Button string `json:"button"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&request); err != nil || request.Button == "" {
+			automationReply(w, http.StatusBadRequest, map[string]string{"error": `use {"button": "NAME"}`})
+			return
+		}
+		if err := window.click(request.Button); err != nil {
+			automationReply(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		automationReply(w, http.StatusOK, map[string]string{"clicked": request.Button})
+	})
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: automationTimeout}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Automation: %v", err)
+		}
+	}()
+	log.Printf("Automation: serving the window on %s", path)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+		os.Remove(path)
+	}, nil
+}
+
+// automationReply writes v as the JSON response of an -automation request
+func automationReply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// fyneAutomation drives a Fyne window for -automation
+type fyneAutomation struct {
+	w fyne.Window
+}
+
+// snapshot returns the visible text and buttons of the window, read on the Fyne thread
+func (f fyneAutomation) snapshot() (AutomationSnapshot, error) {
+	snapshot := AutomationSnapshot{Renderer: "fyne", Texts: []string{}, Buttons: []AutomationButton{}}
+	fyne.DoAndWait(func() {
+		snapshot.Title = f.w.Title()
+		walkFyneObjects(f.w.Content(), func(o fyne.CanvasObject) {
+			if text := fyneObjectText(o); text != "" {
+				snapshot.Texts = append(snapshot.Texts, text)
+			}
+			if b, ok := o.(*widget.Button); ok {
+				snapshot.Buttons = append(snapshot.Buttons, AutomationButton{Name: b.Text, Enabled: !b.Disabled()})
+			}
+		})
+	})
+	return snapshot, nil
+}
+
+// click taps the first visible button named name, as the user would
+func (f fyneAutomation) click(name string) error {
+	var found *widget.Button
+	fyne.DoAndWait(func() {
+		walkFyneObjects(f.w.Content(), func(o fyne.CanvasObject) {
+			if b, ok := o.(*widget.Button); ok && found == nil && b.Text == name {
+				found = b
+			}
+		})
+	})
+	switch {
+	case found == nil:
+		return fmt.Errorf("no button %q", name)
+	case found.Disabled():
+		return fmt.Errorf("button %q is disabled", name)
+	case found.OnTapped != nil:
+		fyne.Do(found.OnTapped)
+	}
+	return nil
+}
+
+// walkFyneObjects calls visit with o and each visible object inside it, in layout order
+func walkFyneObjects(o fyne.CanvasObject, visit func(fyne.CanvasObject)) {
+	if o == nil || !o.Visible() {
+		return
+	}
+	visit(o)
+	switch o := o.(type) {
+	case *fyne.Container:
+		for _, child := range o.Objects {
+			walkFyneObjects(child, visit)
+		}
+	case *container.Scroll:
+		walkFyneObjects(o.Content, visit)
+	case *widget.Accordion:
+		for _, item := range o.Items {
+			if item.Open {
+				walkFyneObjects(item.Detail, visit)
+			}
+		}
+	}
+}
+
+// fyneObjectText returns the text o shows, "" for objects without text (buttons are listed apart)
+func fyneObjectText(o fyne.CanvasObject) string {
+	switch o := o.(type) {
+	case *widget.Label:
+		return o.Text
+	case *widget.RichText:
+		return strings.TrimSpace(o.String())
+	case *widget.Hyperlink:
+		return o.Text
+	case *widget.Entry:
+		return o.Text
+	case *widget.Select:
+		return o.Selected
+	case *widget.Check:
+		return o.Text
+	case *widget.Accordion:
+		titles := make([]string, len(o.Items))
+		for i, item := range o.Items {
+			titles[i] = item.Title
+		}
+		return strings.Join(titles, "\n")
+	case *canvas.Text:
+		return o.Text
+	}
+	return ""
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
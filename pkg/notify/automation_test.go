@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeAutomationWindow is a window with an OK and a disabled Snooze button
+type fakeAutomationWindow struct {
+	clicked []string
+}
+
+func (f *fakeAutomationWindow) snapshot() (AutomationSnapshot, error) {
+	return AutomationSnapshot{
+		Renderer: "fake",
+		Title:    "Patch",
+		Texts:    []string{"Reboot tonight"},
+		Buttons:  []AutomationButton{{Name: "OK", Enabled: true}, {Name: "Snooze"}},
+	}, nil
+}
+
+func (f *fakeAutomationWindow) click(name string) error {
+	if name != "OK" {
+		return fmt.Errorf("button %q is disabled", name)
+	}
+	f.clicked = append(f.clicked, name)
+	return nil
+}
+
+// TestAutomation tests reading the window and clicking its buttons over the -automation socket
+func TestAutomation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kbn") // Short, as Unix socket paths are limited to about 100 bytes
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.sock")
+
+	window := &fakeAutomationWindow{}
+	stop, err := startAutomation(path, window)
+	if err != nil {
+		t.Fatalf("startAutomation failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, %v, want 0600", info, err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+
+	resp, err := client.Get("http://notify/v1/window")
+	if err != nil {
+		t.Fatalf("GET /v1/window failed: %v", err)
+	}
+	var snapshot AutomationSnapshot
+	err = json.NewDecoder(resp.Body).Decode(&snapshot)
+	resp.Body.Close()
+	if err != nil || snapshot.Title != "Patch" || len(snapshot.Buttons) != 2 {
+		t.Errorf("GET /v1/window = %+v, %v", snapshot, err)
+	}
+
+	tests := map[string]int{
+		`{"button": "OK"}`:     http.StatusOK,
+		`{"button": "Snooze"}`: http.StatusConflict,
+		`{}`:                   http.StatusBadRequest,
+	}
+	for body, want := range tests {
+		resp, err := client.Post("http://notify/v1/click", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /v1/click %s failed: %v", body, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("POST /v1/click %s = %d, want %d", body, resp.StatusCode, want)
+		}
+	}
+	if len(window.clicked) != 1 || window.clicked[0] != "OK" {
+		t.Errorf("clicked = %q, want [OK]", window.clicked)
+	}
+
+	stop()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("stop did not remove the socket")
+	}
+}
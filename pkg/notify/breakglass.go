@@ -0,0 +1,238 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Priorities for Notification.Priority
+const (
+	PriorityNormal     = "normal"     // Default: business hours and user-level muting apply
+	PriorityBreakGlass = "breakglass" // Emergency: bypasses them, full-screen and topmost with sound, requires a signed token
+)
+
+// BreakGlassClaims is the signed part of a break-glass authorization token
+type BreakGlassClaims struct {
+	Issuer  string `json:"iss"`
+	Reason  string `json:"reason,omitempty"`
+	Issued  int64  `json:"iat"`
+	Expires int64  `json:"exp"`
+	Digest  string `json:"digest"` // SHA-256 of the title and message the token authorizes
+}
+
+// ValidatePriority checks a -priority value
+func ValidatePriority(priority string) error {
+	switch priority {
+	case "", PriorityNormal, PriorityBreakGlass:
+		return nil
+	}
+	return fmt.Errorf("invalid priority %q (use %s or %s)", priority, PriorityNormal, PriorityBreakGlass)
+}
+
+// BreakGlassKeyPath returns the system-wide file of public keys that may authorize
+// break-glass notifications (one base64 Ed25519 key per line, # comments allowed)
+// Linux: /etc/krankybearnotify/breakglass.pub
+// macOS: /Library/Application Support/KrankyBearNotify/breakglass.pub
+// Windows: %ProgramData%\KrankyBearNotify\breakglass.pub
+func BreakGlassKeyPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "KrankyBearNotify", "breakglass.pub")
+	case "darwin":
+		return "/Library/Application Support/KrankyBearNotify/breakglass.pub"
+	default:
+		return "/etc/krankybearnotify/breakglass.pub"
+	}
+}
+
+// LoadBreakGlassKeys reads the public keys from path
+// On Unix the file must not be writable by group or others, otherwise any user
+// could add their own key and authorize break-glass notifications
+func LoadBreakGlassKeys(path string) ([]ed25519.PublicKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("no break-glass keys installed: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0022 != 0 {
+		return nil, fmt.Errorf("break-glass key file %s is writable by group or others (mode %v), refusing to trust it", path, info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read break-glass keys: %v", err)
+	}
+	var keys []ed25519.PublicKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(text)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s:%d: not a base64 Ed25519 public key", path, line)
+		}
+		keys = append(keys, ed25519.PublicKey(raw))
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no break-glass keys in %s", path)
+	}
+	return keys, nil
+}
+
+// breakGlassDigest returns the hash binding a token to one title and message
+// The text is hashed normalized and with templates unrendered, so the same token
+// verifies in every user's session
+func breakGlassDigest(n Notification) string {
+	n = n.Normalize()
+	sum := sha256.Sum256([]byte(n.Title + "\x00" + n.Message))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignBreakGlass returns a token authorizing n as a break-glass notification for valid
+// The token is base64url(claims JSON) "." base64url(Ed25519 signature of the claims)
+func SignBreakGlass(key ed25519.PrivateKey, n Notification, issuer, reason string, valid time.Duration) (string, error) {
+	if issuer == "" {
+		return "", fmt.Errorf("break-glass tokens need an issuer")
+	}
+	if valid <= 0 {
+		return "", fmt.Errorf("break-glass token validity must be positive")
+	}
+	now := time.Now()
+	payload, err := json.Marshal(BreakGlassClaims{
+		Issuer:  issuer,
+		Reason:  reason,
+		Issued:  now.Unix(),
+		Expires: now.Add(valid).Unix(),
+		Digest:  breakGlassDigest(n),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode break-glass claims: %v", err)
+	}
+	signature := ed25519.Sign(key, payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// breakGlassAllowed are the flags a break-glass notification may set besides its title and
+// message: they change how the window looks or behaves, not what it says or asks for.
+// Anything else (icons, -button, -input, -choices, -html, -link, -otp, -on-click,
+// -callback-url, ...) would show or collect content the token does not sign
+var breakGlassAllowed = map[string]bool{
+	"title": true, "message": true, "priority": true, "breakglass-token": true,
+	"timeout": true, "timeout-min": true, "timeout-max": true, "reading-speed": true, "hide-countdown": true,
+	"width": true, "height": true, "locale": true, "tz": true, "category": true, "id": true,
+	"urgency": true, "builtin-icon": true, "theme": true, "bg-color": true, "fg-color": true, "accent-color": true, "font-size": true,
+	"sensitive": true, "redact-after-ack": true, "respect-dnd": true, "override-dnd": true, "attention-after": true,
+	"desktop": true, "remember-position": true, "monitor": true, "topmost": true, "frameless": true, "fullscreen": true,
+	"stacking": true, "rtl": true, "remote-spec": true,
+}
+
+// breakGlassRefusedFlag returns the first flag of n outside breakGlassAllowed, or ""
+// A flag counts as set when it differs from both its default and the zero value, so
+// notifications from specs and the library are judged like command lines
+func breakGlassRefusedFlag(n Notification) string {
+	var bound Notification
+	fs := flag.NewFlagSet("breakglass", flag.ContinueOnError)
+	BindFlags(fs, &bound)
+	bound = n // The flags now read n's values
+	refused := ""
+	fs.VisitAll(func(f *flag.Flag) {
+		if refused != "" || breakGlassAllowed[f.Name] {
+			return
+		}
+		switch value := f.Value.String(); value {
+		case f.DefValue, "", "0", "0s", "false":
+		default:
+			refused = f.Name
+		}
+	})
+	return refused
+}
+
+// VerifyBreakGlass checks that token was signed by one of keys, has not expired at now,
+// authorizes exactly n's title and message, and that n sets nothing else that shows or
+// collects content (see breakGlassAllowed)
+func VerifyBreakGlass(token string, n Notification, keys []ed25519.PublicKey, now time.Time) (BreakGlassClaims, error) {
+	var claims BreakGlassClaims
+	if token == "" {
+		return claims, fmt.Errorf("break-glass priority requires a signed authorization token (-breakglass-token)")
+	}
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, fmt.Errorf("malformed break-glass token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return claims, fmt.Errorf("malformed break-glass token: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return claims, fmt.Errorf("malformed break-glass token: %v", err)
+	}
+
+	trusted := false
+	for _, key := range keys {
+		if ed25519.Verify(key, payload, signature) {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return claims, fmt.Errorf("break-glass token is not signed by a trusted key")
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("malformed break-glass claims: %v", err)
+	}
+	if now.Unix() > claims.Expires {
+		return claims, fmt.Errorf("break-glass token from %s expired at %s", claims.Issuer, time.Unix(claims.Expires, 0).Format(time.RFC3339))
+	}
+	if claims.Digest != breakGlassDigest(n) {
+		return claims, fmt.Errorf("break-glass token from %s was issued for a different title or message", claims.Issuer)
+	}
+	if flag := breakGlassRefusedFlag(n); flag != "" {
+		return claims, fmt.Errorf("break-glass notifications cannot use -%s: the token only covers the title and message", flag)
+	}
+	return claims, nil
+}
+
+// AuthorizeBreakGlass verifies n's BreakGlassToken against keys, or the keys
+// installed at BreakGlassKeyPath when keys is nil
+func AuthorizeBreakGlass(n Notification, keys []ed25519.PublicKey) (BreakGlassClaims, error) {
+	if keys == nil {
+		var err error
+		if keys, err = LoadBreakGlassKeys(BreakGlassKeyPath()); err != nil {
+			return BreakGlassClaims{}, err
+		}
+	}
+	return VerifyBreakGlass(n.BreakGlassToken, n, keys, time.Now())
+}
+
+// auditBreakGlass records a break-glass delivery on stderr (even without -debug) and in the system log
+func auditBreakGlass(n Notification, claims BreakGlassClaims) {
+	entry := fmt.Sprintf("BREAK GLASS notification %q authorized by %s (reason: %q, token expires %s), pid %d",
+		n.Title, claims.Issuer, claims.Reason, time.Unix(claims.Expires, 0).Format(time.RFC3339), os.Getpid())
+	fmt.Fprintf(os.Stderr, "*** %s ***\n", entry)
+	log.Println(entry)
+	if err := writeAuditLog(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not write break-glass entry to the system log: %v\n", err)
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// writeAuditLog records entry in syslog (auth facility, so it lands next to sudo and login records)
+func writeAuditLog(entry string) error {
+	output, err := exec.Command("logger", "-p", "auth.warning", "-t", "krankybearnotify", entry).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("logger failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// playAlertSound plays an alert sound without waiting for it
+// macOS: afplay with a system sound
+// Linux: canberra-gtk-play or paplay with the freedesktop warning sound, falling back to the terminal bell
+func playAlertSound() {
+	var candidates [][]string
+	if runtime.GOOS == "darwin" {
+		candidates = [][]string{{"afplay", "/System/Library/Sounds/Sosumi.aiff"}}
+	} else {
+		candidates = [][]string{
+			{"canberra-gtk-play", "-i", "dialog-warning"},
+			{"paplay", "/usr/share/sounds/freedesktop/stereo/dialog-warning.oga"},
+		}
+	}
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(candidate[0], candidate[1:]...)
+		if err := cmd.Start(); err == nil {
+			go cmd.Wait()
+			return
+		}
+	}
+	fmt.Fprint(os.Stderr, "\a") // stdout carries the -result-json payload
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
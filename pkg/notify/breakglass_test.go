@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBreakGlassToken tests that tokens only verify for the signed message, key and validity
+func TestBreakGlassToken(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	n := Notification{Title: "Security incident", Message: "Disconnect from the network now"}
+
+	token, err := SignBreakGlass(privateKey, n, "soc@example.com", "ransomware", time.Hour)
+	if err != nil {
+		t.Fatalf("SignBreakGlass failed: %v", err)
+	}
+
+	claims, err := VerifyBreakGlass(token, n, []ed25519.PublicKey{otherKey, publicKey}, time.Now())
+	if err != nil {
+		t.Fatalf("Valid token rejected: %v", err)
+	}
+	if claims.Issuer != "soc@example.com" || claims.Reason != "ransomware" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	tampered := n
+	tampered.Message = "Install this update from example.net"
+	withHTML := n
+	withHTML.HTML = `<a href="https://example.net">Install this update</a>`
+	withLink := n
+	withLink.Link = "https://example.net/update"
+	withOTP := n
+	withOTP.OTP = "483921"
+	withInput := n
+	withInput.Input, withInput.InputPlaceholder = true, "Your password"
+	withCallback := n
+	withCallback.CallbackURL = "https://example.net/collect"
+	withIcon := n
+	withIcon.IconPath = "https://example.net/logo.png"
+	withButton := n
+	withButton.ButtonText = "Call +1 555 0100"
+	styled := n
+	styled.ButtonText, styled.Urgency, styled.Timeout, styled.Fullscreen, styled.Monitor = DefaultButton, UrgencyCritical, 0, true, MonitorAll
+	tests := []struct {
+		name  string
+		token string
+		n     Notification
+		keys  []ed25519.PublicKey
+		now   time.Time
+		want  string
+	}{
+		{"missing token", "", n, []ed25519.PublicKey{publicKey}, time.Now(), "requires a signed"},
+		{"malformed", "not-a-token", n, []ed25519.PublicKey{publicKey}, time.Now(), "malformed"},
+		{"untrusted key", token, n, []ed25519.PublicKey{otherKey}, time.Now(), "not signed by a trusted key"},
+		{"expired", token, n, []ed25519.PublicKey{publicKey}, time.Now().Add(2 * time.Hour), "expired"},
+		{"other message", token, tampered, []ed25519.PublicKey{publicKey}, time.Now(), "different title or message"},
+		{"html", token, withHTML, []ed25519.PublicKey{publicKey}, time.Now(), "cannot use -html"},
+		{"link", token, withLink, []ed25519.PublicKey{publicKey}, time.Now(), "cannot use -link"},
+		{"otp", token, withOTP, []ed25519.PublicKey{publicKey}, time.Now(), "cannot use -otp"},
+		{"input", token, withInput, []ed25519.PublicKey{publicKey}, time.Now(), "cannot use -input"},
+		{"callback url", token, withCallback, []ed25519.PublicKey{publicKey}, time.Now(), "cannot use -callback-url"},
+		{"icon", token, withIcon, []ed25519.PublicKey{publicKey}, time.Now(), "cannot use -icon"},
+		{"button", token, withButton, []ed25519.PublicKey{publicKey}, time.Now(), "cannot use -button"},
+	}
+	if _, err := VerifyBreakGlass(token, styled, []ed25519.PublicKey{publicKey}, time.Now()); err != nil {
+		t.Errorf("Token rejected for a notification that only changes the look: %v", err)
+	}
+	for _, tt := range tests {
+		_, err := VerifyBreakGlass(tt.token, tt.n, tt.keys, tt.now)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+// TestLoadBreakGlassKeys tests the key file format and the permission check
+func TestLoadBreakGlassKeys(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	path := filepath.Join(t.TempDir(), "breakglass.pub")
+	data := "# security team key\n\n" + base64.StdEncoding.EncodeToString(publicKey) + "\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadBreakGlassKeys(path)
+	if err != nil {
+		t.Fatalf("LoadBreakGlassKeys failed: %v", err)
+	}
+	if len(keys) != 1 || !keys[0].Equal(publicKey) {
+		t.Errorf("Expected the one key from the file, got %v", keys)
+	}
+
+	if runtime.GOOS != "windows" {
+		os.Chmod(path, 0666)
+		if _, err := LoadBreakGlassKeys(path); err == nil {
+			t.Error("Expected a world-writable key file to be rejected")
+		}
+		os.Chmod(path, 0644)
+	}
+
+	os.WriteFile(path, []byte("not a key\n"), 0644)
+	if _, err := LoadBreakGlassKeys(path); err == nil {
+		t.Error("Expected an invalid key line to be rejected")
+	}
+}
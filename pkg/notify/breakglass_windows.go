@@ -0,0 +1,30 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// messageBeep plays a system sound (MessageBeep in user32.dll, declared in gui_check_windows.go)
+var messageBeep = user32.NewProc("MessageBeep")
+
+// writeAuditLog records entry as a warning in the Application event log
+func writeAuditLog(entry string) error {
+	output, err := exec.Command("eventcreate", "/T", "WARNING", "/ID", "911", "/L", "APPLICATION",
+		"/SO", "KrankyBearNotify", "/D", entry).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("eventcreate failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// playAlertSound plays the critical stop sound without waiting for it
+func playAlertSound() {
+	const MB_ICONHAND = 0x00000010
+	messageBeep.Call(uintptr(MB_ICONHAND))
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -1,6 +1,6 @@
 //go:build linux
 
-package main
+package notify
 
 import (
 	"fmt"
@@ -12,7 +12,7 @@ import (
 
 // broadcastWallMessage sends a message to all logged-in users via wall command
 // This is used when GUI is not available (headless, SSH, etc.)
-func broadcastWallMessage(title, message string, timeout int) error {
+func broadcastWallMessage(n Notification) error {
 	// Check if wall command is available
 	_, err := exec.LookPath("wall")
 	if err != nil {
@@ -20,17 +20,21 @@ func broadcastWallMessage(title, message string, timeout int) error {
 	}
 
 	// Build the broadcast message
+	ui := n.ui()
 	var sb strings.Builder
+	if n.Priority == PriorityBreakGlass {
+		sb.WriteString("\a" + ui.text("wall.emergency") + "\n")
+	}
 	sb.WriteString("=" + strings.Repeat("=", 60) + "=\n")
-	sb.WriteString(fmt.Sprintf("  %s\n", strings.ToUpper(title)))
+	sb.WriteString(fmt.Sprintf("  %s\n", strings.ToUpper(n.Title)))
 	sb.WriteString("=" + strings.Repeat("=", 60) + "=\n\n")
-	sb.WriteString(message)
+	sb.WriteString(n.plainMessage())
 	sb.WriteString("\n\n")
-	if timeout > 0 {
-		sb.WriteString(fmt.Sprintf("[This notification will be displayed for %d seconds]\n", timeout))
+	if n.Timeout > 0 {
+		sb.WriteString(ui.text("wall.timeout", n.Timeout) + "\n")
 	}
 	sb.WriteString("=" + strings.Repeat("=", 60) + "=\n")
-	sb.WriteString(fmt.Sprintf("Sent: %s\n", time.Now().Format("2006-01-02 15:04:05")))
+	sb.WriteString(ui.text("wall.sent", time.Now().Format("2006-01-02 15:04:05")) + "\n")
 
 	broadcastMsg := sb.String()
 
@@ -45,11 +49,11 @@ func broadcastWallMessage(title, message string, timeout int) error {
 	}
 
 	// If timeout is specified, wait and send a "notification expired" message
-	if timeout > 0 {
-		time.Sleep(time.Duration(timeout) * time.Second)
+	if n.Timeout > 0 {
+		time.Sleep(time.Duration(n.Timeout) * time.Second)
 
 		expiryCmd := exec.Command("wall")
-		expiryMsg := fmt.Sprintf("\n[Notification '%s' has expired]\n", title)
+		expiryMsg := "\n" + ui.text("wall.expired", n.Title) + "\n"
 		expiryCmd.Stdin = strings.NewReader(expiryMsg)
 		expiryCmd.Run() // Ignore errors on expiry message
 	}
@@ -57,8 +61,8 @@ func broadcastWallMessage(title, message string, timeout int) error {
 	return nil
 }
 
-// isWallAvailable checks if the wall command is available on this system
-func isWallAvailable() bool {
+// IsWallAvailable checks if the wall command is available on this system
+func IsWallAvailable() bool {
 	_, err := exec.LookPath("wall")
 	return err == nil
 }
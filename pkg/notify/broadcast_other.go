@@ -1,15 +1,15 @@
 //go:build !linux
 
-package main
+package notify
 
 import "fmt"
 
 // broadcastWallMessage is a stub for non-Linux platforms
-func broadcastWallMessage(title, message string, timeout int) error {
+func broadcastWallMessage(n Notification) error {
 	return fmt.Errorf("wall broadcast is only available on Linux")
 }
 
-// isWallAvailable is a stub for non-Linux platforms
-func isWallAvailable() bool {
+// IsWallAvailable is a stub for non-Linux platforms
+func IsWallAvailable() bool {
 	return false
 }
@@ -1,4 +1,4 @@
-package main
+package notify
 
 import (
 	"runtime"
@@ -7,7 +7,7 @@ import (
 
 // TestWallAvailability tests the wall command availability check
 func TestWallAvailability(t *testing.T) {
-	result := isWallAvailable()
+	result := IsWallAvailable()
 
 	if runtime.GOOS == "linux" {
 		// On Linux, we expect wall to potentially be available
@@ -29,9 +29,9 @@ func TestBroadcastWallMessage(t *testing.T) {
 	// This test just verifies the function can be called
 	// It won't actually send messages unless running on Linux with wall available
 
-	err := broadcastWallMessage("Test Title", "Test Message", 0)
+	err := broadcastWallMessage(Notification{Title: "Test Title", Message: "Test Message"})
 
-	if runtime.GOOS == "linux" && isWallAvailable() {
+	if runtime.GOOS == "linux" && IsWallAvailable() {
 		// On Linux with wall, it might succeed (if we have permissions)
 		// or fail (if we don't have permissions)
 		t.Logf("Broadcast attempt on Linux: error=%v", err)
@@ -49,12 +49,12 @@ func TestBroadcastFallbackLogic(t *testing.T) {
 	t.Log("Testing notification fallback hierarchy:")
 
 	// Test GUI availability
-	guiAvailable := isGUIAvailable()
+	guiAvailable := IsGUIAvailable()
 	t.Logf("1. GUI Available: %v", guiAvailable)
 
 	if !guiAvailable && runtime.GOOS == "linux" {
 		// If no GUI on Linux, check wall
-		wallAvailable := isWallAvailable()
+		wallAvailable := IsWallAvailable()
 		t.Logf("2. Wall Broadcast Available (Linux fallback): %v", wallAvailable)
 
 		if !wallAvailable {
@@ -64,7 +64,7 @@ func TestBroadcastFallbackLogic(t *testing.T) {
 
 	// Test OpenGL (for GUI environments)
 	if guiAvailable {
-		openglAvailable := isOpenGLAvailable()
+		openglAvailable := IsOpenGLAvailable()
 		t.Logf("2. OpenGL Available: %v", openglAvailable)
 	}
 }
@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// builtinIconApp is the built-in icon name of the KrankyBear beret, the icon of the app itself
+const builtinIconApp = "krankybear"
+
+// builtinIconFiles are the other built-in icons, named by file name without extension: KrankyBear
+// artwork and info, warning, error and question glyphs in the urgency colors
+//
+//go:embed icons/*
+var builtinIconFiles embed.FS
+
+// BuiltinIcons returns the names -builtin-icon accepts, sorted
+func BuiltinIcons() []string {
+	names := []string{builtinIconApp}
+	entries, _ := builtinIconFiles.ReadDir("icons")
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateBuiltinIcon checks the -builtin-icon flag value
+func ValidateBuiltinIcon(name string) error {
+	if name == "" {
+		return nil
+	}
+	_, _, err := builtinIcon(name)
+	return err
+}
+
+// builtinIcon returns the file name and content of the built-in icon name
+func builtinIcon(name string) (string, []byte, error) {
+	if name == builtinIconApp {
+		return builtinIconApp + ".png", resourceKrankyBearBeretPng.Content(), nil
+	}
+	entries, _ := builtinIconFiles.ReadDir("icons")
+	for _, entry := range entries {
+		if strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())) == name {
+			data, err := builtinIconFiles.ReadFile("icons/" + entry.Name())
+			return entry.Name(), data, err
+		}
+	}
+	return "", nil, fmt.Errorf("unknown built-in icon %q (use %s)", name, strings.Join(BuiltinIcons(), ", "))
+}
+
+// withBuiltinIcon returns n with IconPath set to its BuiltinIcon written to the per-user icon
+// cache, so every backend loads it like an -icon file; an IconPath of its own takes precedence
+func (n Notification) withBuiltinIcon() Notification {
+	if n.BuiltinIcon == "" || n.IconPath != "" {
+		return n
+	}
+	path, err := writeBuiltinIcon(n.BuiltinIcon, iconCacheDir())
+	if err != nil {
+		log.Printf("Warning: %v, showing the notification without it", err)
+		return n
+	}
+	n.IconPath = path
+	return n
+}
+
+// writeBuiltinIcon returns the file of the built-in icon name in dir, writing it unless the same
+// icon is there already (rewriting it would change the key of its rasterized copy)
+func writeBuiltinIcon(name, dir string) (string, error) {
+	file, data, err := builtinIcon(name)
+	if err != nil {
+		return "", err
+	}
+	iconPath := filepath.Join(dir, "builtin-"+file)
+	if cached, err := os.ReadFile(iconPath); err == nil && bytes.Equal(cached, data) {
+		return iconPath, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("could not create icon cache: %v", err)
+	}
+	if err := os.WriteFile(iconPath, data, 0600); err != nil {
+		return "", fmt.Errorf("could not write icon cache: %v", err)
+	}
+	pruneIconCache(dir, iconPath, time.Now())
+	return iconPath, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestBuiltinIcons tests that every built-in icon can be drawn at the icon size
+func TestBuiltinIcons(t *testing.T) {
+	names := BuiltinIcons()
+	for _, want := range []string{"krankybear", "info", "warning", "error", "question"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("BuiltinIcons() = %v, missing %q", names, want)
+		}
+	}
+	for _, name := range names {
+		file, data, err := builtinIcon(name)
+		if err != nil {
+			t.Fatalf("builtinIcon(%q) failed: %v", name, err)
+		}
+		var img image.Image
+		if isSVGIcon(file, data) {
+			img, err = rasterizeSVG(data, iconMaxSide)
+		} else {
+			img, _, err = image.Decode(bytes.NewReader(data))
+		}
+		if err != nil {
+			t.Errorf("Built-in icon %s cannot be drawn: %v", file, err)
+		} else if b := img.Bounds(); max(b.Dx(), b.Dy()) != iconMaxSide {
+			t.Errorf("Built-in icon %s is %dx%d, want %d on its longest side", file, b.Dx(), b.Dy(), iconMaxSide)
+		}
+	}
+
+	if ValidateBuiltinIcon("warning") != nil || ValidateBuiltinIcon("") != nil {
+		t.Error("Built-in icon names, and no name, should be valid")
+	}
+	for _, bad := range []string{"Warning", "../bundled"} {
+		if ValidateBuiltinIcon(bad) == nil {
+			t.Errorf("ValidateBuiltinIcon(%q) should fail like the schema enum", bad)
+		}
+	}
+}
+
+// TestWriteBuiltinIcon tests that a built-in icon is written to the cache once, and that an
+// -icon file takes precedence
+func TestWriteBuiltinIcon(t *testing.T) {
+	dir := t.TempDir()
+	path, err := writeBuiltinIcon("warning", dir)
+	if err != nil || path != filepath.Join(dir, "builtin-warning.svg") {
+		t.Fatalf("writeBuiltinIcon = %q, %v", path, err)
+	}
+	written, _ := os.Stat(path)
+	if again, err := writeBuiltinIcon("warning", dir); err != nil || again != path {
+		t.Errorf("writeBuiltinIcon again = %q, %v, want %q", again, err, path)
+	}
+	if info, _ := os.Stat(path); !info.ModTime().Equal(written.ModTime()) {
+		t.Error("An unchanged built-in icon should not be written again")
+	}
+
+	n := Notification{IconPath: "/opt/agent/logo.png", BuiltinIcon: "warning"}.withBuiltinIcon()
+	if n.IconPath != "/opt/agent/logo.png" {
+		t.Errorf("An -icon file should take precedence, got %q", n.IconPath)
+	}
+}
@@ -0,0 +1,70 @@
+package notify
+
+import "fmt"
+
+// Capabilities are the features a delivery method shows; what a method lacks is left out, or
+// shown as text (see Notification.Downgrades)
+type Capabilities struct {
+	Choices      bool // A drop-down of Choices returning the chosen option
+	Digest       bool // The DigestItems in a collapsed list; without it they are added to the message as lines
+	Input        bool // A text field returning what was entered
+	HTML         bool // The HTML body; without it the Message (or the text of the HTML) is shown
+	Icon         bool // The IconPath image
+	Link         bool // The Link, clickable; without it the Link is added to the message as text
+	OTP          bool // The code display with copy button and countdown; without it the code is a line of text
+	Screenshot   bool // The ContextScreenshot pane with its consent box
+	MobileMirror bool // The QR code of the MobileMirror page
+	AckFile      bool // Acknowledging by touching the AckFile
+	ID           bool // Closing (Cancel) or updating (Replace) by later notifications with the same ID
+}
+
+// methodCapabilities are the capabilities of the Result.Method values; methods not listed
+// (messagebox, wall, wtsmessage, osascript) show the title and plain message only
+var methodCapabilities = map[string]Capabilities{
+	"fyne":              {Choices: true, Digest: true, Input: true, Icon: true, Link: true, OTP: true, Screenshot: true, MobileMirror: true, AckFile: true, ID: true},
+	"webview":           {Choices: true, Digest: true, Input: true, HTML: true, Icon: true, Link: true, OTP: true, AckFile: true, ID: true},
+	"toast":             {Icon: true, Link: true},
+	"terminal-notifier": {Icon: true, Link: true},
+	"notify-send":       {Icon: true},
+}
+
+func init() {
+	// Acknowledged on the phone or through the ack file, the notification was in a Fyne window
+	methodCapabilities["mobile"] = methodCapabilities["fyne"]
+	methodCapabilities["ack-file"] = methodCapabilities["fyne"]
+}
+
+// MethodCapabilities returns the capabilities of a delivery method (Result.Method)
+func MethodCapabilities(method string) Capabilities {
+	return methodCapabilities[method]
+}
+
+// Downgrades returns what of n the delivery method (Result.Method) could not show as given, one
+// decision per feature, e.g. "choices: not shown by messagebox, no option can be chosen"; nil
+// when it shows everything, and for "users", whose sessions each decide for themselves
+func (n Notification) Downgrades(method string) []string {
+	if method == "" || method == "users" {
+		return nil
+	}
+	c := MethodCapabilities(method)
+	var downgrades []string
+	add := func(lacks bool, feature, decision string) {
+		if lacks {
+			downgrades = append(downgrades, fmt.Sprintf("%s: %s", feature, fmt.Sprintf(decision, method)))
+		}
+	}
+	add(len(n.ChoiceList()) > 0 && !c.Choices, "choices", "not shown by %s, no option can be chosen")
+	add(len(n.DigestList()) > 0 && !c.Digest, "digest-items", "added to the message as lines by %s")
+	add(n.Input && !c.Input, "input", "not shown by %s, no input is returned")
+	add(n.HTML != "" && !c.HTML, "html", "shown as plain text by %s")
+	add((n.IconPath != "" || n.IconData != "" || n.BuiltinIcon != "") && !c.Icon, "icon", "not shown by %s")
+	add(n.Link != "" && !c.Link, "link", "added to the message as text by %s")
+	add(n.OTP != "" && !c.OTP, "otp", "shown as a line of text by %s, without copy button or countdown")
+	add(n.ContextScreenshot && !c.Screenshot, "context-screenshot", "not taken by %s")
+	add(n.MobileMirror && !c.MobileMirror, "mobile-mirror", "not offered by %s")
+	add(n.AckFile != "" && !c.AckFile, "ack-file", "not watched by %s")
+	add(n.ID != "" && !c.ID, "id", "not closed or updated by later notifications with the same ID with %s")
+	return downgrades
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
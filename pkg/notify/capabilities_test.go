@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDowngrades tests the downgrade decisions recorded for methods lacking features
+func TestDowngrades(t *testing.T) {
+	n := Notification{Choices: "Now,Tonight", HTML: "<b>Reboot</b>", Link: "https://example.com", IconPath: "icon.png"}
+	tests := []struct {
+		method string
+		want   string // Features downgraded, in order
+	}{
+		{"webview", ""},
+		{"fyne", "html"},
+		{"mobile", "html"},
+		{"toast", "choices html"},
+		{"notify-send", "choices html link"},
+		{"messagebox", "choices html icon link"},
+		{"users", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		var features []string
+		for _, downgrade := range n.Downgrades(tt.method) {
+			feature, decision, _ := strings.Cut(downgrade, ": ")
+			if !strings.Contains(decision, tt.method) {
+				t.Errorf("Downgrades(%q): %q does not name the method", tt.method, downgrade)
+			}
+			features = append(features, feature)
+		}
+		if got := strings.Join(features, " "); got != tt.want {
+			t.Errorf("Downgrades(%q) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+	if d := (Notification{BuiltinIcon: "warning"}).Downgrades("wall"); len(d) != 1 || !strings.HasPrefix(d[0], "icon: ") {
+		t.Errorf("Downgrades of a -builtin-icon in wall = %q, want the icon", d)
+	}
+	if d := (Notification{DigestItems: "Backup failed\nDisk full"}).Downgrades("toast"); len(d) != 1 || !strings.HasPrefix(d[0], "digest-items: ") {
+		t.Errorf("Downgrades of -digest-items in a toast = %q, want the digest items", d)
+	}
+	if d := (Notification{DigestItems: "Backup failed"}).Downgrades("fyne"); d != nil {
+		t.Errorf("Downgrades of -digest-items in fyne = %q, want none", d)
+	}
+	if d := (Notification{Title: "Plain"}).Downgrades("wall"); d != nil {
+		t.Errorf("Downgrades of a plain notification = %q, want none", d)
+	}
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ValidateColor checks a -bg-color, -fg-color or -accent-color value: hex RGB as rrggbb or rgb,
+// with or without a leading #; name is the flag named in the error
+func ValidateColor(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := parseHexColor(value); err != nil {
+		return fmt.Errorf("invalid -%s %q: %v", name, value, err)
+	}
+	return nil
+}
+
+// parseHexColor parses a hex RGB color, "#1b1f3a", "1b1f3a" or the short "#abc"
+func parseHexColor(value string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.NRGBA{}, fmt.Errorf("use a hex color such as #1b1f3a or #abc")
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("use a hex color such as #1b1f3a or #abc")
+	}
+	return color.NRGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+// cssColor returns a color flag value as a CSS color, empty when it is not set or not valid
+func cssColor(value string) string {
+	c, err := parseHexColor(value)
+	if value == "" || err != nil {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// colorCSS returns the WebView style rules of the -bg-color, -fg-color and -accent-color of n,
+// which override the default look (the urgency rules come after them)
+func colorCSS(n Notification) string {
+	var rules []string
+	if bg := cssColor(n.BackgroundColor); bg != "" {
+		rules = append(rules, fmt.Sprintf(".notification-card, .otp-copy, .input { background: %s; }", bg))
+	}
+	if fg := cssColor(n.ForegroundColor); fg != "" {
+		rules = append(rules, fmt.Sprintf(".title, .message, .otp-code, .input { color: %s; }", fg))
+		rules = append(rules, fmt.Sprintf(".timer, .otp-expiry { color: %s; opacity: 0.7; }", fg))
+	}
+	if accent := cssColor(n.AccentColor); accent != "" {
+		rules = append(rules, fmt.Sprintf("body, .ok-button { background: %s; }", accent))
+		rules = append(rules, fmt.Sprintf(".message a, .link a, .otp-copy { color: %s; border-color: %s; }", accent, accent))
+	}
+	return strings.Join(rules, "\n        ")
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
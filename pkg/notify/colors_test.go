@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// TestParseHexColor tests the accepted -bg-color, -fg-color and -accent-color values
+func TestParseHexColor(t *testing.T) {
+	tests := map[string]color.NRGBA{
+		"#1b1f3a": {R: 0x1b, G: 0x1f, B: 0x3a, A: 0xff},
+		"1B1F3A":  {R: 0x1b, G: 0x1f, B: 0x3a, A: 0xff},
+		"#abc":    {R: 0xaa, G: 0xbb, B: 0xcc, A: 0xff},
+	}
+	for value, want := range tests {
+		if got, err := parseHexColor(value); err != nil || got != want {
+			t.Errorf("parseHexColor(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"red", "#12345", "#1b1f3a80", "#ggg", "#+1234f"} {
+		if err := ValidateColor("bg-color", value); err == nil {
+			t.Errorf("ValidateColor(%q) accepted an invalid color", value)
+		}
+	}
+	if err := ValidateColor("bg-color", ""); err != nil {
+		t.Errorf("ValidateColor(\"\") = %v", err)
+	}
+}
+
+// TestColorCSS tests that the WebView rules use the normalized colors, and only those set
+func TestColorCSS(t *testing.T) {
+	if css := colorCSS(Notification{}); css != "" {
+		t.Errorf("colorCSS without colors = %q, want none", css)
+	}
+	css := colorCSS(Notification{BackgroundColor: "FFF", AccentColor: "#E30613"})
+	for _, want := range []string{".notification-card, .otp-copy, .input { background: #ffffff; }", "body, .ok-button { background: #e30613; }"} {
+		if !strings.Contains(css, want) {
+			t.Errorf("colorCSS = %q, want %q in it", css, want)
+		}
+	}
+	if strings.Contains(css, ".title") {
+		t.Errorf("colorCSS = %q, has a text color without -fg-color", css)
+	}
+}
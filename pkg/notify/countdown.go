@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"time"
+)
+
+// timeoutCountdown returns the line under the button counting down to the Timeout, as the
+// WebView window shows it
+func timeoutCountdown(ui uiText, left time.Duration) string {
+	seconds := int(left.Round(time.Second) / time.Second)
+	if seconds <= 0 {
+		return ui.text("closing")
+	}
+	return ui.text("countdown", seconds)
+}
+
+// showCountdown reports whether the window of n counts down to its Timeout
+func (n Notification) showCountdown() bool {
+	return n.Timeout > 0 && !n.HideCountdown
+}
+
+// countdownCSS returns the WebView styles hiding the countdown for -hide-countdown
+func countdownCSS(n Notification) string {
+	if n.showCountdown() {
+		return ""
+	}
+	return `.timer { display: none; }`
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
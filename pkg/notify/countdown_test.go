@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTimeoutCountdown tests the countdown line of the Fyne window and when it is shown
+func TestTimeoutCountdown(t *testing.T) {
+	lines := map[time.Duration]string{
+		30 * time.Second:        "Auto-closing in 30s",
+		1400 * time.Millisecond: "Auto-closing in 1s",
+		200 * time.Millisecond:  "Closing...",
+		-time.Second:            "Closing...",
+	}
+	for left, want := range lines {
+		if got := timeoutCountdown(Notification{Locale: "en"}.ui(), left); got != want {
+			t.Errorf("timeoutCountdown(%v) = %q, want %q", left, got, want)
+		}
+	}
+
+	for _, tt := range []struct {
+		n    Notification
+		want bool
+	}{
+		{Notification{Timeout: 10}, true},
+		{Notification{Timeout: 0}, false},
+		{Notification{Timeout: 10, HideCountdown: true}, false},
+	} {
+		if got := tt.n.showCountdown(); got != tt.want {
+			t.Errorf("showCountdown(%+v) = %v, want %v", tt.n, got, tt.want)
+		}
+		if hidden := countdownCSS(tt.n) != ""; hidden == tt.want {
+			t.Errorf("countdownCSS(%+v) hides the countdown: %v, want %v", tt.n, hidden, !tt.want)
+		}
+	}
+}
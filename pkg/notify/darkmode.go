@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Themes for Notification.Theme
+const (
+	ThemeAuto  = "auto"  // Follow the dark/light preference of the user's desktop (also when empty)
+	ThemeDark  = "dark"  // Always dark
+	ThemeLight = "light" // Always light
+)
+
+// ValidateTheme checks the -theme flag value
+func ValidateTheme(theme string) error {
+	switch theme {
+	case "", ThemeAuto, ThemeDark, ThemeLight:
+		return nil
+	}
+	return fmt.Errorf("invalid theme %q (use %s, %s or %s)", theme, ThemeAuto, ThemeDark, ThemeLight)
+}
+
+// darkTheme reports whether n is shown dark: as -theme says, or as the desktop prefers for auto
+func (n Notification) darkTheme() bool {
+	switch n.Theme {
+	case ThemeDark:
+		return true
+	case ThemeLight:
+		return false
+	}
+	return systemPrefersDark()
+}
+
+// systemPrefersDark reports whether the desktop of this session is set to dark mode, false when unknown
+// Windows: AppsUseLightTheme; macOS: AppleInterfaceStyle; Linux: the GNOME color scheme,
+// the KDE color scheme, or a dark GTK theme
+func systemPrefersDark() bool {
+	switch runtime.GOOS {
+	case "windows":
+		// Format: "    AppsUseLightTheme    REG_DWORD    0x0"
+		output, err := exec.Command("reg", "query", `HKCU\Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`, "/v", "AppsUseLightTheme").Output()
+		if err != nil {
+			return false
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 3 && fields[0] == "AppsUseLightTheme" {
+				return fields[2] == "0x0"
+			}
+		}
+		return false
+	case "darwin":
+		// Only set (to "Dark") in dark mode
+		output, err := exec.Command("defaults", "read", "-g", "AppleInterfaceStyle").Output()
+		return err == nil && strings.TrimSpace(string(output)) == "Dark"
+	}
+
+	if output, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "color-scheme").Output(); err == nil {
+		switch strings.Trim(strings.TrimSpace(string(output)), "'") {
+		case "prefer-dark":
+			return true
+		case "prefer-light":
+			return false
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if data, err := os.ReadFile(filepath.Join(home, ".config", "kdeglobals")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if scheme, ok := strings.CutPrefix(strings.TrimSpace(line), "ColorScheme="); ok {
+					return strings.Contains(strings.ToLower(scheme), "dark")
+				}
+			}
+		}
+	}
+	gtkTheme := os.Getenv("GTK_THEME")
+	if gtkTheme == "" {
+		if output, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "gtk-theme").Output(); err == nil {
+			gtkTheme = string(output)
+		}
+	}
+	return strings.Contains(strings.ToLower(gtkTheme), "dark")
+}
+
+// darkCSS restyles the WebView window for a dark desktop; the -bg-color, -fg-color and
+// -accent-color rules come after it
+const darkCSS = `body { background: linear-gradient(135deg, #2b2f5e 0%, #3d2754 100%); }
+        .notification-card { background: #2b2b30; box-shadow: 0 10px 40px rgba(0,0,0,0.5); }
+        .title, .otp-code { color: #f0f0f0; }
+        .message { color: #c8c8c8; }
+        .message a, .link a { color: #9fb0ff; }
+        .otp-copy { background: #2b2b30; color: #9fb0ff; border-color: #9fb0ff; }
+        .input { background: #1e1e22; color: #f0f0f0; border-color: #555; }
+        .timer, .otp-expiry { color: #8a8a8a; }`
+
+// themeCSS returns the WebView style rules of the theme of n, none for light
+func themeCSS(n Notification) string {
+	if n.darkTheme() {
+		return darkCSS
+	}
+	return ""
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,27 @@
+package notify
+
+import "testing"
+
+// TestValidateTheme tests the -theme values
+func TestValidateTheme(t *testing.T) {
+	for _, theme := range []string{"", ThemeAuto, ThemeDark, ThemeLight} {
+		if err := ValidateTheme(theme); err != nil {
+			t.Errorf("ValidateTheme(%q) = %v", theme, err)
+		}
+	}
+	for _, theme := range []string{"Dark", "night", "system"} {
+		if err := ValidateTheme(theme); err == nil {
+			t.Errorf("ValidateTheme(%q) should fail", theme)
+		}
+	}
+}
+
+// TestThemeCSS tests that -theme dark and light override the desktop preference in WebView
+func TestThemeCSS(t *testing.T) {
+	if css := themeCSS(Notification{Theme: ThemeDark}); css != darkCSS {
+		t.Errorf("themeCSS(dark) = %q, want the dark rules", css)
+	}
+	if css := themeCSS(Notification{Theme: ThemeLight}); css != "" {
+		t.Errorf("themeCSS(light) = %q, want none", css)
+	}
+}
@@ -0,0 +1,894 @@
+package notify
+
+import (
+	"fmt"
+	"image/color"
+	"image/gif"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showNotification displays a Fyne notification window for n (title, message, timeout, optional icon, window dimensions, and button text)
+// screenshot, when not nil, is shown in a details pane with a consent banner; slot is the window
+// slot the window is stacked in (see claimWindowSlot), -1 for none; onHang is Notifier.OnHang
+// Returns the action taken (acknowledged or timeout), the method that ended up displaying it,
+// what the user entered (n.Input, n.Choices) when the button was clicked, and whether the user agreed to share the screenshot
+func showNotification(n Notification, screenshot *contextScreenshot, slot int, onHang func()) (action string, method string, resp response, shareScreenshot bool, err error) {
+	action = ActionAcknowledged
+	method = "fyne"
+
+	// Add panic recovery in case Fyne initialization fails despite OpenGL check
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Fyne GUI failed to initialize (panic): %v", r)
+			log.Println("Falling back to alternative notification method")
+
+			// Try fallbacks
+			if runtime.GOOS != "windows" {
+				err = fmt.Errorf("Fyne GUI failed and no fallback available for this platform")
+				return
+			}
+			if werr := showWindowsMessageBox(n); werr != nil {
+				err = fmt.Errorf("all notification methods failed: %v", werr)
+				return
+			}
+			action = ActionAcknowledged
+			method = "messagebox"
+			shareScreenshot = false
+		}
+	}()
+
+	a := app.New()
+	if custom, ok := newNotificationTheme(a.Settings().Theme(), n); ok {
+		a.Settings().SetTheme(custom)
+	}
+	w := newWindow(a, n.Title, n)
+	w.SetIcon(resourceKrankyBearBeretPng)
+
+	// Windows: in VMs without proper OpenGL, Fyne may hang invisibly instead of failing
+	// A window waiting to be acknowledged (timeout 0) is left open once it started
+	var hung atomic.Bool
+	if runtime.GOOS == "windows" {
+		started := make(chan struct{})
+		a.Lifecycle().SetOnStarted(func() { close(started) })
+		var closeWithin time.Duration
+		if n.Timeout > 0 {
+			closeWithin = time.Duration(n.Timeout)*time.Second + fyneCloseGrace
+		}
+		defer close(startWatchdog(started, fyneStartTimeout, closeWithin, func(reason string) {
+			log.Printf("Warning: Fyne watchdog: %s, closing the window", reason)
+			hung.Store(true)
+			go func() {
+				defer func() {
+					// Catch any panic from Quit()
+					if r := recover(); r != nil {
+						log.Printf("Panic during graceful quit (expected if hung): %v", r)
+					}
+				}()
+				fyne.DoAndWait(a.Quit)
+			}()
+			if onHang != nil {
+				onHang()
+			}
+		}))
+		log.Printf("Fyne watchdog set: %v to start", fyneStartTimeout)
+	}
+
+	// Set the window size BEFORE creating content
+	// This ensures the layout managers respect our dimensions
+	windowSize := fyne.NewSize(float32(n.Width), float32(n.Height))
+
+	// Create the UI
+	// -rtl, or a message in Arabic or Hebrew: text on the right, icon on the right
+	rtl := n.rightToLeft()
+	titleLabel := widget.NewLabel(n.Title)
+	titleLabel.TextStyle.Bold = true
+	titleLabel.Alignment = textAlign(rtl)
+
+	messageLabel := messageText(n.Message, rtl)
+	var linkLabel *widget.Hyperlink
+	if u, err := url.Parse(n.Link); err == nil && n.Link != "" {
+		linkLabel = widget.NewHyperlink(n.Link, u)
+	}
+
+	// Closed when the window has closed, stopping the goroutines that update it
+	runDone := make(chan struct{})
+
+	// -otp: the code in large digits, counting down to its expiry
+	var otpDisplay fyne.CanvasObject
+	if n.OTP != "" {
+		otpDisplay = otpDetails(a, n, runDone)
+		windowSize.Height += 70
+	}
+
+	// -state: the icon follows the state, with a spinner while working; with -state-id,
+	// UpdateState changes it (and the message) while the window is open
+	messageSlot := container.NewStack(messageLabel)
+	var stateIcons map[string]string
+	var stateProgress *widget.ProgressBarInfinite
+	if n.hasState() {
+		stateIcons, _ = ParseStateIcons(n.StateIcons) // Checked by the CLI
+		stateProgress = widget.NewProgressBarInfinite()
+		if n.initialState() != StateWorking {
+			stateProgress.Hide()
+		}
+		windowSize.Height += 20
+	}
+
+	// -redact-after-ack: the message is gone from the screen before the window closes
+	redact := func() {
+		if !n.RedactAfterAck {
+			return
+		}
+		messageLabel.Hide()
+		if linkLabel != nil {
+			linkLabel.Hide()
+		}
+		if otpDisplay != nil {
+			otpDisplay.Hide()
+		}
+	}
+
+	// -input: the entered text only counts when the user clicks the button (or presses Enter)
+	var inputEntry *widget.Entry
+	if n.Input {
+		inputEntry = widget.NewEntry()
+		inputEntry.SetText(n.InputDefault)
+		inputEntry.SetPlaceHolder(n.InputPlaceholder)
+		windowSize.Height += 40
+	}
+
+	// -choices: the button stays disabled until an option is chosen
+	var choiceSelect *widget.Select
+	if choices := n.ChoiceList(); len(choices) > 0 {
+		choiceSelect = widget.NewSelect(choices, nil)
+		choiceSelect.PlaceHolder = n.ui().text("choose")
+		windowSize.Height += 40
+	}
+
+	// Sharing needs an explicit tick, and only counts when the user clicks the button
+	var consentCheck *widget.Check
+	okButton := widget.NewButton(n.ButtonText, func() {
+		shareScreenshot = consentCheck != nil && consentCheck.Checked
+		if inputEntry != nil {
+			resp.Input = inputEntry.Text
+		}
+		if choiceSelect != nil {
+			resp.Choice = choiceSelect.Selected
+		}
+		redact()
+		w.Close()
+	})
+	if choiceSelect != nil {
+		okButton.Disable()
+		choiceSelect.OnChanged = func(string) { okButton.Enable() }
+	}
+	if inputEntry != nil {
+		inputEntry.OnSubmitted = func(string) {
+			if !okButton.Disabled() {
+				okButton.OnTapped()
+			}
+		}
+	}
+
+	// Create the main content (title, message, button)
+	mainContent := container.NewVBox(
+		titleLabel,
+		widget.NewSeparator(),
+		messageSlot,
+	)
+	if stateProgress != nil {
+		mainContent.Add(stateProgress)
+	}
+	if linkLabel != nil {
+		mainContent.Add(linkLabel)
+	}
+	if otpDisplay != nil {
+		mainContent.Add(otpDisplay)
+	}
+	if items := n.DigestList(); len(items) > 0 {
+		mainContent.Add(digestDetails(n.ui(), items, rtl))
+		windowSize.Height += 40
+	}
+	mainContent.Add(widget.NewSeparator())
+	if inputEntry != nil {
+		mainContent.Add(inputEntry)
+	}
+	if choiceSelect != nil {
+		mainContent.Add(choiceSelect)
+	}
+	if screenshot != nil {
+		consentCheck = widget.NewCheck(n.ui().text("screenshot.share"), nil)
+		mainContent.Add(screenshotDetails(n.ui(), screenshot, n.ButtonText, consentCheck))
+		mainContent.Add(widget.NewSeparator())
+	}
+	// The phone page is served only while this window is open
+	var mirror *mobileMirror
+	if n.MobileMirror {
+		var mirrorErr error
+		if mirror, mirrorErr = startMobileMirror(n); mirrorErr != nil {
+			log.Printf("Warning: Mobile mirror unavailable: %v", mirrorErr)
+		} else {
+			defer mirror.Close()
+			mainContent.Add(mirrorDetails(n.ui(), mirror))
+			mainContent.Add(widget.NewSeparator())
+			windowSize.Height += mirrorQRSize
+		}
+	}
+	mainContent.Add(okButton)
+
+	// The time left before the window closes by itself, as the WebView window shows it
+	if n.showCountdown() {
+		mainContent.Add(timeoutDetails(n, runDone))
+		windowSize.Height += 30
+	}
+
+	// Add icon if specified, or else the icon of the urgency; -state shows the icon of the state
+	// (an animated state icon plays until the next state replaces it)
+	var iconImage fyne.CanvasObject
+	stateReplaced := make(chan struct{})
+	if n.hasState() {
+		iconImage = stateIcon(n.initialState(), stateIcons, untilClosed(runDone, stateReplaced))
+	} else if image := loadIcon(n.IconPath, runDone); image != nil {
+		iconImage = image
+	} else {
+		iconImage = urgencyIcon(n.Urgency)
+	}
+	var content fyne.CanvasObject
+	var iconContainer *fyne.Container
+	if iconImage != nil {
+		// Create horizontal layout with icon on the left (on the right when right to left)
+		// Use Border layout to ensure message text gets proper width
+		iconContainer = container.NewVBox(iconImage)
+		var left, right fyne.CanvasObject = container.NewPadded(iconContainer), nil
+		if rtl {
+			left, right = right, left
+		}
+		content = container.NewBorder(
+			nil,                              // top
+			nil,                              // bottom
+			left,                             // left (icon)
+			right,                            // right (icon when right to left)
+			container.NewPadded(mainContent), // center (content gets remaining space)
+		)
+	} else {
+		content = mainContent
+	}
+
+	// Wrap content in a padded container, under a bar in the accent color of the urgency
+	var paddedContent fyne.CanvasObject = container.NewPadded(content)
+	if accent, ok := urgencyAccent(n.Urgency); ok {
+		bar := canvas.NewRectangle(accent)
+		bar.SetMinSize(fyne.NewSize(0, urgencyBarHeight))
+		paddedContent = container.NewBorder(bar, nil, nil, nil, paddedContent)
+	}
+
+	// An ignored window pulses its background, so it sits behind the content
+	windowContent := paddedContent
+	if n.AttentionAfter > 0 {
+		background := canvas.NewRectangle(color.Transparent)
+		windowContent = container.NewStack(background, paddedContent)
+		watchAttention(a, w, n.AttentionAfter, background)
+	}
+	// Full screen: the notification at its usual size in the middle of the screen, rather than
+	// stretched across it
+	fullScreen := n.fullScreen()
+	if fullScreen {
+		windowContent = container.NewCenter(container.NewGridWrap(windowSize, windowContent))
+	}
+	w.SetContent(windowContent)
+	w.Resize(windowSize)
+	w.SetFixedSize(false) // Allow manual resizing but start at our size
+	w.CenterOnScreen()
+
+	// The button is in the -accent-color, unless the urgency colors it
+	if n.AccentColor != "" {
+		okButton.Importance = widget.HighImportance
+	}
+	switch n.Urgency {
+	case UrgencyWarning:
+		okButton.Importance = widget.WarningImportance
+	case UrgencyCritical:
+		okButton.Importance = widget.DangerImportance
+	}
+
+	// Break glass and -fullscreen: full screen (which also keeps it above other windows) and focused
+	if n.Priority == PriorityBreakGlass {
+		okButton.Importance = widget.DangerImportance
+	}
+	if fullScreen {
+		w.SetFullScreen(true)
+	}
+
+	// Set up auto-close if timeout is specified
+	if n.Timeout > 0 {
+		go func() {
+			time.Sleep(time.Duration(n.Timeout) * time.Second)
+			fyne.DoAndWait(func() {
+				action = ActionTimeout
+				w.Close()
+			})
+		}()
+	}
+
+	// Acknowledging on the phone closes the window
+	if mirror != nil {
+		go func() {
+			select {
+			case <-mirror.Acknowledged():
+				fyne.Do(func() {
+					method = "mobile"
+					redact()
+					w.Close()
+				})
+			case <-runDone:
+			}
+		}()
+	}
+
+	// -ack-file: touching the file (e.g. with a keyboard shortcut running notify ack) acknowledges
+	// without using the window, for users the window is not accessible to
+	if n.AckFile != "" {
+		acked := watchAckFile(n.AckFile, runDone)
+		go func() {
+			select {
+			case <-acked:
+				fyne.Do(func() {
+					method = "ack-file"
+					redact()
+					w.Close()
+				})
+			case <-runDone:
+			}
+		}()
+	}
+
+	// Show the window
+	w.Show()
+	if inputEntry != nil {
+		w.Canvas().Focus(inputEntry)
+	}
+
+	// Force the window to respect our size after showing
+	// This is necessary because Fyne may resize based on content
+	if fullScreen {
+		w.RequestFocus()
+	} else {
+		w.Resize(windowSize)
+	}
+
+	// Windows: virtual desktop and remembered position; Windows and X11: monitor and stacking
+	placed := placeWindow(n.Title, n, fullScreen, slot)
+
+	// -monitor all: a copy on every other monitor, closed with the window
+	copiesPlaced := func() {}
+	if n.Monitor == MonitorAll && !fullScreen {
+		w.SetMaster()
+		copiesPlaced = showScreenCopies(a, w, okButton, n, inputEntry != nil || choiceSelect != nil || screenshot != nil, windowSize)
+	}
+
+	// -state-id: updates of the state replace the icon, and the message when they have one
+	stateWatched := func() {}
+	if n.StateID != "" {
+		stateWatched = watchState(n, func(update StateUpdate) {
+			fyne.Do(func() {
+				close(stateReplaced)
+				stateReplaced = make(chan struct{})
+				if icon := stateIcon(update.State, stateIcons, untilClosed(runDone, stateReplaced)); icon != nil && iconContainer != nil {
+					iconContainer.Objects = []fyne.CanvasObject{icon}
+					iconContainer.Refresh()
+				}
+				if update.State == StateWorking {
+					stateProgress.Show()
+				} else {
+					stateProgress.Hide()
+				}
+				if update.Message != "" {
+					messageLabel = messageText(update.Message, rtl)
+					messageSlot.Objects = []fyne.CanvasObject{messageLabel}
+					messageSlot.Refresh()
+				}
+			})
+		})
+	}
+
+	// -id: a later notification with -replace and the same ID changes the title and message
+	// (not the window title, by which placeWindow finds the window), -cancel closes the window
+	idWatched := func() {}
+	if n.ID != "" {
+		idWatched = watchWindowID(n, func(title, message string) {
+			fyne.Do(func() {
+				titleLabel.SetText(title)
+				messageLabel = messageText(message, rtl)
+				messageSlot.Objects = []fyne.CanvasObject{messageLabel}
+				messageSlot.Refresh()
+			})
+		}, func() {
+			fyne.Do(func() {
+				action = ActionCanceled
+				w.Close()
+			})
+		})
+	}
+
+	// -automation: test scripts read the window and click its buttons over a local socket
+	if n.Automation != "" {
+		if stop, err := startAutomation(n.Automation, fyneAutomation{w}); err != nil {
+			log.Printf("Warning: Automation unavailable: %v", err)
+		} else {
+			defer stop()
+		}
+	}
+
+	// Run the app
+	a.Run()
+	close(runDone)
+	placed()
+	copiesPlaced()
+	stateWatched()
+	idWatched()
+
+	if hung.Load() {
+		return action, method, resp, false, fmt.Errorf("Fyne did not respond (no working OpenGL?), the window was closed")
+	}
+	return action, method, resp, shareScreenshot, nil
+}
+
+// urgencyBarHeight is the height of the accent bar at the top of windows with an urgency
+const urgencyBarHeight = 6
+
+// newWindow creates a window titled title for n: with -frameless a splash window, which has no
+// title bar or borders on every platform (full-screen windows have none anyway)
+func newWindow(a fyne.App, title string, n Notification) fyne.Window {
+	if drv, ok := a.Driver().(desktop.Driver); ok && n.Frameless && !n.fullScreen() {
+		w := drv.CreateSplashWindow()
+		w.SetTitle(title) // Not shown, but placeWindow finds the window by it
+		return w
+	}
+	return a.NewWindow(title)
+}
+
+// urgencyIcon returns the theme icon of an urgency in its color, or nil
+func urgencyIcon(urgency string) fyne.CanvasObject {
+	var icon fyne.Resource
+	switch urgency {
+	case UrgencyInfo:
+		icon = theme.NewPrimaryThemedResource(theme.InfoIcon())
+	case UrgencyWarning:
+		icon = theme.NewWarningThemedResource(theme.WarningIcon())
+	case UrgencyCritical:
+		icon = theme.NewErrorThemedResource(theme.ErrorIcon())
+	default:
+		return nil
+	}
+	image := canvas.NewImageFromResource(icon)
+	image.FillMode = canvas.ImageFillContain
+	image.SetMinSize(fyne.NewSize(48, 48))
+	return image
+}
+
+// textAlign returns the alignment of the text in a window laid out right to left, or not
+func textAlign(rtl bool) fyne.TextAlign {
+	if rtl {
+		return fyne.TextAlignTrailing
+	}
+	return fyne.TextAlignLeading
+}
+
+// messageText returns the message as a word-wrapped label, or as rich text with clickable
+// links (opened in the default browser) when it contains http(s) links; rtl aligns it right
+func messageText(message string, rtl bool) fyne.CanvasObject {
+	parts := splitLinks(message)
+	hasLink := false
+	for _, part := range parts {
+		hasLink = hasLink || part.URL != ""
+	}
+	if !hasLink {
+		label := widget.NewLabel(message)
+		label.Wrapping = fyne.TextWrapWord // Enable word wrapping
+		label.Alignment = textAlign(rtl)
+		return label
+	}
+
+	style := widget.RichTextStyleInline
+	style.Alignment = textAlign(rtl)
+	var segments []widget.RichTextSegment
+	for _, part := range parts {
+		if u, err := url.Parse(part.URL); err == nil && part.URL != "" {
+			segments = append(segments, &widget.HyperlinkSegment{Alignment: style.Alignment, Text: part.Text, URL: u})
+		} else {
+			segments = append(segments, &widget.TextSegment{Style: style, Text: part.Text})
+		}
+	}
+	text := widget.NewRichText(segments...)
+	text.Wrapping = fyne.TextWrapWord
+	return text
+}
+
+// otpDetails returns the -otp code in large digits with a copy button and, with n.OTPExpiry,
+// a countdown under it; at expiry the code is removed from the window (and from the clipboard
+// if it is still there) until stop is closed
+func otpDetails(a fyne.App, n Notification, stop <-chan struct{}) fyne.CanvasObject {
+	code := canvas.NewText(formatOTP(n.OTP), theme.Color(theme.ColorNameForeground))
+	code.TextSize = 32
+	code.TextStyle = fyne.TextStyle{Bold: true, Monospace: true}
+
+	ui := n.ui()
+	var copyButton *widget.Button
+	copyButton = widget.NewButtonWithIcon(ui.text("copy"), theme.ContentCopyIcon(), func() {
+		a.Clipboard().SetContent(n.OTP)
+		copyButton.SetText(ui.text("copied"))
+	})
+	row := container.NewHBox(layout.NewSpacer(), code, copyButton, layout.NewSpacer())
+	if n.OTPExpiry <= 0 {
+		return row
+	}
+
+	expires := time.Now().Add(n.OTPExpiry)
+	countdown := widget.NewLabel(otpCountdown(ui, n.OTPExpiry))
+	countdown.Alignment = fyne.TextAlignCenter
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			left := time.Until(expires)
+			fyne.Do(func() {
+				countdown.SetText(otpCountdown(ui, left))
+				if left > 0 {
+					return
+				}
+				code.Text = "------"
+				code.Refresh()
+				copyButton.SetText(ui.text("copy"))
+				copyButton.Disable()
+				if a.Clipboard().Content() == n.OTP {
+					a.Clipboard().SetContent("")
+				}
+			})
+			if left <= 0 {
+				return
+			}
+		}
+	}()
+	return container.NewVBox(row, countdown)
+}
+
+// timeoutDetails returns a bar (without the percentage) and a line counting down to n.Timeout, until stop is closed
+func timeoutDetails(n Notification, stop <-chan struct{}) fyne.CanvasObject {
+	timeout := time.Duration(n.Timeout) * time.Second
+	closes := time.Now().Add(timeout)
+	ui := n.ui()
+	line := widget.NewLabel(timeoutCountdown(ui, timeout))
+	line.Alignment = fyne.TextAlignTrailing // Opposite the text, as in the WebView window
+	if n.rightToLeft() {
+		line.Alignment = fyne.TextAlignLeading
+	}
+	line.SizeName = theme.SizeNameCaptionText
+	bar := widget.NewProgressBar()
+	bar.Max = timeout.Seconds()
+	bar.SetValue(bar.Max)
+	bar.TextFormatter = func() string { return "" }
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			left := time.Until(closes)
+			fyne.Do(func() {
+				line.SetText(timeoutCountdown(ui, left))
+				bar.SetValue(max(left.Seconds(), 0))
+			})
+			if left <= 0 {
+				return
+			}
+		}
+	}()
+	return container.NewVBox(bar, line)
+}
+
+// mirrorQRSize is the side of the -mobile-mirror QR code in the window
+const mirrorQRSize = 160
+
+// mirrorDetails returns the -mobile-mirror QR code with a caption saying what it is for
+func mirrorDetails(ui uiText, mirror *mobileMirror) fyne.CanvasObject {
+	caption := widget.NewLabel(ui.text("mirror.scan"))
+	caption.Wrapping = fyne.TextWrapWord
+
+	qr := canvas.NewImageFromImage(mirror.QR)
+	qr.FillMode = canvas.ImageFillContain
+	qr.ScaleMode = canvas.ImageScalePixels // Smoothing blurs the modules
+	qr.SetMinSize(fyne.NewSize(mirrorQRSize, mirrorQRSize))
+	return container.NewVBox(caption, qr)
+}
+
+// screenshotDetails returns the consent banner and a collapsed details pane with the screenshot thumbnail
+func screenshotDetails(ui uiText, screenshot *contextScreenshot, buttonText string, consentCheck *widget.Check) fyne.CanvasObject {
+	banner := widget.NewLabel(ui.text("screenshot.banner", screenshot.Taken.Format("15:04:05"), ui.text("details"), buttonText))
+	banner.Wrapping = fyne.TextWrapWord
+	banner.Importance = widget.WarningImportance
+
+	thumbnail := canvas.NewImageFromImage(screenshot.Image)
+	thumbnail.FillMode = canvas.ImageFillContain
+	thumbnail.SetMinSize(fyne.NewSize(320, 180))
+
+	details := widget.NewAccordion(widget.NewAccordionItem(ui.text("details"), container.NewVBox(thumbnail, consentCheck)))
+	return container.NewVBox(banner, details)
+}
+
+// digestDetails returns the notifications of a digest in a collapsed, scrolling list
+func digestDetails(ui uiText, items []string, rtl bool) fyne.CanvasObject {
+	list := container.NewVBox()
+	for _, item := range items {
+		label := widget.NewLabel("• " + item)
+		label.Wrapping = fyne.TextWrapWord
+		label.Alignment = textAlign(rtl)
+		list.Add(label)
+	}
+	scroll := container.NewVScroll(list)
+	scroll.SetMinSize(fyne.NewSize(0, 150))
+	return widget.NewAccordion(widget.NewAccordionItem(ui.text("digest.list", len(items)), scroll))
+}
+
+// DefaultIcon returns the KrankyBear icon used for notification and inbox windows
+func DefaultIcon() fyne.Resource {
+	return resourceKrankyBearBeretPng
+}
+
+// calculateWindowSize calculates optimal window dimensions based on content
+// Returns width and height capped at reasonable maximums
+func calculateWindowSize(title, message, buttonText string, hasIcon bool) (int, int) {
+	// Base dimensions
+	minWidth := 300
+	minHeight := 150
+	maxWidth := 600
+	maxHeight := 400
+
+	// Estimate based on text length
+	// Average character width: ~7 pixels for normal text
+	// Average line height: ~20 pixels
+
+	// Calculate width based on longest line in message
+	messageWidth := estimateTextWidth(message)
+	titleWidth := estimateTextWidth(title)
+	buttonWidth := 100 + len(buttonText)*7 // Button has padding
+
+	// Use the longest element
+	contentWidth := messageWidth
+	if titleWidth > contentWidth {
+		contentWidth = titleWidth
+	}
+	if buttonWidth > contentWidth {
+		contentWidth = buttonWidth
+	}
+
+	// Add padding and icon space
+	width := contentWidth + 60 // 30px padding on each side
+	if hasIcon {
+		width += 80 // Space for icon
+	}
+
+	// Apply width constraints BEFORE calculating line count
+	// This ensures line count is based on the actual available width
+	if width < minWidth {
+		width = minWidth
+	}
+	if width > maxWidth {
+		width = maxWidth
+	}
+
+	// Calculate height based on message lines (using constrained width)
+	messageLines := estimateLineCount(message, width-60)
+	titleLines := 1
+	if len(title) > 50 {
+		titleLines = 2
+	}
+
+	// Calculate total height
+	height := 40 + // Top padding
+		(titleLines * 30) + // Title
+		(messageLines * 25) + // Message lines
+		50 + // Button
+		30 // Bottom padding
+
+	// Apply height constraints
+	if height < minHeight {
+		height = minHeight
+	}
+	if height > maxHeight {
+		height = maxHeight
+	}
+
+	return width, height
+}
+
+// estimateTextWidth estimates the pixel width of text
+func estimateTextWidth(text string) int {
+	const avgCharWidth = 7
+	maxLineLength := 0
+
+	// Split by newlines and find longest line
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		if len(line) > maxLineLength {
+			maxLineLength = len(line)
+		}
+	}
+
+	return maxLineLength * avgCharWidth
+}
+
+// estimateLineCount estimates how many lines the text will take with word wrapping
+func estimateLineCount(text string, availableWidth int) int {
+	if text == "" {
+		return 1
+	}
+
+	const avgCharWidth = 7
+	charsPerLine := availableWidth / avgCharWidth
+
+	if charsPerLine <= 0 {
+		charsPerLine = 40 // Fallback
+	}
+
+	// Count lines considering word wrap
+	words := strings.Fields(text)
+	lines := 1
+	currentLineLength := 0
+
+	for _, word := range words {
+		wordLength := len(word) + 1 // +1 for space
+		if currentLineLength+wordLength > charsPerLine {
+			lines++
+			currentLineLength = wordLength
+		} else {
+			currentLineLength += wordLength
+		}
+	}
+
+	// Add explicit newlines
+	lines += strings.Count(text, "\n")
+
+	return lines
+}
+
+// resolveIconPath resolves an icon path by looking in the executable's directory if it's just a filename
+// Returns the resolved path that should be used to load the icon
+func resolveIconPath(iconPath string) string {
+	if iconPath == "" {
+		return ""
+	}
+
+	// Determine the actual path to use
+	actualPath := iconPath
+
+	// Check if the path is just a filename (no directory separators)
+	if filepath.Base(iconPath) == iconPath {
+		// It's just a filename, look in the executable's directory
+		exePath, err := os.Executable()
+		if err != nil {
+			log.Printf("Warning: Could not determine executable path: %v", err)
+		} else {
+			exeDir := filepath.Dir(exePath)
+			exeDirIconPath := filepath.Join(exeDir, iconPath)
+
+			// Check if the file exists in the executable's directory
+			if _, err := os.Stat(exeDirIconPath); err == nil {
+				log.Printf("Found icon in executable directory: %s", exeDirIconPath)
+				actualPath = exeDirIconPath
+			} else {
+				log.Printf("Icon not found in executable directory (%s), trying current directory", exeDirIconPath)
+			}
+		}
+	}
+
+	return actualPath
+}
+
+// loadIcon loads an image from the specified file path and returns it as a canvas.Image
+// If only a filename is provided (no directory separators), it will look for the file
+// in the executable's directory first, then fall back to the current directory
+// Note: the notify CLI adds a .png extension to -icon values without one, so iconPath should already have an extension
+// Animated GIFs play until stop is closed
+func loadIcon(iconPath string, stop <-chan struct{}) *canvas.Image {
+	if iconPath == "" {
+		return nil
+	}
+
+	// Resolve the icon path (look in exe directory if needed), validate it and
+	// downscale large images, so Fyne gets an absolute path to a small image
+	absPath, err := prepareIcon(iconPath)
+	if err != nil {
+		log.Printf("Warning: Not showing the icon: %v", err)
+		return nil
+	}
+	log.Printf("Loading icon from: %s", absPath)
+
+	// Load the image using Fyne's storage
+	// Note: NewFileURI handles Windows paths correctly, including spaces
+	uri := storage.NewFileURI(absPath)
+	log.Printf("Icon URI: %s", uri.String())
+
+	img := canvas.NewImageFromURI(uri)
+	if anim := loadAnimatedGIF(absPath); anim != nil {
+		img = animatedIcon(anim, stop)
+	}
+
+	if img == nil {
+		log.Printf("Warning: Failed to load icon from URI: %s (path: %s)", uri.String(), absPath)
+		return nil
+	}
+
+	log.Printf("Successfully loaded icon: %s", absPath)
+
+	// Set image properties
+	img.FillMode = canvas.ImageFillContain
+	img.SetMinSize(fyne.NewSize(64, 64))
+
+	return img
+}
+
+// untilClosed returns a channel closed when a or b is
+func untilClosed(a, b <-chan struct{}) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-a:
+		case <-b:
+		}
+		close(done)
+	}()
+	return done
+}
+
+// animatedIcon returns an image playing anim until stop is closed or its loops are done
+func animatedIcon(anim *gif.GIF, stop <-chan struct{}) *canvas.Image {
+	player := newGIFPlayer(anim)
+	img := canvas.NewImageFromImage(player.next())
+	loops := max(anim.LoopCount+1, 1) // LoopCount 0 loops forever, -1 plays once
+	go func() {
+		for loop := 0; anim.LoopCount == 0 || loop < loops; loop++ {
+			for i := range anim.Image {
+				select {
+				case <-stop:
+					return
+				case <-time.After(gifDelay(anim, i)):
+				}
+				if anim.LoopCount != 0 && loop == loops-1 && i == len(anim.Image)-1 {
+					return // The last frame stays
+				}
+				fyne.Do(func() {
+					player.next()
+					img.Refresh()
+				})
+			}
+		}
+	}()
+	return img
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+)
+
+// Values of Notification.RespectDND: what to do while the user has do not disturb on
+const (
+	DNDDefer     = "defer"     // Wait until do not disturb ends, then show the notification
+	DNDDowngrade = "downgrade" // Show a toast instead of the window, which the notification center keeps quietly
+)
+
+// dndPollInterval is how often a deferred notification checks whether do not disturb has ended
+const dndPollInterval = 30 * time.Second
+
+// DoNotDisturb is the do not disturb state of this session
+type DoNotDisturb struct {
+	Active bool
+	Source string // What is on, e.g. "Focus Assist (priority only)", "macOS Focus" or "GNOME do not disturb"
+}
+
+// DetectDoNotDisturb reports whether the user of this session has do not disturb on: Focus
+// Assist (or a full-screen presentation or game) on Windows, a Focus on macOS, GNOME's do not
+// disturb on Linux; not active when it cannot be told
+func DetectDoNotDisturb() DoNotDisturb {
+	return detectDoNotDisturb()
+}
+
+// ValidateRespectDND checks the -respect-dnd flag value
+func ValidateRespectDND(mode string) error {
+	switch mode {
+	case "", DNDDefer, DNDDowngrade:
+		return nil
+	}
+	return fmt.Errorf("invalid do not disturb handling %q (use %s or %s)", mode, DNDDefer, DNDDowngrade)
+}
+
+// respectsDND reports whether n defers to do not disturb: RespectDND is set, and neither
+// OverrideDND nor break glass overrides it
+func (n Notification) respectsDND() bool {
+	return n.RespectDND != "" && !n.OverrideDND && n.Priority != PriorityBreakGlass
+}
+
+// respectDND applies n.RespectDND to a notification about to be shown in this session: with
+// DNDDefer it waits until do not disturb ends; with DNDDowngrade it shows n as a toast and
+// returns its result with handled true (a toast cannot ask for Input or Choices, so those wait)
+func (nt *Notifier) respectDND(n Notification) (result Result, handled bool, err error) {
+	if !n.respectsDND() {
+		return Result{}, false, nil
+	}
+	state := DetectDoNotDisturb()
+	if !state.Active {
+		return Result{}, false, nil
+	}
+	if n.RespectDND == DNDDefer || n.Input || n.Choices != "" {
+		log.Printf("Do not disturb is on (%s), waiting until it ends", state.Source)
+		for state.Active {
+			time.Sleep(dndPollInterval)
+			state = DetectDoNotDisturb()
+		}
+		log.Println("Do not disturb ended, showing the notification")
+		return Result{}, false, nil
+	}
+
+	log.Printf("Do not disturb is on (%s), showing a toast instead of the window", state.Source)
+	activation := ""
+	if runtime.GOOS == "windows" && nt.ToastActivation != nil {
+		activation = nt.ToastActivation(n)
+	}
+	method, err := showNativeNotification(n, activation)
+	if err != nil {
+		return Result{}, true, fmt.Errorf("failed to show native notification: %v", err)
+	}
+	return Result{Action: ActionDelivered, Method: method}, true, nil
+}
+
+// ReportDoNotDisturb prints the do not disturb state of this session for notify check dnd;
+// returns true when it is off
+func ReportDoNotDisturb() bool {
+	state := DetectDoNotDisturb()
+	if !state.Active {
+		fmt.Println("Do not disturb is off")
+		return true
+	}
+	fmt.Printf("Do not disturb is on: %s\n", state.Source)
+	fmt.Println("Notifications with -respect-dnd wait (defer) or become toasts (downgrade), unless -override-dnd")
+	return false
+}
+
+// focusAssistSource describes a Windows Focus Assist profile (0 is off)
+func focusAssistSource(profile uint32) string {
+	switch profile {
+	case 0:
+		return ""
+	case 1:
+		return "Focus Assist (priority only)"
+	case 2:
+		return "Focus Assist (alarms only)"
+	}
+	return fmt.Sprintf("Focus Assist (profile %d)", profile)
+}
+
+// focusAssertions reports whether the macOS do not disturb store (Assertions.json) has a Focus
+// turned on by the user
+func focusAssertions(data []byte) (bool, error) {
+	var store struct {
+		Data []struct {
+			StoreAssertionRecords []json.RawMessage `json:"storeAssertionRecords"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return false, err
+	}
+	for _, d := range store.Data {
+		if len(d.StoreAssertionRecords) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,33 @@
+//go:build darwin
+
+package notify
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// detectDoNotDisturb reads the Focus turned on in Control Center from the do not disturb store
+// (macOS 12 and later; reading it may need Full Disk Access), or the doNotDisturb setting of
+// Notification Center on older releases; Focus schedules are not seen
+func detectDoNotDisturb() DoNotDisturb {
+	if home, err := os.UserHomeDir(); err == nil {
+		if data, err := os.ReadFile(filepath.Join(home, "Library", "DoNotDisturb", "DB", "Assertions.json")); err == nil {
+			if active, err := focusAssertions(data); err == nil {
+				if active {
+					return DoNotDisturb{Active: true, Source: "macOS Focus"}
+				}
+				return DoNotDisturb{}
+			}
+		}
+	}
+	output, err := exec.Command("defaults", "-currentHost", "read", "com.apple.notificationcenterui", "doNotDisturb").Output()
+	if err == nil && strings.TrimSpace(string(output)) == "1" {
+		return DoNotDisturb{Active: true, Source: "macOS do not disturb"}
+	}
+	return DoNotDisturb{}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,20 @@
+//go:build !darwin && !windows
+
+package notify
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// detectDoNotDisturb reads GNOME's do not disturb switch, which turns notification banners off
+// (show-banners false); other desktops are not detected
+func detectDoNotDisturb() DoNotDisturb {
+	output, err := exec.Command("gsettings", "get", "org.gnome.desktop.notifications", "show-banners").Output()
+	if err == nil && strings.TrimSpace(string(output)) == "false" {
+		return DoNotDisturb{Active: true, Source: "GNOME do not disturb"}
+	}
+	return DoNotDisturb{}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
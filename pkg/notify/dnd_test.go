@@ -0,0 +1,53 @@
+package notify
+
+import "testing"
+
+// TestValidateRespectDND tests the -respect-dnd values
+func TestValidateRespectDND(t *testing.T) {
+	for _, mode := range []string{"", DNDDefer, DNDDowngrade} {
+		if err := ValidateRespectDND(mode); err != nil {
+			t.Errorf("ValidateRespectDND(%q) = %v", mode, err)
+		}
+	}
+	for _, mode := range []string{"Defer", "wait", "ignore"} {
+		if err := ValidateRespectDND(mode); err == nil {
+			t.Errorf("ValidateRespectDND(%q) should fail", mode)
+		}
+	}
+}
+
+// TestRespectsDND tests that -override-dnd and break glass override -respect-dnd
+func TestRespectsDND(t *testing.T) {
+	tests := []struct {
+		n    Notification
+		want bool
+	}{
+		{Notification{}, false},
+		{Notification{RespectDND: DNDDefer}, true},
+		{Notification{RespectDND: DNDDowngrade, OverrideDND: true}, false},
+		{Notification{RespectDND: DNDDefer, Priority: PriorityBreakGlass}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.n.respectsDND(); got != tt.want {
+			t.Errorf("respectsDND(%+v) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestFocusAssertions tests reading the macOS do not disturb store
+func TestFocusAssertions(t *testing.T) {
+	on := `{"data":[{"storeAssertionRecords":[{"assertionDetails":{"assertionDetailsModeIdentifier":"com.apple.donotdisturb.mode.default"}}]}],"header":{"version":1}}`
+	off := `{"data":[{}],"header":{"version":1}}`
+	if active, err := focusAssertions([]byte(on)); err != nil || !active {
+		t.Errorf("focusAssertions(on) = %v, %v", active, err)
+	}
+	if active, err := focusAssertions([]byte(off)); err != nil || active {
+		t.Errorf("focusAssertions(off) = %v, %v", active, err)
+	}
+	if _, err := focusAssertions([]byte("not json")); err == nil {
+		t.Error("focusAssertions accepted a broken store")
+	}
+	if focusAssistSource(0) != "" || focusAssistSource(2) != "Focus Assist (alarms only)" {
+		t.Errorf("focusAssistSource = %q, %q", focusAssistSource(0), focusAssistSource(2))
+	}
+}
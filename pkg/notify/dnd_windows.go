@@ -0,0 +1,54 @@
+//go:build windows
+
+package notify
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	ntQueryWnfStateData          = syscall.NewLazyDLL("ntdll.dll").NewProc("NtQueryWnfStateData")
+	shQueryUserNotificationState = syscall.NewLazyDLL("shell32.dll").NewProc("SHQueryUserNotificationState")
+)
+
+// wnfQuietHoursProfile is the WNF state holding the active Focus Assist profile
+// (WNF_SHEL_QUIETHOURS_ACTIVE_PROFILE_CHANGED): 0 off, 1 priority only, 2 alarms only
+const wnfQuietHoursProfile uint64 = 0x0D83063EA3BF1C75
+
+// detectDoNotDisturb reads the Focus Assist profile (undocumented, but what the Action Center
+// itself uses), then asks the shell whether a presentation or full-screen game is running, when
+// Focus Assist's automatic rules hold notifications back too
+func detectDoNotDisturb() DoNotDisturb {
+	if ntQueryWnfStateData.Find() == nil {
+		stateName := wnfQuietHoursProfile
+		var changeStamp, profile uint32
+		size := uint32(unsafe.Sizeof(profile))
+		status, _, _ := ntQueryWnfStateData.Call(uintptr(unsafe.Pointer(&stateName)), 0, 0,
+			uintptr(unsafe.Pointer(&changeStamp)), uintptr(unsafe.Pointer(&profile)), uintptr(unsafe.Pointer(&size)))
+		if status == 0 && profile != 0 {
+			return DoNotDisturb{Active: true, Source: focusAssistSource(profile)}
+		}
+	}
+
+	const (
+		QUNS_RUNNING_D3D_FULL_SCREEN = 3
+		QUNS_PRESENTATION_MODE       = 4
+	)
+	if shQueryUserNotificationState.Find() != nil {
+		return DoNotDisturb{}
+	}
+	var state int32
+	if hr, _, _ := shQueryUserNotificationState.Call(uintptr(unsafe.Pointer(&state))); hr != 0 {
+		return DoNotDisturb{}
+	}
+	switch state {
+	case QUNS_RUNNING_D3D_FULL_SCREEN:
+		return DoNotDisturb{Active: true, Source: "full-screen game"}
+	case QUNS_PRESENTATION_MODE:
+		return DoNotDisturb{Active: true, Source: "presentation mode"}
+	}
+	return DoNotDisturb{}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
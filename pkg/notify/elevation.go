@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"fmt"
+)
+
+// Elevation states reported by ElevationState
+const (
+	elevationSystem   = "system"   // Windows LocalSystem account
+	elevationElevated = "elevated" // Full administrator token (UAC elevated or UAC disabled), or root
+	elevationFiltered = "filtered" // Administrator running with a UAC filtered (limited) token
+	elevationStandard = "standard" // Standard user without administrator rights
+)
+
+// DescribeElevationState returns a human-readable explanation of an elevation state
+func DescribeElevationState(state string) string {
+	switch state {
+	case elevationSystem:
+		return "Running as SYSTEM - notifications will be shown to logged-in users"
+	case elevationElevated:
+		return "Running elevated - notifications will be shown to logged-in users"
+	case elevationFiltered:
+		return "Administrator with a filtered UAC token - run elevated to notify other users"
+	default:
+		return "Standard user - notifications are shown in the current session only"
+	}
+}
+
+// ReportElevation prints the elevation state for scripts
+// Returns true when the process can notify other users (system or elevated)
+func ReportElevation() bool {
+	state := ElevationState()
+	fmt.Printf("Elevation state: %s\n", state)
+	fmt.Println(DescribeElevationState(state))
+	return state == elevationSystem || state == elevationElevated
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+)
+
+// EscalationStep is one delivery attempt of a notification with a deadline (Options.DeliverBy)
+type EscalationStep struct {
+	At    time.Time
+	Mode  string // ModeNative for the first, non-blocking attempt, then ModeAuto (a window)
+	Final bool   // At the deadline: wall broadcast (Linux) and a window that stays until acknowledged
+}
+
+// escalationFractions are the points of the time left before the deadline at which the
+// notification is shown again, so attempts get closer together as the deadline approaches
+var escalationFractions = []float64{0, 0.5, 0.75, 0.875}
+
+// EscalationPlan returns the attempts for a notification that must be acknowledged by deadline:
+// a toast at start, windows at half, three quarters and seven eighths of the time left,
+// and the final widening at the deadline
+// A deadline that has already passed gets only the final step, at start
+func EscalationPlan(start, deadline time.Time) []EscalationStep {
+	if !deadline.After(start) {
+		return []EscalationStep{{At: start, Mode: ModeAuto, Final: true}}
+	}
+	window := deadline.Sub(start)
+	var steps []EscalationStep
+	for i, fraction := range escalationFractions {
+		mode := ModeAuto
+		if i == 0 {
+			mode = ModeNative
+		}
+		at := start.Add(time.Duration(float64(window) * fraction))
+		// Attempts less than a minute apart (or from the deadline) would only stack windows
+		if i > 0 && (at.Sub(steps[len(steps)-1].At) < time.Minute || deadline.Sub(at) < time.Minute) {
+			continue
+		}
+		steps = append(steps, EscalationStep{At: at, Mode: mode})
+	}
+	return append(steps, EscalationStep{At: deadline, Mode: ModeAuto, Final: true})
+}
+
+// sendBy delivers opts following EscalationPlan until the user acknowledges it
+// Windows close in time for the next step; the final window has no timeout
+func (nt *Notifier) sendBy(opts Options) (Result, error) {
+	if opts.Mode != "" && opts.Mode != ModeAuto {
+		log.Printf("Deliver by: ignoring mode %s, the escalation chooses the channels", opts.Mode)
+	}
+	steps := EscalationPlan(time.Now(), opts.DeliverBy)
+	log.Printf("Deliver by %s: %d steps", opts.DeliverBy.Format(time.RFC3339), len(steps))
+
+	var result Result
+	var lastErr error
+	delivered := false
+	for i, step := range steps {
+		if wait := time.Until(step.At); wait > 0 {
+			log.Printf("Escalation: waiting %v for step %d", wait.Round(time.Second), i+1)
+			time.Sleep(wait)
+		}
+
+		stepOpts := opts
+		stepOpts.DeliverBy = time.Time{}
+		stepOpts.Mode = step.Mode
+		if step.Final {
+			stepOpts.Timeout = 0
+			if runtime.GOOS == "linux" && IsWallAvailable() {
+				log.Println("Escalation: deadline reached, also sending wall broadcast")
+				if err := broadcastWallMessage(stepOpts.Notification); err != nil {
+					log.Printf("Warning: Wall broadcast failed: %v", err)
+				}
+			}
+		} else if next := time.Until(steps[i+1].At); step.Mode == ModeAuto && (stepOpts.Timeout == 0 || time.Duration(stepOpts.Timeout)*time.Second > next) {
+			stepOpts.Timeout = int(next.Seconds())
+			if stepOpts.Timeout < 1 {
+				stepOpts.Timeout = 1
+			}
+		}
+
+		log.Printf("Escalation step %d/%d: mode %s", i+1, len(steps), step.Mode)
+		stepResult, err := nt.Send(stepOpts)
+		if err != nil {
+			// A channel that is not available here (e.g. no notification center) moves on to the next
+			log.Printf("Warning: Escalation step %d failed: %v", i+1, err)
+			lastErr = err
+			continue
+		}
+		result, delivered = stepResult, true
+		if result.Action == ActionAcknowledged || result.Action == ActionSuppressed {
+			return result, nil
+		}
+	}
+	if !delivered {
+		return Result{}, fmt.Errorf("notification could not be delivered by %s: %v", opts.DeliverBy.Format(time.RFC3339), lastErr)
+	}
+	return result, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEscalationPlan tests that attempts get closer together as the deadline approaches
+func TestEscalationPlan(t *testing.T) {
+	start := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	deadline := time.Date(2025, 3, 3, 17, 0, 0, 0, time.UTC)
+
+	steps := EscalationPlan(start, deadline)
+	want := []struct {
+		at    string
+		mode  string
+		final bool
+	}{
+		{"09:00", ModeNative, false},
+		{"13:00", ModeAuto, false},
+		{"15:00", ModeAuto, false},
+		{"16:00", ModeAuto, false},
+		{"17:00", ModeAuto, true},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("Got %d steps, want %d: %+v", len(steps), len(want), steps)
+	}
+	for i, w := range want {
+		got := steps[i]
+		if got.At.Format("15:04") != w.at || got.Mode != w.mode || got.Final != w.final {
+			t.Errorf("Step %d = %s %s final=%v, want %s %s final=%v", i, got.At.Format("15:04"), got.Mode, got.Final, w.at, w.mode, w.final)
+		}
+	}
+}
+
+// TestEscalationPlanShortDeadline tests that attempts less than a minute apart are dropped
+func TestEscalationPlanShortDeadline(t *testing.T) {
+	start := time.Date(2025, 3, 3, 16, 58, 0, 0, time.UTC)
+	deadline := start.Add(90 * time.Second)
+
+	steps := EscalationPlan(start, deadline)
+	if len(steps) != 2 || steps[0].Mode != ModeNative || !steps[1].Final {
+		t.Errorf("Expected a toast and the final step, got %+v", steps)
+	}
+}
+
+// TestEscalationPlanPassedDeadline tests that a deadline already passed goes straight to the final step
+func TestEscalationPlanPassedDeadline(t *testing.T) {
+	start := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	steps := EscalationPlan(start, start.Add(-time.Hour))
+	if len(steps) != 1 || !steps[0].Final || !steps[0].At.Equal(start) {
+		t.Errorf("Expected only the final step at start, got %+v", steps)
+	}
+}
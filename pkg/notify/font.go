@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Range of Notification.FontSize, in the pixels of the window's text
+const (
+	MinFontSize = 8
+	MaxFontSize = 72
+)
+
+// maxFontFileSize caps a -font file, which the WebView window embeds in its page
+const maxFontFileSize = 10 << 20
+
+// headingScale is the size of WebView titles relative to the message text with -font-size
+const headingScale = 1.5
+
+// ValidateFont checks the -font file: a TrueType or OpenType font that exists
+func ValidateFont(path string) error {
+	if path == "" {
+		return nil
+	}
+	if fontFormat(path) == "" {
+		return fmt.Errorf("invalid -font %q: use a .ttf or .otf file", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid -font: %v", err)
+	}
+	if info.Size() > maxFontFileSize {
+		return fmt.Errorf("invalid -font %q: larger than %d MB", path, maxFontFileSize>>20)
+	}
+	return nil
+}
+
+// ValidateFontSize checks the -font-size value, 0 for the default size
+func ValidateFontSize(size int) error {
+	if size != 0 && (size < MinFontSize || size > MaxFontSize) {
+		return fmt.Errorf("invalid -font-size %d (use %d to %d)", size, MinFontSize, MaxFontSize)
+	}
+	return nil
+}
+
+// fontFormat returns the CSS format of a font file from its extension, empty when not supported
+func fontFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ttf":
+		return "truetype"
+	case ".otf":
+		return "opentype"
+	}
+	return ""
+}
+
+// fontCSS returns the WebView style rules of the -font and -font-size of n
+// The font is embedded in the page, so it needs no file access from the web engine
+func fontCSS(n Notification) string {
+	var rules []string
+	if n.Font != "" {
+		data, err := os.ReadFile(n.Font)
+		if err != nil {
+			log.Printf("Warning: Could not load -font, using the default font: %v", err)
+		} else {
+			mimeType := "font/ttf"
+			if fontFormat(n.Font) == "opentype" {
+				mimeType = "font/otf"
+			}
+			rules = append(rules,
+				fmt.Sprintf(`@font-face { font-family: "NotifyFont"; src: url(data:%s;base64,%s) format("%s"); }`,
+					mimeType, base64.StdEncoding.EncodeToString(data), fontFormat(n.Font)),
+				`body, .input, .ok-button { font-family: "NotifyFont", -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Arial, sans-serif; }`)
+		}
+	}
+	if n.FontSize > 0 {
+		rules = append(rules,
+			fmt.Sprintf(".message, .input, .ok-button { font-size: %dpx; }", n.FontSize),
+			fmt.Sprintf(".title { font-size: %dpx; }", int(math.Round(float64(n.FontSize)*headingScale))))
+	}
+	return strings.Join(rules, "\n        ")
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidateFont tests the -font and -font-size checks
+func TestValidateFont(t *testing.T) {
+	dir := t.TempDir()
+	font := filepath.Join(dir, "Brand.TTF")
+	if err := os.WriteFile(font, []byte("font"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateFont(font); err != nil {
+		t.Errorf("ValidateFont(%q) = %v", font, err)
+	}
+	for _, path := range []string{filepath.Join(dir, "missing.otf"), filepath.Join(dir, "brand.woff2")} {
+		if err := ValidateFont(path); err == nil {
+			t.Errorf("ValidateFont(%q) accepted it", path)
+		}
+	}
+
+	for _, size := range []int{0, MinFontSize, 18, MaxFontSize} {
+		if err := ValidateFontSize(size); err != nil {
+			t.Errorf("ValidateFontSize(%d) = %v", size, err)
+		}
+	}
+	for _, size := range []int{-1, MinFontSize - 1, MaxFontSize + 1} {
+		if err := ValidateFontSize(size); err == nil {
+			t.Errorf("ValidateFontSize(%d) accepted it", size)
+		}
+	}
+}
+
+// TestFontCSS tests that the WebView embeds the -font and sizes the text with -font-size
+func TestFontCSS(t *testing.T) {
+	if css := fontCSS(Notification{}); css != "" {
+		t.Errorf("fontCSS without a font = %q, want none", css)
+	}
+	font := filepath.Join(t.TempDir(), "brand.otf")
+	if err := os.WriteFile(font, []byte("font"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	css := fontCSS(Notification{Font: font, FontSize: 20})
+	for _, want := range []string{`url(data:font/otf;base64,Zm9udA==) format("opentype")`, ".message, .input, .ok-button { font-size: 20px; }", ".title { font-size: 30px; }"} {
+		if !strings.Contains(css, want) {
+			t.Errorf("fontCSS = %q, want %q in it", css, want)
+		}
+	}
+	if css := fontCSS(Notification{Font: filepath.Join(t.TempDir(), "gone.ttf")}); css != "" {
+		t.Errorf("fontCSS with a missing font = %q, want the default font", css)
+	}
+}
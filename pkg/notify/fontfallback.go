@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+)
+
+// fallbackFontDirName is the directory next to the notify executable searched first for a
+// fallback font, so installers can ship one for machines without CJK fonts
+const fallbackFontDirName = "fonts"
+
+// collectionFont is a font file the Fyne window can fall back to, and the index of the font
+// to use when it is a .ttc collection
+type collectionFont struct {
+	name  string
+	index int
+}
+
+// CJK fonts of Windows, macOS and Linux distributions, by the script that prefers them
+// Noto Sans CJK collections hold the JP, KR, SC and TC fonts in that order
+var (
+	japaneseFonts = []collectionFont{
+		{"NotoSansCJK-Regular.ttc", 0}, {"NotoSansCJKjp-Regular.otf", 0}, {"SourceHanSans-Regular.ttc", 0},
+		{"YuGothR.ttc", 0}, {"meiryo.ttc", 0}, {"msgothic.ttc", 0}, {"ヒラギノ角ゴシック W3.ttc", 0},
+		{"ipagp.ttf", 0}, {"TakaoPGothic.ttf", 0},
+	}
+	koreanFonts = []collectionFont{
+		{"NotoSansCJK-Regular.ttc", 1}, {"NotoSansCJKkr-Regular.otf", 0}, {"SourceHanSans-Regular.ttc", 1},
+		{"malgun.ttf", 0}, {"AppleSDGothicNeo.ttc", 0}, {"NanumGothic.ttf", 0}, {"UnDotum.ttf", 0},
+	}
+	chineseFonts = []collectionFont{
+		{"NotoSansCJK-Regular.ttc", 2}, {"NotoSansCJKsc-Regular.otf", 0}, {"SourceHanSans-Regular.ttc", 2},
+		{"msyh.ttc", 0}, {"simsun.ttc", 0}, {"Hiragino Sans GB.ttc", 0}, {"STHeiti Light.ttc", 0},
+		{"wqy-microhei.ttc", 0}, {"wqy-zenhei.ttc", 0}, {"DroidSansFallbackFull.ttf", 0}, {"DroidSansFallback.ttf", 0},
+	}
+	unicodeFonts = []collectionFont{{"Arial Unicode.ttf", 0}, {"ARIALUNI.TTF", 0}}
+)
+
+// fallbackFontCandidates returns the fonts for the CJK text in text, those of its script
+// first, or nil when text has none
+func fallbackFontCandidates(text string) []collectionFont {
+	var han, kana, hangul bool
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana = true
+		case unicode.Is(unicode.Hangul, r):
+			hangul = true
+		case unicode.In(r, unicode.Han, unicode.Bopomofo):
+			han = true
+		}
+	}
+	var candidates []collectionFont
+	switch {
+	case kana:
+		candidates = append(candidates, japaneseFonts...)
+		candidates = append(candidates, chineseFonts...)
+		candidates = append(candidates, koreanFonts...)
+	case hangul:
+		candidates = append(candidates, koreanFonts...)
+		candidates = append(candidates, japaneseFonts...)
+		candidates = append(candidates, chineseFonts...)
+	case han:
+		candidates = append(candidates, chineseFonts...)
+		candidates = append(candidates, japaneseFonts...)
+		candidates = append(candidates, koreanFonts...)
+	default:
+		return nil
+	}
+	return append(candidates, unicodeFonts...)
+}
+
+// fontDirs returns the directories searched for fallback fonts: fonts next to the executable,
+// then the system's and the user's font directories
+func fontDirs() []string {
+	var dirs []string
+	if exePath, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Join(filepath.Dir(exePath), fallbackFontDirName))
+	}
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		dirs = append(dirs, filepath.Join(os.Getenv("WINDIR"), "Fonts"), filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts"))
+	case "darwin":
+		dirs = append(dirs, "/System/Library/Fonts", "/Library/Fonts", filepath.Join(home, "Library", "Fonts"))
+	default:
+		dirs = append(dirs, "/usr/share/fonts", "/usr/local/share/fonts", filepath.Join(home, ".local", "share", "fonts"), filepath.Join(home, ".fonts"))
+	}
+	return dirs
+}
+
+// findFonts returns the paths of the font files in dirs (and their subdirectories) by
+// lower-case file name, the first directory taking precedence
+func findFonts(dirs []string) map[string]string {
+	found := map[string]string{}
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			name := strings.ToLower(d.Name())
+			if _, ok := found[name]; !ok {
+				found[name] = path
+			}
+			return nil
+		})
+	}
+	return found
+}
+
+// fallbackFont returns a font with the CJK characters of text for the Fyne window, nil when
+// text has none or no such font is installed
+// Fyne's own fonts cover Latin, Greek, Cyrillic and emoji; its search of the system fonts for
+// other characters finds nothing when it cannot build its font cache, as in service sessions
+func fallbackFont(text string) fyne.Resource {
+	candidates := fallbackFontCandidates(text)
+	if len(candidates) == 0 {
+		return nil
+	}
+	fonts := findFonts(fontDirs())
+	for _, candidate := range candidates {
+		path, ok := fonts[strings.ToLower(candidate.name)]
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err == nil && strings.EqualFold(filepath.Ext(path), ".ttc") {
+			data, err = extractCollectionFont(data, candidate.index)
+		}
+		if err != nil {
+			log.Printf("Warning: Could not load fallback font %s: %v", path, err)
+			continue
+		}
+		log.Printf("Font: using %s for the CJK text", path)
+		return fyne.NewStaticResource(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+".otf", data)
+	}
+	log.Printf("Warning: No CJK font found, the text may show as boxes (install one such as Noto Sans CJK)")
+	return nil
+}
+
+// extractCollectionFont returns font index of a .ttc collection as a font file of its own,
+// which Fyne can load: the tables of that font, copied after a new table directory
+func extractCollectionFont(data []byte, index int) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "ttcf" {
+		return nil, fmt.Errorf("not a font collection")
+	}
+	count := int(binary.BigEndian.Uint32(data[8:]))
+	if index >= count || len(data) < 12+4*count {
+		return nil, fmt.Errorf("no font %d in a collection of %d", index, count)
+	}
+	offset := int(binary.BigEndian.Uint32(data[12+4*index:]))
+	if offset+12 > len(data) {
+		return nil, fmt.Errorf("font %d is outside the collection", index)
+	}
+	tables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	directory := offset + 12
+	if directory+16*tables > len(data) {
+		return nil, fmt.Errorf("table directory of font %d is outside the collection", index)
+	}
+
+	// The header and table directory, then each table at a 4-byte boundary
+	out := make([]byte, 12+16*tables)
+	copy(out, data[offset:directory+16*tables])
+	for i := 0; i < tables; i++ {
+		record := out[12+16*i:]
+		start := int(binary.BigEndian.Uint32(record[8:]))
+		length := int(binary.BigEndian.Uint32(record[12:]))
+		if start+length > len(data) {
+			return nil, fmt.Errorf("table %q of font %d is outside the collection", record[:4], index)
+		}
+		binary.BigEndian.PutUint32(record[8:], uint32(len(out)))
+		out = append(out, data[start:start+length]...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out, nil
+}
+
+// windowText returns the text a Fyne window of n can show, to find the scripts it needs
+func (n Notification) windowText() string {
+	parts := []string{n.Title, n.Message, HTMLText(n.HTML), n.ButtonText, n.Choices, n.InputDefault, n.InputPlaceholder}
+	for _, text := range n.ui().texts {
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
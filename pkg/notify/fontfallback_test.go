@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFallbackFontCandidates tests that the fonts of the script come first, and none for Latin text
+func TestFallbackFontCandidates(t *testing.T) {
+	if got := fallbackFontCandidates("Reboot at 22:00 🔧 Ünïcödé"); got != nil {
+		t.Errorf("Latin and emoji text should need no fallback font, got %v", got)
+	}
+	for text, want := range map[string]collectionFont{
+		"サーバーを再起動します":      japaneseFonts[0],
+		"서버를 다시 시작합니다":     koreanFonts[0],
+		"服务器将重新启动":         chineseFonts[0],
+		"再起動 (kanji only)": chineseFonts[0],
+	} {
+		got := fallbackFontCandidates(text)
+		if len(got) == 0 || got[0] != want {
+			t.Errorf("fallbackFontCandidates(%q) starts with %v, want %v", text, got, want)
+		}
+		if last := got[len(got)-1]; last != unicodeFonts[len(unicodeFonts)-1] {
+			t.Errorf("fallbackFontCandidates(%q) should end with the Unicode fonts, got %v", text, last)
+		}
+	}
+}
+
+// TestFindFonts tests that font files are found in subdirectories by lower-case name, the first
+// directory taking precedence
+func TestFindFonts(t *testing.T) {
+	bundled, system := t.TempDir(), t.TempDir()
+	for _, path := range []string{
+		filepath.Join(bundled, "NotoSansCJK-Regular.ttc"),
+		filepath.Join(system, "opentype", "noto", "NotoSansCJK-Regular.ttc"),
+		filepath.Join(system, "truetype", "msyh.ttc"),
+	} {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("font"), 0644)
+	}
+	found := findFonts([]string{bundled, filepath.Join(bundled, "missing"), system})
+	if found["notosanscjk-regular.ttc"] != filepath.Join(bundled, "NotoSansCJK-Regular.ttc") {
+		t.Errorf("Expected the bundled font first, got %q", found["notosanscjk-regular.ttc"])
+	}
+	if found["msyh.ttc"] != filepath.Join(system, "truetype", "msyh.ttc") {
+		t.Errorf("Expected msyh.ttc in a subdirectory, got %q", found["msyh.ttc"])
+	}
+}
+
+// TestExtractCollectionFont tests that a font of a collection becomes a font file with its own tables
+func TestExtractCollectionFont(t *testing.T) {
+	// A collection of two fonts with one table each, the second font's at an odd length
+	font := func(tag string, offset, length int) []byte {
+		b := make([]byte, 28)
+		binary.BigEndian.PutUint32(b, 0x00010000)
+		binary.BigEndian.PutUint16(b[4:], 1)
+		copy(b[12:], tag)
+		binary.BigEndian.PutUint32(b[20:], uint32(offset))
+		binary.BigEndian.PutUint32(b[24:], uint32(length))
+		return b
+	}
+	var ttc bytes.Buffer
+	ttc.WriteString("ttcf")
+	binary.Write(&ttc, binary.BigEndian, []uint32{0x00010000, 2, 20, 48})
+	ttc.Write(font("cmap", 76, 4))
+	ttc.Write(font("glyf", 80, 3))
+	ttc.WriteString("AAAABBB")
+
+	got, err := extractCollectionFont(ttc.Bytes(), 1)
+	if err != nil {
+		t.Fatalf("extractCollectionFont failed: %v", err)
+	}
+	want := append(font("glyf", 28, 3), 'B', 'B', 'B', 0)
+	if !bytes.Equal(got, want) {
+		t.Errorf("extractCollectionFont = %x, want %x", got, want)
+	}
+
+	if _, err := extractCollectionFont(ttc.Bytes(), 2); err == nil {
+		t.Error("A font index past the collection should fail")
+	}
+	if _, err := extractCollectionFont([]byte("OTTO and more bytes"), 0); err == nil {
+		t.Error("A font that is not a collection should fail")
+	}
+}
@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Animated GIF icons: frame delays as browsers play them (0 and 1 hundredths mean "as fast as
+// possible", which they slow down), and at most iconMaxFrames frames in the Fyne window, so a long
+// animation does not keep thousands of frames in memory
+const (
+	gifMinDelay     = 20 * time.Millisecond
+	gifDefaultDelay = 100 * time.Millisecond
+	iconMaxFrames   = 300
+)
+
+// prepareAnimatedGIF returns the animated GIF icon at path when it is at most iconMaxSide pixels,
+// otherwise a copy with every frame scaled down, cached per user like downscaled icons
+func prepareAnimatedGIF(path string, info os.FileInfo, anim *gif.GIF) (string, error) {
+	width, height := anim.Config.Width, anim.Config.Height
+	if width <= iconMaxSide && height <= iconMaxSide {
+		return path, nil
+	}
+	cachePath := strings.TrimSuffix(iconCachePath(path, info), ".png") + ".gif"
+	if _, err := os.Stat(cachePath); err == nil {
+		log.Printf("Using cached downscaled animated icon for %s", path)
+		touchCachedIcon(cachePath)
+		return cachePath, nil
+	}
+
+	scaled := scaleGIF(anim, iconMaxSide)
+	log.Printf("Icon %s (animated gif, %dx%d, %d frames) scaled to %dx%d", path, width, height, len(anim.Image),
+		scaled.Image[0].Bounds().Dx(), scaled.Image[0].Bounds().Dy())
+	if err := writeCached(cachePath, func(w io.Writer) error { return gif.EncodeAll(w, scaled) }); err != nil {
+		return "", err
+	}
+	return cachePath, nil
+}
+
+// scaleGIF returns anim with every frame composed as it is shown and scaled to side pixels on its
+// longest side, in the colors of the original frame
+func scaleGIF(anim *gif.GIF, side int) *gif.GIF {
+	player := newGIFPlayer(anim)
+	scaled := &gif.GIF{LoopCount: anim.LoopCount}
+	for i, frame := range anim.Image {
+		img := scaleThumbnail(player.next(), side)
+		palette := color.Palette(frame.Palette)
+		if len(palette) < 256 {
+			// A transparent entry keeps transparent areas transparent after scaling
+			palette = append(palette[:len(palette):len(palette)], color.Transparent)
+		}
+		dst := image.NewPaletted(img.Bounds(), palette)
+		draw.FloydSteinberg.Draw(dst, dst.Bounds(), img, img.Bounds().Min)
+		scaled.Image = append(scaled.Image, dst)
+		scaled.Delay = append(scaled.Delay, anim.Delay[i])
+		scaled.Disposal = append(scaled.Disposal, gif.DisposalBackground) // Every frame is complete
+	}
+	return scaled
+}
+
+// gifDelay returns how long frame i of anim is shown
+func gifDelay(anim *gif.GIF, i int) time.Duration {
+	delay := time.Duration(anim.Delay[i]) * 10 * time.Millisecond
+	if delay < gifMinDelay {
+		return gifDefaultDelay
+	}
+	return delay
+}
+
+// gifPlayer composes the frames of an animated GIF, which may cover part of the image and leave
+// the rest as the frames before drew it, into complete images
+type gifPlayer struct {
+	anim     *gif.GIF
+	index    int // Next frame
+	canvas   *image.RGBA
+	previous *image.RGBA // The canvas before a DisposalPrevious frame
+
+	dispose     byte // Disposal of the frame on the canvas, applied before drawing the next
+	disposeRect image.Rectangle
+}
+
+// newGIFPlayer returns a player of anim starting at its first frame
+func newGIFPlayer(anim *gif.GIF) *gifPlayer {
+	bounds := image.Rect(0, 0, anim.Config.Width, anim.Config.Height)
+	if bounds.Empty() {
+		bounds = anim.Image[0].Bounds()
+	}
+	return &gifPlayer{anim: anim, canvas: image.NewRGBA(bounds)}
+}
+
+// next draws the next frame, the first again after the last, and returns the canvas; the canvas
+// is the same image every time
+func (p *gifPlayer) next() *image.RGBA {
+	if p.index == len(p.anim.Image) {
+		p.index, p.dispose = 0, gif.DisposalNone
+		draw.Draw(p.canvas, p.canvas.Bounds(), image.Transparent, image.Point{}, draw.Src)
+	}
+	switch p.dispose {
+	case gif.DisposalBackground:
+		draw.Draw(p.canvas, p.disposeRect, image.Transparent, image.Point{}, draw.Src)
+	case gif.DisposalPrevious:
+		if p.previous != nil {
+			copy(p.canvas.Pix, p.previous.Pix)
+		}
+	}
+
+	frame := p.anim.Image[p.index]
+	p.dispose, p.disposeRect = gif.DisposalNone, frame.Bounds()
+	if p.index < len(p.anim.Disposal) {
+		p.dispose = p.anim.Disposal[p.index]
+	}
+	if p.dispose == gif.DisposalPrevious {
+		if p.previous == nil {
+			p.previous = image.NewRGBA(p.canvas.Bounds())
+		}
+		copy(p.previous.Pix, p.canvas.Pix)
+	}
+	draw.Draw(p.canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	p.index++
+	return p.canvas
+}
+
+// loadAnimatedGIF returns the frames of the GIF at path when it is animated, at most iconMaxFrames
+func loadAnimatedGIF(path string) *gif.GIF {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	anim, err := gif.DecodeAll(file)
+	if err != nil || len(anim.Image) < 2 {
+		return nil
+	}
+	if len(anim.Image) > iconMaxFrames {
+		log.Printf("Animated icon %s has %d frames, playing the first %d", path, len(anim.Image), iconMaxFrames)
+		anim.Image, anim.Delay = anim.Image[:iconMaxFrames], anim.Delay[:iconMaxFrames]
+		if len(anim.Disposal) > iconMaxFrames {
+			anim.Disposal = anim.Disposal[:iconMaxFrames]
+		}
+	}
+	return anim
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGIF returns a size x size animation of three frames: a red background, a blue square in the
+// top left corner that is cleared after it is shown, and a green square in the bottom right corner
+func testGIF(size int) *gif.GIF {
+	palette := color.Palette{color.Transparent, color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}, color.RGBA{0, 0xff, 0, 0xff}}
+	frame := func(rect image.Rectangle, index uint8) *image.Paletted {
+		img := image.NewPaletted(rect, palette)
+		for i := range img.Pix {
+			img.Pix[i] = index
+		}
+		return img
+	}
+	half := size / 2
+	return &gif.GIF{
+		Image: []*image.Paletted{
+			frame(image.Rect(0, 0, size, size), 1),
+			frame(image.Rect(0, 0, half, half), 2),
+			frame(image.Rect(half, half, size, size), 3),
+		},
+		Delay:    []int{0, 50, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalNone},
+		Config:   image.Config{ColorModel: palette, Width: size, Height: size},
+	}
+}
+
+// TestGIFPlayer tests that frames are composed over the ones before, with their disposal
+func TestGIFPlayer(t *testing.T) {
+	player := newGIFPlayer(testGIF(8))
+	red, blue, green := color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}, color.RGBA{0, 0xff, 0, 0xff}
+	steps := []struct {
+		topLeft, bottomRight color.RGBA
+	}{
+		{red, red},
+		{blue, red},
+		{red, green}, // The blue square is disposed to what was there before
+		{red, red},   // Played again from the start
+	}
+	for i, step := range steps {
+		canvas := player.next()
+		if got := canvas.RGBAAt(1, 1); got != step.topLeft {
+			t.Errorf("Step %d: top left is %v, want %v", i, got, step.topLeft)
+		}
+		if got := canvas.RGBAAt(6, 6); got != step.bottomRight {
+			t.Errorf("Step %d: bottom right is %v, want %v", i, got, step.bottomRight)
+		}
+	}
+
+	anim := testGIF(8)
+	for i, want := range []time.Duration{gifDefaultDelay, 500 * time.Millisecond, 100 * time.Millisecond} {
+		if got := gifDelay(anim, i); got != want {
+			t.Errorf("gifDelay(%d) = %v, want %v", i, got, want)
+		}
+	}
+}
+
+// TestPrepareAnimatedGIF tests that a large animated GIF is scaled to a cached GIF with all its
+// frames, and a small one is used as it is
+func TestPrepareAnimatedGIF(t *testing.T) {
+	dir := t.TempDir()
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache) // os.UserCacheDir on Linux
+	t.Setenv("HOME", cache)           // macOS
+	t.Setenv("LocalAppData", cache)   // Windows
+
+	write := func(name string, anim *gif.GIF) string {
+		path := filepath.Join(dir, name)
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if err := gif.EncodeAll(file, anim); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	small := write("small.gif", testGIF(64))
+	if got, err := prepareIcon(small); err != nil || got != small {
+		t.Errorf("prepareIcon(small) = %q, %v, want %q", got, err, small)
+	}
+
+	large := write("large.gif", testGIF(512))
+	got, err := prepareIcon(large)
+	if err != nil || !strings.HasPrefix(got, cache) || filepath.Ext(got) != ".gif" {
+		t.Fatalf("prepareIcon(large) = %q, %v, want a GIF in the cache", got, err)
+	}
+	anim := loadAnimatedGIF(got)
+	if anim == nil || len(anim.Image) != 3 {
+		t.Fatalf("Expected the scaled icon to keep its 3 frames, got %v", anim)
+	}
+	if b := anim.Image[0].Bounds(); b.Dx() != iconMaxSide || b.Dy() != iconMaxSide {
+		t.Errorf("Scaled to %dx%d, want %dx%d", b.Dx(), b.Dy(), iconMaxSide, iconMaxSide)
+	}
+	if r, g, b, _ := newGIFPlayer(anim).next().At(iconMaxSide/2, iconMaxSide/2).RGBA(); r>>8 != 0xff || g != 0 || b != 0 {
+		t.Errorf("Expected the first frame to stay red, got %v", anim.Image[0].At(iconMaxSide/2, iconMaxSide/2))
+	}
+}
@@ -1,6 +1,6 @@
 //go:build darwin
 
-package main
+package notify
 
 import (
 	"fmt"
@@ -56,70 +56,70 @@ func getUIDForUser(username string) string {
 }
 
 // showNotificationToUsers shows notifications to all GUI users on macOS
-func showNotificationToUsers(title, message string, timeout int, iconPath string, width, height int, buttonText string) error {
+// With opts.Targets only their sessions are notified, and a result is returned per target
+func showNotificationToUsers(exePath string, opts Options) ([]TargetResult, error) {
 	users := getMacGUIUsers()
-	if len(users) == 0 {
-		return fmt.Errorf("no GUI users found")
+	if len(users) == 0 && len(opts.Targets) == 0 {
+		return nil, fmt.Errorf("no GUI users found")
+	}
+	sessions := make([]userSession, len(users))
+	for i, user := range users {
+		sessions[i] = userSession{Username: user.Username, ID: user.UID, Name: "console"}
 	}
 
 	var lastErr error
 	successCount := 0
 
-	for _, user := range users {
-		err := showNotificationAsMacUser(user, title, message, timeout, iconPath, width, height, buttonText)
-		if err != nil {
-			lastErr = err
+	deliveries := planDeliveries(opts, sessions)
+	errs := make([]error, len(deliveries))
+	for i, delivery := range deliveries {
+		errs[i] = showNotificationAsMacUser(users[delivery.session], exePath, delivery.opts.Notification)
+		if errs[i] != nil {
+			lastErr = errs[i]
 		} else {
 			successCount++
 		}
 	}
+	results := targetResults(opts.Targets, sessions, deliveries, errs)
 
 	if successCount == 0 && lastErr != nil {
-		return fmt.Errorf("failed to show notification to any user: %v", lastErr)
+		return results, fmt.Errorf("failed to show notification to any user: %v", lastErr)
 	}
 
-	return nil
+	return results, nil
 }
 
 // showNotificationAsMacUser shows a notification as a specific macOS user
-func showNotificationAsMacUser(user MacGUIUser, title, message string, timeout int, iconPath string, width, height int, buttonText string) error {
-	// Get the path to the current executable
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %v", err)
-	}
-
+func showNotificationAsMacUser(user MacGUIUser, exePath string, n Notification) error {
 	// Build the command to run as the user using launchctl asuser
 	args := []string{
 		"asuser",
 		user.UID,
 		exePath,
-		"-title", title,
-		"-message", message,
-		"-button", buttonText,
-		"-timeout", fmt.Sprintf("%d", timeout),
-		"-width", fmt.Sprintf("%d", width),
-		"-height", fmt.Sprintf("%d", height),
 	}
 
 	// Add icon if specified
-	if iconPath != "" {
+	child := n
+	child.IconPath = ""
+	if IsIconURL(n.IconPath) {
+		child.IconPath = n.IconPath // The child downloads it into the user's cache
+	} else if n.IconPath != "" {
 		// Make sure the icon path is absolute
-		absIconPath := iconPath
-		if !strings.HasPrefix(iconPath, "/") {
+		absIconPath := n.IconPath
+		if !strings.HasPrefix(n.IconPath, "/") {
 			// If just a filename, look in the executable's directory
 			exeDir := exePath[:strings.LastIndex(exePath, "/")]
-			absIconPath = exeDir + "/" + iconPath
+			absIconPath = exeDir + "/" + n.IconPath
 		}
 
 		// Check if the file exists and is readable
 		fileInfo, err := os.Stat(absIconPath)
-		if err != nil && !strings.HasPrefix(iconPath, "/") {
+		if err != nil && !strings.HasPrefix(n.IconPath, "/") {
 			// File not found and it's a relative path - try case-insensitive search
 			exeDir := exePath[:strings.LastIndex(exePath, "/")]
 			entries, readErr := os.ReadDir(exeDir)
 			if readErr == nil {
-				lowerIconPath := strings.ToLower(iconPath)
+				lowerIconPath := strings.ToLower(n.IconPath)
 				for _, entry := range entries {
 					if strings.ToLower(entry.Name()) == lowerIconPath {
 						// Found a case-insensitive match
@@ -140,9 +140,10 @@ func showNotificationAsMacUser(user MacGUIUser, title, message string, timeout i
 				os.Chmod(absIconPath, mode.Perm()|0004)
 				defer os.Chmod(absIconPath, originalPerm)
 			}
-			args = append(args, "-image", absIconPath)
+			child.IconPath = absIconPath
 		}
 	}
+	args = append(args, child.childArgs()...)
 
 	// Execute using launchctl
 	cmd := exec.Command("launchctl", args...)
@@ -164,6 +165,14 @@ func isWindowsGUIAvailable() bool {
 	return false
 }
 
+// ElevationState reports root as elevated and everyone else as a standard user
+func ElevationState() string {
+	if os.Geteuid() == 0 {
+		return elevationElevated
+	}
+	return elevationStandard
+}
+
 // isRunningAsSystem is a stub for non-Windows platforms
 func isRunningAsSystem() bool {
 	return false
@@ -186,19 +195,24 @@ func shouldUseWallBroadcast() bool {
 	return false
 }
 
-// hideConsoleWindow is a stub for non-Windows platforms
-func hideConsoleWindow() {
+// HideConsoleWindow is a stub for non-Windows platforms
+func HideConsoleWindow() {
 	// No-op on macOS (no console window to hide)
 }
 
-// checkLinuxDependencies is a stub for non-Linux platforms
-func checkLinuxDependencies() {
-	// No-op on macOS
+// ReportDependencies is a stub for non-Linux platforms
+func ReportDependencies() bool {
+	return false
 }
 
-// checkLinuxDependenciesQuiet is a stub for non-Linux platforms
-func checkLinuxDependenciesQuiet() {
+// WarnMissingDependencies is a stub for non-Linux platforms
+func WarnMissingDependencies() {
 	// No-op on macOS
 }
 
+// RemoveReconnectTask is a stub for non-Windows platforms
+func RemoveReconnectTask(taskName string) {
+	// No-op: deliver-on-reconnect tasks are Windows only
+}
+
 // "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
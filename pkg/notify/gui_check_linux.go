@@ -1,6 +1,6 @@
 //go:build linux
 
-package main
+package notify
 
 import (
 	"fmt"
@@ -260,6 +260,46 @@ func getDisplayFromPID(pid string) string {
 	return ""
 }
 
+// getUserTimeZone returns the TZ set in a user's graphical session, or "" when the
+// session uses the system zone
+func getUserTimeZone(username string) string {
+	for _, pid := range findUserGraphicalProcesses(username) {
+		data, err := os.ReadFile("/proc/" + pid + "/environ")
+		if err != nil {
+			continue
+		}
+		for _, envVar := range strings.Split(string(data), "\x00") {
+			if strings.HasPrefix(envVar, "TZ=") {
+				return strings.TrimPrefix(envVar, "TZ=")
+			}
+		}
+	}
+	return ""
+}
+
+// getUserLocale returns the language of a user's graphical session (LC_ALL, LC_MESSAGES or
+// LANG), or "" when none is set
+func getUserLocale(username string) string {
+	for _, pid := range findUserGraphicalProcesses(username) {
+		data, err := os.ReadFile("/proc/" + pid + "/environ")
+		if err != nil {
+			continue
+		}
+		env := map[string]string{}
+		for _, envVar := range strings.Split(string(data), "\x00") {
+			if name, value, ok := strings.Cut(envVar, "="); ok {
+				env[name] = value
+			}
+		}
+		for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if env[name] != "" {
+				return env[name]
+			}
+		}
+	}
+	return ""
+}
+
 // shouldShowToOtherUsers determines if we should try to show GUI to other logged-in users
 // This is true when running as root without our own DISPLAY access
 func shouldShowToOtherUsers() bool {
@@ -304,39 +344,41 @@ func shouldUseWallBroadcast() bool {
 
 // showNotificationToUsers shows GUI notifications to all users with active graphical sessions
 // This is used when running as root to notify logged-in GUI users
-func showNotificationToUsers(title, message string, timeout int, iconPath string, width, height int, buttonText string) error {
-	sessions := getGraphicalSessions()
-	if len(sessions) == 0 {
-		return fmt.Errorf("no graphical sessions found")
+// With opts.Targets only their sessions are notified, and a result is returned per target
+func showNotificationToUsers(exePath string, opts Options) ([]TargetResult, error) {
+	graphical := getGraphicalSessions()
+	if len(graphical) == 0 && len(opts.Targets) == 0 {
+		return nil, fmt.Errorf("no graphical sessions found")
+	}
+	sessions := make([]userSession, len(graphical))
+	for i, session := range graphical {
+		sessions[i] = userSession{Username: session.Username, ID: session.SessionID, Name: session.SessionType}
 	}
 
 	var lastErr error
 	successCount := 0
 
-	for _, session := range sessions {
-		err := showNotificationAsUser(session, title, message, timeout, iconPath, width, height, buttonText)
-		if err != nil {
-			lastErr = err
+	deliveries := planDeliveries(opts, sessions)
+	errs := make([]error, len(deliveries))
+	for i, delivery := range deliveries {
+		errs[i] = showNotificationAsUser(graphical[delivery.session], exePath, delivery.opts.Notification)
+		if errs[i] != nil {
+			lastErr = errs[i]
 		} else {
 			successCount++
 		}
 	}
+	results := targetResults(opts.Targets, sessions, deliveries, errs)
 
 	if successCount == 0 && lastErr != nil {
-		return fmt.Errorf("failed to show notification to any user: %v", lastErr)
+		return results, fmt.Errorf("failed to show notification to any user: %v", lastErr)
 	}
 
-	return nil
+	return results, nil
 }
 
 // showNotificationAsUser shows a notification as a specific user with their display
-func showNotificationAsUser(session GraphicalSession, title, message string, timeout int, iconPath string, width, height int, buttonText string) error {
-	// Get the path to the current executable
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %v", err)
-	}
-
+func showNotificationAsUser(session GraphicalSession, exePath string, n Notification) error {
 	// Check and fix directory permissions in the path
 	var restoreDirPerms []func()
 	if os.Geteuid() == 0 {
@@ -367,7 +409,7 @@ func showNotificationAsUser(session GraphicalSession, title, message string, tim
 						capturedDir := exeDir
 						capturedPerm := originalPerm
 						restoreDirPerms = append(restoreDirPerms, func() {
-							time.Sleep(time.Duration(timeout+2) * time.Second)
+							time.Sleep(time.Duration(n.Timeout+2) * time.Second)
 							if err := os.Chmod(capturedDir, capturedPerm); err != nil {
 								log.Printf("Warning: Could not restore directory permissions: %v\n", err)
 							} else {
@@ -401,7 +443,7 @@ func showNotificationAsUser(session GraphicalSession, title, message string, tim
 			} else {
 				// Create a function to restore permissions later
 				restoreExePerms = func() {
-					time.Sleep(time.Duration(timeout+2) * time.Second)
+					time.Sleep(time.Duration(n.Timeout+2) * time.Second)
 					if err := os.Chmod(exePath, originalPerm); err != nil {
 						log.Printf("Warning: Could not restore executable permissions: %v\n", err)
 					} else {
@@ -416,30 +458,32 @@ func showNotificationAsUser(session GraphicalSession, title, message string, tim
 	finalIconPath := ""
 	var restoreIconPerms func()
 
-	if iconPath != "" {
+	if IsIconURL(n.IconPath) {
+		finalIconPath = n.IconPath // The child downloads it into the user's cache
+	} else if n.IconPath != "" {
 		// Make sure the icon path is absolute
-		absIconPath := iconPath
-		if !strings.HasPrefix(iconPath, "/") {
+		absIconPath := n.IconPath
+		if !strings.HasPrefix(n.IconPath, "/") {
 			// If just a filename, look in the executable's directory
 			// This handles cases where the icon is in the same dir as the binary
 			exeDir := exePath[:strings.LastIndex(exePath, "/")]
-			absIconPath = exeDir + "/" + iconPath
-			log.Printf("Converted relative icon path '%s' to '%s'", iconPath, absIconPath)
+			absIconPath = exeDir + "/" + n.IconPath
+			log.Printf("Converted relative icon path '%s' to '%s'", n.IconPath, absIconPath)
 		}
 
 		// Check if the file exists and is readable
 		fileInfo, err := os.Stat(absIconPath)
-		if err != nil && !strings.HasPrefix(iconPath, "/") {
+		if err != nil && !strings.HasPrefix(n.IconPath, "/") {
 			// File not found and it's a relative path - try case-insensitive search
 			exeDir := exePath[:strings.LastIndex(exePath, "/")]
 			entries, readErr := os.ReadDir(exeDir)
 			if readErr == nil {
-				lowerIconPath := strings.ToLower(iconPath)
+				lowerIconPath := strings.ToLower(n.IconPath)
 				for _, entry := range entries {
 					if strings.ToLower(entry.Name()) == lowerIconPath {
 						// Found a case-insensitive match
 						absIconPath = exeDir + "/" + entry.Name()
-						log.Printf("Found case-insensitive match: '%s' -> '%s'", iconPath, entry.Name())
+						log.Printf("Found case-insensitive match: '%s' -> '%s'", n.IconPath, entry.Name())
 						fileInfo, err = os.Stat(absIconPath)
 						break
 					}
@@ -468,7 +512,7 @@ func showNotificationAsUser(session GraphicalSession, title, message string, tim
 					// We'll call this after the notification timeout
 					restoreIconPerms = func() {
 						// Wait for notification to finish displaying (add buffer to timeout)
-						time.Sleep(time.Duration(timeout+2) * time.Second)
+						time.Sleep(time.Duration(n.Timeout+2) * time.Second)
 						if err := os.Chmod(absIconPath, originalPerm); err != nil {
 							fmt.Printf("Warning: Could not restore icon permissions: %v\n", err)
 						} else {
@@ -485,19 +529,10 @@ func showNotificationAsUser(session GraphicalSession, title, message string, tim
 	}
 
 	// Build the command arguments (after the environment vars)
-	cmdArgs := []string{
-		"-title", title,
-		"-message", message,
-		"-button", buttonText,
-		"-timeout", fmt.Sprintf("%d", timeout),
-		"-width", fmt.Sprintf("%d", width),
-		"-height", fmt.Sprintf("%d", height),
-	}
-
-	// Add icon if we have a valid path
-	if finalIconPath != "" {
-		cmdArgs = append(cmdArgs, "-image", finalIconPath)
-	}
+	// Only pass the icon if we have a valid path
+	child := n
+	child.IconPath = finalIconPath
+	cmdArgs := child.childArgs()
 
 	// Build sudo command with proper environment variable handling
 	// Use 'env' to set environment variables for the child process
@@ -513,6 +548,20 @@ func showNotificationAsUser(session GraphicalSession, title, message string, tim
 		args = append(args, "XAUTHORITY="+xauth)
 	}
 
+	// Render {{localtime:...}} in the user's own zone unless -tz was given
+	if n.TimeZone == "" {
+		if tz := getUserTimeZone(session.Username); tz != "" {
+			args = append(args, "TZ="+tz)
+		}
+	}
+
+	// Show the built-in text in the user's own language unless -locale was given
+	if n.Locale == "" {
+		if locale := getUserLocale(session.Username); locale != "" {
+			args = append(args, "LANG="+locale)
+		}
+	}
+
 	// Add the executable path
 	args = append(args, exePath)
 
@@ -525,9 +574,9 @@ func showNotificationAsUser(session GraphicalSession, title, message string, tim
 	// Let stderr pass through so we can see any errors
 	cmd.Stderr = os.Stderr
 
-	err = cmd.Start() // Use Start() instead of Run() to not wait
+	err := cmd.Start() // Use Start() instead of Run() to not wait
 	if err != nil {
-		return fmt.Errorf("failed to run as user %s: %v", session.Username, err)
+		return diagnoseLaunchFailure(fmt.Errorf("failed to run as user %s: %v", session.Username, err))
 	}
 
 	// Restore permissions after the notification timeout (in background)
@@ -541,6 +590,18 @@ func showNotificationAsUser(session GraphicalSession, title, message string, tim
 		go restoreIconPerms()
 	}
 
+	// A launch blocked by SELinux/AppArmor (or sudo policy) exits almost immediately,
+	// while a displayed notification keeps running until it is dismissed
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		if err != nil {
+			return diagnoseLaunchFailure(fmt.Errorf("notification for user %s exited during launch: %v", session.Username, err))
+		}
+	case <-time.After(launchCheckWindow):
+	}
+
 	return nil
 }
 
@@ -582,13 +643,21 @@ func isWindowsGUIAvailable() bool {
 	return false
 }
 
+// ElevationState reports root as elevated and everyone else as a standard user
+func ElevationState() string {
+	if os.Geteuid() == 0 {
+		return elevationElevated
+	}
+	return elevationStandard
+}
+
 // isRunningAsSystem is a stub for non-Windows platforms
 func isRunningAsSystem() bool {
 	return false
 }
 
-// hideConsoleWindow is a stub for non-Windows platforms
-func hideConsoleWindow() {
+// HideConsoleWindow is a stub for non-Windows platforms
+func HideConsoleWindow() {
 	// No-op on Linux (no console window to hide)
 }
 
@@ -858,20 +927,18 @@ func printDependencyReport() {
 	fmt.Println()
 }
 
-// checkLinuxDependencies runs a full dependency check and exits
-func checkLinuxDependencies() {
+// ReportDependencies prints a full dependency and security module report
+// Returns true when all required libraries are installed
+func ReportDependencies() bool {
 	printDependencyReport()
+	printSecurityModuleReport()
 	allOk, _, _ := checkDependencies()
-	if allOk {
-		os.Exit(0)
-	} else {
-		os.Exit(1)
-	}
+	return allOk
 }
 
-// checkLinuxDependenciesQuiet checks dependencies and prints a warning if any are missing
+// WarnMissingDependencies checks dependencies and prints a warning if any are missing
 // This is called during -check-gui to provide helpful feedback
-func checkLinuxDependenciesQuiet() {
+func WarnMissingDependencies() {
 	allOk, missing, distro := checkDependencies()
 	if !allOk {
 		fmt.Println()
@@ -890,4 +957,9 @@ func checkLinuxDependenciesQuiet() {
 	}
 }
 
+// RemoveReconnectTask is a stub for non-Windows platforms
+func RemoveReconnectTask(taskName string) {
+	// No-op: deliver-on-reconnect tasks are Windows only
+}
+
 // "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -1,6 +1,6 @@
 //go:build !linux && !darwin && !windows
 
-package main
+package notify
 
 import "fmt"
 
@@ -29,6 +29,11 @@ func shouldShowToOtherUsers() bool {
 	return false
 }
 
+// ElevationState is a stub for unsupported platforms
+func ElevationState() string {
+	return elevationStandard
+}
+
 // isRunningAsSystem is a stub for unsupported platforms
 func isRunningAsSystem() bool {
 	return false
@@ -40,23 +45,28 @@ func shouldUseWallBroadcast() bool {
 }
 
 // showNotificationToUsers is a stub for unsupported platforms
-func showNotificationToUsers(title, message string, timeout int, iconPath string, width, height int, buttonText string) error {
-	return fmt.Errorf("showNotificationToUsers is not supported on this platform")
+func showNotificationToUsers(exePath string, opts Options) ([]TargetResult, error) {
+	return nil, fmt.Errorf("showNotificationToUsers is not supported on this platform")
 }
 
-// hideConsoleWindow is a stub for non-Windows platforms
-func hideConsoleWindow() {
+// HideConsoleWindow is a stub for non-Windows platforms
+func HideConsoleWindow() {
 	// No-op on unsupported platforms
 }
 
-// checkLinuxDependencies is a stub for non-Linux platforms
-func checkLinuxDependencies() {
-	// No-op on other platforms
+// ReportDependencies is a stub for non-Linux platforms
+func ReportDependencies() bool {
+	return false
 }
 
-// checkLinuxDependenciesQuiet is a stub for non-Linux platforms
-func checkLinuxDependenciesQuiet() {
+// WarnMissingDependencies is a stub for non-Linux platforms
+func WarnMissingDependencies() {
 	// No-op on other platforms
 }
 
+// RemoveReconnectTask is a stub for non-Windows platforms
+func RemoveReconnectTask(taskName string) {
+	// No-op: deliver-on-reconnect tasks are Windows only
+}
+
 // "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
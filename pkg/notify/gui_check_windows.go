@@ -1,6 +1,6 @@
 //go:build windows
 
-package main
+package notify
 
 import (
 	"fmt"
@@ -8,7 +8,9 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"syscall"
+	"unsafe"
 )
 
 var (
@@ -16,6 +18,18 @@ var (
 	getProcessWindowStation = user32.NewProc("GetProcessWindowStation")
 )
 
+// Token information classes and elevation types used by ElevationState
+const (
+	tokenElevationTypeClass = 18 // TokenElevationType
+	tokenElevationClass     = 20 // TokenElevation
+
+	tokenElevationTypeDefault = 1 // UAC disabled, built-in Administrator, or standard user
+	tokenElevationTypeFull    = 2 // Elevated administrator token
+	tokenElevationTypeLimited = 3 // Filtered administrator token (not elevated)
+
+	localSystemSID = "S-1-5-18"
+)
+
 // isWindowsGUIAvailable checks if GUI mode is available on Windows
 // It checks if the process has access to a window station
 func isWindowsGUIAvailable() bool {
@@ -25,8 +39,16 @@ func isWindowsGUIAvailable() bool {
 
 // WindowsGUIUser represents a logged-in GUI user on Windows
 type WindowsGUIUser struct {
-	Username  string
-	SessionID string
+	Username    string
+	SessionName string // "console" or "rdp-tcp#N"; empty for disconnected sessions
+	SessionID   string
+	State       string // "Active" or "Disc" (disconnected RDP/console session)
+}
+
+// isDisconnected reports whether the user's session is disconnected
+// (e.g. an RDP session that was closed without logging off)
+func (u WindowsGUIUser) isDisconnected() bool {
+	return strings.EqualFold(u.State, "Disc")
 }
 
 // getWindowsGUIUsers returns all users with active GUI sessions
@@ -66,13 +88,22 @@ func getWindowsGUIUsers() []WindowsGUIUser {
 
 		// Session ID is typically field 2 (after username and session name)
 		// But if session name is missing (e.g., console), it shifts
+		// The state column follows the session ID
 		sessionID := ""
+		sessionName := ""
+		state := ""
 		if len(fields) >= 2 {
 			// Try to find the numeric session ID
-			for _, field := range fields[1:] {
+			for i, field := range fields[1:] {
 				// Session IDs are typically numeric
 				if field != "" && (field[0] >= '0' && field[0] <= '9') {
 					sessionID = field
+					if i+2 < len(fields) {
+						state = fields[i+2]
+					}
+					if i == 1 {
+						sessionName = fields[1]
+					}
 					break
 				}
 			}
@@ -80,8 +111,10 @@ func getWindowsGUIUsers() []WindowsGUIUser {
 
 		if sessionID != "" {
 			users = append(users, WindowsGUIUser{
-				Username:  username,
-				SessionID: sessionID,
+				Username:    username,
+				SessionName: sessionName,
+				SessionID:   sessionID,
+				State:       state,
 			})
 		}
 	}
@@ -90,17 +123,52 @@ func getWindowsGUIUsers() []WindowsGUIUser {
 }
 
 // showNotificationToUsers shows notifications to all GUI users on Windows
-func showNotificationToUsers(title, message string, timeout int, iconPath string, width, height int, buttonText string) error {
+// Disconnected sessions are handled according to opts.Disconnected, and in
+// Safe Mode (where Task Scheduler and PsExec are unavailable) a plain session
+// message is sent instead of launching exePath in each session
+// With opts.Targets only their sessions are notified, and a result is returned per target
+func showNotificationToUsers(exePath string, opts Options) ([]TargetResult, error) {
+	disconnected := opts.Disconnected
 	users := getWindowsGUIUsers()
-	if len(users) == 0 {
-		return fmt.Errorf("no GUI users found")
+	if len(users) == 0 && len(opts.Targets) == 0 {
+		return nil, fmt.Errorf("no GUI users found")
+	}
+	sessions := make([]userSession, len(users))
+	for i, user := range users {
+		sessions[i] = userSession{Username: user.Username, ID: user.SessionID, Name: user.SessionName, Disconnected: user.isDisconnected()}
+	}
+
+	safeMode := isSafeMode()
+	if safeMode {
+		log.Println("Windows is running in Safe Mode, using session messages")
 	}
 
 	var lastErr error
 	successCount := 0
-
-	for _, user := range users {
-		err := showNotificationAsWindowsUser(user, title, message, timeout, iconPath, width, height, buttonText)
+	var queued []int
+
+	deliveries := planDeliveries(opts, sessions)
+	errs := make([]error, len(deliveries))
+	for i, delivery := range deliveries {
+		user := users[delivery.session]
+		var err error
+		switch {
+		case user.isDisconnected() && disconnected == DisconnectedSkip:
+			log.Printf("Skipping disconnected session %s for user %s", user.SessionID, user.Username)
+			errs[i] = errSessionSkipped
+			continue
+		case user.isDisconnected() && disconnected == DisconnectedQueue:
+			log.Printf("Queueing notification until session %s for user %s is reconnected", user.SessionID, user.Username)
+			queued = append(queued, i)
+			continue
+		case user.isDisconnected() && !safeMode:
+			err = scheduleDeliveryOnReconnect(user, exePath, delivery.opts)
+		case safeMode:
+			err = sendSessionMessage(user.SessionID, delivery.opts.Notification)
+		default:
+			err = showNotificationAsWindowsUser(user, exePath, delivery.opts)
+		}
+		errs[i] = err
 		if err != nil {
 			lastErr = err
 		} else {
@@ -108,76 +176,52 @@ func showNotificationToUsers(title, message string, timeout int, iconPath string
 		}
 	}
 
+	// Wait for queued sessions in parallel so one user does not hold up another
+	if len(queued) > 0 {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, i := range queued {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				delivery := deliveries[i]
+				user := users[delivery.session]
+				active, ok := waitForActiveSession(user, disconnectedQueueMaxWait)
+				var err error
+				switch {
+				case !ok:
+					err = fmt.Errorf("session %s for user %s was not reconnected within %v", user.SessionID, user.Username, disconnectedQueueMaxWait)
+				case safeMode:
+					err = sendSessionMessage(active.SessionID, delivery.opts.Notification)
+				default:
+					err = showNotificationAsWindowsUser(active, exePath, delivery.opts)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				errs[i] = err
+				if err != nil {
+					log.Printf("Queued notification for user %s failed: %v", user.Username, err)
+					lastErr = err
+				} else {
+					successCount++
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+	results := targetResults(opts.Targets, sessions, deliveries, errs)
+
 	if successCount == 0 && lastErr != nil {
-		return fmt.Errorf("failed to show notification to any user: %v", lastErr)
+		return results, fmt.Errorf("failed to show notification to any user: %v", lastErr)
 	}
 
-	return nil
+	return results, nil
 }
 
 // showNotificationAsWindowsUser shows a notification to a specific Windows user
-func showNotificationAsWindowsUser(user WindowsGUIUser, title, message string, timeout int, iconPath string, width, height int, buttonText string) error {
-	// Get the path to the current executable
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %v", err)
-	}
-
-	// Build the command arguments
-	// Let the child process auto-detect the best GUI mode, or pass through forced mode flags
-	// (it will run as the target user, so Fyne/WebView should work)
-	args := []string{}
-
-	// CRITICAL: Add -target-user flag to prevent infinite loop
-	args = append(args, "-target-user")
-	log.Println("Adding -target-user flag to prevent re-elevation")
-
-	// Pass through mode flags and feature flags if they were specified
-	// Check os.Args to see what the parent was called with
-	passedFlags := []string{}
-	for _, arg := range os.Args {
-		// Pass through mode flags, autosize flag, and debug flag
-		if arg == "-win-webview" || arg == "-win-basic" || arg == "-autosize" || arg == "-debug" {
-			args = append(args, arg)
-			passedFlags = append(passedFlags, arg)
-		}
-	}
-	if len(passedFlags) > 0 {
-		log.Printf("Passing flags to child process: %v", passedFlags)
-	} else {
-		log.Printf("No special flags detected in os.Args: %v", os.Args)
-	}
-
-	// Add notification parameters
-	args = append(args, "-title", title)
-	args = append(args, "-message", message)
-	args = append(args, "-button", buttonText)
-	args = append(args, "-timeout", fmt.Sprintf("%d", timeout))
-	args = append(args, "-width", fmt.Sprintf("%d", width))
-	args = append(args, "-height", fmt.Sprintf("%d", height))
-
-	// Add icon if specified
-	if iconPath != "" {
-		// Ensure absolute path for Windows
-		absIconPath := iconPath
-		if !strings.Contains(iconPath, ":") && !strings.HasPrefix(iconPath, "\\\\") {
-			// Use executable directory as base, not working directory
-			// This ensures the icon path is correct when launched as another user
-			exeDir := exePath
-			if lastSlash := strings.LastIndex(exeDir, "\\"); lastSlash > 0 {
-				exeDir = exeDir[:lastSlash]
-			}
-			absIconPath = exeDir + "\\" + iconPath
-		}
-
-		// Verify file exists before passing it
-		if _, err := os.Stat(absIconPath); err == nil {
-			args = append(args, "-image", absIconPath)
-			log.Printf("Including icon in child process args: %s", absIconPath)
-		} else {
-			log.Printf("Icon file not found, skipping: %s (error: %v)", absIconPath, err)
-		}
-	}
+func showNotificationAsWindowsUser(user WindowsGUIUser, exePath string, opts Options) error {
+	n := opts.Notification
+	args := buildWindowsChildArgs(exePath, opts)
 
 	// Build command string for PsExec or PowerShell
 	cmdStr := fmt.Sprintf("\"%s\"", exePath)
@@ -224,7 +268,7 @@ func showNotificationAsWindowsUser(user WindowsGUIUser, title, message string, t
 	}
 
 	// Fallback: Use PowerShell with task scheduler
-	taskName := fmt.Sprintf("KrankyBearNotify_%s_%d", user.Username, timeout)
+	taskName := fmt.Sprintf("KrankyBearNotify_%s_%d", user.Username, n.Timeout)
 
 	// Build argument string with proper PowerShell escaping
 	// We need to build a single string that will be passed to -Argument parameter
@@ -351,6 +395,59 @@ try {
 	return nil
 }
 
+// buildWindowsChildArgs returns the arguments for a child exePath process that shows
+// the notification in another user's session
+func buildWindowsChildArgs(exePath string, opts Options) []string {
+	n := opts.Notification
+
+	// Build the command arguments
+	// Let the child process auto-detect the best GUI mode, or pass through forced mode flags
+	// (it will run as the target user, so Fyne/WebView should work)
+	args := []string{}
+
+	// CRITICAL: Add -target-user flag to prevent infinite loop
+	args = append(args, "-target-user")
+	log.Println("Adding -target-user flag to prevent re-elevation")
+
+	// Pass through the delivery mode, autosize and debug
+	if modeArgs := opts.modeArgs(); len(modeArgs) > 0 {
+		args = append(args, modeArgs...)
+		log.Printf("Passing flags to child process: %v", modeArgs)
+	}
+
+	// Add icon if specified
+	child := n
+	child.IconPath = ""
+	if IsIconURL(n.IconPath) {
+		child.IconPath = n.IconPath // The child downloads it into the user's cache
+	} else if n.IconPath != "" {
+		// Ensure absolute path for Windows
+		absIconPath := n.IconPath
+		if !strings.Contains(n.IconPath, ":") && !strings.HasPrefix(n.IconPath, "\\\\") {
+			// Use executable directory as base, not working directory
+			// This ensures the icon path is correct when launched as another user
+			exeDir := exePath
+			if lastSlash := strings.LastIndex(exeDir, "\\"); lastSlash > 0 {
+				exeDir = exeDir[:lastSlash]
+			}
+			absIconPath = exeDir + "\\" + n.IconPath
+		}
+
+		// Verify file exists before passing it
+		if _, err := os.Stat(absIconPath); err == nil {
+			child.IconPath = absIconPath
+			log.Printf("Including icon in child process args: %s", absIconPath)
+		} else {
+			log.Printf("Icon file not found, skipping: %s (error: %v)", absIconPath, err)
+		}
+	}
+
+	// Add notification parameters
+	args = append(args, child.childArgs()...)
+
+	return args
+}
+
 // isLinuxGUIAvailable is a stub for non-Linux platforms
 func isLinuxGUIAvailable() bool {
 	return false
@@ -366,6 +463,50 @@ func isGraphicalTargetActive() bool {
 	return false
 }
 
+// ElevationState inspects the process token to determine whether we are
+// SYSTEM, an elevated administrator, an administrator with a filtered UAC token,
+// or a standard user
+func ElevationState() string {
+	token, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		log.Printf("Elevation check: could not open process token: %v", err)
+		return elevationStandard
+	}
+	defer token.Close()
+
+	if tokenUser, err := token.GetTokenUser(); err == nil {
+		if sid, err := tokenUser.User.Sid.String(); err == nil && sid == localSystemSID {
+			return elevationSystem
+		}
+	}
+
+	var elevationType uint32
+	var returnedLen uint32
+	err = syscall.GetTokenInformation(token, tokenElevationTypeClass,
+		(*byte)(unsafe.Pointer(&elevationType)), uint32(unsafe.Sizeof(elevationType)), &returnedLen)
+	if err != nil {
+		log.Printf("Elevation check: could not query TokenElevationType: %v", err)
+		return elevationStandard
+	}
+
+	switch elevationType {
+	case tokenElevationTypeFull:
+		return elevationElevated
+	case tokenElevationTypeLimited:
+		return elevationFiltered
+	}
+
+	// TokenElevationTypeDefault: no split token, so check whether the token itself is elevated
+	// (UAC disabled or the built-in Administrator account)
+	var isElevated uint32
+	err = syscall.GetTokenInformation(token, tokenElevationClass,
+		(*byte)(unsafe.Pointer(&isElevated)), uint32(unsafe.Sizeof(isElevated)), &returnedLen)
+	if err == nil && isElevated != 0 {
+		return elevationElevated
+	}
+	return elevationStandard
+}
+
 // isRunningAsSystem checks if we're running as SYSTEM account on Windows
 func isRunningAsSystem() bool {
 	cmd := exec.Command("whoami")
@@ -396,17 +537,11 @@ func shouldShowToOtherUsers() bool {
 		}
 	}
 
-	// Check if running as SYSTEM
-	if isRunningAsSystem() {
-		return true
-	}
-
-	// Check if we're running elevated (as Administrator)
-	// Try to open a privileged registry key
-	cmd := exec.Command("net", "session")
-	err := cmd.Run()
-	// If this succeeds, we're running elevated
-	return err == nil
+	// SYSTEM and elevated administrators can launch notifications into other sessions
+	// A filtered (non-elevated) administrator token cannot, so treat it like a standard user
+	state := ElevationState()
+	log.Printf("Elevation state: %s", state)
+	return state == elevationSystem || state == elevationElevated
 }
 
 // shouldUseWallBroadcast is a stub for non-Linux platforms
@@ -414,9 +549,9 @@ func shouldUseWallBroadcast() bool {
 	return false
 }
 
-// hideConsoleWindow hides the console window using Windows API
+// HideConsoleWindow hides the console window using Windows API
 // This is called when running as target user to prevent console from showing
-func hideConsoleWindow() {
+func HideConsoleWindow() {
 	// Get handle to kernel32.dll
 	kernel32 := syscall.NewLazyDLL("kernel32.dll")
 	getConsoleWindow := kernel32.NewProc("GetConsoleWindow")
@@ -439,13 +574,13 @@ func hideConsoleWindow() {
 	log.Println("Console window hidden via Windows API")
 }
 
-// checkLinuxDependencies is a stub for non-Linux platforms
-func checkLinuxDependencies() {
-	// No-op on Windows
+// ReportDependencies is a stub for non-Linux platforms
+func ReportDependencies() bool {
+	return false
 }
 
-// checkLinuxDependenciesQuiet is a stub for non-Linux platforms
-func checkLinuxDependenciesQuiet() {
+// WarnMissingDependencies is a stub for non-Linux platforms
+func WarnMissingDependencies() {
 	// No-op on Windows
 }
 
@@ -1,15 +1,15 @@
 //go:build !windows
 
-package main
+package notify
 
-// isOpenGLAvailable always returns true on non-Windows platforms
+// IsOpenGLAvailable always returns true on non-Windows platforms
 // (macOS and Linux handle OpenGL differently and Fyne works well on them)
-func isOpenGLAvailable() bool {
+func IsOpenGLAvailable() bool {
 	return true
 }
 
 // showWindowsMessageBox is not available on non-Windows platforms
-func showWindowsMessageBox(title, message string, timeout int) error {
+func showWindowsMessageBox(n Notification) error {
 	return nil
 }
 
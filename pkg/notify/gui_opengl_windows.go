@@ -1,6 +1,6 @@
 //go:build windows
 
-package main
+package notify
 
 import (
 	"log"
@@ -63,9 +63,15 @@ var (
 	wglMakeCurrent    = opengl32Dll.NewProc("wglMakeCurrent")
 )
 
-// isOpenGLAvailable checks if OpenGL is actually functional on Windows
+// IsOpenGLAvailable checks if OpenGL is actually functional on Windows
 // This is more robust than just checking if the DLL exists
-func isOpenGLAvailable() bool {
+func IsOpenGLAvailable() bool {
+	// Under x64 emulation on ARM the OpenGL found below is a compatibility layer Fyne fails with
+	if e := DetectEmulation(); emulatedOpenGLUnreliable(e) {
+		log.Printf("OpenGL check: running as %s, not using OpenGL", e)
+		return false
+	}
+
 	// First, basic check: can we load opengl32.dll?
 	if err := opengl32Dll.Load(); err != nil {
 		log.Printf("OpenGL check: opengl32.dll not found: %v", err)
@@ -137,12 +143,12 @@ func isOpenGLAvailable() bool {
 }
 
 // showWindowsMessageBox shows a native Windows MessageBox as fallback
-func showWindowsMessageBox(title, message string, timeout int) error {
+func showWindowsMessageBox(n Notification) error {
 	// Get MessageBoxW from user32.dll (user32 is declared in gui_check_windows.go)
 	messageBox := user32.NewProc("MessageBoxW")
 
-	titlePtr, _ := syscall.UTF16PtrFromString(title)
-	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	titlePtr, _ := syscall.UTF16PtrFromString(n.Title)
+	messagePtr, _ := syscall.UTF16PtrFromString(n.plainMessage())
 
 	// MB_OK | MB_ICONINFORMATION | MB_TOPMOST
 	const MB_OK = 0x00000000
@@ -150,11 +156,27 @@ func showWindowsMessageBox(title, message string, timeout int) error {
 	const MB_TOPMOST = 0x00040000
 
 	flags := MB_OK | MB_ICONINFORMATION | MB_TOPMOST
+	switch n.Urgency {
+	case UrgencyWarning:
+		const MB_ICONWARNING = 0x00000030
+		flags = MB_OK | MB_ICONWARNING | MB_TOPMOST
+	case UrgencyCritical:
+		const MB_ICONERROR = 0x00000010
+		flags = MB_OK | MB_ICONERROR | MB_TOPMOST
+	}
+
+	// Break glass: stop icon, system modal and brought to the foreground
+	if n.Priority == PriorityBreakGlass {
+		const MB_ICONHAND = 0x00000010
+		const MB_SYSTEMMODAL = 0x00001000
+		const MB_SETFOREGROUND = 0x00010000
+		flags = MB_OK | MB_ICONHAND | MB_SYSTEMMODAL | MB_SETFOREGROUND | MB_TOPMOST
+	}
 
-	if timeout > 0 {
+	if n.Timeout > 0 {
 		// For timeout, we'd need to use a timer and close the window
 		// For simplicity, we'll just show the message
-		messageWithTimeout, _ := syscall.UTF16PtrFromString(message + "\n\n(Auto-close not supported in fallback mode)")
+		messageWithTimeout, _ := syscall.UTF16PtrFromString(n.plainMessage() + "\n\n(Auto-close not supported in fallback mode)")
 		messageBox.Call(
 			0,
 			uintptr(unsafe.Pointer(messageWithTimeout)),
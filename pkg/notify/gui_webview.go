@@ -0,0 +1,721 @@
+//go:build webview || windows
+// +build webview windows
+
+package notify
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webviewWindow is the window showWebViewNotification shows its page in: the webview library
+// in -tags webview builds, otherwise WebView2 loaded at run time on Windows
+type webviewWindow interface {
+	SetTitle(title string)
+	SetSize(width, height int)
+	Bind(name string, f interface{}) error
+	SetHtml(html string)
+	Eval(js string)
+	Dispatch(f func())
+	Terminate()
+	Run()
+	Destroy()
+}
+
+// showWebViewNotification shows a notification using HTML/CSS/JavaScript in a webview
+// This is a fallback when OpenGL is not available but webview is
+// Returns the action taken (acknowledged or timeout) and what the user entered (n.Input, n.Choices) when the button was clicked
+// slot is the window slot the window is stacked in (see claimWindowSlot), -1 for none
+func showWebViewNotification(n Notification, slot int) (string, response, error) {
+	// On Windows, set a custom user data folder to avoid permission issues
+	// when running as SYSTEM (e.g., via scheduled tasks)
+	// WebView2 needs a writable location for its cache/data
+	if os.Getenv("WEBVIEW2_USER_DATA_FOLDER") == "" {
+		// Use the directory where notify.exe is located
+		exePath, err := os.Executable()
+		if err == nil {
+			// Get directory path (remove the executable filename)
+			exeDir := exePath[:len(exePath)-len("\\notify.exe")]
+			webviewDataDir := exeDir + "\\webview-data"
+			os.Setenv("WEBVIEW2_USER_DATA_FOLDER", webviewDataDir)
+			log.Printf("WebView: Set custom user data folder to %s", webviewDataDir)
+
+			// Try to create the directory if it doesn't exist
+			if err := os.MkdirAll(webviewDataDir, 0755); err != nil {
+				log.Printf("WebView: Warning - could not create data directory: %v", err)
+			}
+		} else {
+			log.Printf("WebView: Warning - could not determine executable path: %v", err)
+		}
+	}
+
+	// Create webview
+	w, err := newWebViewWindow()
+	if err != nil {
+		return "", response{}, err
+	}
+	defer w.Destroy()
+
+	w.SetTitle(n.Title)
+	w.SetSize(n.Width, n.Height)
+
+	// Load and encode the icon as base64 if provided; -state shows the icon of the state
+	iconHTML := `<span class="icon">` + urgencyEmoji(n.Urgency) + `</span>`
+	if n.IconPath != "" {
+		if icon, err := webviewIconHTML(n.IconPath); err == nil {
+			iconHTML = icon
+			log.Printf("WebView: Successfully loaded and encoded icon")
+		} else {
+			log.Printf("Warning: Not showing the icon: %v", err)
+		}
+	}
+	stateIcons, _ := ParseStateIcons(n.StateIcons) // Checked by the CLI
+	stateIconHTML := func(state string) string {
+		if path := stateIcons[state]; path != "" {
+			icon, err := webviewIconHTML(path)
+			if err == nil {
+				return icon
+			}
+			log.Printf("Warning: Not showing the icon of state %s: %v", state, err)
+		}
+		return `<span class="icon">` + stateEmoji(state) + `</span>`
+	}
+	if n.hasState() {
+		iconHTML = stateIconHTML(n.initialState())
+	}
+	iconHTML = `<span id="state-icon">` + iconHTML + `</span>`
+
+	// Text field for -input, submitted with the button or Enter
+	inputHTML := ""
+	if n.Input {
+		inputHTML = fmt.Sprintf(`<input class="input" id="input" type="text" value="%s" placeholder="%s" autofocus onkeydown="if (event.key === 'Enter') closeWindow()">`,
+			template.HTMLEscapeString(n.InputDefault), template.HTMLEscapeString(n.InputPlaceholder))
+	}
+
+	// Built-in text in the notification's language; the script formats the %d and %s itself
+	ui := n.ui()
+	uiJSON, _ := json.Marshal(map[string]string{
+		"countdown":  ui.text("countdown"),
+		"closing":    ui.text("closing"),
+		"copied":     ui.text("copied"),
+		"otpExpires": ui.text("otp.expires"),
+		"otpExpired": ui.text("otp.expired"),
+	})
+
+	// Drop-down for -choices; the button is enabled once an option is chosen
+	choiceHTML := ""
+	buttonDisabled := ""
+	if choices := n.ChoiceList(); len(choices) > 0 {
+		options := fmt.Sprintf(`<option value="" disabled selected>%s</option>`, template.HTMLEscapeString(ui.text("choose")))
+		for _, choice := range choices {
+			options += fmt.Sprintf(`<option>%s</option>`, template.HTMLEscapeString(choice))
+		}
+		choiceHTML = fmt.Sprintf(`<select class="input" id="choice" onchange="document.getElementById('ok').disabled = false">%s</select>`, options)
+		buttonDisabled = " disabled"
+	}
+
+	// Message body: the text, or the -html fragment (sanitized unless -allow-unsafe-html)
+	// -redact-after-ack: closeWindow empties the elements of the redact class first
+	redactClass := ""
+	if n.RedactAfterAck {
+		redactClass = " redact"
+	}
+	messageHTML := fmt.Sprintf(`<div class="message%s">%s</div>`, redactClass, linkifyHTML(n.Message))
+	if n.HTML != "" {
+		body := n.HTML
+		if !n.AllowUnsafeHTML {
+			body = sanitizeHTML(body)
+		}
+		messageHTML = fmt.Sprintf(`<div class="message rich%s">%s</div>`, redactClass, body)
+	}
+	if n.Link != "" {
+		messageHTML += fmt.Sprintf(`<div class="link%s"><a href="%s">%s</a></div>`, redactClass, template.HTMLEscapeString(n.Link), template.HTMLEscapeString(n.Link))
+	}
+
+	// Digest: the notifications it stands for, in a collapsed list
+	if items := n.DigestList(); len(items) > 0 {
+		list := ""
+		for _, item := range items {
+			list += "<li>" + template.HTMLEscapeString(item) + "</li>"
+		}
+		messageHTML += fmt.Sprintf(`<details class="digest%s"><summary>%s</summary><ul>%s</ul></details>`, redactClass, template.HTMLEscapeString(ui.text("digest.list", len(items))), list)
+	}
+
+	// -otp: the script copies data-code and counts down data-expiry seconds
+	if n.OTP != "" {
+		messageHTML += fmt.Sprintf(`<div class="otp%s" id="otp" data-code="%s" data-expiry="%d"><div><span class="otp-code" id="otp-code">%s</span>`+
+			`<button class="otp-copy" id="otp-copy" onclick="copyOTP()">%s</button></div><div class="otp-expiry" id="otp-expiry"></div></div>`,
+			redactClass, template.HTMLEscapeString(n.OTP), int(n.OTPExpiry.Round(time.Second)/time.Second), template.HTMLEscapeString(formatOTP(n.OTP)), template.HTMLEscapeString(ui.text("copy")))
+	}
+
+	// Build HTML content with embedded CSS and JavaScript
+	html := fmt.Sprintf(`
+<!DOCTYPE html>
+<html dir="%s">
+<head>
+    <meta charset="UTF-8">
+    <style>
+        * {
+            margin: 0;
+            padding: 0;
+            box-sizing: border-box;
+        }
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Arial, sans-serif;
+            background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%);
+            display: flex;
+            justify-content: center;
+            align-items: center;
+            height: 100vh;
+            padding: 20px;
+        }
+        .notification-card {
+            background: white;
+            border-radius: 12px;
+            box-shadow: 0 10px 40px rgba(0,0,0,0.2);
+            padding: 30px;
+            max-width: 450px;
+            width: 100%%;
+            animation: slideIn 0.3s ease-out;
+        }
+        @keyframes slideIn {
+            from {
+                transform: translateY(-20px);
+                opacity: 0;
+            }
+            to {
+                transform: translateY(0);
+                opacity: 1;
+            }
+        }
+        .title {
+            font-size: 24px;
+            font-weight: bold;
+            color: #333;
+            margin-bottom: 15px;
+            display: flex;
+            align-items: center;
+        }
+        .icon {
+            width: 32px;
+            height: 32px;
+            margin-inline-end: 12px;
+            font-size: 32px;
+        }
+        .icon-img {
+            width: 48px;
+            height: 48px;
+            margin-inline-end: 12px;
+            object-fit: contain;
+        }
+        .spin {
+            display: inline-block;
+            animation: spin 2s linear infinite;
+        }
+        @keyframes spin {
+            to { transform: rotate(360deg); }
+        }
+        .message {
+            font-size: 16px;
+            color: #666;
+            line-height: 1.6;
+            margin-bottom: 20px;
+            white-space: pre-wrap;
+        }
+        .message.rich {
+            white-space: normal;
+            max-height: 60vh;
+            overflow-y: auto;
+        }
+        .message.rich h1, .message.rich h2, .message.rich h3, .message.rich p, .message.rich ul, .message.rich ol, .message.rich table {
+            margin-bottom: 10px;
+        }
+        .message.rich ul, .message.rich ol {
+            padding-inline-start: 24px;
+        }
+        .message.rich img {
+            max-width: 100%%;
+        }
+        .message.rich td, .message.rich th {
+            padding: 2px 8px;
+            text-align: start;
+        }
+        .message a, .link a {
+            color: #667eea;
+        }
+        .link {
+            margin: -10px 0 20px;
+            font-size: 14px;
+            overflow-wrap: anywhere;
+        }
+        .digest {
+            margin: -10px 0 20px;
+            font-size: 14px;
+            text-align: start;
+        }
+        .digest ul {
+            max-height: 150px;
+            overflow-y: auto;
+        }
+        .otp {
+            text-align: center;
+            margin-bottom: 20px;
+        }
+        .otp-code {
+            font-family: Consolas, Menlo, monospace;
+            font-size: 32px;
+            font-weight: bold;
+            color: #333;
+            letter-spacing: 2px;
+            vertical-align: middle;
+        }
+        .otp-copy {
+            margin-inline-start: 12px;
+            padding: 4px 12px;
+            font-size: 14px;
+            border: 1px solid #667eea;
+            border-radius: 6px;
+            background: white;
+            color: #667eea;
+            cursor: pointer;
+            vertical-align: middle;
+        }
+        .otp-copy:disabled {
+            opacity: 0.5;
+            cursor: default;
+        }
+        .otp-expiry {
+            color: #999;
+            font-size: 13px;
+            margin-top: 6px;
+        }
+        .input {
+            width: 100%%;
+            padding: 8px 10px;
+            font-size: 16px;
+            border: 1px solid #ccc;
+            border-radius: 6px;
+            margin-bottom: 20px;
+        }
+        .ok-button:disabled {
+            opacity: 0.5;
+            cursor: default;
+        }
+        .button-container {
+            display: flex;
+            justify-content: flex-end;
+        }
+        .ok-button {
+            background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%);
+            color: white;
+            border: none;
+            padding: 10px 30px;
+            border-radius: 6px;
+            font-size: 16px;
+            cursor: pointer;
+            transition: transform 0.2s, box-shadow 0.2s;
+        }
+        .ok-button:hover {
+            transform: translateY(-2px);
+            box-shadow: 0 5px 15px rgba(102, 126, 234, 0.4);
+        }
+        .ok-button:active {
+            transform: translateY(0);
+        }
+        .timer {
+            text-align: end;
+            color: #999;
+            font-size: 12px;
+            margin-top: 10px;
+        }
+        %s
+    </style>
+</head>
+<body>
+    <div class="notification-card">
+        <div class="title">
+            %s
+            <span id="title-text">%s</span>
+        </div>
+        %s
+        %s
+        %s
+        <div class="button-container">
+            <button class="ok-button" id="ok" onclick="closeWindow()"%s>%s</button>
+        </div>
+        <div class="timer" id="timer"></div>
+    </div>
+    <script>
+        let timeLeft = %d;
+        const ui = %s;
+        
+        function closeWindow() {
+            // Call the Go closeApp function, or submit with the -input text and -choices option
+            const input = document.getElementById('input');
+            const choice = document.getElementById('choice');
+            if (document.getElementById('ok').disabled) {
+                return;
+            }
+            document.querySelectorAll('.redact').forEach(function(element) {
+                element.textContent = '';
+            });
+            if (input || choice) {
+                submit(input ? input.value : '', choice ? choice.value : '');
+            } else {
+                closeApp();
+            }
+        }
+        
+        function updateTimer() {
+            if (timeLeft > 0) {
+                document.getElementById('timer').textContent = ui.countdown.replace('%%d', timeLeft);
+                timeLeft--;
+                setTimeout(updateTimer, 1000);
+            } else if (timeLeft === 0) {
+                document.getElementById('timer').textContent = ui.closing;
+                timeoutApp();
+            }
+        }
+        
+        if (timeLeft > 0) {
+            updateTimer();
+        }
+
+        // -otp: copy the code, count down to its expiry, then remove it (and from the clipboard if copied)
+        const otp = document.getElementById('otp');
+        let otpCopied = false;
+        function copyOTP() {
+            const code = otp.dataset.code;
+            const copied = function() {
+                otpCopied = true;
+                document.getElementById('otp-copy').textContent = ui.copied;
+            };
+            if (navigator.clipboard) {
+                navigator.clipboard.writeText(code).then(copied, function() { copyOTPFallback(code) && copied(); });
+            } else if (copyOTPFallback(code)) {
+                copied();
+            }
+        }
+        function copyOTPFallback(code) {
+            const area = document.createElement('textarea');
+            area.value = code;
+            document.body.appendChild(area);
+            area.select();
+            const ok = document.execCommand('copy');
+            document.body.removeChild(area);
+            return ok;
+        }
+        if (otp && Number(otp.dataset.expiry) > 0) {
+            const expires = Date.now() + Number(otp.dataset.expiry) * 1000;
+            const updateOTP = function() {
+                const left = Math.round((expires - Date.now()) / 1000);
+                const expiry = document.getElementById('otp-expiry');
+                if (left > 0) {
+                    const pad = function(n) { return String(n).padStart(2, '0'); };
+                    const hours = Math.floor(left / 3600);
+                    const minutes = Math.floor(left / 60) %% 60;
+                    expiry.textContent = ui.otpExpires.replace('%%s', (hours > 0 ? hours + ':' + pad(minutes) : minutes) + ':' + pad(left %% 60));
+                    setTimeout(updateOTP, 1000);
+                    return;
+                }
+                expiry.textContent = ui.otpExpired;
+                document.getElementById('otp-code').textContent = '------';
+                document.getElementById('otp-copy').disabled = true;
+                otp.dataset.code = '';
+                if (otpCopied && navigator.clipboard) {
+                    navigator.clipboard.writeText('').catch(function() {});
+                }
+            };
+            updateOTP();
+        }
+
+        // -state-id: the icon, and the message when the update has one
+        function setState(icon, message) {
+            document.getElementById('state-icon').innerHTML = icon;
+            const text = document.querySelector('.message');
+            if (message && text) {
+                text.classList.remove('rich');
+                text.textContent = message;
+            }
+        }
+
+        // -id: the title and message of a later notification with -replace and the same ID
+        function setContent(title, message) {
+            document.getElementById('title-text').textContent = title;
+            const text = document.querySelector('.message');
+            if (text) {
+                text.classList.remove('rich');
+                text.textContent = message;
+            }
+        }
+
+        // Links open in the browser, the window keeps the notification
+        document.addEventListener('click', function(event) {
+            const link = event.target.closest('a[href]');
+            if (link) {
+                event.preventDefault();
+                openLink(link.href);
+            }
+        });
+    </script>
+</body>
+</html>
+`, n.htmlDir(), strings.Join([]string{themeCSS(n), colorCSS(n), fontCSS(n), urgencyCSS(n.Urgency), fullScreenCSS(n), countdownCSS(n)}, "\n        "), iconHTML, template.HTMLEscapeString(n.Title), messageHTML, inputHTML, choiceHTML, buttonDisabled, n.ButtonText, n.Timeout, uiJSON)
+
+	// Record the first action taken - the button click and the timeout can race
+	var actionMu sync.Mutex
+	action := ""
+	setAction := func(a string) {
+		actionMu.Lock()
+		defer actionMu.Unlock()
+		if action == "" {
+			action = a
+		}
+	}
+
+	// Bind the close functions BEFORE setting HTML and running
+	w.Bind("closeApp", func() {
+		setAction(ActionAcknowledged)
+		w.Terminate()
+	})
+	var resp response
+	w.Bind("submit", func(input, choice string) {
+		actionMu.Lock()
+		if action == "" {
+			action, resp = ActionAcknowledged, response{Input: input, Choice: choice}
+		}
+		actionMu.Unlock()
+		w.Terminate()
+	})
+	w.Bind("openLink", OpenLink)
+	w.Bind("timeoutApp", func() {
+		setAction(ActionTimeout)
+		w.Terminate()
+	})
+
+	// -automation: test scripts read the page and click its buttons over a local socket
+	if n.Automation != "" {
+		if stop, err := startAutomation(n.Automation, newWebViewAutomation(w, n.Title)); err != nil {
+			log.Printf("Warning: Automation unavailable: %v", err)
+		} else {
+			defer stop()
+		}
+	}
+
+	w.SetHtml(html)
+
+	// Auto-close timer (backup in case JS doesn't work)
+	if n.Timeout > 0 {
+		go func() {
+			time.Sleep(time.Duration(n.Timeout) * time.Second)
+			setAction(ActionTimeout)
+			w.Terminate()
+		}()
+	}
+
+	if n.Monitor == MonitorAll {
+		log.Printf("Placement: -monitor all shows copies with the Fyne window only, WebView uses the primary monitor")
+	}
+	// -state-id: updates of the state are passed to setState
+	stateWatched := func() {}
+	if n.StateID != "" {
+		stateWatched = watchState(n, func(update StateUpdate) {
+			icon, _ := json.Marshal(stateIconHTML(update.State))
+			message, _ := json.Marshal(update.Message)
+			w.Dispatch(func() {
+				w.Eval(fmt.Sprintf("setState(%s, %s)", icon, message))
+			})
+		})
+	}
+
+	// -id: later notifications with the same ID are passed to setContent, or close the window
+	idWatched := func() {}
+	if n.ID != "" {
+		idWatched = watchWindowID(n, func(title, message string) {
+			titleJSON, _ := json.Marshal(title)
+			messageJSON, _ := json.Marshal(message)
+			w.Dispatch(func() {
+				w.Eval(fmt.Sprintf("setContent(%s, %s)", titleJSON, messageJSON))
+			})
+		}, func() {
+			setAction(ActionCanceled)
+			w.Terminate()
+		})
+	}
+
+	// -ack-file: touching the file acknowledges without using the window
+	if n.AckFile != "" {
+		stopAckFile := make(chan struct{})
+		defer close(stopAckFile)
+		acked := watchAckFile(n.AckFile, stopAckFile)
+		go func() {
+			select {
+			case <-acked:
+				setAction(ActionAcknowledged)
+				w.Terminate()
+			case <-stopAckFile:
+			}
+		}()
+	}
+
+	placed := placeWindow(n.Title, n, n.fullScreen(), slot)
+	if n.fullScreen() {
+		go func() {
+			if err := fullScreenWindow(n.Title); err != nil {
+				log.Printf("Warning: Could not show the window full screen: %v", err)
+			}
+		}()
+	}
+	w.Run()
+	placed()
+	stateWatched()
+	idWatched()
+
+	// Closing the window directly counts as acknowledgment
+	setAction(ActionAcknowledged)
+	return action, resp, nil
+}
+
+// webviewSnapshotScript passes the visible text and buttons of the page to automationReply
+const webviewSnapshotScript = `(function() {
+    const visible = function(e) { return e.offsetParent !== null; };
+    automationReply(JSON.stringify({
+        texts: document.body.innerText.split('\n').map(function(s) { return s.trim(); }).filter(Boolean),
+        buttons: Array.from(document.querySelectorAll('button')).filter(visible).map(function(b) {
+            return {name: b.textContent.trim(), enabled: !b.disabled};
+        })
+    }));
+})()`
+
+// webviewClickScript clicks the visible button named by its argument, after telling
+// automationReply whether it could ("" when it did), since the click may close the window
+const webviewClickScript = `(function(name) {
+    const button = Array.from(document.querySelectorAll('button')).find(function(b) {
+        return b.offsetParent !== null && b.textContent.trim() === name;
+    });
+    if (!button) {
+        automationReply('no button "' + name + '"');
+    } else if (button.disabled) {
+        automationReply('button "' + name + '" is disabled');
+    } else {
+        automationReply('');
+        button.click();
+    }
+})(%s)`
+
+// webviewAutomation drives a WebView window for -automation with scripts, which answer by
+// calling the bound automationReply function
+type webviewAutomation struct {
+	w       webviewWindow
+	title   string
+	mu      sync.Mutex // One script at a time, so replies cannot be mixed up
+	replies chan string
+}
+
+// newWebViewAutomation binds automationReply in w, before its page is set
+func newWebViewAutomation(w webviewWindow, title string) *webviewAutomation {
+	a := &webviewAutomation{w: w, title: title, replies: make(chan string, 1)}
+	w.Bind("automationReply", func(reply string) {
+		select {
+		case a.replies <- reply:
+		default:
+		}
+	})
+	return a
+}
+
+// eval runs script in the page and returns what it passed to automationReply
+func (a *webviewAutomation) eval(script string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-a.replies: // A late reply to a script that timed out
+	default:
+	}
+	a.w.Dispatch(func() {
+		a.w.Eval(script)
+	})
+	select {
+	case reply := <-a.replies:
+		return reply, nil
+	case <-time.After(automationTimeout):
+		return "", fmt.Errorf("the window did not answer within %v", automationTimeout)
+	}
+}
+
+// snapshot returns the visible text and buttons of the page
+func (a *webviewAutomation) snapshot() (AutomationSnapshot, error) {
+	reply, err := a.eval(webviewSnapshotScript)
+	if err != nil {
+		return AutomationSnapshot{}, err
+	}
+	snapshot := AutomationSnapshot{Renderer: "webview", Title: a.title}
+	if err := json.Unmarshal([]byte(reply), &snapshot); err != nil {
+		return AutomationSnapshot{}, fmt.Errorf("could not read the page: %v", err)
+	}
+	return snapshot, nil
+}
+
+// click clicks the first visible button named name, as the user would
+func (a *webviewAutomation) click(name string) error {
+	nameJSON, _ := json.Marshal(name)
+	reply, err := a.eval(fmt.Sprintf(webviewClickScript, nameJSON))
+	if err != nil {
+		return err
+	}
+	if reply != "" {
+		return fmt.Errorf("%s", reply)
+	}
+	return nil
+}
+
+// webviewIconHTML returns the image at path as an img element with the image embedded
+// (base64), resolving the path in the executable directory and downscaling large images
+func webviewIconHTML(path string) (string, error) {
+	actualPath, err := prepareIcon(path)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("WebView: Loading icon from: %s", actualPath)
+	imageData, err := os.ReadFile(actualPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Detect image type (simple detection based on file extension)
+	mimeType := "image/png"
+	if len(actualPath) > 4 {
+		ext := actualPath[len(actualPath)-4:]
+		switch ext {
+		case ".jpg", "jpeg":
+			mimeType = "image/jpeg"
+		case ".gif":
+			mimeType = "image/gif"
+		case ".bmp":
+			mimeType = "image/bmp"
+		case "webp":
+			mimeType = "image/webp"
+		}
+	}
+	return fmt.Sprintf(`<img class="icon-img" src="data:%s;base64,%s" alt="Icon">`, mimeType, base64.StdEncoding.EncodeToString(imageData)), nil
+}
+
+// urgencyCSS returns the styles of an urgency: a top border and button in its accent color
+func urgencyCSS(urgency string) string {
+	accent, ok := urgencyAccent(urgency)
+	if !ok {
+		return ""
+	}
+	hex := fmt.Sprintf("#%02x%02x%02x", accent.R, accent.G, accent.B)
+	return fmt.Sprintf(`.notification-card { border-top: 6px solid %s; }
+        .ok-button { background: %s; }`, hex, hex)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,48 @@
+//go:build webview
+// +build webview
+
+package notify
+
+import (
+	"errors"
+	"log"
+
+	webview "github.com/webview/webview_go"
+)
+
+// cgoWebView is a window of the webview library, linked through cgo
+type cgoWebView struct {
+	webview.WebView
+}
+
+// newWebViewWindow creates a webview library window
+func newWebViewWindow() (webviewWindow, error) {
+	w := webview.New(false)
+	if w == nil {
+		return nil, errors.New("could not create the webview window")
+	}
+	return cgoWebView{w}, nil
+}
+
+// SetSize sets the window size in pixels
+func (w cgoWebView) SetSize(width, height int) {
+	w.WebView.SetSize(width, height, webview.HintNone)
+}
+
+// WebViewCompiledIn reports whether this binary has the WebView window: built with -tags
+// webview, or for Windows, which loads WebView2 at run time
+const WebViewCompiledIn = true
+
+// webViewSupport is how this binary gets the WebView window, for -check-webview
+const webViewSupport = "compiled in (-tags webview)"
+
+// isWebViewAvailable checks if webview can be used
+// The webview library links against the platform engine, so also check that its
+// runtime is installed (Windows needs the WebView2 Runtime, Linux needs webkit2gtk)
+func isWebViewAvailable() bool {
+	available, detail := DetectWebViewRuntime()
+	log.Printf("WebView runtime: %s", detail)
+	return available
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,26 @@
+//go:build !webview && !windows
+// +build !webview,!windows
+
+package notify
+
+import "fmt"
+
+// showWebViewNotification stub when webview is not available
+func showWebViewNotification(n Notification, slot int) (string, response, error) {
+	return "", response{}, fmt.Errorf("webview support not compiled in (use build tag: -tags webview)")
+}
+
+// WebViewCompiledIn reports whether this binary has the WebView window: built with -tags
+// webview, or for Windows, which loads WebView2 at run time
+const WebViewCompiledIn = false
+
+// webViewSupport is how this binary gets the WebView window, for -check-webview
+// WebKit and webkit2gtk are only reached through the webview library, linked with cgo
+const webViewSupport = "not compiled in (macOS and Linux need a build with -tags webview)"
+
+// isWebViewAvailable always returns false when webview is not compiled
+func isWebViewAvailable() bool {
+	return false
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
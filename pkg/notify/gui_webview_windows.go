@@ -0,0 +1,607 @@
+//go:build windows && !webview
+// +build windows,!webview
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// Without -tags webview, Windows builds show the WebView window with the WebView2 Runtime loaded
+// at run time: its COM interfaces are reached with LoadLibrary and syscall, so no cgo or
+// WebView2 SDK is needed to build notify.exe
+
+// Window and message functions in user32.dll, kernel32.dll and ole32.dll
+var (
+	registerClassEx   = user32.NewProc("RegisterClassExW")
+	createWindowEx    = user32.NewProc("CreateWindowExW")
+	defWindowProc     = user32.NewProc("DefWindowProcW")
+	destroyWindow     = user32.NewProc("DestroyWindow")
+	getMessage        = user32.NewProc("GetMessageW")
+	translateMessage  = user32.NewProc("TranslateMessage")
+	dispatchMessage   = user32.NewProc("DispatchMessageW")
+	postMessage       = user32.NewProc("PostMessageW")
+	postThreadMessage = user32.NewProc("PostThreadMessageW")
+	postQuitMessage   = user32.NewProc("PostQuitMessage")
+	setWindowText     = user32.NewProc("SetWindowTextW")
+	getClientRect     = user32.NewProc("GetClientRect")
+	loadCursor        = user32.NewProc("LoadCursorW")
+
+	getModuleHandle    = syscall.NewLazyDLL("kernel32.dll").NewProc("GetModuleHandleW")
+	getCurrentThreadID = syscall.NewLazyDLL("kernel32.dll").NewProc("GetCurrentThreadId")
+	coTaskMemFree      = ole32.NewProc("CoTaskMemFree")
+)
+
+// Window messages and styles
+const (
+	wmDestroy  = 0x0002
+	wmSize     = 0x0005
+	wmQuit     = 0x0012
+	wmDispatch = 0x8000 // WM_APP: runs the functions passed to Dispatch
+
+	swShow    = 5
+	idcArrow  = 32512
+	colorBack = 6 // COLOR_WINDOW + 1
+)
+
+// webView2ClassName is the window class of WebView2 windows
+const webView2ClassName = "KrankyBearNotifyWebView"
+
+// Methods of the WebView2 COM interfaces used (WebView2.h), counted in their vtables after
+// IUnknown's QueryInterface, AddRef and Release
+const (
+	comAddRef  = 1
+	comRelease = 2
+
+	environmentCreateController = 3 // ICoreWebView2Environment::CreateCoreWebView2Controller
+
+	controllerPutIsVisible    = 4  // ICoreWebView2Controller::put_IsVisible
+	controllerPutBounds       = 6  // ICoreWebView2Controller::put_Bounds
+	controllerClose           = 24 // ICoreWebView2Controller::Close
+	controllerGetCoreWebView2 = 25 // ICoreWebView2Controller::get_CoreWebView2
+
+	webViewNavigateToString     = 6  // ICoreWebView2::NavigateToString
+	webViewAddScript            = 27 // ICoreWebView2::AddScriptToExecuteOnDocumentCreated
+	webViewExecuteScript        = 29 // ICoreWebView2::ExecuteScript
+	webViewAddWebMessageHandler = 34 // ICoreWebView2::add_WebMessageReceived
+
+	messageTryGetString = 5 // ICoreWebView2WebMessageReceivedEventArgs::TryGetWebMessageAsString
+)
+
+// webView2BindScript defines a page function that passes its arguments to the Go function
+// bound under its name, as the webview library's bindings do
+const webView2BindScript = `window[%[1]s] = function() {
+    window.chrome.webview.postMessage(JSON.stringify({name: %[1]s, args: Array.prototype.slice.call(arguments)}));
+    return Promise.resolve();
+};`
+
+// webView2Object is a WebView2 COM object; its vtable is sized for the largest method used
+type webView2Object struct {
+	vtbl *[35]uintptr
+}
+
+// call calls method on the object and returns its HRESULT
+func (o *webView2Object) call(method int, args ...uintptr) uintptr {
+	hr, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	return hr
+}
+
+// webView2Handler is a COM object implementing a WebView2 completion or event handler: they all
+// are IUnknown followed by Invoke, with an HRESULT or the sender, and the result or event
+type webView2Handler struct {
+	vtbl   *[4]uintptr
+	invoke func(result uintptr, object *webView2Object)
+}
+
+// webView2HandlerVtbl is the vtable of every webView2Handler, made by registerWebView2Class
+// The handlers belong to their window, which keeps them until it is destroyed, so AddRef and
+// Release do not count
+var webView2HandlerVtbl [4]uintptr
+
+// webView2Windows are the open windows by handle, for webView2WindowProc
+var (
+	webView2WindowsMu sync.Mutex
+	webView2Windows   = map[uintptr]*webView2Window{}
+)
+
+// webView2ClassOnce registers the window class and makes the callbacks once per process, as
+// syscall.NewCallback callbacks are never freed
+var (
+	webView2ClassOnce sync.Once
+	webView2ClassErr  error
+)
+
+// webView2Window is a window showing a page with WebView2, driven like a webview library window
+// It must be created, set up and run on one goroutine, which it locks to its OS thread until
+// Destroy; Dispatch and Terminate may be called from any goroutine
+type webView2Window struct {
+	hwnd       uintptr
+	thread     uintptr
+	comStarted bool
+
+	environment *webView2Object
+	controller  *webView2Object
+	webview     *webView2Object
+	handlers    []*webView2Handler // Kept alive while WebView2 may call them
+	ready       bool               // The controller was created, or failed with err
+	err         error
+
+	mu         sync.Mutex
+	bindings   map[string]reflect.Value
+	dispatched []func()
+	destroyed  bool // Dispatch and Terminate do nothing once the window and thread are let go
+}
+
+// newWebViewWindow creates a hidden window and starts WebView2 in it, waiting until it is ready
+func newWebViewWindow() (webviewWindow, error) {
+	runtime.LockOSThread()
+	w := &webView2Window{bindings: map[string]reflect.Value{}}
+	if err := w.create(); err != nil {
+		w.Destroy()
+		return nil, err
+	}
+	return w, nil
+}
+
+// create creates the window, then the WebView2 environment and controller, which WebView2
+// reports through handlers while the window's messages are processed
+func (w *webView2Window) create() error {
+	const COINIT_APARTMENTTHREADED = 0x2
+	if hr, _, _ := coInitializeEx.Call(0, COINIT_APARTMENTTHREADED); int32(hr) >= 0 {
+		w.comStarted = true
+	}
+	w.thread, _, _ = getCurrentThreadID.Call()
+	webView2ClassOnce.Do(registerWebView2Class)
+	if webView2ClassErr != nil {
+		return webView2ClassErr
+	}
+
+	className, _ := syscall.UTF16PtrFromString(webView2ClassName)
+	instance, _, _ := getModuleHandle.Call(0)
+	hwnd, _, err := createWindowEx.Call(0, uintptr(unsafe.Pointer(className)), 0, WS_OVERLAPPEDWINDOW,
+		CW_USEDEFAULT, CW_USEDEFAULT, CW_USEDEFAULT, CW_USEDEFAULT, 0, 0, instance, 0)
+	if hwnd == 0 {
+		return fmt.Errorf("could not create the window: %v", err)
+	}
+	w.hwnd = hwnd
+	webView2WindowsMu.Lock()
+	webView2Windows[hwnd] = w
+	webView2WindowsMu.Unlock()
+
+	messages := w.newHandler(func(_ uintptr, args *webView2Object) {
+		var message *uint16
+		if hr := args.call(messageTryGetString, uintptr(unsafe.Pointer(&message))); int32(hr) < 0 {
+			return
+		}
+		defer coTaskMemFree.Call(uintptr(unsafe.Pointer(message)))
+		w.callBinding(utf16PtrToString(message))
+	})
+	controllerCreated := w.newHandler(func(result uintptr, controller *webView2Object) {
+		if err := hresultError("could not create the WebView2 controller", result); err != nil {
+			w.ready, w.err = true, err
+			return
+		}
+		controller.call(comAddRef)
+		w.controller = controller
+		if err := hresultError("could not get the WebView2 view", controller.call(controllerGetCoreWebView2, uintptr(unsafe.Pointer(&w.webview)))); err != nil {
+			w.ready, w.err = true, err
+			return
+		}
+		var token int64
+		w.webview.call(webViewAddWebMessageHandler, uintptr(unsafe.Pointer(messages)), uintptr(unsafe.Pointer(&token)))
+		w.resize()
+		w.ready = true
+	})
+	environmentCreated := w.newHandler(func(result uintptr, environment *webView2Object) {
+		if err := hresultError("could not start the WebView2 Runtime", result); err != nil {
+			w.ready, w.err = true, err
+			return
+		}
+		environment.call(comAddRef)
+		w.environment = environment
+		if err := hresultError("could not create the WebView2 controller", environment.call(environmentCreateController, w.hwnd, uintptr(unsafe.Pointer(controllerCreated)))); err != nil {
+			w.ready, w.err = true, err
+		}
+	})
+	if err := createWebView2Environment(environmentCreated); err != nil {
+		return err
+	}
+
+	for !w.ready {
+		if !w.processMessage() {
+			return errors.New("the window was closed while WebView2 was starting")
+		}
+	}
+	return w.err
+}
+
+// newHandler returns a COM handler calling invoke, kept until the window is destroyed
+func (w *webView2Window) newHandler(invoke func(result uintptr, object *webView2Object)) *webView2Handler {
+	handler := &webView2Handler{vtbl: &webView2HandlerVtbl, invoke: invoke}
+	w.handlers = append(w.handlers, handler)
+	return handler
+}
+
+// registerWebView2Class registers the window class and makes the vtable of the handlers
+func registerWebView2Class() {
+	webView2HandlerVtbl = [4]uintptr{
+		syscall.NewCallback(func(this *webView2Handler, iid *winGUID, object **webView2Handler) uintptr {
+			*object = this // QueryInterface: a handler only has the interface it is passed as
+			return 0
+		}),
+		syscall.NewCallback(func(this *webView2Handler) uintptr { return 1 }), // AddRef
+		syscall.NewCallback(func(this *webView2Handler) uintptr { return 1 }), // Release
+		syscall.NewCallback(func(this *webView2Handler, result uintptr, object *webView2Object) uintptr {
+			this.invoke(result, object)
+			return 0
+		}),
+	}
+
+	className, _ := syscall.UTF16PtrFromString(webView2ClassName)
+	instance, _, _ := getModuleHandle.Call(0)
+	cursor, _, _ := loadCursor.Call(0, idcArrow)
+	class := struct {
+		size       uint32
+		style      uint32
+		wndProc    uintptr
+		clsExtra   int32
+		wndExtra   int32
+		instance   uintptr
+		icon       uintptr
+		cursor     uintptr
+		background uintptr
+		menuName   *uint16
+		className  *uint16
+		iconSm     uintptr
+	}{wndProc: syscall.NewCallback(webView2WindowProc), instance: instance, cursor: cursor, background: colorBack, className: className}
+	class.size = uint32(unsafe.Sizeof(class))
+	if atom, _, err := registerClassEx.Call(uintptr(unsafe.Pointer(&class))); atom == 0 {
+		webView2ClassErr = fmt.Errorf("could not register the window class: %v", err)
+	}
+}
+
+// webView2WindowProc handles the messages of WebView2 windows
+func webView2WindowProc(hwnd, message, wParam, lParam uintptr) uintptr {
+	webView2WindowsMu.Lock()
+	w := webView2Windows[hwnd]
+	webView2WindowsMu.Unlock()
+	if w != nil {
+		switch message {
+		case wmSize:
+			w.resize()
+		case wmDispatch:
+			w.runDispatched()
+			return 0
+		case wmDestroy:
+			// Closed by the user, as Destroy forgets the window first
+			postQuitMessage.Call(0)
+			return 0
+		}
+	}
+	result, _, _ := defWindowProc.Call(hwnd, message, wParam, lParam)
+	return result
+}
+
+// createWebView2Environment starts the WebView2 Runtime, which calls handler with its
+// environment: through WebView2Loader.dll when it is shipped next to notify.exe, otherwise
+// through the runtime's EmbeddedBrowserWebView.dll, which WebView2Loader.dll calls itself
+// Both use WEBVIEW2_USER_DATA_FOLDER as the user data folder
+func createWebView2Environment(handler *webView2Handler) error {
+	folder := os.Getenv("WEBVIEW2_USER_DATA_FOLDER")
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not determine the executable path: %v", err)
+	}
+	if folder == "" {
+		folder = exe + ".WebView2" // WebView2Loader.dll's default
+	}
+	folderPtr, err := syscall.UTF16PtrFromString(folder)
+	if err != nil {
+		return err
+	}
+
+	loader := filepath.Join(filepath.Dir(exe), "WebView2Loader.dll")
+	if _, err := os.Stat(loader); err == nil {
+		create := syscall.NewLazyDLL(loader).NewProc("CreateCoreWebView2EnvironmentWithOptions")
+		if err := create.Find(); err != nil {
+			return fmt.Errorf("could not load %s: %v", loader, err)
+		}
+		log.Printf("WebView: Starting WebView2 with %s", loader)
+		// Browser folder (the installed runtime), user data folder, options, handler
+		hr, _, _ := create.Call(0, uintptr(unsafe.Pointer(folderPtr)), 0, uintptr(unsafe.Pointer(handler)))
+		return hresultError("could not start the WebView2 Runtime", hr)
+	}
+
+	dll, err := webView2RuntimeDLL()
+	if err != nil {
+		return err
+	}
+	create := syscall.NewLazyDLL(dll).NewProc("CreateWebViewEnvironmentWithOptionsInternal")
+	if err := create.Find(); err != nil {
+		return fmt.Errorf("could not load %s: %v", dll, err)
+	}
+	log.Printf("WebView: Starting WebView2 with %s", dll)
+	// Check for a running instance, runtime type 0 (installed), user data folder, options, handler
+	hr, _, _ := create.Call(1, 0, uintptr(unsafe.Pointer(folderPtr)), 0, uintptr(unsafe.Pointer(handler)))
+	return hresultError("could not start the WebView2 Runtime", hr)
+}
+
+// webView2RuntimeDLL returns the path of EmbeddedBrowserWebView.dll in the installed WebView2
+// Runtime, which EdgeUpdate registers with its version and folder
+func webView2RuntimeDLL() (string, error) {
+	arch := map[string]string{"amd64": "x64", "386": "x86", "arm64": "arm64"}[runtime.GOARCH]
+	for _, root := range []struct {
+		key    syscall.Handle
+		path   string
+		access uint32
+	}{
+		{syscall.HKEY_LOCAL_MACHINE, `SOFTWARE` + webView2ClientKey, syscall.KEY_READ | syscall.KEY_WOW64_32KEY},
+		{syscall.HKEY_CURRENT_USER, `Software` + webView2ClientKey, syscall.KEY_READ},
+	} {
+		version := registryString(root.key, root.path, root.access, "pv")
+		location := registryString(root.key, root.path, root.access, "location")
+		if version == "" || version == "0.0.0.0" || location == "" {
+			continue
+		}
+		dll := filepath.Join(location, version, "EBWebView", arch, "EmbeddedBrowserWebView.dll")
+		if _, err := os.Stat(dll); err == nil {
+			return dll, nil
+		}
+	}
+	return "", errors.New("WebView2 Runtime not installed (https://developer.microsoft.com/microsoft-edge/webview2/)")
+}
+
+// registryString returns the string value name of the registry key path, or ""
+func registryString(root syscall.Handle, path string, access uint32, name string) string {
+	pathPtr, _ := syscall.UTF16PtrFromString(path)
+	namePtr, _ := syscall.UTF16PtrFromString(name)
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(root, pathPtr, 0, access, &key); err != nil {
+		return ""
+	}
+	defer syscall.RegCloseKey(key)
+	var value [1024]uint16
+	size := uint32(len(value) * 2)
+	var valueType uint32
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &valueType, (*byte)(unsafe.Pointer(&value[0])), &size); err != nil || valueType != syscall.REG_SZ {
+		return ""
+	}
+	return syscall.UTF16ToString(value[:])
+}
+
+// SetTitle sets the window title
+func (w *webView2Window) SetTitle(title string) {
+	titlePtr, err := syscall.UTF16PtrFromString(title)
+	if err != nil {
+		return
+	}
+	setWindowText.Call(w.hwnd, uintptr(unsafe.Pointer(titlePtr)))
+}
+
+// SetSize sets the window size in pixels
+func (w *webView2Window) SetSize(width, height int) {
+	const (
+		SWP_NOMOVE     = 0x0002
+		SWP_NOZORDER   = 0x0004
+		SWP_NOACTIVATE = 0x0010
+	)
+	setWindowPos.Call(w.hwnd, 0, 0, 0, uintptr(width), uintptr(height), SWP_NOMOVE|SWP_NOZORDER|SWP_NOACTIVATE)
+}
+
+// Bind makes f callable from the page as a function called name, with arguments passed as JSON
+// Bind before SetHtml, as the function is defined when a page is loaded
+func (w *webView2Window) Bind(name string, f interface{}) error {
+	fn := reflect.ValueOf(f)
+	if fn.Kind() != reflect.Func {
+		return fmt.Errorf("%s is not a function", name)
+	}
+	w.mu.Lock()
+	w.bindings[name] = fn
+	w.mu.Unlock()
+	nameJSON, _ := json.Marshal(name)
+	script, _ := syscall.UTF16PtrFromString(fmt.Sprintf(webView2BindScript, nameJSON))
+	return hresultError("could not bind "+name, w.webview.call(webViewAddScript, uintptr(unsafe.Pointer(script)), 0))
+}
+
+// callBinding calls the bound function a page message names, on its own goroutine like the
+// webview library, so it may block
+func (w *webView2Window) callBinding(message string) {
+	var call struct {
+		Name string            `json:"name"`
+		Args []json.RawMessage `json:"args"`
+	}
+	if err := json.Unmarshal([]byte(message), &call); err != nil {
+		return
+	}
+	w.mu.Lock()
+	fn, ok := w.bindings[call.Name]
+	w.mu.Unlock()
+	if !ok || fn.Type().NumIn() != len(call.Args) {
+		log.Printf("WebView: Ignoring a call of %q with %d arguments", call.Name, len(call.Args))
+		return
+	}
+	args := make([]reflect.Value, len(call.Args))
+	for i, raw := range call.Args {
+		arg := reflect.New(fn.Type().In(i))
+		if err := json.Unmarshal(raw, arg.Interface()); err != nil {
+			log.Printf("WebView: Ignoring a call of %q: %v", call.Name, err)
+			return
+		}
+		args[i] = arg.Elem()
+	}
+	go fn.Call(args)
+}
+
+// SetHtml shows html as the page
+func (w *webView2Window) SetHtml(html string) {
+	htmlPtr, err := syscall.UTF16PtrFromString(html)
+	if err != nil {
+		log.Printf("WebView: Could not show the page: %v", err)
+		return
+	}
+	if err := hresultError("could not show the page", w.webview.call(webViewNavigateToString, uintptr(unsafe.Pointer(htmlPtr)))); err != nil {
+		log.Printf("WebView: %v", err)
+	}
+}
+
+// Eval runs js in the page, from the window's goroutine or a function passed to Dispatch
+func (w *webView2Window) Eval(js string) {
+	jsPtr, err := syscall.UTF16PtrFromString(js)
+	if err != nil {
+		return
+	}
+	w.webview.call(webViewExecuteScript, uintptr(unsafe.Pointer(jsPtr)), 0)
+}
+
+// Dispatch runs f on the window's goroutine
+func (w *webView2Window) Dispatch(f func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.destroyed {
+		return
+	}
+	w.dispatched = append(w.dispatched, f)
+	postMessage.Call(w.hwnd, wmDispatch, 0, 0)
+}
+
+// runDispatched runs the functions passed to Dispatch
+func (w *webView2Window) runDispatched() {
+	w.mu.Lock()
+	dispatched := w.dispatched
+	w.dispatched = nil
+	w.mu.Unlock()
+	for _, f := range dispatched {
+		f()
+	}
+}
+
+// Terminate ends Run
+func (w *webView2Window) Terminate() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.destroyed {
+		postThreadMessage.Call(w.thread, wmQuit, 0, 0)
+	}
+}
+
+// Run shows the window and processes its messages until Terminate or the window is closed
+func (w *webView2Window) Run() {
+	showWindowProc.Call(w.hwnd, swShow)
+	w.controller.call(controllerPutIsVisible, 1)
+	for w.processMessage() {
+	}
+}
+
+// processMessage waits for a message of the thread and processes it, and returns false for
+// WM_QUIT
+func (w *webView2Window) processMessage() bool {
+	var message struct {
+		hwnd    uintptr
+		message uint32
+		wParam  uintptr
+		lParam  uintptr
+		time    uint32
+		x, y    int32
+		private uint32
+	}
+	if got, _, _ := getMessage.Call(uintptr(unsafe.Pointer(&message)), 0, 0, 0); int32(got) <= 0 {
+		return false
+	}
+	translateMessage.Call(uintptr(unsafe.Pointer(&message)))
+	dispatchMessage.Call(uintptr(unsafe.Pointer(&message)))
+	return true
+}
+
+// resize fits the view to the window
+func (w *webView2Window) resize() {
+	if w.controller == nil {
+		return
+	}
+	var rect winRect
+	getClientRect.Call(w.hwnd, uintptr(unsafe.Pointer(&rect)))
+	// RECT is passed by value: on the stack on 386, in two registers on arm64, by reference on amd64
+	switch runtime.GOARCH {
+	case "386":
+		w.controller.call(controllerPutBounds, uintptr(rect.Left), uintptr(rect.Top), uintptr(rect.Right), uintptr(rect.Bottom))
+	case "arm64":
+		w.controller.call(controllerPutBounds, uintptr(uint64(uint32(rect.Left))|uint64(uint32(rect.Top))<<32),
+			uintptr(uint64(uint32(rect.Right))|uint64(uint32(rect.Bottom))<<32))
+	default:
+		w.controller.call(controllerPutBounds, uintptr(unsafe.Pointer(&rect)))
+	}
+}
+
+// Destroy closes WebView2 and the window, and releases the goroutine's OS thread
+func (w *webView2Window) Destroy() {
+	w.mu.Lock()
+	w.destroyed = true
+	w.mu.Unlock()
+	if w.controller != nil {
+		w.controller.call(controllerClose)
+	}
+	for _, object := range []*webView2Object{w.webview, w.controller, w.environment} {
+		if object != nil {
+			object.call(comRelease)
+		}
+	}
+	w.webview, w.controller, w.environment = nil, nil, nil
+	if w.hwnd != 0 {
+		webView2WindowsMu.Lock()
+		delete(webView2Windows, w.hwnd)
+		webView2WindowsMu.Unlock()
+		destroyWindow.Call(w.hwnd)
+		w.hwnd = 0
+	}
+	if w.comStarted {
+		coUninitialize.Call()
+		w.comStarted = false
+	}
+	runtime.UnlockOSThread()
+}
+
+// hresultError returns an error for a failed HRESULT, or nil
+func hresultError(message string, hr uintptr) error {
+	if int32(hr) < 0 {
+		return fmt.Errorf("%s: HRESULT 0x%08X", message, uint32(hr))
+	}
+	return nil
+}
+
+// utf16PtrToString returns the NUL-terminated UTF-16 string at p
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for ptr := unsafe.Pointer(p); *(*uint16)(ptr) != 0; ptr = unsafe.Add(ptr, 2) {
+		n++
+	}
+	return string(utf16.Decode(unsafe.Slice(p, n)))
+}
+
+// WebViewCompiledIn reports whether this binary has the WebView window: built with -tags
+// webview, or for Windows, which loads WebView2 at run time
+const WebViewCompiledIn = true
+
+// webViewSupport is how this binary gets the WebView window, for -check-webview
+const webViewSupport = "WebView2 loaded at run time (no -tags webview needed on Windows)"
+
+// isWebViewAvailable checks that the WebView2 Runtime is installed
+func isWebViewAvailable() bool {
+	available, detail := DetectWebViewRuntime()
+	log.Printf("WebView runtime: %s", detail)
+	return available
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,323 @@
+package notify
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlToken is a piece of an HTML fragment: text, or a start or end tag
+type htmlToken struct {
+	Text  string // Text, entities decoded; empty for tags
+	Tag   string // Lower-case tag name, empty for text
+	End   bool
+	Attrs []htmlAttr
+}
+
+// htmlAttr is an attribute of a start tag, its value with entities decoded
+type htmlAttr struct {
+	Key, Val string
+}
+
+// htmlAllowed lists the elements sanitizeHTML keeps and their attributes besides title
+// Formatting, lists, tables, links and images: enough for an announcement, nothing that
+// runs code, loads a page, styles outside the message or asks for input
+var htmlAllowed = map[string][]string{
+	"a": {"href"}, "img": {"src", "alt", "width", "height"},
+	"p": nil, "br": nil, "hr": nil, "div": nil, "span": nil, "blockquote": nil, "pre": nil, "code": nil,
+	"b": nil, "strong": nil, "i": nil, "em": nil, "u": nil, "s": nil, "small": nil, "sub": nil, "sup": nil, "mark": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"table": nil, "caption": nil, "thead": nil, "tbody": nil, "tfoot": nil, "tr": nil,
+	"th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+}
+
+// htmlVoid are the allowed elements without content or end tag
+var htmlVoid = map[string]bool{"br": true, "hr": true, "img": true}
+
+// htmlDropContent are elements whose content is dropped with them: it is code, styling,
+// or markup the browser does not show as text
+var htmlDropContent = map[string]bool{
+	"script": true, "style": true, "template": true, "noscript": true, "title": true, "textarea": true,
+	"iframe": true, "object": true, "embed": true, "frameset": true, "noframes": true, "noembed": true,
+	"svg": true, "math": true, "select": true, "xmp": true, "plaintext": true, "head": true,
+}
+
+// htmlBlocks are elements that start a new line in HTMLText
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "blockquote": true, "pre": true, "hr": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"table": true, "caption": true, "tr": true,
+}
+
+// tokenizeHTML splits an HTML fragment into text and tags, dropping comments, doctypes and the
+// content of htmlDropContent elements; a '<' that does not start a complete tag is text
+// Callers build their output only from the tokens, so markup this simpler parser reads
+// differently than a browser ends up as escaped text, never as markup
+func tokenizeHTML(fragment string) []htmlToken {
+	var tokens []htmlToken
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, htmlToken{Text: html.UnescapeString(text.String())})
+			text.Reset()
+		}
+	}
+
+	s := fragment
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			next := strings.IndexByte(s[i:], '<')
+			if next < 0 {
+				next = len(s) - i
+			}
+			text.WriteString(s[i : i+next])
+			i += next
+			continue
+		}
+
+		rest := s[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			flush()
+			if end := strings.Index(rest[4:], "-->"); end >= 0 {
+				i += 4 + end + 3
+			} else {
+				i = len(s)
+			}
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+			flush()
+			if end := strings.IndexByte(rest, '>'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(s)
+			}
+		case len(rest) > 1 && isASCIILetter(rest[1]), len(rest) > 2 && rest[1] == '/' && isASCIILetter(rest[2]):
+			tok, length := parseHTMLTag(rest)
+			if length == 0 {
+				text.WriteByte('<')
+				i++
+				continue
+			}
+			flush()
+			i += length
+			if htmlDropContent[tok.Tag] && !tok.End {
+				// Skip to the end tag (to the end of the fragment if there is none)
+				end := strings.Index(strings.ToLower(s[i:]), "</"+tok.Tag)
+				if end < 0 {
+					i = len(s)
+				} else if gt := strings.IndexByte(s[i+end:], '>'); gt >= 0 {
+					i += end + gt + 1
+				} else {
+					i = len(s)
+				}
+				continue
+			}
+			tokens = append(tokens, tok)
+		default:
+			text.WriteByte('<')
+			i++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// parseHTMLTag parses the tag at the start of s, returning it and its length, or a length
+// of 0 when the tag is not terminated
+func parseHTMLTag(s string) (htmlToken, int) {
+	var tok htmlToken
+	i := 1
+	if s[i] == '/' {
+		tok.End = true
+		i++
+	}
+	start := i
+	for i < len(s) && (isASCIILetter(s[i]) || s[i] >= '0' && s[i] <= '9' || s[i] == '-') {
+		i++
+	}
+	tok.Tag = strings.ToLower(s[start:i])
+
+	for {
+		for i < len(s) && (isHTMLSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			return tok, 0
+		}
+		if s[i] == '>' {
+			return tok, i + 1
+		}
+
+		start := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' && s[i] != '/' && s[i] != '=' {
+			i++
+		}
+		key := strings.ToLower(s[start:i])
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				end := strings.IndexByte(s[i+1:], s[i])
+				if end < 0 {
+					return tok, 0
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		if key != "" && !tok.End {
+			tok.Attrs = append(tok.Attrs, htmlAttr{Key: key, Val: html.UnescapeString(value)})
+		}
+	}
+}
+
+// sanitizeHTML returns fragment with only the htmlAllowed elements and attributes, links limited
+// to http(s) and mailto, images to http(s) and data:image, all text escaped and every element closed
+func sanitizeHTML(fragment string) string {
+	var b strings.Builder
+	var open []string
+	for _, tok := range tokenizeHTML(fragment) {
+		attrs, allowed := htmlAllowed[tok.Tag]
+		switch {
+		case tok.Tag == "":
+			b.WriteString(html.EscapeString(tok.Text))
+		case !allowed:
+			// Dropped, its content is kept as text
+		case tok.End:
+			// Close the element and any left open inside it; end tags of elements that are not open are dropped
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == tok.Tag {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		default:
+			b.WriteString("<" + tok.Tag)
+			for _, attr := range tok.Attrs {
+				if value, ok := sanitizeHTMLAttr(attr, attrs); ok {
+					b.WriteString(" " + attr.Key + `="` + html.EscapeString(value) + `"`)
+				}
+			}
+			b.WriteString(">")
+			if !htmlVoid[tok.Tag] {
+				open = append(open, tok.Tag)
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// sanitizeHTMLAttr returns the value attr keeps on an element allowing attrs, and whether it is kept
+func sanitizeHTMLAttr(attr htmlAttr, attrs []string) (string, bool) {
+	if attr.Key != "title" && !containsString(attrs, attr.Key) {
+		return "", false
+	}
+	switch attr.Key {
+	case "href":
+		return safeHTMLURL(attr.Val, "http", "https", "mailto")
+	case "src":
+		if link, ok := safeHTMLURL(attr.Val, "http", "https"); ok {
+			return link, true
+		}
+		link, ok := safeHTMLURL(attr.Val, "data")
+		for _, format := range []string{"png", "jpeg", "gif", "webp"} {
+			if ok && strings.HasPrefix(strings.ToLower(link), "data:image/"+format+";base64,") {
+				return link, true
+			}
+		}
+		return "", false
+	case "width", "height", "colspan", "rowspan", "start":
+		if attr.Val == "" || strings.Trim(attr.Val, "0123456789") != "" || len(attr.Val) > 4 {
+			return "", false
+		}
+	}
+	return attr.Val, true
+}
+
+// safeHTMLURL returns link without the whitespace and control characters browsers ignore in URLs,
+// and whether it is absolute with one of schemes
+func safeHTMLURL(link string, schemes ...string) (string, bool) {
+	link = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, link)
+	scheme, _, found := strings.Cut(link, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		return "", false
+	}
+	return link, containsString(schemes, strings.ToLower(scheme))
+}
+
+// HTMLText returns the text of an HTML fragment (-html) for backends that cannot show HTML:
+// tags removed, one line per paragraph, list item or table row
+func HTMLText(fragment string) string {
+	var b strings.Builder
+	for _, tok := range tokenizeHTML(fragment) {
+		switch {
+		case tok.Tag == "":
+			// Line breaks in the source are spaces, as in the browser
+			b.WriteString(strings.Map(func(r rune) rune {
+				if r < 0x80 && isHTMLSpace(byte(r)) {
+					return ' '
+				}
+				return r
+			}, tok.Text))
+		case tok.Tag == "li" && !tok.End:
+			b.WriteString("\n- ")
+		case tok.Tag == "br", htmlBlocks[tok.Tag]:
+			b.WriteString("\n")
+		case tok.Tag == "td" || tok.Tag == "th":
+			b.WriteString(" ")
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" && line != "-" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isASCIILetter reports whether c is an ASCII letter, the start of an HTML tag name
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isHTMLSpace reports whether c is HTML whitespace
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
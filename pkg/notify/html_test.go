@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSanitizeHTML tests that announcement markup is kept and anything that could run
+// code, leave the page or break out of the message is removed
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"formatting", `<h2>Maintenance</h2><p>Tonight <b>22:00</b>-<i>23:00</i></p>`, `<h2>Maintenance</h2><p>Tonight <b>22:00</b>-<i>23:00</i></p>`},
+		{"list", `<ul><li>Save<li>Log off</ul>`, `<ul><li>Save<li>Log off</li></li></ul>`},
+		{"link", `<a href="https://intranet.example.com/it" title="IT">details</a>`, `<a href="https://intranet.example.com/it" title="IT">details</a>`},
+		{"mailto", `<a href=mailto:it@example.com>mail</a>`, `<a href="mailto:it@example.com">mail</a>`},
+		{"script", `before<script>alert(1)</script>after`, `beforeafter`},
+		{"script case", `<SCRIPT>alert(1)</ScRiPt>ok`, `ok`},
+		{"unclosed script", `ok<script>alert(1)`, `ok`},
+		{"style", `<style>body{display:none}</style>text`, `text`},
+		{"event handler", `<img src="https://example.com/a.png" onerror="alert(1)">`, `<img src="https://example.com/a.png">`},
+		{"javascript link", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"obfuscated scheme", `<a href=" jav&#x09;ascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"entity scheme", `<a href="&#106;avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"relative link", `<a href="/etc/passwd">x</a>`, `<a>x</a>`},
+		{"data image", `<img src="data:image/png;base64,iVBORw0KGgo=" alt="logo">`, `<img src="data:image/png;base64,iVBORw0KGgo=" alt="logo">`},
+		{"data html", `<img src="data:text/html;base64,PHNjcmlwdD4=">`, `<img>`},
+		{"style attribute", `<p style="position:fixed;top:0">x</p>`, `<p>x</p>`},
+		{"size attribute", `<img src="https://e.com/a.png" width="64" height="100%">`, `<img src="https://e.com/a.png" width="64">`},
+		{"iframe", `<iframe src="https://evil.example"></iframe>shown`, `shown`},
+		{"unknown tag", `<marquee>moving</marquee>`, `moving`},
+		{"form", `<form action="https://evil.example"><input name=p>pw</form>`, `pw`},
+		{"comment", `a<!-- <script>alert(1)</script> -->b`, `ab`},
+		{"stray end", `</div></p>text`, `text`},
+		{"unclosed", `<b><i>bold italic`, `<b><i>bold italic</i></b>`},
+		{"misnested", `<b><i>x</b>y</i>`, `<b><i>x</i></b>y`},
+		{"quote in value", `<a href='https://e.com/?q="x"' title="a&quot;b">q</a>`, `<a href="https://e.com/?q=&#34;x&#34;" title="a&#34;b">q</a>`},
+		{"not a tag", `1 < 2 and 3 <> 4`, `1 &lt; 2 and 3 &lt;&gt; 4`},
+		{"unterminated tag", `<a href="https://e.com`, `&lt;a href=&#34;https://e.com`},
+		{"entities", `Tom &amp; Jerry &lt;3`, `Tom &amp; Jerry &lt;3`},
+		{"close button", `</div><button onclick="closeApp()">`, ``},
+	}
+	for _, tt := range tests {
+		if got := sanitizeHTML(tt.in); got != tt.want {
+			t.Errorf("%s: sanitizeHTML(%q)\n got  %q\n want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestSanitizeHTMLNeverEmitsScript tests that for markup meant to confuse a sanitizer, the
+// output only has allowed elements, without event handlers or javascript: links
+func TestSanitizeHTMLNeverEmitsScript(t *testing.T) {
+	inputs := []string{
+		`<scr<script>ipt>alert(1)</script>`,
+		`<<script>script>alert(1)<</script>/script>`,
+		`<img src=x onerror=alert(1)//`,
+		`<a href="https://e.com" onclick="alert(1)">`,
+		`<svg><script>alert(1)</script></svg>`,
+		`<svg/onload=alert(1)>`,
+		`<p title="</p><script>alert(1)</script>">x</p>`,
+		`<a href="javascript&colon;alert(1)">x</a>`,
+		`<!--><script>alert(1)</script>-->`,
+		`<math><mi xlink:href="javascript:alert(1)">x</mi></math>`,
+		`<?xml version="1.0"?><script>alert(1)</script>`,
+	}
+	for _, in := range inputs {
+		got := sanitizeHTML(in)
+		for _, tok := range tokenizeHTML(got) {
+			if _, ok := htmlAllowed[tok.Tag]; tok.Tag != "" && !ok {
+				t.Errorf("sanitizeHTML(%q) = %q, has a <%s> element", in, got, tok.Tag)
+			}
+			for _, attr := range tok.Attrs {
+				if strings.HasPrefix(attr.Key, "on") || strings.Contains(strings.ToLower(attr.Val), "javascript:") {
+					t.Errorf("sanitizeHTML(%q) = %q, has %s=%q", in, got, attr.Key, attr.Val)
+				}
+			}
+		}
+	}
+}
+
+// TestHTMLText tests the plain text shown for -html by backends without HTML
+func TestHTMLText(t *testing.T) {
+	in := `<h2>Maintenance   tonight</h2>
+<p>Save your work &amp; log off.<br>Details:</p>
+<ul><li>Start 22:00</li><li>End <b>23:00</b></li></ul>
+<script>alert(1)</script>`
+	want := "Maintenance tonight\nSave your work & log off.\nDetails:\n- Start 22:00\n- End 23:00"
+	if got := HTMLText(in); got != want {
+		t.Errorf("HTMLText() =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// icnsLegacy are the sizes of the RGB images of older .icns files, by type, and the types of
+// their 8-bit masks
+var icnsLegacy = map[string]struct {
+	side int
+	mask string
+}{
+	"is32": {16, "s8mk"},
+	"il32": {32, "l8mk"},
+	"ih32": {48, "h8mk"},
+	"it32": {128, "t8mk"},
+}
+
+// icnsImage is an image of an .icns file that can be decoded: PNG, or RGB with an optional mask
+type icnsImage struct {
+	side int // Pixels of the longest side
+	png  []byte
+	rgb  []byte
+	mask []byte
+}
+
+// readICNS returns the image of an .icns file with the most pixels; images in JPEG 2000, which
+// some icons of 256 pixels and more use, are skipped
+func readICNS(r io.Reader) (icnsImage, error) {
+	data, err := io.ReadAll(io.LimitReader(r, iconMaxFileSize+1))
+	if err != nil {
+		return icnsImage{}, err
+	}
+	if len(data) < 8 || string(data[:4]) != "icns" {
+		return icnsImage{}, errors.New("icns: not an icon file")
+	}
+	entries := map[string][]byte{}
+	for offset := 8; offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset+4:]))
+		if length < 8 || offset+length > len(data) {
+			break
+		}
+		entries[string(data[offset:offset+4])] = data[offset+8 : offset+length]
+		offset += length
+	}
+
+	var best icnsImage
+	for kind, entry := range entries {
+		candidate := icnsImage{}
+		if bytes.HasPrefix(entry, []byte(pngSignature)) {
+			config, err := png.DecodeConfig(bytes.NewReader(entry))
+			if err != nil {
+				continue
+			}
+			candidate = icnsImage{side: max(config.Width, config.Height), png: entry}
+		} else if legacy, ok := icnsLegacy[kind]; ok {
+			candidate = icnsImage{side: legacy.side, rgb: entry, mask: entries[legacy.mask]}
+		} else {
+			continue
+		}
+		if candidate.side > best.side || (candidate.side == best.side && candidate.png != nil) {
+			best = candidate
+		}
+	}
+	if best.side == 0 {
+		return icnsImage{}, errors.New("icns: no PNG or RGB images (JPEG 2000 is not supported)")
+	}
+	return best, nil
+}
+
+// decodeICNS decodes the best image of an .icns file
+func decodeICNS(r io.Reader) (image.Image, error) {
+	best, err := readICNS(r)
+	if err != nil {
+		return nil, err
+	}
+	if best.png != nil {
+		return png.Decode(bytes.NewReader(best.png))
+	}
+	return decodeICNSRGB(best.rgb, best.mask, best.side)
+}
+
+// decodeICNSConfig returns the size of the best image of an .icns file
+func decodeICNSConfig(r io.Reader) (image.Config, error) {
+	best, err := readICNS(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if best.png != nil {
+		return png.DecodeConfig(bytes.NewReader(best.png))
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: best.side, Height: best.side}, nil
+}
+
+// decodeICNSRGB decodes an RGB image of an older .icns file: the red, green and blue planes one
+// after the other, run-length encoded (it32 starts with four zero bytes), or interleaved when
+// uncompressed; mask is the alpha plane, opaque when missing
+func decodeICNSRGB(data, mask []byte, side int) (image.Image, error) {
+	pixels := side * side
+	var planes []byte
+	if len(data) == 3*pixels {
+		planes = make([]byte, 0, 3*pixels)
+		for channel := range 3 {
+			for i := range pixels {
+				planes = append(planes, data[3*i+channel])
+			}
+		}
+	} else {
+		if side == 128 && len(data) >= 4 && binary.BigEndian.Uint32(data) == 0 {
+			data = data[4:]
+		}
+		for len(data) > 0 && len(planes) < 3*pixels {
+			run := int(data[0])
+			if run < 0x80 {
+				if 1+run+1 > len(data) {
+					return nil, errors.New("icns: image data is truncated")
+				}
+				planes = append(planes, data[1:1+run+1]...)
+				data = data[1+run+1:]
+			} else {
+				if len(data) < 2 {
+					return nil, errors.New("icns: image data is truncated")
+				}
+				for range run - 125 {
+					planes = append(planes, data[1])
+				}
+				data = data[2:]
+			}
+		}
+	}
+	if len(planes) < 3*pixels {
+		return nil, errors.New("icns: image data is truncated")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, side, side))
+	for i := range pixels {
+		alpha := byte(0xff)
+		if len(mask) == pixels {
+			alpha = mask[i]
+		}
+		img.Pix[4*i], img.Pix[4*i+1], img.Pix[4*i+2], img.Pix[4*i+3] = planes[i], planes[pixels+i], planes[2*pixels+i], alpha
+	}
+	return img, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
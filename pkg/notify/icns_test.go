@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"testing"
+)
+
+// testICNS returns an .icns file of the entries, in order of type and data
+func testICNS(entries ...any) []byte {
+	var body bytes.Buffer
+	for i := 0; i < len(entries); i += 2 {
+		data := entries[i+1].([]byte)
+		body.WriteString(entries[i].(string))
+		binary.Write(&body, binary.BigEndian, uint32(8+len(data)))
+		body.Write(data)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("icns")
+	binary.Write(&buf, binary.BigEndian, uint32(8+body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// testICNSRLE returns the run-length encoded planes of a side x side image of c
+func testICNSRLE(side int, c color.NRGBA) []byte {
+	var data []byte
+	for _, value := range []byte{c.R, c.G, c.B} {
+		for left := side * side; left > 0; {
+			run := min(left, 130)
+			if run < 3 { // Runs repeat at least 3 times; shorter ones are copied
+				data = append(data, byte(run-1))
+				for range run {
+					data = append(data, value)
+				}
+			} else {
+				data = append(data, byte(run+125), value)
+			}
+			left -= run
+		}
+	}
+	return data
+}
+
+// TestDecodeICNS tests that the largest image of an .icns file is decoded, PNG or RGB
+func TestDecodeICNS(t *testing.T) {
+	red, green, blue := color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0xff, 0, 0xff}, color.NRGBA{0, 0, 0xff, 0xff}
+	mask := make([]byte, 32*32)
+	for i := range mask {
+		mask[i] = 0xff
+	}
+	mask[0] = 0
+
+	interleaved := make([]byte, 0, 3*16*16)
+	for range 16 * 16 {
+		interleaved = append(interleaved, green.R, green.G, green.B)
+	}
+
+	tests := []struct {
+		name   string
+		file   []byte
+		size   int
+		center color.NRGBA
+		masked bool
+	}{
+		{"PNG", testICNS("ic07", testPNG(128, blue), "ic04", testPNG(16, red)), 128, blue, false},
+		{"RLE with mask", testICNS("is32", testICNSRLE(16, red), "il32", testICNSRLE(32, green), "l8mk", mask), 32, green, true},
+		{"uncompressed", testICNS("is32", interleaved), 16, green, false},
+		{"it32 prefix", testICNS("it32", append([]byte{0, 0, 0, 0}, testICNSRLE(128, red)...), "ic05", testPNG(32, blue)), 128, red, false},
+		{"JPEG 2000 skipped", testICNS("ic10", []byte("\x00\x00\x00\x0cjP  \r\n\x87\n"), "ic07", testPNG(128, blue)), 128, blue, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, format, err := decodeIcon(bytes.NewReader(tt.file))
+			if err != nil || format != "icns" {
+				t.Fatalf("decodeIcon = %v, %q, want an icns image", err, format)
+			}
+			if b := img.Bounds(); b.Dx() != tt.size || b.Dy() != tt.size {
+				t.Errorf("Decoded %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.size, tt.size)
+			}
+			if got := color.NRGBAModel.Convert(img.At(tt.size/2, tt.size/2)); got != tt.center {
+				t.Errorf("Center is %v, want %v", got, tt.center)
+			}
+			if _, _, _, a := img.At(0, 0).RGBA(); (a == 0) != tt.masked {
+				t.Errorf("Top left alpha is %d, want masked %v", a, tt.masked)
+			}
+			config, _, err := decodeIconConfig(bytes.NewReader(tt.file))
+			if err != nil || config.Width != tt.size || config.Height != tt.size {
+				t.Errorf("decodeIconConfig = %dx%d, %v, want %dx%d", config.Width, config.Height, err, tt.size, tt.size)
+			}
+		})
+	}
+
+	for name, file := range map[string][]byte{
+		"no images":      testICNS(),
+		"only JPEG 2000": testICNS("ic10", []byte("\x00\x00\x00\x0cjP  \r\n\x87\n")),
+		"truncated RLE":  testICNS("is32", testICNSRLE(16, red)[:10]),
+	} {
+		if _, _, err := decodeIcon(bytes.NewReader(file)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
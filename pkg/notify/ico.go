@@ -0,0 +1,227 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// iconFormats are the Windows .ico and macOS .icns icons, decoded to the image with the most
+// pixels and handed to backends as a cached PNG (see prepareIcon); they are tried before the image
+// package, where a dependency registers an "ico" decoder that reads the first image instead
+var iconFormats = []struct {
+	name, magic  string
+	decode       func(io.Reader) (image.Image, error)
+	decodeConfig func(io.Reader) (image.Config, error)
+}{
+	{"ico", "\x00\x00\x01\x00", decodeICO, decodeICOConfig},
+	{"icns", "icns", decodeICNS, decodeICNSConfig},
+}
+
+// decodeIconConfig is image.DecodeConfig with the formats of iconFormats
+func decodeIconConfig(r io.ReadSeeker) (image.Config, string, error) {
+	for _, format := range iconFormats {
+		if hasMagic(r, format.magic) {
+			config, err := format.decodeConfig(r)
+			return config, format.name, err
+		}
+	}
+	return image.DecodeConfig(r)
+}
+
+// decodeIcon is image.Decode with the formats of iconFormats
+func decodeIcon(r io.ReadSeeker) (image.Image, string, error) {
+	for _, format := range iconFormats {
+		if hasMagic(r, format.magic) {
+			img, err := format.decode(r)
+			return img, format.name, err
+		}
+	}
+	return image.Decode(r)
+}
+
+// hasMagic reports whether r starts with magic, leaving r at its start
+func hasMagic(r io.ReadSeeker, magic string) bool {
+	head := make([]byte, len(magic))
+	n, _ := io.ReadFull(r, head)
+	r.Seek(0, io.SeekStart)
+	return string(head[:n]) == magic
+}
+
+// pngSignature starts PNG images, also inside .ico and .icns files
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// icoEntry is an image in the directory of an .ico file
+type icoEntry struct {
+	width, height int // 0 in the directory means 256
+	bitCount      int
+	data          []byte
+}
+
+// readICO returns the image of an .ico file with the most pixels, and most colors among those
+func readICO(r io.Reader) (icoEntry, error) {
+	data, err := io.ReadAll(io.LimitReader(r, iconMaxFileSize+1))
+	if err != nil {
+		return icoEntry{}, err
+	}
+	if len(data) < 6 || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return icoEntry{}, errors.New("ico: not an icon file")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	var best icoEntry
+	for i := range count {
+		if len(data) < 6+16*(i+1) {
+			return icoEntry{}, errors.New("ico: directory is truncated")
+		}
+		entry := data[6+16*i:]
+		width, height := int(entry[0]), int(entry[1])
+		if width == 0 {
+			width = 256
+		}
+		if height == 0 {
+			height = 256
+		}
+		size, offset := binary.LittleEndian.Uint32(entry[8:]), binary.LittleEndian.Uint32(entry[12:])
+		if uint64(offset)+uint64(size) > uint64(len(data)) {
+			continue
+		}
+		candidate := icoEntry{width, height, int(binary.LittleEndian.Uint16(entry[6:])), data[offset : offset+size]}
+		if pixels, bestPixels := width*height, best.width*best.height; pixels > bestPixels || (pixels == bestPixels && candidate.bitCount > best.bitCount) {
+			best = candidate
+		}
+	}
+	if best.data == nil {
+		return icoEntry{}, errors.New("ico: no images")
+	}
+	return best, nil
+}
+
+// decodeICO decodes the best image of an .ico file: PNG, or a Windows bitmap with its mask
+func decodeICO(r io.Reader) (image.Image, error) {
+	entry, err := readICO(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(entry.data, []byte(pngSignature)) {
+		return png.Decode(bytes.NewReader(entry.data))
+	}
+	return decodeICOBitmap(entry.data)
+}
+
+// decodeICOConfig returns the size of the best image of an .ico file
+func decodeICOConfig(r io.Reader) (image.Config, error) {
+	entry, err := readICO(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if bytes.HasPrefix(entry.data, []byte(pngSignature)) {
+		return png.DecodeConfig(bytes.NewReader(entry.data))
+	}
+	if len(entry.data) < 40 {
+		return image.Config{}, errors.New("ico: bitmap header is truncated")
+	}
+	width, height := int32(binary.LittleEndian.Uint32(entry.data[4:])), int32(binary.LittleEndian.Uint32(entry.data[8:]))
+	return image.Config{ColorModel: color.NRGBAModel, Width: int(width), Height: int(height / 2)}, nil
+}
+
+// decodeICOBitmap decodes the Windows bitmap of an .ico image: a BITMAPINFOHEADER, a palette of
+// up to 256 colors, the colors bottom-up and an AND mask of transparent pixels, which makes the
+// bitmap height in the header twice the image height
+func decodeICOBitmap(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errors.New("ico: bitmap header is truncated")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2
+	bitCount := int(binary.LittleEndian.Uint16(data[14:]))
+	compression := binary.LittleEndian.Uint32(data[16:])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:]))
+	if width <= 0 || height <= 0 || width > 1024 || height > 1024 || headerSize < 40 || headerSize > len(data) {
+		return nil, fmt.Errorf("ico: unsupported bitmap %dx%d", width, height)
+	}
+	if compression != 0 && !(compression == 3 && bitCount == 32) { // BI_RGB, or BI_BITFIELDS in BGRA order
+		return nil, fmt.Errorf("ico: unsupported bitmap compression %d", compression)
+	}
+	switch bitCount {
+	case 1, 4, 8, 24, 32:
+	default:
+		return nil, fmt.Errorf("ico: unsupported bitmap of %d bits per pixel", bitCount)
+	}
+
+	var palette []color.NRGBA
+	offset := headerSize
+	if bitCount <= 8 {
+		if colorsUsed == 0 {
+			colorsUsed = 1 << bitCount
+		}
+		if colorsUsed > 256 || offset+4*colorsUsed > len(data) {
+			return nil, errors.New("ico: palette is truncated")
+		}
+		for i := range colorsUsed {
+			c := data[offset+4*i:]
+			palette = append(palette, color.NRGBA{R: c[2], G: c[1], B: c[0], A: 0xff})
+		}
+		offset += 4 * colorsUsed
+	} else if compression == 3 && headerSize == 40 {
+		offset += 12 // The color masks after the header (later headers include them)
+	}
+
+	rowSize := (width*bitCount + 31) / 32 * 4
+	maskRowSize := (width + 31) / 32 * 4
+	masked := offset+height*(rowSize+maskRowSize) <= len(data)
+	if offset+height*rowSize > len(data) {
+		return nil, errors.New("ico: bitmap is truncated")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	anyAlpha := false
+	for y := range height {
+		row := data[offset+(height-1-y)*rowSize:]
+		for x := range width {
+			var c color.NRGBA
+			switch bitCount {
+			case 32:
+				c = color.NRGBA{R: row[4*x+2], G: row[4*x+1], B: row[4*x], A: row[4*x+3]}
+				anyAlpha = anyAlpha || c.A != 0
+			case 24:
+				c = color.NRGBA{R: row[3*x+2], G: row[3*x+1], B: row[3*x], A: 0xff}
+			default:
+				perByte := 8 / bitCount
+				index := int(row[x/perByte]>>(8-bitCount*(x%perByte+1))) & (1<<bitCount - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// 32-bit images carry their own alpha; older ones (and 32-bit ones without alpha) use the mask
+	if bitCount == 32 && anyAlpha {
+		return img, nil
+	}
+	for y := range height {
+		for x := range width {
+			transparent := false
+			if masked {
+				mask := data[offset+height*rowSize+(height-1-y)*maskRowSize:]
+				transparent = mask[x/8]&(0x80>>(x%8)) != 0
+			}
+			c := img.NRGBAAt(x, y)
+			c.A = 0xff
+			if transparent {
+				c.A = 0
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testICOBitmap returns a 24-bit bitmap of an .ico file, size x size pixels of c with the top
+// left pixel masked out
+func testICOBitmap(size int, c color.NRGBA) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header, 40)
+	binary.LittleEndian.PutUint32(header[4:], uint32(size))
+	binary.LittleEndian.PutUint32(header[8:], uint32(2*size))
+	binary.LittleEndian.PutUint16(header[12:], 1)
+	binary.LittleEndian.PutUint16(header[14:], 24)
+	buf.Write(header)
+	rowSize := (size*24 + 31) / 32 * 4
+	for range size {
+		row := make([]byte, rowSize)
+		for x := range size {
+			row[3*x], row[3*x+1], row[3*x+2] = c.B, c.G, c.R
+		}
+		buf.Write(row)
+	}
+	maskRowSize := (size + 31) / 32 * 4
+	for y := range size {
+		row := make([]byte, maskRowSize)
+		if y == size-1 { // Bottom-up: the last row is the top one
+			row[0] = 0x80
+		}
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
+// testICO returns an .ico file of the images, each with its width in the directory
+func testICO(images ...[]byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, uint16(len(images))})
+	offset := 6 + 16*len(images)
+	for _, data := range images {
+		width, _, _ := imageSize(data)
+		entry := make([]byte, 16)
+		entry[0], entry[1] = byte(width), byte(width) // 256 is written as 0
+		binary.LittleEndian.PutUint32(entry[8:], uint32(len(data)))
+		binary.LittleEndian.PutUint32(entry[12:], uint32(offset))
+		buf.Write(entry)
+		offset += len(data)
+	}
+	for _, data := range images {
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+// imageSize returns the width of a PNG or .ico bitmap test image
+func imageSize(data []byte) (int, int, error) {
+	if bytes.HasPrefix(data, []byte(pngSignature)) {
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		return config.Width, config.Height, err
+	}
+	return int(binary.LittleEndian.Uint32(data[4:])), int(binary.LittleEndian.Uint32(data[8:])) / 2, nil
+}
+
+// testPNG returns a size x size PNG of c
+func testPNG(size int, c color.NRGBA) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// TestDecodeICO tests that the largest image of an .ico file is decoded, bitmap or PNG
+func TestDecodeICO(t *testing.T) {
+	red, blue := color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0, 0xff, 0xff}
+	tests := []struct {
+		name   string
+		file   []byte
+		size   int
+		center color.NRGBA
+		masked bool
+	}{
+		{"largest bitmap", testICO(testICOBitmap(16, red), testICOBitmap(32, blue)), 32, blue, true},
+		{"PNG over bitmap", testICO(testICOBitmap(16, red), testPNG(48, blue)), 48, blue, false},
+		{"bitmap over PNG", testICO(testPNG(16, blue), testICOBitmap(24, red)), 24, red, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, format, err := decodeIcon(bytes.NewReader(tt.file))
+			if err != nil || format != "ico" {
+				t.Fatalf("decodeIcon = %v, %q, want an ico image", err, format)
+			}
+			if b := img.Bounds(); b.Dx() != tt.size || b.Dy() != tt.size {
+				t.Errorf("Decoded %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.size, tt.size)
+			}
+			if got := color.NRGBAModel.Convert(img.At(tt.size/2, tt.size/2)); got != tt.center {
+				t.Errorf("Center is %v, want %v", got, tt.center)
+			}
+			if _, _, _, a := img.At(0, 0).RGBA(); (a == 0) != tt.masked {
+				t.Errorf("Top left alpha is %d, want masked %v", a, tt.masked)
+			}
+			config, _, err := decodeIconConfig(bytes.NewReader(tt.file))
+			if err != nil || config.Width != tt.size || config.Height != tt.size {
+				t.Errorf("decodeIconConfig = %dx%d, %v, want %dx%d", config.Width, config.Height, err, tt.size, tt.size)
+			}
+		})
+	}
+
+	for name, file := range map[string][]byte{
+		"empty directory":   testICO(),
+		"truncated bitmap":  testICO(testICOBitmap(16, red))[:60],
+		"truncated listing": testICO(testICOBitmap(16, red))[:10],
+	} {
+		if _, _, err := decodeIcon(bytes.NewReader(file)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestPrepareIconICO tests that an .ico icon is converted to a cached PNG
+func TestPrepareIconICO(t *testing.T) {
+	dir := t.TempDir()
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache) // os.UserCacheDir on Linux
+	t.Setenv("HOME", cache)           // macOS
+	t.Setenv("LocalAppData", cache)   // Windows
+
+	path := filepath.Join(dir, "brand.ico")
+	if err := os.WriteFile(path, testICO(testICOBitmap(16, color.NRGBA{0xff, 0, 0, 0xff}), testPNG(64, color.NRGBA{0, 0, 0xff, 0xff})), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := prepareIcon(path)
+	if err != nil || !strings.HasPrefix(got, cache) || filepath.Ext(got) != ".png" {
+		t.Fatalf("prepareIcon = %q, %v, want a PNG in the cache", got, err)
+	}
+	data, err := os.ReadFile(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width, _, err := imageSize(data); err != nil || width != 64 {
+		t.Errorf("Cached PNG is %d wide (%v), want 64", width, err)
+	}
+	if again, err := prepareIcon(path); err != nil || again != got {
+		t.Errorf("prepareIcon again = %q, %v, want the cached %q", again, err, got)
+	}
+}
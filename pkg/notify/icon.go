@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif" // Icon decoders, with JPEG and PNG
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Icon limits: an icon is shown at 64x64, so anything much larger only costs memory and rendering time
+const (
+	iconMaxSide     = 256              // Longest side of the image handed to a backend (4x for HiDPI screens)
+	iconMaxPixels   = 64 * 1000 * 1000 // Larger images are rejected before decoding (64 megapixels)
+	iconMaxFileSize = 50 << 20         // Larger files are rejected before reading (50 MB)
+)
+
+// prepareIcon validates the icon at iconPath (resolved like resolveIconPath) and returns the absolute
+// path of an image backends can load cheaply: the file itself when it is at most iconMaxSide pixels,
+// otherwise a downscaled PNG cached per user, so the full image is only decoded once
+// Animated GIFs stay animated (see prepareAnimatedGIF)
+// SVG icons are rasterized (see prepareSVGIcon), and .ico and .icns icons are converted to a
+// cached PNG of their largest image; other formats Go cannot decode (BMP, WebP) are passed through
+// for the backend to load
+// Missing, corrupt and gigantic images return an error saying why
+func prepareIcon(iconPath string) (string, error) {
+	path, err := filepath.Abs(resolveIconPath(iconPath))
+	if err != nil {
+		return "", fmt.Errorf("could not resolve icon path %s: %v", iconPath, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("icon file not found: %s", path)
+	}
+	if info.Size() > iconMaxFileSize {
+		return "", fmt.Errorf("icon %s is %d MB, larger than the %d MB limit", path, info.Size()>>20, iconMaxFileSize>>20)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("could not open icon: %v", err)
+	}
+	defer file.Close()
+
+	// SVG has no pixels of its own: it is drawn at the size of the largest icon
+	head := make([]byte, 512)
+	read, _ := file.Read(head)
+	if isSVGIcon(path, head[:read]) {
+		return prepareSVGIcon(path, info)
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return "", fmt.Errorf("could not read icon: %v", err)
+	}
+
+	// The header gives the dimensions without decoding the pixels
+	config, format, err := decodeIconConfig(file)
+	if errors.Is(err, image.ErrFormat) {
+		log.Printf("Icon %s is not PNG, JPEG, GIF, ICO or ICNS, passing it to the backend unchanged", path)
+		return path, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("icon %s is corrupt: %v", path, err)
+	}
+	if pixels := config.Width * config.Height; pixels > iconMaxPixels || config.Width <= 0 || config.Height <= 0 {
+		return "", fmt.Errorf("icon %s is %dx%d (%.0f megapixels), larger than the %d megapixel limit",
+			path, config.Width, config.Height, float64(pixels)/1e6, iconMaxPixels/1000/1000)
+	}
+
+	// Animated GIFs keep their frames: scaled down as a GIF, not as a PNG of the first frame
+	if format == "gif" {
+		if _, err := file.Seek(0, 0); err != nil {
+			return "", fmt.Errorf("could not read icon: %v", err)
+		}
+		if anim, err := gif.DecodeAll(file); err == nil && len(anim.Image) > 1 {
+			return prepareAnimatedGIF(path, info, anim)
+		}
+	}
+
+	// Windows and macOS icons are converted to PNG, which every backend can show
+	converted := format == "ico" || format == "icns"
+	cachePath := iconCachePath(path, info)
+	if converted || config.Width > iconMaxSide || config.Height > iconMaxSide {
+		if _, err := os.Stat(cachePath); err == nil {
+			log.Printf("Using cached downscaled or converted icon for %s", path)
+			touchCachedIcon(cachePath)
+			return cachePath, nil
+		}
+	}
+
+	// Decode fully even when small enough, so corrupt pixel data is caught here and not by the backend
+	if _, err := file.Seek(0, 0); err != nil {
+		return "", fmt.Errorf("could not read icon: %v", err)
+	}
+	img, _, err := decodeIcon(file)
+	if err != nil {
+		return "", fmt.Errorf("icon %s is corrupt: %v", path, err)
+	}
+	if config.Width <= iconMaxSide && config.Height <= iconMaxSide {
+		if !converted {
+			return path, nil
+		}
+		log.Printf("Icon %s (%s, %dx%d) converted to PNG", path, format, config.Width, config.Height)
+		if err := writeCachedIcon(cachePath, img); err != nil {
+			return "", err
+		}
+		return cachePath, nil
+	}
+
+	scaled := scaleThumbnail(img, iconMaxSide)
+	log.Printf("Icon %s (%s, %dx%d) scaled to %dx%d", path, format, config.Width, config.Height, scaled.Bounds().Dx(), scaled.Bounds().Dy())
+	if err := writeCachedIcon(cachePath, scaled); err != nil {
+		return "", err
+	}
+	return cachePath, nil
+}
+
+// iconCachePath returns where the downscaled copy of the icon at path is cached; the key
+// includes the size and modification time, so a replaced icon is scaled again
+func iconCachePath(path string, info os.FileInfo) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%d", path, info.Size(), info.ModTime().UnixNano(), iconMaxSide)))
+	return filepath.Join(iconCacheDir(), hex.EncodeToString(sum[:16])+".png")
+}
+
+// writeCachedIcon saves img as PNG at cachePath and prunes the icon cache
+func writeCachedIcon(cachePath string, img image.Image) error {
+	return writeCached(cachePath, func(w io.Writer) error { return png.Encode(w, img) })
+}
+
+// writeCached saves the image encode writes at cachePath and prunes the icon cache (see
+// pruneIconCache)
+func writeCached(cachePath string, encode func(io.Writer) error) error {
+	dir := filepath.Dir(cachePath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("could not create icon cache: %v", err)
+	}
+	tmp, err := os.CreateTemp(dir, "icon-*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not write icon cache: %v", err)
+	}
+	if err := encode(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not encode icon: %v", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), cachePath); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write icon cache: %v", err)
+	}
+	pruneIconCache(dir, cachePath, time.Now())
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
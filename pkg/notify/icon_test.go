@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestPNG writes a width x height PNG into dir and returns its path
+func writeTestPNG(t *testing.T, dir, name string, width, height int) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestPrepareIcon tests that small icons are used as they are, large ones are downscaled
+// once and cached, and corrupt or gigantic ones are rejected with the reason
+func TestPrepareIcon(t *testing.T) {
+	dir := t.TempDir()
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache) // os.UserCacheDir on Linux
+	t.Setenv("HOME", cache)           // macOS
+	t.Setenv("LocalAppData", cache)   // Windows
+
+	small := writeTestPNG(t, dir, "small.png", 64, 64)
+	if got, err := prepareIcon(small); err != nil || got != small {
+		t.Errorf("prepareIcon(small) = %q, %v, want %q", got, err, small)
+	}
+
+	large := writeTestPNG(t, dir, "large.png", 1000, 500)
+	scaled, err := prepareIcon(large)
+	if err != nil {
+		t.Fatalf("prepareIcon(large) failed: %v", err)
+	}
+	if scaled == large || !strings.HasPrefix(scaled, cache) {
+		t.Errorf("prepareIcon(large) = %q, want a path in the cache %s", scaled, cache)
+	}
+	file, err := os.Open(scaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := png.DecodeConfig(file)
+	file.Close()
+	if err != nil || config.Width != 256 || config.Height != 128 {
+		t.Errorf("downscaled icon is %dx%d (%v), want 256x128", config.Width, config.Height, err)
+	}
+	if again, err := prepareIcon(large); err != nil || again != scaled {
+		t.Errorf("second prepareIcon(large) = %q, %v, want the cached %q", again, err, scaled)
+	}
+
+	data, err := os.ReadFile(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := filepath.Join(dir, "corrupt.png")
+	os.WriteFile(corrupt, data[:len(data)/2], 0600)
+	if _, err := prepareIcon(corrupt); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("prepareIcon(corrupt) error = %v, want corrupt", err)
+	}
+
+	// Claim 100000x100000 in the header (IHDR width and height follow the 8 byte signature
+	// and the chunk length and type), fixing the chunk CRC so only the size is wrong
+	huge := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(huge[16:], 100000)
+	binary.BigEndian.PutUint32(huge[20:], 100000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	hugePath := filepath.Join(dir, "huge.png")
+	os.WriteFile(hugePath, huge, 0600)
+	if _, err := prepareIcon(hugePath); err == nil || !strings.Contains(err.Error(), "megapixel limit") {
+		t.Errorf("prepareIcon(huge) error = %v, want megapixel limit", err)
+	}
+
+	svg := filepath.Join(dir, "icon.svg")
+	os.WriteFile(svg, []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24"><circle cx="12" cy="12" r="10"/></svg>`), 0600)
+	if got, err := prepareIcon(svg); err != nil || !strings.HasPrefix(got, cache) || filepath.Ext(got) != ".png" {
+		t.Errorf("prepareIcon(svg) = %q, %v, want a rasterized PNG in the cache", got, err)
+	}
+
+	if _, err := prepareIcon(filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("prepareIcon(missing) succeeded")
+	}
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the icon cache: icons unused for a month are removed, the least recently used
+// ones beyond 100 MB too, and downloaded icons are downloaded again after a day, so a replaced
+// logo shows up without clearing the cache
+const (
+	DefaultIconCacheMaxAge   = 30 * 24 * time.Hour
+	DefaultIconCacheMaxBytes = 100 << 20
+	DefaultIconCacheRefresh  = 24 * time.Hour
+)
+
+// IconCache says where the icons notify downloads, converts and scales are cached, and how long
+// and how much of them is kept; the zero value is the defaults, in the user cache directory
+type IconCache struct {
+	Dir      string        // Empty for krankybearnotify/icons in the user cache directory
+	MaxAge   time.Duration // Icons unused this long are removed, 0 for DefaultIconCacheMaxAge
+	MaxBytes int64         // The least recently used icons beyond this are removed, 0 for DefaultIconCacheMaxBytes
+	Refresh  time.Duration // Downloaded icons older than this are downloaded again, 0 for DefaultIconCacheRefresh
+}
+
+var (
+	iconCacheMu     sync.Mutex
+	iconCacheConfig IconCache
+)
+
+// SetIconCache configures the icon cache of this process, before notifications are sent; each
+// user's notify process, when running as root/SYSTEM, uses the cache its own config sets
+func SetIconCache(c IconCache) {
+	iconCacheMu.Lock()
+	defer iconCacheMu.Unlock()
+	iconCacheConfig = c
+}
+
+// currentIconCache returns the icon cache configuration with the defaults filled in
+func currentIconCache() IconCache {
+	iconCacheMu.Lock()
+	c := iconCacheConfig
+	iconCacheMu.Unlock()
+	if c.Dir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		c.Dir = filepath.Join(dir, "krankybearnotify", "icons")
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = DefaultIconCacheMaxAge
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = DefaultIconCacheMaxBytes
+	}
+	if c.Refresh <= 0 {
+		c.Refresh = DefaultIconCacheRefresh
+	}
+	return c
+}
+
+// iconCacheDir returns the directory of cached icons: downloaded, inline, built-in, rasterized,
+// converted and downscaled ones alike
+func iconCacheDir() string {
+	return currentIconCache().Dir
+}
+
+// touchCachedIcon marks the cached icon at path as used, so it is the last to be evicted
+// Downloaded, inline and built-in icons are not touched: their modification time says when a
+// download is refreshed, and is part of the key of their rasterized or downscaled copies
+func touchCachedIcon(path string) {
+	now := time.Now()
+	os.Chtimes(path, now, now)
+}
+
+// pruneIconCache removes the icons in dir unused for MaxAge, then the least recently used ones
+// until the rest fit in MaxBytes; keep, the icon just cached, is never removed
+func pruneIconCache(dir, keep string, now time.Time) {
+	c := currentIconCache()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	type cachedIcon struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var icons []cachedIcon
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			// Being written by another notify process, or left behind by one that died doing so
+			if now.Sub(info.ModTime()) > time.Hour {
+				os.Remove(path)
+			}
+			continue
+		}
+		if now.Sub(info.ModTime()) > c.MaxAge && path != keep {
+			os.Remove(path)
+			continue
+		}
+		icons = append(icons, cachedIcon{path, info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	if total <= c.MaxBytes {
+		return
+	}
+	sort.Slice(icons, func(i, j int) bool { return icons[i].used.Before(icons[j].used) })
+	for _, icon := range icons {
+		if total <= c.MaxBytes {
+			break
+		}
+		if icon.path == keep {
+			continue
+		}
+		if err := os.Remove(icon.path); err == nil {
+			total -= icon.size
+		}
+	}
+	if total > c.MaxBytes {
+		log.Printf("Icon cache %s is %d KB, over its %d KB limit with only the newest icon left", dir, total>>10, c.MaxBytes>>10)
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestPruneIconCache tests that icons unused for MaxAge are removed, then the least recently used
+// ones until the cache fits in MaxBytes, but never the icon just cached
+func TestPruneIconCache(t *testing.T) {
+	dir := t.TempDir()
+	SetIconCache(IconCache{Dir: dir, MaxAge: 10 * 24 * time.Hour, MaxBytes: 3000})
+	defer SetIconCache(IconCache{})
+
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, now.Add(-age), now.Add(-age))
+		return path
+	}
+	expired := write("expired.png", 10, 11*24*time.Hour)
+	oldest := write("oldest.png", 1000, 5*24*time.Hour)
+	older := write("older.png", 1000, 4*24*time.Hour)
+	recent := write("recent.png", 1000, time.Hour)
+	writing := write("icon-1.tmp", 1000, time.Minute)
+	abandoned := write("icon-2.tmp", 1000, 2*time.Hour)
+	kept := write("new.png", 500, 0)
+
+	pruneIconCache(dir, kept, now)
+	for path, want := range map[string]bool{expired: false, oldest: false, older: true, recent: true, writing: true, abandoned: false, kept: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s kept = %v, want %v", filepath.Base(path), err == nil, want)
+		}
+	}
+
+	// An icon larger than the whole cache stays, as the one just cached
+	huge := write("huge.png", 5000, 0)
+	pruneIconCache(dir, huge, now)
+	if _, err := os.Stat(huge); err != nil {
+		t.Errorf("Expected the icon just cached to stay: %v", err)
+	}
+	if _, err := os.Stat(recent); err == nil {
+		t.Error("Expected the other icons to be evicted for it")
+	}
+
+	if got := iconCacheDir(); got != dir {
+		t.Errorf("iconCacheDir() = %q, want the configured %q", got, dir)
+	}
+	if c := currentIconCache(); c.Refresh != DefaultIconCacheRefresh {
+		t.Errorf("Refresh = %v, want the default %v", c.Refresh, DefaultIconCacheRefresh)
+	}
+}
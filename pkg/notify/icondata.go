@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DecodeIconData returns the image of an -icon-data value: base64, standard or URL-safe, padded
+// or not, optionally wrapped over several lines or given as a data: URL
+// ("data:image/png;base64,iVBOR...")
+func DecodeIconData(data string) ([]byte, error) {
+	if strings.HasPrefix(data, "data:") {
+		_, payload, ok := strings.Cut(data, ";base64,")
+		if !ok {
+			return nil, errors.New("invalid -icon-data: a data: URL must be base64 (data:image/png;base64,...)")
+		}
+		data = payload
+	}
+	data = strings.Join(strings.Fields(data), "")
+	if len(data) > base64.StdEncoding.EncodedLen(iconMaxFileSize) {
+		return nil, fmt.Errorf("-icon-data is larger than the %d MB limit", iconMaxFileSize>>20)
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(data); err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, errors.New("invalid -icon-data: not base64")
+}
+
+// ValidateIconData checks the -icon-data flag value: base64 of an image
+func ValidateIconData(data string) error {
+	if data == "" {
+		return nil
+	}
+	decoded, err := DecodeIconData(data)
+	if err != nil {
+		return err
+	}
+	_, err = iconDataExtension(decoded)
+	return err
+}
+
+// iconDataExtension returns the file extension of an inline icon by its content, for backends that
+// pick the decoder by extension
+func iconDataExtension(data []byte) (string, error) {
+	if isSVGIcon("", data) {
+		return ".svg", nil
+	}
+	if strings.HasPrefix(string(data), "icns") { // Not known to http.DetectContentType
+		return ".icns", nil
+	}
+	mediaType := http.DetectContentType(data)
+	if ext, ok := iconExtensions[mediaType]; ok {
+		return ext, nil
+	}
+	return "", fmt.Errorf("invalid -icon-data: %s, not an image", mediaType)
+}
+
+// withIconData returns n with IconPath set to its IconData written to the per-user icon cache, so
+// every backend loads it like an -icon file; an IconPath of its own takes precedence
+func (n Notification) withIconData() Notification {
+	if n.IconData == "" || n.IconPath != "" {
+		return n
+	}
+	path, err := writeIconData(n.IconData, iconCacheDir())
+	if err != nil {
+		log.Printf("Warning: %v, showing the notification without it", err)
+		return n
+	}
+	n.IconPath = path
+	return n
+}
+
+// writeIconData returns the file of the inline icon data in dir, named by its content so the same
+// icon is written (and rasterized or downscaled) only once
+func writeIconData(data, dir string) (string, error) {
+	decoded, err := DecodeIconData(data)
+	if err != nil {
+		return "", err
+	}
+	ext, err := iconDataExtension(decoded)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(decoded)
+	iconPath := filepath.Join(dir, "data-"+hex.EncodeToString(sum[:16])+ext)
+	if _, err := os.Stat(iconPath); err == nil {
+		return iconPath, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("could not create icon cache: %v", err)
+	}
+	tmp, err := os.CreateTemp(dir, "icon-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("could not write icon cache: %v", err)
+	}
+	_, err = tmp.Write(decoded)
+	tmp.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), iconPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("could not write icon cache: %v", err)
+	}
+	pruneIconCache(dir, iconPath, time.Now())
+	return iconPath, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDecodeIconData tests the base64 forms -icon-data accepts, and rejecting text that is not an image
+func TestDecodeIconData(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 16, 16)))
+	icon := buf.Bytes()
+	encoded := base64.StdEncoding.EncodeToString(icon)
+
+	for name, data := range map[string]string{
+		"standard": encoded,
+		"raw":      strings.TrimRight(encoded, "="),
+		"url-safe": base64.URLEncoding.EncodeToString(icon),
+		"wrapped":  encoded[:20] + "\n" + encoded[20:40] + "\r\n" + encoded[40:],
+		"data url": "data:image/png;base64," + encoded,
+	} {
+		got, err := DecodeIconData(data)
+		if err != nil || !bytes.Equal(got, icon) {
+			t.Errorf("%s: DecodeIconData = %d bytes, %v, want the PNG", name, len(got), err)
+		}
+	}
+	if ValidateIconData(encoded) != nil {
+		t.Error("A PNG should be valid -icon-data")
+	}
+	if ValidateIconData(base64.StdEncoding.EncodeToString([]byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`))) != nil {
+		t.Error("An SVG should be valid -icon-data")
+	}
+	for _, bad := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("echo hello")), "data:text/plain,hello"} {
+		if ValidateIconData(bad) == nil {
+			t.Errorf("ValidateIconData(%q) should fail", bad)
+		}
+	}
+}
+
+// TestWriteIconData tests that inline icons are written to the cache by content, with the extension
+// of their type
+func TestWriteIconData(t *testing.T) {
+	dir := t.TempDir()
+	svg := base64.StdEncoding.EncodeToString([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"/>`))
+	path, err := writeIconData(svg, dir)
+	if err != nil || filepath.Dir(path) != dir || filepath.Ext(path) != ".svg" {
+		t.Fatalf("writeIconData = %q, %v, want an .svg in the cache", path, err)
+	}
+	if again, err := writeIconData("data:image/svg+xml;base64,"+svg, dir); err != nil || again != path {
+		t.Errorf("The same icon should have the same file, got %q, %v", again, err)
+	}
+
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache) // os.UserCacheDir on Linux
+	t.Setenv("HOME", cache)           // macOS
+	t.Setenv("LocalAppData", cache)   // Windows
+	n := Notification{IconData: svg, BuiltinIcon: "warning"}
+	if got := n.withIconData(); !strings.HasPrefix(got.IconPath, cache) || !strings.HasPrefix(filepath.Base(got.IconPath), "data-") {
+		t.Errorf("Expected -icon-data to be used before the -builtin-icon, got %q", got.IconPath)
+	}
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// iconFetchTimeout is how long a slow intranet server can delay the window with an icon download
+const iconFetchTimeout = 10 * time.Second
+
+// iconExtensions are the file extensions of downloaded icons by Content-Type, for backends that
+// pick the decoder by extension
+var iconExtensions = map[string]string{
+	"image/png":                ".png",
+	"image/jpeg":               ".jpg",
+	"image/gif":                ".gif",
+	"image/svg+xml":            ".svg",
+	"image/bmp":                ".bmp",
+	"image/webp":               ".webp",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+	"image/icns":               ".icns",
+	"image/x-icns":             ".icns",
+}
+
+// IsIconURL reports whether an -icon value is an http(s) URL to download rather than a file
+func IsIconURL(icon string) bool {
+	lower := strings.ToLower(icon)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// ValidateIconURL checks an -icon URL: http or https with a host
+func ValidateIconURL(icon string) error {
+	u, err := url.Parse(icon)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid -icon URL %q", icon)
+	}
+	return nil
+}
+
+// withDownloadedIcon returns n with an IconPath URL replaced by the downloaded file, cached per
+// user; when the download fails, a copy cached earlier is used, else no icon
+func (n Notification) withDownloadedIcon() Notification {
+	if !IsIconURL(n.IconPath) {
+		return n
+	}
+	path, err := downloadIcon(n.IconPath, iconCacheDir(), time.Now())
+	if err != nil {
+		log.Printf("Warning: %v, showing the notification without it", err)
+		path = ""
+	}
+	n.IconPath = path
+	return n
+}
+
+// downloadIcon returns the file of the icon at rawURL in dir: the cached copy when younger than
+// the icon cache Refresh, else a fresh download, else the stale copy
+func downloadIcon(rawURL, dir string, now time.Time) (string, error) {
+	sum := sha256.Sum256([]byte(rawURL))
+	prefix := filepath.Join(dir, "url-"+hex.EncodeToString(sum[:16]))
+	cached, _ := filepath.Glob(prefix + ".*")
+	if len(cached) > 0 {
+		if info, err := os.Stat(cached[0]); err == nil && now.Sub(info.ModTime()) < currentIconCache().Refresh {
+			log.Printf("Using cached icon for %s", rawURL)
+			return cached[0], nil
+		}
+	}
+
+	path, err := fetchIcon(rawURL, prefix)
+	if err != nil {
+		if len(cached) > 0 {
+			log.Printf("Warning: %v, using the copy cached earlier", err)
+			return cached[0], nil
+		}
+		return "", err
+	}
+	for _, old := range cached {
+		if old != path {
+			os.Remove(old)
+		}
+	}
+	pruneIconCache(dir, path, now)
+	log.Printf("Downloaded icon %s to %s", rawURL, path)
+	return path, nil
+}
+
+// fetchIcon downloads the icon at rawURL to prefix plus the extension of its type, at most
+// iconMaxFileSize
+func fetchIcon(rawURL, prefix string) (string, error) {
+	client := &http.Client{Timeout: iconFetchTimeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("could not download icon: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not download icon %s: %s", rawURL, resp.Status)
+	}
+	if resp.ContentLength > iconMaxFileSize {
+		return "", fmt.Errorf("icon %s is %d MB, larger than the %d MB limit", rawURL, resp.ContentLength>>20, iconMaxFileSize>>20)
+	}
+
+	ext := strings.ToLower(path.Ext(resp.Request.URL.Path))
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		if typed, ok := iconExtensions[mediaType]; ok {
+			ext = typed
+		} else if strings.HasPrefix(mediaType, "text/") {
+			return "", fmt.Errorf("could not download icon %s: the server sent %s, not an image", rawURL, mediaType)
+		}
+	}
+	if len(ext) < 2 || len(ext) > 5 {
+		ext = ".png" // Decoders of PNG, JPEG and GIF look at the content, not the name
+	}
+
+	if err := os.MkdirAll(filepath.Dir(prefix), 0700); err != nil {
+		return "", fmt.Errorf("could not create icon cache: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(prefix), "icon-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("could not write icon cache: %v", err)
+	}
+	written, err := io.Copy(tmp, io.LimitReader(resp.Body, iconMaxFileSize+1))
+	tmp.Close()
+	if err == nil && written > iconMaxFileSize {
+		err = fmt.Errorf("larger than the %d MB limit", iconMaxFileSize>>20)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("could not download icon %s: %v", rawURL, err)
+	}
+	if err := os.Rename(tmp.Name(), prefix+ext); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("could not write icon cache: %v", err)
+	}
+	return prefix + ext, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
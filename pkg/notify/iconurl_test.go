@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDownloadIcon tests downloading an icon, reusing the cached copy for a day, and falling
+// back to the stale copy when the server is down
+func TestDownloadIcon(t *testing.T) {
+	requests, down := 0, false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case down:
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+		case r.URL.Path == "/login":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>Sign in</html>"))
+		default:
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte("<svg/>"))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	now := time.Now()
+	path, err := downloadIcon(server.URL+"/logo", dir, now)
+	if err != nil || filepath.Ext(path) != ".svg" || filepath.Dir(path) != dir {
+		t.Fatalf("downloadIcon = %q, %v, want an .svg in the cache", path, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "<svg/>" {
+		t.Errorf("Downloaded %q", data)
+	}
+
+	if again, err := downloadIcon(server.URL+"/logo", dir, now.Add(time.Hour)); err != nil || again != path || requests != 1 {
+		t.Errorf("Within a day expected the cached copy without a request, got %q, %v after %d requests", again, err, requests)
+	}
+
+	down = true
+	if stale, err := downloadIcon(server.URL+"/logo", dir, now.Add(2*DefaultIconCacheRefresh)); err != nil || stale != path || requests != 2 {
+		t.Errorf("Expected the stale copy after a failed download, got %q, %v after %d requests", stale, err, requests)
+	}
+	if _, err := downloadIcon(server.URL+"/other.png", dir, now); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the server error without a cached copy, got %v", err)
+	}
+
+	down = false
+	if _, err := downloadIcon(server.URL+"/login", dir, now); err == nil || !strings.Contains(err.Error(), "not an image") {
+		t.Errorf("Expected a login page to be rejected, got %v", err)
+	}
+}
+
+// TestIconURL tests telling -icon URLs from files
+func TestIconURL(t *testing.T) {
+	for icon, want := range map[string]bool{
+		"https://intranet/logo.png": true,
+		"HTTP://intranet/logo":      true,
+		"/opt/agent/logo.png":       false,
+		"C:\\agent\\logo.png":       false,
+		"logo.png":                  false,
+	} {
+		if got := IsIconURL(icon); got != want {
+			t.Errorf("IsIconURL(%q) = %v, want %v", icon, got, want)
+		}
+	}
+	if ValidateIconURL("https:///logo.png") == nil {
+		t.Error("An icon URL without a host should fail")
+	}
+	if n := (Notification{IconPath: "/opt/agent/logo.png"}).withDownloadedIcon(); n.IconPath != "/opt/agent/logo.png" {
+		t.Errorf("A file icon should be kept, got %q", n.IconPath)
+	}
+}
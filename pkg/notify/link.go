@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// linkPattern finds http(s) links in notification text
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"']+`)
+
+// messageLink is a part of a message: plain text, or a link when URL is set
+type messageLink struct {
+	Text string
+	URL  string
+}
+
+// splitLinks splits text into plain parts and the http(s) links in it, leaving punctuation that
+// ends a sentence ("see https://example.com/kb.") out of the link
+func splitLinks(text string) []messageLink {
+	var parts []messageLink
+	last := 0
+	for _, span := range linkPattern.FindAllStringIndex(text, -1) {
+		link := strings.TrimRight(text[span[0]:span[1]], ".,;:!?)")
+		if span[0] > last {
+			parts = append(parts, messageLink{Text: text[last:span[0]]})
+		}
+		parts = append(parts, messageLink{Text: link, URL: link})
+		last = span[0] + len(link)
+	}
+	if last < len(text) {
+		parts = append(parts, messageLink{Text: text[last:]})
+	}
+	return parts
+}
+
+// linkifyHTML escapes text for an HTML page, with its http(s) links made clickable
+func linkifyHTML(text string) string {
+	var sb strings.Builder
+	for _, part := range splitLinks(text) {
+		if part.URL == "" {
+			sb.WriteString(html.EscapeString(part.Text))
+		} else {
+			fmt.Fprintf(&sb, `<a href="%s">%s</a>`, html.EscapeString(part.URL), html.EscapeString(part.Text))
+		}
+	}
+	return sb.String()
+}
+
+// ValidateLink checks a -link value: an absolute http or https URL
+func ValidateLink(link string) error {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid link %q (use an http or https URL)", link)
+	}
+	return nil
+}
+
+// PrimaryLink returns the link a click on a toast opens: n.Link, or else the first link in the message
+func (n Notification) PrimaryLink() string {
+	if n.Link != "" {
+		return n.Link
+	}
+	for _, part := range splitLinks(n.Message) {
+		if part.URL != "" {
+			return part.URL
+		}
+	}
+	return ""
+}
+
+// plainMessage returns the message for backends that can only show text, with the digest
+// items, n.Link and the -otp code added so the user can still see (and copy) them
+func (n Notification) plainMessage() string {
+	message := n.Message
+	if items := n.DigestList(); len(items) > 0 {
+		message += "\n\n- " + strings.Join(items, "\n- ")
+	}
+	if n.Link != "" && !strings.Contains(n.Message, n.Link) {
+		message += "\n\n" + n.Link
+	}
+	if line := n.otpLine(); line != "" {
+		message += "\n\n" + line
+	}
+	return message
+}
+
+// OpenLink opens a link clicked in the notification in the default browser (or mail client)
+func OpenLink(link string) {
+	link, ok := safeHTMLURL(link, "http", "https", "mailto")
+	if !ok {
+		log.Printf("Not opening link %q: only http, https and mailto links are opened", link)
+		return
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+	case "darwin":
+		cmd = exec.Command("open", link)
+	default:
+		cmd = exec.Command("xdg-open", link)
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("Could not open link %s: %v", link, err)
+		return
+	}
+	go cmd.Wait()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
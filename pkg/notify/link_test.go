@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSplitLinks tests finding links in a message, without the punctuation that ends a sentence
+func TestSplitLinks(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []messageLink
+	}{
+		{"No links here", []messageLink{{Text: "No links here"}}},
+		{"https://example.com", []messageLink{{Text: "https://example.com", URL: "https://example.com"}}},
+		{"See https://example.com/kb/42.", []messageLink{
+			{Text: "See "}, {Text: "https://example.com/kb/42", URL: "https://example.com/kb/42"}, {Text: "."}}},
+		{"(details: HTTP://example.com/a?b=1), then reboot", []messageLink{
+			{Text: "(details: "}, {Text: "HTTP://example.com/a?b=1", URL: "HTTP://example.com/a?b=1"}, {Text: "), then reboot"}}},
+		{"ftp://example.com and javascript:alert(1)", []messageLink{{Text: "ftp://example.com and javascript:alert(1)"}}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitLinks(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitLinks(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestLinkifyHTML tests that message text is escaped and its links made clickable
+func TestLinkifyHTML(t *testing.T) {
+	in := `<b>Save</b> & see https://example.com/?a=1&b="2"`
+	want := `&lt;b&gt;Save&lt;/b&gt; &amp; see <a href="https://example.com/?a=1&amp;b=">https://example.com/?a=1&amp;b=</a>&#34;2&#34;`
+	if got := linkifyHTML(in); got != want {
+		t.Errorf("linkifyHTML(%q)\n got  %q\n want %q", in, got, want)
+	}
+}
+
+// TestValidateLink tests the -link values accepted
+func TestValidateLink(t *testing.T) {
+	for _, link := range []string{"https://example.com", "http://intranet/kb?id=42"} {
+		if err := ValidateLink(link); err != nil {
+			t.Errorf("ValidateLink(%q) failed: %v", link, err)
+		}
+	}
+	for _, link := range []string{"example.com", "/kb/42", "javascript:alert(1)", "file:///etc/passwd", "https://", "mailto:it@example.com"} {
+		if err := ValidateLink(link); err == nil {
+			t.Errorf("ValidateLink(%q) accepted an invalid link", link)
+		}
+	}
+}
+
+// TestPrimaryLinkAndPlainMessage tests the link toasts open and the text shown by backends without links
+func TestPrimaryLinkAndPlainMessage(t *testing.T) {
+	tests := []struct {
+		n           Notification
+		wantLink    string
+		wantMessage string
+	}{
+		{Notification{Message: "Reboot tonight"}, "", "Reboot tonight"},
+		{Notification{Message: "See https://example.com/a and https://example.com/b"}, "https://example.com/a", "See https://example.com/a and https://example.com/b"},
+		{Notification{Message: "See https://example.com/a", Link: "https://example.com/kb"}, "https://example.com/kb", "See https://example.com/a\n\nhttps://example.com/kb"},
+		{Notification{Message: "Details at https://example.com/kb", Link: "https://example.com/kb"}, "https://example.com/kb", "Details at https://example.com/kb"},
+		{Notification{Message: "2 notifications", DigestItems: "Backup failed\n\n Disk full "}, "", "2 notifications\n\n- Backup failed\n- Disk full"},
+	}
+	for _, tt := range tests {
+		if got := tt.n.PrimaryLink(); got != tt.wantLink {
+			t.Errorf("PrimaryLink() for %q = %q, want %q", tt.n.Message, got, tt.wantLink)
+		}
+		if got := tt.n.plainMessage(); got != tt.wantMessage {
+			t.Errorf("plainMessage() for %q = %q, want %q", tt.n.Message, got, tt.wantMessage)
+		}
+	}
+}
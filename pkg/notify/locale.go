@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the language of the built-in UI strings when the session's is not translated
+const DefaultLocale = "en"
+
+// localeFiles are the translations of the built-in UI strings, one JSON object of key to
+// fmt format per language (en.json has every key)
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// localePattern matches a locale as given to -locale or found in the environment: a language,
+// optionally with a region or script ("pt-BR", "de_DE", "zh-Hant")
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+var (
+	translationsOnce sync.Once
+	translations     map[string]map[string]string // By lower-case locale, e.g. "de", "pt-br"
+
+	sessionLocaleOnce sync.Once
+	sessionLocale     string
+)
+
+// loadTranslations reads the embedded translations once
+func loadTranslations() map[string]map[string]string {
+	translationsOnce.Do(func() {
+		translations = map[string]map[string]string{}
+		entries, _ := localeFiles.ReadDir("locales")
+		for _, entry := range entries {
+			data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+			if err != nil {
+				continue
+			}
+			var strs map[string]string
+			if json.Unmarshal(data, &strs) == nil {
+				translations[strings.ToLower(strings.TrimSuffix(entry.Name(), ".json"))] = strs
+			}
+		}
+	})
+	return translations
+}
+
+// Locales returns the languages the built-in UI strings are translated to
+func Locales() []string {
+	var locales []string
+	for locale := range loadTranslations() {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// ValidateLocale checks a -locale value: a language with a translation, such as "de" or
+// "de-AT" (German); empty uses the session's
+func ValidateLocale(locale string) error {
+	if locale == "" {
+		return nil
+	}
+	if !localePattern.MatchString(locale) {
+		return fmt.Errorf("invalid -locale %q (use a language such as de or pt-BR)", locale)
+	}
+	if matchLocale(locale) == "" {
+		return fmt.Errorf("no translation for -locale %q (available: %s)", locale, strings.Join(Locales(), ", "))
+	}
+	return nil
+}
+
+// matchLocale returns the translation for locale: the exact one ("pt-br"), else its
+// language's ("pt"), else ""
+func matchLocale(locale string) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	all := loadTranslations()
+	if _, ok := all[locale]; ok {
+		return locale
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	if _, ok := all[language]; ok {
+		return language
+	}
+	return ""
+}
+
+// parseSystemLocale returns the locale of an environment value such as "de_DE.UTF-8" or
+// "sr_RS@latin", "" for C, POSIX and values that are not a locale
+func parseSystemLocale(value string) string {
+	value, _, _ = strings.Cut(value, ".")
+	value, _, _ = strings.Cut(value, "@")
+	if value == "C" || value == "POSIX" || !localePattern.MatchString(value) {
+		return ""
+	}
+	return value
+}
+
+// detectSessionLocale returns the locale of this session: LC_ALL, LC_MESSAGES or LANG, else
+// the user's language setting on Windows and macOS, else ""
+func detectSessionLocale() string {
+	sessionLocaleOnce.Do(func() {
+		for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if value := os.Getenv(name); value != "" {
+				sessionLocale = parseSystemLocale(value)
+				return
+			}
+		}
+		sessionLocale = parseSystemLocale(systemLocale())
+	})
+	return sessionLocale
+}
+
+// uiText is the built-in UI strings of a locale, English where it has no translation
+type uiText struct {
+	texts, fallback map[string]string
+}
+
+// ui returns the built-in UI strings of n: Locale, else the session's, else English
+func (n Notification) ui() uiText {
+	all := loadTranslations()
+	locale := ""
+	if n.Locale != "" {
+		locale = matchLocale(n.Locale)
+	} else if session := detectSessionLocale(); session != "" {
+		locale = matchLocale(session)
+	}
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	return uiText{texts: all[locale], fallback: all[DefaultLocale]}
+}
+
+// text returns the string key, formatted with args
+func (t uiText) text(key string, args ...interface{}) string {
+	format, ok := t.texts[key]
+	if !ok {
+		format = t.fallback[key]
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// buttonText returns the text of the button: DefaultButton in the notification's language,
+// else ButtonText as given
+func (n Notification) buttonText() string {
+	if n.ButtonText != DefaultButton {
+		return n.ButtonText
+	}
+	return n.ui().text("button")
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,19 @@
+//go:build darwin
+
+package notify
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// systemLocale returns the region and language of System Settings, e.g. "de_DE"
+func systemLocale() string {
+	output, err := exec.Command("defaults", "read", "-g", "AppleLocale").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
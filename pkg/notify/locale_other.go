@@ -0,0 +1,10 @@
+//go:build !darwin && !windows
+
+package notify
+
+// systemLocale returns "": Linux desktops set the language in LANG and LC_* only
+func systemLocale() string {
+	return ""
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestTranslations tests that every translation has only keys of en.json, with the same fmt verbs
+func TestTranslations(t *testing.T) {
+	verbs := regexp.MustCompile(`%[a-z]`)
+	all := loadTranslations()
+	english := all[DefaultLocale]
+	if len(english) == 0 {
+		t.Fatal("No en.json translation")
+	}
+	for locale, texts := range all {
+		for key, text := range texts {
+			want, ok := english[key]
+			if !ok {
+				t.Errorf("%s.json: key %q is not in en.json", locale, key)
+				continue
+			}
+			if got, wantVerbs := strings.Join(verbs.FindAllString(text, -1), ""), strings.Join(verbs.FindAllString(want, -1), ""); got != wantVerbs {
+				t.Errorf("%s.json: %q has verbs %q, en.json has %q", locale, key, got, wantVerbs)
+			}
+		}
+	}
+}
+
+// TestMatchLocale tests -locale and session locales against the translations
+func TestMatchLocale(t *testing.T) {
+	for locale, want := range map[string]string{
+		"de":    "de",
+		"de_AT": "de",
+		"DE-ch": "de",
+		"ja-JP": "ja",
+		"en":    "en",
+		"xx":    "",
+	} {
+		if got := matchLocale(locale); got != want {
+			t.Errorf("matchLocale(%q) = %q, want %q", locale, got, want)
+		}
+	}
+
+	for value, want := range map[string]string{
+		"de_DE.UTF-8": "de_DE",
+		"sr_RS@latin": "sr_RS",
+		"fr":          "fr",
+		"C":           "",
+		"POSIX":       "",
+		"C.UTF-8":     "",
+		"":            "",
+	} {
+		if got := parseSystemLocale(value); got != want {
+			t.Errorf("parseSystemLocale(%q) = %q, want %q", value, got, want)
+		}
+	}
+
+	for _, locale := range []string{"", "es", "fr_CA", "de-AT"} {
+		if err := ValidateLocale(locale); err != nil {
+			t.Errorf("ValidateLocale(%q) = %v", locale, err)
+		}
+	}
+	for _, locale := range []string{"pt-BR-x", "klingon", "de DE", "x"} {
+		if ValidateLocale(locale) == nil {
+			t.Errorf("ValidateLocale(%q) should fail", locale)
+		}
+	}
+}
+
+// TestUIText tests the translated button and formatted strings, with English for missing keys
+func TestUIText(t *testing.T) {
+	if got := (Notification{ButtonText: DefaultButton, Locale: "es"}).buttonText(); got != "Aceptar" {
+		t.Errorf("Default button in es = %q, want Aceptar", got)
+	}
+	if got := (Notification{ButtonText: "Reboot", Locale: "es"}).buttonText(); got != "Reboot" {
+		t.Errorf("-button Reboot in es = %q, want Reboot", got)
+	}
+	if got := (Notification{Locale: "de"}).ui().text("countdown", 30); !strings.Contains(got, "30") || strings.Contains(got, "Auto-closing") {
+		t.Errorf("countdown in de = %q", got)
+	}
+	ui := uiText{texts: map[string]string{}, fallback: loadTranslations()[DefaultLocale]}
+	if got := ui.text("otp.code", "483921"); got != "Code: 483921" {
+		t.Errorf("Missing key = %q, want the English text", got)
+	}
+}
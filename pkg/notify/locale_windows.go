@@ -0,0 +1,25 @@
+//go:build windows
+
+package notify
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getUserDefaultLocaleName = syscall.NewLazyDLL("kernel32.dll").NewProc("GetUserDefaultLocaleName")
+
+// systemLocale returns the user's display language setting, e.g. "de-DE"
+func systemLocale() string {
+	const LOCALE_NAME_MAX_LENGTH = 85
+	var name [LOCALE_NAME_MAX_LENGTH]uint16
+	if getUserDefaultLocaleName.Find() != nil {
+		return ""
+	}
+	if n, _, _ := getUserDefaultLocaleName.Call(uintptr(unsafe.Pointer(&name[0])), LOCALE_NAME_MAX_LENGTH); n == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(name[:])
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"image"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// mobileMirror serves the notification to the user's phone for -mobile-mirror: a page at an
+// unguessable URL on this machine's LAN address, shown in the window as a QR code
+// The session lives as long as the window; a phone on another network cannot reach it
+type mobileMirror struct {
+	URL string
+	QR  image.Image
+
+	server       *http.Server
+	acknowledged chan struct{} // Closed when the user acknowledges on the phone
+	once         sync.Once
+}
+
+// mirrorPage is the mobile page: the notification and a button posting the acknowledgment
+// Plain HTML so it works in any phone browser without JavaScript
+var mirrorPage = template.Must(template.New("mirror").Parse(`<!DOCTYPE html>
+<html dir="{{.Dir}}">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Arial, sans-serif; margin: 0; padding: 20px;
+       background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); min-height: 100vh; box-sizing: border-box; }
+.card { background: white; border-radius: 12px; box-shadow: 0 10px 40px rgba(0,0,0,0.2); padding: 24px; }
+h1 { font-size: 22px; color: #333; margin: 0 0 12px; }
+p { font-size: 17px; color: #555; line-height: 1.5; white-space: pre-wrap; }
+button { width: 100%; padding: 14px; font-size: 18px; color: white; background: #667eea; border: none; border-radius: 8px; }
+</style>
+</head>
+<body>
+<div class="card">
+<h1>{{.Title}}</h1>
+{{if .Done}}<p>{{.DoneText}}</p>
+{{else}}<p>{{.Message}}</p>
+<form method="post"><button type="submit">{{.Button}}</button></form>
+{{end}}</div>
+</body>
+</html>
+`))
+
+// startMobileMirror serves n on the LAN address of this machine and encodes its URL as a QR code
+func startMobileMirror(n Notification) (*mobileMirror, error) {
+	ip, err := lanAddress()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, fmt.Errorf("could not listen for the mobile mirror: %v", err)
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("could not generate the mobile mirror token: %v", err)
+	}
+	path := "/m/" + hex.EncodeToString(token)
+
+	m := &mobileMirror{
+		URL:          fmt.Sprintf("http://%s%s", listener.Addr(), path),
+		acknowledged: make(chan struct{}),
+	}
+	qr, err := encodeQR([]byte(m.URL))
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	m.QR = qr.Image(4)
+
+	m.server = &http.Server{Handler: m.handler(n, path), ReadHeaderTimeout: 10 * time.Second}
+	go m.server.Serve(listener)
+	log.Printf("Mobile mirror listening on %s", listener.Addr())
+	return m, nil
+}
+
+// handler serves the page for n at path: GET shows it, POST acknowledges it
+func (m *mobileMirror) handler(n Notification, path string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Mobile mirror: %s from %s", r.Method, r.RemoteAddr)
+		page := struct {
+			Title, Message, Button, DoneText, Dir string
+			Done                                  bool
+		}{n.Title, n.Message, n.ButtonText, n.ui().text("mirror.done"), n.htmlDir(), false}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			page.Done = true
+			m.once.Do(func() { close(m.acknowledged) })
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "use GET or POST", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer") // The URL is the credential
+		mirrorPage.Execute(w, page)
+	})
+	return mux
+}
+
+// Acknowledged returns a channel that is closed when the user acknowledges on the phone
+func (m *mobileMirror) Acknowledged() <-chan struct{} {
+	return m.acknowledged
+}
+
+// Close ends the session: the page stops answering once the window is gone
+func (m *mobileMirror) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	m.server.Shutdown(ctx)
+}
+
+// lanAddress returns the address of the interface with the default route, the one a
+// phone on the same Wi-Fi can reach (no packet is sent: UDP "connects" only pick a route)
+func lanAddress() (net.IP, error) {
+	conn, err := net.Dial("udp", "192.0.2.1:9") // TEST-NET-1, never routed anywhere real
+	if err != nil {
+		return nil, fmt.Errorf("no network for the mobile mirror: %v", err)
+	}
+	defer conn.Close()
+	ip := conn.LocalAddr().(*net.UDPAddr).IP
+	if ip.IsLoopback() {
+		return nil, fmt.Errorf("no network for the mobile mirror: only loopback is available")
+	}
+	return ip, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMobileMirrorHandler tests that the phone page shows the notification escaped,
+// that posting acknowledges it, and that other paths are not served
+func TestMobileMirrorHandler(t *testing.T) {
+	m := &mobileMirror{acknowledged: make(chan struct{})}
+	n := Notification{Title: "Reboot <tonight>", Message: "Save your work", ButtonText: "Got it"}
+	handler := m.handler(n, "/m/abc123")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/m/abc123", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET returned %d", rec.Code)
+	}
+	if !strings.Contains(body, "Reboot &lt;tonight&gt;") || !strings.Contains(body, "Got it") {
+		t.Errorf("GET page does not show the escaped notification:\n%s", body)
+	}
+	select {
+	case <-m.Acknowledged():
+		t.Fatal("GET acknowledged the notification")
+	default:
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/m/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET of another session returned %d, want 404", rec.Code)
+	}
+
+	// Posting twice (a reload) must not panic on the closed channel
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/m/abc123", nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Acknowledged") {
+			t.Errorf("POST returned %d:\n%s", rec.Code, rec.Body.String())
+		}
+	}
+	select {
+	case <-m.Acknowledged():
+	default:
+		t.Error("POST did not acknowledge the notification")
+	}
+}
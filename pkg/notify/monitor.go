@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Monitors for Notification.Monitor, besides a monitor number
+// Monitor 1 is the primary monitor, the others are numbered from left to right (then top to bottom)
+const (
+	MonitorPrimary = "primary" // The primary monitor, the same as 1
+	MonitorAll     = "all"     // The primary monitor, with a copy of the window on every other one
+)
+
+// ValidateMonitor checks a -monitor value
+func ValidateMonitor(monitor string) error {
+	switch monitor {
+	case "", MonitorPrimary, MonitorAll:
+		return nil
+	}
+	if number, err := strconv.Atoi(monitor); err == nil && number >= 1 {
+		return nil
+	}
+	return fmt.Errorf("invalid monitor %q (use a monitor number from 1, %s or %s)", monitor, MonitorPrimary, MonitorAll)
+}
+
+// screen is the area of a monitor windows are placed in, in virtual screen coordinates
+type screen struct {
+	X, Y, Width, Height int
+	Primary             bool
+}
+
+// orderScreens sorts screens into -monitor numbers: the primary one first, then the others
+// from left to right and top to bottom
+func orderScreens(screens []screen) {
+	sort.SliceStable(screens, func(i, j int) bool {
+		a, b := screens[i], screens[j]
+		if a.Primary != b.Primary {
+			return a.Primary
+		}
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		return a.Y < b.Y
+	})
+}
+
+// monitorScreen returns the screen a -monitor value places the window on among ordered screens;
+// all places it on the primary one, like primary
+func monitorScreen(screens []screen, monitor string) (screen, error) {
+	if len(screens) == 0 {
+		return screen{}, fmt.Errorf("no monitors found")
+	}
+	number := 1
+	if monitor != MonitorPrimary && monitor != MonitorAll {
+		var err error
+		if number, err = strconv.Atoi(monitor); err != nil {
+			return screen{}, fmt.Errorf("invalid monitor %q", monitor)
+		}
+	}
+	if number < 1 || number > len(screens) {
+		return screen{}, fmt.Errorf("monitor %d not found (%d connected)", number, len(screens))
+	}
+	return screens[number-1], nil
+}
+
+// centerOnScreen returns the position of a width by height window centered on s, kept
+// on s by its top left corner when the window is larger
+func centerOnScreen(s screen, width, height int) (x, y int) {
+	x, y = s.X+(s.Width-width)/2, s.Y+(s.Height-height)/2
+	if x < s.X {
+		x = s.X
+	}
+	if y < s.Y {
+		y = s.Y
+	}
+	return x, y
+}
+
+// xrandrMonitorPattern matches a monitor of xrandr --listmonitors: " 0: +*DP-1 2560/597x1440/336+0+0  DP-1"
+var xrandrMonitorPattern = regexp.MustCompile(`^\s*\d+:\s+\+?(\*?)\S*\s+(\d+)/\d+x(\d+)/\d+\+(-?\d+)\+(-?\d+)`)
+
+// parseXrandrMonitors returns the screens in the output of xrandr --listmonitors, unordered
+func parseXrandrMonitors(output string) []screen {
+	var screens []screen
+	for _, line := range strings.Split(output, "\n") {
+		m := xrandrMonitorPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		width, _ := strconv.Atoi(m[2])
+		height, _ := strconv.Atoi(m[3])
+		x, _ := strconv.Atoi(m[4])
+		y, _ := strconv.Atoi(m[5])
+		screens = append(screens, screen{X: x, Y: y, Width: width, Height: height, Primary: m[1] == "*"})
+	}
+	return screens
+}
+
+// screenCopyTitle is the title of the copy of a -monitor all window on monitor number, which
+// tells the copies apart for placement
+func screenCopyTitle(title string, number int) string {
+	return fmt.Sprintf("%s (monitor %d)", title, number)
+}
+
+// showScreenCopies shows a copy of the notification on every monitor but the primary one for
+// -monitor all, and places them; acknowledging a copy taps okButton of the window w, unless the
+// answer needs more than the button (input, choices, screenshot consent): the copy then
+// brings w to the front instead
+// The returned function, called after the app has run, stops the placement of the copies
+func showScreenCopies(a fyne.App, w fyne.Window, okButton *widget.Button, n Notification, answerInWindow bool, size fyne.Size) (done func()) {
+	screens, err := listScreens()
+	if err != nil {
+		log.Printf("Warning: -monitor all cannot list the monitors, showing one window: %v", err)
+		return func() {}
+	}
+	var placed []func()
+	for number := 2; number <= len(screens); number++ {
+		title := screenCopyTitle(n.Title, number)
+		copyWindow := newWindow(a, title, n)
+		copyWindow.SetIcon(resourceKrankyBearBeretPng)
+
+		titleLabel := widget.NewLabel(n.Title)
+		titleLabel.TextStyle.Bold = true
+		titleLabel.Alignment = textAlign(n.rightToLeft())
+		button := widget.NewButton(n.ButtonText, func() { okButton.OnTapped() })
+		if answerInWindow {
+			button = widget.NewButton(n.ui().text("monitor.answer"), func() {
+				w.Show()
+				w.RequestFocus()
+			})
+		}
+		button.Importance = okButton.Importance
+		copyWindow.SetContent(container.NewPadded(container.NewVBox(
+			titleLabel,
+			widget.NewSeparator(),
+			messageText(n.Message, n.rightToLeft()),
+			widget.NewSeparator(),
+			button,
+		)))
+		copyWindow.Resize(size)
+		copyWindow.Show()
+
+		copyN := n
+		copyN.Monitor = strconv.Itoa(number)
+		copyN.PositionID = ""
+		placed = append(placed, placeWindow(title, copyN, false, -1))
+	}
+	if len(placed) > 0 {
+		log.Printf("Placement: copies shown on %d other monitors", len(placed))
+	}
+	return func() {
+		for _, done := range placed {
+			done()
+		}
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
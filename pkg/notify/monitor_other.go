@@ -0,0 +1,227 @@
+//go:build !windows
+
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listScreens returns the monitors xrandr reports, in -monitor order
+// macOS is not supported: its windows cannot be moved from outside the GUI library
+func listScreens() ([]screen, error) {
+	if runtime.GOOS == "darwin" {
+		return nil, fmt.Errorf("not supported on macOS")
+	}
+	output, err := exec.Command("xrandr", "--listmonitors").Output()
+	if err != nil {
+		return nil, fmt.Errorf("xrandr failed: %v", err)
+	}
+	screens := parseXrandrMonitors(string(output))
+	if len(screens) == 0 {
+		return nil, fmt.Errorf("xrandr listed no monitors")
+	}
+	orderScreens(screens)
+	return screens, nil
+}
+
+// wmctrlWindowPattern matches a window of wmctrl -l -p -G: ID, desktop, PID, x, y, width, height, host, title
+var wmctrlWindowPattern = regexp.MustCompile(`^(0x[0-9a-fA-F]+)\s+-?\d+\s+(\d+)\s+(-?\d+)\s+(-?\d+)\s+(\d+)\s+(\d+)\s+\S+\s(.*)$`)
+
+// findWmctrlWindow returns the ID, position and size of the window of process pid titled title
+// in the output of wmctrl -l -p -G
+func findWmctrlWindow(output, title string, pid int) (id string, x, y, width, height int, ok bool) {
+	for _, line := range strings.Split(output, "\n") {
+		m := wmctrlWindowPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil || m[7] != title || m[2] != strconv.Itoa(pid) {
+			continue
+		}
+		x, _ = strconv.Atoi(m[3])
+		y, _ = strconv.Atoi(m[4])
+		width, _ = strconv.Atoi(m[5])
+		height, _ = strconv.Atoi(m[6])
+		return m[1], x, y, width, height, true
+	}
+	return "", 0, 0, 0, 0, false
+}
+
+// moveToMonitor centers the window of this process titled title on the monitor of a -monitor
+// value, waiting up to 5 seconds for the GUI to create it
+// Wayland compositors place windows themselves, so this only works with X11
+func moveToMonitor(title, monitor string) error {
+	screens, err := listScreens()
+	if err != nil {
+		return err
+	}
+	s, err := monitorScreen(screens, monitor)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("wmctrl"); err != nil {
+		return fmt.Errorf("wmctrl is not installed")
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(250 * time.Millisecond) {
+		output, err := exec.Command("wmctrl", "-l", "-p", "-G").Output()
+		if err != nil {
+			return fmt.Errorf("wmctrl failed: %v", err)
+		}
+		id, _, _, width, height, ok := findWmctrlWindow(string(output), title, os.Getpid())
+		if !ok {
+			continue
+		}
+		x, y := centerOnScreen(s, width, height)
+		if output, err := exec.Command("wmctrl", "-i", "-r", id, "-e", fmt.Sprintf("0,%d,%d,-1,-1", x, y)).CombinedOutput(); err != nil {
+			return fmt.Errorf("wmctrl failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+		}
+		log.Printf("Placement: window moved to monitor %s at %d,%d", monitor, x, y)
+		return nil
+	}
+	return fmt.Errorf("window %q not found", title)
+}
+
+// removeWindowFrame asks the window manager to drop the title bar and borders of the window of
+// this process titled title (the Motif hints that GTK and Qt use for undecorated windows),
+// waiting up to 5 seconds for the GUI to create it
+// Wayland compositors decorate windows themselves, so this only works with X11
+func removeWindowFrame(title string) error {
+	if runtime.GOOS == "darwin" {
+		return fmt.Errorf("not supported on macOS")
+	}
+	for _, tool := range []string{"wmctrl", "xprop"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%s is not installed", tool)
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(250 * time.Millisecond) {
+		output, err := exec.Command("wmctrl", "-l", "-p", "-G").Output()
+		if err != nil {
+			return fmt.Errorf("wmctrl failed: %v", err)
+		}
+		id, _, _, _, _, ok := findWmctrlWindow(string(output), title, os.Getpid())
+		if !ok {
+			continue
+		}
+		// flags 2 (decorations set), functions 0, decorations 0 (none)
+		if output, err := exec.Command("xprop", "-id", id, "-f", "_MOTIF_WM_HINTS", "32c", "-set", "_MOTIF_WM_HINTS", "2, 0, 0, 0, 0").CombinedOutput(); err != nil {
+			return fmt.Errorf("xprop failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+		}
+		log.Printf("Placement: window frame removed")
+		return nil
+	}
+	return fmt.Errorf("window %q not found", title)
+}
+
+// restoreWindowPosition moves and sizes the window of this process titled title as the window
+// shown with id was last left, fitted onto the monitors connected now (see fitWindowPosition),
+// waiting up to 5 seconds for the GUI to create it
+func restoreWindowPosition(title, id string) error {
+	positions, err := loadWindowPositions()
+	if err != nil {
+		return err
+	}
+	position, ok := positions[id]
+	if !ok {
+		return nil
+	}
+	screens, err := listScreens()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("wmctrl"); err != nil {
+		return fmt.Errorf("wmctrl is not installed")
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(250 * time.Millisecond) {
+		output, err := exec.Command("wmctrl", "-l", "-p", "-G").Output()
+		if err != nil {
+			return fmt.Errorf("wmctrl failed: %v", err)
+		}
+		windowID, _, _, width, height, ok := findWmctrlWindow(string(output), title, os.Getpid())
+		if !ok {
+			continue
+		}
+		if position.Width <= 0 || position.Height <= 0 {
+			position.Width, position.Height = width, height
+		}
+		fitted, ok := fitWindowPosition(position, screens)
+		if !ok {
+			log.Printf("Placement: remembered position %d,%d for %q is off screen, centering instead", position.X, position.Y, id)
+			return nil
+		}
+		geometry := fmt.Sprintf("0,%d,%d,%d,%d", fitted.X, fitted.Y, fitted.Width, fitted.Height)
+		if output, err := exec.Command("wmctrl", "-i", "-r", windowID, "-e", geometry).CombinedOutput(); err != nil {
+			return fmt.Errorf("wmctrl failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+		}
+		log.Printf("Placement: restored %q to %d,%d, %dx%d", id, fitted.X, fitted.Y, fitted.Width, fitted.Height)
+		return nil
+	}
+	return fmt.Errorf("window %q not found", title)
+}
+
+// trackWindowPosition follows the window of this process titled title until stop is closed,
+// then remembers its last position and size under id (polling wmctrl, because the GUI library
+// does not report moves)
+func trackWindowPosition(title, id string, stop <-chan struct{}) {
+	if _, err := exec.LookPath("wmctrl"); err != nil || runtime.GOOS == "darwin" {
+		return
+	}
+	var last windowPosition
+	known := false
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for open := true; open; {
+		if output, err := exec.Command("wmctrl", "-l", "-p", "-G").Output(); err == nil {
+			if _, x, y, width, height, ok := findWmctrlWindow(string(output), title, os.Getpid()); ok && width > 0 {
+				last, known = windowPosition{X: x, Y: y, Width: width, Height: height}, true
+			}
+		}
+		select {
+		case <-stop:
+			open = false
+		case <-ticker.C:
+		}
+	}
+	if !known {
+		return
+	}
+	if err := saveWindowPosition(id, last.X, last.Y, last.Width, last.Height); err != nil {
+		log.Printf("Placement: could not remember the position: %v", err)
+	}
+}
+
+// fullScreenWindow asks the window manager to show the window of this process titled title full
+// screen (above panels and other windows), waiting up to 5 seconds for the GUI to create it
+// Wayland compositors decide themselves, so this only works with X11
+func fullScreenWindow(title string) error {
+	if runtime.GOOS == "darwin" {
+		return fmt.Errorf("not supported on macOS")
+	}
+	if _, err := exec.LookPath("wmctrl"); err != nil {
+		return fmt.Errorf("wmctrl is not installed")
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(250 * time.Millisecond) {
+		output, err := exec.Command("wmctrl", "-l", "-p", "-G").Output()
+		if err != nil {
+			return fmt.Errorf("wmctrl failed: %v", err)
+		}
+		id, _, _, _, _, ok := findWmctrlWindow(string(output), title, os.Getpid())
+		if !ok {
+			continue
+		}
+		if output, err := exec.Command("wmctrl", "-i", "-r", id, "-b", "add,fullscreen").CombinedOutput(); err != nil {
+			return fmt.Errorf("wmctrl failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+		}
+		exec.Command("wmctrl", "-i", "-a", id).Run() // Focused, so the button takes Enter
+		log.Printf("Placement: window shown full screen")
+		return nil
+	}
+	return fmt.Errorf("window %q not found", title)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package notify
+
+import "testing"
+
+// TestFindWmctrlWindow tests finding this process's window in wmctrl -l -p -G output
+func TestFindWmctrlWindow(t *testing.T) {
+	output := `0x03a00003  0 4120   0    0    1920 1080 host Desktop
+0x04200007  0 5511   760  440  400  200  host Disk space low
+0x04400007  0 5600   760  440  420  210  host Disk space low
+0x04400009  0 5600   760  440  420  210  host Disk space low (monitor 2)
+`
+	id, x, y, width, height, ok := findWmctrlWindow(output, "Disk space low", 5600)
+	if !ok || id != "0x04400007" || x != 760 || y != 440 || width != 420 || height != 210 {
+		t.Errorf("findWmctrlWindow = %q %d,%d %dx%d %v, want 0x04400007 760,440 420x210", id, x, y, width, height, ok)
+	}
+	if id, _, _, _, _, ok := findWmctrlWindow(output, screenCopyTitle("Disk space low", 2), 5600); !ok || id != "0x04400009" {
+		t.Errorf("findWmctrlWindow of the copy = %q %v, want 0x04400009", id, ok)
+	}
+	if _, _, _, _, _, ok := findWmctrlWindow(output, "Disk space low", 9999); ok {
+		t.Error("findWmctrlWindow found a window of another process")
+	}
+}
@@ -0,0 +1,88 @@
+package notify
+
+import "testing"
+
+// TestValidateMonitor tests the -monitor values
+func TestValidateMonitor(t *testing.T) {
+	for _, monitor := range []string{"", "1", "2", "12", MonitorPrimary, MonitorAll} {
+		if err := ValidateMonitor(monitor); err != nil {
+			t.Errorf("ValidateMonitor(%q) = %v", monitor, err)
+		}
+	}
+	for _, monitor := range []string{"0", "-1", "left", "Primary", "1.5"} {
+		if err := ValidateMonitor(monitor); err == nil {
+			t.Errorf("ValidateMonitor(%q) should fail", monitor)
+		}
+	}
+}
+
+// TestParseXrandrMonitors tests reading the monitors of xrandr --listmonitors
+func TestParseXrandrMonitors(t *testing.T) {
+	output := `Monitors: 3
+ 0: +*DP-1 2560/597x1440/336+1920+0  DP-1
+ 1: +HDMI-1 1920/527x1080/296+0+180  HDMI-1
+ 2: +eDP-1 1920/344x1200/215+-1920+0  eDP-1
+`
+	screens := parseXrandrMonitors(output)
+	want := []screen{
+		{X: 1920, Y: 0, Width: 2560, Height: 1440, Primary: true},
+		{X: 0, Y: 180, Width: 1920, Height: 1080},
+		{X: -1920, Y: 0, Width: 1920, Height: 1200},
+	}
+	if len(screens) != len(want) {
+		t.Fatalf("parseXrandrMonitors found %d monitors, want %d: %+v", len(screens), len(want), screens)
+	}
+	for i := range want {
+		if screens[i] != want[i] {
+			t.Errorf("monitor %d = %+v, want %+v", i, screens[i], want[i])
+		}
+	}
+}
+
+// TestMonitorScreen tests the monitor numbers: the primary monitor first, then left to right
+func TestMonitorScreen(t *testing.T) {
+	screens := []screen{
+		{X: 1920, Width: 2560, Height: 1440, Primary: true},
+		{X: 4480, Width: 1920, Height: 1080},
+		{X: -1920, Width: 1920, Height: 1200},
+	}
+	orderScreens(screens)
+
+	tests := []struct {
+		monitor string
+		wantX   int
+	}{
+		{"1", 1920},
+		{MonitorPrimary, 1920},
+		{MonitorAll, 1920},
+		{"2", -1920},
+		{"3", 4480},
+	}
+	for _, tt := range tests {
+		s, err := monitorScreen(screens, tt.monitor)
+		if err != nil {
+			t.Errorf("monitorScreen(%q) failed: %v", tt.monitor, err)
+			continue
+		}
+		if s.X != tt.wantX {
+			t.Errorf("monitorScreen(%q) is at x %d, want %d", tt.monitor, s.X, tt.wantX)
+		}
+	}
+	if _, err := monitorScreen(screens, "4"); err == nil {
+		t.Error("monitorScreen(\"4\") should fail with 3 monitors")
+	}
+	if _, err := monitorScreen(nil, MonitorPrimary); err == nil {
+		t.Error("monitorScreen without monitors should fail")
+	}
+}
+
+// TestCenterOnScreen tests that windows are centered, and larger ones kept on the monitor
+func TestCenterOnScreen(t *testing.T) {
+	s := screen{X: -1920, Y: 0, Width: 1920, Height: 1080}
+	if x, y := centerOnScreen(s, 400, 200); x != -1160 || y != 440 {
+		t.Errorf("centerOnScreen = %d,%d, want -1160,440", x, y)
+	}
+	if x, y := centerOnScreen(s, 2400, 1200); x != -1920 || y != 0 {
+		t.Errorf("centerOnScreen of a larger window = %d,%d, want -1920,0", x, y)
+	}
+}
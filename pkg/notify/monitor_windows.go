@@ -0,0 +1,118 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Monitor functions in user32.dll
+var (
+	enumDisplayMonitors = user32.NewProc("EnumDisplayMonitors")
+	getMonitorInfo      = user32.NewProc("GetMonitorInfoW")
+	monitorFromWindow   = user32.NewProc("MonitorFromWindow")
+)
+
+// monitorInfo is MONITORINFO
+type monitorInfo struct {
+	cbSize    uint32
+	rcMonitor winRect
+	rcWork    winRect
+	dwFlags   uint32
+}
+
+// enumMonitors collects the screens EnumDisplayMonitors reports to enumMonitorsCallback,
+// which is created once: Windows callbacks are never freed
+var (
+	enumMonitorsMu       sync.Mutex
+	enumMonitors         []screen
+	enumMonitorsCallback = syscall.NewCallback(func(monitor, hdc, rect, data uintptr) uintptr {
+		const MONITORINFOF_PRIMARY = 0x1
+		info := monitorInfo{cbSize: uint32(unsafe.Sizeof(monitorInfo{}))}
+		if ok, _, _ := getMonitorInfo.Call(monitor, uintptr(unsafe.Pointer(&info))); ok != 0 {
+			work := info.rcWork // Without the taskbar
+			enumMonitors = append(enumMonitors, screen{
+				X:       int(work.Left),
+				Y:       int(work.Top),
+				Width:   int(work.Right - work.Left),
+				Height:  int(work.Bottom - work.Top),
+				Primary: info.dwFlags&MONITORINFOF_PRIMARY != 0,
+			})
+		}
+		return 1 // Continue
+	})
+)
+
+// listScreens returns the work areas of the monitors, in -monitor order
+func listScreens() ([]screen, error) {
+	enumMonitorsMu.Lock()
+	defer enumMonitorsMu.Unlock()
+	enumMonitors = nil
+	if ok, _, err := enumDisplayMonitors.Call(0, 0, enumMonitorsCallback, 0); ok == 0 {
+		return nil, fmt.Errorf("EnumDisplayMonitors failed: %v", err)
+	}
+	screens := append([]screen(nil), enumMonitors...)
+	orderScreens(screens)
+	return screens, nil
+}
+
+// moveToMonitor centers hwnd on the monitor of a -monitor value
+func moveToMonitor(hwnd uintptr, monitor string) error {
+	const (
+		SWP_NOSIZE     = 0x0001
+		SWP_NOZORDER   = 0x0004
+		SWP_NOACTIVATE = 0x0010
+	)
+	screens, err := listScreens()
+	if err != nil {
+		return err
+	}
+	s, err := monitorScreen(screens, monitor)
+	if err != nil {
+		return err
+	}
+	var rect winRect
+	getWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&rect)))
+	x, y := centerOnScreen(s, int(rect.Right-rect.Left), int(rect.Bottom-rect.Top))
+	if ok, _, err := setWindowPos.Call(hwnd, 0, uintptr(x), uintptr(y), 0, 0, SWP_NOSIZE|SWP_NOZORDER|SWP_NOACTIVATE); ok == 0 {
+		return fmt.Errorf("SetWindowPos failed: %v", err)
+	}
+	log.Printf("Placement: window moved to monitor %s at %d,%d", monitor, x, y)
+	return nil
+}
+
+// fullScreenWindow makes this process's window titled title cover the whole monitor it is on,
+// taskbar included, without frame and above other windows, waiting up to 5 seconds for the GUI
+// to create it
+func fullScreenWindow(title string) error {
+	const (
+		MONITOR_DEFAULTTONEAREST = 2
+		HWND_TOPMOST             = ^uintptr(0) // (HWND)-1
+		SWP_FRAMECHANGED         = 0x0020
+		SWP_SHOWWINDOW           = 0x0040
+	)
+	hwnd := waitForWindow(title, 5*time.Second, nil)
+	if hwnd == 0 {
+		return fmt.Errorf("window %q not found", title)
+	}
+	removeFrame(hwnd)
+	monitor, _, _ := monitorFromWindow.Call(hwnd, MONITOR_DEFAULTTONEAREST)
+	info := monitorInfo{cbSize: uint32(unsafe.Sizeof(monitorInfo{}))}
+	if ok, _, err := getMonitorInfo.Call(monitor, uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return fmt.Errorf("GetMonitorInfo failed: %v", err)
+	}
+	r := info.rcMonitor
+	if ok, _, err := setWindowPos.Call(hwnd, HWND_TOPMOST, uintptr(r.Left), uintptr(r.Top), uintptr(r.Right-r.Left), uintptr(r.Bottom-r.Top), SWP_FRAMECHANGED|SWP_SHOWWINDOW); ok == 0 {
+		return fmt.Errorf("SetWindowPos failed: %v", err)
+	}
+	setForegroundWindow.Call(hwnd)
+	log.Printf("Placement: window shown full screen")
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// nativeDeliveryBudget bounds how long handing a -native notification to the OS may take
+// (longer than quickDeliveryBudget: starting PowerShell for a Windows toast takes a second or two)
+const nativeDeliveryBudget = 10 * time.Second
+
+// showNativeNotification posts n to the OS notification center (toast on Windows,
+// Notification Center on macOS, libnotify on Linux) and returns without waiting for the user
+// activation is the URI a click on a Windows toast launches (see Notifier.ToastActivation)
+// Returns the delivery method
+func showNativeNotification(n Notification, activation string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), nativeDeliveryBudget)
+	defer cancel()
+
+	start := time.Now()
+	method, err := sendNativeNotification(ctx, n, activation)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Native notification delivered via %s in %v", method, time.Since(start))
+	return method, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
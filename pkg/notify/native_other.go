@@ -0,0 +1,62 @@
+//go:build !windows
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// sendNativeNotification uses the notification services of -quick (osascript on macOS,
+// notify-send on Linux), which already go through the notification center
+// On macOS terminal-notifier is preferred when installed: osascript banners are shown as
+// Script Editor, terminal-notifier's with the KrankyBear icon and grouped per notify
+// Toast activation is Windows only, activation is ignored
+func sendNativeNotification(ctx context.Context, n Notification, activation string) (string, error) {
+	if runtime.GOOS == "darwin" {
+		if _, err := exec.LookPath("terminal-notifier"); err == nil {
+			return sendTerminalNotifier(ctx, n)
+		}
+	}
+	return sendQuickNotification(ctx, n)
+}
+
+// sendTerminalNotifier posts n to macOS Notification Center with terminal-notifier
+func sendTerminalNotifier(ctx context.Context, n Notification) (string, error) {
+	args := []string{"-title", n.Title, "-message", terminalNotifierText(n.plainMessage()), "-group", "KrankyBearNotify"}
+	if n.IconPath != "" {
+		if iconPath, err := prepareIcon(n.IconPath); err != nil {
+			log.Printf("Warning: Not showing the icon: %v", err)
+		} else {
+			args = append(args, "-appIcon", iconPath, "-contentImage", iconPath)
+		}
+	}
+	if n.Priority == PriorityBreakGlass {
+		// Break glass is delivered even in Focus / do-not-disturb
+		args = append(args, "-sound", "Sosumi", "-ignoreDnD")
+	} else {
+		args = append(args, "-sound", "default")
+	}
+	if link := n.PrimaryLink(); link != "" {
+		args = append(args, "-open", link) // Clicking the banner opens the link
+	}
+	if output, err := exec.CommandContext(ctx, "terminal-notifier", args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("terminal-notifier failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return "terminal-notifier", nil
+}
+
+// terminalNotifierText escapes a message terminal-notifier would otherwise misread:
+// a leading "-" is taken as an option and a leading "[" as a list of values
+func terminalNotifierText(s string) string {
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "[") {
+		return "\\" + s
+	}
+	return s
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
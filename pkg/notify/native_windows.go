@@ -0,0 +1,179 @@
+//go:build windows
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Application User Model IDs toasts are shown under
+// Toasts need a registered AUMID: notify registers its own for the current user (display name
+// and icon in Action Center), and falls back to Windows PowerShell's, present on every Windows 10+ install
+const (
+	toastAppID         = "KrankyBear.Notify"
+	toastFallbackAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+)
+
+// sendNativeNotification shows a toast in the Windows notification center (Action Center)
+// through the WinRT ToastNotificationManager (via PowerShell, so no WinRT bindings are needed)
+func sendNativeNotification(ctx context.Context, n Notification, activation string) (string, error) {
+	toastXML := buildToastXML(n, activation)
+	escapedXML := strings.ReplaceAll(toastXML, "'", "''")
+	iconPath, err := toastAppIcon()
+	if err != nil {
+		log.Printf("Warning: Could not write toast app icon: %v", err)
+	}
+	escapedIcon := strings.ReplaceAll(iconPath, "'", "''")
+
+	// -sensitive: the toast expires once it has left the screen, so Action Center does not keep it
+	expiry := ""
+	if n.Sensitive {
+		seconds := n.Timeout
+		if seconds <= 0 || seconds > 25 {
+			seconds = 25 // The long toast duration
+		}
+		expiry = fmt.Sprintf("$toast.ExpirationTime = [DateTimeOffset]::Now.AddSeconds(%d)", seconds)
+	}
+
+	// Registering the AUMID under HKCU\Software\Classes\AppUserModelId gives the toast
+	// the KrankyBearNotify name and icon without an installer-created Start Menu shortcut
+	psScript := fmt.Sprintf(`
+try {
+    $appId = '%s'
+    try {
+        $key = 'HKCU:\Software\Classes\AppUserModelId\' + $appId
+        New-Item -Path $key -Force | Out-Null
+        Set-ItemProperty -Path $key -Name DisplayName -Value 'KrankyBearNotify'
+        if ('%s' -ne '') { Set-ItemProperty -Path $key -Name IconUri -Value '%s' }
+    } catch {
+        $appId = '%s'
+    }
+    [Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+    [Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+    $xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+    $xml.LoadXml('%s')
+    $toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+    %s
+    [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($appId).Show($toast)
+    exit 0
+} catch {
+    Write-Host "ERROR: $_"
+    exit 1
+}
+`, toastAppID, escapedIcon, escapedIcon, toastFallbackAppID, escapedXML, expiry)
+
+	cmd := exec.CommandContext(ctx, "powershell.exe",
+		"-WindowStyle", "Hidden",
+		"-NoProfile",
+		"-NonInteractive",
+		"-NoLogo",
+		"-ExecutionPolicy", "Bypass",
+		"-Command", psScript)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: 0x08000000, // CREATE_NO_WINDOW
+	}
+
+	output, err := cmd.CombinedOutput()
+	outputStr := strings.TrimSpace(string(output))
+	if err != nil || strings.Contains(outputStr, "ERROR:") {
+		return "", fmt.Errorf("toast notification failed: %v (output: %s)", err, outputStr)
+	}
+	return "toast", nil
+}
+
+// toastAppIcon writes the KrankyBear icon for the toast app identity to the user's
+// local app data (Action Center needs a file path) and returns its path
+func toastAppIcon() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	iconPath := filepath.Join(cacheDir, "KrankyBearNotify", "toast-icon.png")
+	if _, err := os.Stat(iconPath); err == nil {
+		return iconPath, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(iconPath), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(iconPath, resourceKrankyBearBeretPng.Content(), 0644); err != nil {
+		return "", err
+	}
+	return iconPath, nil
+}
+
+// buildToastXML returns the ToastGeneric content for n
+// Toasts stay 7 seconds by default; notifications with a longer (or no) timeout use the long duration (25 seconds),
+// and critical ones (Urgency) stay until dismissed
+// With an activation URI, clicking the toast or its button launches it (with "&open=1" when the
+// click should also open the link), so Action Center clicks are recorded after notify exited
+func buildToastXML(n Notification, activation string) string {
+	escape := func(s string) string {
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+
+	duration := "short"
+	if n.Timeout == 0 || n.Timeout > 7 {
+		duration = "long"
+	}
+	// Critical toasts use the reminder scenario: they stay on screen until dismissed, which
+	// Windows only honors for toasts with a button
+	attributes := fmt.Sprintf(`duration="%s"`, duration)
+	critical := n.Urgency == UrgencyCritical
+	if critical {
+		attributes += ` scenario="reminder"`
+	}
+	var sb strings.Builder
+	// Clicking the toast (or its button) opens the link in the default browser
+	link := n.PrimaryLink()
+	openLink := link
+	if activation != "" && link != "" {
+		openLink = activation + "&open=1"
+	}
+	launch := openLink
+	if launch == "" {
+		launch = activation
+	}
+	if launch != "" {
+		sb.WriteString(fmt.Sprintf(`<toast %s activationType="protocol" launch="%s"><visual><binding template="ToastGeneric">`, attributes, escape(launch)))
+	} else {
+		sb.WriteString(fmt.Sprintf(`<toast %s><visual><binding template="ToastGeneric">`, attributes))
+	}
+	sb.WriteString("<text>" + escape(n.Title) + "</text>")
+	sb.WriteString("<text>" + escape(n.plainMessage()) + "</text>")
+	if n.IconPath != "" {
+		if iconPath, err := prepareIcon(n.IconPath); err != nil {
+			log.Printf("Warning: Not showing the icon: %v", err)
+		} else {
+			sb.WriteString(`<image placement="appLogoOverride" src="file:///` + escape(filepath.ToSlash(iconPath)) + `"/>`)
+		}
+	}
+	sb.WriteString("</binding></visual>")
+	if link != "" || activation != "" || critical {
+		sb.WriteString("<actions>")
+		if activation != "" {
+			sb.WriteString(`<action content="` + escape(n.ButtonText) + `" activationType="protocol" arguments="` + escape(activation) + `"/>`)
+		} else if critical {
+			sb.WriteString(`<action content="` + escape(n.ButtonText) + `" activationType="system" arguments="dismiss"/>`)
+		}
+		if link != "" {
+			sb.WriteString(`<action content="Open link" activationType="protocol" arguments="` + escape(openLink) + `"/>`)
+		}
+		sb.WriteString("</actions>")
+	}
+	sb.WriteString("</toast>")
+	return sb.String()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
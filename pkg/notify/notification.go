@@ -0,0 +1,432 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Notification holds the parameters shared by every delivery backend
+// (Fyne, WebView, MessageBox, wall, quick, and the fan-out to other users)
+// Adding a field here, to BindFlags and to childArgs carries it
+// through every mode; notification_test.go fails if one of them is missed
+type Notification struct {
+	Title      string
+	Message    string
+	ButtonText string
+	Timeout    int // Seconds, 0 for no timeout, TimeoutAuto for the time to read it
+	IconPath   string
+	Link       string // http(s) URL shown as a link under the message and opened by clicking a toast
+	Width      int
+	Height     int
+	TimeZone   string // IANA zone for {{localtime:...}}, empty for the target user's own zone
+	Locale     string // Language of the built-in UI strings (DefaultButton, countdown, wall), e.g. "de", empty for the target user's own
+	Category   string // Users can opt out of categories (see Notifier.Suppress), empty for uncategorized
+	Sound      string // Fyne/WebView/MessageBox: SoundSystem, a WAV or MP3 file, or a system sound name played when shown
+
+	IconData    string // Base64 image (see DecodeIconData), ignored when there is an IconPath
+	BuiltinIcon string // Icon bundled with notify by name (see BuiltinIcons), ignored when there is an IconPath or IconData
+
+	Input            bool   // Fyne/WebView: show a text field, its value is returned in Result.Input when the user clicks the button
+	InputDefault     string // Initial text of the Input field
+	InputPlaceholder string // Hint shown in the empty Input field
+	Choices          string // Fyne/WebView: comma-separated options of a drop-down, the chosen one is returned in Result.Choice
+	DigestItems      string // Fyne/WebView: notifications a digest stands for, one per line, listed in a collapsed pane under the message
+
+	HTML            string // WebView: message body as an HTML fragment, sanitized unless AllowUnsafeHTML; other backends show Message
+	AllowUnsafeHTML bool   // Show HTML as given, scripts included
+
+	Theme           string // Fyne/WebView: ThemeDark or ThemeLight, empty or ThemeAuto to follow the desktop
+	BackgroundColor string // Fyne/WebView: hex color of the window background (the card in WebView), empty for the default
+	ForegroundColor string // Fyne/WebView: hex color of the text
+	AccentColor     string // Fyne/WebView: hex color of the button and links, and of the WebView page instead of the purple gradient
+	Font            string // Fyne/WebView: path of a TrueType or OpenType font for the text, empty for the default
+	FontSize        int    // Fyne/WebView: size of the message text in pixels (headings scale with it), 0 for the default
+
+	State      string // Fyne/WebView: icon of a job state, StatePending, StateWorking (with a spinner), StateSuccess or StateFailed, instead of the -icon
+	StateID    string // Fyne/WebView: ID under which UpdateState changes the state (and message) while the window is open
+	StateIcons string // Comma-separated state=image entries replacing the built-in icons of those states
+
+	OTP       string        // Fyne/WebView: one-time code shown large with a copy button; other backends show it as text
+	OTPExpiry time.Duration // Count down and replace OTP after this long, 0 to keep it
+
+	OnClick  string   // Command line the notify CLI runs when the user acknowledges, e.g. "updater.exe /now"
+	OnChoice []string // "Option=command line" entries, run instead of OnClick when that Choices option was chosen
+
+	CallbackURL string // URL the notify CLI POSTs the result to when the notification finishes
+
+	Urgency         string // One of the Urgency constants: icon, accent color, critical stays on top; empty for the plain look
+	Priority        string // One of the Priority constants, empty for PriorityNormal
+	BreakGlassToken string // Signed authorization required for PriorityBreakGlass (see SignBreakGlass)
+
+	ContextScreenshot bool          // Fyne: show a thumbnail of the screen in a details pane, shared only with the user's consent
+	AttentionAfter    time.Duration // Fyne: pulse, flash and raise the window when it has been ignored this long, 0 to never
+	MobileMirror      bool          // Fyne: show a QR code opening the notification on a phone on the same network, which can acknowledge it
+	AckFile           string        // Fyne/WebView: creating or touching this file (AckFileDefault for the user's own) acknowledges the notification
+	RespectDND        string        // While the user has do not disturb on: DNDDefer or DNDDowngrade, empty to show the notification anyway
+	OverrideDND       bool          // Ignore RespectDND, e.g. for critical alerts sent with a default RespectDND
+
+	Sensitive      bool // Message has one-time codes or personal data: kept out of screen capture (Windows) and notification history
+	RedactAfterAck bool // Fyne/WebView: blank the message in the window as soon as the user acknowledges it
+
+	Desktop    string // Windows: virtual desktop of the window, DesktopActive (default) or DesktopAll
+	PositionID string // Windows/Linux X11: remember where the user leaves the window under this ID (or PositionCategory) and reopen it there
+	Monitor    string // Windows/Linux X11: monitor number of the window (1 is the primary), MonitorPrimary, or MonitorAll for a copy on every monitor
+	Topmost    bool   // Windows/Linux X11: keep the Fyne/WebView window above other windows, as critical urgency does
+	Frameless  bool   // Fyne/WebView window without title bar and borders, like a toast card
+	Fullscreen bool   // Fyne/WebView window covering the whole screen, as break glass does
+	Stacking   string // Fyne/WebView window while other notify windows are open: StackingStack (default), StackingQueue or StackingOverlap
+
+	HideCountdown bool // Fyne/WebView: no "Auto-closing in Ns" countdown of the Timeout
+	RTL           bool // Fyne/WebView: lay the window out right to left, as for a message in Arabic or Hebrew
+
+	ReadingSpeed int // Words per minute of a TimeoutAuto notification, 0 for DefaultReadingSpeed
+	TimeoutMin   int // Shortest TimeoutAuto in seconds, 0 for DefaultTimeoutMin
+	TimeoutMax   int // Longest TimeoutAuto in seconds, 0 for DefaultTimeoutMax
+
+	ID      string // Fyne/WebView: a later notification with this ID closes the window, or with Replace changes its title and message
+	Replace bool   // Show the title and message in the window already open with the same ID instead of a new window
+	Cancel  string // Close the window shown with this ID instead of showing a notification
+
+	Automation string // Fyne/WebView: Unix socket on which end-to-end tests read the window and click its buttons
+
+	RemoteSpec bool // Sent from another machine (notify serve, agent, subscribe): templates do not read this machine's environment
+}
+
+// BindFlags defines the notify CLI notification flags on fs, storing their values in n
+func BindFlags(fs *flag.FlagSet, n *Notification) {
+	fs.StringVar(&n.Title, "title", DefaultTitle, "Notification title (decoded from percent-encoding with -encoded)")
+	fs.StringVar(&n.Message, "message", DefaultMessage, "Notification message (decoded from percent-encoding with -encoded)")
+	fs.StringVar(&n.ButtonText, "button", DefaultButton, "Button text (decoded from percent-encoding with -encoded); the default is translated to -locale")
+	n.Timeout = DefaultTimeout
+	fs.Var((*timeoutValue)(&n.Timeout), "timeout", "Timeout in seconds (0 for no timeout), or auto for the time to read the title and message at -reading-speed")
+	fs.IntVar(&n.Width, "width", DefaultWidth, "Window width in pixels")
+	fs.IntVar(&n.Height, "height", DefaultHeight, "Window height in pixels")
+	fs.StringVar(&n.Link, "link", "", "URL shown as a clickable link under the message, opened by clicking a -native toast (links in the message are clickable too)")
+
+	fs.StringVar(&n.Sound, "sound", "", "Play a sound when the notification appears: system, a .wav or .mp3 file, or a system sound name (e.g. Glass, dialog-warning, SystemExclamation)")
+	fs.StringVar(&n.Category, "category", "", "Notification category users can opt out of, e.g. newsletter (see notify optout)")
+	fs.StringVar(&n.Locale, "locale", "", "Language of the built-in window and wall text, e.g. de or pt-BR (default: each user's own language)")
+	fs.StringVar(&n.TimeZone, "tz", "", "Time zone for {{localtime:...}} in the title/message, e.g. Europe/Berlin (default: each user's local zone)")
+
+	fs.BoolVar(&n.Input, "input", false, "Show a text field and print the entered value to stdout when the user clicks the button")
+	fs.StringVar(&n.InputDefault, "input-default", "", "Initial text of the -input field")
+	fs.StringVar(&n.InputPlaceholder, "input-placeholder", "", "Hint shown in the empty -input field, e.g. \"Ticket number\"")
+	fs.StringVar(&n.Choices, "choices", "", "Comma-separated options of a drop-down, e.g. \"Now,Tonight,Tomorrow\"; the chosen one is printed to stdout and sets the exit code")
+	fs.StringVar(&n.DigestItems, "digest-items", "", "Notifications this one stands for, one per line, listed in a collapsed \"View list\" pane under the message (notify serve -digest-window digests)")
+
+	fs.StringVar(&n.HTML, "html", "", "WebView: Message body as an HTML fragment, e.g. \"<h2>Maintenance</h2><p>Tonight at <b>22:00</b></p>\"; sanitized, other modes show -message or the text of the fragment (decoded from percent-encoding with -encoded)")
+	fs.StringVar(&n.Theme, "theme", ThemeAuto, "Window theme: auto (follow the desktop's dark/light preference), dark or light")
+	fs.StringVar(&n.BackgroundColor, "bg-color", "", "Window background color as hex, e.g. 1b1f3a or \"#1b1f3a\" (Fyne window, WebView card)")
+	fs.StringVar(&n.ForegroundColor, "fg-color", "", "Text color as hex, e.g. f5f5f5 (Fyne and WebView)")
+	fs.StringVar(&n.AccentColor, "accent-color", "", "Accent color as hex for the button and links, e.g. e30613; also replaces the purple WebView background")
+	fs.StringVar(&n.Font, "font", "", "TrueType or OpenType font file (.ttf, .otf) for the Fyne and WebView text")
+	fs.IntVar(&n.FontSize, "font-size", 0, "Message text size in pixels (8 to 72), headings scale with it (0 for the default)")
+	fs.StringVar(&n.State, "state", "", "Job state shown as the icon: pending, working (with a spinner), success or failed (replaces -icon)")
+	fs.StringVar(&n.StateID, "state-id", "", "ID under which \"notify state ID STATE\" changes the state and message while the window is open, e.g. nightly-backup")
+	fs.StringVar(&n.StateIcons, "state-icons", "", "Images replacing the icons of states, e.g. \"working=spinner.gif,success=done.png\"")
+	fs.BoolVar(&n.AllowUnsafeHTML, "allow-unsafe-html", false, "Show -html without sanitizing it (scripts, styles, forms and any link are kept); only for HTML you wrote yourself")
+
+	fs.StringVar(&n.OTP, "otp", "", "One-time code to show in large digits with a copy button, e.g. 483921 (add -sensitive to keep it out of screen capture)")
+	fs.DurationVar(&n.OTPExpiry, "otp-expiry", 0, "Count down and remove the -otp code after this long, e.g. 5m (0 to keep it)")
+
+	fs.StringVar(&n.OnClick, "on-click", "", "Command to run when the user acknowledges the notification, e.g. \"C:\\Tools\\updater.exe /now\" (quote arguments with spaces)")
+	fs.StringVar(&n.CallbackURL, "callback-url", "", "POST a JSON result (action, user, hostname, timestamp) to this http(s) URL when the notification is acknowledged, times out or is handed off")
+	fs.Var((*stringList)(&n.OnChoice), "on-choice", "Command to run when this -choices option is chosen, as Option=command (repeat for each option; replaces -on-click for that option)")
+
+	fs.StringVar(&n.Urgency, "urgency", "", "Urgency: info, warning or critical; sets the icon (without -icon), accent color and default timeout (10s, 30s, none), keeps critical windows on top, and maps to libnotify urgency and toast scenario")
+	fs.StringVar(&n.Priority, "priority", PriorityNormal, "Priority: normal, or breakglass for emergencies (bypasses business hours, full-screen with sound, requires -breakglass-token)")
+	fs.StringVar(&n.BreakGlassToken, "breakglass-token", "", "Signed token authorizing -priority breakglass for this title and message (see notify breakglass sign)")
+
+	fs.BoolVar(&n.ContextScreenshot, "context-screenshot", false, "Capture a thumbnail of the user's screen into a details pane; it is added to the result only if the user agrees to share it")
+	fs.BoolVar(&n.MobileMirror, "mobile-mirror", false, "Show a QR code that opens the notification on a phone on the same network, where it can be acknowledged")
+	fs.StringVar(&n.AckFile, "ack-file", "", "Acknowledge when this file is created or touched, for users who cannot use the window (e.g. with a screen reader); default for the file \"notify ack\" touches")
+	fs.StringVar(&n.RespectDND, "respect-dnd", "", "While the user has do not disturb on (Focus Assist, macOS Focus, GNOME): defer (wait until it ends) or downgrade (show a toast instead of the window)")
+	fs.BoolVar(&n.OverrideDND, "override-dnd", false, "Show the notification even when the user has do not disturb on, overriding -respect-dnd (for critical alerts)")
+	fs.DurationVar(&n.AttentionAfter, "attention-after", 0, "Pulse, flash and raise the window when the user has not interacted with it for this long, e.g. 60s (0 to never)")
+
+	fs.BoolVar(&n.Sensitive, "sensitive", false, "Message has one-time codes or personal data: exclude the window from screenshots and screen recording (Windows) and keep the message out of the inbox and notification history")
+	fs.BoolVar(&n.RedactAfterAck, "redact-after-ack", false, "Blank the message in the window as soon as the user acknowledges it, and in the inbox")
+
+	fs.StringVar(&n.Desktop, "desktop", DesktopActive, "Windows: Virtual desktop to show the window on: active (the one the user is viewing) or all")
+	fs.StringVar(&n.PositionID, "remember-position", "", "Remember where the user moves and sizes the window under this ID, e.g. patch-reminder, or category for its -category, and reopen it there (Windows and Linux X11)")
+	fs.StringVar(&n.Monitor, "monitor", "", "Monitor to show the window on: a number (1 is the primary monitor, the others count from left to right), primary, or all for a copy on every monitor (Windows and Linux X11)")
+	fs.BoolVar(&n.Topmost, "topmost", false, "Keep the window above other windows until it is closed (Windows and Linux X11; critical -urgency implies it)")
+	fs.BoolVar(&n.Frameless, "frameless", false, "Show the window without title bar and borders, like a toast card")
+	fs.BoolVar(&n.Fullscreen, "fullscreen", false, "Cover the whole screen with the notification until it is acknowledged, for alerts such as an evacuation")
+	fs.StringVar(&n.Stacking, "stacking", StackingStack, "While other notify windows are open: stack (below or above them, Windows and Linux X11), queue (wait until they are closed) or overlap")
+	fs.BoolVar(&n.HideCountdown, "hide-countdown", false, "Do not show the \"Auto-closing in Ns\" countdown (and bar) of the -timeout in the window")
+	fs.BoolVar(&n.RTL, "rtl", false, "Lay the window out right to left: text aligned right, icon on the right (default: when the message starts in Arabic, Hebrew or another right-to-left script)")
+	fs.IntVar(&n.ReadingSpeed, "reading-speed", DefaultReadingSpeed, "Words per minute of -timeout auto (lower for non-native readers, higher for short alerts)")
+	fs.IntVar(&n.TimeoutMin, "timeout-min", DefaultTimeoutMin, "Shortest -timeout auto in seconds")
+	fs.IntVar(&n.TimeoutMax, "timeout-max", DefaultTimeoutMax, "Longest -timeout auto in seconds, e.g. 300 for long policy texts")
+	fs.StringVar(&n.ID, "id", "", "ID of the window, e.g. backup-progress: a later notification with the same -id closes it (or updates it with -replace), -cancel closes it")
+	fs.BoolVar(&n.Replace, "replace", false, "Show the title and message in the window already open with the same -id instead of closing it and opening a new one")
+	fs.StringVar(&n.Cancel, "cancel", "", "Close the window shown with this -id instead of showing a notification")
+	fs.BoolVar(&n.RemoteSpec, "remote-spec", false, "Internal: The notification was sent from another machine, {{env.NAME}} is not rendered (set by notify serve, agent and subscribe)")
+	fs.StringVar(&n.Automation, "automation", "", "Testing: serve the text and buttons of the window on this Unix socket, e.g. /tmp/notify.sock, for end-to-end tests of notification specs")
+
+	// Icon flag with alias
+	fs.StringVar(&n.IconPath, "icon", "", "Path to icon image file (PNG, JPEG, etc.) (decoded from percent-encoding with -encoded)")
+	fs.StringVar(&n.IconPath, "image", "", "Path to icon image file (alias for -icon) (decoded from percent-encoding with -encoded)")
+	fs.StringVar(&n.IconData, "icon-data", "", "Icon image as base64 (or a data:image/...;base64, URL) instead of an -icon file, for tools that can only send a command line")
+	fs.StringVar(&n.BuiltinIcon, "builtin-icon", "", "Icon bundled with notify, used when there is no -icon or -icon-data: "+strings.Join(BuiltinIcons(), ", "))
+}
+
+// stringList is a flag that can be repeated, collecting its values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// ChoiceList returns the options of Choices, trimmed, without empty entries
+func (n Notification) ChoiceList() []string {
+	var choices []string
+	for _, choice := range strings.Split(n.Choices, ",") {
+		if choice = strings.TrimSpace(choice); choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// DigestList returns the lines of DigestItems, trimmed, without empty lines
+func (n Notification) DigestList() []string {
+	var items []string
+	for _, item := range strings.Split(n.DigestItems, "\n") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// childArgs returns the arguments that reproduce n in a child notify process
+// launched in another user's session, ending with -checksum for VerifyChecksum
+// IconPath must already be resolved to a path the target user can read
+func (n Notification) childArgs() []string {
+	return append(n.flagArgs(), "-checksum", n.Checksum())
+}
+
+// flagArgs returns the notification flags of childArgs
+func (n Notification) flagArgs() []string {
+	args := []string{
+		"-title", n.Title,
+		"-message", n.Message,
+		"-button", n.ButtonText,
+		"-timeout", (*timeoutValue)(&n.Timeout).String(),
+		"-width", fmt.Sprintf("%d", n.Width),
+		"-height", fmt.Sprintf("%d", n.Height),
+	}
+	if n.IconPath != "" {
+		args = append(args, "-image", n.IconPath)
+	}
+	if n.IconData != "" {
+		args = append(args, "-icon-data", n.IconData)
+	}
+	if n.BuiltinIcon != "" {
+		args = append(args, "-builtin-icon", n.BuiltinIcon)
+	}
+	if n.Link != "" {
+		args = append(args, "-link", n.Link)
+	}
+	if n.Locale != "" {
+		args = append(args, "-locale", n.Locale)
+	}
+	if n.TimeZone != "" {
+		args = append(args, "-tz", n.TimeZone)
+	}
+	if n.Category != "" {
+		args = append(args, "-category", n.Category)
+	}
+	if n.Sound != "" {
+		args = append(args, "-sound", n.Sound)
+	}
+	if n.Input {
+		args = append(args, "-input")
+	}
+	if n.InputDefault != "" {
+		args = append(args, "-input-default", n.InputDefault)
+	}
+	if n.InputPlaceholder != "" {
+		args = append(args, "-input-placeholder", n.InputPlaceholder)
+	}
+	if n.Choices != "" {
+		args = append(args, "-choices", n.Choices)
+	}
+	if n.DigestItems != "" {
+		args = append(args, "-digest-items", n.DigestItems)
+	}
+	if n.HTML != "" {
+		args = append(args, "-html", n.HTML)
+	}
+	if n.AllowUnsafeHTML {
+		args = append(args, "-allow-unsafe-html")
+	}
+	if n.Theme != "" {
+		args = append(args, "-theme", n.Theme)
+	}
+	if n.BackgroundColor != "" {
+		args = append(args, "-bg-color", n.BackgroundColor)
+	}
+	if n.ForegroundColor != "" {
+		args = append(args, "-fg-color", n.ForegroundColor)
+	}
+	if n.AccentColor != "" {
+		args = append(args, "-accent-color", n.AccentColor)
+	}
+	if n.Font != "" {
+		args = append(args, "-font", n.Font)
+	}
+	if n.FontSize != 0 {
+		args = append(args, "-font-size", fmt.Sprintf("%d", n.FontSize))
+	}
+	if n.State != "" {
+		args = append(args, "-state", n.State)
+	}
+	if n.StateID != "" {
+		args = append(args, "-state-id", n.StateID)
+	}
+	if n.StateIcons != "" {
+		args = append(args, "-state-icons", n.StateIcons)
+	}
+	if n.OTP != "" {
+		args = append(args, "-otp", n.OTP)
+	}
+	if n.OTPExpiry > 0 {
+		args = append(args, "-otp-expiry", n.OTPExpiry.String())
+	}
+	if n.OnClick != "" {
+		args = append(args, "-on-click", n.OnClick)
+	}
+	for _, command := range n.OnChoice {
+		args = append(args, "-on-choice", command)
+	}
+	if n.CallbackURL != "" {
+		args = append(args, "-callback-url", n.CallbackURL)
+	}
+	if n.Urgency != "" {
+		args = append(args, "-urgency", n.Urgency)
+	}
+	if n.Priority != "" {
+		args = append(args, "-priority", n.Priority)
+	}
+	if n.BreakGlassToken != "" {
+		args = append(args, "-breakglass-token", n.BreakGlassToken)
+	}
+	if n.ContextScreenshot {
+		args = append(args, "-context-screenshot")
+	}
+	if n.MobileMirror {
+		args = append(args, "-mobile-mirror")
+	}
+	if n.AckFile != "" {
+		args = append(args, "-ack-file", n.AckFile)
+	}
+	if n.RespectDND != "" {
+		args = append(args, "-respect-dnd", n.RespectDND)
+	}
+	if n.OverrideDND {
+		args = append(args, "-override-dnd")
+	}
+	if n.AttentionAfter > 0 {
+		args = append(args, "-attention-after", n.AttentionAfter.String())
+	}
+	if n.Sensitive {
+		args = append(args, "-sensitive")
+	}
+	if n.RedactAfterAck {
+		args = append(args, "-redact-after-ack")
+	}
+	if n.Desktop != "" {
+		args = append(args, "-desktop", n.Desktop)
+	}
+	if n.PositionID != "" {
+		args = append(args, "-remember-position", n.PositionID)
+	}
+	if n.Monitor != "" {
+		args = append(args, "-monitor", n.Monitor)
+	}
+	if n.Topmost {
+		args = append(args, "-topmost")
+	}
+	if n.Frameless {
+		args = append(args, "-frameless")
+	}
+	if n.Fullscreen {
+		args = append(args, "-fullscreen")
+	}
+	if n.Stacking != "" {
+		args = append(args, "-stacking", n.Stacking)
+	}
+	if n.ReadingSpeed != 0 {
+		args = append(args, "-reading-speed", fmt.Sprintf("%d", n.ReadingSpeed))
+	}
+	if n.TimeoutMin != 0 {
+		args = append(args, "-timeout-min", fmt.Sprintf("%d", n.TimeoutMin))
+	}
+	if n.TimeoutMax != 0 {
+		args = append(args, "-timeout-max", fmt.Sprintf("%d", n.TimeoutMax))
+	}
+	if n.HideCountdown {
+		args = append(args, "-hide-countdown")
+	}
+	if n.RTL {
+		args = append(args, "-rtl")
+	}
+	if n.ID != "" {
+		args = append(args, "-id", n.ID)
+	}
+	if n.Replace {
+		args = append(args, "-replace")
+	}
+	if n.Cancel != "" {
+		args = append(args, "-cancel", n.Cancel)
+	}
+	if n.Automation != "" {
+		args = append(args, "-automation", n.Automation)
+	}
+	if n.RemoteSpec {
+		args = append(args, "-remote-spec")
+	}
+	return args
+}
+
+// Checksum returns a digest of the notification flags childArgs passes to a child process
+// Elevated fan-out reaches the child through sudo, launchctl or a scheduled task, each with
+// its own quoting and encoding; the child recomputes it to tell whether the text arrived intact
+func (n Notification) Checksum() string {
+	h := sha256.New()
+	for _, arg := range n.flagArgs() {
+		fmt.Fprintf(h, "%d:%s", len(arg), arg) // Length-prefixed, so arguments cannot run into each other
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// VerifyChecksum checks received, the notification a child process parsed from its command line
+// (before any decoding, spec or config file is applied), against the -checksum of its parent
+// An empty checksum (notify run by hand, or by an older version) is not checked
+func VerifyChecksum(received Notification, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+	if got := received.Checksum(); got != checksum {
+		if received.Sensitive {
+			return fmt.Errorf("notification changed on its way to this process (checksum %s, sent %s), not showing mangled text", got, checksum)
+		}
+		return fmt.Errorf("notification changed on its way to this process (checksum %s, sent %s), not showing mangled text; received %q",
+			got, checksum, received.flagArgs())
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestNotificationChildArgsParity tests that every Notification field survives the
+// trip through childArgs and the command-line flags, so the fan-out to other users
+// never silently drops a parameter
+func TestNotificationChildArgsParity(t *testing.T) {
+	// Give every field a distinct non-default value
+	var want Notification
+	v := reflect.ValueOf(&want).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(fmt.Sprintf("value for %s", v.Type().Field(i).Name))
+		case reflect.Int, reflect.Int64:
+			field.SetInt(int64(1000 + i))
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Slice:
+			name := v.Type().Field(i).Name
+			field.Set(reflect.ValueOf([]string{"first " + name, "second " + name}))
+		default:
+			t.Fatalf("Notification.%s has type %s, add it to this test", v.Type().Field(i).Name, field.Type())
+		}
+	}
+
+	var got Notification
+	fs := flag.NewFlagSet("child", flag.ContinueOnError)
+	BindFlags(fs, &got)
+	checksum := fs.String("checksum", "", "") // Defined by the notify CLI
+	if err := fs.Parse(want.childArgs()); err != nil {
+		t.Fatalf("Child args did not parse: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Notification lost fields through childArgs:\n got  %+v\n want %+v", got, want)
+	}
+	if err := VerifyChecksum(got, *checksum); err != nil {
+		t.Errorf("Checksum of the parsed child args does not verify: %v", err)
+	}
+}
+
+// TestVerifyChecksum tests that text changed between the parent and the child process
+// is detected, and that a missing checksum (notify run by hand) is accepted
+func TestVerifyChecksum(t *testing.T) {
+	sent := Notification{Title: "Maintenance", Message: "Café closes at 5 \"today\"", ButtonText: "OK", Timeout: 30}
+	checksum := sent.Checksum()
+	if err := VerifyChecksum(sent, checksum); err != nil {
+		t.Errorf("VerifyChecksum(unchanged) = %v", err)
+	}
+
+	mangled := []Notification{sent, sent, sent, sent}
+	mangled[0].Message = "Café closes at 5 today"                                      // Quotes stripped by a shell
+	mangled[1].Message = "CafÃ© closes at 5 \"today\""                                 // UTF-8 read as a code page
+	mangled[2].Title, mangled[2].Message = "Maintenance Café", "closes at 5 \"today\"" // Split on the wrong space
+	mangled[3].Timeout = 3
+	for _, n := range mangled {
+		if err := VerifyChecksum(n, checksum); err == nil {
+			t.Errorf("VerifyChecksum did not detect %q", n.flagArgs())
+		}
+	}
+
+	if err := VerifyChecksum(mangled[0], ""); err != nil {
+		t.Errorf("VerifyChecksum without a checksum = %v", err)
+	}
+
+	// -sensitive text is not repeated in the error (it ends up in logs)
+	secret := Notification{Title: "Sign-in code", Message: "Your code is 482913", Sensitive: true}
+	secretChecksum := secret.Checksum()
+	secret.Message = "Your code is 482914"
+	if err := VerifyChecksum(secret, secretChecksum); err == nil || strings.Contains(err.Error(), "4829") {
+		t.Errorf("VerifyChecksum(sensitive) = %v, want an error without the message", err)
+	}
+}
+
+// TestBackendsTakeNotification tests that every delivery backend accepts the
+// Notification struct (or Options, which embeds it) rather than its own list of parameters
+func TestBackendsTakeNotification(t *testing.T) {
+	notificationType := reflect.TypeOf(Notification{})
+	optionsType := reflect.TypeOf(Options{})
+	backends := map[string]interface{}{
+		"showNotification":        showNotification,
+		"showWebViewNotification": showWebViewNotification,
+		"showWindowsMessageBox":   showWindowsMessageBox,
+		"broadcastWallMessage":    broadcastWallMessage,
+		"showNotificationToUsers": showNotificationToUsers,
+		"showQuickNotification":   showQuickNotification,
+		"showNativeNotification":  showNativeNotification,
+	}
+
+	for name, backend := range backends {
+		fn := reflect.TypeOf(backend)
+		found := false
+		for i := 0; i < fn.NumIn(); i++ {
+			if fn.In(i) == notificationType || fn.In(i) == optionsType {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s does not take a Notification: %s", name, fn)
+		}
+	}
+}
+
+// TestChoiceList tests that -choices is split into trimmed, non-empty options
+func TestChoiceList(t *testing.T) {
+	n := Notification{Choices: " Now, Tonight ,,Tomorrow morning, "}
+	got := n.ChoiceList()
+	want := []string{"Now", "Tonight", "Tomorrow morning"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChoiceList() = %q, want %q", got, want)
+	}
+	if got := (Notification{}).ChoiceList(); len(got) != 0 {
+		t.Errorf("ChoiceList() without choices = %q, want none", got)
+	}
+}
@@ -0,0 +1,578 @@
+// Package notify shows desktop notifications on Windows, macOS and Linux with the
+// platform detection and fallbacks of the notify CLI (Fyne, WebView, MessageBox, wall,
+// and other users' sessions when running as root/SYSTEM)
+package notify
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Defaults used by the notify CLI flags and by Options fields left at their zero value
+const (
+	DefaultTitle   = "Notification"
+	DefaultMessage = "This is a notification message"
+	DefaultButton  = "OK" // Translated to the Locale (see Locales)
+	DefaultTimeout = 10   // seconds
+	DefaultWidth   = 400  // pixels
+	DefaultHeight  = 250  // pixels
+)
+
+// Result actions describing what happened to a notification
+const (
+	ActionAcknowledged = "acknowledged" // User clicked the button
+	ActionTimeout      = "timeout"      // Notification closed itself after the timeout
+	ActionDelivered    = "delivered"    // Handed off (wall broadcast, other users' sessions), no ack available
+	ActionSkipped      = "skipped"      // Not displayed on this machine (e.g. outside the rollout)
+	ActionSuppressed   = "suppressed"   // Not displayed because the user opted out of its category
+	ActionReplaced     = "replaced"     // Shown in the window already open with the same ID (Replace), whose process reports the outcome
+	ActionCanceled     = "canceled"     // Window closed by Cancel, or by a later notification with the same ID
+)
+
+// Delivery modes for Options.Mode
+const (
+	ModeAuto    = "auto"    // Fyne, falling back to WebView, MessageBox and wall
+	ModeQuick   = "quick"   // Lightest native mechanism within 500ms, no GUI framework
+	ModeNative  = "native"  // OS notification center (toast, Notification Center, libnotify), no window
+	ModeWebView = "webview" // WebView (WebView2 on Windows, elsewhere requires the webview build tag)
+	ModeBasic   = "basic"   // Windows MessageBox
+	ModeWall    = "wall"    // Linux wall broadcast only
+)
+
+// Options is a notification plus how it should be delivered
+type Options struct {
+	Notification
+
+	Mode         string // One of the Mode constants, empty for ModeAuto
+	Autosize     bool   // Size the window to the message (max 600x400) when Width/Height are the defaults
+	GUIOnly      bool   // Linux: when notifying other users, skip the wall broadcast to terminals
+	Disconnected string // Windows: policy for disconnected sessions, empty for deliver-on-reconnect
+	Debug        bool   // Pass -debug to notify processes launched in other users' sessions
+
+	// Targets, when set, limits the fan-out of root/SYSTEM to these users' sessions, each with
+	// their own title and message; there is no wall broadcast or fallback to this session
+	Targets []Target
+
+	// DeliverBy, when set, escalates until the user acknowledges: a toast, then windows
+	// closer together as the deadline approaches, then wall and a window that stays (see EscalationPlan)
+	DeliverBy time.Time
+
+	// Every, when set, shows the notification again this long after each showing until the
+	// user acknowledges it (with one of the StopOn choices, when set), at most Count times
+	// and not after Until (zero for no limit; see sendEvery)
+	Every  time.Duration
+	Count  int
+	Until  time.Time
+	StopOn string // Comma-separated Choices that end the recurrence, empty for any acknowledgment
+}
+
+// Result describes how a notification was delivered
+type Result struct {
+	Action string // One of the Action constants
+	Method string // "fyne", "mobile" (acknowledged on the -mobile-mirror page), "ack-file" (acknowledged by touching the AckFile), "webview", "messagebox", "wall", "users", or the quick/native mode mechanism ("toast", ...)
+	Input  string // Text the user entered in an Input notification, set when they clicked the button
+	Choice string // Option the user chose in a Choices notification, set when they clicked the button
+
+	// Downgrades says what of the notification Method could not show as given, e.g. choices
+	// in a MessageBox (see Notification.Downgrades)
+	Downgrades []string
+
+	Targets []TargetResult // What happened for each of Options.Targets, in order
+}
+
+// response is what the user entered in the window when they clicked the button
+type response struct {
+	Input  string
+	Choice string
+}
+
+// Notifier delivers notifications with the platform detection and fallbacks of the notify CLI:
+// other users' sessions when running as root/SYSTEM, then Fyne, WebView, MessageBox and wall
+type Notifier struct {
+	// Executable is the notify CLI relaunched in other users' sessions when running
+	// with elevated privileges; empty uses the running executable
+	Executable string
+
+	// OnDisplay, when set, is called just before the notification is displayed in
+	// this session (not when it is handed to other users, wall, or quick mode)
+	OnDisplay func(n Notification)
+
+	// Suppress, when set, is called before the notification is delivered to this session's
+	// user (every mode except wall broadcasts); returning true skips it with ActionSuppressed
+	// Break-glass notifications are never suppressed
+	Suppress func(n Notification) bool
+
+	// ToastActivation, when set, is called before a Windows toast (ModeNative) is shown and returns
+	// the URI that clicking the toast or its button launches, e.g. a protocol registered for the
+	// notify CLI, so clicks are recorded after this process has exited; "" shows the toast without
+	ToastActivation func(n Notification) string
+
+	// OnScreenshotShared, when set, is called with the PNG thumbnail of a ContextScreenshot
+	// notification if the user agreed to share it
+	OnScreenshotShared func(png []byte)
+
+	// OnHang, when set, is called on Windows when a Fyne window has not started within 30
+	// seconds (VMs without working OpenGL can hang invisibly), or is still open 15 seconds after
+	// its timeout; the window is asked to quit and Send returns an error, but a hung Fyne may
+	// never return, so a program can exit here. Windows waiting to be acknowledged are not affected
+	OnHang func()
+
+	// BreakGlassKeys are the public keys trusted to authorize PriorityBreakGlass;
+	// nil uses the keys installed at BreakGlassKeyPath
+	BreakGlassKeys []ed25519.PublicKey
+}
+
+// New returns a Notifier that relaunches the running executable for other users' sessions
+func New() *Notifier {
+	return &Notifier{}
+}
+
+// Send delivers the notification and blocks until it is acknowledged, times out, or is handed off
+// Fyne can only run one app per process, so programs showing several GUI notifications
+// should run each in its own process (as the notify CLI does)
+// Features the delivery method cannot show are left out or shown as text, and listed in
+// Result.Downgrades
+func (nt *Notifier) Send(opts Options) (Result, error) {
+	result, err := nt.send(opts)
+	if err == nil {
+		result.Downgrades = opts.Notification.Downgrades(result.Method)
+	}
+	return result, err
+}
+
+// send delivers the notification for Send
+func (nt *Notifier) send(opts Options) (Result, error) {
+	if opts.Disconnected == "" {
+		opts.Disconnected = DisconnectedDeliverOnReconnect
+	}
+	opts.Notification = opts.Notification.Normalize()
+	if opts.Timeout == TimeoutAuto {
+		opts.Timeout = opts.ReadingTimeout()
+	}
+	if err := ValidateDisconnectedPolicy(opts.Disconnected); err != nil {
+		return Result{}, err
+	}
+	if err := ValidatePriority(opts.Priority); err != nil {
+		return Result{}, err
+	}
+	if len(opts.Targets) > 0 {
+		if opts.Mode == ModeQuick || opts.Mode == ModeWall {
+			return Result{}, fmt.Errorf("targets are reached through their own sessions, which %s mode does not use", opts.Mode)
+		}
+		if !shouldShowToOtherUsers() {
+			return Result{}, fmt.Errorf("targets need notify to run as root/SYSTEM to reach other users' sessions")
+		}
+	}
+
+	// Cancel closes a window instead of showing one, in each user's session when fanning out
+	if opts.Cancel != "" {
+		return nt.cancel(opts)
+	}
+
+	// Each user's own notify process escalates or repeats, so only do it here when not fanning out
+	if !opts.DeliverBy.IsZero() && !shouldShowToOtherUsers() {
+		return nt.sendBy(opts)
+	}
+	if opts.Every > 0 && !shouldShowToOtherUsers() {
+		return nt.sendEvery(opts)
+	}
+
+	// Break glass is only honored with a valid token, and every use is audited
+	if opts.Priority == PriorityBreakGlass {
+		claims, err := AuthorizeBreakGlass(opts.Notification, nt.BreakGlassKeys)
+		if err != nil {
+			return Result{}, err
+		}
+		auditBreakGlass(opts.Notification, claims)
+	}
+
+	// Render {{localtime:...}} and {{username}} for this session (or TimeZone); the fan-out to other
+	// users gets the templates unrendered so each child renders them for that user, in their own zone
+	fanOut := opts
+	n, err := opts.Notification.WithLocalTimes(time.Now())
+	if err != nil {
+		return Result{}, err
+	}
+	n.ButtonText = n.buttonText()
+
+	// An -icon URL is downloaded (and -icon-data or a -builtin-icon written) by the process that shows the
+	// notification: the children of the fan-out each write it to their user's cache, which the user can read
+	if opts.Mode == ModeQuick || !shouldShowToOtherUsers() {
+		n = n.withDownloadedIcon().withIconData().withBuiltinIcon()
+	}
+
+	switch opts.Mode {
+	case "", ModeAuto:
+		// Detection and fallbacks below
+
+	case ModeQuick:
+		// Quick mode bypasses every GUI framework and the fan-out to other users
+		if nt.suppressed(n) {
+			return Result{Action: ActionSuppressed}, nil
+		}
+		if n.Sound != "" {
+			log.Println("Quick mode: not playing -sound, the notification service plays its own")
+		}
+		method, err := showQuickNotification(n)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Action: ActionDelivered, Method: method}, nil
+
+	case ModeNative:
+		// Root/SYSTEM has no notification center of its own, so fan out like the GUI modes
+		if shouldShowToOtherUsers() {
+			log.Println("Native mode requested, but running with elevated privileges with logged-in users")
+			log.Println("Will launch as target user (mode will be passed to child process)")
+			break
+		}
+		if nt.suppressed(n) {
+			return Result{Action: ActionSuppressed}, nil
+		}
+		if n.Sound != "" {
+			log.Println("Native mode: not playing -sound, the notification center plays its own")
+		}
+		activation := ""
+		if runtime.GOOS == "windows" && nt.ToastActivation != nil {
+			activation = nt.ToastActivation(n)
+		}
+		method, err := showNativeNotification(n, activation)
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to show native notification: %v", err)
+		}
+		return Result{Action: ActionDelivered, Method: method}, nil
+
+	case ModeWall:
+		if runtime.GOOS != "linux" {
+			return Result{}, fmt.Errorf("wall mode is only available on Linux")
+		}
+		if !IsWallAvailable() {
+			return Result{}, fmt.Errorf("wall command not found, install with: sudo apt install bsdutils")
+		}
+		log.Println("Force-wall mode enabled, using wall broadcast")
+		if err := broadcastWallMessage(n); err != nil {
+			return Result{}, fmt.Errorf("failed to send wall broadcast: %v", err)
+		}
+		return Result{Action: ActionDelivered, Method: "wall"}, nil
+
+	case ModeWebView:
+		// Skip if running as SYSTEM with other users (handled by the elevated notification logic)
+		if shouldShowToOtherUsers() {
+			log.Println("WebView mode requested, but running as SYSTEM with logged-in users")
+			log.Println("Will launch as target user (mode will be passed to child process)")
+			break
+		}
+		if nt.suppressed(n) {
+			return Result{Action: ActionSuppressed}, nil
+		}
+		if result, handled, err := nt.respectDND(n); handled {
+			return result, err
+		}
+		log.Println("WebView mode enabled, skipping OpenGL check")
+		if !isWebViewAvailable() {
+			return Result{}, fmt.Errorf("WebView not available (run -check-webview for details)")
+		}
+		log.Println("Using WebView (HTML/CSS/JS)")
+		if takeOverID(n) {
+			return Result{Action: ActionReplaced}, nil
+		}
+		slot, release := claimWindowSlot(n)
+		defer release()
+		nt.displayed(n)
+		action, resp, err := showWebViewNotification(n, slot)
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to show WebView notification: %v", err)
+		}
+		return Result{Action: action, Method: "webview", Input: resp.Input, Choice: resp.Choice}, nil
+
+	case ModeBasic:
+		if runtime.GOOS != "windows" {
+			return Result{}, fmt.Errorf("basic mode is only supported on Windows")
+		}
+		// Skip if running as SYSTEM with other users (handled by the elevated notification logic)
+		if shouldShowToOtherUsers() {
+			log.Println("Basic mode requested, but running as SYSTEM with logged-in users")
+			log.Println("Will launch as target user (mode will be passed to child process)")
+			break
+		}
+		if nt.suppressed(n) {
+			return Result{Action: ActionSuppressed}, nil
+		}
+		if result, handled, err := nt.respectDND(n); handled {
+			return result, err
+		}
+		log.Println("Windows basic mode enabled, using MessageBox")
+		_, release := claimWindowSlot(n) // Message boxes cannot be moved, but can queue
+		defer release()
+		nt.displayed(n)
+		if err := showWindowsMessageBox(n); err != nil {
+			return Result{}, fmt.Errorf("failed to show notification: %v", err)
+		}
+		return Result{Action: ActionAcknowledged, Method: "messagebox"}, nil
+
+	default:
+		return Result{}, fmt.Errorf("unknown delivery mode %q (use auto, quick, native, webview, basic or wall)", opts.Mode)
+	}
+
+	// Targets: only their sessions, each with its own notification, and a result per target
+	if len(opts.Targets) > 0 {
+		exePath, err := nt.executable()
+		if err != nil {
+			return Result{}, err
+		}
+		results, err := showNotificationToUsers(exePath, fanOut)
+		if err != nil {
+			return Result{}, err
+		}
+		action := ActionSkipped
+		for _, result := range results {
+			if result.Action == ActionDelivered {
+				action = ActionDelivered
+			}
+		}
+		return Result{Action: action, Method: "users", Targets: results}, nil
+	}
+
+	// Special handling when running as root/SYSTEM/Administrator
+	// Show to BOTH GUI users and terminal users (Linux only has wall)
+	if shouldShowToOtherUsers() {
+		log.Println("Running with elevated privileges, notifying logged-in users")
+
+		guiSuccess := false
+		wallSuccess := false
+
+		// Try to show GUI to logged-in GUI users
+		exePath, err := nt.executable()
+		if err == nil {
+			_, err = showNotificationToUsers(exePath, fanOut)
+		}
+		if err == nil {
+			log.Println("✓ Notification shown to GUI user(s)")
+			guiSuccess = true
+		} else {
+			log.Printf("✗ Could not show GUI to users: %v", err)
+		}
+
+		// Linux-specific: Send wall broadcast to terminal sessions
+		// Skip if GUIOnly is set
+		if runtime.GOOS == "linux" && !opts.GUIOnly && IsWallAvailable() {
+			log.Println("Also sending wall broadcast to terminal sessions")
+			if err := broadcastWallMessage(n); err != nil {
+				log.Printf("✗ Wall broadcast failed: %v", err)
+			} else {
+				log.Println("✓ Wall broadcast sent to terminal users")
+				wallSuccess = true
+			}
+		}
+
+		// Done if at least one method succeeded
+		if guiSuccess || wallSuccess {
+			method := "users"
+			if !guiSuccess {
+				method = "wall"
+			}
+			return Result{Action: ActionDelivered, Method: method}, nil
+		}
+
+		// If both failed, check if we're running as SYSTEM on Windows
+		// SYSTEM doesn't have a desktop, so don't try to show GUI to SYSTEM itself
+		if runtime.GOOS == "windows" && isRunningAsSystem() {
+			log.Println("ERROR: Running as SYSTEM but could not notify any users via scheduled task")
+			log.Println("SYSTEM account has no desktop/display - cannot show GUI directly")
+			return Result{}, fmt.Errorf("notification failed: no logged-in users found or scheduled task creation failed")
+		}
+
+		// If both failed, log and continue to try normal GUI
+		log.Println("Warning: Could not notify via GUI or wall, trying normal GUI mode")
+	}
+
+	// The fan-out above leaves opt-outs and do not disturb to each user's own notify process
+	if nt.suppressed(n) {
+		return Result{Action: ActionSuppressed}, nil
+	}
+	if result, handled, err := nt.respectDND(n); handled {
+		return result, err
+	}
+
+	// Auto-size window if requested
+	if opts.Autosize {
+		calculatedWidth, calculatedHeight := calculateWindowSize(n.Title, n.Message, n.ButtonText, n.IconPath != "")
+		// Use calculated size but respect user-provided maximums
+		if n.Width == DefaultWidth {
+			n.Width = calculatedWidth
+		}
+		if n.Height == DefaultHeight {
+			n.Height = calculatedHeight
+		}
+		log.Printf("Auto-sizing enabled: calculated %dx%d, using %dx%d", calculatedWidth, calculatedHeight, n.Width, n.Height)
+	}
+
+	// Verify GUI is available before showing notification
+	if !IsGUIAvailable() {
+		// Try wall broadcast on Linux as fallback
+		if runtime.GOOS == "linux" && IsWallAvailable() {
+			log.Println("GUI not available, using wall broadcast")
+			if err := broadcastWallMessage(n); err != nil {
+				return Result{}, fmt.Errorf("failed to broadcast message: %v", err)
+			}
+			return Result{Action: ActionDelivered, Method: "wall"}, nil
+		}
+		return Result{}, fmt.Errorf("GUI mode is not available and no fallback notification method found")
+	}
+
+	// From here on the notification is displayed in this user's session, stacked below or queued
+	// behind the windows of other notify processes, or shown in the window already open with its ID
+	if takeOverID(n) {
+		return Result{Action: ActionReplaced}, nil
+	}
+	slot, release := claimWindowSlot(n)
+	defer release()
+	nt.displayed(n)
+
+	// Check OpenGL availability (primarily for Windows)
+	openglAvailable := IsOpenGLAvailable()
+	log.Printf("OpenGL availability check result: %v", openglAvailable)
+
+	if !openglAvailable {
+		log.Println("Warning: OpenGL not available, trying alternative GUI")
+
+		// On Windows, when running as SYSTEM, skip WebView (it has permission issues)
+		// and go directly to MessageBox which is more reliable for services
+		skipWebView := false
+		if runtime.GOOS == "windows" && isRunningAsSystem() {
+			log.Println("Running as SYSTEM on Windows, skipping WebView (using MessageBox for reliability)")
+			skipWebView = true
+		}
+
+		// Try WebView first (works on all platforms, better UI) unless skipped
+		if !skipWebView && isWebViewAvailable() {
+			log.Println("Using WebView (HTML/CSS/JS) for notification")
+			action, resp, err := showWebViewNotification(n, slot)
+			if err != nil {
+				log.Printf("WebView failed: %v, trying basic fallback", err)
+			} else {
+				return Result{Action: action, Method: "webview", Input: resp.Input, Choice: resp.Choice}, nil
+			}
+		}
+
+		// Fall back to native OS dialogs as last resort
+		if runtime.GOOS != "windows" {
+			return Result{}, fmt.Errorf("OpenGL not available and no suitable fallback GUI for this platform")
+		}
+		log.Println("Using native Windows MessageBox")
+		if err := showWindowsMessageBox(n); err != nil {
+			return Result{}, fmt.Errorf("failed to show notification: %v", err)
+		}
+		return Result{Action: ActionAcknowledged, Method: "messagebox"}, nil
+	}
+
+	// Create the notification window with Fyne (when OpenGL is available)
+	log.Println("Attempting to create Fyne GUI (OpenGL detected as available)")
+	var screenshot *contextScreenshot
+	if n.ContextScreenshot {
+		if screenshot, err = captureContextScreenshot(); err != nil {
+			log.Printf("Warning: Could not capture context screenshot: %v", err)
+		}
+	}
+	action, method, resp, shared, err := showNotification(n, screenshot, slot, nt.OnHang)
+	if err != nil {
+		return Result{}, err
+	}
+	if shared && nt.OnScreenshotShared != nil {
+		nt.OnScreenshotShared(screenshot.PNG)
+	}
+	return Result{Action: action, Method: method, Input: resp.Input, Choice: resp.Choice}, nil
+}
+
+// displayed plays the break-glass alert sound (or else n.Sound) and runs the OnDisplay hook
+// for a notification shown in this session
+func (nt *Notifier) displayed(n Notification) {
+	if n.Priority == PriorityBreakGlass {
+		playAlertSound()
+	} else if n.Sound != "" {
+		playSound(n.Sound)
+	}
+	if nt.OnDisplay != nil {
+		nt.OnDisplay(n)
+	}
+}
+
+// suppressed reports whether this session's user opted out of n (logging why)
+func (nt *Notifier) suppressed(n Notification) bool {
+	if nt.Suppress == nil || n.Priority == PriorityBreakGlass {
+		return false
+	}
+	if !nt.Suppress(n) {
+		return false
+	}
+	log.Printf("Notification suppressed: the user opted out of category %q", n.Category)
+	return true
+}
+
+// executable returns the notify CLI to relaunch in other users' sessions
+func (nt *Notifier) executable() (string, error) {
+	if nt.Executable != "" {
+		return nt.Executable, nil
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %v", err)
+	}
+	return exePath, nil
+}
+
+// modeArgs returns the delivery flags passed to notify processes in other users' sessions
+func (opts Options) modeArgs() []string {
+	var args []string
+	switch opts.Mode {
+	case ModeWebView:
+		args = append(args, "-force-webview")
+	case ModeBasic:
+		args = append(args, "-win-basic")
+	case ModeNative:
+		args = append(args, "-native")
+	}
+	if opts.Autosize {
+		args = append(args, "-autosize")
+	}
+	if !opts.DeliverBy.IsZero() {
+		args = append(args, "-deliver-by", opts.DeliverBy.Format(time.RFC3339))
+	}
+	if opts.Every > 0 {
+		args = append(args, "-every", opts.Every.String())
+		if opts.Count > 0 {
+			args = append(args, "-count", strconv.Itoa(opts.Count))
+		}
+		if !opts.Until.IsZero() {
+			args = append(args, "-until", opts.Until.Format(time.RFC3339))
+		}
+		if opts.StopOn != "" {
+			args = append(args, "-stop-on", opts.StopOn)
+		}
+	}
+	if opts.Debug {
+		args = append(args, "-debug")
+	}
+	return args
+}
+
+// IsGUIAvailable checks if GUI mode is available on the current system
+func IsGUIAvailable() bool {
+	switch runtime.GOOS {
+	case "linux":
+		return isLinuxGUIAvailable()
+	case "darwin":
+		return isMacGUIAvailable()
+	case "windows":
+		return isWindowsGUIAvailable()
+	default:
+		return false
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
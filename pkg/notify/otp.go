@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxOTPLength bounds -otp, longer codes are not meant to be read out to a helpdesk
+const maxOTPLength = 32
+
+// ValidateOTP checks an -otp value: up to maxOTPLength letters, digits or dashes
+// The code is not repeated in the error, it may end up in logs
+func ValidateOTP(otp string) error {
+	if otp == "" {
+		return nil
+	}
+	if len(otp) > maxOTPLength || strings.Trim(otp, "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-") != "" {
+		return fmt.Errorf("invalid -otp (use up to %d letters, digits or dashes)", maxOTPLength)
+	}
+	return nil
+}
+
+// formatOTP returns otp as shown in the window: codes of digits in groups of three
+// (or four) so they are easier to read out, "483 921"; copying gives the code itself
+func formatOTP(otp string) string {
+	if strings.Trim(otp, "0123456789") != "" || len(otp) < 6 {
+		return otp
+	}
+	size := 3
+	switch {
+	case len(otp)%3 == 0:
+	case len(otp)%4 == 0:
+		size = 4
+	default:
+		return otp
+	}
+	var groups []string
+	for i := 0; i < len(otp); i += size {
+		groups = append(groups, otp[i:i+size])
+	}
+	return strings.Join(groups, " ")
+}
+
+// otpCountdown returns the expiry line under the code for the time left
+func otpCountdown(ui uiText, left time.Duration) string {
+	if left <= 0 {
+		return ui.text("otp.expired")
+	}
+	seconds := int(left.Round(time.Second) / time.Second)
+	if seconds >= 3600 {
+		return ui.text("otp.expires", fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60))
+	}
+	return ui.text("otp.expires", fmt.Sprintf("%d:%02d", seconds/60, seconds%60))
+}
+
+// otpLine returns the code as a line of text for backends without the code display
+func (n Notification) otpLine() string {
+	if n.OTP == "" {
+		return ""
+	}
+	if n.OTPExpiry <= 0 {
+		return n.ui().text("otp.code", n.OTP)
+	}
+	valid := n.OTPExpiry.Round(time.Second).String()
+	if strings.HasSuffix(valid, "m0s") {
+		valid = strings.TrimSuffix(valid, "0s")
+	}
+	if strings.HasSuffix(valid, "h0m") {
+		valid = strings.TrimSuffix(valid, "0m")
+	}
+	return n.ui().text("otp.valid", n.OTP, valid)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
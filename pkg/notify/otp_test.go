@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+// TestValidateOTP tests the -otp values accepted
+func TestValidateOTP(t *testing.T) {
+	for _, otp := range []string{"", "483921", "AB12-CD34", "0000000000000000000000000000000a"} {
+		if err := ValidateOTP(otp); err != nil {
+			t.Errorf("ValidateOTP(%q) failed: %v", otp, err)
+		}
+	}
+	for _, otp := range []string{"483 921", "48392<", "483921\n", "000000000000000000000000000000000"} {
+		if err := ValidateOTP(otp); err == nil {
+			t.Errorf("ValidateOTP(%q) accepted an invalid code", otp)
+		}
+	}
+}
+
+// TestFormatOTP tests grouping the digits of a code for reading it out
+func TestFormatOTP(t *testing.T) {
+	tests := map[string]string{
+		"4839":       "4839",
+		"483921":     "483 921",
+		"48392101":   "4839 2101",
+		"483921017":  "483 921 017",
+		"4839210":    "4839210",
+		"AB12-CD34":  "AB12-CD34",
+		"4839210177": "4839210177",
+	}
+	for in, want := range tests {
+		if got := formatOTP(in); got != want {
+			t.Errorf("formatOTP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestOTPText tests the countdown and the code line of text-only backends
+func TestOTPText(t *testing.T) {
+	countdowns := map[time.Duration]string{
+		5 * time.Minute:                       "Expires in 5:00",
+		59*time.Second + 600*time.Millisecond: "Expires in 1:00",
+		90*time.Minute + 5*time.Second:        "Expires in 1:30:05",
+		0:                                     "This code has expired",
+	}
+	for left, want := range countdowns {
+		if got := otpCountdown(Notification{Locale: "en"}.ui(), left); got != want {
+			t.Errorf("otpCountdown(%v) = %q, want %q", left, got, want)
+		}
+	}
+
+	lines := map[time.Duration]string{
+		0:                "Code: 483921",
+		5 * time.Minute:  "Code: 483921 (valid for 5m)",
+		30 * time.Second: "Code: 483921 (valid for 30s)",
+		time.Hour:        "Code: 483921 (valid for 1h)",
+		90 * time.Second: "Code: 483921 (valid for 1m30s)",
+	}
+	for expiry, want := range lines {
+		n := Notification{Message: "Read this code to the helpdesk", OTP: "483921", OTPExpiry: expiry}
+		if got := n.otpLine(); got != want {
+			t.Errorf("otpLine() with expiry %v = %q, want %q", expiry, got, want)
+		}
+		if got := n.plainMessage(); got != n.Message+"\n\n"+want {
+			t.Errorf("plainMessage() = %q, want the code after the message", got)
+		}
+	}
+}
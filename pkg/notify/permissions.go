@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// Permission states reported by the macOS privacy (TCC) checks
+const (
+	PermissionGranted       = "granted"
+	PermissionDenied        = "denied"
+	PermissionNotDetermined = "not determined" // Never asked, or a prompt nobody answered (headless)
+	PermissionUnknown       = "unknown"        // macOS offers no way to read it from this process
+)
+
+// PermissionCheck is the state of one macOS privacy permission notify uses
+type PermissionCheck struct {
+	Name     string // As in System Settings > Privacy & Security (or Notifications)
+	Feature  string // What in notify needs it
+	State    string // One of the Permission constants
+	Detail   string
+	Required bool // Notifications fail without it; the others only degrade one feature
+}
+
+// AppIdentity is the process macOS attributes notify's privacy requests to, as PPPC payloads identify it
+type AppIdentity struct {
+	Identifier      string // Bundle ID, or the executable path for a bare binary
+	IdentifierType  string // "bundleID" or "path"
+	CodeRequirement string // Designated requirement (codesign -d -r-), empty when unsigned
+}
+
+// Bundle IDs macOS shows notify's notification center banners under (see native_other.go)
+const (
+	terminalNotifierBundleID = "fr.julienxx.oss.terminal-notifier"
+	scriptEditorBundleID     = "com.apple.ScriptEditor2" // osascript "display notification"
+)
+
+// systemEventsRequirement is the code requirement of System Events, the target of notify's Apple events
+const systemEventsRequirement = `identifier "com.apple.systemevents" and anchor apple`
+
+// PermissionsProfile returns a configuration profile (.mobileconfig) for MDM that grants notify
+// what can be granted without a prompt: Apple events to System Events (PPPC), and banners for
+// notifierBundleID (notification settings). Screen recording cannot be pre-approved by MDM,
+// so the profile only lets standard users approve it themselves
+func PermissionsProfile(app AppIdentity, notifierBundleID string) string {
+	client := fmt.Sprintf(`<key>Identifier</key>
+<string>%s</string>
+<key>IdentifierType</key>
+<string>%s</string>
+<key>CodeRequirement</key>
+<string>%s</string>
+<key>StaticCode</key>
+<false/>`, plistEscape(app.Identifier), plistEscape(app.IdentifierType), plistEscape(app.CodeRequirement))
+
+	profile := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+<key>PayloadContent</key>
+<array>
+<dict>
+<key>PayloadType</key>
+<string>com.apple.TCC.configuration-profile-policy</string>
+<key>PayloadIdentifier</key>
+<string>com.github.amarillier.KrankyBearNotify.pppc</string>
+<key>PayloadUUID</key>
+<string>%s</string>
+<key>PayloadVersion</key>
+<integer>1</integer>
+<key>PayloadDisplayName</key>
+<string>KrankyBearNotify privacy preferences</string>
+<key>Services</key>
+<dict>
+<key>AppleEvents</key>
+<array>
+<dict>
+%s
+<key>AEReceiverIdentifier</key>
+<string>com.apple.systemevents</string>
+<key>AEReceiverIdentifierType</key>
+<string>bundleID</string>
+<key>AEReceiverCodeRequirement</key>
+<string>%s</string>
+<key>Authorization</key>
+<string>Allow</string>
+</dict>
+</array>
+<key>ScreenCapture</key>
+<array>
+<dict>
+%s
+<key>Authorization</key>
+<string>AllowStandardUserToSetSystemService</string>
+</dict>
+</array>
+</dict>
+</dict>
+<dict>
+<key>PayloadType</key>
+<string>com.apple.notificationsettings</string>
+<key>PayloadIdentifier</key>
+<string>com.github.amarillier.KrankyBearNotify.notifications</string>
+<key>PayloadUUID</key>
+<string>%s</string>
+<key>PayloadVersion</key>
+<integer>1</integer>
+<key>PayloadDisplayName</key>
+<string>KrankyBearNotify notifications</string>
+<key>NotificationSettings</key>
+<array>
+<dict>
+<key>BundleIdentifier</key>
+<string>%s</string>
+<key>NotificationsEnabled</key>
+<true/>
+<key>AlertType</key>
+<integer>1</integer>
+<key>ShowInNotificationCenter</key>
+<true/>
+<key>ShowInLockScreen</key>
+<true/>
+<key>SoundsEnabled</key>
+<true/>
+<key>BadgesEnabled</key>
+<true/>
+</dict>
+</array>
+</dict>
+</array>
+<key>PayloadType</key>
+<string>Configuration</string>
+<key>PayloadIdentifier</key>
+<string>com.github.amarillier.KrankyBearNotify.permissions</string>
+<key>PayloadUUID</key>
+<string>%s</string>
+<key>PayloadVersion</key>
+<integer>1</integer>
+<key>PayloadDisplayName</key>
+<string>KrankyBearNotify permissions</string>
+<key>PayloadScope</key>
+<string>System</string>
+</dict>
+</plist>
+`, newUUID(), client, plistEscape(systemEventsRequirement), client, newUUID(), plistEscape(notifierBundleID), newUUID())
+	return profile
+}
+
+// plistEscape escapes s for a plist <string>
+func plistEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// newUUID returns a random (version 4) UUID for profile payloads
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]))
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
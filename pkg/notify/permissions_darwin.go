@@ -0,0 +1,179 @@
+//go:build darwin
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// permissionProbeTimeout bounds the Apple events probe: headless, a consent prompt is never answered
+const permissionProbeTimeout = 10 * time.Second
+
+// CheckPermissions reports the privacy permissions notify uses on this Mac, as seen by this process
+// The Automation probe sends one harmless Apple event, which may show the consent prompt once
+func CheckPermissions() []PermissionCheck {
+	return []PermissionCheck{
+		checkNotificationPermission(),
+		checkAutomationPermission(),
+		checkScreenRecordingPermission(),
+	}
+}
+
+// notifierBundleID returns the bundle -native banners are posted under (see sendNativeNotification)
+func notifierBundleID() (bundleID, name string) {
+	if _, err := exec.LookPath("terminal-notifier"); err == nil {
+		return terminalNotifierBundleID, "terminal-notifier"
+	}
+	return scriptEditorBundleID, "Script Editor (osascript)"
+}
+
+// checkNotificationPermission looks for the notifier's entry in the Notification Center preferences
+// macOS does not expose whether banners are allowed to other processes, only that the app registered
+func checkNotificationPermission() PermissionCheck {
+	bundleID, name := notifierBundleID()
+	check := PermissionCheck{
+		Name:     "Notifications",
+		Feature:  "-native and -quick banners, posted as " + name,
+		Required: true,
+	}
+	output, err := exec.Command("defaults", "export", "com.apple.ncprefs", "-").Output()
+	if err != nil {
+		check.State = PermissionUnknown
+		check.Detail = fmt.Sprintf("could not read the Notification Center preferences: %v", err)
+		return check
+	}
+	if !strings.Contains(string(output), "<string>"+bundleID+"</string>") {
+		check.State = PermissionNotDetermined
+		check.Detail = name + " has not posted a notification for this user yet; the first banner asks for permission"
+		return check
+	}
+	check.State = PermissionUnknown
+	check.Detail = "registered with Notification Center; check System Settings > Notifications > " + name + " allows banners"
+	return check
+}
+
+// checkAutomationPermission sends System Events a read-only Apple event, as -attention-after does
+func checkAutomationPermission() PermissionCheck {
+	check := PermissionCheck{
+		Name:    "Automation (System Events)",
+		Feature: "-attention-after raising the window",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), permissionProbeTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "osascript", "-e", `tell application "System Events" to get name of first process`).CombinedOutput()
+	switch {
+	case ctx.Err() != nil:
+		check.State = PermissionNotDetermined
+		check.Detail = "no answer to the consent prompt within " + permissionProbeTimeout.String()
+	case err == nil:
+		check.State = PermissionGranted
+	case strings.Contains(string(output), "-1743"):
+		check.State = PermissionDenied
+		check.Detail = "not authorized to send Apple events to System Events (error -1743)"
+	default:
+		check.State = PermissionUnknown
+		check.Detail = strings.TrimSpace(string(output))
+	}
+	return check
+}
+
+// checkScreenRecordingPermission asks CoreGraphics (through JavaScript for Automation, without prompting)
+// whether screen capture is allowed; denied and never asked look the same to CGPreflightScreenCaptureAccess
+func checkScreenRecordingPermission() PermissionCheck {
+	check := PermissionCheck{
+		Name:    "Screen Recording",
+		Feature: "-context-screenshot",
+	}
+	output, err := exec.Command("osascript", "-l", "JavaScript", "-e",
+		`ObjC.import("CoreGraphics"); $.CGPreflightScreenCaptureAccess()`).CombinedOutput()
+	switch strings.TrimSpace(string(output)) {
+	case "true":
+		check.State = PermissionGranted
+	case "false":
+		check.State = PermissionDenied
+		check.Detail = "screenshots only show the desktop background; MDM cannot grant this, the user has to allow it"
+	default:
+		check.State = PermissionUnknown
+		check.Detail = fmt.Sprintf("could not query CoreGraphics: %v", err)
+	}
+	return check
+}
+
+// CurrentAppIdentity returns how PPPC payloads identify this notify: its app bundle when it runs
+// from KrankyBearNotify.app, its path otherwise, with the designated requirement from codesign
+func CurrentAppIdentity() (AppIdentity, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return AppIdentity{}, fmt.Errorf("failed to get executable path: %v", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+
+	app := AppIdentity{Identifier: exePath, IdentifierType: "path"}
+	signedPath := exePath
+	if i := strings.Index(exePath, ".app/Contents/MacOS/"); i >= 0 {
+		bundlePath := exePath[:i+len(".app")]
+		output, err := exec.Command("defaults", "read", filepath.Join(bundlePath, "Contents", "Info"), "CFBundleIdentifier").Output()
+		if err == nil {
+			app = AppIdentity{Identifier: strings.TrimSpace(string(output)), IdentifierType: "bundleID"}
+			signedPath = bundlePath
+		}
+	}
+
+	// codesign prints "designated => REQUIREMENT"; unsigned code has none
+	output, _ := exec.Command("codesign", "-d", "-r-", signedPath).CombinedOutput()
+	for _, line := range strings.Split(string(output), "\n") {
+		if requirement, ok := strings.CutPrefix(line, "designated => "); ok {
+			app.CodeRequirement = strings.TrimSpace(requirement)
+		}
+	}
+	return app, nil
+}
+
+// ReportPermissions prints the permission checks (-check-permissions) and the configuration profile
+// that grants what MDM can grant; returns true when every permission is granted
+func ReportPermissions() bool {
+	allGranted := true
+	fmt.Println("macOS privacy permissions for this process:")
+	for _, check := range CheckPermissions() {
+		required := ""
+		if check.Required {
+			required = ", required"
+		}
+		fmt.Printf("  %-28s %-15s (%s%s)\n", check.Name, check.State, check.Feature, required)
+		if check.Detail != "" {
+			fmt.Printf("  %-28s %s\n", "", check.Detail)
+		}
+		if check.State != PermissionGranted {
+			allGranted = false
+		}
+	}
+	fmt.Println()
+	fmt.Println("Permissions belong to the responsible process: the app that launched notify (Terminal, your")
+	fmt.Println("MDM agent), or notify itself when launchd runs it. Run this check the way notify is deployed.")
+
+	app, err := CurrentAppIdentity()
+	if err != nil {
+		fmt.Printf("Could not build the configuration profile: %v\n", err)
+		return false
+	}
+	bundleID, _ := notifierBundleID()
+	fmt.Println()
+	fmt.Printf("Configuration profile for %s (%s):\n", app.Identifier, app.IdentifierType)
+	if app.CodeRequirement == "" {
+		fmt.Println("Warning: notify is not code signed, so PPPC payloads cannot identify it; sign it (codesign) and run this again")
+	}
+	fmt.Println("Save the following as KrankyBearNotify.mobileconfig and deploy it with your MDM (user-approved MDM required):")
+	fmt.Println()
+	fmt.Print(PermissionsProfile(app, bundleID))
+	return allGranted
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package notify
+
+import "fmt"
+
+// ReportPermissions reports that the privacy permission check is specific to macOS (-check-permissions)
+func ReportPermissions() bool {
+	fmt.Println("Permission check is only available on macOS")
+	return false
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestPermissionsProfile tests that the MDM profile is well-formed and identifies notify and its notifier
+func TestPermissionsProfile(t *testing.T) {
+	app := AppIdentity{
+		Identifier:      "com.github.amarillier.KrankyBearNotify",
+		IdentifierType:  "bundleID",
+		CodeRequirement: `identifier "com.github.amarillier.KrankyBearNotify" and anchor apple generic and certificate leaf[subject.OU] = "ABCDE12345"`,
+	}
+	profile := PermissionsProfile(app, terminalNotifierBundleID)
+
+	decoder := xml.NewDecoder(strings.NewReader(profile))
+	decoder.Strict = true
+	var strs []string
+	inString := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Profile is not well-formed XML: %v", err)
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			inString = tok.Name.Local == "string"
+		case xml.CharData:
+			if inString {
+				strs = append(strs, string(tok))
+			}
+		case xml.EndElement:
+			inString = false
+		}
+	}
+
+	joined := strings.Join(strs, "\n")
+	for _, want := range []string{
+		"com.apple.TCC.configuration-profile-policy",
+		"com.apple.notificationsettings",
+		app.Identifier,
+		app.CodeRequirement, // Unescaped again by the decoder
+		systemEventsRequirement,
+		"AllowStandardUserToSetSystemService",
+		terminalNotifierBundleID,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Profile does not contain %q", want)
+		}
+	}
+
+	if strings.Count(profile, "<key>PayloadUUID</key>") != 3 {
+		t.Errorf("Expected three payload UUIDs")
+	}
+	if a, b := newUUID(), newUUID(); a == b || len(a) != 36 || a[14] != '4' {
+		t.Errorf("newUUID() returned %q and %q, want distinct version 4 UUIDs", a, b)
+	}
+}
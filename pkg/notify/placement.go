@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Virtual desktops for Notification.Desktop (Windows)
+const (
+	DesktopActive = "active" // Default: the desktop the user is viewing when the window opens
+	DesktopAll    = "all"    // Every desktop (the window has no taskbar button then)
+)
+
+// PositionCategory as Notification.PositionID remembers the window under its Category instead of an ID
+const PositionCategory = "category"
+
+// maxWindowPositions bounds the remembered positions, the oldest are forgotten first
+const maxWindowPositions = 200
+
+// ValidateDesktop checks a -desktop value
+func ValidateDesktop(desktop string) error {
+	switch desktop {
+	case "", DesktopActive, DesktopAll:
+		return nil
+	}
+	return fmt.Errorf("invalid desktop %q (use %s or %s)", desktop, DesktopActive, DesktopAll)
+}
+
+// fullScreen reports whether the window of n covers the whole screen: -fullscreen or break glass
+func (n Notification) fullScreen() bool {
+	return n.Fullscreen || n.Priority == PriorityBreakGlass
+}
+
+// fullScreenCSS returns the WebView styles of a full-screen window: a larger card in the middle
+// of the screen
+func fullScreenCSS(n Notification) string {
+	if !n.fullScreen() {
+		return ""
+	}
+	return `.notification-card { max-width: 720px; padding: 48px; }`
+}
+
+// stayOnTop reports whether the window of n is kept above other windows: -topmost or critical urgency
+func (n Notification) stayOnTop() bool {
+	return n.Topmost || n.Urgency == UrgencyCritical
+}
+
+// positionKey returns the key the window of n is remembered under: n.PositionID, or the
+// category for -remember-position category (nothing for an uncategorized notification)
+func (n Notification) positionKey() string {
+	if n.PositionID != PositionCategory {
+		return n.PositionID
+	}
+	if n.Category == "" {
+		return ""
+	}
+	return "category:" + n.Category
+}
+
+// windowPosition is where the user last left a window shown with Notification.PositionID, and
+// the size they gave it (zero in files written before sizes were remembered)
+type windowPosition struct {
+	X      int       `json:"x"`
+	Y      int       `json:"y"`
+	Width  int       `json:"width,omitempty"`
+	Height int       `json:"height,omitempty"`
+	Saved  time.Time `json:"saved"`
+}
+
+// fitWindowPosition returns where a remembered window reopens: moved, and shrunk if needed,
+// onto the screen that shows most of it, so a monitor that was rearranged or resized does not
+// leave it partly off screen; ok is false when no screen shows any of it (its monitor was
+// disconnected) and the window stays centered
+func fitWindowPosition(p windowPosition, screens []screen) (fitted windowPosition, ok bool) {
+	var best screen
+	bestArea := 0
+	for _, s := range screens {
+		width := min(p.X+p.Width, s.X+s.Width) - max(p.X, s.X)
+		height := min(p.Y+p.Height, s.Y+s.Height) - max(p.Y, s.Y)
+		if width > 0 && height > 0 && width*height > bestArea {
+			best, bestArea = s, width*height
+		}
+	}
+	if bestArea == 0 {
+		return windowPosition{}, false
+	}
+	fitted = windowPosition{Width: min(p.Width, best.Width), Height: min(p.Height, best.Height), Saved: p.Saved}
+	fitted.X = max(best.X, min(p.X, best.X+best.Width-fitted.Width))
+	fitted.Y = max(best.Y, min(p.Y, best.Y+best.Height-fitted.Height))
+	return fitted, true
+}
+
+// windowPositionsPath returns the file remembering window positions for the current user
+func windowPositionsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "positions.json"), nil
+}
+
+// loadWindowPositions reads the remembered positions by PositionID
+func loadWindowPositions() (map[string]windowPosition, error) {
+	path, err := windowPositionsPath()
+	if err != nil {
+		return nil, err
+	}
+	positions := map[string]windowPosition{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return positions, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read window positions: %v", err)
+	}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, fmt.Errorf("could not parse window positions %s: %v", path, err)
+	}
+	return positions, nil
+}
+
+// saveWindowPosition remembers the position and size of the window shown with id
+func saveWindowPosition(id string, x, y, width, height int) error {
+	positions, err := loadWindowPositions()
+	if err != nil {
+		positions = map[string]windowPosition{} // Start over rather than never remember again
+	}
+	positions[id] = windowPosition{X: x, Y: y, Width: width, Height: height, Saved: time.Now()}
+	for len(positions) > maxWindowPositions {
+		oldest := ""
+		for key, position := range positions {
+			if oldest == "" || position.Saved.Before(positions[oldest].Saved) {
+				oldest = key
+			}
+		}
+		delete(positions, oldest)
+	}
+
+	path, err := windowPositionsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %v", err)
+	}
+	data, err := json.MarshalIndent(positions, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Written to a temporary file first so a concurrent notify never reads half a file
+	tmp := path + fmt.Sprintf(".%d.tmp", os.Getpid())
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("could not write window positions: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("could not write window positions: %v", err)
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,75 @@
+//go:build !windows
+
+package notify
+
+import "log"
+
+// placeWindow is a stub for non-Windows platforms: virtual desktop placement and capture
+// exclusion need the window APIs of Windows; n.Frameless, n.Monitor, n.PositionID (see
+// positionKey), and n.Topmost or critical n.Urgency (on top), are applied where
+// removeWindowFrame, moveToMonitor, restoreWindowPosition and keepWindowOnTop can
+// The returned function, called after the window closed, remembers the last position and size
+// under n.PositionID; n.Monitor takes precedence over a remembered position, which takes
+// precedence over the window slot (see claimWindowSlot), and fullScreen windows (-fullscreen,
+// break glass) cover the screen, so they are left where they are
+func placeWindow(title string, n Notification, fullScreen bool, slot int) (done func()) {
+	if n.Desktop == DesktopAll {
+		log.Printf("Placement: -desktop is only supported on Windows")
+	}
+	if n.Sensitive {
+		log.Printf("Warning: -sensitive cannot keep the window out of screen capture on this platform")
+	}
+	if n.Frameless && !fullScreen {
+		go func() {
+			if err := removeWindowFrame(title); err != nil {
+				log.Printf("Warning: Could not remove the window frame: %v", err)
+			}
+		}()
+	}
+	key := n.positionKey()
+	if fullScreen {
+		key = ""
+	}
+	switch {
+	case n.Monitor != "" && !fullScreen:
+		go func() {
+			if err := moveToMonitor(title, n.Monitor); err != nil {
+				log.Printf("Warning: Could not move the window to monitor %s: %v", n.Monitor, err)
+			}
+		}()
+	case key != "":
+		go func() {
+			if err := restoreWindowPosition(title, key); err != nil {
+				log.Printf("Placement: could not restore the position: %v", err)
+			}
+		}()
+	case slot > 0 && !fullScreen:
+		go func() {
+			if err := moveToWindowSlot(title, slot); err != nil {
+				log.Printf("Placement: could not stack the window: %v", err)
+			}
+		}()
+	}
+	if n.stayOnTop() && !fullScreen {
+		go func() {
+			if err := keepWindowOnTop(title); err != nil {
+				log.Printf("Warning: Could not keep the window on top: %v", err)
+			}
+		}()
+	}
+	if key == "" {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		trackWindowPosition(title, key, stop)
+	}()
+	return func() {
+		close(stop)
+		<-finished
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestValidateDesktop tests the accepted -desktop values
+func TestValidateDesktop(t *testing.T) {
+	for _, desktop := range []string{"", DesktopActive, DesktopAll} {
+		if err := ValidateDesktop(desktop); err != nil {
+			t.Errorf("ValidateDesktop(%q) = %v", desktop, err)
+		}
+	}
+	if err := ValidateDesktop("current"); err == nil {
+		t.Error("ValidateDesktop(\"current\") accepted an unknown desktop")
+	}
+}
+
+// TestWindowPositions tests that positions are remembered per ID and the oldest are forgotten
+func TestWindowPositions(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // os.UserConfigDir on Linux
+	t.Setenv("HOME", dir)            // macOS
+	t.Setenv("AppData", dir)         // Windows
+
+	if err := saveWindowPosition("patch-reminder", -1280, 200, 0, 0); err != nil {
+		t.Fatalf("saveWindowPosition failed: %v", err)
+	}
+	if err := saveWindowPosition("survey", 10, 20, 640, 360); err != nil {
+		t.Fatalf("saveWindowPosition failed: %v", err)
+	}
+	positions, err := loadWindowPositions()
+	if err != nil {
+		t.Fatalf("loadWindowPositions failed: %v", err)
+	}
+	if got := positions["patch-reminder"]; got.X != -1280 || got.Y != 200 {
+		t.Errorf("patch-reminder at %d,%d, want -1280,200 (left of the primary monitor)", got.X, got.Y)
+	}
+	if got := positions["survey"]; got.X != 10 || got.Y != 20 || got.Width != 640 || got.Height != 360 {
+		t.Errorf("survey at %d,%d, %dx%d, want 10,20, 640x360", got.X, got.Y, got.Width, got.Height)
+	}
+
+	for i := 0; i < maxWindowPositions; i++ {
+		saveWindowPosition(fmt.Sprintf("id-%d", i), i, i, 0, 0)
+	}
+	positions, _ = loadWindowPositions()
+	if len(positions) != maxWindowPositions {
+		t.Errorf("%d positions remembered, want at most %d", len(positions), maxWindowPositions)
+	}
+	if _, ok := positions["patch-reminder"]; ok {
+		t.Error("the oldest position was not forgotten")
+	}
+	if _, ok := positions[fmt.Sprintf("id-%d", maxWindowPositions-1)]; !ok {
+		t.Error("the newest position was forgotten")
+	}
+}
+
+// TestStayOnTop tests that -topmost and critical urgency keep the window on top
+func TestStayOnTop(t *testing.T) {
+	tests := []struct {
+		n    Notification
+		want bool
+	}{
+		{Notification{}, false},
+		{Notification{Topmost: true}, true},
+		{Notification{Urgency: UrgencyWarning}, false},
+		{Notification{Urgency: UrgencyCritical}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.n.stayOnTop(); got != tt.want {
+			t.Errorf("stayOnTop(topmost %v, urgency %q) = %v, want %v", tt.n.Topmost, tt.n.Urgency, got, tt.want)
+		}
+	}
+}
+
+// TestFullScreen tests that -fullscreen and break glass cover the screen, with a larger WebView card
+func TestFullScreen(t *testing.T) {
+	tests := []struct {
+		n    Notification
+		want bool
+	}{
+		{Notification{}, false},
+		{Notification{Fullscreen: true}, true},
+		{Notification{Priority: PriorityBreakGlass}, true},
+		{Notification{Urgency: UrgencyCritical, Topmost: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.n.fullScreen(); got != tt.want {
+			t.Errorf("fullScreen(fullscreen %v, priority %q) = %v, want %v", tt.n.Fullscreen, tt.n.Priority, got, tt.want)
+		}
+		if css := fullScreenCSS(tt.n); (css != "") != tt.want {
+			t.Errorf("fullScreenCSS(fullscreen %v, priority %q) = %q", tt.n.Fullscreen, tt.n.Priority, css)
+		}
+	}
+}
+
+// TestFitWindowPosition tests restoring remembered windows after the monitors changed
+func TestFitWindowPosition(t *testing.T) {
+	screens := []screen{
+		{X: 0, Y: 0, Width: 1920, Height: 1040, Primary: true},
+		{X: 1920, Y: 0, Width: 1280, Height: 984},
+	}
+	tests := []struct {
+		name   string
+		p      windowPosition
+		want   windowPosition
+		wantOK bool
+	}{
+		{"unchanged", windowPosition{X: 100, Y: 50, Width: 500, Height: 300}, windowPosition{X: 100, Y: 50, Width: 500, Height: 300}, true},
+		{"on the second monitor", windowPosition{X: 2000, Y: 700, Width: 500, Height: 300}, windowPosition{X: 2000, Y: 684, Width: 500, Height: 300}, true},
+		{"across both, mostly the first", windowPosition{X: 1700, Y: 0, Width: 400, Height: 300}, windowPosition{X: 1520, Y: 0, Width: 400, Height: 300}, true},
+		{"larger than the monitor", windowPosition{X: 1920, Y: 0, Width: 1600, Height: 1200}, windowPosition{X: 1920, Y: 0, Width: 1280, Height: 984}, true},
+		{"monitor disconnected", windowPosition{X: -1280, Y: 200, Width: 500, Height: 300}, windowPosition{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := fitWindowPosition(tt.p, screens)
+		if ok != tt.wantOK || got.X != tt.want.X || got.Y != tt.want.Y || got.Width != tt.want.Width || got.Height != tt.want.Height {
+			t.Errorf("%s: fitWindowPosition = %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// TestPositionKey tests remembering windows by ID or by category
+func TestPositionKey(t *testing.T) {
+	tests := []struct {
+		n    Notification
+		want string
+	}{
+		{Notification{}, ""},
+		{Notification{PositionID: "patch-reminder", Category: "patching"}, "patch-reminder"},
+		{Notification{PositionID: PositionCategory, Category: "patching"}, "category:patching"},
+		{Notification{PositionID: PositionCategory}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.n.positionKey(); got != tt.want {
+			t.Errorf("positionKey(%q, category %q) = %q, want %q", tt.n.PositionID, tt.n.Category, got, tt.want)
+		}
+	}
+}
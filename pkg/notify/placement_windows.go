@@ -0,0 +1,341 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Window placement functions in user32.dll and COM in ole32.dll
+var (
+	findWindowEx             = user32.NewProc("FindWindowExW")
+	getWindowThreadProcessID = user32.NewProc("GetWindowThreadProcessId")
+	getForegroundWindow      = user32.NewProc("GetForegroundWindow")
+	isWindow                 = user32.NewProc("IsWindow")
+	getWindowRect            = user32.NewProc("GetWindowRect")
+	setWindowPos             = user32.NewProc("SetWindowPos")
+	getWindowLong            = user32.NewProc("GetWindowLongW")
+	setWindowLong            = user32.NewProc("SetWindowLongW")
+	showWindowProc           = user32.NewProc("ShowWindow")
+	setWindowDisplayAffinity = user32.NewProc("SetWindowDisplayAffinity")
+
+	ole32            = syscall.NewLazyDLL("ole32.dll")
+	coInitializeEx   = ole32.NewProc("CoInitializeEx")
+	coUninitialize   = ole32.NewProc("CoUninitialize")
+	coCreateInstance = ole32.NewProc("CoCreateInstance")
+)
+
+// winGUID is a Windows GUID
+type winGUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// winRect is RECT
+type winRect struct {
+	Left, Top, Right, Bottom int32
+}
+
+// CLSID_VirtualDesktopManager and IID_IVirtualDesktopManager (shobjidl_core.h, Windows 10 and later)
+var (
+	clsidVirtualDesktopManager = winGUID{0xaa509086, 0x5ca9, 0x4c25, [8]byte{0x8f, 0x95, 0x58, 0x9d, 0x3c, 0x07, 0xb4, 0x8a}}
+	iidVirtualDesktopManager   = winGUID{0xa5cd92ff, 0x29be, 0x454c, [8]byte{0x8d, 0x04, 0xd8, 0x28, 0x79, 0xfb, 0x3f, 0x1b}}
+)
+
+// virtualDesktopManager is an IVirtualDesktopManager COM object: IUnknown (QueryInterface,
+// AddRef, Release), then IsWindowOnCurrentVirtualDesktop, GetWindowDesktopId, MoveWindowToDesktop
+type virtualDesktopManager struct {
+	vtbl *[6]uintptr
+}
+
+// placeWindow applies n.Frameless, n.Monitor, n.Desktop, n.PositionID (see positionKey), n.Sensitive and n.Topmost or critical n.Urgency (on top) to this process's window titled title
+// once it exists, and tracks where the user moves it; the returned function, called after the
+// window closed, remembers the last position and size under n.PositionID
+// fullScreen windows (-fullscreen, break glass) cover the screen, so only the desktop applies;
+// n.Monitor takes precedence over a remembered position, which takes precedence over the
+// window slot (see claimWindowSlot)
+func placeWindow(title string, n Notification, fullScreen bool, slot int) (done func()) {
+	key := n.positionKey()
+	if fullScreen {
+		key = ""
+	}
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		hwnd := waitForWindow(title, 5*time.Second, stop)
+		if hwnd == 0 {
+			log.Printf("Placement: window %q not found", title)
+			return
+		}
+
+		if n.Sensitive {
+			excludeFromCapture(hwnd)
+		}
+		if n.stayOnTop() && !fullScreen {
+			keepOnTop(hwnd)
+		}
+		if n.Frameless && !fullScreen {
+			removeFrame(hwnd)
+		}
+		switch {
+		case n.Monitor != "" && !fullScreen:
+			if err := moveToMonitor(hwnd, n.Monitor); err != nil {
+				log.Printf("Warning: Could not move the window to monitor %s: %v", n.Monitor, err)
+			}
+		case key != "":
+			restoreWindowPosition(hwnd, key)
+		case slot > 0 && !fullScreen:
+			if err := moveToWindowSlot(hwnd, slot); err != nil {
+				log.Printf("Placement: could not stack the window: %v", err)
+			}
+		}
+		switch n.Desktop {
+		case DesktopAll:
+			showOnAllDesktops(hwnd)
+		default:
+			if err := moveToActiveDesktop(hwnd); err != nil {
+				log.Printf("Placement: could not move the window to the active virtual desktop: %v", err)
+			}
+		}
+
+		if key != "" {
+			trackWindowPosition(hwnd, key, stop)
+		}
+	}()
+	return func() {
+		close(stop)
+		<-finished
+	}
+}
+
+// waitForWindow returns this process's top-level window titled title, waiting up to timeout
+// for the GUI to create it, or 0
+func waitForWindow(title string, timeout time.Duration, stop <-chan struct{}) uintptr {
+	titlePtr, err := syscall.UTF16PtrFromString(title)
+	if err != nil {
+		return 0
+	}
+	pid := uint32(syscall.Getpid())
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		// Several windows can share the title, even in other notify processes
+		var hwnd uintptr
+		for {
+			hwnd, _, _ = findWindowEx.Call(0, hwnd, 0, uintptr(unsafe.Pointer(titlePtr)))
+			if hwnd == 0 {
+				break
+			}
+			var windowPID uint32
+			getWindowThreadProcessID.Call(hwnd, uintptr(unsafe.Pointer(&windowPID)))
+			if windowPID == pid {
+				return hwnd
+			}
+		}
+		select {
+		case <-stop:
+			return 0
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return 0
+}
+
+// restoreWindowPosition moves and sizes hwnd as the window shown with id was last left, fitted
+// onto the monitors connected now (see fitWindowPosition)
+func restoreWindowPosition(hwnd uintptr, id string) {
+	const (
+		SWP_NOZORDER   = 0x0004
+		SWP_NOACTIVATE = 0x0010
+	)
+	positions, err := loadWindowPositions()
+	if err != nil {
+		log.Printf("Placement: %v", err)
+		return
+	}
+	position, ok := positions[id]
+	if !ok {
+		return
+	}
+
+	if position.Width <= 0 || position.Height <= 0 {
+		var rect winRect
+		getWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&rect)))
+		position.Width, position.Height = int(rect.Right-rect.Left), int(rect.Bottom-rect.Top)
+	}
+	screens, err := listScreens()
+	if err != nil {
+		log.Printf("Placement: %v", err)
+		return
+	}
+	fitted, ok := fitWindowPosition(position, screens)
+	if !ok {
+		log.Printf("Placement: remembered position %d,%d for %q is off screen, centering instead", position.X, position.Y, id)
+		return
+	}
+	setWindowPos.Call(hwnd, 0, uintptr(fitted.X), uintptr(fitted.Y), uintptr(fitted.Width), uintptr(fitted.Height), SWP_NOZORDER|SWP_NOACTIVATE)
+	log.Printf("Placement: restored %q to %d,%d, %dx%d", id, fitted.X, fitted.Y, fitted.Width, fitted.Height)
+}
+
+// trackWindowPosition follows hwnd until it is destroyed or stop is closed, then remembers its
+// last position under id (polling, because the GUI library does not report moves)
+func trackWindowPosition(hwnd uintptr, id string, stop <-chan struct{}) {
+	var last winRect
+	known := false
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for open := true; open; {
+		if alive, _, _ := isWindow.Call(hwnd); alive == 0 {
+			break
+		}
+		var rect winRect
+		if ok, _, _ := getWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&rect))); ok != 0 && rect.Right > rect.Left {
+			last, known = rect, true
+		}
+		select {
+		case <-stop:
+			open = false
+		case <-ticker.C:
+		}
+	}
+	if !known {
+		return
+	}
+	if err := saveWindowPosition(id, int(last.Left), int(last.Top), int(last.Right-last.Left), int(last.Bottom-last.Top)); err != nil {
+		log.Printf("Placement: could not remember the position: %v", err)
+	}
+}
+
+// showOnAllDesktops makes hwnd a tool window, which Windows shows on every virtual desktop
+// The only public way: pinning a window to all desktops is not in the documented API
+func showOnAllDesktops(hwnd uintptr) {
+	const (
+		WS_EX_TOOLWINDOW = 0x00000080
+		WS_EX_APPWINDOW  = 0x00040000
+		SW_HIDE          = 0
+		SW_SHOWNA        = 8
+	)
+	gwlExStyle := int32(-20) // GWL_EXSTYLE
+	style, _, _ := getWindowLong.Call(hwnd, uintptr(gwlExStyle))
+	style = (style | WS_EX_TOOLWINDOW) &^ WS_EX_APPWINDOW
+	// The taskbar and the desktops only notice the new style when the window is shown again
+	showWindowProc.Call(hwnd, SW_HIDE)
+	setWindowLong.Call(hwnd, uintptr(gwlExStyle), style)
+	showWindowProc.Call(hwnd, SW_SHOWNA)
+	log.Printf("Placement: window shown on all virtual desktops")
+}
+
+// keepOnTop makes hwnd a topmost window, which stays above other (non-topmost) windows
+func keepOnTop(hwnd uintptr) {
+	const (
+		HWND_TOPMOST   = ^uintptr(0) // (HWND)-1
+		SWP_NOSIZE     = 0x0001
+		SWP_NOMOVE     = 0x0002
+		SWP_NOACTIVATE = 0x0010
+	)
+	if ok, _, err := setWindowPos.Call(hwnd, HWND_TOPMOST, 0, 0, 0, 0, SWP_NOSIZE|SWP_NOMOVE|SWP_NOACTIVATE); ok == 0 {
+		log.Printf("Warning: Could not keep the window on top: %v", err)
+		return
+	}
+	log.Printf("Placement: window kept on top")
+}
+
+// removeFrame removes the title bar and borders of hwnd; the window keeps its client area, so
+// it shrinks by the size of its frame
+func removeFrame(hwnd uintptr) {
+	const (
+		WS_MAXIMIZEBOX   = 0x00010000
+		WS_MINIMIZEBOX   = 0x00020000
+		WS_THICKFRAME    = 0x00040000
+		WS_SYSMENU       = 0x00080000
+		WS_CAPTION       = 0x00C00000
+		SWP_NOSIZE       = 0x0001
+		SWP_NOMOVE       = 0x0002
+		SWP_NOZORDER     = 0x0004
+		SWP_NOACTIVATE   = 0x0010
+		SWP_FRAMECHANGED = 0x0020
+	)
+	gwlStyle := int32(-16) // GWL_STYLE
+	style, _, _ := getWindowLong.Call(hwnd, uintptr(gwlStyle))
+	style &^= WS_CAPTION | WS_THICKFRAME | WS_SYSMENU | WS_MINIMIZEBOX | WS_MAXIMIZEBOX
+	setWindowLong.Call(hwnd, uintptr(gwlStyle), style)
+	// The new style only takes effect when the frame is recalculated
+	if ok, _, err := setWindowPos.Call(hwnd, 0, 0, 0, 0, 0, SWP_NOSIZE|SWP_NOMOVE|SWP_NOZORDER|SWP_NOACTIVATE|SWP_FRAMECHANGED); ok == 0 {
+		log.Printf("Warning: Could not remove the window frame: %v", err)
+		return
+	}
+	log.Printf("Placement: window frame removed")
+}
+
+// excludeFromCapture keeps hwnd out of screenshots, screen recordings and screen sharing
+// (it shows as black on Windows versions before 10 2004, which lack WDA_EXCLUDEFROMCAPTURE)
+func excludeFromCapture(hwnd uintptr) {
+	const (
+		WDA_MONITOR            = 0x01
+		WDA_EXCLUDEFROMCAPTURE = 0x11
+	)
+	if ok, _, _ := setWindowDisplayAffinity.Call(hwnd, WDA_EXCLUDEFROMCAPTURE); ok != 0 {
+		log.Printf("Placement: window excluded from screen capture")
+		return
+	}
+	if ok, _, err := setWindowDisplayAffinity.Call(hwnd, WDA_MONITOR); ok == 0 {
+		log.Printf("Warning: Could not exclude the window from screen capture: %v", err)
+		return
+	}
+	log.Printf("Placement: window blacked out in screen capture")
+}
+
+// moveToActiveDesktop moves hwnd to the virtual desktop of the foreground window, the one the
+// user is viewing, when Windows opened it on another (as with windows of scheduled tasks)
+func moveToActiveDesktop(hwnd uintptr) error {
+	const (
+		COINIT_MULTITHREADED = 0x0
+		CLSCTX_ALL           = 0x17
+	)
+	// COM is initialized per thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if hr, _, _ := coInitializeEx.Call(0, COINIT_MULTITHREADED); int32(hr) >= 0 {
+		defer coUninitialize.Call()
+	}
+
+	var manager *virtualDesktopManager
+	hr, _, _ := coCreateInstance.Call(uintptr(unsafe.Pointer(&clsidVirtualDesktopManager)), 0, CLSCTX_ALL,
+		uintptr(unsafe.Pointer(&iidVirtualDesktopManager)), uintptr(unsafe.Pointer(&manager)))
+	if int32(hr) < 0 || manager == nil {
+		return fmt.Errorf("virtual desktops not available (HRESULT 0x%08x)", uint32(hr))
+	}
+	this := uintptr(unsafe.Pointer(manager))
+	defer syscall.SyscallN(manager.vtbl[2], this) // Release
+
+	var onCurrent int32
+	if hr, _, _ := syscall.SyscallN(manager.vtbl[3], this, hwnd, uintptr(unsafe.Pointer(&onCurrent))); int32(hr) < 0 {
+		return fmt.Errorf("IsWindowOnCurrentVirtualDesktop failed (HRESULT 0x%08x)", uint32(hr))
+	}
+	if onCurrent != 0 {
+		return nil
+	}
+
+	foreground, _, _ := getForegroundWindow.Call()
+	if foreground == 0 {
+		return fmt.Errorf("no foreground window to find the active desktop from")
+	}
+	var desktop winGUID
+	if hr, _, _ := syscall.SyscallN(manager.vtbl[4], this, foreground, uintptr(unsafe.Pointer(&desktop))); int32(hr) < 0 {
+		return fmt.Errorf("GetWindowDesktopId failed (HRESULT 0x%08x)", uint32(hr))
+	}
+	if hr, _, _ := syscall.SyscallN(manager.vtbl[5], this, hwnd, uintptr(unsafe.Pointer(&desktop))); int32(hr) < 0 {
+		return fmt.Errorf("MoveWindowToDesktop failed (HRESULT 0x%08x)", uint32(hr))
+	}
+	log.Printf("Placement: moved the window to the active virtual desktop")
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sessions for PlanTarget.Session: where notify would be started on the target machine
+const (
+	SessionDesktop  = "desktop"  // A user's own graphical session (console or local login)
+	SessionRDP      = "rdp"      // A user's Windows Remote Desktop session
+	SessionSSH      = "ssh"      // A user's remote shell, without a display
+	SessionSystem   = "system"   // root/SYSTEM (service, scheduled task, management agent) with users logged in
+	SessionHeadless = "headless" // No display and nobody logged in graphically
+)
+
+// PlanTarget describes the machine a delivery plan is evaluated for, which need not be this one
+type PlanTarget struct {
+	OS      string // "windows", "darwin" or "linux" (as runtime.GOOS)
+	Session string // One of the Session constants
+	WebView bool   // The notify build on the target has WebView support (-tags webview, or Windows)
+}
+
+// PlanStep is one mechanism of a delivery plan
+type PlanStep struct {
+	Method   string // Result.Method the step reports, e.g. "fyne", "webview", "toast", "wall", "users"
+	Detail   string // What the step does, or why it might not apply
+	Fallback bool   // Only used when the steps before it are unavailable or fail
+	InUser   bool   // Run by the notify process launched in each logged-in user's session
+}
+
+// ValidatePlanTarget checks the -os and -session values of a plan; "macos" is accepted for darwin
+func ValidatePlanTarget(target PlanTarget) (PlanTarget, error) {
+	target.OS = strings.ToLower(target.OS)
+	if target.OS == "macos" {
+		target.OS = "darwin"
+	}
+	switch target.OS {
+	case "windows", "darwin", "linux":
+	default:
+		return target, fmt.Errorf("invalid -os %q (use windows, macos or linux)", target.OS)
+	}
+	target.Session = strings.ToLower(target.Session)
+	switch target.Session {
+	case SessionDesktop, SessionSSH, SessionSystem, SessionHeadless:
+	case SessionRDP:
+		if target.OS != "windows" {
+			return target, fmt.Errorf("-session rdp is only modeled for Windows (use desktop for xrdp and VNC sessions)")
+		}
+	default:
+		return target, fmt.Errorf("invalid -session %q (use %s, %s, %s, %s or %s)",
+			target.Session, SessionDesktop, SessionRDP, SessionSSH, SessionSystem, SessionHeadless)
+	}
+	return target, nil
+}
+
+// DeliveryPlan returns the mechanisms Notifier.Send would use for opts on target, in order,
+// without detecting or showing anything; the error says why delivery would fail there
+// The plan assumes what is typical for the session: OpenGL on desktops but not over RDP,
+// wall installed on Linux, and for SessionSystem at least one logged-in user
+func DeliveryPlan(opts Options, target PlanTarget) ([]PlanStep, error) {
+	target, err := ValidatePlanTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	mode := opts.Mode
+	if mode == "" || !opts.DeliverBy.IsZero() {
+		mode = ModeAuto // Escalation chooses the channels itself
+	}
+	switch mode {
+	case ModeAuto, ModeQuick, ModeNative, ModeWebView, ModeBasic, ModeWall:
+	default:
+		return nil, fmt.Errorf("unknown delivery mode %q (use auto, quick, native, webview, basic or wall)", opts.Mode)
+	}
+
+	// Modes that never fan out to other users
+	switch mode {
+	case ModeQuick:
+		return quickPlan(target)
+	case ModeWall:
+		if target.OS != "linux" {
+			return nil, fmt.Errorf("wall mode is only available on Linux")
+		}
+		return []PlanStep{{Method: "wall", Detail: "wall broadcast to every terminal"}}, nil
+	case ModeBasic:
+		if target.OS != "windows" {
+			return nil, fmt.Errorf("basic mode is only supported on Windows")
+		}
+	}
+
+	if target.Session == SessionSystem {
+		steps := []PlanStep{usersStep(opts, target)}
+		if target.OS == "linux" && !opts.GUIOnly && mode == ModeAuto {
+			steps = append(steps, PlanStep{Method: "wall", Detail: "also broadcast to terminal sessions (-gui-only skips it)"})
+		}
+		userTarget := target
+		userTarget.Session = SessionDesktop
+		userSteps, err := sessionPlan(mode, userTarget)
+		for _, step := range userSteps {
+			step.InUser = true
+			steps = append(steps, step)
+		}
+		return steps, err
+	}
+	return sessionPlan(mode, target)
+}
+
+// usersStep describes the fan-out to logged-in users when running as root/SYSTEM
+func usersStep(opts Options, target PlanTarget) PlanStep {
+	step := PlanStep{Method: "users"}
+	switch target.OS {
+	case "windows":
+		disconnected := opts.Disconnected
+		if disconnected == "" {
+			disconnected = DisconnectedDeliverOnReconnect
+		}
+		step.Detail = fmt.Sprintf("notify relaunched in each logged-in user's session; disconnected sessions: %s", disconnected)
+	case "darwin":
+		step.Detail = "notify relaunched in the console user's session (launchctl asuser)"
+	default:
+		step.Detail = "notify relaunched in each graphical session (sudo -u with the session's display)"
+	}
+	return step
+}
+
+// quickPlan is the plan of ModeQuick, which only reaches the session notify runs in
+func quickPlan(target PlanTarget) ([]PlanStep, error) {
+	if target.Session != SessionDesktop && target.Session != SessionRDP {
+		return nil, fmt.Errorf("quick mode only reaches the session notify runs in, which has no desktop in a %s session", target.Session)
+	}
+	switch target.OS {
+	case "windows":
+		return []PlanStep{{Method: "wtsmessage", Detail: "WTSSendMessage message box, within 500 ms"}}, nil
+	case "darwin":
+		return []PlanStep{{Method: "osascript", Detail: "Notification Center banner, within 500 ms"}}, nil
+	default:
+		return []PlanStep{{Method: "notify-send", Detail: "libnotify notification, within 500 ms"}}, nil
+	}
+}
+
+// sessionPlan is the plan for a notification shown in the session of a target that does not fan out
+func sessionPlan(mode string, target PlanTarget) ([]PlanStep, error) {
+	hasDisplay := target.Session == SessionDesktop || target.Session == SessionRDP
+
+	switch mode {
+	case ModeNative:
+		if !hasDisplay {
+			return nil, fmt.Errorf("native mode needs the notification center of a desktop session, not %s", target.Session)
+		}
+		switch target.OS {
+		case "windows":
+			return []PlanStep{{Method: "toast", Detail: "toast notification in Action Center"}}, nil
+		case "darwin":
+			return []PlanStep{
+				{Method: "terminal-notifier", Detail: "Notification Center banner, when terminal-notifier is installed"},
+				{Method: "osascript", Detail: "Notification Center banner shown as Script Editor", Fallback: true},
+			}, nil
+		default:
+			return []PlanStep{{Method: "notify-send", Detail: "libnotify notification"}}, nil
+		}
+
+	case ModeWebView:
+		if !target.WebView {
+			return nil, fmt.Errorf("WebView not available: the notify build has no WebView support (-tags webview on macOS and Linux)")
+		}
+		if !hasDisplay {
+			return nil, fmt.Errorf("WebView needs a desktop session, not %s", target.Session)
+		}
+		return []PlanStep{{Method: "webview", Detail: "WebView window"}}, nil
+
+	case ModeBasic:
+		if !hasDisplay {
+			return nil, fmt.Errorf("a message box needs a desktop session, not %s", target.Session)
+		}
+		return []PlanStep{{Method: "messagebox", Detail: "Windows MessageBox"}}, nil
+	}
+
+	// ModeAuto
+	if !hasDisplay {
+		if target.OS == "linux" {
+			return []PlanStep{{Method: "wall", Detail: "no GUI available, wall broadcast to every terminal"}}, nil
+		}
+		if target.Session == SessionHeadless {
+			return nil, fmt.Errorf("GUI mode is not available and no fallback notification method found")
+		}
+		return nil, fmt.Errorf("a %s session has no desktop to show a window on; run notify as root/SYSTEM to reach logged-in users", target.Session)
+	}
+
+	var steps []PlanStep
+	if target.Session == SessionRDP {
+		steps = append(steps, PlanStep{Method: "fyne", Detail: "window, only if the RDP session provides OpenGL 2.1 (usually not without GPU acceleration)"})
+	} else {
+		steps = append(steps, PlanStep{Method: "fyne", Detail: "window (OpenGL)"})
+	}
+	if target.WebView {
+		steps = append(steps, PlanStep{Method: "webview", Detail: "WebView window, when OpenGL is not available", Fallback: true})
+	}
+	if target.OS == "windows" {
+		steps = append(steps, PlanStep{Method: "messagebox", Detail: "Windows MessageBox, when OpenGL and WebView are not available", Fallback: true})
+	}
+	return steps, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
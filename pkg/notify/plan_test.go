@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+// planMethods returns the methods of a plan, with "user:" before those run in each user's session
+func planMethods(steps []PlanStep) string {
+	var methods []string
+	for _, step := range steps {
+		if step.InUser {
+			methods = append(methods, "user:"+step.Method)
+		} else {
+			methods = append(methods, step.Method)
+		}
+	}
+	return strings.Join(methods, " ")
+}
+
+// TestDeliveryPlan tests the fallback chains for foreign platforms and sessions
+func TestDeliveryPlan(t *testing.T) {
+	tests := []struct {
+		mode    string
+		target  PlanTarget
+		methods string
+		fails   bool
+	}{
+		{ModeAuto, PlanTarget{OS: "windows", Session: SessionDesktop}, "fyne messagebox", false},
+		{ModeAuto, PlanTarget{OS: "windows", Session: SessionRDP, WebView: true}, "fyne webview messagebox", false},
+		{ModeAuto, PlanTarget{OS: "windows", Session: SessionSystem}, "users user:fyne user:messagebox", false},
+		{ModeAuto, PlanTarget{OS: "windows", Session: SessionSSH}, "", true},
+		{ModeAuto, PlanTarget{OS: "linux", Session: SessionSSH}, "wall", false},
+		{ModeAuto, PlanTarget{OS: "linux", Session: SessionSystem}, "users wall user:fyne", false},
+		{ModeAuto, PlanTarget{OS: "macos", Session: SessionHeadless}, "", true},
+		{ModeNative, PlanTarget{OS: "darwin", Session: SessionDesktop}, "terminal-notifier osascript", false},
+		{ModeNative, PlanTarget{OS: "linux", Session: SessionSystem}, "users user:notify-send", false},
+		{ModeQuick, PlanTarget{OS: "windows", Session: SessionSystem}, "", true},
+		{ModeQuick, PlanTarget{OS: "windows", Session: SessionRDP}, "wtsmessage", false},
+		{ModeWall, PlanTarget{OS: "darwin", Session: SessionDesktop}, "", true},
+		{ModeBasic, PlanTarget{OS: "linux", Session: SessionDesktop}, "", true},
+		{ModeWebView, PlanTarget{OS: "linux", Session: SessionDesktop}, "", true},
+	}
+	for _, tt := range tests {
+		steps, err := DeliveryPlan(Options{Mode: tt.mode}, tt.target)
+		if (err != nil) != tt.fails {
+			t.Errorf("DeliveryPlan(%s, %+v) error = %v, want failure %v", tt.mode, tt.target, err, tt.fails)
+		}
+		if got := planMethods(steps); got != tt.methods {
+			t.Errorf("DeliveryPlan(%s, %+v) = %q, want %q", tt.mode, tt.target, got, tt.methods)
+		}
+	}
+}
+
+// TestDeliveryPlanGUIOnly tests that -gui-only drops the wall broadcast of a Linux fan-out
+func TestDeliveryPlanGUIOnly(t *testing.T) {
+	steps, err := DeliveryPlan(Options{GUIOnly: true}, PlanTarget{OS: "linux", Session: SessionSystem, WebView: true})
+	if err != nil || planMethods(steps) != "users user:fyne user:webview" {
+		t.Errorf("DeliveryPlan = %q, %v", planMethods(steps), err)
+	}
+}
+
+// TestValidatePlanTarget tests the -os and -session checks
+func TestValidatePlanTarget(t *testing.T) {
+	if target, err := ValidatePlanTarget(PlanTarget{OS: "macOS", Session: "Desktop"}); err != nil || target.OS != "darwin" || target.Session != SessionDesktop {
+		t.Errorf("ValidatePlanTarget(macOS) = %+v, %v", target, err)
+	}
+	for _, target := range []PlanTarget{
+		{OS: "freebsd", Session: SessionDesktop},
+		{OS: "linux", Session: SessionRDP},
+		{OS: "windows", Session: "console"},
+	} {
+		if _, err := ValidatePlanTarget(target); err == nil {
+			t.Errorf("ValidatePlanTarget(%+v) should fail", target)
+		}
+	}
+}
@@ -0,0 +1,472 @@
+package notify
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// A minimal QR code encoder for -mobile-mirror links (ISO/IEC 18004): byte mode,
+// error correction level M, versions 1 to 10, which holds up to 213 bytes
+
+// qrVersion is the block structure of one QR version at error correction level M
+type qrVersion struct {
+	ecPerBlock int   // Error correction codewords in every block
+	blocks     []int // Data codewords of each block, shorter blocks first
+	alignment  []int // Row/column centers of the alignment patterns
+}
+
+// qrVersionsM are versions 1 to 10 at level M
+var qrVersionsM = []qrVersion{
+	{ecPerBlock: 10, blocks: []int{16}},
+	{ecPerBlock: 16, blocks: []int{28}, alignment: []int{6, 18}},
+	{ecPerBlock: 26, blocks: []int{44}, alignment: []int{6, 22}},
+	{ecPerBlock: 18, blocks: []int{32, 32}, alignment: []int{6, 26}},
+	{ecPerBlock: 24, blocks: []int{43, 43}, alignment: []int{6, 30}},
+	{ecPerBlock: 16, blocks: []int{27, 27, 27, 27}, alignment: []int{6, 34}},
+	{ecPerBlock: 18, blocks: []int{31, 31, 31, 31}, alignment: []int{6, 22, 38}},
+	{ecPerBlock: 22, blocks: []int{38, 38, 39, 39}, alignment: []int{6, 24, 42}},
+	{ecPerBlock: 22, blocks: []int{36, 36, 36, 37, 37}, alignment: []int{6, 26, 46}},
+	{ecPerBlock: 26, blocks: []int{43, 43, 43, 43, 44}, alignment: []int{6, 28, 50}},
+}
+
+// dataCodewords returns the number of data codewords of v
+func (v qrVersion) dataCodewords() int {
+	total := 0
+	for _, n := range v.blocks {
+		total += n
+	}
+	return total
+}
+
+// qrCode is an encoded symbol: Modules[y][x] is true for a dark module
+type qrCode struct {
+	Version  int
+	Mask     int
+	Modules  [][]bool
+	function [][]bool // Finder, timing, alignment, format and version modules, which masks skip
+}
+
+// encodeQR encodes data in the smallest version that fits, with the mask that scores best
+func encodeQR(data []byte) (*qrCode, error) {
+	version := 0
+	for i, v := range qrVersionsM {
+		countBits := 8
+		if i+1 >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*v.dataCodewords() {
+			version = i + 1
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%d bytes is too long for a QR code (at most 213)", len(data))
+	}
+
+	codewords := qrInterleave(qrVersionsM[version-1], qrDataCodewords(data, version))
+	best := (*qrCode)(nil)
+	bestPenalty := 0
+	for mask := 0; mask < 8; mask++ {
+		qr := newQRCode(version)
+		qr.placeData(codewords)
+		qr.applyMask(mask)
+		qr.drawFormat(mask)
+		if penalty := qr.penalty(); best == nil || penalty < bestPenalty {
+			best, bestPenalty = qr, penalty
+		}
+	}
+	return best, nil
+}
+
+// qrDataCodewords returns data in byte mode with terminator and padding, filling the data codewords of version
+func qrDataCodewords(data []byte, version int) []byte {
+	capacity := qrVersionsM[version-1].dataCodewords() * 8
+	var bits []bool
+	appendBits := func(value, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (value>>i)&1 == 1)
+		}
+	}
+
+	appendBits(0x4, 4) // Byte mode
+	if version >= 10 {
+		appendBits(len(data), 16)
+	} else {
+		appendBits(len(data), 8)
+	}
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	appendBits(0, terminator)
+	appendBits(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, capacity/8)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for _, bit := range bits[i : i+8] {
+			b <<= 1
+			if bit {
+				b |= 1
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity/8; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// qrInterleave splits data into the blocks of v, adds their error correction and interleaves them
+func qrInterleave(v qrVersion, data []byte) []byte {
+	divisor := reedSolomonDivisor(v.ecPerBlock)
+	var dataBlocks, ecBlocks [][]byte
+	for _, n := range v.blocks {
+		dataBlocks = append(dataBlocks, data[:n])
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(data[:n], divisor))
+		data = data[n:]
+	}
+
+	var result []byte
+	longest := v.blocks[len(v.blocks)-1]
+	for i := 0; i < longest; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) with the QR code polynomial x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree, highest coefficient first
+// (the leading 1 is implied)
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords of data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// newQRCode returns an empty symbol of version with the function patterns drawn
+func newQRCode(version int) *qrCode {
+	size := version*4 + 17
+	qr := &qrCode{Version: version}
+	qr.Modules = make([][]bool, size)
+	qr.function = make([][]bool, size)
+	for y := range qr.Modules {
+		qr.Modules[y] = make([]bool, size)
+		qr.function[y] = make([]bool, size)
+	}
+
+	for i := 0; i < size; i++ {
+		qr.setFunction(6, i, i%2 == 0) // Timing patterns
+		qr.setFunction(i, 6, i%2 == 0)
+	}
+	qr.drawFinder(3, 3)
+	qr.drawFinder(size-4, 3)
+	qr.drawFinder(3, size-4)
+
+	alignment := qrVersionsM[version-1].alignment
+	last := len(alignment) - 1
+	for i, y := range alignment {
+		for j, x := range alignment {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // Overlaps a finder pattern
+			}
+			qr.drawAlignment(x, y)
+		}
+	}
+
+	qr.drawFormat(0) // Reserves the format modules, drawn again once the mask is chosen
+	if version >= 7 {
+		qr.drawVersion()
+	}
+	return qr
+}
+
+// size returns the width (and height) of the symbol in modules
+func (qr *qrCode) size() int {
+	return len(qr.Modules)
+}
+
+// setFunction sets a function module, which data placement and masking skip
+func (qr *qrCode) setFunction(x, y int, dark bool) {
+	qr.Modules[y][x] = dark
+	qr.function[y][x] = true
+}
+
+// drawFinder draws a finder pattern centered at x, y with its light separator
+func (qr *qrCode) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= qr.size() || yy < 0 || yy >= qr.size() {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			qr.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centered at x, y
+func (qr *qrCode) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			qr.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// qrFormatBits returns the 15 format bits for level M and mask
+func qrFormatBits(mask int) int {
+	data := 0<<3 | mask // Level M is 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawFormat draws both copies of the format bits and the dark module
+func (qr *qrCode) drawFormat(mask int) {
+	bits := qrFormatBits(mask)
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+	size := qr.size()
+
+	for i := 0; i <= 5; i++ {
+		qr.setFunction(8, i, bit(i))
+	}
+	qr.setFunction(8, 7, bit(6))
+	qr.setFunction(8, 8, bit(7))
+	qr.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		qr.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		qr.setFunction(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.setFunction(8, size-15+i, bit(i))
+	}
+	qr.setFunction(8, size-8, true)
+}
+
+// qrVersionBits returns the 18 version bits (versions 7 and up)
+func qrVersionBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return version<<12 | rem
+}
+
+// drawVersion draws both copies of the version bits
+func (qr *qrCode) drawVersion() {
+	bits := qrVersionBits(qr.Version)
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := qr.size()-11+i%3, i/3
+		qr.setFunction(a, b, dark)
+		qr.setFunction(b, a, dark)
+	}
+}
+
+// placeData fills the non-function modules with codewords in the zigzag order,
+// two columns at a time from the bottom right, skipping the vertical timing pattern
+func (qr *qrCode) placeData(codewords []byte) {
+	size := qr.size()
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < size; vert++ {
+			y := vert
+			if upward {
+				y = size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if qr.function[y][x] || i >= len(codewords)*8 {
+					continue // Remainder bits stay light
+				}
+				qr.Modules[y][x] = (codewords[i/8]>>(7-i%8))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// qrMasked reports whether mask inverts the module at x, y
+func qrMasked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// applyMask inverts the data modules selected by mask
+func (qr *qrCode) applyMask(mask int) {
+	qr.Mask = mask
+	for y := range qr.Modules {
+		for x := range qr.Modules[y] {
+			if !qr.function[y][x] && qrMasked(mask, x, y) {
+				qr.Modules[y][x] = !qr.Modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol for mask selection: long runs, 2x2 blocks,
+// finder-like patterns and an unbalanced dark/light ratio all make it harder to scan
+func (qr *qrCode) penalty() int {
+	size := qr.size()
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return qr.Modules[x][y]
+		}
+		return qr.Modules[y][x]
+	}
+
+	penalty := 0
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < size; y++ {
+			run := 1
+			for x := 1; x <= size; x++ {
+				if x < size && at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += run - 2
+				}
+				run = 1
+			}
+			for x := 0; x+11 <= size; x++ {
+				for _, pattern := range finderLike {
+					match := true
+					for k, dark := range pattern {
+						if at(x+k, y, transpose) != dark {
+							match = false
+							break
+						}
+					}
+					if match {
+						penalty += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if qr.Modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				c := qr.Modules[y][x]
+				if c == qr.Modules[y][x+1] && c == qr.Modules[y+1][x] && c == qr.Modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := size * size
+	deviation := abs(dark*20-total*10) / total // Steps of 5% away from half dark
+	return penalty + deviation*10
+}
+
+// Image renders the symbol with scale pixels per module and the 4-module quiet zone scanners need
+func (qr *qrCode) Image(scale int) image.Image {
+	const quiet = 4
+	side := (qr.size() + 2*quiet) * scale
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for py := 0; py < side; py++ {
+		for px := 0; px < side; px++ {
+			x, y := px/scale-quiet, py/scale-quiet
+			if x >= 0 && y >= 0 && x < qr.size() && y < qr.size() && qr.Modules[y][x] {
+				img.SetGray(px, py, color.Gray{Y: 0})
+			} else {
+				img.SetGray(px, py, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
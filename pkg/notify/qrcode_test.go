@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestReedSolomon tests the error correction codewords against the 1-M "HELLO WORLD" example
+func TestReedSolomon(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	got := reedSolomonRemainder(data, reedSolomonDivisor(10))
+	if !bytes.Equal(got, want) {
+		t.Errorf("reedSolomonRemainder = %v, want %v", got, want)
+	}
+}
+
+// TestQRFormatAndVersionBits tests the BCH-coded format and version information against the standard's tables
+func TestQRFormatAndVersionBits(t *testing.T) {
+	formats := map[int]int{
+		0: 0b101010000010010,
+		1: 0b101000100100101,
+		4: 0b100010111111001,
+		7: 0b100101010100000,
+	}
+	for mask, want := range formats {
+		if got := qrFormatBits(mask); got != want {
+			t.Errorf("qrFormatBits(%d) = %015b, want %015b", mask, got, want)
+		}
+	}
+	if got, want := qrVersionBits(7), 0b000111110010010100; got != want {
+		t.Errorf("qrVersionBits(7) = %018b, want %018b", got, want)
+	}
+}
+
+// TestQRDataModules tests that the function patterns leave exactly the data modules
+// the standard gives for each version (codewords plus remainder bits)
+func TestQRDataModules(t *testing.T) {
+	for version := 1; version <= len(qrVersionsM); version++ {
+		v := qrVersionsM[version-1]
+		remainder := 0
+		if version >= 2 && version <= 6 {
+			remainder = 7
+		}
+		want := 8*(v.dataCodewords()+v.ecPerBlock*len(v.blocks)) + remainder
+
+		qr := newQRCode(version)
+		got := 0
+		for y := range qr.function {
+			for x := range qr.function[y] {
+				if !qr.function[y][x] {
+					got++
+				}
+			}
+		}
+		if got != want {
+			t.Errorf("Version %d has %d data modules, want %d", version, got, want)
+		}
+	}
+}
+
+// TestEncodeQR tests version selection and that the codewords read back from the symbol
+func TestEncodeQR(t *testing.T) {
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{14, 1},
+		{50, 4},
+		{106, 6},
+		{107, 7},
+		{180, 9},
+		{181, 10},
+		{213, 10},
+	}
+	for _, tt := range tests {
+		data := []byte(strings.Repeat("k", tt.length))
+		qr, err := encodeQR(data)
+		if err != nil {
+			t.Errorf("encodeQR(%d bytes) failed: %v", tt.length, err)
+			continue
+		}
+		if qr.Version != tt.version {
+			t.Errorf("encodeQR(%d bytes) chose version %d, want %d", tt.length, qr.Version, tt.version)
+		}
+		if size := len(qr.Modules); size != 17+4*qr.Version {
+			t.Errorf("Version %d symbol is %d modules wide", qr.Version, size)
+		}
+
+		want := qrInterleave(qrVersionsM[qr.Version-1], qrDataCodewords(data, qr.Version))
+		if got := readQRCodewords(qr); !bytes.Equal(got, want) {
+			t.Errorf("encodeQR(%d bytes): codewords read back differ from those placed", tt.length)
+		}
+	}
+
+	if _, err := encodeQR(make([]byte, 214)); err == nil {
+		t.Error("encodeQR accepted 214 bytes")
+	}
+}
+
+// readQRCodewords reads the codewords back from qr, undoing its mask, in the zigzag order
+func readQRCodewords(qr *qrCode) []byte {
+	size := len(qr.Modules)
+	var codewords []byte
+	var current byte
+	bits := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if qr.function[y][x] {
+					continue
+				}
+				current <<= 1
+				if qr.Modules[y][x] != qrMasked(qr.Mask, x, y) {
+					current |= 1
+				}
+				if bits++; bits%8 == 0 {
+					codewords = append(codewords, current)
+				}
+			}
+		}
+	}
+	return codewords
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// quickDeliveryBudget is the hard limit for handing a -quick notification to the OS
+const quickDeliveryBudget = 500 * time.Millisecond
+
+// quickResult carries the outcome of a platform quick delivery
+type quickResult struct {
+	method string
+	err    error
+}
+
+// showQuickNotification delivers a notification with the lightest native mechanism
+// available (see sendQuickNotification), skipping Fyne, WebView and user fan-out
+// Returns the delivery method, or an error if delivery failed or took longer
+// than quickDeliveryBudget
+func showQuickNotification(n Notification) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), quickDeliveryBudget)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan quickResult, 1)
+	go func() {
+		method, err := sendQuickNotification(ctx, n)
+		done <- quickResult{method, err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return "", result.err
+		}
+		log.Printf("Quick notification delivered via %s in %v", result.method, time.Since(start))
+		return result.method, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("quick notification not delivered within %v", quickDeliveryBudget)
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,54 @@
+//go:build !windows
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// sendQuickNotification posts a banner through the desktop notification service
+// macOS: osascript "display notification" (Notification Center)
+// Linux and others: notify-send (freedesktop notifications over D-Bus)
+// The command is killed if it outlives ctx
+func sendQuickNotification(ctx context.Context, n Notification) (string, error) {
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(n.plainMessage()), appleScriptString(n.Title))
+		if output, err := exec.CommandContext(ctx, "osascript", "-e", script).CombinedOutput(); err != nil {
+			return "", fmt.Errorf("osascript failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+		}
+		return "osascript", nil
+	}
+
+	if _, err := exec.LookPath("notify-send"); err != nil {
+		return "", fmt.Errorf("notify-send not found (install libnotify-bin)")
+	}
+	args := []string{"-a", "KrankyBearNotify", "-t", fmt.Sprintf("%d", n.Timeout*1000)}
+	if n.IconPath != "" {
+		args = append(args, "-i", n.IconPath)
+	}
+	if urgency := n.libnotifyUrgency(); urgency != "" {
+		args = append(args, "-u", urgency)
+	}
+	if n.Sensitive {
+		// Transient notifications are not kept in the notification history
+		args = append(args, "-h", "int:transient:1")
+	}
+	args = append(args, n.Title, n.plainMessage())
+	if output, err := exec.CommandContext(ctx, "notify-send", args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("notify-send failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return "notify-send", nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
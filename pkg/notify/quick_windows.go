@@ -0,0 +1,19 @@
+//go:build windows
+
+package notify
+
+import "context"
+
+// wtsCurrentSession is WTS_CURRENT_SESSION, the session of the calling process
+const wtsCurrentSession = 0xFFFFFFFF
+
+// sendQuickNotification shows a message box in the current session with WTSSendMessage,
+// which returns without loading any GUI framework or waiting for the user
+func sendQuickNotification(ctx context.Context, n Notification) (string, error) {
+	if err := wtsSendMessageToSession(wtsCurrentSession, n.Title, n.plainMessage(), n.Timeout); err != nil {
+		return "", err
+	}
+	return "wtsmessage", nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
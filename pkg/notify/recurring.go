@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// MinEvery is the shortest Options.Every: reminders closer together would only stack windows
+const MinEvery = time.Minute
+
+// ValidateEvery checks the recurrence of a notification: Every of at least MinEvery, and
+// Count, Until and StopOn only with Every, StopOn naming some of choices
+func ValidateEvery(every time.Duration, count int, until time.Time, stopOn string, choices []string) error {
+	switch {
+	case every < 0:
+		return fmt.Errorf("invalid -every %v: must not be negative", every)
+	case every > 0 && every < MinEvery:
+		return fmt.Errorf("invalid -every %v: must be at least %v", every, MinEvery)
+	case count < 0:
+		return fmt.Errorf("invalid -count %d: must not be negative", count)
+	case every == 0 && (count != 0 || !until.IsZero() || stopOn != ""):
+		return fmt.Errorf("-count, -until and -stop-on require -every")
+	}
+	for _, choice := range splitStopOn(stopOn) {
+		found := false
+		for _, c := range choices {
+			found = found || c == choice
+		}
+		if !found {
+			return fmt.Errorf("invalid -stop-on %q: not one of the -choices", choice)
+		}
+	}
+	return nil
+}
+
+// splitStopOn returns the choices of Options.StopOn
+func splitStopOn(stopOn string) []string {
+	var choices []string
+	for _, choice := range strings.Split(stopOn, ",") {
+		if choice = strings.TrimSpace(choice); choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// stopsRecurrence reports whether result ends a notification shown with Options.Every:
+// acknowledged (with one of the StopOn choices, when set), or not shown to this user at all
+// Timeouts, deliveries without an acknowledgment and other choices show it again
+func (opts Options) stopsRecurrence(result Result) bool {
+	switch result.Action {
+	case ActionTimeout, ActionDelivered:
+		return false
+	case ActionAcknowledged:
+		stopOn := splitStopOn(opts.StopOn)
+		if len(stopOn) == 0 {
+			return true
+		}
+		for _, choice := range stopOn {
+			if result.Choice == choice {
+				return true
+			}
+		}
+		return false
+	}
+	return true // Skipped, suppressed, replaced or canceled
+}
+
+// sendEvery shows opts every Every until stopsRecurrence, Count showings or Until
+// Each showing starts Every after the previous one started, or right after it closed when it
+// stayed open longer; the result is that of the last showing
+func (nt *Notifier) sendEvery(opts Options) (Result, error) {
+	showOpts := opts
+	showOpts.Every, showOpts.Count, showOpts.Until, showOpts.StopOn = 0, 0, time.Time{}, ""
+	for shown := 1; ; shown++ {
+		started := time.Now()
+		log.Printf("Every %v: showing %d", opts.Every, shown)
+		result, err := nt.Send(showOpts)
+		if err != nil {
+			return result, err
+		}
+		if opts.stopsRecurrence(result) {
+			return result, nil
+		}
+		if opts.Count > 0 && shown >= opts.Count {
+			log.Printf("Every: shown %d times, stopping", shown)
+			return result, nil
+		}
+		next := started.Add(opts.Every)
+		if !opts.Until.IsZero() && next.After(opts.Until) {
+			log.Printf("Every: next showing would be after %s, stopping", opts.Until.Format(time.RFC3339))
+			return result, nil
+		}
+		log.Printf("Every: %s, showing again at %s", result.Action, next.Format(time.RFC3339))
+		// Sleep in steps so a suspended machine shows the reminder soon after it resumes
+		for wait := time.Until(next); wait > 0; wait = time.Until(next) {
+			time.Sleep(min(wait, time.Minute))
+		}
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+// TestStopsRecurrence tests which results end a notification shown with Every
+func TestStopsRecurrence(t *testing.T) {
+	anyAck := Options{Every: time.Hour}
+	reboot := Options{Every: time.Hour, StopOn: "Reboot now, Reboot tonight"}
+	tests := []struct {
+		opts   Options
+		result Result
+		want   bool
+	}{
+		{anyAck, Result{Action: ActionAcknowledged}, true},
+		{anyAck, Result{Action: ActionTimeout}, false},
+		{anyAck, Result{Action: ActionDelivered}, false},
+		{anyAck, Result{Action: ActionSuppressed}, true},
+		{reboot, Result{Action: ActionAcknowledged, Choice: "Reboot tonight"}, true},
+		{reboot, Result{Action: ActionAcknowledged, Choice: "Remind me later"}, false},
+		{reboot, Result{Action: ActionCanceled}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.stopsRecurrence(tt.result); got != tt.want {
+			t.Errorf("stopsRecurrence(%q, %+v) = %v, want %v", tt.opts.StopOn, tt.result, got, tt.want)
+		}
+	}
+}
+
+// TestValidateEvery tests the checks of -every, -count, -until and -stop-on
+func TestValidateEvery(t *testing.T) {
+	choices := []string{"Reboot now", "Remind me later"}
+	until := time.Date(2025, 3, 3, 17, 0, 0, 0, time.UTC)
+	if err := ValidateEvery(time.Hour, 8, until, "Reboot now", choices); err != nil {
+		t.Errorf("ValidateEvery failed: %v", err)
+	}
+	if err := ValidateEvery(0, 0, time.Time{}, "", nil); err != nil {
+		t.Errorf("ValidateEvery without -every failed: %v", err)
+	}
+	for name, err := range map[string]error{
+		"negative":       ValidateEvery(-time.Hour, 0, time.Time{}, "", nil),
+		"too often":      ValidateEvery(30*time.Second, 0, time.Time{}, "", nil),
+		"negative count": ValidateEvery(time.Hour, -1, time.Time{}, "", nil),
+		"count alone":    ValidateEvery(0, 3, time.Time{}, "", nil),
+		"unknown choice": ValidateEvery(time.Hour, 0, time.Time{}, "Later", choices),
+	} {
+		if err == nil {
+			t.Errorf("ValidateEvery should fail: %s", name)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// windowIDPollInterval is how often a window with an ID looks for replacements and cancellation
+const windowIDPollInterval = 250 * time.Millisecond
+
+// windowIDCloseWait is how long Cancel, or a notification taking over an ID, waits for the
+// window showing it to close
+const windowIDCloseWait = 5 * time.Second
+
+// windowRecord is the file of the open window of a Notification.ID: the process showing it,
+// and the content or cancellation a later notify process asks for
+type windowRecord struct {
+	PID     int       `json:"pid"`
+	Title   string    `json:"title,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Cancel  bool      `json:"cancel,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// ValidateID checks an -id or -cancel value: up to 64 letters, digits, dots, dashes and underscores
+func ValidateID(id string) error {
+	if id != "" && !stateIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid notification ID %q (use up to 64 letters, digits, '.', '-' and '_')", id)
+	}
+	return nil
+}
+
+// windowRecordPath returns the file of the window showing id, for the current user
+func windowRecordPath(id string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "ids", id+".json"), nil
+}
+
+// readWindowRecord returns the record of the window showing id; ok is false when no window
+// is showing it (no record, or one left by a process that ended)
+func readWindowRecord(id string) (record windowRecord, ok bool) {
+	path, err := windowRecordPath(id)
+	if err != nil {
+		return windowRecord{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return windowRecord{}, false
+	}
+	if err := json.Unmarshal(data, &record); err != nil || record.PID <= 0 || !processRunning(record.PID) {
+		return windowRecord{}, false
+	}
+	return record, true
+}
+
+// writeWindowRecord stores the record of the window showing id
+func writeWindowRecord(id string, record windowRecord) error {
+	path, err := windowRecordPath(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create ID directory: %v", err)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	// Written to a temporary file first so the window never reads half a file
+	tmp := path + fmt.Sprintf(".%d.tmp", os.Getpid())
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("could not write ID: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("could not write ID: %v", err)
+	}
+	return nil
+}
+
+// CancelWindow closes the window showing id, which reports ActionCanceled, and waits until it
+// has closed; closed is false when no window of the current user is showing id
+func CancelWindow(id string) (closed bool, err error) {
+	if err := ValidateID(id); err != nil {
+		return false, err
+	}
+	if id == "" {
+		return false, fmt.Errorf("no notification ID")
+	}
+	record, ok := readWindowRecord(id)
+	if !ok {
+		return false, nil
+	}
+	record.Cancel, record.Updated = true, time.Now()
+	if err := writeWindowRecord(id, record); err != nil {
+		return false, err
+	}
+	for deadline := time.Now().Add(windowIDCloseWait); time.Now().Before(deadline); {
+		if current, ok := readWindowRecord(id); !ok || current.PID != record.PID {
+			return true, nil
+		}
+		time.Sleep(windowIDPollInterval)
+	}
+	return true, fmt.Errorf("the window showing %q did not close within %v", id, windowIDCloseWait)
+}
+
+// replaceWindow shows the title and message of n in the window already showing its ID;
+// replaced is false when no window of the current user is showing it
+func replaceWindow(n Notification) (replaced bool, err error) {
+	record, ok := readWindowRecord(n.ID)
+	if !ok || record.Cancel {
+		return false, nil
+	}
+	record.Title, record.Message, record.Updated = n.Title, n.Message, time.Now()
+	if err := writeWindowRecord(n.ID, record); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// takeOverID prepares the window of n for its ID before it is shown: with Replace, the content
+// goes to the window already showing the ID and n is not shown (replaced is true); otherwise
+// that window is closed, so there is only ever one window per ID
+func takeOverID(n Notification) (replaced bool) {
+	if n.ID == "" {
+		return false
+	}
+	if n.Replace {
+		replaced, err := replaceWindow(n)
+		if err != nil {
+			log.Printf("Warning: Could not replace the window showing %q, showing a new one: %v", n.ID, err)
+		}
+		if replaced {
+			log.Printf("ID: shown in the window already showing %q", n.ID)
+			return true
+		}
+	}
+	closed, err := CancelWindow(n.ID)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	} else if closed {
+		log.Printf("ID: closed the window showing %q", n.ID)
+	}
+	return false
+}
+
+// cancel closes the window showing opts.Cancel in this session, or in the session of each
+// logged-in user when running as root/SYSTEM (without wall, whose broadcast cannot be taken back)
+func (nt *Notifier) cancel(opts Options) (Result, error) {
+	if shouldShowToOtherUsers() {
+		exePath, err := nt.executable()
+		if err != nil {
+			return Result{}, err
+		}
+		results, err := showNotificationToUsers(exePath, opts)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Action: ActionDelivered, Method: "users", Targets: results}, nil
+	}
+	closed, err := CancelWindow(opts.Cancel)
+	if err != nil {
+		return Result{}, err
+	}
+	if !closed {
+		log.Printf("ID: no window is showing %q, nothing to cancel", opts.Cancel)
+		return Result{Action: ActionSkipped}, nil
+	}
+	return Result{Action: ActionCanceled}, nil
+}
+
+// watchWindowID registers the window of n under its ID, then calls replace with the title and
+// message of every later notification with -replace, and cancel when it is canceled; the
+// returned function, called when the window has closed, stops watching and removes the record
+func watchWindowID(n Notification, replace func(title, message string), cancel func()) (done func()) {
+	last := windowRecord{PID: os.Getpid(), Updated: time.Now()}
+	if err := writeWindowRecord(n.ID, last); err != nil {
+		log.Printf("Warning: Replacing and canceling by ID unavailable: %v", err)
+		return func() {}
+	}
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(windowIDPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			record, ok := readWindowRecord(n.ID)
+			if !ok || record.PID != last.PID || record.Updated.Equal(last.Updated) {
+				continue
+			}
+			last = record
+			if record.Cancel {
+				log.Printf("ID: %q canceled", n.ID)
+				cancel()
+				return
+			}
+			log.Printf("ID: %q replaced", n.ID)
+			replace(NormalizeText(record.Title), NormalizeText(record.Message))
+		}
+	}()
+	return func() {
+		close(stop)
+		<-finished
+		if record, ok := readWindowRecord(n.ID); ok && record.PID == os.Getpid() {
+			if path, err := windowRecordPath(n.ID); err == nil {
+				os.Remove(path)
+			}
+		}
+	}
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
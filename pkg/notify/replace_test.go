@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWatchWindowID tests that a notification with -replace updates the window showing its ID,
+// and that CancelWindow closes it
+func TestWatchWindowID(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // os.UserConfigDir on Linux
+	t.Setenv("HOME", dir)            // macOS
+	t.Setenv("AppData", dir)         // Windows
+
+	if closed, err := CancelWindow("backup"); closed || err != nil {
+		t.Errorf("CancelWindow with no window = %v, %v, want false", closed, err)
+	}
+	if takeOverID(Notification{ID: "backup", Replace: true}) {
+		t.Error("takeOverID replaced with no window showing the ID")
+	}
+
+	var mu sync.Mutex
+	var contents []string
+	canceled := make(chan struct{})
+	var done func()
+	done = watchWindowID(Notification{ID: "backup"}, func(title, message string) {
+		mu.Lock()
+		contents = append(contents, title+": "+message)
+		mu.Unlock()
+	}, func() {
+		go done() // The window closes, as the Fyne and WebView windows do
+		close(canceled)
+	})
+
+	if !takeOverID(Notification{ID: "backup", Replace: true, Title: "Backup", Message: "50% done"}) {
+		t.Fatal("takeOverID did not replace the open window")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(contents)
+		mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(windowIDPollInterval / 2)
+	}
+	mu.Lock()
+	if len(contents) != 1 || contents[0] != "Backup: 50% done" {
+		t.Errorf("contents = %q, want the replaced title and message", contents)
+	}
+	mu.Unlock()
+
+	if closed, err := CancelWindow("backup"); !closed || err != nil {
+		t.Fatalf("CancelWindow = %v, %v, want the window closed", closed, err)
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Error("CancelWindow returned before the window was canceled")
+	}
+	if _, ok := readWindowRecord("backup"); ok {
+		t.Error("the record of the closed window was not removed")
+	}
+}
+
+// TestValidateID tests -id and -cancel values
+func TestValidateID(t *testing.T) {
+	for _, id := range []string{"", "backup-progress", "job.42_b"} {
+		if err := ValidateID(id); err != nil {
+			t.Errorf("ValidateID(%q) failed: %v", id, err)
+		}
+	}
+	for _, id := range []string{"../etc", "-x", "a b"} {
+		if err := ValidateID(id); err == nil {
+			t.Errorf("ValidateID(%q) should fail", id)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package notify
+
+import "unicode"
+
+// rtlScripts are the scripts written right to left
+var rtlScripts = []*unicode.RangeTable{
+	unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko,
+	unicode.Samaritan, unicode.Mandaic, unicode.Adlam,
+}
+
+// rightToLeft reports whether the window of n is laid out right to left: with RTL, or when
+// the message (the title when the message has no letters) starts in a right-to-left script
+func (n Notification) rightToLeft() bool {
+	if n.RTL {
+		return true
+	}
+	message := n.Message
+	if n.HTML != "" {
+		message = HTMLText(n.HTML)
+	}
+	if rtl, ok := textDirection(message); ok {
+		return rtl
+	}
+	rtl, _ := textDirection(n.Title)
+	return rtl
+}
+
+// textDirection returns the direction of text as the Unicode bidi algorithm finds that of a
+// paragraph: that of its first letter, numbers and punctuation having none; ok is false when
+// text has no letters
+func textDirection(text string) (rtl, ok bool) {
+	for _, r := range text {
+		if unicode.In(r, rtlScripts...) && unicode.IsLetter(r) {
+			return true, true
+		}
+		if unicode.IsLetter(r) {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// htmlDir returns the dir attribute of the WebView and phone pages of n
+func (n Notification) htmlDir() string {
+	if n.rightToLeft() {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
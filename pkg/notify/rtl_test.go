@@ -0,0 +1,28 @@
+package notify
+
+import "testing"
+
+// TestRightToLeft tests the direction found from the first letter of the message, then the title
+func TestRightToLeft(t *testing.T) {
+	tests := []struct {
+		n    Notification
+		want bool
+	}{
+		{Notification{Title: "Maintenance", Message: "Reboot at 22:00"}, false},
+		{Notification{Title: "Maintenance", Message: "سيتم إعادة التشغيل الساعة 22:00"}, true},
+		{Notification{Title: "תחזוקה", Message: "22:00 - שרת הקבצים"}, true},
+		{Notification{Title: "Patch", Message: "12:00 — VPN מתנתק"}, false},
+		{Notification{Title: "עדכון", Message: "22:00"}, true},
+		{Notification{Title: "Update", Message: "22:00"}, false},
+		{Notification{Title: "Update", HTML: "<p><b>تحديث</b> متاح</p>"}, true},
+		{Notification{Title: "Update", Message: "Reboot", RTL: true}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.n.rightToLeft(); got != tt.want {
+			t.Errorf("rightToLeft(%q, %q) = %v, want %v", tt.n.Title, tt.n.Message+tt.n.HTML, got, tt.want)
+		}
+	}
+	if dir := (Notification{Message: "שלום"}).htmlDir(); dir != "rtl" {
+		t.Errorf("htmlDir = %q, want rtl", dir)
+	}
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"time"
+)
+
+// Context screenshot limits
+const (
+	screenshotTimeout       = 5 * time.Second // Capturing should never hold up the notification for long
+	screenshotThumbnailSize = 480             // Longest side of the thumbnail in pixels
+)
+
+// contextScreenshot is a thumbnail of the user's screen taken just before the notification appeared
+type contextScreenshot struct {
+	Image image.Image // Thumbnail shown in the details pane
+	PNG   []byte      // Thumbnail encoded for the result payload
+	Taken time.Time
+}
+
+// captureContextScreenshot captures the screen and scales it down to a thumbnail
+// It must run before the notification window is shown, or the window covers what the user was doing
+func captureContextScreenshot() (*contextScreenshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), screenshotTimeout)
+	defer cancel()
+
+	file, err := os.CreateTemp("", "notify-screenshot-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create screenshot file: %v", err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	taken := time.Now()
+	if err := captureScreen(ctx, file.Name()); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read screenshot: %v", err)
+	}
+	full, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %v", err)
+	}
+
+	thumbnail := scaleThumbnail(full, screenshotThumbnailSize)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, thumbnail); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %v", err)
+	}
+	log.Printf("Context screenshot: %dx%d scaled to %dx%d (%d bytes)",
+		full.Bounds().Dx(), full.Bounds().Dy(), thumbnail.Bounds().Dx(), thumbnail.Bounds().Dy(), buf.Len())
+	return &contextScreenshot{Image: thumbnail, PNG: buf.Bytes(), Taken: taken}, nil
+}
+
+// scaleThumbnail shrinks img so its longest side is at most maxSize, averaging the
+// source pixels under each thumbnail pixel (smaller images are returned unchanged)
+func scaleThumbnail(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxSize && srcH <= maxSize {
+		return img
+	}
+	dstW, dstH := maxSize, srcH*maxSize/srcW
+	if srcH > srcW {
+		dstW, dstH = srcW*maxSize/srcH, maxSize
+	}
+	if dstW < 1 {
+		dstW = 1
+	}
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := bounds.Min.Y+y*srcH/dstH, bounds.Min.Y+(y+1)*srcH/dstH
+		for x := 0; x < dstW; x++ {
+			x0, x1 := bounds.Min.X+x*srcW/dstW, bounds.Min.X+(x+1)*srcW/dstW
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / count), uint16(g / count), uint16(b / count), uint16(a / count)})
+		}
+	}
+	return dst
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
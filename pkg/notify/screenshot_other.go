@@ -0,0 +1,43 @@
+//go:build !windows
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// captureScreen writes a PNG of the whole screen to path
+// macOS: screencapture (silent; needs the Screen Recording permission)
+// Linux: grim on Wayland, otherwise ImageMagick import, otherwise gnome-screenshot
+func captureScreen(ctx context.Context, path string) error {
+	var commands [][]string
+	switch {
+	case runtime.GOOS == "darwin":
+		commands = [][]string{{"screencapture", "-x", "-t", "png", path}}
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		commands = [][]string{{"grim", path}, {"gnome-screenshot", "-f", path}}
+	default:
+		commands = [][]string{{"import", "-window", "root", path}, {"gnome-screenshot", "-f", path}}
+	}
+
+	var failures []string
+	for _, command := range commands {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			failures = append(failures, command[0]+" not found")
+			continue
+		}
+		output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v (output: %s)", command[0], err, strings.TrimSpace(string(output))))
+	}
+	return fmt.Errorf("screen capture failed: %s", strings.Join(failures, "; "))
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
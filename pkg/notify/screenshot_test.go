@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestScaleThumbnail tests that screenshots are scaled to the thumbnail size with their aspect ratio kept
+func TestScaleThumbnail(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		wantW, wantH  int
+	}{
+		{"landscape", 1920, 1080, 480, 270},
+		{"portrait", 1080, 1920, 270, 480},
+		{"dual monitor", 3840, 1080, 480, 135},
+		{"already small", 320, 200, 320, 200},
+		{"thin strip", 4000, 2, 480, 1},
+	}
+	for _, tt := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+		got := scaleThumbnail(img, 480).Bounds()
+		if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+			t.Errorf("%s: scaleThumbnail(%dx%d) = %dx%d, want %dx%d", tt.name, tt.width, tt.height, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
+
+// TestScaleThumbnailAverages tests that each thumbnail pixel is the average of the pixels it covers
+func TestScaleThumbnailAverages(t *testing.T) {
+	// A 4x2 checkerboard of black and white averages to mid grey at 2x1
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	thumbnail := scaleThumbnail(img, 2)
+	if b := thumbnail.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("Thumbnail is %dx%d, want 2x1", b.Dx(), b.Dy())
+	}
+	for x := 0; x < 2; x++ {
+		r, g, b, _ := thumbnail.At(x, 0).RGBA()
+		if r>>8 != 127 || g>>8 != 127 || b>>8 != 127 {
+			t.Errorf("Pixel %d = (%d, %d, %d), want mid grey", x, r>>8, g>>8, b>>8)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// captureScreen writes a PNG of the virtual screen (all monitors) to path using
+// System.Drawing through PowerShell
+func captureScreen(ctx context.Context, path string) error {
+	escapedPath := strings.ReplaceAll(path, "'", "''")
+	psScript := fmt.Sprintf(`
+try {
+    Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+    $bounds = [System.Windows.Forms.SystemInformation]::VirtualScreen
+    $bitmap = New-Object System.Drawing.Bitmap $bounds.Width, $bounds.Height
+    $graphics = [System.Drawing.Graphics]::FromImage($bitmap)
+    $graphics.CopyFromScreen($bounds.Left, $bounds.Top, 0, 0, $bitmap.Size)
+    $bitmap.Save('%s', [System.Drawing.Imaging.ImageFormat]::Png)
+    $graphics.Dispose()
+    $bitmap.Dispose()
+    exit 0
+} catch {
+    Write-Host "ERROR: $_"
+    exit 1
+}
+`, escapedPath)
+
+	cmd := exec.CommandContext(ctx, "powershell.exe",
+		"-WindowStyle", "Hidden",
+		"-NoProfile",
+		"-NonInteractive",
+		"-NoLogo",
+		"-ExecutionPolicy", "Bypass",
+		"-Command", psScript)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: 0x08000000, // CREATE_NO_WINDOW
+	}
+
+	output, err := cmd.CombinedOutput()
+	outputStr := strings.TrimSpace(string(output))
+	if err != nil || strings.Contains(outputStr, "ERROR:") {
+		return fmt.Errorf("screen capture failed: %v (output: %s)", err, outputStr)
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,219 @@
+//go:build linux
+
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// launchCheckWindow is how long showNotificationAsUser waits for the sudo/env launch
+// to fail before assuming the notification is up (denied launches exit almost immediately)
+const launchCheckWindow = time.Second
+
+// maxReportedDenials caps how many audit lines are included in errors and reports
+const maxReportedDenials = 5
+
+// SecurityModuleStatus describes the Linux security modules that can block the per-user launch
+type SecurityModuleStatus struct {
+	SELinux  string   // "enforcing", "permissive", "disabled", or "" when SELinux is not present
+	AppArmor bool     // AppArmor is enabled in the kernel
+	Denials  []string // Recent SELinux/AppArmor denials involving the launch path
+}
+
+// getSELinuxMode returns the SELinux mode from getenforce, falling back to selinuxfs
+func getSELinuxMode() string {
+	if output, err := exec.Command("getenforce").Output(); err == nil {
+		return strings.ToLower(strings.TrimSpace(string(output)))
+	}
+	data, err := os.ReadFile("/sys/fs/selinux/enforce")
+	if err != nil {
+		return ""
+	}
+	if strings.TrimSpace(string(data)) == "1" {
+		return "enforcing"
+	}
+	return "permissive"
+}
+
+// isAppArmorEnabled reports whether the AppArmor module is loaded and enabled
+func isAppArmorEnabled() bool {
+	data, err := os.ReadFile("/sys/module/apparmor/parameters/enabled")
+	return err == nil && strings.TrimSpace(string(data)) == "Y"
+}
+
+// getSecurityModuleStatus probes SELinux and AppArmor and collects recent denials
+func getSecurityModuleStatus() SecurityModuleStatus {
+	status := SecurityModuleStatus{
+		SELinux:  getSELinuxMode(),
+		AppArmor: isAppArmorEnabled(),
+	}
+	if status.SELinux == "enforcing" || status.SELinux == "permissive" || status.AppArmor {
+		status.Denials = findSecurityDenials()
+	}
+	return status
+}
+
+// launchDenialTokens returns the audit fields that identify our launch path:
+// sudo, env, and the notify executable itself
+func launchDenialTokens() []string {
+	tokens := []string{`comm="sudo"`, `comm="env"`}
+	if exePath, err := os.Executable(); err == nil {
+		tokens = append(tokens, `comm="`+filepath.Base(exePath)+`"`, exePath)
+	}
+	return tokens
+}
+
+// filterDenials returns the lines containing marker and at least one of tokens,
+// keeping only the newest maxReportedDenials
+func filterDenials(lines []string, marker string, tokens []string) []string {
+	var denials []string
+	for _, line := range lines {
+		if !strings.Contains(line, marker) {
+			continue
+		}
+		for _, token := range tokens {
+			if strings.Contains(line, token) {
+				denials = append(denials, strings.TrimSpace(line))
+				break
+			}
+		}
+	}
+	if len(denials) > maxReportedDenials {
+		denials = denials[len(denials)-maxReportedDenials:]
+	}
+	return denials
+}
+
+// readLogTail returns the lines of the last maxBytes of a log file
+func readLogTail(path string, maxBytes int64) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Size() > maxBytes {
+		f.Seek(-maxBytes, io.SeekEnd)
+	}
+	data, _ := io.ReadAll(f)
+	return strings.Split(string(data), "\n")
+}
+
+// findSecurityDenials probes the audit log (SELinux AVCs) and kernel log (AppArmor)
+// for denials from the last few minutes involving the launch path
+// Reading the audit log needs root, which is when the per-user launch is used anyway
+func findSecurityDenials() []string {
+	tokens := launchDenialTokens()
+	var denials []string
+
+	// SELinux: ausearch understands the audit format; fall back to the raw log
+	if output, err := exec.Command("ausearch", "-m", "AVC,USER_AVC", "-ts", "recent").Output(); err == nil {
+		denials = append(denials, filterDenials(strings.Split(string(output), "\n"), "denied", tokens)...)
+	} else {
+		denials = append(denials, filterDenials(readLogTail("/var/log/audit/audit.log", 256*1024), "avc:  denied", tokens)...)
+	}
+
+	// AppArmor: denials go to the kernel log
+	if output, err := exec.Command("journalctl", "-k", "--since", "-10min", "--no-pager", "-q").Output(); err == nil {
+		denials = append(denials, filterDenials(strings.Split(string(output), "\n"), `apparmor="DENIED"`, tokens)...)
+	} else {
+		denials = append(denials, filterDenials(readLogTail("/var/log/kern.log", 256*1024), `apparmor="DENIED"`, tokens)...)
+	}
+
+	return denials
+}
+
+// securityRemediation returns specific steps for the active security modules
+func securityRemediation(status SecurityModuleStatus) []string {
+	var steps []string
+	exePath, _ := os.Executable()
+
+	if status.SELinux == "enforcing" {
+		steps = append(steps,
+			"Explain the denials: ausearch -m AVC,USER_AVC -ts recent | audit2why",
+			"Check the executable's label (binaries copied from a home directory keep user_home_t): ls -Z "+exePath+" && restorecon -v "+exePath,
+			"Install the reference policy module from the selinux/ directory (see README), or build a local one: ausearch -m AVC -ts recent | audit2allow -M krankybearnotify-local && semodule -i krankybearnotify-local.pp",
+		)
+		if len(status.Denials) == 0 {
+			steps = append(steps, "No denials were logged; dontaudit rules may hide them: semodule -DB, retry, then semodule -B")
+		}
+	}
+
+	for _, denial := range status.Denials {
+		if strings.Contains(denial, `apparmor="DENIED"`) {
+			steps = append(steps,
+				"List the confining profile: aa-status",
+				"Allow the launch in the profile's local override (/etc/apparmor.d/local/<profile>) and reload it with apparmor_parser -r, or test with aa-complain <profile>",
+			)
+			break
+		}
+	}
+	return steps
+}
+
+// diagnoseLaunchFailure adds SELinux/AppArmor findings and remediation to a per-user launch error
+// The error is returned unchanged when no security module can explain it
+func diagnoseLaunchFailure(err error) error {
+	status := getSecurityModuleStatus()
+	if status.SELinux != "enforcing" && len(status.Denials) == 0 {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(err.Error())
+	if status.SELinux == "enforcing" {
+		b.WriteString("\nSELinux is enforcing and may be blocking the sudo/env launch")
+	}
+	for _, denial := range status.Denials {
+		b.WriteString("\n  denied: " + denial)
+	}
+	for _, step := range securityRemediation(status) {
+		b.WriteString("\n  - " + step)
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// printSecurityModuleReport prints the SELinux/AppArmor section of -check-deps
+func printSecurityModuleReport() {
+	status := getSecurityModuleStatus()
+
+	fmt.Println("=== Security Modules ===")
+	switch status.SELinux {
+	case "":
+		fmt.Println("SELinux: not present")
+	default:
+		fmt.Printf("SELinux: %s\n", status.SELinux)
+	}
+	if status.AppArmor {
+		fmt.Println("AppArmor: enabled")
+	} else {
+		fmt.Println("AppArmor: not enabled")
+	}
+
+	if len(status.Denials) > 0 {
+		fmt.Println()
+		fmt.Println("- Recent denials involving the notify launch path:")
+		for _, denial := range status.Denials {
+			fmt.Printf("  ✗ %s\n", denial)
+		}
+	} else if os.Geteuid() != 0 && (status.SELinux != "" || status.AppArmor) {
+		fmt.Println("- Run as root to check the audit log for denials")
+	}
+
+	if steps := securityRemediation(status); len(steps) > 0 && (len(status.Denials) > 0 || status.SELinux == "enforcing") {
+		fmt.Println()
+		fmt.Println("If notifications to other users' sessions do not appear:")
+		for _, step := range steps {
+			fmt.Printf("  - %s\n", step)
+		}
+	}
+	fmt.Println()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
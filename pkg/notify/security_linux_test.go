@@ -0,0 +1,41 @@
+//go:build linux
+
+package notify
+
+import "testing"
+
+// TestFilterDenials tests that only denials involving the launch path are kept
+func TestFilterDenials(t *testing.T) {
+	lines := []string{
+		`type=AVC msg=audit(1.1:1): avc:  denied  { execute } for pid=1 comm="sudo" name="notify" scontext=staff_u:staff_r:staff_sudo_t`,
+		`type=AVC msg=audit(1.1:2): avc:  denied  { read } for pid=2 comm="sshd" name="motd"`,
+		`type=AVC msg=audit(1.1:3): avc:  granted  { execute } for pid=3 comm="env"`,
+		`audit: type=1400 apparmor="DENIED" operation="exec" profile="sudo" comm="env" name="/opt/notify/notify"`,
+	}
+	tokens := []string{`comm="sudo"`, `comm="env"`}
+
+	denials := filterDenials(lines, "denied", tokens)
+	if len(denials) != 1 || denials[0] != lines[0] {
+		t.Errorf("Expected only the sudo SELinux denial, got %v", denials)
+	}
+
+	denials = filterDenials(lines, `apparmor="DENIED"`, tokens)
+	if len(denials) != 1 || denials[0] != lines[3] {
+		t.Errorf("Expected only the AppArmor denial, got %v", denials)
+	}
+}
+
+// TestFilterDenialsCap tests that only the newest denials are reported
+func TestFilterDenialsCap(t *testing.T) {
+	var lines []string
+	for i := 0; i < maxReportedDenials+3; i++ {
+		lines = append(lines, `avc:  denied comm="sudo" n=`+string(rune('a'+i)))
+	}
+	denials := filterDenials(lines, "denied", []string{`comm="sudo"`})
+	if len(denials) != maxReportedDenials {
+		t.Fatalf("Expected %d denials, got %d", maxReportedDenials, len(denials))
+	}
+	if denials[len(denials)-1] != lines[len(lines)-1] {
+		t.Errorf("Expected the newest denial last, got %q", denials[len(denials)-1])
+	}
+}
@@ -0,0 +1,23 @@
+package notify
+
+import "fmt"
+
+// Policies for users whose session is disconnected (e.g. an RDP window closed
+// without logging off), selected with -disconnected
+const (
+	DisconnectedSkip               = "skip"                 // Do not notify disconnected sessions
+	DisconnectedQueue              = "queue"                // Wait (up to disconnectedQueueMaxWait) for the session to become active
+	DisconnectedDeliverOnReconnect = "deliver-on-reconnect" // Register a one-shot task that fires when the user reconnects
+)
+
+// ValidateDisconnectedPolicy checks the -disconnected flag value
+func ValidateDisconnectedPolicy(policy string) error {
+	switch policy {
+	case DisconnectedSkip, DisconnectedQueue, DisconnectedDeliverOnReconnect:
+		return nil
+	}
+	return fmt.Errorf("invalid -disconnected value %q (use %s, %s or %s)",
+		policy, DisconnectedSkip, DisconnectedQueue, DisconnectedDeliverOnReconnect)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,217 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	wtsapi32         = syscall.NewLazyDLL("wtsapi32.dll")
+	wtsSendMessage   = wtsapi32.NewProc("WTSSendMessageW")
+	getSystemMetrics = user32.NewProc("GetSystemMetrics")
+)
+
+const (
+	smCleanBoot = 67 // GetSystemMetrics: 0 normal boot, 1 Safe Mode, 2 Safe Mode with Networking
+
+	// disconnectedQueueMaxWait bounds how long -disconnected queue waits for a session
+	disconnectedQueueMaxWait = 8 * time.Hour
+
+	// disconnectedReconnectWindow is how long a deliver-on-reconnect task stays armed
+	disconnectedReconnectWindow = 24 * time.Hour
+)
+
+// isSafeMode reports whether Windows was booted into Safe Mode
+func isSafeMode() bool {
+	ret, _, _ := getSystemMetrics.Call(smCleanBoot)
+	return ret != 0
+}
+
+// sendSessionMessage shows a plain message box in another session with WTSSendMessage
+// This works from SYSTEM without launching a process in the session, so it is used
+// in Safe Mode where Task Scheduler and PsExec are unavailable
+func sendSessionMessage(sessionID string, n Notification) error {
+	var id uint32
+	if _, err := fmt.Sscanf(sessionID, "%d", &id); err != nil {
+		return fmt.Errorf("invalid session ID %q: %v", sessionID, err)
+	}
+	if err := wtsSendMessageToSession(id, n.Title, n.plainMessage(), n.Timeout); err != nil {
+		return fmt.Errorf("WTSSendMessage to session %s failed: %v", sessionID, err)
+	}
+	log.Printf("Sent session message to session %s", sessionID)
+	return nil
+}
+
+// wtsSendMessageToSession calls WTSSendMessage without waiting for the user to respond
+func wtsSendMessageToSession(id uint32, title, message string, timeout int) error {
+	titlePtr, err := syscall.UTF16PtrFromString(title)
+	if err != nil {
+		return fmt.Errorf("invalid title: %v", err)
+	}
+	messagePtr, err := syscall.UTF16PtrFromString(message)
+	if err != nil {
+		return fmt.Errorf("invalid message: %v", err)
+	}
+
+	// Lengths are in bytes, without the terminating null
+	titleLen := len(syscall.StringToUTF16(title))*2 - 2
+	messageLen := len(syscall.StringToUTF16(message))*2 - 2
+
+	const (
+		wtsCurrentServerHandle = 0
+		mbOK                   = 0x00000000
+		mbIconInformation      = 0x00000040
+	)
+
+	var response uint32
+	ret, _, callErr := wtsSendMessage.Call(
+		wtsCurrentServerHandle,
+		uintptr(id),
+		uintptr(unsafe.Pointer(titlePtr)),
+		uintptr(titleLen),
+		uintptr(unsafe.Pointer(messagePtr)),
+		uintptr(messageLen),
+		mbOK|mbIconInformation,
+		uintptr(timeout),
+		uintptr(unsafe.Pointer(&response)),
+		0, // Don't wait for the user to respond
+	)
+	if ret == 0 {
+		return callErr
+	}
+	return nil
+}
+
+// waitForActiveSession polls quser until the user's session is active again
+// Returns the refreshed user entry (the session ID can change on reconnect) and
+// false if the session did not become active within maxWait
+func waitForActiveSession(user WindowsGUIUser, maxWait time.Duration) (WindowsGUIUser, bool) {
+	deadline := time.Now().Add(maxWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(30 * time.Second)
+		for _, current := range getWindowsGUIUsers() {
+			if strings.EqualFold(current.Username, user.Username) && !current.isDisconnected() {
+				log.Printf("Session %s for user %s is active again", current.SessionID, current.Username)
+				return current, true
+			}
+		}
+	}
+	return user, false
+}
+
+// scheduleDeliveryOnReconnect registers a scheduled task that shows the notification
+// when the user reconnects to their session (locally or over RDP)
+// The child removes the task when it runs, and the trigger expires after
+// disconnectedReconnectWindow in case the user never comes back
+func scheduleDeliveryOnReconnect(user WindowsGUIUser, exePath string, opts Options) error {
+	args := buildWindowsChildArgs(exePath, opts)
+
+	taskName := fmt.Sprintf("KrankyBearNotify_Reconnect_%s_%d", user.Username, time.Now().Unix())
+	args = append(args, "-reconnect-task", taskName)
+
+	var argParts []string
+	for _, arg := range args {
+		escaped := strings.ReplaceAll(arg, `"`, `\"`)
+		argParts = append(argParts, fmt.Sprintf(`"%s"`, escaped))
+	}
+	escapedArgString := strings.ReplaceAll(strings.Join(argParts, " "), "'", "''")
+	escapedExePath := strings.ReplaceAll(exePath, "'", "''")
+	escapedUsername := strings.ReplaceAll(user.Username, "'", "''")
+	endBoundary := time.Now().Add(disconnectedReconnectWindow).Format("2006-01-02T15:04:05")
+
+	// Session state change triggers are not exposed by New-ScheduledTaskTrigger,
+	// so they are created from the CIM class directly
+	// StateChange 1 = TASK_CONSOLE_CONNECT, 3 = TASK_REMOTE_CONNECT
+	psScript := fmt.Sprintf(`
+$ErrorActionPreference = 'Stop'
+try {
+    $username = '%s'
+    $userPrincipal = $username
+    if ($username -notlike '*\*') {
+        $userPrincipal = "$env:COMPUTERNAME\$username"
+    }
+
+    $class = Get-CimClass -ClassName MSFT_TaskSessionStateChangeTrigger -Namespace Root/Microsoft/Windows/TaskScheduler
+    $triggers = @()
+    foreach ($change in 1, 3) {
+        $trigger = New-CimInstance -CimClass $class -ClientOnly
+        $trigger.StateChange = $change
+        $trigger.UserId = $userPrincipal
+        $trigger.Enabled = $true
+        $trigger.EndBoundary = '%s'
+        $triggers += $trigger
+    }
+
+    $action = New-ScheduledTaskAction -Execute '%s' -Argument '%s'
+    $settings = New-ScheduledTaskSettingsSet -AllowStartIfOnBatteries -DontStopIfGoingOnBatteries -ExecutionTimeLimit (New-TimeSpan -Minutes 30) -DeleteExpiredTaskAfter (New-TimeSpan -Minutes 1) -MultipleInstances IgnoreNew
+    $principal = New-ScheduledTaskPrincipal -UserId $userPrincipal -LogonType Interactive -RunLevel Highest
+    Register-ScheduledTask -TaskName '%s' -Action $action -Settings $settings -Trigger $triggers -Principal $principal -Force | Out-Null
+
+    # Let the user delete the task so the child can remove it after it runs (best effort)
+    try {
+        $sid = (New-Object System.Security.Principal.NTAccount($userPrincipal)).Translate([System.Security.Principal.SecurityIdentifier]).Value
+        $service = New-Object -ComObject Schedule.Service
+        $service.Connect()
+        $task = $service.GetFolder('\').GetTask('%s')
+        $task.SetSecurityDescriptor($task.GetSecurityDescriptor(4) + "(A;;FA;;;$sid)", 0)
+    } catch {
+        Write-Host "WARNING: could not grant task access: $_"
+    }
+
+    exit 0
+} catch {
+    Write-Host "ERROR: $_"
+    exit 1
+}
+`, escapedUsername, endBoundary, escapedExePath, escapedArgString, taskName, taskName)
+
+	log.Printf("Registering reconnect task %s for user %s (session %s is disconnected)", taskName, user.Username, user.SessionID)
+
+	cmd := exec.Command("powershell.exe",
+		"-WindowStyle", "Hidden",
+		"-NoProfile",
+		"-NonInteractive",
+		"-NoLogo",
+		"-ExecutionPolicy", "Bypass",
+		"-Command", psScript)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: 0x08000000, // CREATE_NO_WINDOW
+	}
+
+	output, err := cmd.CombinedOutput()
+	outputStr := string(output)
+	if err != nil || strings.Contains(outputStr, "ERROR:") {
+		return fmt.Errorf("failed to register reconnect task for user %s: %v (output: %s)", user.Username, err, outputStr)
+	}
+	if strings.Contains(outputStr, "WARNING:") {
+		log.Printf("Reconnect task for user %s: %s", user.Username, strings.TrimSpace(outputStr))
+	}
+
+	log.Printf("Notification for user %s will be shown when the session is reconnected", user.Username)
+	return nil
+}
+
+// RemoveReconnectTask deletes the deliver-on-reconnect task that launched this process
+// so the notification is only shown on the first reconnect
+func RemoveReconnectTask(taskName string) {
+	cmd := exec.Command("schtasks.exe", "/Delete", "/TN", taskName, "/F")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: 0x08000000, // CREATE_NO_WINDOW
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Printf("Warning: Could not remove reconnect task %s: %v (output: %s)", taskName, err, strings.TrimSpace(string(output)))
+		return
+	}
+	log.Printf("Removed reconnect task %s", taskName)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
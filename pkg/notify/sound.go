@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SoundSystem is the -sound value for the platform's default notification sound
+const SoundSystem = "system"
+
+// soundFileExtensions are the audio files -sound plays
+var soundFileExtensions = []string{".wav", ".mp3"}
+
+// IsSoundFile reports whether a -sound value is a file rather than a named system sound
+func IsSoundFile(sound string) bool {
+	if strings.ContainsAny(sound, `/\`) {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(sound))
+	for _, known := range soundFileExtensions {
+		if ext == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSound checks a -sound value: "system", a WAV or MP3 file that exists, or the name of
+// a system sound (e.g. Glass on macOS, dialog-warning on Linux, SystemExclamation on Windows)
+func ValidateSound(sound string) error {
+	if sound == "" || sound == SoundSystem {
+		return nil
+	}
+	if !IsSoundFile(sound) {
+		if strings.Trim(sound, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789.-_ ") != "" {
+			return fmt.Errorf("invalid -sound %q (use system, a .wav or .mp3 file, or a system sound name)", sound)
+		}
+		return nil
+	}
+	ext := strings.ToLower(filepath.Ext(sound))
+	if ext != ".wav" && ext != ".mp3" {
+		return fmt.Errorf("invalid -sound %q (only .wav and .mp3 files are played)", sound)
+	}
+	info, err := os.Stat(sound)
+	if err != nil {
+		return fmt.Errorf("sound file not found: %s", sound)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("-sound %s is a directory", sound)
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,56 @@
+//go:build !windows
+
+package notify
+
+import (
+	"log"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// playSound plays a -sound value without waiting for it
+// macOS: afplay, named sounds from /System/Library/Sounds
+// Linux: paplay, aplay (WAV), mpg123 (MP3) or ffplay for files; canberra-gtk-play or paplay
+// with the freedesktop sound theme for named sounds
+func playSound(sound string) {
+	var candidates [][]string
+	switch {
+	case runtime.GOOS == "darwin" && IsSoundFile(sound):
+		candidates = [][]string{{"afplay", sound}}
+	case runtime.GOOS == "darwin":
+		if sound == SoundSystem {
+			sound = "Glass"
+		}
+		candidates = [][]string{{"afplay", filepath.Join("/System/Library/Sounds", sound+".aiff")}}
+	case IsSoundFile(sound):
+		ffplay := []string{"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet", sound}
+		if strings.EqualFold(filepath.Ext(sound), ".mp3") {
+			candidates = [][]string{{"mpg123", "-q", sound}, ffplay, {"paplay", sound}}
+		} else {
+			candidates = [][]string{{"paplay", sound}, {"aplay", "-q", sound}, ffplay}
+		}
+	default:
+		if sound == SoundSystem {
+			sound = "message-new-instant"
+		}
+		candidates = [][]string{
+			{"canberra-gtk-play", "-i", sound},
+			{"paplay", filepath.Join("/usr/share/sounds/freedesktop/stereo", sound+".oga")},
+		}
+	}
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(candidate[0], candidate[1:]...)
+		if err := cmd.Start(); err == nil {
+			go cmd.Wait()
+			return
+		}
+	}
+	log.Printf("Warning: No player found for -sound %s", sound)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
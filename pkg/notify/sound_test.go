@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestValidateSound tests -sound values: system, files and system sound names
+func TestValidateSound(t *testing.T) {
+	dir := t.TempDir()
+	wav := filepath.Join(dir, "chime.wav")
+	if err := os.WriteFile(wav, []byte("RIFF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, sound := range []string{"", SoundSystem, wav, "Glass", "dialog-warning", "SystemExclamation", "Notification.Default"} {
+		if err := ValidateSound(sound); err != nil {
+			t.Errorf("ValidateSound(%q) failed: %v", sound, err)
+		}
+	}
+	for _, sound := range []string{filepath.Join(dir, "missing.mp3"), "chime.wav", dir + string(os.PathSeparator), filepath.Join(dir, "chime.ogg"), "beep; rm -rf"} {
+		if err := ValidateSound(sound); err == nil {
+			t.Errorf("ValidateSound(%q) should fail", sound)
+		}
+	}
+}
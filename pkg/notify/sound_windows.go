@@ -0,0 +1,68 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	winmm           = syscall.NewLazyDLL("winmm.dll")
+	playSoundW      = winmm.NewProc("PlaySoundW")
+	mciSendStringW  = winmm.NewProc("mciSendStringW")
+	soundAliasFlags = uintptr(0x00010000 | 0x0001 | 0x0002) // SND_ALIAS | SND_ASYNC | SND_NODEFAULT
+	soundFileFlags  = uintptr(0x00020000 | 0x0001 | 0x0002) // SND_FILENAME | SND_ASYNC | SND_NODEFAULT
+)
+
+// playSound plays a -sound value without waiting for it: WAV files and named sounds (event
+// aliases such as SystemExclamation) with PlaySound, MP3 files through MCI
+// Playback stops when notify exits, so it is cut short when the window closes first
+func playSound(sound string) {
+	if IsSoundFile(sound) && strings.EqualFold(filepath.Ext(sound), ".mp3") {
+		if err := playMCI(sound); err != nil {
+			log.Printf("Warning: Could not play -sound %s: %v", sound, err)
+		}
+		return
+	}
+
+	flags, name := soundAliasFlags, sound
+	if IsSoundFile(sound) {
+		flags = soundFileFlags
+	} else if sound == SoundSystem {
+		name = "Notification.Default"
+	}
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return
+	}
+	if ok, _, _ := playSoundW.Call(uintptr(unsafe.Pointer(namePtr)), 0, flags); ok == 0 {
+		// Windows 7 has no Notification.Default, and unknown aliases play nothing
+		log.Printf("Warning: Could not play -sound %s, using the default beep", sound)
+		const MB_ICONASTERISK = 0x00000040
+		messageBeep.Call(uintptr(MB_ICONASTERISK))
+	}
+}
+
+// playMCI plays an MP3 file with the MCI mpegvideo device
+func playMCI(path string) error {
+	for _, command := range []string{
+		fmt.Sprintf(`open "%s" type mpegvideo alias krankybearsound`, path),
+		"play krankybearsound",
+	} {
+		commandPtr, err := syscall.UTF16PtrFromString(command)
+		if err != nil {
+			return err
+		}
+		if code, _, _ := mciSendStringW.Call(uintptr(unsafe.Pointer(commandPtr)), 0, 0, 0); code != 0 {
+			return fmt.Errorf("MCI error %d", code)
+		}
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
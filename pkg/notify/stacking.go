@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stacking of the windows of notify processes running at the same time, for Notification.Stacking
+const (
+	StackingStack   = "stack"   // Default: each window below (or above) the ones already open
+	StackingQueue   = "queue"   // Wait until the windows already open are closed
+	StackingOverlap = "overlap" // Every window centered, on top of each other
+)
+
+// Window slots: the lock files of the open windows, one per slot, slot 0 centered
+const (
+	maxWindowSlots         = 16
+	windowSlotGap          = 12 // Pixels between stacked windows
+	windowSlotCascade      = 40 // Pixels a column of stacked windows is moved right when the screen is full
+	windowSlotPollInterval = 500 * time.Millisecond
+)
+
+// ValidateStacking checks a -stacking value
+func ValidateStacking(stacking string) error {
+	switch stacking {
+	case "", StackingStack, StackingQueue, StackingOverlap:
+		return nil
+	}
+	return fmt.Errorf("invalid stacking %q (use %s, %s or %s)", stacking, StackingStack, StackingQueue, StackingOverlap)
+}
+
+// stacking returns how the window of n shares the screen with other notify windows
+// Full-screen windows cover each other, so they queue; macOS windows cannot be moved from
+// outside the GUI library, so they queue instead of stacking
+func (n Notification) stacking() string {
+	switch {
+	case n.Stacking == StackingOverlap:
+		return StackingOverlap
+	case n.Stacking == StackingQueue, n.fullScreen(), runtime.GOOS == "darwin":
+		return StackingQueue
+	}
+	return StackingStack
+}
+
+// windowSlotsDir returns the directory of the window slot lock files of the current user
+func windowSlotsDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "windows"), nil
+}
+
+// claimWindowSlot takes a window slot for n before its window is shown: the lowest free one
+// when stacking, slot 0 once it is free when queueing (waiting for the windows of other notify
+// processes to close); release frees it after the window closed
+// slot is -1 when the window is not stacked (overlap, no free slot, or no lock directory)
+func claimWindowSlot(n Notification) (slot int, release func()) {
+	stacking := n.stacking()
+	if stacking == StackingOverlap {
+		return -1, func() {}
+	}
+	dir, err := windowSlotsDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0700)
+	}
+	if err != nil {
+		log.Printf("Stacking: %v, the window may cover others", err)
+		return -1, func() {}
+	}
+
+	if stacking == StackingQueue {
+		for waiting := false; !tryWindowSlot(dir, 0); waiting = true {
+			if !waiting {
+				log.Printf("Stacking: queued behind the notification window already open")
+			}
+			time.Sleep(windowSlotPollInterval)
+		}
+		return 0, func() { releaseWindowSlot(dir, 0) }
+	}
+
+	for slot := 0; slot < maxWindowSlots; slot++ {
+		if tryWindowSlot(dir, slot) {
+			if slot > 0 {
+				log.Printf("Stacking: %d notification windows already open, stacking this one", slot)
+			}
+			return slot, func() { releaseWindowSlot(dir, slot) }
+		}
+	}
+	log.Printf("Stacking: %d notification windows already open, the window may cover others", maxWindowSlots)
+	return -1, func() {}
+}
+
+// windowSlotPath returns the lock file of a window slot in dir
+func windowSlotPath(dir string, slot int) string {
+	return filepath.Join(dir, fmt.Sprintf("slot-%d.lock", slot))
+}
+
+// tryWindowSlot takes a window slot in dir by creating its lock file with this process ID,
+// reports whether it did; a lock file left by a process that ended (or that cannot be read) is
+// taken over
+func tryWindowSlot(dir string, slot int) bool {
+	path := windowSlotPath(dir, slot)
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return true
+		}
+		if !os.IsExist(err) {
+			log.Printf("Stacking: could not create %s: %v", path, err)
+			return false
+		}
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return false
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid > 0 && processRunning(pid) {
+			return false
+		}
+		os.Remove(path) // Stale, or removed in the meantime
+	}
+	return false
+}
+
+// releaseWindowSlot frees a window slot in dir taken by this process
+func releaseWindowSlot(dir string, slot int) {
+	path := windowSlotPath(dir, slot)
+	data, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		return // Taken over, e.g. after this process was suspended for long
+	}
+	if err := os.Remove(path); err != nil {
+		log.Printf("Stacking: could not remove %s: %v", path, err)
+	}
+}
+
+// stackedPosition returns the position of the width by height window in a window slot on s:
+// slot 0 centered, the next ones below it down to the bottom of s, then above it up to the top,
+// then again from the center in a column moved right
+func stackedPosition(s screen, width, height, slot int) (x, y int) {
+	x, y = centerOnScreen(s, width, height)
+	step := height + windowSlotGap
+	below := max(0, (s.Y+s.Height-y-height)/step)
+	above := max(0, (y-s.Y)/step)
+	rows := 1 + below + above
+	row, column := slot%rows, slot/rows
+	x += column * windowSlotCascade
+	if row <= below {
+		return x, y + row*step
+	}
+	return x, y - (row-below)*step
+}
+
+// screenOf returns the screen showing most of the window at x, y, or the first one
+func screenOf(screens []screen, x, y, width, height int) screen {
+	best, bestArea := screens[0], 0
+	for _, s := range screens {
+		w := min(x+width, s.X+s.Width) - max(x, s.X)
+		h := min(y+height, s.Y+s.Height) - max(y, s.Y)
+		if w > 0 && h > 0 && w*h > bestArea {
+			best, bestArea = s, w*h
+		}
+	}
+	return best
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,52 @@
+//go:build !windows
+
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// processRunning reports whether the process pid is still running (signal 0 only checks it
+// exists; EPERM means it runs as another user)
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// moveToWindowSlot moves the window of this process titled title to its window slot (see
+// stackedPosition) on the monitor it is on, waiting up to 5 seconds for the GUI to create it
+// Wayland compositors place windows themselves, so this only works with X11
+func moveToWindowSlot(title string, slot int) error {
+	screens, err := listScreens()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("wmctrl"); err != nil {
+		return fmt.Errorf("wmctrl is not installed")
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(250 * time.Millisecond) {
+		output, err := exec.Command("wmctrl", "-l", "-p", "-G").Output()
+		if err != nil {
+			return fmt.Errorf("wmctrl failed: %v", err)
+		}
+		id, x, y, width, height, ok := findWmctrlWindow(string(output), title, os.Getpid())
+		if !ok {
+			continue
+		}
+		x, y = stackedPosition(screenOf(screens, x, y, width, height), width, height, slot)
+		if output, err := exec.Command("wmctrl", "-i", "-r", id, "-e", fmt.Sprintf("0,%d,%d,-1,-1", x, y)).CombinedOutput(); err != nil {
+			return fmt.Errorf("wmctrl failed: %v (output: %s)", err, strings.TrimSpace(string(output)))
+		}
+		log.Printf("Placement: window stacked in slot %d at %d,%d", slot, x, y)
+		return nil
+	}
+	return fmt.Errorf("window %q not found", title)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"os"
+	"testing"
+)
+
+// TestValidateStacking tests the -stacking values
+func TestValidateStacking(t *testing.T) {
+	for _, valid := range []string{"", StackingStack, StackingQueue, StackingOverlap} {
+		if err := ValidateStacking(valid); err != nil {
+			t.Errorf("ValidateStacking(%q) = %v", valid, err)
+		}
+	}
+	if err := ValidateStacking("cascade"); err == nil {
+		t.Error("ValidateStacking(cascade) accepted it")
+	}
+}
+
+// TestStackedPosition tests that stacked windows go below the centered one, then above it,
+// then into the next column
+func TestStackedPosition(t *testing.T) {
+	s := screen{X: 0, Y: 0, Width: 1000, Height: 1000}
+	tests := []struct {
+		slot int
+		x, y int
+	}{
+		{0, 300, 400},
+		{1, 300, 612},
+		{2, 300, 188},
+		{3, 340, 400},
+		{4, 340, 612},
+	}
+	for _, tt := range tests {
+		if x, y := stackedPosition(s, 400, 200, tt.slot); x != tt.x || y != tt.y {
+			t.Errorf("stackedPosition(slot %d) = %d,%d, want %d,%d", tt.slot, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+// TestWindowSlots tests taking, holding and releasing window slots, and taking over stale ones
+func TestWindowSlots(t *testing.T) {
+	dir := t.TempDir()
+	if !tryWindowSlot(dir, 0) {
+		t.Fatal("Could not take a free slot")
+	}
+	if tryWindowSlot(dir, 0) {
+		t.Error("Took a slot held by a running process")
+	}
+	releaseWindowSlot(dir, 0)
+	if !tryWindowSlot(dir, 0) {
+		t.Error("Could not take a released slot")
+	}
+
+	if err := os.WriteFile(windowSlotPath(dir, 1), []byte("not a process ID\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !tryWindowSlot(dir, 1) {
+		t.Error("Could not take over a stale slot")
+	}
+}
@@ -0,0 +1,62 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"log"
+	"syscall"
+	"unsafe"
+)
+
+// Process functions in kernel32.dll
+var (
+	openProcess        = syscall.NewLazyDLL("kernel32.dll").NewProc("OpenProcess")
+	getExitCodeProcess = syscall.NewLazyDLL("kernel32.dll").NewProc("GetExitCodeProcess")
+)
+
+// processRunning reports whether the process pid is still running
+func processRunning(pid int) bool {
+	const (
+		PROCESS_QUERY_LIMITED_INFORMATION = 0x1000
+		STILL_ACTIVE                      = 259
+	)
+	handle, _, _ := openProcess.Call(PROCESS_QUERY_LIMITED_INFORMATION, 0, uintptr(pid))
+	if handle == 0 {
+		return false
+	}
+	defer syscall.CloseHandle(syscall.Handle(handle))
+	var code uint32
+	if ok, _, _ := getExitCodeProcess.Call(handle, uintptr(unsafe.Pointer(&code))); ok == 0 {
+		return true // Exists, but cannot tell more
+	}
+	return code == STILL_ACTIVE
+}
+
+// moveToWindowSlot moves hwnd to its window slot (see stackedPosition) on the monitor it is on
+func moveToWindowSlot(hwnd uintptr, slot int) error {
+	const (
+		SWP_NOSIZE     = 0x0001
+		SWP_NOZORDER   = 0x0004
+		SWP_NOACTIVATE = 0x0010
+	)
+	screens, err := listScreens()
+	if err != nil {
+		return err
+	}
+	if len(screens) == 0 {
+		return fmt.Errorf("no monitors found")
+	}
+	var rect winRect
+	getWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&rect)))
+	width, height := int(rect.Right-rect.Left), int(rect.Bottom-rect.Top)
+	s := screenOf(screens, int(rect.Left), int(rect.Top), width, height)
+	x, y := stackedPosition(s, width, height, slot)
+	if ok, _, err := setWindowPos.Call(hwnd, 0, uintptr(x), uintptr(y), 0, 0, SWP_NOSIZE|SWP_NOZORDER|SWP_NOACTIVATE); ok == 0 {
+		return fmt.Errorf("SetWindowPos failed: %v", err)
+	}
+	log.Printf("Placement: window stacked in slot %d at %d,%d", slot, x, y)
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
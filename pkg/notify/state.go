@@ -0,0 +1,256 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+)
+
+// States of Notification.State: the icon of the window, changed with UpdateState while it is open
+const (
+	StatePending = "pending" // Hourglass: the job has not started yet
+	StateWorking = "working" // Spinner: the job is running
+	StateSuccess = "success" // Green check mark
+	StateFailed  = "failed"  // Red error icon
+)
+
+// statePollInterval is how often a window with a StateID looks for updates
+const statePollInterval = 250 * time.Millisecond
+
+// stateIDPattern matches a valid Notification.StateID, which names a file
+var stateIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// StateUpdate is the state of the window showing a Notification.StateID
+type StateUpdate struct {
+	State   string    `json:"state"`
+	Message string    `json:"message,omitempty"` // Replaces the message of the window, empty to keep it
+	Updated time.Time `json:"updated"`
+}
+
+// ValidateState checks a -state value
+func ValidateState(state string) error {
+	switch state {
+	case "", StatePending, StateWorking, StateSuccess, StateFailed:
+		return nil
+	}
+	return fmt.Errorf("invalid state %q (use %s, %s, %s or %s)", state, StatePending, StateWorking, StateSuccess, StateFailed)
+}
+
+// ValidateStateID checks a -state-id value: up to 64 letters, digits, dots, dashes and underscores
+func ValidateStateID(id string) error {
+	if id != "" && !stateIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid state ID %q (use up to 64 letters, digits, '.', '-' and '_')", id)
+	}
+	return nil
+}
+
+// ParseStateIcons parses -state-icons, "state=image,..." with an image file per state
+func ParseStateIcons(value string) (map[string]string, error) {
+	icons := map[string]string{}
+	if strings.TrimSpace(value) == "" {
+		return icons, nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		state, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid -state-icons entry %q (use state=image)", entry)
+		}
+		if state == "" {
+			return nil, fmt.Errorf("invalid -state-icons entry %q: no state", entry)
+		}
+		if err := ValidateState(state); err != nil {
+			return nil, fmt.Errorf("invalid -state-icons entry %q: %v", entry, err)
+		}
+		icons[state] = path
+	}
+	return icons, nil
+}
+
+// FormatStateIcons returns icons as a -state-icons value, in a stable order
+func FormatStateIcons(icons map[string]string) string {
+	entries := make([]string, 0, len(icons))
+	for state, path := range icons {
+		entries = append(entries, state+"="+path)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// statePath returns the file holding the state of the window showing id, for the current user
+func statePath(id string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "states", id+".json"), nil
+}
+
+// UpdateState changes the state of the window showing id, optionally with a new message; it
+// fails when no window of the current user is showing id
+func UpdateState(id, state, message string) error {
+	if err := ValidateStateID(id); err != nil {
+		return err
+	}
+	if id == "" {
+		return fmt.Errorf("no state ID")
+	}
+	if err := ValidateState(state); err != nil {
+		return err
+	}
+	if state == "" {
+		return fmt.Errorf("no state")
+	}
+	if _, err := readState(id); err != nil {
+		return err
+	}
+	return writeState(id, StateUpdate{State: state, Message: NormalizeText(message), Updated: time.Now()})
+}
+
+// readState returns the state of the window showing id
+func readState(id string) (StateUpdate, error) {
+	path, err := statePath(id)
+	if err != nil {
+		return StateUpdate{}, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return StateUpdate{}, fmt.Errorf("no notification is showing state ID %q", id)
+	}
+	if err != nil {
+		return StateUpdate{}, fmt.Errorf("could not read state: %v", err)
+	}
+	var update StateUpdate
+	if err := json.Unmarshal(data, &update); err != nil {
+		return StateUpdate{}, fmt.Errorf("could not parse state %s: %v", path, err)
+	}
+	return update, nil
+}
+
+// writeState stores the state of the window showing id
+func writeState(id string, update StateUpdate) error {
+	path, err := statePath(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create state directory: %v", err)
+	}
+	data, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	// Written to a temporary file first so the window never reads half a file
+	tmp := path + fmt.Sprintf(".%d.tmp", os.Getpid())
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("could not write state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("could not write state: %v", err)
+	}
+	return nil
+}
+
+// watchState publishes the initial state of the window of n, then calls apply with every
+// update; the returned function, called when the window has closed, stops watching and removes
+// the state file, so updaters can tell the window is gone
+func watchState(n Notification, apply func(StateUpdate)) (done func()) {
+	last := StateUpdate{State: n.initialState(), Updated: time.Now()}
+	if err := writeState(n.StateID, last); err != nil {
+		log.Printf("Warning: State updates unavailable: %v", err)
+		return func() {}
+	}
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(statePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			update, err := readState(n.StateID)
+			if err != nil || update.Updated.Equal(last.Updated) {
+				continue
+			}
+			last = update
+			log.Printf("State: %s", update.State)
+			apply(update)
+		}
+	}()
+	return func() {
+		close(stop)
+		<-finished
+		if path, err := statePath(n.StateID); err == nil {
+			os.Remove(path)
+		}
+	}
+}
+
+// hasState reports whether the window of n shows a state icon: -state or -state-id is set
+func (n Notification) hasState() bool {
+	return n.State != "" || n.StateID != ""
+}
+
+// initialState is the state the window of n opens in, StatePending when only the StateID is set
+func (n Notification) initialState() string {
+	if n.State == "" {
+		return StatePending
+	}
+	return n.State
+}
+
+// stateEmoji is the WebView icon of a state
+func stateEmoji(state string) string {
+	switch state {
+	case StatePending:
+		return "⏳"
+	case StateWorking:
+		return `<span class="spin">⚙️</span>`
+	case StateSuccess:
+		return "✅"
+	case StateFailed:
+		return "❌"
+	}
+	return "📢"
+}
+
+// stateIcon returns the Fyne icon of a state: its image from icons (animated GIFs play until stop
+// is closed), or a theme icon in the color of the state
+func stateIcon(state string, icons map[string]string, stop <-chan struct{}) fyne.CanvasObject {
+	if image := loadIcon(icons[state], stop); image != nil {
+		return image
+	}
+	var icon fyne.Resource
+	switch state {
+	case StatePending:
+		icon = theme.NewThemedResource(theme.HistoryIcon())
+	case StateWorking:
+		icon = theme.NewPrimaryThemedResource(theme.ViewRefreshIcon())
+	case StateSuccess:
+		icon = theme.NewSuccessThemedResource(theme.ConfirmIcon())
+	case StateFailed:
+		icon = theme.NewErrorThemedResource(theme.ErrorIcon())
+	default:
+		return nil
+	}
+	image := canvas.NewImageFromResource(icon)
+	image.FillMode = canvas.ImageFillContain
+	image.SetMinSize(fyne.NewSize(48, 48))
+	return image
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestParseStateIcons tests -state-icons parsing, its errors and FormatStateIcons round trips
+func TestParseStateIcons(t *testing.T) {
+	icons, err := ParseStateIcons(" working=spin.gif, failed=/icons/red.png")
+	if err != nil {
+		t.Fatalf("ParseStateIcons failed: %v", err)
+	}
+	if len(icons) != 2 || icons[StateWorking] != "spin.gif" || icons[StateFailed] != "/icons/red.png" {
+		t.Errorf("ParseStateIcons = %v", icons)
+	}
+	if got, want := FormatStateIcons(icons), "failed=/icons/red.png,working=spin.gif"; got != want {
+		t.Errorf("FormatStateIcons = %q, want %q", got, want)
+	}
+	if icons, err := ParseStateIcons(""); err != nil || len(icons) != 0 {
+		t.Errorf("ParseStateIcons(\"\") = %v, %v, want no icons", icons, err)
+	}
+	for _, value := range []string{"working", "working=", "=spin.gif", "done=check.png"} {
+		if _, err := ParseStateIcons(value); err == nil {
+			t.Errorf("ParseStateIcons(%q) should fail", value)
+		}
+	}
+
+	for _, id := range []string{"", "backup", "run-4321", "db1.nightly_backup"} {
+		if err := ValidateStateID(id); err != nil {
+			t.Errorf("ValidateStateID(%q) failed: %v", id, err)
+		}
+	}
+	for _, id := range []string{"../backup", ".hidden", "a b", "x/y"} {
+		if err := ValidateStateID(id); err == nil {
+			t.Errorf("ValidateStateID(%q) should fail", id)
+		}
+	}
+}
+
+// TestWatchState tests that UpdateState reaches the window and fails once it has closed
+func TestWatchState(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // os.UserConfigDir on Linux
+	t.Setenv("HOME", dir)            // macOS
+	t.Setenv("AppData", dir)         // Windows
+
+	if err := UpdateState("backup", StateSuccess, ""); err == nil {
+		t.Error("UpdateState succeeded with no window showing the state ID")
+	}
+
+	var mu sync.Mutex
+	var updates []StateUpdate
+	done := watchState(Notification{StateID: "backup"}, func(update StateUpdate) {
+		mu.Lock()
+		updates = append(updates, update)
+		mu.Unlock()
+	})
+	if update, err := readState("backup"); err != nil || update.State != StatePending {
+		t.Errorf("initial state = %+v, %v, want %s", update, err, StatePending)
+	}
+	if err := UpdateState("backup", "done", ""); err == nil {
+		t.Error("UpdateState accepted an invalid state")
+	}
+	if err := UpdateState("backup", StateFailed, "Disk full"); err != nil {
+		t.Fatalf("UpdateState failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(updates)
+		mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(statePollInterval / 2)
+	}
+	done()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(updates) != 1 || updates[0].State != StateFailed || updates[0].Message != "Disk full" {
+		t.Errorf("updates = %+v, want one failed update with its message", updates)
+	}
+	if err := UpdateState("backup", StateSuccess, ""); err == nil {
+		t.Error("UpdateState succeeded after the window closed")
+	}
+}
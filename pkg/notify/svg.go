@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fyne-io/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// isSVGIcon reports whether the icon at path is an SVG, by its extension or, for downloaded
+// and extension-less files, its first bytes
+func isSVGIcon(path string, head []byte) bool {
+	if strings.EqualFold(filepath.Ext(path), ".svg") {
+		return true
+	}
+	start := strings.ToLower(string(bytes.TrimSpace(head)))
+	return strings.HasPrefix(start, "<svg") || (strings.HasPrefix(start, "<?xml") && strings.Contains(start, "<svg"))
+}
+
+// prepareSVGIcon returns the SVG icon at path rasterized to a PNG of iconMaxSide pixels on its
+// longest side, cached per user like downscaled icons, since toasts and the WebView window
+// cannot all show SVG, and Fyne draws it again on every resize
+func prepareSVGIcon(path string, info os.FileInfo) (string, error) {
+	cachePath := iconCachePath(path, info)
+	if _, err := os.Stat(cachePath); err == nil {
+		log.Printf("Using cached rasterized icon for %s", path)
+		touchCachedIcon(cachePath)
+		return cachePath, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read icon: %v", err)
+	}
+	img, err := rasterizeSVG(data, iconMaxSide)
+	if err != nil {
+		return "", fmt.Errorf("icon %s is not a valid SVG: %v", path, err)
+	}
+	log.Printf("Icon %s (svg) rasterized to %dx%d", path, img.Bounds().Dx(), img.Bounds().Dy())
+	if err := writeCachedIcon(cachePath, img); err != nil {
+		return "", err
+	}
+	return cachePath, nil
+}
+
+// rasterizeSVG draws an SVG image with side pixels on its longest side, keeping its aspect ratio
+func rasterizeSVG(data []byte, side int) (img image.Image, err error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	w, h := icon.ViewBox.W, icon.ViewBox.H
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("no width, height or viewBox")
+	}
+	width, height := side, side
+	if w > h {
+		height = max(1, int(float64(side)*h/w+0.5))
+	} else {
+		width = max(1, int(float64(side)*w/h+0.5))
+	}
+
+	icon.SetTarget(0, 0, float64(width), float64(height))
+	rgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scanner := rasterx.NewScannerGV(width, height, rgba, rgba.Bounds())
+	defer func() {
+		if r := recover(); r != nil { // The SVG parser is lenient, the renderer less so
+			img, err = nil, fmt.Errorf("could not draw it: %v", r)
+		}
+	}()
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1)
+	return rgba, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,43 @@
+package notify
+
+import "testing"
+
+// TestRasterizeSVG tests that an SVG is drawn at the icon size, keeping its aspect ratio
+func TestRasterizeSVG(t *testing.T) {
+	logo := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 10"><rect width="20" height="10" fill="#ff0000"/></svg>`)
+	img, err := rasterizeSVG(logo, iconMaxSide)
+	if err != nil {
+		t.Fatalf("rasterizeSVG failed: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != iconMaxSide || b.Dy() != iconMaxSide/2 {
+		t.Errorf("Rasterized to %dx%d, want %dx%d", b.Dx(), b.Dy(), iconMaxSide, iconMaxSide/2)
+	}
+	if r, g, b, a := img.At(iconMaxSide/2, iconMaxSide/4).RGBA(); r>>8 != 0xff || g>>8 != 0 || b>>8 != 0 || a>>8 != 0xff {
+		t.Errorf("Expected red in the middle, got %v", img.At(iconMaxSide/2, iconMaxSide/4))
+	}
+
+	for _, bad := range []string{"not an image", `<svg xmlns="http://www.w3.org/2000/svg"></svg>`} {
+		if _, err := rasterizeSVG([]byte(bad), iconMaxSide); err == nil {
+			t.Errorf("rasterizeSVG(%q) should fail", bad)
+		}
+	}
+}
+
+// TestIsSVGIcon tests recognizing SVG icons by extension or content
+func TestIsSVGIcon(t *testing.T) {
+	tests := []struct {
+		path, head string
+		want       bool
+	}{
+		{"/opt/agent/Logo.SVG", "", true},
+		{"/cache/url-1234.png", "\n<svg xmlns=\"http://www.w3.org/2000/svg\">", true},
+		{"/cache/url-1234.png", "<?xml version=\"1.0\"?>\n<svg>", true},
+		{"/opt/agent/logo.png", "\x89PNG\r\n", false},
+		{"/opt/agent/data.xml", "<?xml version=\"1.0\"?><items/>", false},
+	}
+	for _, tt := range tests {
+		if got := isSVGIcon(tt.path, []byte(tt.head)); got != tt.want {
+			t.Errorf("isSVGIcon(%q, %q) = %v, want %v", tt.path, tt.head, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Session filters for Target.Session, besides a session ID
+const (
+	TargetAnySession          = "any"          // Every session of the user (also when empty)
+	TargetActiveSession       = "active"       // Connected sessions
+	TargetDisconnectedSession = "disconnected" // Windows: disconnected sessions (delivered as Options.Disconnected says)
+	TargetConsoleSession      = "console"      // Windows and macOS: the session at the physical console
+	TargetRDPSession          = "rdp"          // Windows: Remote Desktop sessions
+)
+
+// TargetFailed is the TargetResult.Action of a target whose notify process could not be launched
+const TargetFailed = "failed"
+
+// Target is one recipient of the elevated fan-out (Options.Targets): a user, which of their
+// sessions to reach, and the title and message to show them instead of the notification's own
+type Target struct {
+	Username string // Matched case-insensitively, with or without a DOMAIN\ prefix
+	Session  string // One of the Target*Session filters, or a session ID; empty for any
+	Title    string // Shown instead of Notification.Title when not empty
+	Message  string // Shown instead of Notification.Message when not empty
+}
+
+// TargetResult is what the fan-out did for one Target, in the order of Options.Targets
+type TargetResult struct {
+	Username string   `json:"username"`
+	Sessions []string `json:"sessions,omitempty"` // IDs of the sessions the notification was launched in
+	Action   string   `json:"action"`             // ActionDelivered, ActionSkipped (no matching session) or TargetFailed
+	Error    string   `json:"error,omitempty"`
+}
+
+// errSessionSkipped is the delivery error of a session skipped by Options.Disconnected
+var errSessionSkipped = errors.New("disconnected session skipped")
+
+// userSession is a session the elevated fan-out can reach, as Targets are matched against it
+type userSession struct {
+	Username     string
+	ID           string
+	Name         string // "console", "rdp-tcp#0" (Windows), or the session type ("x11", "wayland")
+	Disconnected bool
+}
+
+// targetDelivery is the notification the fan-out launches in one session
+type targetDelivery struct {
+	session int // Index in the platform's session list
+	target  int // Index in Options.Targets, -1 without targets
+	opts    Options
+}
+
+// ValidateTargetSession checks a Target.Session filter
+func ValidateTargetSession(session string) error {
+	switch strings.ToLower(session) {
+	case "", TargetAnySession, TargetActiveSession, TargetDisconnectedSession, TargetConsoleSession, TargetRDPSession:
+		return nil
+	}
+	for _, r := range session {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("invalid session %q (use %s, %s, %s, %s, %s or a session ID)", session,
+				TargetAnySession, TargetActiveSession, TargetDisconnectedSession, TargetConsoleSession, TargetRDPSession)
+		}
+	}
+	return nil
+}
+
+// matches reports whether s is one of the sessions t asks for
+func (t Target) matches(s userSession) bool {
+	if !strings.EqualFold(bareUsername(t.Username), bareUsername(s.Username)) {
+		return false
+	}
+	switch session := strings.ToLower(t.Session); session {
+	case "", TargetAnySession:
+		return true
+	case TargetActiveSession:
+		return !s.Disconnected
+	case TargetDisconnectedSession:
+		return s.Disconnected
+	case TargetConsoleSession:
+		return strings.EqualFold(s.Name, "console")
+	case TargetRDPSession:
+		return strings.HasPrefix(strings.ToLower(s.Name), "rdp-")
+	default:
+		return session == s.ID
+	}
+}
+
+// bareUsername strips the DOMAIN\ prefix of a Windows account name
+func bareUsername(username string) string {
+	return username[strings.LastIndex(username, `\`)+1:]
+}
+
+// apply returns n with the title and message overrides of t
+func (t Target) apply(n Notification) Notification {
+	if t.Title != "" {
+		n.Title = t.Title
+	}
+	if t.Message != "" {
+		n.Message = t.Message
+		n.HTML = "" // The override is plain text, not the -html of everyone else
+	}
+	return n
+}
+
+// planDeliveries returns the notifications the fan-out launches in sessions: opts in every session
+// without Targets, otherwise each target's notification in the sessions it matches (a session
+// matched by several targets gets each of them)
+func planDeliveries(opts Options, sessions []userSession) []targetDelivery {
+	var deliveries []targetDelivery
+	if len(opts.Targets) == 0 {
+		for i := range sessions {
+			deliveries = append(deliveries, targetDelivery{session: i, target: -1, opts: opts})
+		}
+		return deliveries
+	}
+	for t, target := range opts.Targets {
+		for i, session := range sessions {
+			if target.matches(session) {
+				targetOpts := opts
+				targetOpts.Notification = target.apply(opts.Notification)
+				targetOpts.Targets = nil
+				deliveries = append(deliveries, targetDelivery{session: i, target: t, opts: targetOpts})
+			}
+		}
+	}
+	return deliveries
+}
+
+// targetResults returns the result of each of targets from the deliveries planDeliveries
+// returned for them and their errors (nil when launched), logging them
+func targetResults(targets []Target, sessions []userSession, deliveries []targetDelivery, errs []error) []TargetResult {
+	if len(targets) == 0 {
+		return nil
+	}
+	results := make([]TargetResult, len(targets))
+	for t, target := range targets {
+		results[t] = TargetResult{Username: target.Username, Action: ActionSkipped}
+	}
+	for i, delivery := range deliveries {
+		result := &results[delivery.target]
+		if errors.Is(errs[i], errSessionSkipped) {
+			continue
+		}
+		if errs[i] != nil {
+			if result.Action == ActionSkipped {
+				result.Action = TargetFailed
+			}
+			result.Error = errs[i].Error()
+			continue
+		}
+		result.Action = ActionDelivered
+		result.Sessions = append(result.Sessions, sessions[delivery.session].ID)
+	}
+	for _, result := range results {
+		switch result.Action {
+		case ActionDelivered:
+			log.Printf("Target %s: delivered to session(s) %s", result.Username, strings.Join(result.Sessions, ", "))
+		case ActionSkipped:
+			log.Printf("Target %s: no matching session, skipped", result.Username)
+		default:
+			log.Printf("Target %s: failed: %s", result.Username, result.Error)
+		}
+	}
+	return results
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
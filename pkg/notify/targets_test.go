@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestTargetMatches tests the username and session filters of a Target
+func TestTargetMatches(t *testing.T) {
+	console := userSession{Username: `CORP\alice`, ID: "1", Name: "console"}
+	rdp := userSession{Username: "alice", ID: "3", Name: "rdp-tcp#0"}
+	disconnected := userSession{Username: "alice", ID: "4", Disconnected: true}
+	tests := []struct {
+		target  Target
+		session userSession
+		want    bool
+	}{
+		{Target{Username: "alice"}, console, true},
+		{Target{Username: "ALICE", Session: TargetAnySession}, rdp, true},
+		{Target{Username: `corp\alice`}, rdp, true},
+		{Target{Username: "bob"}, console, false},
+		{Target{Username: "alice", Session: TargetConsoleSession}, console, true},
+		{Target{Username: "alice", Session: TargetConsoleSession}, rdp, false},
+		{Target{Username: "alice", Session: TargetRDPSession}, rdp, true},
+		{Target{Username: "alice", Session: TargetActiveSession}, disconnected, false},
+		{Target{Username: "alice", Session: TargetDisconnectedSession}, disconnected, true},
+		{Target{Username: "alice", Session: "3"}, rdp, true},
+		{Target{Username: "alice", Session: "3"}, console, false},
+	}
+	for _, test := range tests {
+		if got := test.target.matches(test.session); got != test.want {
+			t.Errorf("%+v matches %+v = %v, want %v", test.target, test.session, got, test.want)
+		}
+	}
+}
+
+// TestValidateTargetSession tests the accepted session filters
+func TestValidateTargetSession(t *testing.T) {
+	for _, session := range []string{"", "any", "Active", "disconnected", "console", "rdp", "12"} {
+		if err := ValidateTargetSession(session); err != nil {
+			t.Errorf("ValidateTargetSession(%q) = %v", session, err)
+		}
+	}
+	for _, session := range []string{"tty1", "-1", "rdp-tcp#0"} {
+		if err := ValidateTargetSession(session); err == nil {
+			t.Errorf("ValidateTargetSession(%q) accepted an invalid session", session)
+		}
+	}
+}
+
+// TestPlanDeliveries tests which notification each session gets with and without targets
+func TestPlanDeliveries(t *testing.T) {
+	sessions := []userSession{
+		{Username: "alice", ID: "1", Name: "console"},
+		{Username: "bob", ID: "2", Name: "rdp-tcp#0"},
+		{Username: "alice", ID: "3", Name: "rdp-tcp#1"},
+	}
+	opts := Options{Notification: Notification{Title: "Disk quota", Message: "Clean up", HTML: "<b>Clean up</b>"}}
+
+	if deliveries := planDeliveries(opts, sessions); len(deliveries) != len(sessions) {
+		t.Fatalf("without targets, got %d deliveries, want one per session", len(deliveries))
+	}
+
+	opts.Targets = []Target{
+		{Username: "alice", Session: TargetRDPSession, Message: "You use 91%"},
+		{Username: "bob", Title: "Bob's quota"},
+		{Username: "carol"},
+	}
+	deliveries := planDeliveries(opts, sessions)
+	if len(deliveries) != 2 {
+		t.Fatalf("got %d deliveries, want 2: %+v", len(deliveries), deliveries)
+	}
+	alice, bob := deliveries[0], deliveries[1]
+	if alice.session != 2 || alice.target != 0 || alice.opts.Message != "You use 91%" || alice.opts.HTML != "" {
+		t.Errorf("alice's delivery = %+v", alice)
+	}
+	if bob.session != 1 || bob.target != 1 || bob.opts.Title != "Bob's quota" || bob.opts.HTML == "" {
+		t.Errorf("bob's delivery = %+v, want his title and the shared HTML message", bob)
+	}
+	if alice.opts.Targets != nil {
+		t.Errorf("a delivery still carries the targets")
+	}
+}
+
+// TestTargetResults tests the per-target results from the launch errors of the deliveries
+func TestTargetResults(t *testing.T) {
+	targets := []Target{{Username: "alice"}, {Username: "bob"}, {Username: "carol"}, {Username: "dave"}}
+	sessions := []userSession{
+		{Username: "alice", ID: "1"},
+		{Username: "alice", ID: "3"},
+		{Username: "bob", ID: "2"},
+		{Username: "dave", ID: "5", Disconnected: true},
+	}
+	deliveries := []targetDelivery{
+		{session: 0, target: 0},
+		{session: 1, target: 0},
+		{session: 2, target: 1},
+		{session: 3, target: 3},
+	}
+	errs := []error{nil, errors.New("access denied"), errors.New("access denied"), errSessionSkipped}
+
+	want := []TargetResult{
+		{Username: "alice", Sessions: []string{"1"}, Action: ActionDelivered, Error: "access denied"},
+		{Username: "bob", Action: TargetFailed, Error: "access denied"},
+		{Username: "carol", Action: ActionSkipped},
+		{Username: "dave", Action: ActionSkipped},
+	}
+	if got := targetResults(targets, sessions, deliveries, errs); !reflect.DeepEqual(got, want) {
+		t.Errorf("targetResults = %+v, want %+v", got, want)
+	}
+	if got := targetResults(nil, sessions, deliveries[:1], errs[:1]); got != nil {
+		t.Errorf("targetResults without targets = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,571 @@
+package notify
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// templateTagPattern matches a {{...}} tag; tags that are not a variable, function or
+// conditional (such as {{localtime:...}}) are left as written
+var templateTagPattern = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// templateFuncs are the functions of {{NAME ARG...}} tags, called with the values of their arguments
+var templateFuncs = map[string]func(r *templateRenderer, args []string) (string, error){
+	"upper":         templateUpper,
+	"lower":         templateLower,
+	"plural":        templatePlural,
+	"s":             templateS,
+	"humanBytes":    templateHumanBytes,
+	"humanDuration": templateHumanDuration,
+	"date":          templateDate,
+	"default":       templateDefault,
+}
+
+// templateWord is a word of a tag: a name, a number or a "quoted string"
+type templateWord struct {
+	text   string
+	quoted bool
+}
+
+// templateNode is text, a tag or an {{if}} block of a parsed template
+type templateNode struct {
+	raw             string         // The text, or the tag as written
+	inner           string         // The inside of a tag, trimmed
+	words           []templateWord // The words of a tag; nil for text
+	then, otherwise []templateNode // The blocks of an {{if}}
+	elseRaw, endRaw string         // The {{else}} (if any) and {{end}} of an {{if}} as written
+}
+
+// templateRenderer renders a parsed template
+type templateRenderer struct {
+	vars       map[string]string
+	loc        *time.Location
+	now        time.Time
+	lastNumber string // The last number shown, which a bare {{s}} pluralizes; "" when unknown
+}
+
+// RenderTemplate renders the variables, functions and conditionals of text with vars:
+//
+//	{{name}}                       the variable name
+//	{{upper name}} {{lower name}}  the variable in upper or lower case
+//	{{plural count "box" "boxes"}} the singular when count is 1, otherwise the plural (default: singular+"s")
+//	{{s}} {{s count}}              "s" unless the count (default: the last number shown) is 1
+//	{{humanBytes size}}            a byte count as "1.5 GB"
+//	{{humanDuration secs}}         seconds or a duration ("90m", "2d") as "1h 30m"
+//	{{date VALUE [+OFFSET] [LAYOUT]}} a time (as in {{localtime}}, "now+2d" and variables too) in loc;
+//	                               {{date}} alone is today's date
+//	{{default name "fallback"}}    the variable, or fallback when it is empty or missing
+//	{{if COND}}...{{else}}...{{end}} COND is a value (true unless empty, 0 or false) or
+//	                               "A OP B" with OP ==, != or (numeric) <, <=, >, >=
+//
+// Arguments are variable names, numbers or "quoted strings". Tags using a variable vars
+// lacks are left as written (so a later pass with more variables can render them), as are
+// tags that are neither a variable nor a function, such as {{localtime:...}}
+func RenderTemplate(text string, vars map[string]string, loc *time.Location, now time.Time) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	nodes, err := parseTemplate(text)
+	if err != nil {
+		return text, err
+	}
+	r := &templateRenderer{vars: vars, loc: loc, now: now}
+	var b strings.Builder
+	if err := r.render(&b, nodes); err != nil {
+		return text, err
+	}
+	return b.String(), nil
+}
+
+// WithTemplate returns n with the title and message rendered by RenderTemplate with vars,
+// dates in the display zone
+func (n Notification) WithTemplate(vars map[string]string, now time.Time) (Notification, error) {
+	loc, err := n.displayLocation()
+	if err != nil {
+		return n, err
+	}
+	if n.Title, err = RenderTemplate(n.Title, vars, loc, now); err != nil {
+		return n, err
+	}
+	if n.Message, err = RenderTemplate(n.Message, vars, loc, now); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// TemplateEnvPrefix starts the names of the environment variables templates can read as
+// env.NAME, so a template cannot read secrets such as AWS_SECRET_ACCESS_KEY
+const TemplateEnvPrefix = "KRANKYBEAR_"
+
+// BuiltinVars returns the template variables of the session showing a notification:
+// hostname, username (without a Windows domain), time (HH:MM in loc) and env.NAME for each
+// environment variable starting with TemplateEnvPrefix; {{date}} is the template function
+func BuiltinVars(loc *time.Location, now time.Time) map[string]string {
+	vars := map[string]string{"time": now.In(loc).Format("15:04")}
+	if hostname, err := os.Hostname(); err == nil {
+		vars["hostname"] = hostname
+	}
+	if u, err := user.Current(); err == nil {
+		username := u.Username
+		if i := strings.LastIndex(username, `\`); i >= 0 {
+			username = username[i+1:]
+		}
+		vars["username"] = username
+	}
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, TemplateEnvPrefix) {
+			vars["env."+name] = value
+		}
+	}
+	return vars
+}
+
+// parseTemplate splits text into text, tags and {{if}} blocks
+func parseTemplate(text string) ([]templateNode, error) {
+	type block struct {
+		node     templateNode
+		inElse   bool
+		children []templateNode
+	}
+	var stack []*block
+	var top []templateNode
+	appendNode := func(node templateNode) {
+		if len(stack) == 0 {
+			top = append(top, node)
+			return
+		}
+		b := stack[len(stack)-1]
+		b.children = append(b.children, node)
+	}
+
+	last := 0
+	for _, loc := range templateTagPattern.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > last {
+			appendNode(templateNode{raw: text[last:loc[0]]})
+		}
+		last = loc[1]
+		raw := text[loc[0]:loc[1]]
+		words, ok := splitTemplateWords(text[loc[2]:loc[3]])
+		if !ok || len(words) == 0 || words[0].quoted {
+			appendNode(templateNode{raw: raw})
+			continue
+		}
+		switch words[0].text {
+		case "if":
+			if len(words) != 2 && len(words) != 4 {
+				return nil, fmt.Errorf("invalid template %s: expected {{if VALUE}} or {{if A OP B}}", raw)
+			}
+			stack = append(stack, &block{node: templateNode{raw: raw, words: words}})
+		case "else":
+			if len(stack) == 0 || stack[len(stack)-1].inElse {
+				return nil, fmt.Errorf("invalid template: {{else}} without {{if}}")
+			}
+			b := stack[len(stack)-1]
+			b.node.then, b.children, b.node.elseRaw, b.inElse = b.children, nil, raw, true
+		case "end":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("invalid template: {{end}} without {{if}}")
+			}
+			b := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if b.inElse {
+				b.node.otherwise = b.children
+			} else {
+				b.node.then = b.children
+			}
+			b.node.endRaw = raw
+			appendNode(b.node)
+		default:
+			appendNode(templateNode{raw: raw, inner: text[loc[2]:loc[3]], words: words})
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("invalid template %s: missing {{end}}", stack[len(stack)-1].node.raw)
+	}
+	if last < len(text) {
+		appendNode(templateNode{raw: text[last:]})
+	}
+	return top, nil
+}
+
+// splitTemplateWords splits the inside of a tag at spaces, keeping "quoted strings" whole;
+// ok is false for an unterminated quote
+func splitTemplateWords(s string) (words []templateWord, ok bool) {
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return nil, false
+			}
+			words = append(words, templateWord{text: s[1 : end+1], quoted: true})
+			s = s[end+2:]
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		words = append(words, templateWord{text: s[:end]})
+		s = s[end:]
+	}
+	return words, true
+}
+
+// render writes nodes to b
+func (r *templateRenderer) render(b *strings.Builder, nodes []templateNode) error {
+	for _, node := range nodes {
+		switch {
+		case node.words == nil:
+			b.WriteString(node.raw)
+		case node.endRaw != "":
+			if err := r.renderIf(b, node); err != nil {
+				return err
+			}
+		default:
+			s, err := r.evaluate(node)
+			if err != nil {
+				return err
+			}
+			b.WriteString(s)
+		}
+	}
+	return nil
+}
+
+// renderIf writes the block of an {{if}} that its condition selects, or the whole block as
+// written when the condition uses a missing variable
+func (r *templateRenderer) renderIf(b *strings.Builder, node templateNode) error {
+	cond, known, err := r.condition(node.words[1:])
+	if err != nil {
+		return fmt.Errorf("invalid template %s: %v", node.raw, err)
+	}
+	if !known {
+		b.WriteString(node.raw)
+		if err := r.render(b, node.then); err != nil {
+			return err
+		}
+		if node.elseRaw != "" {
+			b.WriteString(node.elseRaw)
+			if err := r.render(b, node.otherwise); err != nil {
+				return err
+			}
+		}
+		b.WriteString(node.endRaw)
+		return nil
+	}
+	if cond {
+		return r.render(b, node.then)
+	}
+	return r.render(b, node.otherwise)
+}
+
+// condition evaluates the words of an {{if}} after "if"
+func (r *templateRenderer) condition(words []templateWord) (cond, known bool, err error) {
+	if len(words) == 3 && !isTemplateOperator(words[1].text) {
+		return false, false, fmt.Errorf("unknown operator %q", words[1].text)
+	}
+	values, known := r.values(words)
+	if !known {
+		return false, false, nil
+	}
+	if len(values) == 1 {
+		v := strings.ToLower(strings.TrimSpace(values[0]))
+		return v != "" && v != "0" && v != "false", true, nil
+	}
+	a, op, c := values[0], words[1].text, values[2]
+	switch op {
+	case "==":
+		return a == c, true, nil
+	case "!=":
+		return a != c, true, nil
+	}
+	x, err := parseTemplateNumber(a)
+	if err != nil {
+		return false, true, err
+	}
+	y, err := parseTemplateNumber(c)
+	if err != nil {
+		return false, true, err
+	}
+	switch op {
+	case "<":
+		return x < y, true, nil
+	case "<=":
+		return x <= y, true, nil
+	case ">":
+		return x > y, true, nil
+	}
+	return x >= y, true, nil
+}
+
+// evaluate renders a tag: a variable or a function call; anything else, and tags using a
+// missing variable, are left as written
+func (r *templateRenderer) evaluate(node templateNode) (string, error) {
+	name := node.words[0].text
+	fn, isFunc := templateFuncs[name]
+	if !isFunc {
+		value, ok := r.vars[node.inner] // Whole, so names with spaces work too
+		if !ok {
+			r.lastNumber = ""
+			return node.raw, nil
+		}
+		if _, err := parseTemplateNumber(value); err == nil {
+			r.lastNumber = value
+		}
+		return value, nil
+	}
+
+	args, known := r.values(node.words[1:])
+	if name == "default" && len(node.words) == 3 && !known {
+		args, known = []string{r.vars[node.words[1].text], node.words[2].text}, true
+	}
+	if !known || (name == "s" && len(args) == 0 && r.lastNumber == "") {
+		r.lastNumber = ""
+		return node.raw, nil
+	}
+	s, err := fn(r, args)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %v", node.raw, err)
+	}
+	return s, nil
+}
+
+// values returns the values of argument words: quoted strings and numbers as written,
+// names their variable, and "now..." as written for date; known is false when a variable is missing
+func (r *templateRenderer) values(words []templateWord) (values []string, known bool) {
+	for i, w := range words {
+		switch {
+		case w.quoted, i == 1 && len(words) == 3 && isTemplateOperator(w.text):
+			values = append(values, w.text)
+		case w.text == "now" || strings.HasPrefix(w.text, "now+") || strings.HasPrefix(w.text, "now-"),
+			strings.HasPrefix(w.text, "+"), strings.HasPrefix(w.text, "-"):
+			values = append(values, w.text)
+		default:
+			if _, err := strconv.ParseFloat(w.text, 64); err == nil {
+				values = append(values, w.text)
+				continue
+			}
+			value, ok := r.vars[w.text]
+			if !ok {
+				return nil, false
+			}
+			values = append(values, value)
+		}
+	}
+	return values, true
+}
+
+// isTemplateOperator reports whether s is a comparison operator of {{if A OP B}}
+func isTemplateOperator(s string) bool {
+	switch s {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// parseTemplateNumber parses a numeric template value
+func parseTemplateNumber(s string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return f, nil
+}
+
+// parseTemplateDuration parses a Go duration that may start with days ("2d", "1d12h")
+func parseTemplateDuration(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+	rest := s
+	if strings.HasPrefix(rest, "-") {
+		sign, rest = -1, rest[1:]
+	} else {
+		rest = strings.TrimPrefix(rest, "+")
+	}
+	var days time.Duration
+	if i := strings.IndexByte(rest, 'd'); i > 0 {
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		days, rest = time.Duration(n)*24*time.Hour, rest[i+1:]
+	}
+	if rest == "" {
+		return sign * days, nil
+	}
+	d, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return sign * (days + d), nil
+}
+
+// checkTemplateArgs checks that a function has between min and max arguments
+func checkTemplateArgs(args []string, min, max int, usage string) error {
+	if len(args) < min || len(args) > max {
+		return fmt.Errorf("expected %s", usage)
+	}
+	return nil
+}
+
+func templateUpper(r *templateRenderer, args []string) (string, error) {
+	if err := checkTemplateArgs(args, 1, 1, "upper VALUE"); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(args[0]), nil
+}
+
+func templateLower(r *templateRenderer, args []string) (string, error) {
+	if err := checkTemplateArgs(args, 1, 1, "lower VALUE"); err != nil {
+		return "", err
+	}
+	return strings.ToLower(args[0]), nil
+}
+
+func templatePlural(r *templateRenderer, args []string) (string, error) {
+	if err := checkTemplateArgs(args, 2, 3, `plural COUNT "singular" ["plural"]`); err != nil {
+		return "", err
+	}
+	count, err := parseTemplateNumber(args[0])
+	if err != nil {
+		return "", err
+	}
+	r.lastNumber = args[0]
+	if count == 1 {
+		return args[1], nil
+	}
+	if len(args) == 3 {
+		return args[2], nil
+	}
+	return args[1] + "s", nil
+}
+
+func templateS(r *templateRenderer, args []string) (string, error) {
+	if err := checkTemplateArgs(args, 0, 1, "s or s COUNT"); err != nil {
+		return "", err
+	}
+	value := r.lastNumber
+	if len(args) == 1 {
+		value = args[0]
+	}
+	count, err := parseTemplateNumber(value)
+	if err != nil {
+		return "", err
+	}
+	if count == 1 {
+		return "", nil
+	}
+	return "s", nil
+}
+
+func templateHumanBytes(r *templateRenderer, args []string) (string, error) {
+	if err := checkTemplateArgs(args, 1, 1, "humanBytes BYTES"); err != nil {
+		return "", err
+	}
+	n, err := parseTemplateNumber(args[0])
+	if err != nil {
+		return "", err
+	}
+	if math.Abs(n) < 1024 {
+		return fmt.Sprintf("%.0f B", n), nil
+	}
+	units := []string{"KB", "MB", "GB", "TB", "PB", "EB"}
+	unit := -1
+	for math.Abs(n) >= 1024 && unit < len(units)-1 {
+		n /= 1024
+		unit++
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(n, 'f', 1, 64), ".0") + " " + units[unit], nil
+}
+
+func templateHumanDuration(r *templateRenderer, args []string) (string, error) {
+	if err := checkTemplateArgs(args, 1, 1, "humanDuration SECONDS_OR_DURATION"); err != nil {
+		return "", err
+	}
+	var d time.Duration
+	if secs, err := strconv.ParseFloat(strings.TrimSpace(args[0]), 64); err == nil {
+		d = time.Duration(secs * float64(time.Second))
+	} else if d, err = parseTemplateDuration(strings.TrimSpace(args[0])); err != nil {
+		return "", err
+	}
+	return humanDuration(d), nil
+}
+
+// humanDuration formats d with its two largest units, as "2d 3h", "1h 30m" or "45s"
+func humanDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	d = d.Round(time.Second)
+	units := []struct {
+		size time.Duration
+		name string
+	}{{24 * time.Hour, "d"}, {time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"}}
+	for i, u := range units {
+		if d < u.size && i < len(units)-1 {
+			continue
+		}
+		parts := []string{fmt.Sprintf("%d%s", d/u.size, u.name)}
+		if i+1 < len(units) {
+			if n := d % u.size / units[i+1].size; n > 0 {
+				parts = append(parts, fmt.Sprintf("%d%s", n, units[i+1].name))
+			}
+		}
+		return sign + strings.Join(parts, " ")
+	}
+	return "0s"
+}
+
+func templateDate(r *templateRenderer, args []string) (string, error) {
+	if err := checkTemplateArgs(args, 0, 3, `date VALUE [+OFFSET] ["LAYOUT"]`); err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return r.now.In(r.loc).Format(defaultDateLayout), nil
+	}
+	value := args[0]
+	var offset time.Duration
+	if strings.HasPrefix(value, "now+") || strings.HasPrefix(value, "now-") {
+		d, err := parseTemplateDuration(strings.TrimPrefix(value, "now"))
+		if err != nil {
+			return "", err
+		}
+		value, offset = "now", d
+	}
+	t, err := parseTemplateTime(value, r.now)
+	if err != nil {
+		return "", err
+	}
+	layout := defaultLocalTimeLayout
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+			d, err := parseTemplateDuration(arg)
+			if err != nil {
+				return "", err
+			}
+			offset += d
+			continue
+		}
+		layout = arg
+	}
+	return t.Add(offset).In(r.loc).Format(layout), nil
+}
+
+func templateDefault(r *templateRenderer, args []string) (string, error) {
+	if err := checkTemplateArgs(args, 2, 2, `default VALUE "fallback"`); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(args[0]) == "" {
+		return args[1], nil
+	}
+	return args[0], nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
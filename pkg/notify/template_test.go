@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRenderTemplate tests the template variables, functions and conditionals
+func TestRenderTemplate(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	vars := map[string]string{
+		"count":     "3",
+		"one":       "1",
+		"size":      "1610612736",
+		"secs":      "5400",
+		"name":      "Web Farm",
+		"empty":     "",
+		"deadline":  "2025-03-04T17:00:00Z",
+		"team lead": "Ann",
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"{{count}} update{{s}} pending, ~{{humanBytes size}}", "3 updates pending, ~1.5 GB"},
+		{"{{one}} update{{s}}, {{count}} {{plural count \"box\" \"boxes\"}}", "1 update, 3 boxes"},
+		{"{{plural one \"file\"}} and {{plural count \"file\"}}", "file and files"},
+		{"{{upper name}} {{lower name}}", "WEB FARM web farm"},
+		{"{{humanDuration secs}}, {{humanDuration \"50h\"}}, {{humanDuration 45}}", "1h 30m, 2d 2h, 45s"},
+		{"{{humanBytes 512}} {{humanBytes 1024}}", "512 B 1 KB"},
+		{"{{date now+2d \"Mon 2 Jan\"}}", "Mon 3 Mar"},
+		{"{{date deadline -1d \"2 Jan 15:04\"}}", "3 Mar 17:00"},
+		{"{{date}}", "Sat 1 Mar 2025"},
+		{"{{default empty \"none\"}} {{default missing \"none\"}} {{default name \"none\"}}", "none none Web Farm"},
+		{"{{if count > 1}}many{{else}}few{{end}}", "many"},
+		{"{{if empty}}set{{else}}unset{{end}}{{if name == \"Web Farm\"}}!{{end}}", "unset!"},
+		{"{{if one}}{{if count != 3}}no{{else}}nested{{end}}{{end}}", "nested"},
+		{"Lead: {{team lead}}", "Lead: Ann"},
+		{"{{missing}} {{upper missing}} {{localtime:now|15:04}}", "{{missing}} {{upper missing}} {{localtime:now|15:04}}"},
+		{"{{if missing}}{{count}}{{end}}", "{{if missing}}3{{end}}"},
+		{"{{missing}} file{{s}}", "{{missing}} file{{s}}"},
+		{"No templates here", "No templates here"},
+	}
+	for _, tt := range tests {
+		got, err := RenderTemplate(tt.text, vars, time.UTC, now)
+		if err != nil {
+			t.Errorf("RenderTemplate(%q) failed: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("RenderTemplate(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestRenderTemplateErrors tests that bad templates are reported
+func TestRenderTemplateErrors(t *testing.T) {
+	vars := map[string]string{"name": "Web Farm", "count": "3"}
+	for _, text := range []string{
+		"{{if count}}unterminated",
+		"stray {{end}}",
+		"{{if count}}a{{else}}b{{else}}c{{end}}",
+		"{{humanBytes name}}",
+		"{{upper}}",
+		"{{if name > 2}}x{{end}}",
+		"{{if count is 3}}x{{end}}",
+		"{{date \"yesterday\"}}",
+	} {
+		if _, err := RenderTemplate(text, vars, time.UTC, time.Now()); err == nil {
+			t.Errorf("RenderTemplate(%q) accepted it", text)
+		}
+	}
+}
+
+// TestBuiltinVars tests the variables of the session showing a notification
+func TestBuiltinVars(t *testing.T) {
+	t.Setenv("KRANKYBEAR_TEST_SITE", "Berlin")
+	t.Setenv("NOTIFY_TEST_SECRET", "hunter2")
+	now := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	n := Notification{
+		Title:   "{{hostname}}",
+		Message: "{{username}} in {{env.KRANKYBEAR_TEST_SITE}} at {{time}} on {{date}}{{env.KRANKYBEAR_TEST_MISSING}}{{env.NOTIFY_TEST_SECRET}}",
+	}
+	got, err := n.WithTemplate(BuiltinVars(time.UTC, now), now)
+	if err != nil {
+		t.Fatalf("WithTemplate failed: %v", err)
+	}
+	vars := BuiltinVars(time.UTC, now)
+	hostname, _ := os.Hostname()
+	if got.Title != hostname || vars["username"] == "" {
+		t.Errorf("Unexpected hostname %q or username %q", got.Title, vars["username"])
+	}
+	if want := vars["username"] + " in Berlin at 12:30 on Sat 1 Mar 2025{{env.KRANKYBEAR_TEST_MISSING}}{{env.NOTIFY_TEST_SECRET}}"; got.Message != want {
+		t.Errorf("Message = %q, want %q", got.Message, want)
+	}
+
+	// A notification from another machine reads no environment variables at all
+	remote, err := Notification{Title: "{{env.KRANKYBEAR_TEST_SITE}}", RemoteSpec: true}.WithLocalTimes(now)
+	if err != nil || remote.Title != "{{env.KRANKYBEAR_TEST_SITE}}" {
+		t.Errorf("Remote title = %q, %v, want it unrendered", remote.Title, err)
+	}
+}
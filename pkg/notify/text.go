@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeText prepares untrusted text for display: invalid UTF-8 is replaced,
+// line endings become \n, control characters other than tab and newline are removed,
+// as are bidirectional overrides (which can make a message read differently than it
+// is written), and the result is in Unicode NFC so equal-looking text compares equal
+func NormalizeText(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r':
+			return '\n'
+		case unicode.IsControl(r), isBidiControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return norm.NFC.String(s)
+}
+
+// isBidiControl reports whether r is an explicit bidirectional embedding, override or isolate
+func isBidiControl(r rune) bool {
+	return (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069')
+}
+
+// Normalize returns n with NormalizeText applied to the title, message, button, input, choice and HTML texts
+func (n Notification) Normalize() Notification {
+	n.Title = NormalizeText(n.Title)
+	n.Message = NormalizeText(n.Message)
+	n.ButtonText = NormalizeText(n.ButtonText)
+	n.InputDefault = NormalizeText(n.InputDefault)
+	n.InputPlaceholder = NormalizeText(n.InputPlaceholder)
+	n.Choices = NormalizeText(n.Choices)
+	n.HTML = NormalizeText(n.HTML)
+	return n
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// TestNormalizeText tests NFC composition and the characters that are removed or replaced
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Reboot at 50% capacity + 2 nodes", "Reboot at 50% capacity + 2 nodes"},
+		{"decomposed", "Cafe\u0301", "Caf\u00e9"},
+		{"line endings", "one\r\ntwo\rthree\n\tfour", "one\ntwo\nthree\n\tfour"},
+		{"controls", "bell\a escape\x1b[31m nul\x00 c1\u0085", "bell escape[31m nul c1"},
+		{"bidi override", "invoice_\u202egpj.exe", "invoice_gpj.exe"},
+		{"invalid utf-8", "bad \xff byte", "bad \uFFFD byte"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeText(tt.in); got != tt.want {
+			t.Errorf("%s: NormalizeText(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+// FuzzNormalizeText tests that normalized text is valid NFC UTF-8 without control
+// characters, and that normalizing again changes nothing
+func FuzzNormalizeText(f *testing.F) {
+	for _, seed := range []string{"", "Hello%20World", "a+b", "Cafe\u0301", "x\r\ny", "\x00\x1b\u202e", "\xff\xfe", "\u0915\u094d\u0937"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got := NormalizeText(s)
+		if !utf8.ValidString(got) {
+			t.Fatalf("NormalizeText(%q) = %q is not valid UTF-8", s, got)
+		}
+		if !norm.NFC.IsNormalString(got) {
+			t.Fatalf("NormalizeText(%q) = %q is not NFC", s, got)
+		}
+		if i := strings.IndexFunc(got, func(r rune) bool {
+			return (unicode.IsControl(r) && r != '\n' && r != '\t') || isBidiControl(r)
+		}); i >= 0 {
+			t.Fatalf("NormalizeText(%q) = %q keeps a control character at %d", s, got, i)
+		}
+		if again := NormalizeText(got); again != got {
+			t.Fatalf("NormalizeText is not idempotent: %q -> %q -> %q", s, got, again)
+		}
+	})
+}
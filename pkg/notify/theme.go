@@ -0,0 +1,107 @@
+//go:generate fyne bundle -o bundled.go -a Resources/Images/KrankyBearBeret.png
+
+package notify
+
+import (
+	"image/color"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+type appTheme struct {
+	fyne.Theme
+}
+
+func (a *appTheme) Size(n fyne.ThemeSizeName) float32 {
+	if n == theme.SizeNameHeadingText {
+		return a.Theme.Size(n) * 1.5
+	}
+
+	return a.Theme.Size(n)
+}
+
+// notificationTheme is a Fyne theme with the -theme, colors and font of a notification
+type notificationTheme struct {
+	fyne.Theme
+	colors   map[fyne.ThemeColorName]color.Color
+	variant  *fyne.ThemeVariant // Set by -theme dark or light, nil to follow the desktop
+	font     fyne.Resource      // -font (or a fallback font for CJK text) for all but monospace text, nil for the theme's
+	fontSize float32            // -font-size, 0 for the theme's
+}
+
+// newNotificationTheme returns base with the theme, colors and font of n, and false when n sets none
+// Fyne follows the desktop's dark/light preference itself, so -theme auto keeps base's variant
+func newNotificationTheme(base fyne.Theme, n Notification) (fyne.Theme, bool) {
+	colors := map[fyne.ThemeColorName]color.Color{}
+	set := func(value string, names ...fyne.ThemeColorName) {
+		if c, err := parseHexColor(value); value != "" && err == nil {
+			for _, name := range names {
+				colors[name] = c
+			}
+		}
+	}
+	set(n.BackgroundColor, theme.ColorNameBackground)
+	set(n.ForegroundColor, theme.ColorNameForeground)
+	set(n.AccentColor, theme.ColorNamePrimary, theme.ColorNameHyperlink)
+
+	var variant *fyne.ThemeVariant
+	switch n.Theme {
+	case ThemeDark:
+		dark := theme.VariantDark
+		variant = &dark
+	case ThemeLight:
+		light := theme.VariantLight
+		variant = &light
+	}
+
+	var font fyne.Resource
+	if n.Font != "" {
+		var err error
+		if font, err = fyne.LoadResourceFromPath(n.Font); err != nil {
+			log.Printf("Warning: Could not load -font, using the default font: %v", err)
+			font = nil
+		}
+	} else {
+		font = fallbackFont(n.windowText())
+	}
+
+	if len(colors) == 0 && variant == nil && font == nil && n.FontSize == 0 {
+		return base, false
+	}
+	return &notificationTheme{Theme: base, colors: colors, variant: variant, font: font, fontSize: float32(n.FontSize)}, true
+}
+
+func (t *notificationTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if col, ok := t.colors[name]; ok {
+		return col
+	}
+	if t.variant != nil {
+		variant = *t.variant
+	}
+	return t.Theme.Color(name, variant)
+}
+
+func (t *notificationTheme) Font(style fyne.TextStyle) fyne.Resource {
+	if t.font != nil && !style.Monospace && !style.Symbol {
+		return t.font
+	}
+	return t.Theme.Font(style)
+}
+
+// Size scales the text sizes of the theme to -font-size, keeping headings as much larger
+func (t *notificationTheme) Size(name fyne.ThemeSizeName) float32 {
+	if t.fontSize == 0 {
+		return t.Theme.Size(name)
+	}
+	switch name {
+	case theme.SizeNameText:
+		return t.fontSize
+	case theme.SizeNameHeadingText:
+		return t.Theme.Size(name) * t.fontSize / t.Theme.Size(theme.SizeNameText)
+	}
+	return t.Theme.Size(name)
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
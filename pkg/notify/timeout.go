@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TimeoutAuto is the Notification.Timeout of -timeout auto: the time to read the notification
+// at ReadingSpeed, between TimeoutMin and TimeoutMax (see ReadingTimeout)
+const TimeoutAuto = -1
+
+// Defaults of -timeout auto
+const (
+	DefaultReadingSpeed = 200 // Words per minute, a comfortable pace for on-screen text
+	DefaultTimeoutMin   = 5   // Seconds
+	DefaultTimeoutMax   = 120 // Seconds
+)
+
+// readingGrace is added to the reading time of -timeout auto, for noticing the window and
+// reaching for the button
+const readingGrace = 3
+
+// timeoutValue is the -timeout flag: seconds, or "auto" for TimeoutAuto
+type timeoutValue int
+
+func (t *timeoutValue) String() string {
+	if t != nil && int(*t) == TimeoutAuto {
+		return "auto"
+	}
+	if t == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*t))
+}
+
+func (t *timeoutValue) Set(value string) error {
+	if strings.EqualFold(value, "auto") {
+		*t = TimeoutAuto
+		return nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return fmt.Errorf("use seconds (0 for no timeout) or auto")
+	}
+	*t = timeoutValue(seconds)
+	return nil
+}
+
+// ValidateReadingTimeout checks the -reading-speed, -timeout-min and -timeout-max flag values
+func ValidateReadingTimeout(speed, min, max int) error {
+	if speed < 0 || min < 0 || max < 0 {
+		return fmt.Errorf("-reading-speed, -timeout-min and -timeout-max must not be negative")
+	}
+	if max > 0 && min > max {
+		return fmt.Errorf("-timeout-min (%ds) is longer than -timeout-max (%ds)", min, max)
+	}
+	return nil
+}
+
+// readingWords counts the words of text for ReadingTimeout; each Chinese, Japanese or Korean
+// character counts as a word, as these scripts are written without spaces
+func readingWords(text string) int {
+	words := 0
+	for _, field := range strings.Fields(text) {
+		other := false
+		for _, r := range field {
+			if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+				words++
+			} else {
+				other = true
+			}
+		}
+		if other {
+			words++
+		}
+	}
+	return words
+}
+
+// ReadingTimeout returns the timeout of -timeout auto in seconds: the words of the title and
+// message (the text of HTML) read at ReadingSpeed words per minute, plus a few seconds,
+// kept between TimeoutMin and TimeoutMax; zero values use the defaults
+func (n Notification) ReadingTimeout() int {
+	speed, min, max := n.ReadingSpeed, n.TimeoutMin, n.TimeoutMax
+	if speed <= 0 {
+		speed = DefaultReadingSpeed
+	}
+	if min <= 0 {
+		min = DefaultTimeoutMin
+	}
+	if max <= 0 {
+		max = DefaultTimeoutMax
+	}
+
+	message := n.Message
+	if n.HTML != "" {
+		message = HTMLText(n.HTML)
+	}
+	words := readingWords(n.Title) + readingWords(message)
+	seconds := int(math.Ceil(float64(words)*60/float64(speed))) + readingGrace
+	if seconds < min {
+		seconds = min
+	}
+	if seconds > max {
+		seconds = max
+	}
+	return seconds
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
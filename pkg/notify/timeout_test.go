@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestReadingTimeout tests that -timeout auto grows with the text, within its bounds
+func TestReadingTimeout(t *testing.T) {
+	words := func(n int) string {
+		return strings.TrimSpace(strings.Repeat("word ", n))
+	}
+	tests := []struct {
+		name string
+		n    Notification
+		want int
+	}{
+		{"short", Notification{Title: "Saved", Message: "Done"}, DefaultTimeoutMin},
+		{"paragraph", Notification{Title: "Maintenance", Message: words(99)}, 30 + readingGrace},
+		{"policy", Notification{Title: "Policy", Message: words(2000)}, DefaultTimeoutMax},
+		{"slow reader", Notification{Message: words(100), ReadingSpeed: 100}, 60 + readingGrace},
+		{"bounds", Notification{Message: words(2000), TimeoutMin: 10, TimeoutMax: 300}, 300},
+		{"html", Notification{Message: "x", HTML: "<p>" + words(200) + "</p>"}, 60 + readingGrace},
+		{"cjk", Notification{Message: strings.Repeat("読", 200)}, 60 + readingGrace},
+	}
+	for _, tt := range tests {
+		if got := tt.n.ReadingTimeout(); got != tt.want {
+			t.Errorf("%s: ReadingTimeout() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestTimeoutFlag tests -timeout seconds and auto
+func TestTimeoutFlag(t *testing.T) {
+	tests := map[string]int{"30": 30, "0": 0, "auto": TimeoutAuto, "AUTO": TimeoutAuto}
+	for value, want := range tests {
+		var n Notification
+		fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+		BindFlags(fs, &n)
+		if err := fs.Parse([]string{"-timeout", value}); err != nil || n.Timeout != want {
+			t.Errorf("-timeout %s = %d, %v, want %d", value, n.Timeout, err, want)
+		}
+	}
+	for _, value := range []string{"soon", "-5"} {
+		var n Notification
+		fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		BindFlags(fs, &n)
+		if err := fs.Parse([]string{"-timeout", value}); err == nil {
+			t.Errorf("-timeout %s should fail", value)
+		}
+	}
+	if err := ValidateReadingTimeout(200, 60, 30); err == nil {
+		t.Error("ValidateReadingTimeout should refuse a minimum above the maximum")
+	}
+}
@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	// Embedded zone database so -tz works on Windows and minimal Linux images without tzdata
+	_ "time/tzdata"
+)
+
+// defaultLocalTimeLayout is the Go time layout used when a {{localtime:...}} template has none
+const defaultLocalTimeLayout = "Mon 2 Jan 15:04 MST"
+
+// defaultDateLayout is the Go time layout of {{date}}
+const defaultDateLayout = "Mon 2 Jan 2006"
+
+// localTimePattern matches {{localtime}}, {{localtime:VALUE}} and {{localtime:VALUE|LAYOUT}}
+var localTimePattern = regexp.MustCompile(`\{\{\s*localtime(?::([^}|]*))?(?:\|([^}]*))?\s*\}\}`)
+
+// zonedTimeLayouts are accepted for "<date time> <IANA zone>" template values
+var zonedTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// LoadTimeZone resolves a -tz value ("Europe/Berlin", "UTC", "Local")
+func LoadTimeZone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q (use an IANA name like Europe/Berlin)", name)
+	}
+	return loc, nil
+}
+
+// displayLocation returns the zone times are shown in: -tz when given, otherwise this
+// session's local zone (per-user TZ is passed to children by the fan-out)
+func (n Notification) displayLocation() (*time.Location, error) {
+	if n.TimeZone == "" {
+		return time.Local, nil
+	}
+	return LoadTimeZone(n.TimeZone)
+}
+
+// WithLocalTimes returns n with {{localtime:...}} templates, the template functions
+// (RenderTemplate) and the variables of this session (BuiltinVars) in the title and message
+// rendered in the display zone; a RemoteSpec gets no env.NAME variables
+func (n Notification) WithLocalTimes(now time.Time) (Notification, error) {
+	loc, err := n.displayLocation()
+	if err != nil {
+		return n, err
+	}
+	vars := BuiltinVars(loc, now)
+	if n.RemoteSpec {
+		for name := range vars {
+			if strings.HasPrefix(name, "env.") {
+				delete(vars, name)
+			}
+		}
+	}
+	if n, err = n.WithTemplate(vars, now); err != nil {
+		return n, err
+	}
+	if n.Title, err = renderLocalTimes(n.Title, loc, now); err != nil {
+		return n, err
+	}
+	if n.Message, err = renderLocalTimes(n.Message, loc, now); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// renderLocalTimes replaces every {{localtime:...}} template in text with the time
+// converted to loc
+// VALUE is "now" (the default), "now+DURATION"/"now-DURATION" (days as "2d" too), an RFC 3339 timestamp,
+// or "<date time> <IANA zone>" such as "2025-03-01 18:00 Europe/Berlin"
+func renderLocalTimes(text string, loc *time.Location, now time.Time) (string, error) {
+	var renderErr error
+	rendered := localTimePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := localTimePattern.FindStringSubmatch(match)
+		t, err := parseTemplateTime(strings.TrimSpace(parts[1]), now)
+		if err != nil {
+			if renderErr == nil {
+				renderErr = fmt.Errorf("invalid template %s: %v", match, err)
+			}
+			return match
+		}
+		layout := strings.TrimSpace(parts[2])
+		if layout == "" {
+			layout = defaultLocalTimeLayout
+		}
+		return t.In(loc).Format(layout)
+	})
+	return rendered, renderErr
+}
+
+// parseTemplateTime parses the VALUE of a {{localtime:VALUE}} template
+func parseTemplateTime(value string, now time.Time) (time.Time, error) {
+	if value == "" || value == "now" {
+		return now, nil
+	}
+	if strings.HasPrefix(value, "now+") || strings.HasPrefix(value, "now-") {
+		offset, err := parseTemplateDuration(strings.TrimPrefix(value, "now"))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(offset), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	// "<date time> <zone>": the zone is the last space-separated field
+	if i := strings.LastIndex(value, " "); i > 0 {
+		loc, err := LoadTimeZone(value[i+1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		for _, layout := range zonedTimeLayouts {
+			if t, err := time.ParseInLocation(layout, value[:i], loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected now, now+DURATION, an RFC 3339 time or \"YYYY-MM-DD HH:MM Zone/Name\"")
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRenderLocalTimes tests rendering {{localtime:...}} templates in a target zone
+func TestRenderLocalTimes(t *testing.T) {
+	berlin, err := LoadTimeZone("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadTimeZone failed: %v", err)
+	}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Reboot at {{localtime:2025-03-01T17:00:00Z|15:04}}", "Reboot at 18:00"},
+		{"{{localtime:2025-03-01 18:00 America/New_York|15:04 MST}}", "00:00 CET"},
+		{"Now {{localtime|15:04}}, later {{ localtime:now+2h30m | 15:04 }}", "Now 13:00, later 15:30"},
+		{"{{localtime:2025-07-01T10:00:00Z}}", "Tue 1 Jul 12:00 CEST"},
+		{"No templates here", "No templates here"},
+	}
+	for _, tt := range tests {
+		got, err := renderLocalTimes(tt.text, berlin, now)
+		if err != nil {
+			t.Errorf("renderLocalTimes(%q) failed: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("renderLocalTimes(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestRenderLocalTimesErrors tests that bad template values are reported
+func TestRenderLocalTimesErrors(t *testing.T) {
+	for _, text := range []string{
+		"{{localtime:tomorrow}}",
+		"{{localtime:now+soon}}",
+		"{{localtime:2025-03-01 18:00 Mars/Olympus}}",
+	} {
+		if _, err := renderLocalTimes(text, time.UTC, time.Now()); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
+
+// TestWithLocalTimesTimeZone tests that -tz selects the display zone
+func TestWithLocalTimesTimeZone(t *testing.T) {
+	n := Notification{Title: "{{localtime:2025-01-15T08:00:00Z|15:04}}", TimeZone: "Asia/Tokyo"}
+	got, err := n.WithLocalTimes(time.Now())
+	if err != nil {
+		t.Fatalf("WithLocalTimes failed: %v", err)
+	}
+	if got.Title != "17:00" {
+		t.Errorf("Expected 17:00 in Tokyo, got %q", got.Title)
+	}
+
+	n.TimeZone = "Nowhere/Special"
+	if _, err := n.WithLocalTimes(time.Now()); err == nil {
+		t.Error("Expected an error for an unknown -tz")
+	}
+}
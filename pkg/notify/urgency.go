@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// Urgency levels for Notification.Urgency, each with its own icon, accent color and default timeout
+const (
+	UrgencyInfo     = "info"     // Blue, closes after DefaultTimeout
+	UrgencyWarning  = "warning"  // Amber, closes after UrgencyWarningTimeout
+	UrgencyCritical = "critical" // Red, always on top and stays until acknowledged
+)
+
+// UrgencyWarningTimeout is the default timeout of UrgencyWarning notifications, in seconds
+const UrgencyWarningTimeout = 30
+
+// ValidateUrgency checks the -urgency flag value
+func ValidateUrgency(urgency string) error {
+	switch urgency {
+	case "", UrgencyInfo, UrgencyWarning, UrgencyCritical:
+		return nil
+	}
+	return fmt.Errorf("invalid urgency %q (use %s, %s or %s)", urgency, UrgencyInfo, UrgencyWarning, UrgencyCritical)
+}
+
+// UrgencyTimeout returns the timeout of an urgency when none is given: DefaultTimeout for info
+// (and no urgency), UrgencyWarningTimeout for warning, and 0 (no timeout) for critical
+func UrgencyTimeout(urgency string) int {
+	switch urgency {
+	case UrgencyWarning:
+		return UrgencyWarningTimeout
+	case UrgencyCritical:
+		return 0
+	}
+	return DefaultTimeout
+}
+
+// urgencyAccent returns the accent color of an urgency, and false without one
+func urgencyAccent(urgency string) (color.NRGBA, bool) {
+	switch urgency {
+	case UrgencyInfo:
+		return color.NRGBA{R: 0x1e, G: 0x88, B: 0xe5, A: 0xff}, true
+	case UrgencyWarning:
+		return color.NRGBA{R: 0xff, G: 0xa0, B: 0x00, A: 0xff}, true
+	case UrgencyCritical:
+		return color.NRGBA{R: 0xd3, G: 0x2f, B: 0x2f, A: 0xff}, true
+	}
+	return color.NRGBA{}, false
+}
+
+// urgencyEmoji is the WebView icon of an urgency, shown when there is no -icon
+func urgencyEmoji(urgency string) string {
+	switch urgency {
+	case UrgencyInfo:
+		return "ℹ️"
+	case UrgencyWarning:
+		return "⚠️"
+	case UrgencyCritical:
+		return "🚨"
+	}
+	return "📢"
+}
+
+// libnotifyUrgency is the notify-send -u value of n, empty for the default (normal)
+// Critical (break glass and UrgencyCritical) is shown in do-not-disturb and stays until dismissed;
+// GNOME lists low urgency notifications without a banner
+func (n Notification) libnotifyUrgency() string {
+	if n.Priority == PriorityBreakGlass {
+		return "critical"
+	}
+	switch n.Urgency {
+	case UrgencyInfo:
+		return "low"
+	case UrgencyWarning:
+		return "normal"
+	case UrgencyCritical:
+		return "critical"
+	}
+	return ""
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
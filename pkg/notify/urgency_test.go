@@ -0,0 +1,51 @@
+package notify
+
+import "testing"
+
+// TestValidateUrgency tests the -urgency values
+func TestValidateUrgency(t *testing.T) {
+	for _, urgency := range []string{"", UrgencyInfo, UrgencyWarning, UrgencyCritical} {
+		if err := ValidateUrgency(urgency); err != nil {
+			t.Errorf("ValidateUrgency(%q) = %v", urgency, err)
+		}
+	}
+	for _, urgency := range []string{"high", "Critical", "low"} {
+		if err := ValidateUrgency(urgency); err == nil {
+			t.Errorf("ValidateUrgency(%q) should fail", urgency)
+		}
+	}
+}
+
+// TestUrgencyTimeout tests the default timeouts of the urgencies
+func TestUrgencyTimeout(t *testing.T) {
+	tests := map[string]int{
+		"":              DefaultTimeout,
+		UrgencyInfo:     DefaultTimeout,
+		UrgencyWarning:  UrgencyWarningTimeout,
+		UrgencyCritical: 0,
+	}
+	for urgency, want := range tests {
+		if got := UrgencyTimeout(urgency); got != want {
+			t.Errorf("UrgencyTimeout(%q) = %d, want %d", urgency, got, want)
+		}
+	}
+}
+
+// TestLibnotifyUrgency tests the notify-send urgency of -urgency and break glass
+func TestLibnotifyUrgency(t *testing.T) {
+	tests := []struct {
+		n    Notification
+		want string
+	}{
+		{Notification{}, ""},
+		{Notification{Urgency: UrgencyInfo}, "low"},
+		{Notification{Urgency: UrgencyWarning}, "normal"},
+		{Notification{Urgency: UrgencyCritical}, "critical"},
+		{Notification{Urgency: UrgencyInfo, Priority: PriorityBreakGlass}, "critical"},
+	}
+	for _, tt := range tests {
+		if got := tt.n.libnotifyUrgency(); got != tt.want {
+			t.Errorf("libnotifyUrgency(%+v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
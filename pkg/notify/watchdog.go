@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// fyneStartTimeout is how long the Windows watchdog waits for Fyne to start showing a window
+const fyneStartTimeout = 30 * time.Second
+
+// fyneCloseGrace is how long a window with a timeout may stay open after it before the Windows
+// watchdog closes it
+const fyneCloseGrace = 15 * time.Second
+
+// startWatchdog calls hang when started is not closed within startWithin, or when the window
+// is still open closeWithin after it started (0 for no limit); close the returned channel when
+// the window has closed
+func startWatchdog(started <-chan struct{}, startWithin, closeWithin time.Duration, hang func(reason string)) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		startTimer := time.NewTimer(startWithin)
+		defer startTimer.Stop()
+		select {
+		case <-done:
+			return
+		case <-startTimer.C:
+			hang(fmt.Sprintf("not started after %v", startWithin))
+			return
+		case <-started:
+		}
+		if closeWithin <= 0 {
+			return
+		}
+		closeTimer := time.NewTimer(closeWithin)
+		defer closeTimer.Stop()
+		select {
+		case <-done:
+		case <-closeTimer.C:
+			hang(fmt.Sprintf("still open %v after it started", closeWithin))
+		}
+	}()
+	return done
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
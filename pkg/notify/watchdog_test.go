@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+// TestStartWatchdog tests that the watchdog fires for a window that never started or outstays
+// its timeout, but not for a window waiting to be acknowledged once it started
+func TestStartWatchdog(t *testing.T) {
+	watch := func(started bool, closeWithin time.Duration) chan string {
+		start := make(chan struct{})
+		if started {
+			close(start)
+		}
+		hangs := make(chan string, 1)
+		done := startWatchdog(start, 20*time.Millisecond, closeWithin, func(reason string) { hangs <- reason })
+		t.Cleanup(func() { close(done) })
+		return hangs
+	}
+
+	notStarted, outstayed, waiting := watch(false, 0), watch(true, 30*time.Millisecond), watch(true, 0)
+	for name, hangs := range map[string]chan string{"not started": notStarted, "outstayed": outstayed} {
+		select {
+		case <-hangs:
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: expected the watchdog to fire", name)
+		}
+	}
+	select {
+	case reason := <-waiting:
+		t.Errorf("Expected a started window without a timeout to be left open, got %q", reason)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	done := startWatchdog(make(chan struct{}), 20*time.Millisecond, 0, func(string) { t.Error("Expected no hang after the window closed") })
+	close(done)
+	time.Sleep(50 * time.Millisecond)
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// webView2ClientKey is the EdgeUpdate client ID of the Evergreen WebView2 Runtime
+const webView2ClientKey = `\Microsoft\EdgeUpdate\Clients\{F3017226-FE2A-4295-8BDF-00C3A9A7E4C5}`
+
+// DetectWebViewRuntime checks whether the platform web engine used by the webview
+// backend is installed, independently of whether webview support is compiled in
+// Returns whether it was found and a short description for -check-webview
+func DetectWebViewRuntime() (bool, string) {
+	switch runtime.GOOS {
+	case "windows":
+		// Machine-wide installs register under WOW6432Node, per-user installs under HKCU
+		for _, key := range []string{
+			`HKLM\SOFTWARE\WOW6432Node` + webView2ClientKey,
+			`HKLM\SOFTWARE` + webView2ClientKey,
+			`HKCU\Software` + webView2ClientKey,
+		} {
+			output, err := exec.Command("reg", "query", key, "/v", "pv").Output()
+			if err != nil {
+				continue
+			}
+			for _, line := range strings.Split(string(output), "\n") {
+				// Format: "    pv    REG_SZ    120.0.2210.91"
+				fields := strings.Fields(line)
+				if len(fields) == 3 && fields[0] == "pv" && fields[2] != "0.0.0.0" {
+					return true, "WebView2 Runtime " + fields[2]
+				}
+			}
+		}
+		return false, "WebView2 Runtime not installed (https://developer.microsoft.com/microsoft-edge/webview2/)"
+	case "darwin":
+		// WebKit ships with macOS
+		return true, "WebKit (system framework)"
+	case "linux":
+		if output, err := exec.Command("ldconfig", "-p").Output(); err == nil {
+			for _, lib := range []string{"libwebkit2gtk-4.1.so", "libwebkit2gtk-4.0.so"} {
+				if strings.Contains(string(output), lib) {
+					return true, strings.TrimSuffix(lib, ".so")
+				}
+			}
+		}
+		// ldconfig may not be in PATH for non-root users, check the usual library directories
+		for _, dir := range []string{"/usr/lib/x86_64-linux-gnu", "/usr/lib/aarch64-linux-gnu", "/usr/lib64", "/usr/lib"} {
+			for _, lib := range []string{"libwebkit2gtk-4.1.so.0", "libwebkit2gtk-4.0.so.37"} {
+				if _, err := os.Stat(dir + "/" + lib); err == nil {
+					return true, dir + "/" + lib
+				}
+			}
+		}
+		return false, "webkit2gtk not installed (sudo apt install libwebkit2gtk-4.1-0)"
+	}
+	return false, fmt.Sprintf("no webview runtime known for %s", runtime.GOOS)
+}
+
+// ReportWebView prints whether WebView mode can be used and returns the answer
+// Both WebView support (a webview build, or WebView2 loaded at run time on Windows) and the
+// platform runtime are required
+func ReportWebView() bool {
+	available, detail := DetectWebViewRuntime()
+	fmt.Printf("WebView support: %s\n", webViewSupport)
+	if available {
+		fmt.Printf("WebView runtime: %s\n", detail)
+	} else {
+		fmt.Printf("WebView runtime: missing - %s\n", detail)
+	}
+
+	if WebViewCompiledIn && available {
+		fmt.Println("WebView mode is available")
+		return true
+	}
+	fmt.Println("WebView mode is not available")
+	return false
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// printFallbackPlan prints the -plan report: the mechanisms notify would try for opts on the
+// -os and -session target, without detecting anything on this machine
+// It returns the exit code: 1 when the notification could not be delivered on the target
+func printFallbackPlan(opts notify.Options, targetOS, session string) int {
+	if targetOS == "" {
+		targetOS = runtime.GOOS
+	}
+	if session == "" {
+		session = notify.SessionDesktop
+	}
+	target, err := notify.ValidatePlanTarget(notify.PlanTarget{OS: targetOS, Session: session, WebView: notify.WebViewCompiledIn || targetOS == "windows"})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	steps, planErr := notify.DeliveryPlan(opts, target)
+
+	fmt.Printf("=== Delivery Plan: %s, %s session ===\n", target.OS, target.Session)
+	if opts.DeliverBy.IsZero() {
+		fmt.Printf("Mode: %s\n", opts.Mode)
+	} else {
+		fmt.Println("Mode: auto (-deliver-by ignores mode flags)")
+	}
+	if target.WebView {
+		fmt.Println("WebView: built in (-tags webview, or WebView2 on Windows), assuming the runtime is installed")
+	} else {
+		fmt.Println("WebView: not built in")
+	}
+	inUser := false
+	for _, step := range steps {
+		indent := "  "
+		if step.InUser {
+			if !inUser {
+				fmt.Println("  In each user's session:")
+				inUser = true
+			}
+			indent = "    "
+		}
+		if step.Fallback {
+			fmt.Printf("%selse %-17s %s\n", indent, step.Method, step.Detail)
+		} else {
+			fmt.Printf("%s%-22s %s\n", indent, step.Method, step.Detail)
+		}
+		for _, downgrade := range opts.Notification.Downgrades(step.Method) {
+			fmt.Printf("%s%-22s   %s\n", indent, "", downgrade)
+		}
+	}
+	if !opts.DeliverBy.IsZero() {
+		fmt.Println("Deliver by: the first attempt is a toast, later ones follow this plan until acknowledged (see -dry-run)")
+	}
+	if planErr != nil {
+		fmt.Printf("Would fail: %v\n", planErr)
+		return 1
+	}
+	return 0
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// ContentPolicy limits what upstream systems can push into a notification, from the
+// policy section of a config file; zero values and an empty domain list mean no limit
+type ContentPolicy struct {
+	MaxTitleLength    int      `yaml:"max_title_length"`    // Characters
+	MaxMessageLength  int      `yaml:"max_message_length"`  // Characters
+	MaxIconBytes      int64    `yaml:"max_icon_bytes"`      // Size of the -icon file or -icon-data
+	AllowedURLDomains []string `yaml:"allowed_url_domains"` // Domains (and their subdomains) links may point to
+
+	// Categories delivered even to users who opted out of them (see notify optout)
+	MandatoryCategories []string `yaml:"mandatory_categories"`
+}
+
+// urlPattern finds links in notification text
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"']+`)
+
+// check returns every way n breaks the policy as one error, or nil
+// source names the config file the policy came from
+func (p ContentPolicy) check(n notify.Notification, source string) error {
+	var problems []string
+	if p.MaxTitleLength > 0 {
+		if length := utf8.RuneCountInString(n.Title); length > p.MaxTitleLength {
+			problems = append(problems, fmt.Sprintf("title is %d characters, the limit is %d", length, p.MaxTitleLength))
+		}
+	}
+	if p.MaxMessageLength > 0 {
+		if length := utf8.RuneCountInString(n.Message); length > p.MaxMessageLength {
+			problems = append(problems, fmt.Sprintf("message is %d characters, the limit is %d", length, p.MaxMessageLength))
+		}
+	}
+	if p.MaxIconBytes > 0 && n.IconPath != "" {
+		if info, err := os.Stat(n.IconPath); err == nil && info.Size() > p.MaxIconBytes {
+			problems = append(problems, fmt.Sprintf("icon %s is %d bytes, the limit is %d", n.IconPath, info.Size(), p.MaxIconBytes))
+		}
+	}
+	if p.MaxIconBytes > 0 && n.IconData != "" {
+		if data, err := notify.DecodeIconData(n.IconData); err == nil && int64(len(data)) > p.MaxIconBytes {
+			problems = append(problems, fmt.Sprintf("-icon-data is %d bytes, the limit is %d", len(data), p.MaxIconBytes))
+		}
+	}
+	if len(p.AllowedURLDomains) > 0 {
+		// Links in -html attributes count too, with their entities decoded as the browser would
+		text := n.Title + "\n" + n.Message + "\n" + n.Link + "\n" + html.UnescapeString(n.HTML)
+		if notify.IsIconURL(n.IconPath) {
+			text += "\n" + n.IconPath // Icons are downloaded from the allowed domains only
+		}
+		for _, link := range urlPattern.FindAllString(text, -1) {
+			link = strings.TrimRight(link, ".,;:!?)")
+			if !urlAllowed(link, p.AllowedURLDomains) {
+				problems = append(problems, fmt.Sprintf("link %s is not in the allowed domains (%s)", link, strings.Join(p.AllowedURLDomains, ", ")))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("notification rejected by the content policy in %s:\n  %s", source, strings.Join(problems, "\n  "))
+}
+
+// urlAllowed reports whether link points to one of domains or a subdomain of one
+func urlAllowed(link string, domains []string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestContentPolicy tests the length, icon size and link domain limits
+func TestContentPolicy(t *testing.T) {
+	icon := filepath.Join(t.TempDir(), "icon.png")
+	os.WriteFile(icon, make([]byte, 2048), 0644)
+
+	policy := ContentPolicy{
+		MaxTitleLength:    10,
+		MaxMessageLength:  80,
+		MaxIconBytes:      1024,
+		AllowedURLDomains: []string{"example.com"},
+	}
+	tests := []struct {
+		name string
+		n    notify.Notification
+		want string
+	}{
+		{"long title", notify.Notification{Title: "Überprüfung", Message: "See https://intranet.example.com/kb/42."}, "title is 11"},
+		{"within limits", notify.Notification{Title: "Patch", Message: "See https://example.com/patch, then reboot"}, ""},
+		{"long message", notify.Notification{Title: "Patch", Message: strings.Repeat("x", 81)}, "message is 81 characters"},
+		{"big icon", notify.Notification{Title: "Patch", Message: "Reboot", IconPath: icon}, "icon " + icon + " is 2048 bytes"},
+		{"other domain", notify.Notification{Title: "Patch", Message: "Get it at http://example.com.evil.net/x"}, "link http://example.com.evil.net/x is not in the allowed domains"},
+		{"html link", notify.Notification{Title: "Patch", Message: "Get it", HTML: `<a href="https://evil.net/x">here</a>`}, "link https://evil.net/x is not in the allowed domains"},
+		{"link flag", notify.Notification{Title: "Patch", Message: "Get it", Link: "https://evil.net/x"}, "link https://evil.net/x is not in the allowed domains"},
+		{"big icon data", notify.Notification{Title: "Patch", Message: "Reboot", IconData: base64.StdEncoding.EncodeToString(make([]byte, 2048))}, "-icon-data is 2048 bytes"},
+		{"icon url", notify.Notification{Title: "Patch", Message: "Get it", IconPath: "https://evil.net/logo.png"}, "link https://evil.net/logo.png is not in the allowed domains"},
+		{"html entities", notify.Notification{Title: "Patch", Message: "Get it", HTML: `<a href="https&#58;//evil.net/x">here</a>`}, "link https://evil.net/x is not in the allowed domains"},
+	}
+	for _, tt := range tests {
+		err := policy.check(tt.n, "test.yaml")
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	if err := (ContentPolicy{}).check(notify.Notification{Title: strings.Repeat("x", 100000)}, "test.yaml"); err != nil {
+		t.Errorf("Expected an empty policy to allow everything, got %v", err)
+	}
+}
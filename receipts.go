@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serveStatusInterrupted is the status of a receipt whose notification was being shown when
+// notify serve stopped (crash, reboot): it may or may not have been seen, and is not shown again
+const serveStatusInterrupted = "interrupted"
+
+// receiptRetention is how long notify serve keeps delivery receipts; a notification ID older
+// than this is unknown again, so central servers must not resend IDs that old
+const receiptRetention = 400 * 24 * time.Hour
+
+// maxReconcileIDs bounds the IDs of one POST /v1/receipts
+const maxReconcileIDs = 10000
+
+// notificationIDPattern matches a notification ID chosen by a client (PUT /v1/notifications/{id})
+var notificationIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,127}$`)
+
+// deliveryReceipt records that notify serve received a notification, and what became of it,
+// so the same notification ID is never shown twice on this machine
+type deliveryReceipt struct {
+	ID       string              `json:"id"`
+	Status   string              `json:"status"` // serveStatusPending, Done, Failed or Interrupted
+	Result   *NotificationResult `json:"result,omitempty"`
+	Error    string              `json:"error,omitempty"`
+	Received time.Time           `json:"received"`
+	Updated  time.Time           `json:"updated"`
+}
+
+// receiptStore is the delivery receipts of notify serve, persisted in a JSON file after every
+// change so they survive crashes and restarts
+type receiptStore struct {
+	path string
+
+	mu       sync.Mutex
+	receipts map[string]deliveryReceipt
+}
+
+// defaultReceiptsPath returns the receipts file of notify serve for the current user
+func defaultReceiptsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "receipts.json"), nil
+}
+
+// openReceiptStore loads the receipts at path; notifications still pending were interrupted
+// by the previous notify serve stopping
+func openReceiptStore(path string) (*receiptStore, error) {
+	rs := &receiptStore{path: path, receipts: map[string]deliveryReceipt{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read receipts: %v", err)
+	}
+	if err := json.Unmarshal(data, &rs.receipts); err != nil {
+		return nil, fmt.Errorf("could not parse receipts %s: %v", path, err)
+	}
+	for id, receipt := range rs.receipts {
+		if receipt.Status == serveStatusPending {
+			receipt.Status = serveStatusInterrupted
+			rs.receipts[id] = receipt
+		}
+	}
+	return rs, nil
+}
+
+// claim records the receipt of notification id before it is shown, and reports whether it
+// may be shown: false when it was received before (a failed notification may be retried)
+// The receipt is on disk before claim returns, so a crash cannot lead to showing it twice
+func (rs *receiptStore) claim(id string, now time.Time) (existing deliveryReceipt, claimed bool, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if existing, ok := rs.receipts[id]; ok && existing.Status != serveStatusFailed {
+		return existing, false, nil
+	}
+	rs.receipts[id] = deliveryReceipt{ID: id, Status: serveStatusPending, Received: now, Updated: now}
+	if err := rs.save(now); err != nil {
+		delete(rs.receipts, id)
+		return deliveryReceipt{}, false, err
+	}
+	return deliveryReceipt{}, true, nil
+}
+
+// finish records what became of a claimed notification
+func (rs *receiptStore) finish(record serveRecord, now time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	receipt, ok := rs.receipts[record.ID]
+	if !ok {
+		receipt = deliveryReceipt{ID: record.ID, Received: now}
+	}
+	receipt.Status, receipt.Result, receipt.Error, receipt.Updated = record.Status, record.Result, record.Error, now
+	rs.receipts[record.ID] = receipt
+	return rs.save(now)
+}
+
+// get returns the receipt of notification id
+func (rs *receiptStore) get(id string) (deliveryReceipt, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	receipt, ok := rs.receipts[id]
+	return receipt, ok
+}
+
+// reconcile returns the receipts of ids, and the ids this machine has no receipt of (never
+// received, or forgotten after receiptRetention or a re-imaging), which a server may resend
+func (rs *receiptStore) reconcile(ids []string) (found []deliveryReceipt, unknown []string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	found, unknown = []deliveryReceipt{}, []string{}
+	for _, id := range ids {
+		if receipt, ok := rs.receipts[id]; ok {
+			found = append(found, receipt)
+		} else {
+			unknown = append(unknown, id)
+		}
+	}
+	return found, unknown
+}
+
+// since returns the receipts updated at or after t, oldest first
+func (rs *receiptStore) since(t time.Time) []deliveryReceipt {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	receipts := []deliveryReceipt{}
+	for _, receipt := range rs.receipts {
+		if !receipt.Updated.Before(t) {
+			receipts = append(receipts, receipt)
+		}
+	}
+	sort.Slice(receipts, func(i, j int) bool { return receipts[i].Updated.Before(receipts[j].Updated) })
+	return receipts
+}
+
+// save writes the receipts, forgetting those older than receiptRetention; the file is
+// replaced atomically, so a crash leaves the previous version rather than half a file
+func (rs *receiptStore) save(now time.Time) error {
+	for id, receipt := range rs.receipts {
+		if now.Sub(receipt.Updated) > receiptRetention {
+			delete(rs.receipts, id)
+		}
+	}
+	data, err := json.MarshalIndent(rs.receipts, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(rs.path), 0700); err != nil {
+		return fmt.Errorf("could not create receipts directory: %v", err)
+	}
+	tmp := rs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("could not write receipts: %v", err)
+	}
+	if err := os.Rename(tmp, rs.path); err != nil {
+		return fmt.Errorf("could not write receipts: %v", err)
+	}
+	return nil
+}
+
+// handleReceipts serves the reconciliation handshake: GET /v1/receipts?since=TIME lists the
+// receipts updated since then (all without since), POST /v1/receipts {"ids": [...]} returns
+// the receipts of those IDs and the unknown ones
+func (s *notifyServer) handleReceipts(w http.ResponseWriter, r *http.Request) {
+	if s.receipts == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "delivery receipts are not enabled")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var since time.Time
+		if value := r.URL.Query().Get("since"); value != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, value); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid since %q (use RFC 3339, e.g. 2025-03-01T00:00:00Z)", value))
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"receipts": s.receipts.since(since)})
+	case http.MethodPost:
+		data, ok := readRequestBody(w, r)
+		if !ok {
+			return
+		}
+		var request struct {
+			IDs []string `json:"ids"`
+		}
+		if err := json.Unmarshal(data, &request); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+		if len(request.IDs) > maxReconcileIDs {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("at most %d IDs per request", maxReconcileIDs))
+			return
+		}
+		found, unknown := s.receipts.reconcile(request.IDs)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"receipts": found, "unknown": unknown})
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPost}, ", "))
+		writeJSONError(w, http.StatusMethodNotAllowed, "use GET or POST")
+	}
+}
+
+// validateNotificationID checks a notification ID chosen by a client
+func validateNotificationID(id string) error {
+	if !notificationIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid notification ID %q (use up to 128 letters, digits, '.', ':', '-' and '_')", id)
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
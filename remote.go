@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// remoteCopyDir is where -copy puts the notify binary on a host, relative to the SSH user's home
+const remoteCopyDir = ".cache/krankybearnotify"
+
+// remoteHostResult is what happened on one host of notify remote, a line of its -result-json output
+type remoteHostResult struct {
+	Host   string              `json:"host"`
+	Result *NotificationResult `json:"result,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// sshFunc runs "ssh host command" with stdin and returns its output and the exit code of the
+// remote command (255 when ssh failed); err is set only when ssh could not be run at all
+type sshFunc func(ctx context.Context, host, command string, stdin io.Reader) (stdout, stderr []byte, code int, err error)
+
+// remoteNotifier shows a notification on hosts over SSH with the notify on each host, or a copy
+// of this one
+type remoteNotifier struct {
+	ssh        sshFunc
+	remotePath string // notify on the hosts, as found on the remote PATH or a path
+	copyBinary bool   // Copy this executable to hosts that have no remotePath
+	exePath    string // This executable, for copyBinary
+}
+
+// runRemote handles "notify remote -hosts host1,host2 [OPTIONS] -- notify flags": shows the
+// notification on every host over SSH and reports what happened on each
+func runRemote(args []string) int {
+	fs := flag.NewFlagSet("remote", flag.ExitOnError)
+	hostList := fs.String("hosts", "", "Comma-separated hosts ([user@]host, or a Host of ~/.ssh/config)")
+	hostsFile := fs.String("hosts-file", "", "File listing hosts, one per line (# starts a comment)")
+	remotePath := fs.String("remote-path", "notify", "notify on the hosts: a name on their PATH, or a path")
+	copyBinary := fs.Bool("copy", false, "Copy this notify to hosts that have none (same OS and architecture only)")
+	sshCommand := fs.String("ssh", "ssh", "SSH client to run; keys or an agent must log in without a password")
+	connectTimeout := fs.Int("connect-timeout", 10, "Seconds to wait for each SSH connection")
+	parallel := fs.Int("parallel", 10, "Hosts notified at the same time")
+	resultJSON := fs.Bool("result-json", false, "Print one JSON line per host instead of a summary")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: notify remote -hosts host1,host2 [OPTIONS] -- -title ... -message ...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	hosts, err := remoteHosts(*hostList, *hostsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(hosts) == 0 || fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *parallel < 1 || *connectTimeout < 1 {
+		fmt.Fprintln(os.Stderr, "Error: -parallel and -connect-timeout must be 1 or more")
+		return 2
+	}
+
+	r := &remoteNotifier{ssh: systemSSH(*sshCommand, *connectTimeout), remotePath: *remotePath, copyBinary: *copyBinary}
+	if r.copyBinary {
+		if r.exePath, err = os.Executable(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to get executable path: %v\n", err)
+			return 1
+		}
+	}
+
+	// Notifications waiting for an acknowledgment keep their SSH session open, so hosts are
+	// notified in parallel and reported as they finish
+	results := make(chan remoteHostResult)
+	slots := make(chan struct{}, *parallel)
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results <- r.notify(context.Background(), host, fs.Args())
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	failed := 0
+	for result := range results {
+		if result.Error != "" {
+			failed++
+		}
+		if *resultJSON {
+			data, _ := json.Marshal(result)
+			fmt.Println(string(data))
+		} else if result.Error != "" {
+			fmt.Printf("%s: failed: %s\n", result.Host, result.Error)
+		} else {
+			fmt.Printf("%s: %s\n", result.Host, describeRemoteResult(result.Result))
+		}
+	}
+	if !*resultJSON {
+		fmt.Printf("%d of %d hosts notified\n", len(hosts)-failed, len(hosts))
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// remoteHosts returns the hosts of -hosts and -hosts-file, without duplicates
+func remoteHosts(list, file string) ([]string, error) {
+	names := strings.Split(list, ",")
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read hosts file: %v", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line, _, _ = strings.Cut(line, "#")
+			names = append(names, line)
+		}
+	}
+	var hosts []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t'\"") {
+			return nil, fmt.Errorf("invalid host %q", name)
+		}
+		seen[name] = true
+		hosts = append(hosts, name)
+	}
+	return hosts, nil
+}
+
+// systemSSH runs the SSH client command in batch mode, so a host asking for a password or an
+// unknown host key fails instead of waiting for input
+func systemSSH(command string, connectTimeout int) sshFunc {
+	return func(ctx context.Context, host, remoteCommand string, stdin io.Reader) ([]byte, []byte, int, error) {
+		cmd := exec.CommandContext(ctx, command, "-o", "BatchMode=yes", "-o", fmt.Sprintf("ConnectTimeout=%d", connectTimeout), host, remoteCommand)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, &stdout, &stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stdout.Bytes(), stderr.Bytes(), exitErr.ExitCode(), nil
+		}
+		return stdout.Bytes(), stderr.Bytes(), 0, err
+	}
+}
+
+// notify shows the notification of args on host and returns its result
+func (r *remoteNotifier) notify(ctx context.Context, host string, args []string) remoteHostResult {
+	result := remoteHostResult{Host: host}
+	notifyPath := r.remotePath
+	if r.copyBinary {
+		path, err := r.ensureBinary(ctx, host)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		notifyPath = path
+	}
+
+	quoted := []string{shellQuote(notifyPath)}
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	quoted = append(quoted, "-result-json")
+	stdout, stderr, code, err := r.ssh(ctx, host, strings.Join(quoted, " "), nil)
+	switch {
+	case err != nil:
+		result.Error = err.Error()
+		return result
+	case code == 127:
+		result.Error = fmt.Sprintf("%s not found on the host (use -remote-path or -copy)", notifyPath)
+		return result
+	case code == 255:
+		result.Error = "ssh: " + lastLine(string(stderr))
+		return result
+	}
+
+	// notify prints the result last, also when it exits with an error or a -choices index
+	var parsed NotificationResult
+	if err := json.Unmarshal([]byte(lastLine(string(stdout))), &parsed); err != nil {
+		if code != 0 {
+			result.Error = fmt.Sprintf("notify exited with %d: %s", code, lastLine(string(stderr)))
+		} else {
+			result.Error = fmt.Sprintf("could not read notify result: %v", err)
+		}
+		return result
+	}
+	result.Result = &parsed
+	if code != 0 && code < choiceExitBase {
+		result.Error = fmt.Sprintf("notify exited with %d: %s", code, describeRemoteResult(&parsed))
+	}
+	return result
+}
+
+// ensureBinary returns the notify to run on host: remotePath when the host has it, else this
+// executable copied to remoteCopyDir (unless an executable of this version is there already)
+func (r *remoteNotifier) ensureBinary(ctx context.Context, host string) (string, error) {
+	copyPath := fmt.Sprintf("%s/notify-%s", remoteCopyDir, appVersion)
+	probe := fmt.Sprintf("command -v %s >/dev/null 2>&1 && echo found; test -x %s && echo copied; uname -sm",
+		shellQuote(r.remotePath), shellQuote(copyPath))
+	stdout, stderr, code, err := r.ssh(ctx, host, probe, nil)
+	if err != nil {
+		return "", err
+	}
+	if code != 0 {
+		return "", fmt.Errorf("ssh: %s", lastLine(string(stderr)))
+	}
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	for _, line := range lines {
+		switch strings.TrimSpace(line) {
+		case "found":
+			return r.remotePath, nil
+		case "copied":
+			return copyPath, nil
+		}
+	}
+
+	platform := remotePlatform(lines[len(lines)-1])
+	if local := runtime.GOOS + "/" + runtime.GOARCH; platform != local {
+		return "", fmt.Errorf("host is %s and this notify is %s: install notify there or use -remote-path", platform, local)
+	}
+	binary, err := os.Open(r.exePath)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %v", r.exePath, err)
+	}
+	defer binary.Close()
+	install := fmt.Sprintf("umask 077 && mkdir -p %[1]s && cat > %[2]s.tmp && chmod 700 %[2]s.tmp && mv %[2]s.tmp %[2]s",
+		shellQuote(remoteCopyDir), shellQuote(copyPath))
+	if _, stderr, code, err := r.ssh(ctx, host, install, binary); err != nil || code != 0 {
+		if err == nil {
+			err = errors.New(lastLine(string(stderr)))
+		}
+		return "", fmt.Errorf("could not copy notify: %v", err)
+	}
+	return copyPath, nil
+}
+
+// remotePlatform returns the GOOS/GOARCH of "uname -sm" output, e.g. "Linux x86_64"
+func remotePlatform(uname string) string {
+	fields := strings.Fields(strings.ToLower(uname))
+	if len(fields) != 2 {
+		return "unknown"
+	}
+	arch := map[string]string{"x86_64": "amd64", "amd64": "amd64", "aarch64": "arm64", "arm64": "arm64", "i686": "386", "i386": "386"}[fields[1]]
+	if arch == "" {
+		arch = fields[1]
+	}
+	return fields[0] + "/" + arch
+}
+
+// describeRemoteResult summarizes a host's result: the action, method and the answer given
+func describeRemoteResult(result *NotificationResult) string {
+	description := result.Action
+	if result.Method != "" {
+		description += " (" + result.Method + ")"
+	}
+	if result.Choice != "" {
+		description += ", chose " + result.Choice
+	}
+	if result.Input != nil {
+		description += fmt.Sprintf(", entered %q", *result.Input)
+	}
+	if len(result.Targets) > 0 {
+		users := make([]string, 0, len(result.Targets))
+		for _, target := range result.Targets {
+			users = append(users, target.Username)
+		}
+		sort.Strings(users)
+		description += ", users " + strings.Join(users, ", ")
+	}
+	return description
+}
+
+// shellQuote quotes s as one word for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// lastLine returns the last non-empty line of s
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSSH records the commands run on each host and answers them with reply
+type fakeSSH struct {
+	mu       sync.Mutex
+	commands map[string][]string
+	stdin    map[string]string
+	reply    func(host, command string) (stdout string, code int)
+}
+
+func (f *fakeSSH) run(ctx context.Context, host, command string, stdin io.Reader) ([]byte, []byte, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.commands == nil {
+		f.commands, f.stdin = map[string][]string{}, map[string]string{}
+	}
+	f.commands[host] = append(f.commands[host], command)
+	if stdin != nil {
+		data, _ := io.ReadAll(stdin)
+		f.stdin[host] = string(data)
+	}
+	stdout, code := f.reply(host, command)
+	return []byte(stdout), []byte("error on " + host), code, nil
+}
+
+// TestRemoteNotify tests that notify runs on each host with its arguments quoted, and that the
+// result, a -choices exit code and failures are reported per host
+func TestRemoteNotify(t *testing.T) {
+	ssh := &fakeSSH{reply: func(host, command string) (string, int) {
+		switch host {
+		case "lab1":
+			return `{"action":"acknowledged","method":"fyne","title":"It's late"}` + "\n", 0
+		case "lab2":
+			return "log line\n" + `{"action":"acknowledged","method":"fyne","choice":"Tonight"}` + "\n", choiceExitBase + 1
+		case "lab3":
+			return "", 127
+		case "lab4":
+			return "", 255
+		}
+		return `{"action":"invalid"}`, 2
+	}}
+	r := &remoteNotifier{ssh: ssh.run, remotePath: "notify"}
+	args := []string{"-title", "It's late", "-choices", "Now,Tonight"}
+
+	result := r.notify(context.Background(), "lab1", args)
+	if result.Error != "" || result.Result == nil || result.Result.Action != actionAcknowledged {
+		t.Errorf("lab1: got %+v", result)
+	}
+	if want := `'notify' '-title' 'It'\''s late' '-choices' 'Now,Tonight' -result-json`; ssh.commands["lab1"][0] != want {
+		t.Errorf("Command = %s, want %s", ssh.commands["lab1"][0], want)
+	}
+
+	if result := r.notify(context.Background(), "lab2", args); result.Error != "" || result.Result.Choice != "Tonight" {
+		t.Errorf("lab2: expected the choice exit code to be a success, got %+v", result)
+	}
+	for host, want := range map[string]string{"lab3": "not found", "lab4": "ssh: error on lab4", "lab5": "notify exited with 2"} {
+		if result := r.notify(context.Background(), host, args); !strings.Contains(result.Error, want) {
+			t.Errorf("%s: error %q, want %q", host, result.Error, want)
+		}
+	}
+}
+
+// TestRemoteCopy tests that -copy uses the host's notify when it has one, copies this one to
+// hosts of the same platform that do not, and refuses other platforms
+func TestRemoteCopy(t *testing.T) {
+	exePath := filepath.Join(t.TempDir(), "notify")
+	os.WriteFile(exePath, []byte("binary"), 0755)
+	uname := map[string]string{"linux": "Linux", "darwin": "Darwin", "windows": "MINGW64_NT"}[runtime.GOOS] + " " +
+		map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[runtime.GOARCH]
+
+	ssh := &fakeSSH{reply: func(host, command string) (string, int) {
+		if strings.HasPrefix(command, "command -v") {
+			switch host {
+			case "installed":
+				return "found\n" + uname + "\n", 0
+			case "other":
+				return "Linux riscv64\n", 0
+			}
+			return uname + "\n", 0
+		}
+		return `{"action":"delivered"}`, 0
+	}}
+	r := &remoteNotifier{ssh: ssh.run, remotePath: "notify", copyBinary: true, exePath: exePath}
+
+	if result := r.notify(context.Background(), "installed", nil); result.Error != "" || !strings.HasPrefix(ssh.commands["installed"][1], "'notify' ") {
+		t.Errorf("Expected the installed notify to be used, got %+v and %q", result, ssh.commands["installed"])
+	}
+
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		result := r.notify(context.Background(), "fresh", nil)
+		if result.Error != "" || ssh.stdin["fresh"] != "binary" || len(ssh.commands["fresh"]) != 3 {
+			t.Fatalf("Expected notify to be copied, got %+v, %q", result, ssh.commands["fresh"])
+		}
+		if copied := "'" + remoteCopyDir + "/notify-" + appVersion + "' "; !strings.HasPrefix(ssh.commands["fresh"][2], copied) {
+			t.Errorf("Expected the copy to run, got %s", ssh.commands["fresh"][2])
+		}
+	}
+
+	if result := r.notify(context.Background(), "other", nil); !strings.Contains(result.Error, "linux/riscv64") {
+		t.Errorf("Expected another platform to be refused, got %+v", result)
+	}
+}
+
+// TestRemoteHosts tests that hosts come from the list and the file, without comments and duplicates
+func TestRemoteHosts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hosts.txt")
+	os.WriteFile(file, []byte("# Lab 2\nlab3\nroot@lab4  # spare\n\nlab1\n"), 0644)
+	hosts, err := remoteHosts("lab1, lab2,", file)
+	if err != nil || strings.Join(hosts, " ") != "lab1 lab2 lab3 root@lab4" {
+		t.Errorf("remoteHosts = %q, %v", hosts, err)
+	}
+	if _, err := remoteHosts("lab1,-oProxyCommand=evil", ""); err == nil {
+		t.Error("Expected a host starting with - to be rejected")
+	}
+}
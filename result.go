@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// Result actions reported in the acknowledgment payload
+const (
+	actionAcknowledged = notify.ActionAcknowledged
+	actionTimeout      = notify.ActionTimeout
+	actionDelivered    = notify.ActionDelivered
+	actionSkipped      = notify.ActionSkipped
+	actionSuppressed   = notify.ActionSuppressed
+	actionReplaced     = notify.ActionReplaced
+	actionCanceled     = notify.ActionCanceled
+)
+
+// NotificationResult is the acknowledgment payload describing what happened to a notification
+type NotificationResult struct {
+	MachineID   string                `json:"machine_id"`
+	Action      string                `json:"action"`
+	Method      string                `json:"method,omitempty"`     // "fyne", "webview", "messagebox", "wall", "users", or the -quick mechanism
+	Priority    string                `json:"priority,omitempty"`   // "breakglass" for emergency notifications
+	Screenshot  string                `json:"screenshot,omitempty"` // Base64 PNG thumbnail from -context-screenshot, only if the user agreed to share it
+	Input       *string               `json:"input,omitempty"`      // Text entered for -input, when the user clicked the button
+	Choice      string                `json:"choice,omitempty"`     // Option chosen from -choices
+	Downgrades  []string              `json:"downgrades,omitempty"` // What the method could not show as given, e.g. "choices: not shown by messagebox, ..."
+	Title       string                `json:"title"`
+	Variant     string                `json:"variant,omitempty"` // A/B variant from the spec's variants
+	Digest      int                   `json:"digest,omitempty"`  // Notifications shown together in the -digest-window digest that gave this result
+	Timestamp   string                `json:"timestamp"`
+	ScheduledAt string                `json:"scheduled_at,omitempty"` // RFC 3339 time an -at or -delay notification will be shown
+	Inventory   *Inventory            `json:"inventory,omitempty"`
+	Targets     []notify.TargetResult `json:"targets,omitempty"` // Per-target results of a -targets fan-out
+	Errors      []argumentError       `json:"errors,omitempty"`  // Problems with the arguments of an "invalid" notification
+}
+
+// resultReporter builds and prints the acknowledgment payload when -result-json is set
+type resultReporter struct {
+	jsonOutput       bool
+	includeInventory bool
+	title            string
+	message          string // Kept in the history unless sensitive
+	category         string
+	sensitive        bool                  // -sensitive: the message is kept out of the history
+	priority         string                // Set for -priority breakglass
+	variant          string                // A/B variant selected for this machine
+	inboxID          string                // ID in the local store when displayed in this session
+	screenshot       []byte                // Context screenshot the user agreed to share
+	input            string                // Text entered for -input
+	inputRequested   bool                  // -input was set, so an empty input is still reported
+	choice           string                // Option chosen from -choices
+	downgrades       []string              // What the delivery method could not show as given
+	redactAfterAck   bool                  // -redact-after-ack: blank the stored message once acknowledged
+	callbackURL      string                // -callback-url: also POST the payload here
+	targets          []notify.TargetResult // Per-target results of a -targets fan-out
+
+	// Follow-ups from the -spec file, launched by outcome
+	followUps     []SpecFollowUp
+	specDir       string
+	followUpDepth int
+}
+
+// newResult builds the acknowledgment payload for the given action and delivery method
+func (r resultReporter) newResult(action, method string) NotificationResult {
+	result := NotificationResult{
+		MachineID:  getMachineID(),
+		Action:     action,
+		Method:     method,
+		Priority:   r.priority,
+		Screenshot: encodeScreenshot(r.screenshot),
+		Title:      r.title,
+		Choice:     r.choice,
+		Downgrades: r.downgrades,
+		Variant:    r.variant,
+		Targets:    r.targets,
+		Timestamp:  time.Now().Format(time.RFC3339),
+	}
+	if r.inputRequested && action == actionAcknowledged {
+		result.Input = &r.input
+	}
+	if r.includeInventory {
+		result.Inventory = collectInventory()
+	}
+	return result
+}
+
+// encodeScreenshot returns png as base64 for the result payload, or "" when there is none
+func encodeScreenshot(png []byte) string {
+	if len(png) == 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(png)
+}
+
+// report records the outcome in the local store and the history, launches any follow-ups for it, prints the
+// acknowledgment payload as a single JSON line on stdout and posts it to -callback-url
+func (r resultReporter) report(action, method string) {
+	if r.inboxID != "" {
+		err := updateStoredNotification(r.inboxID, func(item *StoredNotification) {
+			if item.State == inboxStateAcknowledged && action != actionAcknowledged {
+				return // A toast clicked (notify activate) before this process reported the delivery
+			}
+			item.Action = action
+			item.Method = method
+			if action == actionAcknowledged {
+				item.State = inboxStateAcknowledged
+				if r.redactAfterAck {
+					item.Message = redactedMessage
+				}
+			}
+		})
+		if err != nil {
+			log.Printf("Warning: Could not update local store: %v", err)
+		}
+	}
+
+	if err := recordHistory(r.historyEntry(action, method)); err != nil {
+		log.Printf("Warning: Could not record notification in history: %v", err)
+	}
+
+	launchFollowUps(r.followUps, r.specDir, action, r.followUpDepth)
+
+	if !r.jsonOutput && r.callbackURL == "" {
+		return
+	}
+	result := r.newResult(action, method)
+	if r.callbackURL != "" {
+		if err := postCallback(r.callbackURL, result); err != nil {
+			log.Printf("Warning: Could not post result to -callback-url: %v", err)
+		}
+	}
+	if !r.jsonOutput {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("Warning: Could not encode result: %v", err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, string(data))
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// rolloutBucket maps a machine ID and campaign salt to a bucket from 0 to 99
+// The same machine always lands in the same bucket for a given salt,
+// while a different salt reshuffles which machines are in the early buckets
+func rolloutBucket(machineID, salt string) int {
+	sum := sha256.Sum256([]byte(salt + ":" + machineID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
+
+// variantBucket maps a machine ID and campaign salt to a bucket from 0 to total-1 for
+// choosing an A/B variant, hashed separately from rolloutBucket so the machines in the
+// first rollout stages are not all given the same variant
+func variantBucket(machineID, salt string, total int) int {
+	sum := sha256.Sum256([]byte("variant:" + salt + ":" + machineID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+}
+
+// isInRollout reports whether this machine should display a notification
+// that is being rolled out to the given percentage of endpoints
+func isInRollout(machineID, salt string, percent int) bool {
+	if percent >= 100 {
+		return true
+	}
+	if percent <= 0 {
+		return false
+	}
+	return rolloutBucket(machineID, salt) < percent
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
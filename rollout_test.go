@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestRolloutBoundaries tests the 0% and 100% rollout edge cases
+func TestRolloutBoundaries(t *testing.T) {
+	if !isInRollout("any-machine", "campaign", 100) {
+		t.Error("Expected every machine to be included at 100%")
+	}
+	if isInRollout("any-machine", "campaign", 0) {
+		t.Error("Expected no machine to be included at 0%")
+	}
+}
+
+// TestRolloutDeterministic tests that the same machine always gets the same answer
+func TestRolloutDeterministic(t *testing.T) {
+	first := rolloutBucket("machine-1234", "campaignX")
+	for i := 0; i < 10; i++ {
+		if bucket := rolloutBucket("machine-1234", "campaignX"); bucket != first {
+			t.Fatalf("Expected bucket %d, got %d", first, bucket)
+		}
+	}
+}
+
+// TestRolloutDistribution tests that roughly the requested share of machines is selected
+func TestRolloutDistribution(t *testing.T) {
+	const machines = 10000
+	included := 0
+	for i := 0; i < machines; i++ {
+		if isInRollout(fmt.Sprintf("machine-%d", i), "campaignX", 10) {
+			included++
+		}
+	}
+
+	// Expect 10% +/- 2%
+	if included < 800 || included > 1200 {
+		t.Errorf("Expected about 1000 machines in a 10%% rollout, got %d", included)
+	}
+	t.Logf("%d of %d machines included in 10%% rollout", included, machines)
+}
+
+// TestRolloutSaltReshuffles tests that different campaigns select different machines
+func TestRolloutSaltReshuffles(t *testing.T) {
+	differences := 0
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("machine-%d", i)
+		if isInRollout(id, "campaignA", 10) != isInRollout(id, "campaignB", 10) {
+			differences++
+		}
+	}
+	if differences == 0 {
+		t.Error("Expected different salts to select different machines")
+	}
+}
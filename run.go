@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// maxRunTitleLength and maxRunLineLength cap the command line in a notify run title and each
+// output line in its message, in characters
+const (
+	maxRunTitleLength = 60
+	maxRunLineLength  = 200
+)
+
+// runJob handles "notify run [OPTIONS] -- command [args...]": runs the command with its output
+// passed through, optionally with an in-progress notification, then shows whether it succeeded
+// with its duration and the end of its output; it exits with the command's exit code
+func runJob(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	title := fs.String("title", "", "Notification title (default: the command line)")
+	progress := fs.Bool("progress", false, "Show an in-progress notification while the command runs")
+	lines := fs.Int("lines", 10, "Last lines of output shown in the notification (0 for none)")
+	failureOnly := fs.Bool("failure-only", false, "Only notify when the command fails")
+	native := fs.Bool("native", false, "Use the OS notification center instead of a window")
+	debug := fs.Bool("debug", false, "Pass -debug to the notify processes showing the notifications")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: notify run [OPTIONS] -- command [args...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	command := fs.Args()
+	if len(command) == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *lines < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -lines must be 0 or more")
+		return 1
+	}
+	if *title == "" {
+		*title = truncateRunes(strings.Join(command, " "), maxRunTitleLength)
+	}
+	if !*debug {
+		log.SetOutput(io.Discard)
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get executable path: %v\n", err)
+		return 1
+	}
+	notifyArgs := func(title, message, urgency string, timeout int) []string {
+		args := []string{"-title", title, "-message", message, "-urgency", urgency, "-timeout", fmt.Sprintf("%d", timeout)}
+		if *native {
+			args = append(args, "-native")
+		}
+		if *debug {
+			args = append(args, "-debug")
+		}
+		return args
+	}
+
+	// Ctrl+C reaches the command too: notify waits for it to stop and reports how it ended
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	started := time.Now()
+	var progressCmd *exec.Cmd
+	progressMessage := fmt.Sprintf("Running since %s", started.Format("15:04"))
+	if *progress {
+		progressArgs := append(notifyArgs(*title, progressMessage, notify.UrgencyInfo, 0), "-state", notify.StateWorking)
+		progressCmd = exec.Command(exePath, progressArgs...)
+		if err := progressCmd.Start(); err != nil {
+			log.Printf("Warning: Could not show the in-progress notification: %v", err)
+			progressCmd = nil
+		}
+	}
+
+	tail := &tailBuffer{max: *lines}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, tail)
+	cmd.Stderr = io.MultiWriter(os.Stderr, tail)
+	runErr := cmd.Run()
+	duration := time.Since(started)
+
+	// The in-progress notification is done: close it and take it off the pending list of the inbox
+	if progressCmd != nil {
+		progressCmd.Process.Kill() // Fails harmlessly when the user already closed it
+		progressCmd.Wait()
+		retireProgressNotification(*title, progressMessage, started)
+	}
+
+	summary := summarizeJob(*title, runErr, duration, tail.String())
+	if summary.exitCode != 0 || !*failureOnly {
+		final := exec.Command(exePath, notifyArgs(summary.title, summary.message, summary.urgency, notify.UrgencyTimeout(summary.urgency))...)
+		detachProcess(final)
+		if err := final.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not show the notification: %v\n", err)
+		} else {
+			// Don't wait: the command's exit code is returned right away, the notification stays
+			final.Process.Release()
+		}
+	}
+	if summary.startErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", summary.startErr)
+	}
+	return summary.exitCode
+}
+
+// jobSummary is the notification notify run shows when the command has finished
+type jobSummary struct {
+	title    string
+	message  string
+	urgency  string
+	exitCode int   // Exit code of notify run: the command's, 1 when it was killed by a signal
+	startErr error // Set when the command could not be started
+}
+
+// summarizeJob describes how a command run by notify run ended: runErr is what exec.Cmd.Run
+// returned, output the end of its output
+// Failures are critical, so they stay until acknowledged
+func summarizeJob(title string, runErr error, duration time.Duration, output string) jobSummary {
+	elapsed := duration.Round(time.Second)
+	if elapsed == 0 {
+		elapsed = duration.Round(time.Millisecond)
+	}
+	summary := jobSummary{title: title + " succeeded", urgency: notify.UrgencyInfo}
+	var exitErr *exec.ExitError
+	switch {
+	case runErr == nil:
+		summary.message = fmt.Sprintf("Finished in %s", elapsed)
+	case errors.As(runErr, &exitErr):
+		summary.title = title + " failed"
+		summary.urgency = notify.UrgencyCritical
+		summary.exitCode = exitErr.ExitCode()
+		if summary.exitCode < 0 {
+			summary.exitCode = 1
+			summary.message = fmt.Sprintf("Stopped (%v) after %s", runErr, elapsed)
+		} else {
+			summary.message = fmt.Sprintf("Exit code %d after %s", summary.exitCode, elapsed)
+		}
+	default:
+		summary.title = title + " failed"
+		summary.urgency = notify.UrgencyCritical
+		summary.exitCode = 127 // As a shell reports a command it cannot run
+		summary.startErr = runErr
+		summary.message = fmt.Sprintf("Could not start: %v", runErr)
+	}
+	if output = strings.TrimSpace(output); output != "" {
+		summary.message += "\n\n" + output
+	}
+	summary.message = notify.NormalizeText(summary.message)
+	return summary
+}
+
+// retireProgressNotification marks the in-progress notification of notify run as read in the
+// local store: its window was closed because the command finished, not left unanswered
+func retireProgressNotification(title, message string, since time.Time) {
+	err := modifyStore(func(items []StoredNotification) []StoredNotification {
+		for i := range items {
+			item := &items[i]
+			if item.Title == title && item.Message == message && !item.Received.Before(since.Add(-time.Second)) && item.State == inboxStateUnread {
+				item.State = inboxStateRead
+			}
+		}
+		return items
+	})
+	if err != nil {
+		log.Printf("Warning: Could not update local store: %v", err)
+	}
+}
+
+// tailBuffer is an io.Writer that keeps the last max lines written to it, each cut to
+// maxRunLineLength characters; stdout and stderr of a command can write to it concurrently
+type tailBuffer struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte // Start of a line without its newline yet
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.max == 0 {
+		return len(p), nil
+	}
+	data := append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.addLine(string(data[:i]))
+		data = data[i+1:]
+	}
+	// Keep a runaway line (a progress bar redrawn with \r) from growing without bound
+	if len(data) > 4*maxRunLineLength {
+		data = data[len(data)-4*maxRunLineLength:]
+	}
+	t.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// addLine keeps line, and what is left after the last carriage return of a redrawn line
+func (t *tailBuffer) addLine(line string) {
+	line = strings.TrimRight(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	t.lines = append(t.lines, truncateRunes(line, maxRunLineLength))
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
+
+// String returns the kept lines, with a last line that did not end in a newline
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := t.lines
+	if len(t.partial) > 0 && t.max > 0 {
+		last := strings.TrimRight(string(t.partial), "\r")
+		if i := strings.LastIndexByte(last, '\r'); i >= 0 {
+			last = last[i+1:]
+		}
+		lines = append(append([]string(nil), lines...), truncateRunes(last, maxRunLineLength))
+		if len(lines) > t.max {
+			lines = lines[len(lines)-t.max:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateRunes cuts s to at most max characters, ending in an ellipsis when it was cut
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
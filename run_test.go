@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestRunHelperProcess is the failing command of TestSummarizeJob, not a test of its own
+func TestRunHelperProcess(t *testing.T) {
+	if os.Getenv("NOTIFY_RUN_HELPER") != "1" {
+		return
+	}
+	os.Exit(3)
+}
+
+// TestSummarizeJob tests the notification of a command that succeeded, failed and could not start
+func TestSummarizeJob(t *testing.T) {
+	ok := summarizeJob("backup", nil, 133*time.Second+400*time.Millisecond, "done\n")
+	if ok.title != "backup succeeded" || ok.message != "Finished in 2m13s\n\ndone" || ok.urgency != notify.UrgencyInfo || ok.exitCode != 0 {
+		t.Errorf("success = %+v", ok)
+	}
+
+	helper := exec.Command(os.Args[0], "-test.run=^TestRunHelperProcess$")
+	helper.Env = append(os.Environ(), "NOTIFY_RUN_HELPER=1")
+	failed := summarizeJob("backup", helper.Run(), 2*time.Second, "")
+	if failed.title != "backup failed" || failed.message != "Exit code 3 after 2s" || failed.urgency != notify.UrgencyCritical || failed.exitCode != 3 {
+		t.Errorf("failure = %+v", failed)
+	}
+
+	missing := summarizeJob("backup", exec.Command("notify-run-no-such-command").Run(), 0, "")
+	if missing.exitCode != 127 || missing.startErr == nil || !strings.HasPrefix(missing.message, "Could not start: ") {
+		t.Errorf("start failure = %+v", missing)
+	}
+}
+
+// TestTailBuffer tests that only the last lines are kept, cut to length, across writes
+func TestTailBuffer(t *testing.T) {
+	tail := &tailBuffer{max: 3}
+	tail.Write([]byte("one\ntwo\nthr"))
+	tail.Write([]byte("ee\r\nfour\n"))
+	tail.Write([]byte("10%\r50%\r100%"))
+	if got, want := tail.String(), "three\nfour\n100%"; got != want {
+		t.Errorf("tail = %q, want %q", got, want)
+	}
+
+	long := &tailBuffer{max: 1}
+	long.Write([]byte(strings.Repeat("x", 2*maxRunLineLength) + "\n"))
+	if got := []rune(long.String()); len(got) != maxRunLineLength || got[len(got)-1] != '…' {
+		t.Errorf("long line kept %d characters, want %d ending in an ellipsis", len(got), maxRunLineLength)
+	}
+
+	none := &tailBuffer{}
+	none.Write([]byte("output\n"))
+	if got := none.String(); got != "" {
+		t.Errorf("tail without lines = %q, want none", got)
+	}
+}
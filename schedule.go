@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// actionScheduled is the -result-json action of a notification armed with -at or -delay: a
+// waiting notify process shows it later, and reports its outcome to -callback-url
+const actionScheduled = "scheduled"
+
+// scheduleLayouts are the -at formats besides RFC 3339, in -tz or local time
+var scheduleLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05"}
+
+// parseAt parses an -at time: a date and time ("2024-12-01 09:00"), a time of day ("17:00",
+// the next one in tz or local time) or an RFC 3339 timestamp
+func parseAt(value, tz string, now time.Time) (time.Time, error) {
+	return parseTime("at", value, tz, now)
+}
+
+// parseTime parses a future time in the formats of -at for the flag name (at, until)
+func parseTime(name, value, tz string, now time.Time) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		if !at.After(now) {
+			return time.Time{}, fmt.Errorf("-%s %s has already passed", name, value)
+		}
+		return at, nil
+	}
+	loc := time.Local
+	if tz != "" {
+		var err error
+		if loc, err = notify.LoadTimeZone(tz); err != nil {
+			return time.Time{}, err
+		}
+	}
+	for _, layout := range scheduleLayouts {
+		if at, err := time.ParseInLocation(layout, value, loc); err == nil {
+			if !at.After(now) {
+				return time.Time{}, fmt.Errorf("-%s %s has already passed", name, value)
+			}
+			return at, nil
+		}
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -%s %q (use \"2006-01-02 15:04\", HH:MM or an RFC 3339 time)", name, value)
+	}
+	now = now.In(loc)
+	at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1) // Tomorrow
+	}
+	return at, nil
+}
+
+// scheduleTime returns when a notification with -at at or -delay delay is shown, zero for now
+func scheduleTime(at string, delay time.Duration, tz string, now time.Time) (time.Time, error) {
+	switch {
+	case at != "" && delay != 0:
+		return time.Time{}, fmt.Errorf("use either -at or -delay, not both")
+	case at != "":
+		return parseAt(at, tz, now)
+	case delay < 0:
+		return time.Time{}, fmt.Errorf("invalid -delay %v: must not be negative", delay)
+	case delay > 0:
+		return now.Add(delay), nil
+	}
+	return time.Time{}, nil
+}
+
+// scheduledArgs returns the command line args without -at and -delay, for the waiting process
+// that shows the notification at the time passed to it instead (-scheduled-at)
+func scheduledArgs(args []string, at time.Time) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			kept = append(kept, args[i:]...)
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && (name == "at" || name == "delay") {
+			if !hasValue {
+				i++ // The value is the next argument
+			}
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return append(kept, "-scheduled-at", at.Format(time.RFC3339))
+}
+
+// printRecurrence prints the -every reminders for a dry run
+func printRecurrence(every time.Duration, count int, until time.Time, stopOn string) {
+	limit := "until acknowledged"
+	if stopOn != "" {
+		limit = "until one of " + stopOn + " is chosen"
+	}
+	if count > 0 {
+		limit += fmt.Sprintf(", at most %d times", count)
+	}
+	if !until.IsZero() {
+		limit += ", not after " + until.Format("Mon 2 Jan 2006 15:04 MST")
+	}
+	fmt.Printf("Every: shown again every %v %s\n", every, limit)
+}
+
+// armSchedule starts a detached notify process that waits until at and then shows the
+// notification of the command line args, and reports the scheduled time
+// The waiting process does not survive a reboot or logoff
+func armSchedule(at time.Time, args []string, reporter resultReporter) int {
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not schedule the notification: %v\n", err)
+		return 1
+	}
+	cmd := exec.Command(exePath, scheduledArgs(args, at)...)
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not schedule the notification: %v\n", err)
+		return 1
+	}
+	pid := cmd.Process.Pid
+	// Don't wait: the waiting process outlives this one
+	cmd.Process.Release()
+	log.Printf("Scheduled for %s, waiting in process %d", at.Format(time.RFC3339), pid)
+
+	if !reporter.jsonOutput {
+		fmt.Printf("Scheduled for %s (process %d)\n", at.Format("Mon 2 Jan 2006 15:04 MST"), pid)
+		return 0
+	}
+	result := reporter.newResult(actionScheduled, "")
+	result.ScheduledAt = at.Format(time.RFC3339)
+	if data, err := json.Marshal(result); err == nil {
+		fmt.Println(string(data))
+	}
+	return 0
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
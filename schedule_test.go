@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestParseAt tests the -at formats, and that a time of day already passed means tomorrow
+func TestParseAt(t *testing.T) {
+	now := time.Date(2024, 11, 30, 18, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"2024-12-01 09:00":     time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC),
+		"2024-12-01T09:00":     time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC),
+		"19:30":                time.Date(2024, 11, 30, 19, 30, 0, 0, time.UTC),
+		"17:00":                time.Date(2024, 12, 1, 17, 0, 0, 0, time.UTC),
+		"2024-12-01T08:00:00Z": time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		got, err := parseAt(value, "UTC", now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseAt(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"2024-11-01 09:00", "tomorrow", "25:00"} {
+		if _, err := parseAt(value, "UTC", now); err == nil {
+			t.Errorf("parseAt(%q) should fail", value)
+		}
+	}
+}
+
+// TestScheduleTime tests combining -at and -delay
+func TestScheduleTime(t *testing.T) {
+	now := time.Date(2024, 11, 30, 18, 0, 0, 0, time.UTC)
+	if at, err := scheduleTime("", 30*time.Minute, "", now); err != nil || !at.Equal(now.Add(30*time.Minute)) {
+		t.Errorf("-delay 30m = %v, %v", at, err)
+	}
+	if at, err := scheduleTime("", 0, "", now); err != nil || !at.IsZero() {
+		t.Errorf("no -at or -delay = %v, %v, want zero", at, err)
+	}
+	if _, err := scheduleTime("19:00", time.Minute, "", now); err == nil {
+		t.Error("-at with -delay should fail")
+	}
+}
+
+// TestScheduledArgs tests that the waiting process gets the command line without -at and -delay
+func TestScheduledArgs(t *testing.T) {
+	at := time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC)
+	args := []string{"-title", "Maintenance", "-at", "2024-12-01 09:00", "--delay=30m", "-message", "Begins now"}
+	want := []string{"-title", "Maintenance", "-message", "Begins now", "-scheduled-at", "2024-12-01T09:00:00Z"}
+	if got := scheduledArgs(args, at); !reflect.DeepEqual(got, want) {
+		t.Errorf("scheduledArgs = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// serveRequestLimit caps the size of a spec posted to notify serve
+const serveRequestLimit = 64 << 10
+
+// maxServeRecords bounds the notifications notify serve remembers for GET /v1/notifications/{id},
+// the oldest are forgotten first
+const maxServeRecords = 1000
+
+// Statuses of a notification posted to notify serve
+const (
+	serveStatusPending = "pending" // Shown, not finished yet
+	serveStatusDone    = "done"    // Finished with a result
+	serveStatusFailed  = "failed"  // The notify process failed
+)
+
+// serveRecord is a notification posted to notify serve: GET /v1/notifications/{id} returns it,
+// and GET /v1/results streams it when it finishes
+type serveRecord struct {
+	ID     string              `json:"id"`
+	Status string              `json:"status"`
+	Result *NotificationResult `json:"result,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// notifyServer is the HTTP API of notify serve
+// Each notification is shown by a child notify process (Fyne runs one app per process)
+type notifyServer struct {
+	tokens     []serveToken     // Bearer tokens of the -token-file
+	certScopes []serveToken     // Common names of client certificates (-client-scopes) and their scopes, nil without -client-ca
+	oidc       *oidcVerifier    // Validates OIDC bearer tokens, nil without -oidc-issuer
+	receipts   *receiptStore    // Delivery receipts by notification ID, nil when disabled
+	fleet      *fleetController // Agents and their notifications with -controller, nil otherwise
+
+	// launch starts "notify -spec specPath -result-json" and calls done with its result
+	// when the process exits
+	launch func(specPath string, done func(*NotificationResult, error)) error
+
+	mu        sync.Mutex
+	records   map[string]serveRecord
+	order     []string                      // Record IDs, oldest first
+	watchers  map[chan serveRecord]struct{} // Open GET /v1/results streams
+	gotifyIDs int                           // Last numeric ID returned to a Gotify client
+}
+
+// runServe handles "notify serve": accept notification specs (JSON or YAML) over HTTP
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8787", "Address to listen on")
+	tokenFile := fs.String("token-file", "", "File holding the bearer tokens clients must send, one per line followed by its scopes (show, agent, manage; default manage), required unless listening on loopback")
+	receiptsPath := fs.String("receipts", "", "File keeping the delivery receipts, so a notification ID is shown only once (default: receipts.json in the user config directory)")
+	debug := fs.Bool("debug", false, "Log requests and pass -debug to notification processes")
+	controller := fs.Bool("controller", false, "Also accept notify agent connections and push notifications to them (/v1/agents, /v1/fleet/notifications)")
+	tlsCert := fs.String("tls-cert", "", "Serve HTTPS with this certificate (PEM), with -tls-key")
+	tlsKey := fs.String("tls-key", "", "Private key (PEM) of -tls-cert")
+	clientCA := fs.String("client-ca", "", "Require client certificates signed by these CA certificates (PEM), with -tls-cert and -client-scopes")
+	clientScopes := fs.String("client-scopes", "", "File of client certificate common names, one per line followed by its scopes (show, agent, manage; default manage)")
+	oidcIssuer := fs.String("oidc-issuer", "", "Accept OIDC access tokens of this issuer (https URL), with notify:show, notify:agent or notify:manage in their scope claim")
+	oidcAudience := fs.String("oidc-audience", "", "Audience (aud) the OIDC access tokens must be issued for")
+	digestWindow := fs.Duration("digest-window", 0, "Hold notifications arriving within this time of the last one shown and show them together in one digest when it ends, e.g. 30s (default: each at once)")
+	fs.Parse(args)
+
+	if *digestWindow < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -digest-window cannot be negative")
+		return 2
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fmt.Fprintln(os.Stderr, "Error: -tls-cert and -tls-key go together")
+		return 2
+	}
+	if *clientCA != "" && (*tlsCert == "" || *clientScopes == "") || *clientCA == "" && *clientScopes != "" {
+		fmt.Fprintln(os.Stderr, "Error: -client-ca needs -tls-cert and -client-scopes")
+		return 2
+	}
+	if *oidcIssuer != "" && (!strings.HasPrefix(*oidcIssuer, "https://") || *oidcAudience == "") {
+		fmt.Fprintln(os.Stderr, "Error: -oidc-issuer must be an https URL, with -oidc-audience")
+		return 2
+	}
+
+	if !*debug {
+		log.SetOutput(io.Discard)
+	}
+
+	server := &notifyServer{}
+	if *controller {
+		server.fleet = &fleetController{}
+	}
+	if *tokenFile != "" {
+		data, err := os.ReadFile(*tokenFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read token file: %v\n", err)
+			return 1
+		}
+		if server.tokens, err = parseServeTokens(string(data)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *tokenFile, err)
+			return 1
+		}
+	}
+	var tlsConfig *tls.Config
+	if *clientCA != "" {
+		var err error
+		if tlsConfig, err = serveTLSConfig(*clientCA); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		data, err := os.ReadFile(*clientScopes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read client scopes: %v\n", err)
+			return 1
+		}
+		if server.certScopes, err = parseServeTokens(string(data)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *clientScopes, err)
+			return 1
+		}
+	}
+	if *oidcIssuer != "" {
+		server.oidc = newOIDCVerifier(*oidcIssuer, *oidcAudience, &http.Client{Timeout: 10 * time.Second})
+		// Report a wrong or unreachable issuer now; the first token with an unknown key tries again
+		if keys, err := server.oidc.fetchKeys(); err != nil {
+			fmt.Printf("Warning: Could not get the keys of the OIDC issuer: %v\n", err)
+		} else {
+			server.oidc.keys, server.oidc.fetched = keys, time.Now()
+		}
+	}
+	if len(server.tokens) == 0 && server.certScopes == nil && server.oidc == nil && !isLoopbackAddress(*listen) {
+		fmt.Fprintf(os.Stderr, "Error: -token-file, -client-ca or -oidc-issuer is required when listening on %s (not loopback)\n", *listen)
+		return 1
+	}
+
+	if *receiptsPath == "" {
+		path, err := defaultReceiptsPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		*receiptsPath = path
+	}
+	receipts, err := openReceiptStore(*receiptsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	server.receipts = receipts
+
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get executable path: %v\n", err)
+		return 1
+	}
+	server.launch = newDigestLauncher(*digestWindow, func(specPath string, done func(*NotificationResult, error)) error {
+		return launchSpec(exePath, specPath, true, *debug, done)
+	})
+
+	scheme := "http"
+	if *tlsCert != "" {
+		scheme = "https"
+	}
+	fmt.Printf("notify serve listening on %s://%s (POST /v1/notifications, PUT and GET /v1/notifications/{id}, GET /v1/results, GET and POST /v1/receipts, GET /v1/status, ntfy at /ntfy, Gotify at /gotify)\n", scheme, *listen)
+	if server.fleet != nil {
+		fmt.Println("Controller: agents connect to GET /v1/agents/connect, POST /v1/fleet/notifications pushes to them")
+	}
+	if len(server.tokens) == 0 && server.certScopes == nil && server.oidc == nil {
+		fmt.Println("Warning: no -token-file, every local user can show notifications through this server")
+	}
+	httpServer := &http.Server{Addr: *listen, Handler: server.handler(), TLSConfig: tlsConfig}
+	if *tlsCert != "" {
+		err = httpServer.ListenAndServeTLS(*tlsCert, *tlsKey)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// handler returns the routes of the API
+func (s *notifyServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/notifications", s.handleNotifications)
+	mux.HandleFunc("/v1/notifications/", s.handleRecord)
+	mux.HandleFunc("/v1/results", s.handleResults)
+	mux.HandleFunc("/v1/receipts", s.handleReceipts)
+	mux.HandleFunc("/v1/status", s.handleStatus)
+	mux.HandleFunc("/ntfy", s.handleNtfy)
+	mux.HandleFunc("/ntfy/", s.handleNtfy)
+	mux.HandleFunc("/gotify/message", s.handleGotify)
+	if s.fleet != nil {
+		s.fleetRoutes(mux)
+	}
+	return s.authorize(mux)
+}
+
+// handleNotifications shows the posted spec; with ?wait=false it returns 202 and the ID of
+// the notification once its process has started, otherwise the acknowledgment result when
+// it finishes (the ID is in the Location header)
+func (s *notifyServer) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	data, ok := readRequestBody(w, r)
+	if !ok {
+		return
+	}
+	var accepted func(id string)
+	if r.URL.Query().Get("wait") == "false" {
+		accepted = func(id string) {
+			writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted", "id": id})
+		}
+	}
+	s.show(w, data, "", accepted)
+}
+
+// show validates spec data and launches its notify process; accepted writes the response
+// once the process has started, or when nil the acknowledgment result is written when it finishes
+// id is the notification ID chosen by the client, shown only once (see receiptStore.claim),
+// or empty for a new one
+func (s *notifyServer) show(w http.ResponseWriter, data []byte, id string, accepted func(id string)) {
+	// Validate up front so clients get schema errors instead of a failed process
+	if err := checkServeSpec(data); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if id == "" {
+		id = newNotificationID()
+	}
+	if s.receipts != nil {
+		existing, claimed, err := s.receipts.claim(id, time.Now())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !claimed {
+			w.Header().Set("Location", "/v1/notifications/"+id)
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("notification %s was already received at %s (%s), it is not shown again", id, existing.Received.Format(time.RFC3339), existing.Status))
+			return
+		}
+	}
+
+	specFile, err := os.CreateTemp("", "notify-serve-*.yaml")
+	if err != nil {
+		s.update(serveRecord{ID: id, Status: serveStatusFailed, Error: err.Error()})
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	specFile.Write(data)
+	specFile.Close()
+
+	s.update(serveRecord{ID: id, Status: serveStatusPending})
+	finished := make(chan serveRecord, 1)
+	err = s.launch(specFile.Name(), func(result *NotificationResult, err error) {
+		record := serveRecord{ID: id, Status: serveStatusDone, Result: result}
+		if err != nil {
+			record = serveRecord{ID: id, Status: serveStatusFailed, Error: err.Error()}
+		}
+		s.update(record)
+		finished <- record
+	})
+	if err != nil {
+		os.Remove(specFile.Name())
+		s.update(serveRecord{ID: id, Status: serveStatusFailed, Error: err.Error()})
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/v1/notifications/"+id)
+	if accepted != nil {
+		accepted(id)
+		return
+	}
+	record := <-finished
+	if record.Status == serveStatusFailed {
+		writeJSONError(w, http.StatusInternalServerError, record.Error)
+		return
+	}
+	writeJSON(w, http.StatusOK, record.Result)
+}
+
+// checkServeSpec validates spec data posted over HTTP: against the schema, and without the
+// fields that read or write files on this machine or make it send requests elsewhere
+// Icons may be http(s) URLs, which notify downloads
+func checkServeSpec(data []byte) error {
+	spec, errs, err := parseSpec(data, "")
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		lines := make([]string, len(errs))
+		for i, e := range errs {
+			lines[i] = e.String()
+		}
+		return errors.New("invalid spec: " + strings.Join(lines, "; "))
+	}
+	if len(spec.FollowUps) > 0 {
+		return errors.New("follow_ups reference spec files and are not supported over HTTP")
+	}
+	if spec.MsgFile != "" {
+		return errors.New("message_file reads a file on this machine and is not supported over HTTP, send the message")
+	}
+	icons := []struct{ field, icon string }{
+		{"icon", spec.Icon},
+		{"state_icons.pending", spec.StateIcons.Pending},
+		{"state_icons.working", spec.StateIcons.Working},
+		{"state_icons.success", spec.StateIcons.Success},
+		{"state_icons.failed", spec.StateIcons.Failed},
+	}
+	for _, icon := range icons {
+		if icon.icon == "" {
+			continue
+		}
+		if !notify.IsIconURL(icon.icon) {
+			return fmt.Errorf("%s reads a file on this machine and is not supported over HTTP, use an http(s) URL or icon_data", icon.field)
+		}
+		if err := notify.ValidateIconURL(icon.icon); err != nil {
+			return fmt.Errorf("%s: %v", icon.field, err)
+		}
+	}
+	switch {
+	case spec.Font != "":
+		return errors.New("font reads a file on this machine and is not supported over HTTP")
+	case notify.IsSoundFile(spec.Sound):
+		return errors.New("sound files are read on this machine and are not supported over HTTP, use system or a system sound name")
+	case spec.AckFile != "" && spec.AckFile != notify.AckFileDefault:
+		return fmt.Errorf("ack_file writes a file on this machine and is not supported over HTTP, use %q for the user's own", notify.AckFileDefault)
+	case spec.Schedule.Calendar != "" && !strings.HasPrefix(spec.Schedule.Calendar, "http://") && !strings.HasPrefix(spec.Schedule.Calendar, "https://"):
+		return errors.New("schedule.calendar reads a file on this machine and is not supported over HTTP, use an http(s) URL")
+	case spec.Result.CallbackURL != "":
+		return errors.New("result.callback_url makes this machine send requests and is not supported over HTTP, read the result from the response or GET /v1/results")
+	case spec.Delivery.Ntfy != "" || spec.Delivery.Gotify != "":
+		return errors.New("delivery.ntfy and delivery.gotify publish with this machine's tokens and are not supported over HTTP")
+	}
+	return nil
+}
+
+// readRequestBody reads the request body up to serveRequestLimit, writing the error response if it is larger
+func readRequestBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, serveRequestLimit))
+	if err != nil {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("spec larger than %d bytes", serveRequestLimit))
+		return nil, false
+	}
+	return data, true
+}
+
+// handleRecord returns the status, and once finished the result, of a posted notification
+// PUT shows the spec in the body under the ID of the path, once: like POST /v1/notifications,
+// but a notification ID this machine has a receipt of is refused with 409 Conflict
+func (s *notifyServer) handleRecord(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/notifications/")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := validateNotificationID(id); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if s.receipts == nil {
+			writeJSONError(w, http.StatusServiceUnavailable, "delivery receipts are not enabled")
+			return
+		}
+		data, ok := readRequestBody(w, r)
+		if !ok {
+			return
+		}
+		var accepted func(id string)
+		if r.URL.Query().Get("wait") == "false" {
+			accepted = func(id string) {
+				writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted", "id": id})
+			}
+		}
+		s.show(w, data, id, accepted)
+		return
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSONError(w, http.StatusMethodNotAllowed, "use GET or PUT")
+		return
+	}
+	s.mu.Lock()
+	record, ok := s.records[id]
+	s.mu.Unlock()
+	if !ok && s.receipts != nil {
+		// Forgotten since, or received before notify serve restarted
+		var receipt deliveryReceipt
+		if receipt, ok = s.receipts.get(id); ok {
+			record = serveRecord{ID: id, Status: receipt.Status, Result: receipt.Result, Error: receipt.Error}
+		}
+	}
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no notification %q (only the last %d are kept)", id, maxServeRecords))
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+// handleResults streams notifications as they finish, one JSON record per line, until the
+// client disconnects; notifications that finished before the request are not repeated
+func (s *notifyServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	watcher := make(chan serveRecord, 64)
+	s.mu.Lock()
+	if s.watchers == nil {
+		s.watchers = map[chan serveRecord]struct{}{}
+	}
+	s.watchers[watcher] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, watcher)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case record := <-watcher:
+			if err := encoder.Encode(record); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// update stores record, forgetting the oldest beyond maxServeRecords, and sends it to the
+// GET /v1/results streams once the notification has finished
+func (s *notifyServer) update(record serveRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.records == nil {
+		s.records = map[string]serveRecord{}
+	}
+	if _, known := s.records[record.ID]; !known {
+		s.order = append(s.order, record.ID)
+	}
+	s.records[record.ID] = record
+	for len(s.order) > maxServeRecords {
+		delete(s.records, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	if record.Status == serveStatusPending {
+		return
+	}
+	if s.receipts != nil {
+		if err := s.receipts.finish(record, time.Now()); err != nil {
+			log.Printf("Could not record the receipt of %s: %v", record.ID, err)
+		}
+	}
+	for watcher := range s.watchers {
+		select {
+		case watcher <- record:
+		default:
+			log.Printf("Results stream too slow, dropped the result of %s", record.ID)
+		}
+	}
+}
+
+// handleStatus returns the same report as "notify status -json"
+func (s *notifyServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	writeJSON(w, http.StatusOK, collectStatus())
+}
+
+// launchSpec starts a notify process for specPath and calls done with its result when it
+// exits, removing the file; a remote spec, sent from another machine, cannot read this
+// machine's environment with {{env.NAME}} (the result has the rendered title)
+func launchSpec(exePath, specPath string, remote, debug bool, done func(*NotificationResult, error)) error {
+	args := []string{"-spec", specPath, "-result-json"}
+	if remote {
+		args = append(args, "-remote-spec")
+	}
+	if debug {
+		args = append(args, "-debug")
+	}
+	cmd := exec.Command(exePath, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		os.Remove(specPath)
+		return fmt.Errorf("failed to start notify: %v", err)
+	}
+
+	go func() {
+		defer os.Remove(specPath)
+		// -choices exits with the index of the chosen option, which is not a failure
+		var exitErr *exec.ExitError
+		if err := cmd.Wait(); err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() < choiceExitBase) {
+			err = fmt.Errorf("notify failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+			log.Printf("%s: %v", filepath.Base(specPath), err)
+			done(nil, err)
+			return
+		}
+		// The result is the last line on stdout
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		var result NotificationResult
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &result); err != nil {
+			done(nil, fmt.Errorf("could not read notify result: %v", err))
+			return
+		}
+		done(&result, nil)
+	}()
+	return nil
+}
+
+// isLoopbackAddress reports whether a listen address only accepts local connections
+func isLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes {"error": message}
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
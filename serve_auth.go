@@ -0,0 +1,401 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scopes of a notify serve token, after the token in the -token-file
+const (
+	scopeShow   = "show"   // Show notifications and read their results: /v1/notifications, /ntfy and /gotify
+	scopeAgent  = "agent"  // Connect as a notify agent: /v1/agents/connect and /v1/agents/results
+	scopeManage = "manage" // Every endpoint, also results, receipts, status and the fleet; a token without scopes has it
+)
+
+// oidcScopePrefix marks the scopes of an OIDC access token that notify serve grants,
+// e.g. "notify:show" in its scope claim
+const oidcScopePrefix = "notify:"
+
+// oidcKeyRefresh is the shortest time between two downloads of the issuer's keys, so tokens
+// with unknown key IDs cannot make notify serve hammer the issuer
+const oidcKeyRefresh = time.Minute
+
+// oidcLeeway is the clock skew allowed when checking the expiry of an OIDC token
+const oidcLeeway = time.Minute
+
+// serveToken is a bearer token notify serve accepts and the scopes it grants; in the
+// -client-scopes file the token is the common name of a client certificate
+type serveToken struct {
+	token  string
+	scopes []string
+}
+
+// oidcVerifier validates OIDC access tokens (JWTs signed with RS256 or ES256) of one
+// issuer and audience, with the keys published at the issuer's jwks_uri
+type oidcVerifier struct {
+	issuer   string
+	audience string
+	client   *http.Client
+	now      func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey // By key ID
+	fetched time.Time                   // Last download of the keys
+}
+
+// parseServeTokens reads a -token-file: one token per line, optionally followed by its
+// comma-separated scopes, e.g. "tk_monitoring show"; empty lines and # comments are skipped
+// A single token alone, the format of older versions, keeps its access to everything
+func parseServeTokens(data string) ([]serveToken, error) {
+	var tokens []serveToken
+	for i, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		token := serveToken{token: fields[0], scopes: []string{scopeManage}}
+		switch len(fields) {
+		case 1:
+		case 2:
+			token.scopes = strings.Split(fields[1], ",")
+			for _, scope := range token.scopes {
+				if scope != scopeShow && scope != scopeAgent && scope != scopeManage {
+					return nil, fmt.Errorf("line %d: invalid scope %q (use %s, %s or %s)", i+1, scope, scopeShow, scopeAgent, scopeManage)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("line %d: expected a token and its scopes, e.g. \"TOKEN show,agent\"", i+1)
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("no tokens in the token file")
+	}
+	return tokens, nil
+}
+
+// grants reports whether t may use an endpoint that needs scope
+func (t serveToken) grants(scope string) bool {
+	return grantsScope(t.scopes, scope)
+}
+
+// grantsScope reports whether scopes include scope, or manage
+func grantsScope(scopes []string, scope string) bool {
+	for _, granted := range scopes {
+		if granted == scope || granted == scopeManage {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredScope returns the scope a request needs
+func requiredScope(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case path == "/v1/notifications", strings.HasPrefix(path, "/v1/notifications/"),
+		path == "/ntfy", strings.HasPrefix(path, "/ntfy/"), strings.HasPrefix(path, "/gotify/"):
+		return scopeShow
+	case path == "/v1/agents/connect", path == "/v1/agents/results":
+		return scopeAgent
+	}
+	return scopeManage
+}
+
+// serveTLSConfig returns the TLS configuration of -client-ca: every client must present a
+// certificate signed by one of the CA certificates in caFile (PEM)
+func serveTLSConfig(caFile string) (*tls.Config, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the client CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates in %s", caFile)
+	}
+	return &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// authorize rejects requests that are not authenticated, when the server has any of bearer
+// tokens, client certificates or OIDC, and requests for an endpoint outside their scopes
+func (s *notifyServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		if len(s.tokens) > 0 || s.certScopes != nil || s.oidc != nil {
+			scopes, err := s.authenticate(r)
+			if err != nil {
+				writeJSONError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			if scope := requiredScope(r); !grantsScope(scopes, scope) {
+				writeJSONError(w, http.StatusForbidden, fmt.Sprintf("this token does not have the %s scope", scope))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate returns the scopes of the request's credentials: a bearer token (a -token-file
+// token or an OIDC access token), or else the client certificate
+// Gotify publishers send the token as the X-Gotify-Key header or the token query parameter instead
+func (s *notifyServer) authenticate(r *http.Request) ([]string, error) {
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if strings.HasPrefix(r.URL.Path, "/gotify/") && given == "" {
+		given = r.Header.Get("X-Gotify-Key")
+		if given == "" {
+			given = r.URL.Query().Get("token")
+		}
+	}
+	if given != "" {
+		var matched *serveToken
+		for i := range s.tokens {
+			if subtle.ConstantTimeCompare([]byte(given), []byte(s.tokens[i].token)) == 1 {
+				matched = &s.tokens[i]
+			}
+		}
+		if matched != nil {
+			return matched.scopes, nil
+		}
+		if s.oidc != nil && strings.Count(given, ".") == 2 {
+			scopes, err := s.oidc.verify(given)
+			if err != nil {
+				return nil, fmt.Errorf("invalid OIDC token: %v", err)
+			}
+			return scopes, nil
+		}
+		return nil, errors.New("missing or invalid bearer token")
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, client := range s.certScopes {
+			if client.token == name {
+				return client.scopes, nil
+			}
+		}
+		return nil, fmt.Errorf("client certificate %q is not in -client-scopes", name)
+	}
+	return nil, errors.New("missing or invalid bearer token")
+}
+
+// newOIDCVerifier returns a verifier of tokens issued by issuer for audience
+func newOIDCVerifier(issuer, audience string, client *http.Client) *oidcVerifier {
+	return &oidcVerifier{issuer: strings.TrimSuffix(issuer, "/"), audience: audience, client: client, now: time.Now}
+}
+
+// verify checks an OIDC access token's signature, issuer, audience and validity, and
+// returns the notify scopes of its scope (or scp) claim, without oidcScopePrefix
+func (v *oidcVerifier) verify(token string) ([]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %v", err)
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch header.Alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New("bad signature")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 || !ecdsa.Verify(ecKey, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+			return nil, errors.New("bad signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q (use RS256 or ES256)", header.Alg)
+	}
+
+	var claims struct {
+		Issuer    string          `json:"iss"`
+		Audience  json.RawMessage `json:"aud"`
+		Expires   int64           `json:"exp"`
+		NotBefore int64           `json:"nbf"`
+		Scope     string          `json:"scope"`
+		Scp       json.RawMessage `json:"scp"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := v.now()
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != v.issuer:
+		return nil, fmt.Errorf("issued by %q, not %s", claims.Issuer, v.issuer)
+	case !jwtAudience(claims.Audience, v.audience):
+		return nil, fmt.Errorf("not issued for the audience %s", v.audience)
+	case claims.Expires == 0 || now.After(time.Unix(claims.Expires, 0).Add(oidcLeeway)):
+		return nil, errors.New("expired")
+	case claims.NotBefore != 0 && now.Add(oidcLeeway).Before(time.Unix(claims.NotBefore, 0)):
+		return nil, errors.New("not valid yet")
+	}
+
+	granted := strings.Fields(claims.Scope)
+	var scp []string
+	if json.Unmarshal(claims.Scp, &scp) == nil {
+		granted = append(granted, scp...)
+	} else {
+		var scpString string
+		if json.Unmarshal(claims.Scp, &scpString) == nil {
+			granted = append(granted, strings.Fields(scpString)...)
+		}
+	}
+	var scopes []string
+	for _, scope := range granted {
+		if name, ok := strings.CutPrefix(scope, oidcScopePrefix); ok {
+			scopes = append(scopes, name)
+		}
+	}
+	return scopes, nil
+}
+
+// key returns the issuer's public key with ID kid, downloading the keys again when it is
+// unknown (keys are rotated), at most every oidcKeyRefresh
+func (v *oidcVerifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if v.now().Sub(v.fetched) < oidcKeyRefresh {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	v.fetched = v.now()
+	keys, err := v.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// fetchKeys downloads the issuer's keys: the discovery document names the jwks_uri
+func (v *oidcVerifier) fetchKeys() (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != v.issuer || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("the discovery document of %s names issuer %q and no jwks_uri", v.issuer, discovery.Issuer)
+	}
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(n) < 256 || len(e) == 0 || len(e) > 4 {
+				log.Printf("Ignoring OIDC key %q: malformed or shorter than 2048 bits", k.Kid)
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if errX != nil || errY != nil || !key.Curve.IsOnCurve(key.X, key.Y) {
+				log.Printf("Ignoring OIDC key %q: malformed EC key", k.Kid)
+				continue
+			}
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// getJSON decodes the JSON document at url
+func (v *oidcVerifier) getJSON(url string, into interface{}) error {
+	resp, err := v.client.Get(url)
+	if err != nil {
+		return fmt.Errorf("could not reach the OIDC issuer: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not get %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return fmt.Errorf("could not read %s: %v", url, err)
+	}
+	return nil
+}
+
+// decodeJWTPart decodes the base64url JSON header or claims of a JWT
+func decodeJWTPart(part string, into interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("malformed token: %v", err)
+	}
+	if err := json.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("malformed token: %v", err)
+	}
+	return nil
+}
+
+// jwtAudience reports whether the aud claim, a string or a list, includes audience
+func jwtAudience(aud json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(aud, &single) == nil {
+		return single == audience
+	}
+	var list []string
+	json.Unmarshal(aud, &list)
+	for _, a := range list {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
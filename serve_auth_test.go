@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestServeTokenScopes tests the -token-file format and that each token only reaches the
+// endpoints of its scopes
+func TestServeTokenScopes(t *testing.T) {
+	tokens, err := parseServeTokens("# notify serve tokens\nadmin-token\n\nmonitoring show\nlab agent,show\n")
+	if err != nil || len(tokens) != 3 || !tokens[0].grants(scopeManage) || tokens[1].grants(scopeAgent) || !tokens[2].grants(scopeAgent) {
+		t.Fatalf("parseServeTokens = %+v, %v", tokens, err)
+	}
+	for _, data := range []string{"", "# only a comment\n", "tk show,admin\n", "tk show agent\n"} {
+		if _, err := parseServeTokens(data); err == nil {
+			t.Errorf("Expected an error for %q", data)
+		}
+	}
+
+	server := &notifyServer{tokens: tokens, launch: func(specPath string, done func(*NotificationResult, error)) error {
+		os.Remove(specPath)
+		done(&NotificationResult{Action: actionAcknowledged}, nil)
+		return nil
+	}}
+	handler := server.handler()
+	for _, tt := range []struct {
+		token, method, path string
+		status              int
+	}{
+		{"monitoring", "POST", "/v1/notifications", http.StatusOK},
+		{"monitoring", "GET", "/v1/status", http.StatusForbidden},
+		{"monitoring", "GET", "/v1/receipts", http.StatusForbidden},
+		{"lab", "GET", "/v1/results", http.StatusForbidden},
+		{"admin-token", "GET", "/v1/status", http.StatusOK},
+		{"admin-token", "POST", "/v1/notifications", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("version: 1\ntitle: Hello\nmessage: World\n"))
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s with %s: status %d, want %d (%s)", tt.method, tt.path, tt.token, rec.Code, tt.status, rec.Body.String())
+		}
+	}
+}
+
+// TestServeClientCertificates tests that with -client-ca a client certificate authenticates
+// with the scopes of its common name, and that other certificates are refused
+func TestServeClientCertificates(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Notify CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	caCert, _ := x509.ParseCertificate(caDER)
+	clientCert := func(name string, signer *x509.Certificate, signerKey *ecdsa.PrivateKey) tls.Certificate {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		if signer == nil {
+			signer, signerKey = template, key // Self-signed
+		}
+		der, _ := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0644)
+	tlsConfig, err := serveTLSConfig(caFile)
+	if err != nil {
+		t.Fatalf("serveTLSConfig failed: %v", err)
+	}
+	certScopes, _ := parseServeTokens("monitoring.example.com show\n")
+	server := &notifyServer{certScopes: certScopes, launch: func(specPath string, done func(*NotificationResult, error)) error {
+		os.Remove(specPath)
+		done(&NotificationResult{Action: actionAcknowledged}, nil)
+		return nil
+	}}
+	httpServer := httptest.NewUnstartedServer(server.handler())
+	httpServer.TLS = tlsConfig
+	httpServer.StartTLS()
+	defer httpServer.Close()
+
+	request := func(cert *tls.Certificate, method, path string) (int, error) {
+		transport := httpServer.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		}
+		req, _ := http.NewRequest(method, httpServer.URL+path, strings.NewReader("version: 1\ntitle: Hello\nmessage: World\n"))
+		resp, err := (&http.Client{Transport: transport}).Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	monitoring := clientCert("monitoring.example.com", caCert, caKey)
+	if status, err := request(&monitoring, "POST", "/v1/notifications"); status != http.StatusOK {
+		t.Errorf("POST with the monitoring certificate: %d, %v", status, err)
+	}
+	if status, err := request(&monitoring, "GET", "/v1/status"); status != http.StatusForbidden {
+		t.Errorf("GET /v1/status with a show certificate: %d, %v, want 403", status, err)
+	}
+	intruder := clientCert("intruder.example.com", caCert, caKey)
+	if status, err := request(&intruder, "POST", "/v1/notifications"); status != http.StatusUnauthorized {
+		t.Errorf("POST with a certificate not in -client-scopes: %d, %v, want 401", status, err)
+	}
+	selfSigned := clientCert("monitoring.example.com", nil, nil)
+	if _, err := request(&selfSigned, "POST", "/v1/notifications"); err == nil {
+		t.Error("Expected a certificate not signed by the CA to fail the handshake")
+	}
+	if _, err := request(nil, "POST", "/v1/notifications"); err == nil {
+		t.Error("Expected a client without a certificate to fail the handshake")
+	}
+}
+
+// TestOIDCVerifier tests OIDC access tokens: signature, issuer, audience, expiry and the
+// notify scopes of the scope claim, and that notify serve grants them
+func TestOIDCVerifier(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var issuer string
+	idp := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+				{"kid": "rsa1", "kty": "RSA", "n": base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), "e": "AQAB"},
+				{"kid": "ec1", "kty": "EC", "crv": "P-256", "x": base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))), "y": base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32)))},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer idp.Close()
+	issuer = idp.URL
+
+	sign := func(alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(input))
+		var signature []byte
+		if ec, ok := key.(*ecdsa.PrivateKey); ok {
+			r, s, _ := ecdsa.Sign(rand.Reader, ec, digest[:])
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		} else {
+			signature, _ = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+		}
+		return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"iss": issuer, "aud": []string{"notify", "other"}, "exp": time.Now().Add(time.Hour).Unix(), "scope": "openid notify:show"}
+		for k, v := range changes {
+			c[k] = v
+		}
+		return c
+	}
+
+	verifier := newOIDCVerifier(issuer, "notify", idp.Client())
+	if scopes, err := verifier.verify(sign("RS256", "rsa1", rsaKey, claims(nil))); err != nil || len(scopes) != 1 || scopes[0] != scopeShow {
+		t.Errorf("RS256 token: %v, %v", scopes, err)
+	}
+	if scopes, err := verifier.verify(sign("ES256", "ec1", ecKey, claims(map[string]interface{}{"scope": nil, "scp": []string{"notify:manage"}}))); err != nil || len(scopes) != 1 || scopes[0] != scopeManage {
+		t.Errorf("ES256 token with scp: %v, %v", scopes, err)
+	}
+	for name, token := range map[string]string{
+		"other key":       sign("RS256", "rsa1", otherKey, claims(nil)),
+		"unknown key":     sign("RS256", "rsa9", rsaKey, claims(nil)),
+		"bad signature":   strings.TrimSuffix(sign("RS256", "rsa1", rsaKey, claims(nil)), "x") + "x",
+		"other issuer":    sign("RS256", "rsa1", rsaKey, claims(map[string]interface{}{"iss": "https://evil.example.com"})),
+		"other audience":  sign("RS256", "rsa1", rsaKey, claims(map[string]interface{}{"aud": "someone-else"})),
+		"expired":         sign("RS256", "rsa1", rsaKey, claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"without expiry":  sign("RS256", "rsa1", rsaKey, claims(map[string]interface{}{"exp": nil})),
+		"not valid yet":   sign("RS256", "rsa1", rsaKey, claims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})),
+		"alg confusion":   sign("HS256", "rsa1", rsaKey, claims(nil)),
+		"malformed token": "a.b.c",
+	} {
+		if _, err := verifier.verify(token); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+
+	server := &notifyServer{oidc: verifier}
+	handler := server.handler()
+	req := httptest.NewRequest("GET", "/v1/status", nil)
+	req.Header.Set("Authorization", "Bearer "+sign("RS256", "rsa1", rsaKey, claims(nil)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GET /v1/status with a notify:show token: %d, want 403", rec.Code)
+	}
+	req.Header.Set("Authorization", "Bearer "+sign("RS256", "rsa1", rsaKey, claims(map[string]interface{}{"scope": "notify:manage"})))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /v1/status with a notify:manage token: %d, want 200 (%s)", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/client"
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestServeNotifications tests authorization, spec validation and the launch of a valid spec
+func TestServeNotifications(t *testing.T) {
+	var launched string
+	server := &notifyServer{
+		tokens: []serveToken{{token: "secret", scopes: []string{scopeManage}}},
+		launch: func(specPath string, done func(*NotificationResult, error)) error {
+			data, _ := os.ReadFile(specPath)
+			launched = string(data)
+			os.Remove(specPath)
+			done(&NotificationResult{Action: actionAcknowledged, Title: "Hello"}, nil)
+			return nil
+		},
+	}
+	handler := server.handler()
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		body   string
+		status int
+	}{
+		{"no token", "POST", "", "version: 1\ntitle: Hello\nmessage: World\n", http.StatusUnauthorized},
+		{"wrong token", "POST", "wrong", "version: 1\ntitle: Hello\nmessage: World\n", http.StatusUnauthorized},
+		{"wrong method", "GET", "secret", "", http.StatusMethodNotAllowed},
+		{"invalid spec", "POST", "secret", "version: 1\ntitel: Hello\nmessage: World\n", http.StatusBadRequest},
+		{"valid spec", "POST", "secret", "version: 1\ntitle: Hello\nmessage: World\n", http.StatusOK},
+	}
+	for _, tt := range tests {
+		launched = ""
+		req := httptest.NewRequest(tt.method, "/v1/notifications", strings.NewReader(tt.body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", tt.name, tt.status, rec.Code, rec.Body.String())
+		}
+		if (tt.status == http.StatusOK) != (launched != "") {
+			t.Errorf("%s: launched %q", tt.name, launched)
+		}
+	}
+}
+
+// TestCheckServeSpec tests that specs posted over HTTP cannot make this machine read or write
+// its files or send requests elsewhere
+func TestCheckServeSpec(t *testing.T) {
+	const base = "version: 1\ntitle: Hello\nmessage: World\n"
+	for _, allowed := range []string{
+		"icon: https://example.com/logo.png\n",
+		"sound: system\n",
+		"ack_file: default\n",
+		"schedule:\n  calendar: https://example.com/holidays.ics\n",
+		"builtin_icon: info\n",
+	} {
+		if err := checkServeSpec([]byte(base + allowed)); err != nil {
+			t.Errorf("%q rejected: %v", allowed, err)
+		}
+	}
+	for field, rejected := range map[string]string{
+		"follow_ups":          "follow_ups:\n  - on: timeout\n    spec: other.yaml\n",
+		"message_file":        "message_file: /etc/shadow\n",
+		"icon":                "icon: /etc/krankybearnotify/logo.png\n",
+		"https":               "icon: https://\n",
+		"state_icons.failed":  "state_icons:\n  failed: /tmp/x.png\n",
+		"font":                "font: /usr/share/fonts/a.ttf\n",
+		"sound":               "sound: /tmp/alarm.wav\n",
+		"ack_file":            "ack_file: /etc/cron.d/x\n",
+		"schedule.calendar":   "schedule:\n  calendar: /etc/holidays.ics\n",
+		"result.callback_url": "result:\n  callback_url: http://169.254.169.254/latest\n",
+		"delivery.ntfy":       "delivery:\n  ntfy: alerts@attacker.example.com\n",
+	} {
+		if err := checkServeSpec([]byte(base + rejected)); err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected %q to be rejected for %s, got %v", rejected, field, err)
+		}
+	}
+}
+
+// TestServeChoice tests that a notification answered with one of its -choices, which makes
+// notify exit with choiceExitBase plus the index, is returned as a result rather than a failure
+func TestServeChoice(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stand-in notify is a shell script")
+	}
+	exePath := filepath.Join(t.TempDir(), "notify")
+	script := fmt.Sprintf("#!/bin/sh\necho 'log line'\necho '{\"action\":\"acknowledged\",\"method\":\"fyne\",\"choice\":\"Tonight\",\"timestamp\":\"2025-03-01T09:00:00Z\"}'\nexit %d\n", choiceExitBase+1)
+	if err := os.WriteFile(exePath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	server := &notifyServer{launch: func(specPath string, done func(*NotificationResult, error)) error {
+		return launchSpec(exePath, specPath, true, false, done)
+	}}
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+
+	result, err := client.New(httpServer.URL, "").Send(context.Background(), client.Notification{Title: "Reboot", Message: "When?", Choices: []string{"Now", "Tonight"}})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if result.Action != client.ActionAcknowledged || result.Choice != "Tonight" {
+		t.Errorf("Expected the choice Tonight, got %+v", result)
+	}
+
+	os.WriteFile(exePath, []byte("#!/bin/sh\necho 'no display' >&2\nexit 1\n"), 0755)
+	if _, err := client.New(httpServer.URL, "").Send(context.Background(), client.Notification{Title: "Reboot", Message: "When?"}); err == nil || !strings.Contains(err.Error(), "no display") {
+		t.Errorf("Expected exit code 1 to fail with the error, got %v", err)
+	}
+}
+
+// TestServeRemoteSpec tests that notify serve launches its notify processes with -remote-spec,
+// so a posted title such as {{env.AWS_SECRET_ACCESS_KEY}} is not rendered into the result
+func TestServeRemoteSpec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stand-in notify is a shell script")
+	}
+	exePath := filepath.Join(t.TempDir(), "notify")
+	script := "#!/bin/sh\nprintf '{\"action\":\"acknowledged\",\"title\":\"%s\",\"timestamp\":\"2025-03-01T09:00:00Z\"}\\n' \"$*\"\n" // The arguments as the title
+	if err := os.WriteFile(exePath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	server := &notifyServer{launch: func(specPath string, done func(*NotificationResult, error)) error {
+		return launchSpec(exePath, specPath, true, false, done)
+	}}
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+
+	result, err := client.New(httpServer.URL, "").Send(context.Background(), client.Notification{Title: "{{env.KRANKYBEAR_SECRET}}", Message: "x"})
+	if err != nil || !strings.Contains(result.Title, "-remote-spec") {
+		t.Fatalf("Expected the notify process to get -remote-spec, got %+v, %v", result, err)
+	}
+	t.Setenv("KRANKYBEAR_SECRET", "hunter2")
+	n, err := notify.Notification{Title: "{{env.KRANKYBEAR_SECRET}}", RemoteSpec: true}.WithLocalTimes(time.Now())
+	if err != nil || n.Title != "{{env.KRANKYBEAR_SECRET}}" {
+		t.Errorf("Expected {{env.NAME}} not to be rendered for a -remote-spec notification, got %q, %v", n.Title, err)
+	}
+}
+
+// TestIsLoopbackAddress tests which listen addresses may run without a token
+func TestIsLoopbackAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:8787": true,
+		"localhost:8787": true,
+		"[::1]:8787":     true,
+		":8787":          false,
+		"0.0.0.0:8787":   false,
+		"10.0.0.5:8787":  false,
+	} {
+		if got := isLoopbackAddress(addr); got != want {
+			t.Errorf("isLoopbackAddress(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+// TestServeClient tests the Go client against the API: Send, Submit with Get while pending
+// and after, and Watch streaming results as notifications finish
+func TestServeClient(t *testing.T) {
+	release := make(chan struct{})
+	server := &notifyServer{
+		tokens: []serveToken{{token: "secret", scopes: []string{scopeManage}}},
+		launch: func(specPath string, done func(*NotificationResult, error)) error {
+			data, _ := os.ReadFile(specPath)
+			os.Remove(specPath)
+			spec := string(data)
+			go func() {
+				if strings.Contains(spec, "Later") {
+					<-release
+				}
+				if strings.Contains(spec, "Broken") {
+					done(nil, errors.New("notify failed: exit status 1"))
+					return
+				}
+				input := "INC-42"
+				done(&NotificationResult{Action: actionAcknowledged, Method: "fyne", Title: "Hello", Input: &input, Timestamp: time.Now().Format(time.RFC3339)}, nil)
+			}()
+			return nil
+		},
+	}
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+	ctx := context.Background()
+
+	if _, err := client.New(httpServer.URL, "wrong").Send(ctx, client.Notification{Title: "Hello"}); err == nil {
+		t.Fatal("Expected Send with a wrong token to fail")
+	} else if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 *client.Error, got %v", err)
+	}
+
+	c := client.New(httpServer.URL+"/", "secret")
+	watcher, err := c.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	defer watcher.Close()
+
+	result, err := c.Send(ctx, client.Notification{Title: "Hello", Message: "World", Timeout: client.Int(0), Input: true})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if result.Action != client.ActionAcknowledged || result.Input == nil || *result.Input != "INC-42" || result.ID == "" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	id, err := c.Submit(ctx, client.Notification{Title: "Later", Message: "World"})
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if record, err := c.Get(ctx, id); err != nil || record.Status != client.StatusPending {
+		t.Errorf("Get(pending) = %+v, %v", record, err)
+	}
+	close(release)
+
+	if _, err := c.Send(ctx, client.Notification{Title: "Broken", Message: "World"}); err == nil || !strings.Contains(err.Error(), "exit status 1") {
+		t.Errorf("Send(failing) = %v, expected the notify error", err)
+	}
+	if _, err := c.Send(ctx, client.Notification{Title: "Hello", Message: "World", Delivery: &client.Delivery{Mode: "sideways"}}); err == nil || !strings.Contains(err.Error(), "invalid spec") {
+		t.Errorf("Send(invalid) = %v, expected a spec error", err)
+	}
+
+	// The stream has the three notifications that finished, in order of finishing
+	statuses := map[string]string{}
+	for i := 0; i < 3; i++ {
+		record, err := watcher.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		statuses[record.ID] = record.Status
+	}
+	if statuses[result.ID] != client.StatusDone || statuses[id] != client.StatusDone || len(statuses) != 3 {
+		t.Errorf("Unexpected streamed records %v", statuses)
+	}
+	if record, err := c.Get(ctx, id); err != nil || record.Status != client.StatusDone || record.Result == nil || record.Result.Method != "fyne" {
+		t.Errorf("Get(done) = %+v, %v", record, err)
+	}
+	if _, err := c.Get(ctx, "unknown"); err == nil {
+		t.Error("Expected Get of an unknown ID to fail")
+	}
+}
+
+// TestServeReceipts tests that a notification ID is shown only once, across a restart of
+// notify serve, and the reconciliation of receipts
+func TestServeReceipts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "receipts.json")
+	receipts, err := openReceiptStore(path)
+	if err != nil {
+		t.Fatalf("openReceiptStore failed: %v", err)
+	}
+	launches := 0
+	server := &notifyServer{
+		receipts: receipts,
+		launch: func(specPath string, done func(*NotificationResult, error)) error {
+			os.Remove(specPath)
+			launches++
+			done(&NotificationResult{Action: actionAcknowledged, Title: "Patch", Timestamp: time.Now().Format(time.RFC3339)}, nil)
+			return nil
+		},
+	}
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+	ctx := context.Background()
+	c := client.New(httpServer.URL, "")
+
+	n := client.Notification{Title: "Patch", Message: "Tonight"}
+	if result, err := c.SendOnce(ctx, "campaign-7:alice", n); err != nil || result.Action != client.ActionAcknowledged || result.ID != "campaign-7:alice" {
+		t.Fatalf("SendOnce = %+v, %v", result, err)
+	}
+	_, err = c.SendOnce(ctx, "campaign-7:alice", n)
+	if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("SendOnce(again) = %v, want a 409 *client.Error", err)
+	}
+	if err := c.SubmitOnce(ctx, "bad id!", n); err == nil {
+		t.Error("SubmitOnce accepted an invalid ID")
+	}
+	if launches != 1 {
+		t.Errorf("Launched %d times, want once", launches)
+	}
+
+	// A notification being shown when notify serve stops is interrupted, and not shown again
+	if _, claimed, err := receipts.claim("crashed", time.Now()); !claimed || err != nil {
+		t.Fatalf("claim = %v, %v", claimed, err)
+	}
+	reopened, err := openReceiptStore(path)
+	if err != nil {
+		t.Fatalf("openReceiptStore(again) failed: %v", err)
+	}
+	server.receipts = reopened
+	reconciliation, err := c.Reconcile(ctx, []string{"campaign-7:alice", "crashed", "never-sent"})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, receipt := range reconciliation.Receipts {
+		statuses[receipt.ID] = receipt.Status
+	}
+	if statuses["campaign-7:alice"] != client.StatusDone || statuses["crashed"] != client.StatusInterrupted || len(reconciliation.Unknown) != 1 || reconciliation.Unknown[0] != "never-sent" {
+		t.Errorf("Reconcile = %+v", reconciliation)
+	}
+	if record, err := c.Get(ctx, "crashed"); err != nil || record.Status != client.StatusInterrupted {
+		t.Errorf("Get(interrupted) = %+v, %v", record, err)
+	}
+	if list, err := c.Receipts(ctx, time.Time{}); err != nil || len(list) != 2 {
+		t.Errorf("Receipts = %+v, %v", list, err)
+	}
+}
@@ -0,0 +1,736 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+	"gopkg.in/yaml.v3"
+)
+
+// notificationSpecSchema is the published JSON Schema for -spec files
+// (schema/notification-spec.schema.json, also usable by editors and CI linters)
+//
+//go:embed schema/notification-spec.schema.json
+var notificationSpecSchema []byte
+
+// NotificationSpec is a declarative notification definition loaded with -spec
+// Pointer fields distinguish "not set" from zero values so only the keys present
+// in the file are applied
+type NotificationSpec struct {
+	Version    int          `yaml:"version"`
+	Title      string       `yaml:"title"`
+	Message    string       `yaml:"message"`
+	MsgFile    string       `yaml:"message_file"` // Relative to the spec
+	HTML       string       `yaml:"html"`         // Always sanitized: -allow-unsafe-html is not available to specs
+	Button     string       `yaml:"button"`
+	Timeout    *specTimeout `yaml:"timeout"` // Seconds or "auto"
+	Width      *int         `yaml:"width"`
+	Height     *int         `yaml:"height"`
+	Autosize   *bool        `yaml:"autosize"`
+	Screenshot *bool        `yaml:"context_screenshot"`
+	Attention  string       `yaml:"attention_after"` // Go duration, e.g. "60s"
+	Mirror     *bool        `yaml:"mobile_mirror"`
+	AckFile    string       `yaml:"ack_file"`
+	RespectDND string       `yaml:"respect_dnd"`
+	OverDND    *bool        `yaml:"override_dnd"`
+	Sensitive  *bool        `yaml:"sensitive"`
+	OTP        string       `yaml:"otp"`
+	OTPExpiry  string       `yaml:"otp_expiry"` // Go duration, e.g. "5m"
+	RedactAck  *bool        `yaml:"redact_after_ack"`
+	Desktop    string       `yaml:"desktop"`
+	PositionID string       `yaml:"remember_position"`
+	Monitor    string       `yaml:"monitor"`
+	Topmost    *bool        `yaml:"topmost"`
+	Frameless  *bool        `yaml:"frameless"`
+	Fullscreen *bool        `yaml:"fullscreen"`
+	Stacking   string       `yaml:"stacking"`
+	HideTimer  *bool        `yaml:"hide_countdown"`
+	RTL        *bool        `yaml:"rtl"`
+	Reading    *int         `yaml:"reading_speed"`
+	TimeoutMin *int         `yaml:"timeout_min"`
+	TimeoutMax *int         `yaml:"timeout_max"`
+	ID         string       `yaml:"id"`
+	Replace    *bool        `yaml:"replace"`
+	Cancel     string       `yaml:"cancel"`
+	Input      *bool        `yaml:"input"`
+	InputValue string       `yaml:"input_default"`
+	InputHint  string       `yaml:"input_placeholder"`
+	Choices    []string     `yaml:"choices"`
+	Digest     []string     `yaml:"digest_items"`
+	Icon       string       `yaml:"icon"`
+	IconData   string       `yaml:"icon_data"`
+	Builtin    string       `yaml:"builtin_icon"`
+	Link       string       `yaml:"link"`
+	TimeZone   string       `yaml:"timezone"`
+	Locale     string       `yaml:"locale"`
+	Category   string       `yaml:"category"`
+	Sound      string       `yaml:"sound"`
+	Urgency    string       `yaml:"urgency"`
+	Theme      string       `yaml:"theme"`
+	BgColor    string       `yaml:"bg_color"`
+	FgColor    string       `yaml:"fg_color"`
+	Accent     string       `yaml:"accent_color"`
+	Font       string       `yaml:"font"` // Relative to the spec file
+	FontSize   *int         `yaml:"font_size"`
+	State      string       `yaml:"state"`
+	StateID    string       `yaml:"state_id"`
+	StateIcons struct {
+		Pending string `yaml:"pending"` // Relative to the spec file
+		Working string `yaml:"working"`
+		Success string `yaml:"success"`
+		Failed  string `yaml:"failed"`
+	} `yaml:"state_icons"`
+	Priority string `yaml:"priority"`
+	Token    string `yaml:"breakglass_token"`
+	Delivery struct {
+		Mode         string `yaml:"mode"`
+		Disconnected string `yaml:"disconnected"`
+		GUIOnly      *bool  `yaml:"gui_only"`
+		ForceWall    *bool  `yaml:"force_wall"`
+		Ntfy         string `yaml:"ntfy"`
+		Gotify       string `yaml:"gotify"`
+	} `yaml:"delivery"`
+	Schedule struct {
+		BusinessHours string   `yaml:"business_hours"`
+		Calendar      string   `yaml:"calendar"`
+		DeliverBy     string   `yaml:"deliver_by"`
+		At            string   `yaml:"at"`
+		Delay         string   `yaml:"delay"` // Go duration, e.g. "30m"
+		Every         string   `yaml:"every"` // Go duration, e.g. "1h"
+		Count         *int     `yaml:"count"`
+		Until         string   `yaml:"until"`
+		StopOn        []string `yaml:"stop_on"`
+	} `yaml:"schedule"`
+	Rollout struct {
+		Percent *int   `yaml:"percent"`
+		Salt    string `yaml:"salt"`
+	} `yaml:"rollout"`
+	Variants  []SpecVariant  `yaml:"variants"`
+	FollowUps []SpecFollowUp `yaml:"follow_ups"`
+	Result    struct {
+		JSON             *bool  `yaml:"json"`
+		IncludeInventory *bool  `yaml:"include_inventory"`
+		CallbackURL      string `yaml:"callback_url"`
+	} `yaml:"result"`
+
+	variant string // Name of the variant applied by applyVariant
+}
+
+// specTimeout is the timeout of a spec: seconds, or notify.TimeoutAuto for "auto"
+type specTimeout int
+
+// UnmarshalYAML reads seconds or "auto"
+func (t *specTimeout) UnmarshalYAML(node *yaml.Node) error {
+	if node.Value == "auto" {
+		*t = notify.TimeoutAuto
+		return nil
+	}
+	var seconds int
+	if err := node.Decode(&seconds); err != nil {
+		return err
+	}
+	*t = specTimeout(seconds)
+	return nil
+}
+
+// String returns the -timeout flag value of t
+func (t specTimeout) String() string {
+	if t == notify.TimeoutAuto {
+		return "auto"
+	}
+	return strconv.Itoa(int(t))
+}
+
+// SpecFollowUp is a follow-up notification launched when a notification ends with an outcome
+type SpecFollowUp struct {
+	On    string `yaml:"on"`    // Result action (acknowledged, timeout, delivered, skipped, suppressed, replaced, canceled) or "any"
+	Delay string `yaml:"delay"` // Go duration, e.g. "4h"
+	Spec  string `yaml:"spec"`  // Follow-up spec file, relative to the spec that defines it
+}
+
+// jsonSchema is the subset of JSON Schema used by the notification spec schema
+type jsonSchema struct {
+	Description          string                 `json:"description"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Items                *jsonSchema            `json:"items"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Enum                 []interface{}          `json:"enum"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	MinLength            *int                   `json:"minLength"`
+	Pattern              string                 `json:"pattern"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+}
+
+// specError is a validation problem at a position in the spec file
+type specError struct {
+	Line    int
+	Column  int
+	Path    string // Dotted field path, e.g. "rollout.percent"
+	Message string
+}
+
+// String formats the error as line:column: path: message
+func (e specError) String() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// parseSpec validates YAML spec data against the schema and decodes it
+// Follow-up spec paths are resolved against baseDir and must exist (skipped if baseDir is "")
+// Returns the validation errors (with line and column) if the spec is invalid
+func parseSpec(data []byte, baseDir string) (*NotificationSpec, []specError, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal(notificationSpecSchema, &schema); err != nil {
+		return nil, nil, fmt.Errorf("invalid embedded schema: %v", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid YAML: %v", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, []specError{{Line: 1, Column: 1, Message: "spec is empty"}}, nil
+	}
+
+	errs := validateSpecNode(doc.Content[0], &schema, "")
+	if errs = append(errs, checkSpecMessage(doc.Content[0])...); len(errs) > 0 {
+		return nil, errs, nil
+	}
+	if errs := checkSpecTimeZone(doc.Content[0]); len(errs) > 0 {
+		return nil, errs, nil
+	}
+	if errs := checkSpecVariants(doc.Content[0]); len(errs) > 0 {
+		return nil, errs, nil
+	}
+	if baseDir != "" {
+		if errs := checkFollowUpFiles(doc.Content[0], baseDir); len(errs) > 0 {
+			return nil, errs, nil
+		}
+	}
+
+	var spec NotificationSpec
+	if err := doc.Content[0].Decode(&spec); err != nil {
+		return nil, nil, fmt.Errorf("could not decode spec: %v", err)
+	}
+	return &spec, nil, nil
+}
+
+// loadSpec reads and validates a spec file
+// Validation errors are returned as a single error with one file:line:column line per problem
+func loadSpec(path string) (*NotificationSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read spec: %v", err)
+	}
+	spec, errs, err := parseSpec(data, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(errs) > 0 {
+		lines := make([]string, len(errs))
+		for i, e := range errs {
+			lines[i] = path + ":" + e.String()
+		}
+		return nil, fmt.Errorf("invalid spec:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// A calendar, font or message file is relative to the spec, like follow-up specs
+	if cal := spec.Schedule.Calendar; cal != "" && !strings.HasPrefix(cal, "http://") && !strings.HasPrefix(cal, "https://") {
+		spec.Schedule.Calendar = resolveSpecPath(filepath.Dir(path), cal)
+	}
+	if spec.Font != "" {
+		spec.Font = resolveSpecPath(filepath.Dir(path), spec.Font)
+	}
+	if spec.MsgFile != "" {
+		spec.MsgFile = resolveSpecPath(filepath.Dir(path), spec.MsgFile)
+	}
+	for _, icon := range []*string{&spec.StateIcons.Pending, &spec.StateIcons.Working, &spec.StateIcons.Success, &spec.StateIcons.Failed} {
+		if *icon != "" {
+			*icon = resolveSpecPath(filepath.Dir(path), *icon)
+		}
+	}
+	return spec, nil
+}
+
+// validateSpecNode checks a YAML node against a schema, returning every problem found
+func validateSpecNode(node *yaml.Node, schema *jsonSchema, path string) []specError {
+	var errs []specError
+	fail := func(n *yaml.Node, format string, args ...interface{}) {
+		errs = append(errs, specError{Line: n.Line, Column: n.Column, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// anyOf: the value must match one of the schemas, e.g. seconds or "auto"
+	if len(schema.AnyOf) > 0 {
+		for _, option := range schema.AnyOf {
+			if len(validateSpecNode(node, option, path)) == 0 {
+				return nil
+			}
+		}
+		expected := make([]string, len(schema.AnyOf))
+		for i, option := range schema.AnyOf {
+			expected[i] = describeSchema(option)
+		}
+		fail(node, "expected %s, got %s", strings.Join(expected, " or "), describeYAMLNode(node))
+		return errs
+	}
+
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			fail(node, "expected a mapping (key: value pairs), got %s", describeYAMLNode(node))
+			return errs
+		}
+		seen := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := key.Value
+			if path != "" {
+				childPath = path + "." + key.Value
+			}
+			if seen[key.Value] {
+				errs = append(errs, specError{Line: key.Line, Column: key.Column, Path: childPath, Message: "duplicate field"})
+				continue
+			}
+			seen[key.Value] = true
+
+			child, ok := schema.Properties[key.Value]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					msg := "unknown field"
+					if suggestion := closestSpecField(key.Value, schema.Properties); suggestion != "" {
+						msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+					}
+					errs = append(errs, specError{Line: key.Line, Column: key.Column, Path: childPath, Message: msg})
+				}
+				continue
+			}
+			errs = append(errs, validateSpecNode(value, child, childPath)...)
+		}
+		for _, name := range schema.Required {
+			if !seen[name] {
+				fail(node, "missing required field %q", name)
+			}
+		}
+
+	case "string":
+		if node.Kind != yaml.ScalarNode || node.Tag == "!!null" {
+			fail(node, "expected a string, got %s", describeYAMLNode(node))
+			return errs
+		}
+		if schema.MinLength != nil && len(node.Value) < *schema.MinLength {
+			fail(node, "must not be empty")
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(node.Value) {
+				fail(node, "invalid value %q (%s)", node.Value, schema.Description)
+			}
+		}
+
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			fail(node, "expected an integer, got %s", describeYAMLNode(node))
+			return errs
+		}
+		value, _ := strconv.ParseFloat(node.Value, 64)
+		if schema.Minimum != nil && value < *schema.Minimum {
+			fail(node, "must be at least %v (got %s)", *schema.Minimum, node.Value)
+		}
+		if schema.Maximum != nil && value > *schema.Maximum {
+			fail(node, "must be at most %v (got %s)", *schema.Maximum, node.Value)
+		}
+
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			fail(node, "expected a list, got %s", describeYAMLNode(node))
+			return errs
+		}
+		if schema.Items != nil {
+			for i, item := range node.Content {
+				errs = append(errs, validateSpecNode(item, schema.Items, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			fail(node, "expected true or false, got %s", describeYAMLNode(node))
+			return errs
+		}
+	}
+
+	if len(schema.Enum) > 0 && node.Kind == yaml.ScalarNode {
+		var allowed []string
+		match := false
+		for _, v := range schema.Enum {
+			s := fmt.Sprint(v)
+			allowed = append(allowed, s)
+			if s == node.Value {
+				match = true
+			}
+		}
+		if !match {
+			fail(node, "must be one of %s (got %q)", strings.Join(allowed, ", "), node.Value)
+		}
+	}
+	return errs
+}
+
+// checkFollowUpFiles checks that every follow_ups[].spec file exists relative to baseDir
+func checkFollowUpFiles(root *yaml.Node, baseDir string) []specError {
+	var errs []specError
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "follow_ups" {
+			continue
+		}
+		for n, item := range root.Content[i+1].Content {
+			for j := 0; j+1 < len(item.Content); j += 2 {
+				if item.Content[j].Value != "spec" {
+					continue
+				}
+				value := item.Content[j+1]
+				if _, err := os.Stat(resolveSpecPath(baseDir, value.Value)); err != nil {
+					errs = append(errs, specError{Line: value.Line, Column: value.Column,
+						Path: fmt.Sprintf("follow_ups[%d].spec", n), Message: fmt.Sprintf("file %q not found", value.Value)})
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// checkSpecTimeZone checks that the timezone is a zone the embedded database knows
+func checkSpecTimeZone(root *yaml.Node) []specError {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "timezone" {
+			continue
+		}
+		value := root.Content[i+1]
+		if _, err := notify.LoadTimeZone(value.Value); err != nil {
+			return []specError{{Line: value.Line, Column: value.Column, Path: "timezone", Message: err.Error()}}
+		}
+	}
+	return nil
+}
+
+// checkSpecMessage checks that a spec has either message or message_file, which the schema
+// cannot require on its own
+func checkSpecMessage(root *yaml.Node) []specError {
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	var message, file *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case "message":
+			message = root.Content[i]
+		case "message_file":
+			file = root.Content[i]
+		}
+	}
+	switch {
+	case message == nil && file == nil:
+		return []specError{{Line: root.Line, Column: root.Column, Message: `missing required field "message" (or "message_file")`}}
+	case message != nil && file != nil:
+		return []specError{{Line: file.Line, Column: file.Column, Path: "message_file", Message: "use either message or message_file, not both"}}
+	}
+	return nil
+}
+
+// resolveSpecPath resolves a spec path relative to the directory of the spec that references it
+func resolveSpecPath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// describeSchema describes the values a schema accepts for error messages
+func describeSchema(schema *jsonSchema) string {
+	if len(schema.Enum) > 0 {
+		values := make([]string, len(schema.Enum))
+		for i, v := range schema.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		return strings.Join(values, ", ")
+	}
+	switch schema.Type {
+	case "integer":
+		return "an integer"
+	case "boolean":
+		return "true or false"
+	case "array":
+		return "a list"
+	case "object":
+		return "a mapping"
+	}
+	return "a string"
+}
+
+// describeYAMLNode describes a node's type for error messages
+func describeYAMLNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch node.Tag {
+	case "!!null":
+		return "nothing"
+	case "!!int", "!!float":
+		return "number " + node.Value
+	case "!!bool":
+		return node.Value
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
+
+// closestSpecField suggests the known field nearest to a misspelled one, or "" if none is close
+func closestSpecField(name string, properties map[string]*jsonSchema) string {
+	names := make([]string, 0, len(properties))
+	for known := range properties {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", 3 // Only suggest fields within two edits
+	for _, known := range names {
+		if d := editDistance(strings.ToLower(name), known); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// flagValues maps the fields present in the spec to command-line flag values
+func (s *NotificationSpec) flagValues() map[string]string {
+	values := map[string]string{}
+	setString := func(name, value string) {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	setInt := func(name string, value *int) {
+		if value != nil {
+			values[name] = strconv.Itoa(*value)
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			values[name] = strconv.FormatBool(*value)
+		}
+	}
+
+	setString("title", s.Title)
+	setString("message", s.Message)
+	setString("message-file", s.MsgFile)
+	setString("html", s.HTML)
+	setString("button", s.Button)
+	if s.Timeout != nil {
+		values["timeout"] = s.Timeout.String()
+	}
+	setInt("width", s.Width)
+	setInt("height", s.Height)
+	setBool("autosize", s.Autosize)
+	setBool("context-screenshot", s.Screenshot)
+	setString("attention-after", s.Attention)
+	setBool("mobile-mirror", s.Mirror)
+	setString("ack-file", s.AckFile)
+	setString("respect-dnd", s.RespectDND)
+	setBool("override-dnd", s.OverDND)
+	setBool("sensitive", s.Sensitive)
+	setString("otp", s.OTP)
+	setString("otp-expiry", s.OTPExpiry)
+	setBool("redact-after-ack", s.RedactAck)
+	setString("desktop", s.Desktop)
+	setString("remember-position", s.PositionID)
+	setString("monitor", s.Monitor)
+	setBool("topmost", s.Topmost)
+	setBool("frameless", s.Frameless)
+	setBool("fullscreen", s.Fullscreen)
+	setString("stacking", s.Stacking)
+	setBool("hide-countdown", s.HideTimer)
+	setBool("rtl", s.RTL)
+	setInt("reading-speed", s.Reading)
+	setInt("timeout-min", s.TimeoutMin)
+	setInt("timeout-max", s.TimeoutMax)
+	setString("id", s.ID)
+	setBool("replace", s.Replace)
+	setString("cancel", s.Cancel)
+	setBool("input", s.Input)
+	setString("input-default", s.InputValue)
+	setString("input-placeholder", s.InputHint)
+	setString("choices", strings.Join(s.Choices, ","))
+	setString("digest-items", strings.Join(s.Digest, "\n"))
+	setString("icon", s.Icon)
+	setString("icon-data", s.IconData)
+	setString("builtin-icon", s.Builtin)
+	setString("link", s.Link)
+	setString("tz", s.TimeZone)
+	setString("locale", s.Locale)
+	setString("category", s.Category)
+	setString("sound", s.Sound)
+	setString("urgency", s.Urgency)
+	setString("theme", s.Theme)
+	setString("bg-color", s.BgColor)
+	setString("fg-color", s.FgColor)
+	setString("accent-color", s.Accent)
+	setString("font", s.Font)
+	setInt("font-size", s.FontSize)
+	setString("state", s.State)
+	setString("state-id", s.StateID)
+	stateIcons := map[string]string{}
+	for state, icon := range map[string]string{
+		notify.StatePending: s.StateIcons.Pending,
+		notify.StateWorking: s.StateIcons.Working,
+		notify.StateSuccess: s.StateIcons.Success,
+		notify.StateFailed:  s.StateIcons.Failed,
+	} {
+		if icon != "" {
+			stateIcons[state] = icon
+		}
+	}
+	setString("state-icons", notify.FormatStateIcons(stateIcons))
+	setString("priority", s.Priority)
+	setString("breakglass-token", s.Token)
+
+	switch s.Delivery.Mode {
+	case "quick":
+		values["quick"] = "true"
+	case "native":
+		values["native"] = "true"
+	case "webview":
+		values["force-webview"] = "true"
+	case "basic":
+		values["win-basic"] = "true"
+	}
+	setString("disconnected", s.Delivery.Disconnected)
+	setBool("gui-only", s.Delivery.GUIOnly)
+	setBool("force-wall", s.Delivery.ForceWall)
+	setString("ntfy", s.Delivery.Ntfy)
+	setString("gotify", s.Delivery.Gotify)
+
+	setString("business-hours", s.Schedule.BusinessHours)
+	setString("calendar", s.Schedule.Calendar)
+	setString("deliver-by", s.Schedule.DeliverBy)
+	setString("at", s.Schedule.At)
+	setString("delay", s.Schedule.Delay)
+	setString("every", s.Schedule.Every)
+	setInt("count", s.Schedule.Count)
+	setString("until", s.Schedule.Until)
+	setString("stop-on", strings.Join(s.Schedule.StopOn, ","))
+
+	setInt("rollout-percent", s.Rollout.Percent)
+	setString("rollout-salt", s.Rollout.Salt)
+
+	setBool("result-json", s.Result.JSON)
+	setBool("include-inventory", s.Result.IncludeInventory)
+	setString("callback-url", s.Result.CallbackURL)
+	return values
+}
+
+// applySpec loads the spec file and sets every flag it defines that was not
+// given explicitly on the command line (command-line flags override the spec)
+func applySpec(fs *flag.FlagSet, path string) (*NotificationSpec, error) {
+	spec, err := loadSpec(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.Variants) > 0 {
+		// A -rollout-salt on the command line names the campaign, like it does for the rollout
+		salt := spec.Rollout.Salt
+		if f := fs.Lookup("rollout-salt"); f != nil && f.Value.String() != "" {
+			salt = f.Value.String()
+		}
+		spec.applyVariant(getMachineID(), salt)
+	}
+	if err := setUnsetFlags(fs, spec.flagValues(), "spec"); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// setUnsetFlags sets each flag in values that has not been set yet, on the command
+// line or by an earlier source; source names the origin in errors
+func setUnsetFlags(fs *flag.FlagSet, values map[string]string, source string) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	// -image is an alias for -icon, and -icon-data and -builtin-icon replace it
+	if explicit["image"] || explicit["icon-data"] || explicit["builtin-icon"] {
+		explicit["icon"] = true
+	}
+	if explicit["builtin-icon"] {
+		explicit["icon-data"] = true
+	}
+
+	for name, value := range values {
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s value for -%s: %v", source, name, err)
+		}
+	}
+	return nil
+}
+
+// validateSpecFiles implements "notify validate-spec": lints spec files without
+// showing anything, for CI pipelines over repositories of notification definitions
+// Returns the process exit code
+func validateSpecFiles(paths []string) int {
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: notify validate-spec <file.yaml> [file.yaml...]")
+		return 2
+	}
+	failed := 0
+	for _, path := range paths {
+		if _, err := loadSpec(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: OK\n", path)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestParseSpecValid tests that a complete spec validates and decodes
+func TestParseSpecValid(t *testing.T) {
+	data := []byte(`version: 1
+title: Maintenance
+message: Reboot at 22:00
+timeout: 30
+autosize: true
+delivery:
+  mode: quick
+  disconnected: skip
+rollout:
+  percent: 25
+  salt: march-reboots
+result:
+  json: true
+`)
+	spec, errs, err := parseSpec(data, "")
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("Expected valid spec, got err=%v errs=%v", err, errs)
+	}
+	if spec.Title != "Maintenance" || *spec.Timeout != 30 || spec.Delivery.Mode != "quick" || *spec.Rollout.Percent != 25 {
+		t.Errorf("Spec decoded incorrectly: %+v", spec)
+	}
+	if spec.Width != nil {
+		t.Errorf("Expected unset width to stay nil")
+	}
+
+	spec, errs, err = parseSpec([]byte("version: 1\ntitle: Policy\nmessage: Read me\ntimeout: auto\n"), "")
+	if err != nil || len(errs) > 0 || spec.Timeout == nil || spec.flagValues()["timeout"] != "auto" {
+		t.Errorf("Expected timeout: auto to set -timeout auto, got err=%v errs=%v", err, errs)
+	}
+
+	spec, errs, err = parseSpec([]byte("version: 1\ntitle: Policy\nmessage_file: policy.txt\n"), "")
+	if err != nil || len(errs) > 0 || spec.flagValues()["message-file"] != "policy.txt" {
+		t.Errorf("Expected message_file to replace message, got err=%v errs=%v", err, errs)
+	}
+	_, errs, _ = parseSpec([]byte("version: 1\ntitle: Policy\nmessage: Read me\nmessage_file: policy.txt\n"), "")
+	if len(errs) != 1 || !strings.Contains(errs[0].String(), "message_file: use either message or message_file") {
+		t.Errorf("Expected message with message_file to fail, got %v", errs)
+	}
+}
+
+// TestParseSpecCompactJSON tests that JSON specs need no spaces after the colons
+func TestParseSpecCompactJSON(t *testing.T) {
+	spec, errs, err := parseSpec([]byte(`{"version":1,"title":"Maintenance","message":"Tonight","timeout":0,"choices":["Now","Later"]}`), "")
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("parseSpec failed: %v %v", err, errs)
+	}
+	if spec.Title != "Maintenance" || spec.Timeout == nil || *spec.Timeout != 0 || len(spec.Choices) != 2 {
+		t.Errorf("Unexpected spec %+v", spec)
+	}
+}
+
+// TestParseSpecErrors tests that validation errors point at the offending field
+func TestParseSpecErrors(t *testing.T) {
+	data := []byte(`title: Maintenance
+mesage: Reboot at 22:00
+timeout: soon
+rollout:
+  percent: 150
+delivery:
+  mode: fast
+`)
+	_, errs, err := parseSpec(data, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.String())
+	}
+	joined := strings.Join(got, "\n")
+	t.Logf("Validation errors:\n%s", joined)
+
+	for _, want := range []string{
+		`2:1: mesage: unknown field (did you mean "message"?)`,
+		`3:10: timeout: expected an integer or auto, got "soon"`,
+		`5:12: rollout.percent: must be at most 100 (got 150)`,
+		`7:9: delivery.mode: must be one of auto, quick, native, webview, basic (got "fast")`,
+		`missing required field "message"`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected error %q", want)
+		}
+	}
+}
+
+// TestApplySpecFlagsOverride tests that command-line flags take precedence over the spec
+func TestApplySpecFlagsOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notification.yaml")
+	if err := os.WriteFile(path, []byte("title: From spec\nmessage: Spec message\ntimeout: 30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var n notify.Notification
+	fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+	notify.BindFlags(fs, &n)
+	if err := fs.Parse([]string{"-title", "From flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applySpec(fs, path); err != nil {
+		t.Fatalf("applySpec failed: %v", err)
+	}
+
+	if n.Title != "From flag" {
+		t.Errorf("Expected command-line title to win, got %q", n.Title)
+	}
+	if n.Message != "Spec message" || n.Timeout != 30 {
+		t.Errorf("Expected spec values for unset flags, got %+v", n)
+	}
+}
+
+// TestParseSpecFollowUps tests follow-up validation, including missing follow-up files
+func TestParseSpecFollowUps(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reminder.yaml"), []byte("title: Reminder\nmessage: Still pending\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`title: Maintenance
+message: Reboot at 22:00
+follow_ups:
+  - on: timeout
+    delay: 4h
+    spec: reminder.yaml
+  - on: acknowledged
+    delay: soon
+    spec: missing.yaml
+`)
+	_, errs, err := parseSpec(data, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].String(), "follow_ups[1].delay") {
+		t.Fatalf("Expected a delay error for the second follow-up, got %v", errs)
+	}
+
+	data = []byte(strings.Replace(string(data), "delay: soon", "delay: 1h30m", 1))
+	_, errs, _ = parseSpec(data, dir)
+	if len(errs) != 1 || errs[0].String() != `9:11: follow_ups[1].spec: file "missing.yaml" not found` {
+		t.Fatalf("Expected a missing file error, got %v", errs)
+	}
+}
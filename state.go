@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// runState handles "notify state [-message TEXT] ID STATE": changes the state icon of the
+// window shown with -state-id ID, and its message with -message; it fails when no window of
+// this user is showing ID (it was closed, or never opened)
+func runState(args []string) int {
+	fs := flag.NewFlagSet("state", flag.ExitOnError)
+	message := fs.String("message", "", "New message of the window (default: keep it)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: notify state [-message TEXT] ID pending|working|success|failed")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	if err := notify.UpdateState(fs.Arg(0), fs.Arg(1), *message); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
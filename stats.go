@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// VariantStats counts the results of one A/B variant
+type VariantStats struct {
+	Variant      string  `json:"variant"`
+	Results      int     `json:"results"`
+	Acknowledged int     `json:"acknowledged"`
+	TimedOut     int     `json:"timeout"`
+	Other        int     `json:"other"`    // Delivered, skipped or suppressed: no acknowledgment to count
+	AckRate      float64 `json:"ack_rate"` // Acknowledged out of acknowledged plus timed out
+}
+
+// runStats handles "notify stats [FILE...]": acknowledgment rates per A/B variant from
+// -result-json output collected from many machines (stdin when no file is given)
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the statistics as JSON")
+	fs.Parse(args)
+
+	var results []NotificationResult
+	if fs.NArg() == 0 {
+		results = readResults(os.Stdin, "stdin")
+	}
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		results = append(results, readResults(f, path)...)
+		f.Close()
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no results found (expected -result-json lines)")
+		return 1
+	}
+
+	stats := variantStats(results)
+	if *jsonOutput {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	fmt.Printf("%-20s %8s %8s %8s %8s %9s\n", "VARIANT", "RESULTS", "ACKED", "TIMEOUT", "OTHER", "ACK RATE")
+	for _, s := range stats {
+		fmt.Printf("%-20s %8d %8d %8d %8d %8.1f%%\n", s.Variant, s.Results, s.Acknowledged, s.TimedOut, s.Other, s.AckRate*100)
+	}
+	return 0
+}
+
+// readResults reads the result lines from r, skipping lines that are not results
+// (the collected output may include other text)
+func readResults(r io.Reader, source string) []NotificationResult {
+	var results []NotificationResult
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // Results with screenshots are large
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var result NotificationResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil || result.Action == "" {
+			continue
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", source, err)
+	}
+	return results
+}
+
+// variantStats groups results by variant, sorted by name ("(none)" for results without one)
+func variantStats(results []NotificationResult) []VariantStats {
+	byVariant := map[string]*VariantStats{}
+	for _, result := range results {
+		name := result.Variant
+		if name == "" {
+			name = "(none)"
+		}
+		s, ok := byVariant[name]
+		if !ok {
+			s = &VariantStats{Variant: name}
+			byVariant[name] = s
+		}
+		s.Results++
+		switch result.Action {
+		case actionAcknowledged:
+			s.Acknowledged++
+		case actionTimeout:
+			s.TimedOut++
+		default:
+			s.Other++
+		}
+	}
+
+	stats := make([]VariantStats, 0, len(byVariant))
+	for _, s := range byVariant {
+		if answered := s.Acknowledged + s.TimedOut; answered > 0 {
+			s.AckRate = float64(s.Acknowledged) / float64(answered)
+		}
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Variant < stats[j].Variant
+	})
+	return stats
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
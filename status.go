@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// maxStatusRecent is how many recent (non-pending) notifications notify status lists
+const maxStatusRecent = 5
+
+// StatusReport is what notify status shows to the current user
+type StatusReport struct {
+	Version          string               `json:"version"`
+	User             string               `json:"user"`
+	MachineID        string               `json:"machine_id"`
+	GUIAvailable     bool                 `json:"gui_available"`
+	WebViewAvailable bool                 `json:"webview_available"`
+	Elevation        string               `json:"elevation"`
+	Agent            string               `json:"agent"`
+	QuietHours       string               `json:"quiet_hours"`
+	WaitingFollowUps []string             `json:"waiting_follow_ups"`
+	Pending          []StoredNotification `json:"pending"`
+	Recent           []StoredNotification `json:"recent"`
+	StoreError       string               `json:"store_error,omitempty"`
+}
+
+// showStatus implements "notify status": a read-only summary any user can run to see
+// whether notifications can be shown to them and what is pending for their session
+// Returns the process exit code
+func showStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the status as JSON")
+	fs.Parse(args)
+
+	report := collectStatus()
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	fmt.Printf("KrankyBear Notify v%s\n\n", report.Version)
+	fmt.Printf("User:           %s\n", report.User)
+	fmt.Printf("Machine ID:     %s\n", report.MachineID)
+	fmt.Printf("GUI available:  %s\n", yesNo(report.GUIAvailable))
+	fmt.Printf("WebView:        %s\n", yesNo(report.WebViewAvailable))
+	fmt.Printf("Elevation:      %s\n", notify.DescribeElevationState(report.Elevation))
+	fmt.Printf("Agent:          %s\n", report.Agent)
+	fmt.Printf("Quiet hours:    %s\n", report.QuietHours)
+
+	fmt.Printf("\nScheduled follow-ups (%d):\n", len(report.WaitingFollowUps))
+	for _, spec := range report.WaitingFollowUps {
+		fmt.Printf("  %s\n", spec)
+	}
+
+	if report.StoreError != "" {
+		fmt.Printf("\nNotification store: %s\n", report.StoreError)
+		return 0
+	}
+	fmt.Printf("\nPending notifications (%d):\n", len(report.Pending))
+	for _, item := range report.Pending {
+		fmt.Printf("  %s  %-14s  %s\n", item.Received.Format("2006-01-02 15:04"), item.describeState(), item.Title)
+	}
+	if len(report.Pending) > 0 {
+		fmt.Println("  Open them with: notify inbox")
+	}
+	fmt.Printf("\nRecent notifications:\n")
+	if len(report.Recent) == 0 {
+		fmt.Println("  none")
+	}
+	for _, item := range report.Recent {
+		fmt.Printf("  %s  %-14s  %s\n", item.Received.Format("2006-01-02 15:04"), item.describeState(), item.Title)
+	}
+	return 0
+}
+
+// collectStatus gathers the status report without changing anything
+func collectStatus() StatusReport {
+	report := StatusReport{
+		Version:      appVersion,
+		MachineID:    getMachineID(),
+		GUIAvailable: notify.IsGUIAvailable(),
+		Elevation:    notify.ElevationState(),
+		// notify has no resident agent: every notification is its own short-lived process
+		Agent:      "none (notify runs on demand, nothing to check)",
+		QuietHours: "none configured (notifications are shown immediately)",
+	}
+	if u, err := user.Current(); err == nil {
+		report.User = u.Username
+	}
+	report.WebViewAvailable, _ = notify.DetectWebViewRuntime()
+	report.WebViewAvailable = report.WebViewAvailable && notify.WebViewCompiledIn
+	report.WaitingFollowUps = findWaitingFollowUps()
+
+	items, err := loadStore()
+	if err != nil {
+		report.StoreError = err.Error()
+		return report
+	}
+	report.Pending = []StoredNotification{}
+	report.Recent = []StoredNotification{}
+	for _, item := range items {
+		if item.isPending() {
+			report.Pending = append(report.Pending, item)
+		} else if len(report.Recent) < maxStatusRecent {
+			report.Recent = append(report.Recent, item)
+		}
+	}
+	return report
+}
+
+// findWaitingFollowUps lists the spec files of follow-up processes that are still
+// waiting for their delay (started with -followup-delay)
+func findWaitingFollowUps() []string {
+	var commandLines []string
+	switch runtime.GOOS {
+	case "linux":
+		entries, _ := filepath.Glob("/proc/[0-9]*/cmdline")
+		for _, entry := range entries {
+			if data, err := os.ReadFile(entry); err == nil {
+				commandLines = append(commandLines, strings.ReplaceAll(string(data), "\x00", " "))
+			}
+		}
+	case "windows":
+		output, err := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+			"Get-CimInstance Win32_Process | Where-Object { $_.CommandLine -like '*-followup-delay*' } | ForEach-Object { $_.CommandLine }").Output()
+		if err == nil {
+			commandLines = strings.Split(string(output), "\n")
+		}
+	default:
+		if output, err := exec.Command("ps", "-axww", "-o", "args=").Output(); err == nil {
+			commandLines = strings.Split(string(output), "\n")
+		}
+	}
+
+	followUps := []string{}
+	for _, cmdline := range commandLines {
+		if !strings.Contains(cmdline, "-followup-delay") {
+			continue
+		}
+		fields := strings.Fields(cmdline)
+		for i, field := range fields {
+			if strings.Trim(field, `"`) == "-spec" && i+1 < len(fields) {
+				followUps = append(followUps, strings.Trim(fields[i+1], `"`))
+			}
+		}
+	}
+	return followUps
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCollectStatusSplitsPending tests that the status report separates pending and recent notifications
+func TestCollectStatusSplitsPending(t *testing.T) {
+	useTempStore(t)
+
+	err := modifyStore(func(items []StoredNotification) []StoredNotification {
+		for i := 0; i < maxStatusRecent+3; i++ {
+			items = append(items, StoredNotification{
+				ID:       newNotificationID(),
+				Title:    "Done",
+				Received: time.Now().Add(-time.Duration(i) * time.Minute),
+				State:    inboxStateAcknowledged,
+			})
+		}
+		return append(items, StoredNotification{ID: newNotificationID(), Title: "Todo", Received: time.Now(), State: inboxStateUnread})
+	})
+	if err != nil {
+		t.Fatalf("modifyStore failed: %v", err)
+	}
+
+	report := collectStatus()
+	if report.StoreError != "" {
+		t.Fatalf("Unexpected store error: %s", report.StoreError)
+	}
+	if len(report.Pending) != 1 || report.Pending[0].Title != "Todo" {
+		t.Errorf("Expected one pending notification, got %+v", report.Pending)
+	}
+	if len(report.Recent) != maxStatusRecent {
+		t.Errorf("Expected %d recent notifications, got %d", maxStatusRecent, len(report.Recent))
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Inbox states for stored notifications
+const (
+	inboxStateUnread       = "unread"       // Displayed but not acknowledged (e.g. timed out)
+	inboxStateRead         = "read"         // Opened from the inbox
+	inboxStateAcknowledged = "acknowledged" // Acknowledged by the user
+	inboxStateSnoozed      = "snoozed"      // Snoozed from the inbox until SnoozedUntil, then shown again as unread
+)
+
+// redactedMessage replaces the message of -sensitive notifications in the store, and of
+// -redact-after-ack notifications once acknowledged
+const redactedMessage = "(message hidden)"
+
+// maxStoredNotifications caps the local store so it never grows without bound
+const maxStoredNotifications = 200
+
+// StoredNotification is a notification recorded in the local per-user store
+type StoredNotification struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Message      string    `json:"message"`
+	Category     string    `json:"category,omitempty"`
+	Received     time.Time `json:"received"`
+	State        string    `json:"state"`
+	Action       string    `json:"action,omitempty"`
+	Method       string    `json:"method,omitempty"`
+	SnoozedUntil time.Time `json:"snoozed_until,omitempty"`
+	Link         string    `json:"link,omitempty"`         // -link or the first link of the message, opened from the inbox or by a toast click
+	CallbackURL  string    `json:"callback_url,omitempty"` // -callback-url, posted to by notify activate
+}
+
+// isPending reports whether the notification still needs the user's attention
+func (n StoredNotification) isPending() bool {
+	switch n.State {
+	case inboxStateUnread:
+		return true
+	case inboxStateSnoozed:
+		return time.Now().After(n.SnoozedUntil)
+	}
+	return false
+}
+
+// describeState returns a short human-readable state for list views
+func (n StoredNotification) describeState() string {
+	if n.State == inboxStateSnoozed {
+		if time.Now().After(n.SnoozedUntil) {
+			return "snooze expired"
+		}
+		return "snoozed until " + n.SnoozedUntil.Format("15:04")
+	}
+	return n.State
+}
+
+// getStorePath returns the location of the local notification store for the current user
+func getStorePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(configDir, "krankybearnotify", "notifications.json"), nil
+}
+
+// loadStore reads all stored notifications, newest first
+func loadStore() ([]StoredNotification, error) {
+	storePath, err := getStorePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []StoredNotification
+	data, err := os.ReadFile(storePath)
+	if os.IsNotExist(err) {
+		return items, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read notification store: %v", err)
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("could not parse notification store %s: %v", storePath, err)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Received.After(items[j].Received)
+	})
+	return items, nil
+}
+
+// modifyStore applies fn to the stored notifications and writes the result back
+// A lock file serializes concurrent notify processes writing to the same store
+func modifyStore(fn func(items []StoredNotification) []StoredNotification) error {
+	storePath, err := getStorePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(storePath), 0700); err != nil {
+		return fmt.Errorf("could not create store directory: %v", err)
+	}
+
+	unlock, err := lockFile(storePath + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	items, err := loadStore()
+	if err != nil {
+		return err
+	}
+	items = fn(items)
+
+	// Newest first, and drop the oldest entries beyond the cap
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Received.After(items[j].Received)
+	})
+	if len(items) > maxStoredNotifications {
+		items = items[:maxStoredNotifications]
+	}
+
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode notification store: %v", err)
+	}
+
+	// Write to a temp file and rename so readers never see a partial file
+	tmpPath := storePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("could not write notification store: %v", err)
+	}
+	return os.Rename(tmpPath, storePath)
+}
+
+// lockFile takes an exclusive lock by creating lockPath, waiting up to 5 seconds
+// Locks older than 30 seconds are assumed to belong to a crashed process and are removed
+func lockFile(lockPath string) (func(), error) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > 30*time.Second {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", lockPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// newNotificationID returns a random identifier for a stored notification
+func newNotificationID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// recordDelivery adds a notification that is about to be displayed to the local store
+// Returns the stored notification's ID, or "" if it could not be recorded
+func recordDelivery(title, message, category, link string) string {
+	item := StoredNotification{
+		ID:       newNotificationID(),
+		Title:    title,
+		Message:  message,
+		Category: category,
+		Received: time.Now(),
+		State:    inboxStateUnread,
+		Link:     link,
+	}
+	err := modifyStore(func(items []StoredNotification) []StoredNotification {
+		return append(items, item)
+	})
+	if err != nil {
+		log.Printf("Warning: Could not record notification in local store: %v", err)
+		return ""
+	}
+	return item.ID
+}
+
+// updateStoredNotification applies fn to the stored notification with the given ID
+func updateStoredNotification(id string, fn func(item *StoredNotification)) error {
+	found := false
+	err := modifyStore(func(items []StoredNotification) []StoredNotification {
+		for i := range items {
+			if items[i].ID == id {
+				fn(&items[i])
+				found = true
+			}
+		}
+		return items
+	})
+	if err == nil && !found {
+		return fmt.Errorf("notification %s not found in store", id)
+	}
+	return err
+}
+
+// wakeSnoozed marks the snoozed notifications whose snooze ended by now unread again, and
+// returns them to be shown again; the store lock makes sure only one process shows each
+func wakeSnoozed(now time.Time) ([]StoredNotification, error) {
+	items, err := loadStore()
+	if err != nil {
+		return nil, err
+	}
+	due := false
+	for _, item := range items {
+		due = due || item.State == inboxStateSnoozed && !now.Before(item.SnoozedUntil)
+	}
+	if !due {
+		return nil, nil // Nothing to write
+	}
+
+	var woken []StoredNotification
+	err = modifyStore(func(items []StoredNotification) []StoredNotification {
+		for i := range items {
+			if items[i].State == inboxStateSnoozed && !now.Before(items[i].SnoozedUntil) {
+				items[i].State = inboxStateUnread
+				items[i].SnoozedUntil = time.Time{}
+				woken = append(woken, items[i])
+			}
+		}
+		return items
+	})
+	return woken, err
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// useTempStore points the local store at a temporary config directory
+func useTempStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // Linux
+	t.Setenv("HOME", dir)            // macOS
+	t.Setenv("AppData", dir)         // Windows
+}
+
+// TestStoreRecordAndUpdate tests recording a delivery and acknowledging it
+func TestStoreRecordAndUpdate(t *testing.T) {
+	useTempStore(t)
+
+	id := recordDelivery("Title", "Message", "newsletter", "")
+	if id == "" {
+		t.Fatal("Expected recordDelivery to return an ID")
+	}
+
+	items, err := loadStore()
+	if err != nil {
+		t.Fatalf("loadStore failed: %v", err)
+	}
+	if len(items) != 1 || items[0].State != inboxStateUnread || !items[0].isPending() {
+		t.Fatalf("Expected one pending unread notification, got %+v", items)
+	}
+	if items[0].Category != "newsletter" {
+		t.Errorf("Expected category newsletter, got %q", items[0].Category)
+	}
+
+	err = updateStoredNotification(id, func(item *StoredNotification) {
+		item.State = inboxStateAcknowledged
+	})
+	if err != nil {
+		t.Fatalf("updateStoredNotification failed: %v", err)
+	}
+
+	items, _ = loadStore()
+	if items[0].isPending() {
+		t.Errorf("Expected acknowledged notification to no longer be pending")
+	}
+
+	if err := updateStoredNotification("missing", func(item *StoredNotification) {}); err == nil {
+		t.Error("Expected an error when updating an unknown notification")
+	}
+}
+
+// TestStoreCap tests that the store drops the oldest notifications beyond the cap
+func TestStoreCap(t *testing.T) {
+	useTempStore(t)
+
+	err := modifyStore(func(items []StoredNotification) []StoredNotification {
+		for i := 0; i < maxStoredNotifications+10; i++ {
+			items = append(items, StoredNotification{
+				ID:       newNotificationID(),
+				Received: time.Now().Add(time.Duration(i) * time.Minute),
+				State:    inboxStateUnread,
+			})
+		}
+		return items
+	})
+	if err != nil {
+		t.Fatalf("modifyStore failed: %v", err)
+	}
+
+	items, _ := loadStore()
+	if len(items) != maxStoredNotifications {
+		t.Fatalf("Expected %d notifications, got %d", maxStoredNotifications, len(items))
+	}
+	if !items[0].Received.After(items[len(items)-1].Received) {
+		t.Error("Expected notifications to be sorted newest first")
+	}
+}
+
+// TestSnoozedPending tests that snoozed notifications become pending again after the snooze
+func TestSnoozedPending(t *testing.T) {
+	snoozed := StoredNotification{State: inboxStateSnoozed, SnoozedUntil: time.Now().Add(time.Hour)}
+	if snoozed.isPending() {
+		t.Error("Expected snoozed notification to not be pending yet")
+	}
+	expired := StoredNotification{State: inboxStateSnoozed, SnoozedUntil: time.Now().Add(-time.Minute)}
+	if !expired.isPending() {
+		t.Error("Expected expired snooze to be pending")
+	}
+}
+
+// TestWakeSnoozed tests that snoozes that have ended are woken once, as unread, and others are left
+func TestWakeSnoozed(t *testing.T) {
+	useTempStore(t)
+
+	due := recordDelivery("Patch", "Reboot tonight", "", "https://example.com/patch")
+	later := recordDelivery("Survey", "Tell us", "", "")
+	now := time.Now()
+	updateStoredNotification(due, func(item *StoredNotification) {
+		item.State, item.SnoozedUntil = inboxStateSnoozed, now.Add(-time.Minute)
+	})
+	updateStoredNotification(later, func(item *StoredNotification) {
+		item.State, item.SnoozedUntil = inboxStateSnoozed, now.Add(time.Hour)
+	})
+
+	woken, err := wakeSnoozed(now)
+	if err != nil {
+		t.Fatalf("wakeSnoozed failed: %v", err)
+	}
+	if len(woken) != 1 || woken[0].ID != due || woken[0].State != inboxStateUnread || woken[0].Link != "https://example.com/patch" {
+		t.Fatalf("Expected only the due notification woken as unread with its link, got %+v", woken)
+	}
+	if woken, _ := wakeSnoozed(now); len(woken) != 0 {
+		t.Errorf("Expected a snooze to be woken only once, got %+v", woken)
+	}
+	items, _ := loadStore()
+	for _, item := range items {
+		if item.ID == later && item.State != inboxStateSnoozed {
+			t.Errorf("Expected the later snooze to stay snoozed, got %s", item.State)
+		}
+	}
+}
+
+// TestReportRedactAfterAck tests that -redact-after-ack blanks the stored message on
+// acknowledgment only, so a timed-out notification can still be read in the inbox
+func TestReportRedactAfterAck(t *testing.T) {
+	useTempStore(t)
+
+	acknowledged := resultReporter{redactAfterAck: true, inboxID: recordDelivery("Code", "Your code is 482913", "", "")}
+	acknowledged.report(actionAcknowledged, "fyne")
+	timedOut := resultReporter{redactAfterAck: true, inboxID: recordDelivery("Code", "Your code is 771204", "", "")}
+	timedOut.report(actionTimeout, "fyne")
+
+	items, err := loadStore()
+	if err != nil {
+		t.Fatalf("loadStore failed: %v", err)
+	}
+	messages := map[string]string{}
+	for _, item := range items {
+		messages[item.ID] = item.Message
+	}
+	if got := messages[acknowledged.inboxID]; got != redactedMessage {
+		t.Errorf("Acknowledged message = %q, want %q", got, redactedMessage)
+	}
+	if got := messages[timedOut.inboxID]; got != "Your code is 771204" {
+		t.Errorf("Timed out message = %q, want it unchanged", got)
+	}
+}
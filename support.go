@@ -0,0 +1,421 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SupportConfig is the support section of a config file: where notify support-bundle -upload sends bundles
+type SupportConfig struct {
+	UploadURL string `yaml:"upload_url"` // https, or http on loopback
+	TokenFile string `yaml:"token_file"` // File holding the bearer token sent with the upload
+}
+
+// Limits of what notify support-bundle collects
+const (
+	supportCommandTimeout = time.Minute // Per report command, so a hung GUI check does not block the bundle
+	supportLogTail        = 1 << 20     // Bytes of a log file, from its end
+	supportUploadTimeout  = 5 * time.Minute
+)
+
+// secretKeyPattern matches config keys whose values are redacted from support bundles
+var secretKeyPattern = regexp.MustCompile(`(?i)(token|secret|password|passwd|api_?key|credential)`)
+
+// supportReport is a notify command whose output goes into a support bundle
+type supportReport struct {
+	file string
+	args []string
+}
+
+// supportReports are the notify commands a support bundle runs; the probes do not show anything
+var supportReports = func() []supportReport {
+	reports := []supportReport{
+		{"version.txt", []string{"version"}},
+		{"status.txt", []string{"status"}},
+		{"plan.txt", []string{"-plan", "-title", "Support bundle", "-message", "Delivery plan"}},
+		{"dry-run.txt", []string{"-dry-run", "-debug", "-title", "Support bundle", "-message", "Dry run"}},
+	}
+	names := make([]string, 0, len(checkCommands))
+	for name := range checkCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		reports = append(reports, supportReport{"checks/" + name + ".txt", []string{"check", name}})
+	}
+	return reports
+}()
+
+// runSupportBundle handles "notify support-bundle": collects the version, status, delivery plan,
+// dry run, checks, redacted config files and recent logs into a zip, and optionally uploads it
+func runSupportBundle(args []string) int {
+	fs := flag.NewFlagSet("support-bundle", flag.ExitOnError)
+	output := fs.String("o", "", "Zip file to write (default: notify-support-HOST-TIME.zip in the current directory)")
+	configPath := fs.String("config", "", "Config file to include and read the support section from (default: the user and system config files)")
+	upload := fs.Bool("upload", false, "Upload the bundle to the support upload_url of the config file")
+	uploadURL := fs.String("upload-url", "", "Upload the bundle to this URL (https, or http on loopback), overriding the config")
+	tokenFile := fs.String("token-file", "", "File holding the bearer token sent with the upload, overriding the config")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: notify support-bundle [-o FILE] [-config FILE] [-upload] [-upload-url URL] [-token-file FILE]")
+		fmt.Fprintln(os.Stderr, "Collects logs, the delivery plan, dry run, checks, redacted config files and version into a zip for a support ticket")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	configPaths := defaultConfigPaths()
+	if *configPath != "" {
+		configPaths = []string{*configPath}
+	}
+	target, token := *uploadURL, ""
+	if *upload || target != "" {
+		support := findSupportConfig(configPaths)
+		if target == "" {
+			target = support.UploadURL
+		}
+		if *tokenFile == "" {
+			*tokenFile = support.TokenFile
+		}
+		if target == "" {
+			fmt.Fprintln(os.Stderr, "Error: -upload needs -upload-url or support.upload_url in a config file")
+			return 2
+		}
+		if err := validateUploadURL(target); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		if *tokenFile != "" {
+			data, err := os.ReadFile(*tokenFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to read token file: %v\n", err)
+				return 1
+			}
+			token = strings.TrimSpace(string(data))
+		}
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not find notify executable: %v\n", err)
+		return 1
+	}
+	path := *output
+	if path == "" {
+		host, _ := os.Hostname()
+		path = fmt.Sprintf("notify-support-%s-%s.zip", host, time.Now().Format("20060102-150405"))
+	}
+
+	data, err := buildSupportBundle(exePath, configPaths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not write support bundle: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Support bundle written to %s (%d KB)\n", path, (len(data)+1023)/1024)
+
+	if target != "" {
+		if err := uploadSupportBundle(target, token, filepath.Base(path), data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: upload failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Support bundle uploaded to %s\n", redactURL(target))
+	}
+	return 0
+}
+
+// buildSupportBundle returns the zip of a support bundle; a report or log that cannot be
+// collected is noted in manifest.txt rather than failing the bundle
+func buildSupportBundle(exePath string, configPaths []string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	var manifest strings.Builder
+	host, _ := os.Hostname()
+	fmt.Fprintf(&manifest, "KrankyBear Notify support bundle\nVersion: %s\nPlatform: %s/%s\nHost: %s\nCreated: %s\n\n",
+		appVersion, runtime.GOOS, runtime.GOARCH, host, time.Now().Format(time.RFC3339))
+
+	add := func(name string, data []byte) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	for _, report := range supportReports {
+		fmt.Printf("Collecting %s\n", report.file)
+		out, code := runSupportCommand(exePath, report.args)
+		fmt.Fprintf(&manifest, "%-28s notify %s (exit code %d)\n", report.file, strings.Join(report.args, " "), code)
+		if err := add(report.file, out); err != nil {
+			return nil, fmt.Errorf("could not write %s: %v", report.file, err)
+		}
+	}
+
+	for i, path := range configPaths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(&manifest, "%-28s not found\n", path)
+			continue
+		}
+		if err == nil {
+			data, err = redactConfig(data)
+		}
+		if err != nil {
+			fmt.Fprintf(&manifest, "%-28s not included: %v\n", path, err)
+			continue
+		}
+		name := fmt.Sprintf("config/%d-%s", i+1, filepath.Base(path))
+		fmt.Fprintf(&manifest, "%-28s %s (secrets redacted)\n", name, path)
+		if err := add(name, data); err != nil {
+			return nil, fmt.Errorf("could not write %s: %v", name, err)
+		}
+	}
+
+	for _, l := range supportLogs() {
+		fmt.Printf("Collecting %s\n", l.file)
+		var data []byte
+		var err error
+		if l.path != "" {
+			data, err = readFileTail(l.path, supportLogTail)
+		} else {
+			data, err = runSupportLogCommand(l.command)
+		}
+		if err != nil {
+			fmt.Fprintf(&manifest, "%-28s not included: %v\n", l.file, err)
+			continue
+		}
+		fmt.Fprintf(&manifest, "%-28s %s\n", l.file, l.source())
+		if err := add(l.file, data); err != nil {
+			return nil, fmt.Errorf("could not write %s: %v", l.file, err)
+		}
+	}
+
+	if err := add("manifest.txt", []byte(manifest.String())); err != nil {
+		return nil, fmt.Errorf("could not write manifest.txt: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("could not write support bundle: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// runSupportCommand runs notify with args, returning its output (stdout and stderr) and exit
+// code, -1 when it did not finish within supportCommandTimeout
+func runSupportCommand(exePath string, args []string) ([]byte, int) {
+	ctx, cancel := context.WithTimeout(context.Background(), supportCommandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, exePath, args...).CombinedOutput()
+	if ctx.Err() != nil {
+		return append(out, fmt.Sprintf("\n(stopped after %s)\n", supportCommandTimeout)...), -1
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return out, exitErr.ExitCode()
+	}
+	if err != nil {
+		return []byte(err.Error() + "\n"), -1
+	}
+	return out, 0
+}
+
+// supportLog is a log in a support bundle: the end of a file, or the output of a command
+type supportLog struct {
+	file    string
+	path    string
+	command []string
+}
+
+// source describes where a support log came from, for the manifest
+func (l supportLog) source() string {
+	if l.path != "" {
+		return l.path
+	}
+	return strings.Join(l.command, " ")
+}
+
+// supportLogs returns the logs notify writes on this platform: the scheduled task debug log and
+// break-glass audit entries (Windows event log, syslog)
+func supportLogs() []supportLog {
+	switch runtime.GOOS {
+	case "windows":
+		return []supportLog{
+			{file: "logs/notify-debug.log", path: `C:\Temp\notify-debug.log`},
+			{file: "logs/eventlog.txt", command: []string{"wevtutil", "qe", "Application", "/q:*[System[Provider[@Name='KrankyBearNotify']]]", "/c:200", "/rd:true", "/f:text"}},
+		}
+	case "darwin":
+		return []supportLog{
+			{file: "logs/system-log.txt", command: []string{"log", "show", "--last", "7d", "--style", "syslog", "--predicate", `eventMessage CONTAINS "krankybearnotify" OR process == "notify"`}},
+		}
+	}
+	return []supportLog{
+		{file: "logs/journal.txt", command: []string{"journalctl", "--no-pager", "-n", "500", "-t", "krankybearnotify"}},
+	}
+}
+
+// runSupportLogCommand returns the output of a log command, at most supportLogTail bytes from its end
+func runSupportLogCommand(command []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), supportCommandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, command[0], command[1:]...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %v", command[0], err)
+	}
+	if len(out) > supportLogTail {
+		out = out[len(out)-supportLogTail:]
+	}
+	return out, nil
+}
+
+// readFileTail returns at most max bytes from the end of the file at path
+func readFileTail(path string, max int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > max {
+		if _, err := f.Seek(info.Size()-max, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(f)
+}
+
+// redactConfig returns a config file with the values of secret keys (tokens, passwords, ...)
+// and the credentials in URLs replaced by REDACTED
+func redactConfig(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %v", err)
+	}
+	redactNode(&doc, false)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	enc.Close()
+	return buf.Bytes(), nil
+}
+
+// redactNode redacts the scalars under node, all of them when secret (the value of a secret key)
+func redactNode(node *yaml.Node, secret bool) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			redactNode(node.Content[i+1], secret || secretKeyPattern.MatchString(node.Content[i].Value))
+		}
+	case yaml.ScalarNode:
+		if secret {
+			node.Value, node.Tag, node.Style = "REDACTED", "!!str", 0
+		} else if strings.Contains(node.Value, "://") {
+			node.Value = redactURL(node.Value)
+		}
+	default:
+		for _, child := range node.Content {
+			redactNode(child, secret)
+		}
+	}
+}
+
+// redactURL returns raw with its user info and the values of secret query parameters replaced
+// by REDACTED, or raw when it is not a URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if secretKeyPattern.MatchString(key) {
+				query.Set(key, "REDACTED")
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// findSupportConfig returns the support section of the first config file in paths that has one
+func findSupportConfig(paths []string) SupportConfig {
+	for _, path := range paths {
+		config, err := loadConfig(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		}
+		if config != nil && config.Support != nil && config.Support.UploadURL != "" {
+			return *config.Support
+		}
+	}
+	return SupportConfig{}
+}
+
+// validateUploadURL checks a support bundle upload URL: https, or http to a loopback address
+// (the bundle and token must not cross the network in the clear)
+func validateUploadURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid upload URL %q", raw)
+	}
+	switch {
+	case u.Scheme == "https":
+		return nil
+	case u.Scheme == "http" && isLoopbackAddress(net.JoinHostPort(u.Hostname(), "80")):
+		return nil
+	}
+	return fmt.Errorf("upload URL %s must use https (http only to loopback)", redactURL(raw))
+}
+
+// uploadSupportBundle POSTs a support bundle as application/zip, with its file name and the
+// bearer token when there is one
+func uploadSupportBundle(target, token, name string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/zip")
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: supportUploadTimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
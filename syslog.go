@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSyslogMessageSize is the largest syslog datagram read, longer ones are cut
+const maxSyslogMessageSize = 64 << 10
+
+// runWatchSyslog handles -watch-syslog: receives syslog messages on address, a UDP host:port or
+// the path of a Unix datagram socket, and shows a notification for each one that passes
+// -watch-match, until interrupted
+// Messages are RFC 5424 or RFC 3164 (BSD), with the fields named as in the systemd journal
+func runWatchSyslog(address string, opts watchOptions) int {
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get executable path: %v\n", err)
+		return 1
+	}
+	network := "udp"
+	if strings.Contains(address, "/") {
+		network = "unixgram"
+		// A socket left behind by a previous run that was interrupted
+		if info, err := os.Lstat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+	}
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot listen for syslog messages: %v\n", err)
+		return 1
+	}
+	defer conn.Close()
+	filter := newWatchFilter(opts)
+
+	fmt.Printf("Listening for syslog messages on %s (Ctrl+C to stop)\n", conn.LocalAddr())
+	buf := make([]byte, maxSyslogMessageSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot receive syslog messages: %v\n", err)
+			return 1
+		}
+		fields, err := parseSyslog(string(buf[:n]))
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		if opts.rules.match(fields) {
+			showTriggered(exePath, opts.rules.spec(fields), filter, opts.debug)
+		}
+	}
+}
+
+// parseSyslog returns the fields of a syslog message under their systemd journal names:
+// PRIORITY, SYSLOG_FACILITY, _HOSTNAME, SYSLOG_IDENTIFIER, SYSLOG_PID and MESSAGE
+// RFC 5424 messages also set SYSLOG_TIMESTAMP and SYSLOG_MSGID, RFC 3164 ones SYSLOG_TIMESTAMP
+func parseSyslog(data string) (map[string]string, error) {
+	data = strings.TrimRight(data, "\x00\r\n")
+	end := strings.IndexByte(data, '>')
+	if !strings.HasPrefix(data, "<") || end < 2 || end > 4 {
+		return nil, fmt.Errorf("invalid syslog message: no <priority>")
+	}
+	pri, err := strconv.Atoi(data[1:end])
+	if err != nil || pri > 191 {
+		return nil, fmt.Errorf("invalid syslog message: bad priority %q", data[1:end])
+	}
+	fields := map[string]string{
+		"PRIORITY":        strconv.Itoa(pri % 8),
+		"SYSLOG_FACILITY": strconv.Itoa(pri / 8),
+	}
+	rest := data[end+1:]
+
+	// RFC 5424: VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG, "-" for none
+	if strings.HasPrefix(rest, "1 ") {
+		parts := strings.SplitN(rest[2:], " ", 5)
+		if len(parts) < 5 {
+			return nil, fmt.Errorf("invalid RFC 5424 syslog message: missing header fields")
+		}
+		for i, name := range []string{"SYSLOG_TIMESTAMP", "_HOSTNAME", "SYSLOG_IDENTIFIER", "SYSLOG_PID"} {
+			if parts[i] != "-" {
+				fields[name] = parts[i]
+			}
+		}
+		msgID, rest, _ := strings.Cut(parts[4], " ")
+		if msgID != "-" {
+			fields["SYSLOG_MSGID"] = msgID
+		}
+		fields["MESSAGE"] = strings.TrimPrefix(skipStructuredData(rest), "\ufeff")
+		return fields, nil
+	}
+
+	// RFC 3164: TIMESTAMP HOSTNAME TAG[PID]: MSG; the hostname is often left out on the local socket
+	if len(rest) >= len(time.Stamp) {
+		if _, err := time.Parse(time.Stamp, rest[:len(time.Stamp)]); err == nil {
+			fields["SYSLOG_TIMESTAMP"] = rest[:len(time.Stamp)]
+			rest = strings.TrimLeft(rest[len(time.Stamp):], " ")
+		}
+	}
+	if word, after, ok := strings.Cut(rest, " "); ok && !strings.HasSuffix(word, ":") && !strings.Contains(word, "[") {
+		if _, _, isTag := strings.Cut(after, ":"); isTag && fields["SYSLOG_TIMESTAMP"] != "" {
+			fields["_HOSTNAME"] = word
+			rest = after
+		}
+	}
+	if tag, message, ok := strings.Cut(rest, ": "); ok && !strings.Contains(tag, " ") {
+		if name, pid, hasPID := strings.Cut(tag, "["); hasPID {
+			fields["SYSLOG_IDENTIFIER"] = name
+			fields["SYSLOG_PID"] = strings.TrimSuffix(pid, "]")
+		} else {
+			fields["SYSLOG_IDENTIFIER"] = tag
+		}
+		rest = message
+	}
+	fields["MESSAGE"] = rest
+	return fields, nil
+}
+
+// skipStructuredData returns the MSG after the STRUCTURED-DATA of an RFC 5424 message: "-" or
+// [elements], in which \] does not end an element
+func skipStructuredData(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimPrefix(s[1:], " ")
+	}
+	for strings.HasPrefix(s, "[") {
+		i := 1
+		for i < len(s) && s[i] != ']' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(s) {
+			return ""
+		}
+		s = s[i+1:]
+	}
+	return strings.TrimPrefix(s, " ")
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+// TestParseSyslog tests RFC 5424 and RFC 3164 messages, with and without a hostname
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    map[string]string
+	}{
+		{
+			name:    "RFC 5424",
+			message: `<34>1 2026-10-16T09:30:00.003Z web1 sshd 4321 ID47 [exampleSDID@32473 iut="3" eventID="1011\]"] ` + "\ufeff" + "Failed password for root\n",
+			want: map[string]string{
+				"PRIORITY": "2", "SYSLOG_FACILITY": "4", "SYSLOG_TIMESTAMP": "2026-10-16T09:30:00.003Z", "_HOSTNAME": "web1",
+				"SYSLOG_IDENTIFIER": "sshd", "SYSLOG_PID": "4321", "SYSLOG_MSGID": "ID47", "MESSAGE": "Failed password for root",
+			},
+		},
+		{
+			name:    "RFC 5424 without structured data",
+			message: "<165>1 2026-10-16T09:30:00Z - backup - - - Nightly backup failed",
+			want: map[string]string{
+				"PRIORITY": "5", "SYSLOG_FACILITY": "20", "SYSLOG_TIMESTAMP": "2026-10-16T09:30:00Z",
+				"SYSLOG_IDENTIFIER": "backup", "MESSAGE": "Nightly backup failed",
+			},
+		},
+		{
+			name:    "RFC 3164 with hostname",
+			message: "<86>Oct  6 22:14:15 db1 sudo[811]: alice : TTY=pts/0 ; COMMAND=/bin/ls",
+			want: map[string]string{
+				"PRIORITY": "6", "SYSLOG_FACILITY": "10", "SYSLOG_TIMESTAMP": "Oct  6 22:14:15", "_HOSTNAME": "db1",
+				"SYSLOG_IDENTIFIER": "sudo", "SYSLOG_PID": "811", "MESSAGE": "alice : TTY=pts/0 ; COMMAND=/bin/ls",
+			},
+		},
+		{
+			name:    "RFC 3164 from the local socket",
+			message: "<12>Oct 16 09:30:00 backup: Disk /data is full",
+			want: map[string]string{
+				"PRIORITY": "4", "SYSLOG_FACILITY": "1", "SYSLOG_TIMESTAMP": "Oct 16 09:30:00",
+				"SYSLOG_IDENTIFIER": "backup", "MESSAGE": "Disk /data is full",
+			},
+		},
+		{
+			name:    "bare message",
+			message: "<13>Something happened",
+			want:    map[string]string{"PRIORITY": "5", "SYSLOG_FACILITY": "1", "MESSAGE": "Something happened"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := parseSyslog(tt.message)
+			if err != nil {
+				t.Fatalf("parseSyslog failed: %v", err)
+			}
+			if len(fields) != len(tt.want) {
+				t.Errorf("parseSyslog = %v, want %v", fields, tt.want)
+			}
+			for name, value := range tt.want {
+				if fields[name] != value {
+					t.Errorf("%s = %q, want %q", name, fields[name], value)
+				}
+			}
+		})
+	}
+
+	for _, message := range []string{"no priority", "<>1 x", "<999>hello", "<1a>hello", "<34>1 2026-10-16T09:30:00Z web1"} {
+		if _, err := parseSyslog(message); err == nil {
+			t.Errorf("parseSyslog(%q) should fail", message)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// Columns of a -targets file with a meaning of their own; every other column is a variable
+// that {{column}} in the title and message is replaced with, per row (template functions such
+// as {{humanBytes column}} and {{if column}} blocks can use them too)
+const (
+	targetColumnUsername = "username"
+	targetColumnSession  = "session"
+	targetColumnTitle    = "title"
+	targetColumnMessage  = "message"
+)
+
+// loadTargets reads a -targets CSV file: a header row naming the columns (username is required;
+// session, title and message are optional), then one row per user to notify
+// Each row's title and message default to n's, rendered with the row's values as template variables;
+// the targets only carry them when they differ from n's
+func loadTargets(path string, n notify.Notification) ([]notify.Target, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open targets file: %v", err)
+	}
+	defer file.Close()
+	return parseTargets(file, path, n)
+}
+
+// parseTargets parses the -targets CSV read from r (see loadTargets); source names it in errors
+func parseTargets(r io.Reader, source string, n notify.Notification) ([]notify.Target, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%s: empty targets file", source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", source, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Byte order mark of CSV files saved by Excel
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok || name == "" {
+			return nil, fmt.Errorf("%s: empty or repeated column %q in the header", source, name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns[targetColumnUsername]; !ok {
+		return nil, fmt.Errorf("%s: the header has no %s column", source, targetColumnUsername)
+	}
+
+	// Template functions without variables render the same for every row, so compare with n
+	// rendered without variables to find the rows that differ
+	now := time.Now()
+	unchanged, err := n.WithTemplate(nil, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", source, err)
+	}
+	unchanged.Title, unchanged.Message = notify.NormalizeText(unchanged.Title), notify.NormalizeText(unchanged.Message)
+
+	var targets []notify.Target
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", source, err)
+		}
+		line, _ := reader.FieldPos(0)
+		value := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		target := notify.Target{
+			Username: value(targetColumnUsername),
+			Session:  value(targetColumnSession),
+			Title:    value(targetColumnTitle),
+			Message:  value(targetColumnMessage),
+		}
+		if target.Username == "" {
+			return nil, fmt.Errorf("%s:%d: username is required", source, line)
+		}
+		if err := notify.ValidateTargetSession(target.Session); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", source, line, err)
+		}
+		if target.Title == "" {
+			target.Title = n.Title
+		}
+		if target.Message == "" {
+			target.Message = n.Message
+		}
+		vars := map[string]string{}
+		for name, i := range columns {
+			switch name {
+			case targetColumnUsername, targetColumnSession, targetColumnTitle, targetColumnMessage:
+				continue
+			}
+			vars[name] = strings.TrimSpace(row[i])
+		}
+		personal := n
+		personal.Title, personal.Message = target.Title, target.Message
+		if personal, err = personal.WithTemplate(vars, now); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", source, line, err)
+		}
+		target.Title, target.Message = personal.Title, personal.Message
+		// Only what differs is an override, so an unchanged message keeps n's -html
+		if target.Title = notify.NormalizeText(target.Title); target.Title == unchanged.Title {
+			target.Title = ""
+		}
+		if target.Message = notify.NormalizeText(target.Message); target.Message == unchanged.Message {
+			target.Message = ""
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s: no targets after the header", source)
+	}
+	return targets, nil
+}
+
+// printTargetResults prints the per-target results of a -targets fan-out as CSV on stdout
+func printTargetResults(results []notify.TargetResult) {
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write([]string{"username", "action", "sessions", "error"})
+	for _, result := range results {
+		writer.Write([]string{result.Username, result.Action, strings.Join(result.Sessions, " "), result.Error})
+	}
+	writer.Flush()
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
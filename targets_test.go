@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// TestParseTargets tests the columns, variables and overrides of a -targets file
+func TestParseTargets(t *testing.T) {
+	n := notify.Notification{Title: "Disk quota", Message: "You are using {{quota}} of your quota"}
+	csv := "\ufeffUsername, Session, Quota, Title\n" +
+		"alice, rdp, 91%,\n" +
+		"CORP\\bob,,,Bob's quota\n" +
+		"carol,3,{{quota}},\n"
+	targets, err := parseTargets(strings.NewReader(csv), "targets.csv", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []notify.Target{
+		{Username: "alice", Session: "rdp", Message: "You are using 91% of your quota"},
+		{Username: `CORP\bob`, Title: "Bob's quota", Message: "You are using  of your quota"},
+		{Username: "carol", Session: "3"},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("got %d targets, want %d: %+v", len(targets), len(want), targets)
+	}
+	for i := range want {
+		if targets[i] != want[i] {
+			t.Errorf("target %d = %+v, want %+v", i, targets[i], want[i])
+		}
+	}
+}
+
+// TestParseTargetsErrors tests that bad -targets files are rejected with their line
+func TestParseTargetsErrors(t *testing.T) {
+	n := notify.Notification{Title: "Title", Message: "Message"}
+	tests := []struct {
+		csv  string
+		want string
+	}{
+		{"", "empty targets file"},
+		{"user,session\nalice,any\n", "no username column"},
+		{"username,username\nalice,alice\n", "repeated column"},
+		{"username\n", "no targets"},
+		{"username,session\nalice,any\nbob,tty1\n", "targets.csv:3: invalid session"},
+		{"username,session\nalice,any\n,any\n", "targets.csv:3: username is required"},
+		{"username,session\nalice\n", "wrong number of fields"},
+	}
+	for _, test := range tests {
+		_, err := parseTargets(strings.NewReader(test.csv), "targets.csv", n)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("parseTargets(%q) = %v, want an error with %q", test.csv, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarillier/KrankyBearNotify/pkg/notify"
+)
+
+// Default notification templates of -watch-journal and -watch-syslog
+const (
+	defaultEventTitle   = "{{SYSLOG_IDENTIFIER}}"
+	defaultEventMessage = "{{MESSAGE}}"
+)
+
+// eventMatch is a -watch-match expression: FIELD=glob, FIELD!=glob or FIELD~regexp
+type eventMatch struct {
+	field   string
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+// parseEventMatch parses a -watch-match expression; globs match the whole value, with * for
+// any text and ? for one character, regexps anywhere in it
+func parseEventMatch(expr string) (eventMatch, error) {
+	i := strings.IndexAny(expr, "=~!")
+	if i <= 0 {
+		return eventMatch{}, fmt.Errorf("invalid -watch-match %q (use FIELD=glob, FIELD!=glob or FIELD~regexp)", expr)
+	}
+	m := eventMatch{field: expr[:i]}
+	operator, value := expr[i:i+1], expr[i+1:]
+	if operator == "!" {
+		if !strings.HasPrefix(value, "=") {
+			return eventMatch{}, fmt.Errorf("invalid -watch-match %q (use FIELD=glob, FIELD!=glob or FIELD~regexp)", expr)
+		}
+		operator, value, m.negate = "=", value[1:], true
+	}
+	if operator == "~" {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return eventMatch{}, fmt.Errorf("invalid -watch-match %q: %v", expr, err)
+		}
+		m.pattern = pattern
+		return m, nil
+	}
+	glob := regexp.QuoteMeta(value)
+	glob = strings.ReplaceAll(glob, `\*`, ".*")
+	glob = strings.ReplaceAll(glob, `\?`, ".")
+	m.pattern = regexp.MustCompile("^" + glob + "$")
+	return m, nil
+}
+
+// eventRules decide which events of a trigger source (-watch-file, -watch-journal,
+// -watch-syslog) become notifications, and with what text
+type eventRules struct {
+	matches []eventMatch // All must match; a field the event lacks is empty
+	title   string       // Templates with {{FIELD}} placeholders, for events that are not specs
+	message string
+}
+
+// match reports whether an event with fields passes every -watch-match
+func (r eventRules) match(fields map[string]string) bool {
+	for _, m := range r.matches {
+		if m.pattern.MatchString(fields[m.field]) == m.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// eventPlaceholderPattern matches a {{FIELD}} placeholder of the -watch-title and -watch-message templates
+var eventPlaceholderPattern = regexp.MustCompile(`\{\{([A-Za-z0-9_]+)\}\}`)
+
+// renderEventTemplate replaces the {{FIELD}} placeholders of template with the fields of an
+// event, empty for fields it lacks; the template functions (notify.RenderTemplate) can use the
+// fields too
+func renderEventTemplate(template string, fields map[string]string) string {
+	vars := make(map[string]string, len(fields))
+	for _, placeholder := range eventPlaceholderPattern.FindAllString(template, -1) {
+		vars[placeholder[2:len(placeholder)-2]] = ""
+	}
+	for name, value := range fields {
+		vars[name] = value
+	}
+	rendered, err := notify.RenderTemplate(template, vars, time.Local, time.Now())
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return eventPlaceholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+			return fields[placeholder[2:len(placeholder)-2]]
+		})
+	}
+	return rendered
+}
+
+// eventSpec is the notification spec shown for a journal or syslog event
+type eventSpec struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Urgency string `json:"urgency,omitempty"`
+}
+
+// spec returns the notification spec of an event as JSON: the title and message templates
+// filled in, and the urgency of its syslog PRIORITY (emerg to crit are critical, err and
+// warning a warning, the rest info)
+func (r eventRules) spec(fields map[string]string) []byte {
+	s := eventSpec{
+		Title:   strings.TrimSpace(renderEventTemplate(r.title, fields)),
+		Message: strings.TrimSpace(renderEventTemplate(r.message, fields)),
+	}
+	if s.Title == "" {
+		s.Title = notify.DefaultTitle
+	}
+	if s.Message == "" {
+		s.Message = "(empty message)"
+	}
+	if priority, err := strconv.Atoi(fields["PRIORITY"]); err == nil {
+		switch {
+		case priority <= 2:
+			s.Urgency = notify.UrgencyCritical
+		case priority <= 4:
+			s.Urgency = notify.UrgencyWarning
+		default:
+			s.Urgency = notify.UrgencyInfo
+		}
+	}
+	data, _ := json.Marshal(s)
+	return data
+}
+
+// specFields returns the top-level values of a -watch-file spec line as fields for -watch-match,
+// nil when the line is not a JSON object
+func specFields(line []byte) map[string]string {
+	var values map[string]interface{}
+	if err := json.Unmarshal(line, &values); err != nil {
+		return nil
+	}
+	fields := make(map[string]string, len(values))
+	for name, value := range values {
+		switch value := value.(type) {
+		case string:
+			fields[name] = value
+		case float64, bool:
+			fields[name] = fmt.Sprint(value)
+		}
+	}
+	return fields
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestEventRulesMatches tests the -watch-match globs, negations and regexps
+func TestEventRulesMatches(t *testing.T) {
+	var rules eventRules
+	for _, expr := range []string{"SYSLOG_IDENTIFIER=ssh*", "PRIORITY~^[0-3]$", "_HOSTNAME!=test-?"} {
+		m, err := parseEventMatch(expr)
+		if err != nil {
+			t.Fatalf("parseEventMatch(%q) failed: %v", expr, err)
+		}
+		rules.matches = append(rules.matches, m)
+	}
+
+	tests := []struct {
+		fields map[string]string
+		want   bool
+	}{
+		{map[string]string{"SYSLOG_IDENTIFIER": "sshd", "PRIORITY": "3", "_HOSTNAME": "web1"}, true},
+		{map[string]string{"SYSLOG_IDENTIFIER": "sshd", "PRIORITY": "6", "_HOSTNAME": "web1"}, false},
+		{map[string]string{"SYSLOG_IDENTIFIER": "sshd", "PRIORITY": "2", "_HOSTNAME": "test-1"}, false},
+		{map[string]string{"SYSLOG_IDENTIFIER": "cron", "PRIORITY": "2", "_HOSTNAME": "web1"}, false},
+		{map[string]string{"SYSLOG_IDENTIFIER": "sshd", "PRIORITY": "2"}, true}, // No hostname is not test-?
+		{map[string]string{"PRIORITY": "2"}, false},
+	}
+	for _, tt := range tests {
+		if got := rules.match(tt.fields); got != tt.want {
+			t.Errorf("match(%v) = %v, want %v", tt.fields, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"sshd", "=sshd", "MESSAGE!sshd", "MESSAGE~(", ""} {
+		if _, err := parseEventMatch(expr); err == nil {
+			t.Errorf("parseEventMatch(%q) should fail", expr)
+		}
+	}
+}
+
+// TestEventRulesSpec tests the templates and the urgency of the syslog priority
+func TestEventRulesSpec(t *testing.T) {
+	rules := eventRules{title: "{{SYSLOG_IDENTIFIER}} on {{_HOSTNAME}}", message: defaultEventMessage}
+	tests := []struct {
+		fields      map[string]string
+		wantTitle   string
+		wantMessage string
+		wantUrgency string
+	}{
+		{map[string]string{"SYSLOG_IDENTIFIER": "kernel", "_HOSTNAME": "db1", "MESSAGE": "Out of memory", "PRIORITY": "2"}, "kernel on db1", "Out of memory", "critical"},
+		{map[string]string{"SYSLOG_IDENTIFIER": "smartd", "_HOSTNAME": "db1", "MESSAGE": "Disk failing", "PRIORITY": "4"}, "smartd on db1", "Disk failing", "warning"},
+		{map[string]string{"SYSLOG_IDENTIFIER": "cron", "MESSAGE": "Job done", "PRIORITY": "6"}, "cron on", "Job done", "info"},
+		{map[string]string{"MESSAGE": "{{MESSAGE}} stays"}, "on", "{{MESSAGE}} stays", ""},
+	}
+	for _, tt := range tests {
+		var got eventSpec
+		if err := json.Unmarshal(rules.spec(tt.fields), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != tt.wantTitle || got.Message != tt.wantMessage || got.Urgency != tt.wantUrgency {
+			t.Errorf("spec(%v) = %+v, want %q, %q, %q", tt.fields, got, tt.wantTitle, tt.wantMessage, tt.wantUrgency)
+		}
+	}
+
+	var empty eventSpec
+	json.Unmarshal(eventRules{title: defaultEventTitle, message: defaultEventMessage}.spec(map[string]string{}), &empty)
+	if empty.Title == "" || empty.Message == "" {
+		t.Errorf("spec of an empty event = %+v, want a default title and message", empty)
+	}
+}
+
+// TestSpecFields tests the fields -watch-match sees in -watch-file lines
+func TestSpecFields(t *testing.T) {
+	fields := specFields([]byte(`{"title": "Disk space", "urgency": "warning", "timeout": 30, "input": true, "choices": ["a"]}`))
+	want := map[string]string{"title": "Disk space", "urgency": "warning", "timeout": "30", "input": "true"}
+	if len(fields) != len(want) {
+		t.Fatalf("specFields = %v, want %v", fields, want)
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("specFields[%q] = %q, want %q", name, fields[name], value)
+		}
+	}
+	if fields := specFields([]byte(`not json`)); fields != nil {
+		t.Errorf("specFields of a bad line = %v, want nil", fields)
+	}
+}
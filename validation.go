@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exitInvalidArguments is the exit code of a notification refused for its flags, spec or config
+// values before anything was shown; 1 stays for failed deliveries and other errors
+const exitInvalidArguments = 2
+
+// actionInvalid is the -result-json action of a notification refused for its arguments
+const actionInvalid = "invalid"
+
+// argumentError is one problem with the arguments of a notification
+type argumentError struct {
+	Flag    string `json:"flag,omitempty"` // Without the dash, empty when no single flag is at fault
+	Message string `json:"message"`
+}
+
+// argumentErrors collects the problems with the arguments, so all of them are reported at once
+// instead of one per run
+type argumentErrors []argumentError
+
+// check records err, if any, as a problem with flag
+func (e *argumentErrors) check(flag string, err error) {
+	if err != nil {
+		*e = append(*e, argumentError{Flag: flag, Message: err.Error()})
+	}
+}
+
+// add records a problem with flag
+func (e *argumentErrors) add(flag, format string, args ...interface{}) {
+	*e = append(*e, argumentError{Flag: flag, Message: fmt.Sprintf(format, args...)})
+}
+
+// exitIfAny prints the problems and exits with exitInvalidArguments; it returns when there are
+// none. Each problem is an "Error:" line on stderr and, with -result-json, the errors of a
+// single "invalid" result on stdout
+func (e argumentErrors) exitIfAny(jsonOutput bool, title string) {
+	if len(e) == 0 {
+		return
+	}
+	for _, problem := range e {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", problem.Message)
+	}
+	if jsonOutput {
+		result := NotificationResult{
+			MachineID: getMachineID(),
+			Action:    actionInvalid,
+			Title:     title,
+			Timestamp: time.Now().Format(time.RFC3339),
+			Errors:    e,
+		}
+		if data, err := json.Marshal(result); err == nil {
+			fmt.Fprintln(os.Stdout, string(data))
+		}
+	}
+	os.Exit(exitInvalidArguments)
+}
+
+// flagErrorPattern finds the flag named in an error of flag.FlagSet.Parse
+var flagErrorPattern = regexp.MustCompile(`(?:not defined: |for flag |for |needs an argument: )-+([A-Za-z0-9-]+)`)
+
+// parseFlags parses the command line like flag.Parse, but reports a bad flag as an argumentError
+// (flag already printed it with the usage) instead of exiting with the flag package's code
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Init(fs.Name(), flag.ContinueOnError)
+	err := fs.Parse(args)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	argumentErrors{flagProblem(err)}.exitIfAny(resultJSONRequested(args), "")
+}
+
+// flagProblem returns an error of flag.FlagSet.Parse as an argumentError
+func flagProblem(err error) argumentError {
+	problem := argumentError{Message: err.Error()}
+	if m := flagErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		problem.Flag = m[1]
+	}
+	return problem
+}
+
+// resultJSONRequested reports whether args set -result-json, for errors found before the flags
+// could be parsed
+func resultJSONRequested(args []string) bool {
+	requested := false
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "result-json" {
+			continue
+		}
+		requested = true
+		if hasValue {
+			requested, _ = strconv.ParseBool(value)
+		}
+	}
+	return requested
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"testing"
+)
+
+// TestArgumentErrors tests that every problem is collected, and nil errors are not
+func TestArgumentErrors(t *testing.T) {
+	var problems argumentErrors
+	problems.check("theme", nil)
+	problems.check("urgency", errors.New("invalid urgency \"urgent\""))
+	problems.add("rollout-percent", "invalid -rollout-percent %d: must be between 0 and 100", 150)
+	problems.add("", "-os and -session require -plan")
+	want := argumentErrors{
+		{Flag: "urgency", Message: "invalid urgency \"urgent\""},
+		{Flag: "rollout-percent", Message: "invalid -rollout-percent 150: must be between 0 and 100"},
+		{Message: "-os and -session require -plan"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %+v, want %+v", problems, want)
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Errorf("problem %d = %+v, want %+v", i, problems[i], want[i])
+		}
+	}
+}
+
+// TestFlagProblem tests finding the flag at fault in the errors of the flag package
+func TestFlagProblem(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-titel", "x"}, "titel"},
+		{[]string{"-timeout", "soon"}, "timeout"},
+		{[]string{"--timeout=soon"}, "timeout"},
+		{[]string{"-title"}, "title"},
+		{[]string{"-result-json=maybe"}, "result-json"},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.String("title", "", "")
+		fs.Int("timeout", 0, "")
+		fs.Bool("result-json", false, "")
+		err := fs.Parse(tt.args)
+		if err == nil {
+			t.Fatalf("Parse(%q) succeeded", tt.args)
+		}
+		if got := flagProblem(err); got.Flag != tt.want || got.Message != err.Error() {
+			t.Errorf("flagProblem(%q) = %+v, want flag %q", err, got, tt.want)
+		}
+	}
+}
+
+// TestResultJSONRequested tests spotting -result-json before the flags are parsed
+func TestResultJSONRequested(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"-title", "x", "-result-json"}, true},
+		{[]string{"--result-json", "-titel", "x"}, true},
+		{[]string{"-result-json=true"}, true},
+		{[]string{"-result-json=false"}, false},
+		{[]string{"-title", "-result-json"}, true}, // Ambiguous, as flag would read it as the title
+		{[]string{"-message", "result-json"}, false},
+		{[]string{"--", "-result-json"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := resultJSONRequested(tt.args); got != tt.want {
+			t.Errorf("resultJSONRequested(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
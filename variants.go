@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SpecVariant is one phrasing of a notification in an A/B test; fields left empty keep the spec's own
+type SpecVariant struct {
+	Name    string `yaml:"name"`
+	Weight  int    `yaml:"weight"` // Relative share of machines that get this variant
+	Title   string `yaml:"title"`
+	Message string `yaml:"message"`
+	Button  string `yaml:"button"`
+}
+
+// selectVariant picks the variant for a machine: each variant gets a share of machines
+// proportional to its weight, and the same machine always gets the same variant for a salt
+func selectVariant(variants []SpecVariant, machineID, salt string) *SpecVariant {
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	bucket := variantBucket(machineID, salt, total)
+	for i := range variants {
+		if bucket < variants[i].Weight {
+			return &variants[i]
+		}
+		bucket -= variants[i].Weight
+	}
+	return nil
+}
+
+// applyVariant replaces the spec's title, message and button with those of the variant
+// selected for this machine, and records the variant's name for the result
+func (s *NotificationSpec) applyVariant(machineID, salt string) {
+	variant := selectVariant(s.Variants, machineID, salt)
+	if variant == nil {
+		return
+	}
+	s.variant = variant.Name
+	if variant.Title != "" {
+		s.Title = variant.Title
+	}
+	if variant.Message != "" {
+		s.Message = variant.Message
+	}
+	if variant.Button != "" {
+		s.Button = variant.Button
+	}
+}
+
+// checkSpecVariants reports variants sharing a name, which would make results ambiguous
+func checkSpecVariants(root *yaml.Node) []specError {
+	var errs []specError
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "variants" {
+			continue
+		}
+		seen := map[string]bool{}
+		for j, item := range root.Content[i+1].Content {
+			for k := 0; k+1 < len(item.Content); k += 2 {
+				if item.Content[k].Value != "name" {
+					continue
+				}
+				name := item.Content[k+1]
+				if seen[name.Value] {
+					errs = append(errs, specError{Line: name.Line, Column: name.Column, Path: fmt.Sprintf("variants[%d].name", j), Message: fmt.Sprintf("duplicate variant name %q", name.Value)})
+				}
+				seen[name.Value] = true
+			}
+		}
+	}
+	return errs
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
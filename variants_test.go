@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSelectVariantWeights tests that variants are chosen deterministically in proportion to their weights
+func TestSelectVariantWeights(t *testing.T) {
+	variants := []SpecVariant{
+		{Name: "friendly", Weight: 3},
+		{Name: "urgent", Weight: 1},
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		machineID := fmt.Sprintf("machine-%d", i)
+		variant := selectVariant(variants, machineID, "campaignX")
+		if variant == nil {
+			t.Fatalf("No variant selected for %s", machineID)
+		}
+		if again := selectVariant(variants, machineID, "campaignX"); again.Name != variant.Name {
+			t.Fatalf("%s got %s, then %s", machineID, variant.Name, again.Name)
+		}
+		counts[variant.Name]++
+	}
+
+	// Expect 75% / 25% +/- 2%
+	if counts["friendly"] < 7300 || counts["friendly"] > 7700 {
+		t.Errorf("Expected about 7500 machines with the friendly variant, got %v", counts)
+	}
+}
+
+// TestApplyVariant tests that a variant replaces only the fields it sets
+func TestApplyVariant(t *testing.T) {
+	spec := NotificationSpec{
+		Title:    "Patch Tuesday",
+		Message:  "Please reboot tonight",
+		Button:   "OK",
+		Variants: []SpecVariant{{Name: "only", Weight: 1, Message: "Reboot tonight or lose your work"}},
+	}
+	spec.applyVariant("machine-1", "")
+	if spec.variant != "only" || spec.Title != "Patch Tuesday" || spec.Message != "Reboot tonight or lose your work" || spec.Button != "OK" {
+		t.Errorf("Unexpected spec after applyVariant: %+v", spec)
+	}
+}
+
+// TestSpecDuplicateVariants tests that variants with the same name are rejected
+func TestSpecDuplicateVariants(t *testing.T) {
+	data := `title: Test
+message: Hello
+variants:
+  - name: a
+    weight: 1
+  - name: a
+    weight: 2
+`
+	_, errs, err := parseSpec([]byte(data), "")
+	if err != nil {
+		t.Fatalf("parseSpec failed: %v", err)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].String(), `6:11: variants[1].name: duplicate variant name "a"`) {
+		t.Errorf("Expected a duplicate name error, got %v", errs)
+	}
+}
+
+// TestVariantStats tests acknowledgment rates per variant from collected result lines
+func TestVariantStats(t *testing.T) {
+	input := `notify starting
+{"machine_id":"1","action":"acknowledged","title":"T","variant":"a","timestamp":"x"}
+{"machine_id":"2","action":"timeout","title":"T","variant":"a","timestamp":"x"}
+{"machine_id":"3","action":"acknowledged","title":"T","variant":"b","timestamp":"x"}
+{"machine_id":"4","action":"skipped","title":"T","variant":"b","timestamp":"x"}
+{"machine_id":"5","action":"acknowledged","title":"T","timestamp":"x"}
+not json {
+`
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stats := variantStats(readResults(f, path))
+	if len(stats) != 3 {
+		t.Fatalf("Expected 3 variants, got %+v", stats)
+	}
+	want := []VariantStats{
+		{Variant: "(none)", Results: 1, Acknowledged: 1, AckRate: 1},
+		{Variant: "a", Results: 2, Acknowledged: 1, TimedOut: 1, AckRate: 0.5},
+		{Variant: "b", Results: 2, Acknowledged: 1, Other: 1, AckRate: 1},
+	}
+	for i, w := range want {
+		if stats[i] != w {
+			t.Errorf("Stats %d = %+v, want %+v", i, stats[i], w)
+		}
+	}
+}
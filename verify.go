@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// verifyPollInterval is how often notify verify asks the controller how its test notification went
+const verifyPollInterval = 2 * time.Second
+
+// verifyClient sends a self-identifying test notification to one host, through a controller or
+// to the notify serve on the host, and waits for its result
+type verifyClient struct {
+	base   string // URL of the controller, or of the notify serve on the host
+	host   string // Agent name with a controller, empty for notify serve on the host
+	token  string
+	client *http.Client
+	poll   time.Duration
+}
+
+// runVerify handles "notify verify -host NAME": shows a test notification with a verification
+// code on one machine and reports what happened to it, to prove delivery end to end
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	host := fs.String("host", "", "Agent to test (its -name) with -controller, or the http(s) URL of notify serve on the machine")
+	controller := fs.String("controller", "", "URL of the notify serve -controller the agent is connected to")
+	tokenFile := fs.String("token-file", "", "File holding the bearer token (the first one with the manage scope for a controller, show for notify serve)")
+	certFile := fs.String("cert", "", "Client certificate (PEM) for a server with -client-ca, with -key")
+	keyFile := fs.String("key", "", "Private key (PEM) of -cert")
+	caFile := fs.String("ca", "", "CA certificates (PEM) that sign the server's certificate (default: the system's)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "How long the user has to click OK")
+	screenshot := fs.String("screenshot", "", "Ask the user to share a thumbnail of their screen and save it to this PNG file")
+	resultJSON := fs.Bool("result-json", false, "Print the result as JSON instead of a summary")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: notify verify -host NAME -controller URL [OPTIONS]")
+		fmt.Fprintln(os.Stderr, "       notify verify -host https://HOST:8787 [OPTIONS]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *host == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	v := &verifyClient{poll: verifyPollInterval}
+	name := *host
+	scope := scopeShow
+	if *controller != "" {
+		if base, err := url.Parse(*controller); err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+			fmt.Fprintln(os.Stderr, "Error: -controller must be the http(s) URL of notify serve -controller")
+			return 2
+		}
+		if !agentNamePattern.MatchString(*host) {
+			fmt.Fprintf(os.Stderr, "Error: invalid -host %q (use the agent's name)\n", *host)
+			return 2
+		}
+		v.base, v.host, scope = *controller, *host, scopeManage
+	} else {
+		base, err := url.Parse(*host)
+		if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+			fmt.Fprintln(os.Stderr, "Error: -host must be an agent's name with -controller, or the http(s) URL of notify serve on the machine")
+			return 2
+		}
+		v.base, name = *host, base.Hostname()
+	}
+	v.base = strings.TrimSuffix(v.base, "/")
+	if *timeout < 10*time.Second {
+		fmt.Fprintln(os.Stderr, "Error: -timeout must be at least 10s")
+		return 2
+	}
+	tlsConfig, err := agentTLSConfig(*certFile, *keyFile, *caFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	v.client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}}
+	if *tokenFile != "" {
+		if v.token, err = readScopedToken(*tokenFile, scope); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	code := strings.ToUpper(newNotificationID()[:6])
+	if !*resultJSON {
+		fmt.Printf("Showing a test notification with code %s on %s, waiting up to %v for the user\n", code, name, *timeout)
+	}
+	// The notification closes at the timeout, the extra minute is for the notify process and the network
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout+time.Minute)
+	defer cancel()
+	result, err := v.verify(ctx, verifySpec(name, code, *timeout, *screenshot != ""))
+	if err == nil && !strings.Contains(result.Title, code) {
+		err = fmt.Errorf("the result is not that of the test notification (title %q)", result.Title)
+	}
+	if err == nil && *screenshot != "" && result.Screenshot != "" {
+		var png []byte
+		if png, err = base64.StdEncoding.DecodeString(result.Screenshot); err == nil {
+			err = os.WriteFile(*screenshot, png, 0600)
+		}
+		if err != nil {
+			err = fmt.Errorf("could not save the screenshot: %v", err)
+		}
+	}
+
+	if *resultJSON {
+		line := remoteHostResult{Host: name, Result: result}
+		if err != nil {
+			line.Error = err.Error()
+		}
+		data, _ := json.Marshal(line)
+		fmt.Println(string(data))
+	} else if err != nil {
+		fmt.Printf("%s: failed: %v\n", name, err)
+	} else {
+		fmt.Printf("%s: %s\n", name, describeRemoteResult(result))
+		if result.MachineID != "" {
+			fmt.Printf("Machine ID: %s\n", result.MachineID)
+		}
+		if result.Inventory != nil && len(result.Inventory.LoggedInUsers) > 0 {
+			fmt.Printf("Logged in: %s\n", strings.Join(result.Inventory.LoggedInUsers, ", "))
+		}
+		switch {
+		case *screenshot == "":
+		case result.Screenshot != "":
+			fmt.Printf("Screenshot saved to %s\n", *screenshot)
+		default:
+			fmt.Println("The user did not share a screenshot")
+		}
+	}
+	if err != nil {
+		return 1
+	}
+	switch result.Action {
+	case actionAcknowledged, actionDelivered, actionTimeout:
+		return 0 // Shown; a timeout means nobody clicked OK
+	}
+	return 1
+}
+
+// verifySpec returns the spec of the test notification: it names the machine and carries the
+// verification code in its title, so the result shows it is this notification that was shown
+func verifySpec(name, code string, timeout time.Duration, screenshot bool) []byte {
+	spec := map[string]interface{}{
+		"version": 1,
+		"title":   "Notification test " + code,
+		"message": fmt.Sprintf("Your administrator is checking that notifications reach %s. Please click OK.\n\nVerification code: %s", name, code),
+		"timeout": int(timeout / time.Second),
+		"result":  map[string]bool{"include_inventory": true},
+	}
+	if screenshot {
+		spec["context_screenshot"] = true
+	}
+	data, _ := json.Marshal(spec) // JSON is YAML
+	return data
+}
+
+// verify shows spec and returns its result: from the response of notify serve, or by
+// following the fleet notification on the controller until the agent reports it
+func (v *verifyClient) verify(ctx context.Context, spec []byte) (*NotificationResult, error) {
+	if v.host == "" {
+		var result NotificationResult
+		if err := v.do(ctx, http.MethodPost, "/v1/notifications", spec, http.StatusOK, &result); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+
+	var record fleetRecord
+	if err := v.do(ctx, http.MethodPost, "/v1/fleet/notifications?hosts="+url.QueryEscape(v.host), spec, http.StatusAccepted, &record); err != nil {
+		return nil, err
+	}
+	for {
+		status := record.Hosts[v.host]
+		switch status.Status {
+		case serveStatusDone:
+			return status.Result, nil
+		case serveStatusFailed:
+			return nil, errors.New(status.Error)
+		case fleetStatusOffline:
+			return nil, fmt.Errorf("%s has no agent connected to the controller", v.host)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("timed out waiting for the agent's result")
+		case <-time.After(v.poll):
+		}
+		if err := v.do(ctx, http.MethodGet, "/v1/fleet/notifications/"+record.ID, nil, http.StatusOK, &record); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do sends a request to the server and decodes its JSON response, which must have status want
+func (v *verifyClient) do(ctx context.Context, method, path string, body []byte, want int, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, v.base+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if v.token != "" {
+		req.Header.Set("Authorization", "Bearer "+v.token)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, serveRequestLimit<<4))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != want {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &failure) == nil && failure.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, failure.Error)
+		}
+		return fmt.Errorf("%s from %s", resp.Status, v.base)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("could not decode the response: %v", err)
+	}
+	return nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestVerifySpec tests that the test notification is a valid spec that notify serve accepts,
+// carrying the machine's name and the verification code
+func TestVerifySpec(t *testing.T) {
+	data := verifySpec("pc42", "C0FFEE", 2*time.Minute, true)
+	if err := checkServeSpec(data); err != nil {
+		t.Fatalf("notify serve rejects the test notification: %v", err)
+	}
+	spec, problems, err := parseSpec(data, "")
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("parseSpec failed: %v %v", err, problems)
+	}
+	if !strings.Contains(spec.Title, "C0FFEE") || !strings.Contains(spec.Message, "pc42") || !strings.Contains(spec.Message, "C0FFEE") {
+		t.Errorf("Expected the code in the title and the machine and code in the message, got %q / %q", spec.Title, spec.Message)
+	}
+	if spec.Screenshot == nil || !*spec.Screenshot || spec.Result.IncludeInventory == nil || !*spec.Result.IncludeInventory {
+		t.Error("Expected context_screenshot and result.include_inventory")
+	}
+}
+
+// TestVerify tests notify verify against the notify serve of a machine and through a
+// controller to an agent, and that an offline agent is reported
+func TestVerify(t *testing.T) {
+	// Shows the spec by echoing its title back, as the notify process would
+	launch := func(specPath string, done func(*NotificationResult, error)) error {
+		data, _ := os.ReadFile(specPath)
+		os.Remove(specPath)
+		spec, _, err := parseSpec(data, "")
+		if err != nil {
+			return err
+		}
+		done(&NotificationResult{MachineID: "m1", Action: actionAcknowledged, Method: "fyne", Title: spec.Title}, nil)
+		return nil
+	}
+	spec := verifySpec("lab1", "ABC123", time.Minute, false)
+
+	direct := &notifyServer{tokens: []serveToken{{token: "shower", scopes: []string{scopeShow}}}, launch: launch}
+	directServer := httptest.NewServer(direct.handler())
+	defer directServer.Close()
+	v := &verifyClient{base: directServer.URL, token: "shower", client: directServer.Client(), poll: 10 * time.Millisecond}
+	if result, err := v.verify(context.Background(), spec); err != nil || result.Action != actionAcknowledged || result.Title != "Notification test ABC123" {
+		t.Errorf("notify serve: %+v, %v", result, err)
+	}
+	v.token = "wrong"
+	if _, err := v.verify(context.Background(), spec); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected a 401 error with a wrong token, got %v", err)
+	}
+
+	controller := &notifyServer{tokens: []serveToken{{token: "secret", scopes: []string{scopeManage}}}, fleet: &fleetController{}}
+	controllerServer := httptest.NewServer(controller.handler())
+	defer controllerServer.Close()
+	agent := &fleetAgentClient{controller: controllerServer.URL, token: "secret", host: "lab1", client: controllerServer.Client(), launch: launch}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agent.session(ctx)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		controller.fleet.mu.Lock()
+		connected := controller.fleet.agents["lab1"] != nil
+		controller.fleet.mu.Unlock()
+		if connected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the agent to connect")
+		}
+	}
+
+	v = &verifyClient{base: controllerServer.URL, host: "lab1", token: "secret", client: controllerServer.Client(), poll: 10 * time.Millisecond}
+	timeout, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if result, err := v.verify(timeout, spec); err != nil || result.MachineID != "m1" || result.Title != "Notification test ABC123" {
+		t.Errorf("Through the controller: %+v, %v", result, err)
+	}
+	v.host = "lab9"
+	if _, err := v.verify(timeout, spec); err == nil || !strings.Contains(err.Error(), "no agent connected") {
+		t.Errorf("Expected lab9 to be reported offline, got %v", err)
+	}
+}
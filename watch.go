@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// watchPollInterval is how often -watch-file looks for lines appended to the file
+const watchPollInterval = time.Second
+
+// watchOptions controls which lines of a -watch-file become notifications
+type watchOptions struct {
+	dedupe time.Duration // Skip a title and message shown less than this long ago, 0 to show every line
+	rate   int           // Most notifications shown per minute, 0 for no limit
+	debug  bool          // Pass -debug to the notify processes
+	rules  eventRules    // -watch-match, and the templates of events that are not specs
+}
+
+// runWatchFile handles -watch-file: shows a notification for each JSON notification spec
+// appended to path, one per line, until interrupted
+// Lines already in the file when notify starts are not shown; -watch-match applies to the
+// top-level values of the specs
+func runWatchFile(path string, opts watchOptions) int {
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get executable path: %v\n", err)
+		return 1
+	}
+	tail := &fileTail{path: path}
+	if err := tail.start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	filter := newWatchFilter(opts)
+
+	fmt.Printf("Watching %s for notification specs, one JSON object per line (Ctrl+C to stop)\n", path)
+	for {
+		lines, err := tail.poll()
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		for _, line := range lines {
+			if len(opts.rules.matches) > 0 && !opts.rules.match(specFields(line)) {
+				continue
+			}
+			showTriggered(exePath, line, filter, opts.debug)
+		}
+		time.Sleep(watchPollInterval)
+	}
+}
+
+// showTriggered validates a notification spec from a trigger source (a -watch-file line, a
+// journal or syslog event) and launches its notify process, unless filter holds it back;
+// what happened is printed for the log of the watcher
+func showTriggered(exePath string, line []byte, filter *watchFilter, debug bool) {
+	now := time.Now()
+	spec, errs, err := parseSpec(line, "")
+	if err == nil && len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, e := range errs {
+			messages[i] = e.String()
+		}
+		err = fmt.Errorf("invalid spec: %s", strings.Join(messages, "; "))
+	}
+	if err == nil && len(spec.FollowUps) > 0 {
+		err = fmt.Errorf("follow_ups reference spec files and are not supported by -watch-file")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s skipped: %v\n", now.Format(time.RFC3339), err)
+		return
+	}
+	if ok, reason := filter.allow(spec.Title, spec.Message, now); !ok {
+		fmt.Printf("%s %s: %s\n", now.Format(time.RFC3339), reason, spec.Title)
+		return
+	}
+
+	specFile, err := os.CreateTemp("", "notify-watch-*.yaml")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", now.Format(time.RFC3339), err)
+		return
+	}
+	specFile.Write(line)
+	specFile.Close()
+	title := spec.Title
+	err = launchSpec(exePath, specFile.Name(), false, debug, func(result *NotificationResult, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s failed: %s: %v\n", time.Now().Format(time.RFC3339), title, err)
+			return
+		}
+		fmt.Printf("%s %s: %s\n", time.Now().Format(time.RFC3339), result.Action, title)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", now.Format(time.RFC3339), err)
+		return
+	}
+	fmt.Printf("%s shown: %s\n", now.Format(time.RFC3339), title)
+}
+
+// watchFilter holds back duplicates and notifications beyond the rate limit of the trigger sources
+type watchFilter struct {
+	dedupe time.Duration
+	rate   int
+	seen   map[string]time.Time // Title and message of the notifications shown, and when
+	shown  []time.Time          // When notifications were shown in the last minute
+}
+
+// newWatchFilter returns the filter of the -watch-dedupe and -watch-rate of opts
+func newWatchFilter(opts watchOptions) *watchFilter {
+	return &watchFilter{dedupe: opts.dedupe, rate: opts.rate, seen: map[string]time.Time{}}
+}
+
+// allow reports whether a notification with title and message may be shown at now, and
+// otherwise why not ("duplicate" or "rate limited"); allowed ones count as shown
+func (f *watchFilter) allow(title, message string, now time.Time) (bool, string) {
+	key := title + "\x00" + message
+	if f.dedupe > 0 {
+		for seenKey, at := range f.seen {
+			if now.Sub(at) >= f.dedupe {
+				delete(f.seen, seenKey)
+			}
+		}
+		if _, ok := f.seen[key]; ok {
+			return false, "duplicate"
+		}
+	}
+	if f.rate > 0 {
+		recent := f.shown[:0]
+		for _, at := range f.shown {
+			if now.Sub(at) < time.Minute {
+				recent = append(recent, at)
+			}
+		}
+		f.shown = recent
+		if len(f.shown) >= f.rate {
+			return false, "rate limited"
+		}
+		f.shown = append(f.shown, now)
+	}
+	if f.dedupe > 0 {
+		f.seen[key] = now
+	}
+	return true, ""
+}
+
+// fileTail reads the lines appended to a file, following it when it is truncated or replaced
+// (log rotation); the file is only open while reading, so rotation is never blocked
+type fileTail struct {
+	path    string
+	info    os.FileInfo // The file as of the last poll, nil while it does not exist
+	offset  int64       // How much of it has been read
+	partial []byte      // A line without its newline yet
+}
+
+// start skips what the file holds already; it must exist
+func (t *fileTail) start() error {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return fmt.Errorf("cannot watch file: %v", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("cannot watch file: %s is a directory", t.path)
+	}
+	t.info, t.offset = info, info.Size()
+	return nil
+}
+
+// poll returns the complete lines appended since the last poll, without their newlines
+// A file replaced by another (rotated) or truncated is read from its start
+func (t *fileTail) poll() ([][]byte, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return nil, nil // Rotation in progress: wait for the new file
+	}
+	if t.info == nil || !os.SameFile(t.info, info) || info.Size() < t.offset {
+		t.offset, t.partial = 0, nil
+	}
+	t.info = info
+	if info.Size() == t.offset {
+		return nil, nil
+	}
+
+	file, err := os.Open(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read watched file: %v", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.NewSectionReader(file, t.offset, info.Size()-t.offset))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read watched file: %v", err)
+	}
+	t.offset += int64(len(data))
+
+	data = append(t.partial, data...)
+	var lines [][]byte
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSpace(data[:i])
+		switch {
+		case len(line) > serveRequestLimit:
+			log.Printf("Warning: Skipping a line longer than %d bytes in %s", serveRequestLimit, t.path)
+		case len(line) > 0:
+			lines = append(lines, line)
+		}
+		data = data[i+1:]
+	}
+	// Like a request to notify serve, a line is at most serveRequestLimit
+	if len(data) > serveRequestLimit {
+		log.Printf("Warning: Skipping a line longer than %d bytes in %s", serveRequestLimit, t.path)
+		data = nil
+	}
+	t.partial = append([]byte(nil), data...)
+	return lines, nil
+}
+
+// "Now this is not the end. It is not even the beginning of the end. But it is, perhaps, the end of the beginning." Winston Churchill, November 10, 1942
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pollLines returns the lines of one poll as strings
+func pollLines(t *testing.T, tail *fileTail) []string {
+	t.Helper()
+	lines, err := tail.poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range lines {
+		got = append(got, string(line))
+	}
+	return got
+}
+
+// appendFile appends data to the file at path
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFileTail tests that only appended complete lines are returned, across truncation and rotation
+func TestFileTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.jsonl")
+	if err := os.WriteFile(path, []byte(`{"title": "old"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tail := &fileTail{path: path}
+	if err := tail.start(); err != nil {
+		t.Fatal(err)
+	}
+	if got := pollLines(t, tail); len(got) != 0 {
+		t.Errorf("lines before start = %q, want none", got)
+	}
+
+	appendFile(t, path, `{"title": "one"}`+"\n\n"+`{"title": "tw`)
+	if got := strings.Join(pollLines(t, tail), "|"); got != `{"title": "one"}` {
+		t.Errorf("appended lines = %q", got)
+	}
+	appendFile(t, path, `o"}`+"\r\n")
+	if got := strings.Join(pollLines(t, tail), "|"); got != `{"title": "two"}` {
+		t.Errorf("completed line = %q", got)
+	}
+
+	// Truncated in place (copytruncate rotation)
+	if err := os.WriteFile(path, []byte(`{"title": "three"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(pollLines(t, tail), "|"); got != `{"title": "three"}` {
+		t.Errorf("line after truncation = %q", got)
+	}
+
+	// Replaced by a new file (rename rotation)
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := pollLines(t, tail); len(got) != 0 {
+		t.Errorf("lines while rotating = %q, want none", got)
+	}
+	if err := os.WriteFile(path, []byte(`{"title": "four"}`+"\n"+`{"title": "five", "message": "a longer line than before"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(pollLines(t, tail), "|"); got != `{"title": "four"}|{"title": "five", "message": "a longer line than before"}` {
+		t.Errorf("lines of the rotated file = %q", got)
+	}
+}
+
+// TestWatchFilter tests deduplication and the per-minute rate limit of -watch-file
+func TestWatchFilter(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	filter := &watchFilter{dedupe: 10 * time.Minute, rate: 2, seen: map[string]time.Time{}}
+	steps := []struct {
+		title  string
+		after  time.Duration
+		reason string
+	}{
+		{"disk full", 0, ""},
+		{"disk full", time.Second, "duplicate"},
+		{"cpu hot", 2 * time.Second, ""},
+		{"fan failed", 3 * time.Second, "rate limited"},
+		{"fan failed", time.Minute + time.Second, ""},
+		{"disk full", 10 * time.Minute, ""},
+	}
+	for _, step := range steps {
+		ok, reason := filter.allow(step.title, "message", start.Add(step.after))
+		if ok != (step.reason == "") || reason != step.reason {
+			t.Errorf("%s after %v: allow = %v, %q, want %q", step.title, step.after, ok, reason, step.reason)
+		}
+	}
+}